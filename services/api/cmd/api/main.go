@@ -20,6 +20,7 @@ import (
 	"github.com/yakirshlomo/house-helper/services/api/internal/services"
 	"github.com/yakirshlomo/house-helper/services/api/internal/store"
 	"github.com/yakirshlomo/house-helper/services/api/pkg/kafka"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
 	"github.com/yakirshlomo/house-helper/services/api/pkg/temporal"
 )
 
@@ -98,22 +99,36 @@ func main() {
 
 	// Initialize stores
 	stores := &store.Stores{
-		Users:    store.NewUserStore(db),
-		Tasks:    store.NewTaskStore(db),
-		Shopping: store.NewShoppingStore(db),
-		Bills:    store.NewBillStore(db),
-		Timers:   store.NewTimerStore(db),
-		EventLog: store.NewEventLogStore(db),
+		Users:      store.NewUserStore(db),
+		Households: store.NewHouseholdStore(db),
+		Tasks:      store.NewTaskStore(db),
+		Shopping:   store.NewShoppingStore(db),
+		Bills:      store.NewBillStore(db),
+		Timers:     store.NewTimerStore(db),
+		EventLog:   store.NewEventLogStore(db),
+		Webhooks:   store.NewWebhookStore(db),
+		Away:       store.NewAwayStore(db),
+		Points:     store.NewPointsStore(db),
+		Rewards:    store.NewRewardStore(db),
+		Allowances: store.NewAllowanceStore(db),
 	}
 
 	// Initialize services
 	services := &services.Services{
 		Auth:         services.NewAuthService(stores.Users, cfg.JWTSecret),
-		Task:         services.NewTaskService(stores.Tasks, stores.EventLog, kafkaProducer),
+		Task:         services.NewTaskService(stores.Tasks, stores.Households, stores.EventLog, kafkaProducer, temporalClient),
+		Role:         services.NewRoleService(stores.Households),
 		Shopping:     services.NewShoppingService(stores.Shopping, stores.EventLog, kafkaProducer),
-		Bill:         services.NewBillService(stores.Bills, stores.EventLog, kafkaProducer),
+		Bill:         services.NewBillService(stores.Bills, stores.Households, stores.EventLog, kafkaProducer, temporalClient),
 		Timer:        services.NewTimerService(stores.Timers, temporalClient, stores.EventLog),
 		Notification: services.NewNotificationService(),
+		Webhook:      services.NewWebhookService(stores.Webhooks, stores.Households),
+		Away:         services.NewAwayService(stores.Away, stores.Tasks, stores.Households, temporalClient),
+		Assignment:   services.NewAssignmentService(stores.Households),
+		Points:       services.NewPointsService(stores.Points, stores.Households),
+		Reward:       services.NewRewardService(stores.Rewards, stores.Points, stores.Households, kafkaProducer),
+		Allowance:    services.NewAllowanceService(stores.Allowances, stores.Households, kafkaProducer),
+		Household:    services.NewHouseholdService(stores.Households, kafkaProducer, temporalClient),
 	}
 
 	// Initialize handlers
@@ -161,7 +176,10 @@ func main() {
 				tasks.POST("", h.CreateTask)
 				tasks.GET("/:id", h.GetTask)
 				tasks.PUT("/:id", h.UpdateTask)
-				tasks.DELETE("/:id", h.DeleteTask)
+				tasks.DELETE("/:id", h.RequireCapability(models.CapabilityDeleteTasks), h.DeleteTask)
+				tasks.POST("/:id/complete", h.CompleteTask)
+				tasks.POST("/:id/approve", h.ApproveTask)
+				tasks.POST("/:id/reject", h.RejectTask)
 			}
 
 			// Shopping routes
@@ -183,8 +201,8 @@ func main() {
 				}
 			}
 
-			// Bill routes
-			bills := protected.Group("/bills")
+			// Bill routes; children have no access to bills
+			bills := protected.Group("/bills", h.RequireCapability(models.CapabilityBills))
 			{
 				bills.GET("", h.GetBills)
 				bills.POST("", h.CreateBill)
@@ -193,6 +211,7 @@ func main() {
 				bills.DELETE("/:id", h.DeleteBill)
 				bills.POST("/:id/pay", h.PayBill)
 			}
+			protected.GET("/bill-series/:id", h.RequireCapability(models.CapabilityBills), h.GetBillSeries)
 
 			// Timer routes
 			timers := protected.Group("/timers")
@@ -203,6 +222,67 @@ func main() {
 				timers.GET("/:id", h.GetTimer)
 			}
 
+			// Household routes
+			households := protected.Group("/households")
+			{
+				households.POST("", h.CreateHousehold)
+				households.PUT("/:id", h.UpdateHousehold)
+				households.DELETE("/:id", h.DeleteHousehold)
+			}
+
+			// Household webhook routes
+			webhooks := protected.Group("/households/:id/webhooks")
+			{
+				webhooks.GET("", h.GetWebhooks)
+				webhooks.POST("", h.CreateWebhook)
+				webhooks.DELETE("/:webhook_id", h.DeleteWebhook)
+				webhooks.POST("/:webhook_id/enable", h.EnableWebhook)
+				webhooks.GET("/:webhook_id/deliveries", h.GetWebhookDeliveries)
+			}
+
+			// Household away period routes
+			away := protected.Group("/households/:id/away")
+			{
+				away.GET("", h.GetAwayPeriods)
+				away.POST("", h.CreateAwayPeriod)
+				away.DELETE("/:away_id", h.DeleteAwayPeriod)
+			}
+
+			// Household member assignment preference and points routes
+			members := protected.Group("/households/:id/members/:user_id")
+			{
+				members.PUT("/role", h.UpdateMemberRole)
+				members.GET("/assignment", h.GetAssignmentPreferences)
+				members.PUT("/assignment", h.UpdateAssignmentPreferences)
+				members.GET("/points", h.GetMemberPoints)
+				members.GET("/allowance", h.GetAllowance)
+				members.PUT("/allowance", h.UpdateAllowance)
+				members.GET("/allowance/statement", h.GetAllowanceStatement)
+				members.POST("/allowance/payouts", h.PayAllowance)
+			}
+
+			// Household leaderboard
+			protected.GET("/households/:id/leaderboard", h.GetLeaderboard)
+
+			// Household allowance ledger
+			protected.GET("/households/:id/allowance/payouts", h.GetAllowancePayouts)
+
+			// Household rewards catalog and redemption routes
+			rewards := protected.Group("/households/:id/rewards")
+			{
+				rewards.GET("", h.GetRewards)
+				rewards.POST("", h.CreateReward)
+				rewards.PUT("/:reward_id", h.UpdateReward)
+				rewards.DELETE("/:reward_id", h.DeleteReward)
+				rewards.POST("/:reward_id/redeem", h.RedeemReward)
+			}
+			redemptions := protected.Group("/households/:id/redemptions")
+			{
+				redemptions.GET("", h.GetRedemptions)
+				redemptions.POST("/:redemption_id/approve", h.ApproveRedemption)
+				redemptions.POST("/:redemption_id/reject", h.RejectRedemption)
+			}
+
 			// Activity routes
 			protected.GET("/activity", h.GetActivity)
 		}
@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/yakirshlomo/house-helper/services/api/internal/services"
+	"github.com/yakirshlomo/house-helper/services/api/internal/store"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
+)
+
+type AllowanceRequest struct {
+	BaseAmount           float64            `json:"base_amount" binding:"min=0"`
+	CategoryBonuses      map[string]float64 `json:"category_bonuses,omitempty" binding:"omitempty,dive,min=0"`
+	MissedChoreDeduction float64            `json:"missed_chore_deduction" binding:"min=0"`
+}
+
+type AllowancePayoutRequest struct {
+	Week string  `json:"week,omitempty"` // YYYY-MM-DD; any day of the week
+	Note *string `json:"note,omitempty" binding:"omitempty,max=255"`
+}
+
+// payoutCSVHeader is the header row of an allowance ledger export
+var payoutCSVHeader = []string{
+	"week_start", "member_id", "first_name", "last_name", "base_amount", "bonus_amount",
+	"deduction_amount", "amount", "currency", "completed_tasks", "missed_chores",
+	"paid_by", "paid_at", "note",
+}
+
+// GetAllowance godoc
+// @Summary Get allowance
+// @Description Get a child's weekly allowance settings, in the household's currency. Admins and the child may read them
+// @Tags allowance
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Household ID"
+// @Param user_id path string true "Child user ID"
+// @Success 200 {object} models.AllowanceSettings
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Router /v1/households/{id}/members/{user_id}/allowance [get]
+func (h *Handlers) GetAllowance(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	settings, err := h.services.Allowance.GetSettings(c.Request.Context(), userID.(string), c.Param("id"), c.Param("user_id"))
+	if err != nil {
+		h.allowanceError(c, err, "Failed to get allowance")
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateAllowance godoc
+// @Summary Update allowance
+// @Description Set a child's weekly base amount, bonus per completed task by category and deduction per missed chore. Only household admins may manage allowances
+// @Tags allowance
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Household ID"
+// @Param user_id path string true "Child user ID"
+// @Param allowance body AllowanceRequest true "Allowance settings"
+// @Success 200 {object} models.AllowanceSettings
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Router /v1/households/{id}/members/{user_id}/allowance [put]
+func (h *Handlers) UpdateAllowance(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	var req AllowanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings := &models.AllowanceSettings{
+		HouseholdID:          c.Param("id"),
+		UserID:               c.Param("user_id"),
+		BaseAmount:           req.BaseAmount,
+		CategoryBonuses:      req.CategoryBonuses,
+		MissedChoreDeduction: req.MissedChoreDeduction,
+	}
+	if err := h.services.Allowance.UpdateSettings(c.Request.Context(), userID.(string), settings); err != nil {
+		h.allowanceError(c, err, "Failed to update allowance")
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// GetAllowanceStatement godoc
+// @Summary Get allowance statement
+// @Description Get a child's allowance for a week, from Monday in the household's timezone: the base amount, bonuses for the tasks completed by category and deductions for the chores the reminder workflows found missed. The payout is included once the week was paid
+// @Tags allowance
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Household ID"
+// @Param user_id path string true "Child user ID"
+// @Param week query string false "Any day of the week, YYYY-MM-DD; the current week when omitted"
+// @Success 200 {object} models.AllowanceStatement
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Router /v1/households/{id}/members/{user_id}/allowance/statement [get]
+func (h *Handlers) GetAllowanceStatement(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	statement, err := h.services.Allowance.GetStatement(c.Request.Context(), userID.(string), c.Param("id"), c.Param("user_id"), c.Query("week"))
+	if err != nil {
+		h.allowanceError(c, err, "Failed to get allowance statement")
+		return
+	}
+
+	c.JSON(http.StatusOK, statement)
+}
+
+// PayAllowance godoc
+// @Summary Pay allowance
+// @Description Record a week's allowance statement as paid in the append-only allowance ledger. A week is paid once, after it ended. Only household admins may pay allowances
+// @Tags allowance
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Household ID"
+// @Param user_id path string true "Child user ID"
+// @Param payout body AllowancePayoutRequest false "Week, the previous one when omitted, and note"
+// @Success 201 {object} models.AllowancePayout
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Router /v1/households/{id}/members/{user_id}/allowance/payouts [post]
+func (h *Handlers) PayAllowance(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	var req AllowancePayoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	payout, err := h.services.Allowance.RecordPayout(c.Request.Context(), userID.(string), c.Param("id"), c.Param("user_id"), req.Week, req.Note)
+	if err != nil {
+		h.allowanceError(c, err, "Failed to pay allowance")
+		return
+	}
+
+	c.JSON(http.StatusCreated, payout)
+}
+
+// GetAllowancePayouts godoc
+// @Summary Get allowance payouts
+// @Description Get a household's allowance ledger, latest week first, as JSON or CSV. Admins see every child's payouts; other members only their own
+// @Tags allowance
+// @Security BearerAuth
+// @Produce json
+// @Produce text/csv
+// @Param id path string true "Household ID"
+// @Param user_id query string false "Only this child's payouts"
+// @Param from query string false "Weeks from the one this day falls in, YYYY-MM-DD"
+// @Param to query string false "Weeks up to the one this day falls in, YYYY-MM-DD"
+// @Param format query string false "json or csv" default(json)
+// @Success 200 {array} models.AllowancePayout
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /v1/households/{id}/allowance/payouts [get]
+func (h *Handlers) GetAllowancePayouts(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format, want json or csv"})
+		return
+	}
+
+	var childID *string
+	if value := c.Query("user_id"); value != "" {
+		childID = &value
+	}
+
+	payouts, err := h.services.Allowance.ListPayouts(c.Request.Context(), userID.(string), c.Param("id"), childID, c.Query("from"), c.Query("to"))
+	if err != nil {
+		h.allowanceError(c, err, "Failed to get allowance payouts")
+		return
+	}
+
+	if format == "csv" {
+		h.writePayoutsCSV(c, payouts)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"payouts": payouts,
+		"total":   len(payouts),
+	})
+}
+
+// writePayoutsCSV writes an allowance ledger export
+func (h *Handlers) writePayoutsCSV(c *gin.Context, payouts []*models.AllowancePayout) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="allowance-payouts-%s.csv"`, time.Now().UTC().Format("2006-01-02")))
+	c.Status(http.StatusOK)
+
+	amount := func(value float64) string {
+		return strconv.FormatFloat(value, 'f', 2, 64)
+	}
+
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write(payoutCSVHeader)
+	for _, payout := range payouts {
+		note := ""
+		if payout.Note != nil {
+			note = *payout.Note
+		}
+		_ = w.Write([]string{
+			payout.WeekStart.Format("2006-01-02"),
+			payout.UserID,
+			csvCell(payout.FirstName),
+			csvCell(payout.LastName),
+			amount(payout.BaseAmount),
+			amount(payout.BonusAmount),
+			amount(payout.DeductionAmount),
+			amount(payout.Amount),
+			payout.Currency,
+			strconv.Itoa(payout.CompletedTasks),
+			strconv.Itoa(payout.MissedChores),
+			payout.PaidBy,
+			payout.CreatedAt.UTC().Format(time.RFC3339),
+			csvCell(note),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		h.logger.Error("Failed to write allowance payouts", zap.Error(err), zap.String("household_id", c.Param("id")))
+	}
+}
+
+// csvCell returns a free text cell that spreadsheets show as text. Cells
+// starting with =, +, -, @, a tab or a carriage return are otherwise run as
+// formulas, so they are prefixed with a quote.
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// allowanceError writes the response for an error of the allowance service
+func (h *Handlers) allowanceError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidAllowance),
+		errors.Is(err, services.ErrInvalidWeek):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotHouseholdMember):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this household"})
+	case errors.Is(err, services.ErrNotHouseholdAdmin):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only household admins can manage allowances"})
+	case errors.Is(err, services.ErrAllowanceNotChild):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Allowances are for children"})
+	case errors.Is(err, services.ErrWeekNotOver):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, store.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "Week was paid already"})
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Allowance not found"})
+	default:
+		h.logger.Error(message, zap.Error(err), zap.String("household_id", c.Param("id")))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import "testing"
+
+func TestCSVCellEscapesFormulas(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", ""},
+		{"Maya", "Maya"},
+		{"O'Brien", "O'Brien"},
+		{"=HYPERLINK(\"http://evil\")", "'=HYPERLINK(\"http://evil\")"},
+		{"+1 for chores", "'+1 for chores"},
+		{"-5 missed", "'-5 missed"},
+		{"@SUM(A1:A2)", "'@SUM(A1:A2)"},
+		{"\t=1+1", "'\t=1+1"},
+		{"\r=1+1", "'\r=1+1"},
+		{"paid 5 = 3 + 2", "paid 5 = 3 + 2"},
+	}
+
+	for _, tt := range tests {
+		if got := csvCell(tt.value); got != tt.want {
+			t.Errorf("csvCell(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/yakirshlomo/house-helper/services/api/internal/services"
+	"github.com/yakirshlomo/house-helper/services/api/internal/store"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
+)
+
+type AssignmentPreferencesRequest struct {
+	Weight              *float64   `json:"weight,omitempty" binding:"omitempty,gt=0,lt=100"` // omitted to derive it from role and age
+	DateOfBirth         *time.Time `json:"date_of_birth,omitempty"`
+	ExcludedCategories  []string   `json:"excluded_categories" binding:"omitempty,max=20,dive,max=100"`
+	PreferredCategories []string   `json:"preferred_categories" binding:"omitempty,max=20,dive,max=100"`
+}
+
+// GetAssignmentPreferences godoc
+// @Summary Get assignment preferences
+// @Description Get what the chore assignment engine knows of a household member
+// @Tags assignment
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Household ID"
+// @Param user_id path string true "Member user ID"
+// @Success 200 {object} models.AssignmentPreferences
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/households/{id}/members/{user_id}/assignment [get]
+func (h *Handlers) GetAssignmentPreferences(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	prefs, err := h.services.Assignment.GetPreferences(c.Request.Context(), userID.(string), c.Param("id"), c.Param("user_id"))
+	if err != nil {
+		h.assignmentError(c, err, "Failed to get assignment preferences")
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// UpdateAssignmentPreferences godoc
+// @Summary Update assignment preferences
+// @Description Set a member's weight, date of birth and excluded or preferred task categories, used to spread auto-assigned chores fairly. Admins may edit anyone's; members may edit only their own preferred categories
+// @Tags assignment
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Household ID"
+// @Param user_id path string true "Member user ID"
+// @Param preferences body AssignmentPreferencesRequest true "Assignment preferences"
+// @Success 200 {object} models.AssignmentPreferences
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/households/{id}/members/{user_id}/assignment [put]
+func (h *Handlers) UpdateAssignmentPreferences(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	var req AssignmentPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prefs := &models.AssignmentPreferences{
+		HouseholdID:         c.Param("id"),
+		UserID:              c.Param("user_id"),
+		Weight:              req.Weight,
+		DateOfBirth:         req.DateOfBirth,
+		ExcludedCategories:  req.ExcludedCategories,
+		PreferredCategories: req.PreferredCategories,
+	}
+	if err := h.services.Assignment.UpdatePreferences(c.Request.Context(), userID.(string), prefs); err != nil {
+		h.assignmentError(c, err, "Failed to update assignment preferences")
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// assignmentError writes the response for an error of the assignment service
+func (h *Handlers) assignmentError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidAssignmentPreferences):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotHouseholdMember):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this household"})
+	case errors.Is(err, services.ErrNotHouseholdAdmin):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only household admins can edit other members, weights, dates of birth and excluded categories"})
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+	default:
+		h.logger.Error(message, zap.Error(err), zap.String("household_id", c.Param("id")))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
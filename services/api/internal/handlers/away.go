@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/yakirshlomo/house-helper/services/api/internal/services"
+	"github.com/yakirshlomo/house-helper/services/api/internal/store"
+)
+
+type AwayPeriodRequest struct {
+	UserID   *string   `json:"user_id,omitempty" binding:"omitempty,uuid"` // omitted when the whole household is away
+	StartsAt time.Time `json:"starts_at" binding:"required"`
+	EndsAt   time.Time `json:"ends_at" binding:"required"`
+	Note     *string   `json:"note,omitempty" binding:"omitempty,max=255"`
+}
+
+// CreateAwayPeriod godoc
+// @Summary Create away period
+// @Description Record that the household, or one member, is away. While the household is away its recurring chores are paused; away members are not auto-assigned tasks and their reminders wait for their return. Members may record their own periods; household periods and other members' need an admin
+// @Tags away
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Household ID"
+// @Param away body AwayPeriodRequest true "Away period data"
+// @Success 201 {object} models.AwayPeriod
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /v1/households/{id}/away [post]
+func (h *Handlers) CreateAwayPeriod(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	var req AwayPeriodRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	period, err := h.services.Away.CreateAwayPeriod(c.Request.Context(), userID.(string), c.Param("id"), req.UserID, req.StartsAt, req.EndsAt, req.Note)
+	if err != nil {
+		h.awayError(c, err, "Failed to create away period")
+		return
+	}
+
+	c.JSON(http.StatusCreated, period)
+}
+
+// GetAwayPeriods godoc
+// @Summary Get away periods
+// @Description Get the current and upcoming away periods of a household
+// @Tags away
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Household ID"
+// @Success 200 {array} models.AwayPeriod
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /v1/households/{id}/away [get]
+func (h *Handlers) GetAwayPeriods(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	periods, err := h.services.Away.ListAwayPeriods(c.Request.Context(), userID.(string), c.Param("id"))
+	if err != nil {
+		h.awayError(c, err, "Failed to get away periods")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"away":  periods,
+		"total": len(periods),
+	})
+}
+
+// DeleteAwayPeriod godoc
+// @Summary Delete away period
+// @Description Delete an away period; the household's recurring chores resume if it was away. Members may delete their own periods; household periods and other members' need an admin
+// @Tags away
+// @Security BearerAuth
+// @Param id path string true "Household ID"
+// @Param away_id path string true "Away period ID"
+// @Success 204
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/households/{id}/away/{away_id} [delete]
+func (h *Handlers) DeleteAwayPeriod(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	err := h.services.Away.DeleteAwayPeriod(c.Request.Context(), userID.(string), c.Param("id"), c.Param("away_id"))
+	if err != nil {
+		h.awayError(c, err, "Failed to delete away period")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// awayError writes the response for an error of the away service
+func (h *Handlers) awayError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidAwayPeriod):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotHouseholdMember):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this household"})
+	case errors.Is(err, services.ErrNotHouseholdAdmin):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only household admins can manage the household's or other members' away periods"})
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Away period not found"})
+	default:
+		h.logger.Error(message, zap.Error(err), zap.String("household_id", c.Param("id")))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -1,12 +1,17 @@
 package handlers
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"go.uber.org/zap"
+
+	"github.com/yakirshlomo/house-helper/services/api/internal/services"
+	"github.com/yakirshlomo/house-helper/services/api/internal/store"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
 )
 
 type BillRequest struct {
@@ -16,7 +21,20 @@ type BillRequest struct {
 	Currency    string  `json:"currency" binding:"required"`
 	DueDate     string  `json:"due_date" binding:"required"`
 	Category    *string `json:"category,omitempty"`
-	Recurrence  *string `json:"recurrence,omitempty"`
+	Recurrence  *string `json:"recurrence,omitempty" binding:"omitempty,oneof=daily weekly biweekly monthly quarterly yearly"`
+
+	// AmountType sets how the amounts of a recurring bill's later instances
+	// are set: fixed, estimated from the amounts paid, or open until paid
+	AmountType *string `json:"amount_type,omitempty" binding:"omitempty,oneof=fixed estimated open"`
+
+	// AssignedTo is reminded before the due date; the creator when empty.
+	// It must be a member of the household.
+	AssignedTo   *string `json:"assigned_to,omitempty" binding:"omitempty,uuid"`
+	ReminderDays *int    `json:"reminder_days,omitempty" binding:"omitempty,min=0,max=60"`
+}
+
+type PayBillRequest struct {
+	Amount *float64 `json:"amount,omitempty" binding:"omitempty,min=0"`
 }
 
 type BillResponse struct {
@@ -33,6 +51,78 @@ type BillResponse struct {
 	Recurrence  *string    `json:"recurrence,omitempty"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
+
+	AssignedTo   *string  `json:"assigned_to,omitempty"`
+	ReminderDays *int     `json:"reminder_days,omitempty"`
+	PaidBy       *string  `json:"paid_by,omitempty"`
+	PaidAmount   *float64 `json:"paid_amount,omitempty"`
+	SeriesID     *string  `json:"series_id,omitempty"`
+	Sequence     *int     `json:"sequence,omitempty"`
+	AmountType   string   `json:"amount_type"`
+}
+
+type BillSeriesResponse struct {
+	Series    *models.BillSeries `json:"series"`
+	Instances []BillResponse     `json:"instances"`
+}
+
+// newBillResponse converts a stored bill
+func newBillResponse(bill *models.Bill) BillResponse {
+	response := BillResponse{
+		ID:           bill.ID,
+		Name:         bill.Name,
+		Amount:       bill.Amount,
+		Currency:     bill.Currency,
+		DueDate:      bill.DueDate,
+		PaidAt:       bill.PaidAt,
+		HouseholdID:  bill.HouseholdID,
+		Status:       string(bill.Status),
+		Category:     stringPtr(bill.Category),
+		Recurrence:   bill.RecurrenceRule,
+		CreatedAt:    bill.CreatedAt,
+		UpdatedAt:    bill.UpdatedAt,
+		AssignedTo:   bill.AssignedTo,
+		ReminderDays: bill.ReminderDays,
+		PaidBy:       bill.PaidBy,
+		PaidAmount:   bill.PaidAmount,
+		SeriesID:     bill.SeriesID,
+		Sequence:     bill.Sequence,
+		AmountType:   string(bill.AmountType),
+	}
+	if bill.Description != "" {
+		response.Description = stringPtr(bill.Description)
+	}
+	return response
+}
+
+// billFromRequest returns the bill described by a request
+func billFromRequest(req BillRequest, dueDate time.Time) *models.Bill {
+	bill := &models.Bill{
+		Name:           req.Name,
+		Category:       "other",
+		Amount:         req.Amount,
+		Currency:       req.Currency,
+		DueDate:        dueDate,
+		RecurrenceRule: req.Recurrence,
+		AssignedTo:     req.AssignedTo,
+		ReminderDays:   req.ReminderDays,
+	}
+	if req.Description != nil {
+		bill.Description = *req.Description
+	}
+	if req.Category != nil {
+		bill.Category = *req.Category
+	}
+	if req.AmountType != nil {
+		bill.AmountType = models.BillAmountType(*req.AmountType)
+	}
+	return bill
+}
+
+// billEditScope returns the scope query parameter of an edit to a bill
+func billEditScope(c *gin.Context) (services.BillEditScope, bool) {
+	scope := services.BillEditScope(c.DefaultQuery("scope", string(services.BillEditThis)))
+	return scope, scope == services.BillEditThis || scope == services.BillEditThisAndFuture
 }
 
 // GetBills godoc
@@ -120,7 +210,7 @@ func (h *Handlers) GetBills(c *gin.Context) {
 
 // CreateBill godoc
 // @Summary Create bill
-// @Description Create a new bill
+// @Description Create a new bill. The assignee, or the creator, is reminded reminder_days (default 3) before the due date, and followed up with once the bill is overdue until it is paid. A bill with a recurrence starts a series; the next instance is created when it is paid or due
 // @Tags bills
 // @Security BearerAuth
 // @Accept json
@@ -131,6 +221,11 @@ func (h *Handlers) GetBills(c *gin.Context) {
 // @Failure 401 {object} map[string]string
 // @Router /v1/bills [post]
 func (h *Handlers) CreateBill(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
 	householdID, exists := c.Get("household_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Household not found in token"})
@@ -151,24 +246,19 @@ func (h *Handlers) CreateBill(c *gin.Context) {
 	}
 
 	// Determine status based on due date
-	status := "pending"
+	status := models.BillStatusPending
 	if dueDate.Before(time.Now()) {
-		status = "overdue"
+		status = models.BillStatusOverdue
 	}
 
-	bill := BillResponse{
-		ID:          uuid.New().String(),
-		Name:        req.Name,
-		Description: req.Description,
-		Amount:      req.Amount,
-		Currency:    req.Currency,
-		DueDate:     dueDate,
-		HouseholdID: householdID.(string),
-		Status:      status,
-		Category:    req.Category,
-		Recurrence:  req.Recurrence,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+	bill := billFromRequest(req, dueDate)
+	bill.Status = status
+	bill.HouseholdID = householdID.(string)
+	bill.CreatedBy = userID.(string)
+
+	if err := h.services.Bill.CreateBill(c.Request.Context(), bill); err != nil {
+		h.billError(c, err, "Failed to create bill")
+		return
 	}
 
 	h.logger.Info("Bill created",
@@ -177,7 +267,7 @@ func (h *Handlers) CreateBill(c *gin.Context) {
 		zap.Float64("amount", req.Amount),
 	)
 
-	c.JSON(http.StatusCreated, bill)
+	c.JSON(http.StatusCreated, newBillResponse(bill))
 }
 
 // GetBill godoc
@@ -215,12 +305,13 @@ func (h *Handlers) GetBill(c *gin.Context) {
 
 // UpdateBill godoc
 // @Summary Update bill
-// @Description Update an existing bill
+// @Description Update an existing bill. With scope this_and_future the changes also apply to the unpaid instances after it and to the series later instances are created from; the recurrence only changes with that scope
 // @Tags bills
 // @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param id path string true "Bill ID"
+// @Param scope query string false "Instances the changes apply to (this, this_and_future)" default(this)
 // @Param bill body BillRequest true "Updated bill data"
 // @Success 200 {object} BillResponse
 // @Failure 400 {object} map[string]string
@@ -230,6 +321,12 @@ func (h *Handlers) UpdateBill(c *gin.Context) {
 	billID := c.Param("id")
 	householdID, _ := c.Get("household_id")
 
+	scope, ok := billEditScope(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scope. Use this or this_and_future."})
+		return
+	}
+
 	var req BillRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -243,75 +340,111 @@ func (h *Handlers) UpdateBill(c *gin.Context) {
 		return
 	}
 
-	bill := BillResponse{
-		ID:          billID,
-		Name:        req.Name,
-		Description: req.Description,
-		Amount:      req.Amount,
-		Currency:    req.Currency,
-		DueDate:     dueDate,
-		HouseholdID: householdID.(string),
-		Status:      "pending", // TODO: Calculate actual status
-		Category:    req.Category,
-		Recurrence:  req.Recurrence,
-		CreatedAt:   time.Now().Add(-30 * 24 * time.Hour),
-		UpdatedAt:   time.Now(),
+	bill, err := h.services.Bill.UpdateBill(c.Request.Context(), householdID.(string), billID, billFromRequest(req, dueDate), scope)
+	if err != nil {
+		h.billError(c, err, "Failed to update bill")
+		return
 	}
 
 	h.logger.Info("Bill updated",
 		zap.String("bill_id", billID),
 		zap.String("name", req.Name),
+		zap.String("scope", string(scope)),
 	)
 
-	c.JSON(http.StatusOK, bill)
+	c.JSON(http.StatusOK, newBillResponse(bill))
 }
 
 // DeleteBill godoc
 // @Summary Delete bill
-// @Description Delete a bill
+// @Description Delete a bill. With scope this_and_future the bill's series ends and its unpaid instances from this one on are deleted
 // @Tags bills
 // @Security BearerAuth
 // @Param id path string true "Bill ID"
+// @Param scope query string false "Instances to delete (this, this_and_future)" default(this)
 // @Success 204
+// @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Router /v1/bills/{id} [delete]
 func (h *Handlers) DeleteBill(c *gin.Context) {
 	billID := c.Param("id")
+	householdID, _ := c.Get("household_id")
 
-	h.logger.Info("Bill deleted", zap.String("bill_id", billID))
+	scope, ok := billEditScope(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scope. Use this or this_and_future."})
+		return
+	}
+
+	if err := h.services.Bill.DeleteBill(c.Request.Context(), householdID.(string), billID, scope); err != nil {
+		h.billError(c, err, "Failed to delete bill")
+		return
+	}
+
+	h.logger.Info("Bill deleted", zap.String("bill_id", billID), zap.String("scope", string(scope)))
 	c.Status(http.StatusNoContent)
 }
 
+// GetBillSeries godoc
+// @Summary Get bill series
+// @Description Get a recurring bill series with its instances
+// @Tags bills
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Series ID"
+// @Success 200 {object} BillSeriesResponse
+// @Failure 404 {object} map[string]string
+// @Router /v1/bill-series/{id} [get]
+func (h *Handlers) GetBillSeries(c *gin.Context) {
+	householdID, _ := c.Get("household_id")
+
+	series, instances, err := h.services.Bill.GetBillSeries(c.Request.Context(), householdID.(string), c.Param("id"))
+	if err != nil {
+		h.billError(c, err, "Failed to get bill series")
+		return
+	}
+
+	response := BillSeriesResponse{Series: series, Instances: []BillResponse{}}
+	for _, instance := range instances {
+		response.Instances = append(response.Instances, newBillResponse(instance))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
 // PayBill godoc
 // @Summary Pay bill
-// @Description Mark a bill as paid
+// @Description Mark a bill as paid, which stops its reminders. The amount defaults to the bill's amount and is required for a bill with an open amount
 // @Tags bills
 // @Security BearerAuth
+// @Accept json
+// @Produce json
 // @Param id path string true "Bill ID"
+// @Param payment body PayBillRequest false "Payment data"
 // @Success 200 {object} BillResponse
+// @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Router /v1/bills/{id}/pay [post]
 func (h *Handlers) PayBill(c *gin.Context) {
 	billID := c.Param("id")
-	userID, _ := c.Get("user_id")
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
 	householdID, _ := c.Get("household_id")
 
-	// Mock bill payment
-	now := time.Now()
-	bill := BillResponse{
-		ID:          billID,
-		Name:        "Electricity Bill",
-		Description: stringPtr("Monthly electricity payment"),
-		Amount:      150.75,
-		Currency:    "USD",
-		DueDate:     time.Now().Add(5 * 24 * time.Hour),
-		PaidAt:      &now,
-		HouseholdID: householdID.(string),
-		Status:      "paid",
-		Category:    stringPtr("utilities"),
-		Recurrence:  stringPtr("monthly"),
-		CreatedAt:   time.Now().Add(-30 * 24 * time.Hour),
-		UpdatedAt:   now,
+	var req PayBillRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	bill, err := h.services.Bill.PayBill(c.Request.Context(), userID.(string), householdID.(string), billID, req.Amount)
+	if err != nil {
+		h.billError(c, err, "Failed to pay bill")
+		return
 	}
 
 	h.logger.Info("Bill paid",
@@ -320,5 +453,20 @@ func (h *Handlers) PayBill(c *gin.Context) {
 		zap.Float64("amount", bill.Amount),
 	)
 
-	c.JSON(http.StatusOK, bill)
+	c.JSON(http.StatusOK, newBillResponse(bill))
+}
+
+// billError writes the response for an error of the bill service
+func (h *Handlers) billError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrBillNotRecurring), errors.Is(err, services.ErrBillAmountRequired), errors.Is(err, services.ErrBillAssigneeNotMember):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrBillSettled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Bill not found"})
+	default:
+		h.logger.Error(message, zap.Error(err), zap.String("bill_id", c.Param("id")))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
 }
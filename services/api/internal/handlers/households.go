@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/yakirshlomo/house-helper/services/api/internal/services"
+	"github.com/yakirshlomo/house-helper/services/api/internal/store"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
+)
+
+type CreateHouseholdRequest struct {
+	Name        string  `json:"name" binding:"required,min=1,max=255"`
+	Description *string `json:"description,omitempty" binding:"omitempty,max=1000"`
+	Timezone    string  `json:"timezone,omitempty" binding:"omitempty,max=64"`
+	Currency    string  `json:"currency,omitempty" binding:"omitempty,len=3"`
+}
+
+type UpdateHouseholdRequest struct {
+	Name        *string `json:"name,omitempty" binding:"omitempty,min=1,max=255"`
+	Description *string `json:"description,omitempty" binding:"omitempty,max=1000"`
+	Timezone    *string `json:"timezone,omitempty" binding:"omitempty,max=64"`
+	Currency    *string `json:"currency,omitempty" binding:"omitempty,len=3"`
+}
+
+// CreateHousehold godoc
+// @Summary Create household
+// @Description Create a household with the current user as its admin. Its members get a daily digest at 07:00 in the household's timezone, UTC by default
+// @Tags households
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param household body CreateHouseholdRequest true "Household data"
+// @Success 201 {object} models.Household
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /v1/households [post]
+func (h *Handlers) CreateHousehold(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	var req CreateHouseholdRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	household, err := h.services.Household.CreateHousehold(c.Request.Context(), userID.(string), req.Name, req.Description, req.Timezone, req.Currency)
+	if err != nil {
+		h.householdError(c, err, "Failed to create household")
+		return
+	}
+
+	c.JSON(http.StatusCreated, household)
+}
+
+// UpdateHousehold godoc
+// @Summary Update household
+// @Description Update a household; the daily digest moves with its timezone. Only household admins may change a household
+// @Tags households
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Household ID"
+// @Param household body UpdateHouseholdRequest true "Household data"
+// @Success 200 {object} models.Household
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/households/{id} [put]
+func (h *Handlers) UpdateHousehold(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	var req UpdateHouseholdRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	household, err := h.services.Household.UpdateHousehold(c.Request.Context(), userID.(string), c.Param("id"), req.Name, req.Description, req.Timezone, req.Currency)
+	if err != nil {
+		h.householdError(c, err, "Failed to update household")
+		return
+	}
+
+	c.JSON(http.StatusOK, household)
+}
+
+// DeleteHousehold godoc
+// @Summary Delete household
+// @Description Delete a household and stop its daily digest. Only household admins may delete a household
+// @Tags households
+// @Security BearerAuth
+// @Param id path string true "Household ID"
+// @Success 204
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/households/{id} [delete]
+func (h *Handlers) DeleteHousehold(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	if err := h.services.Household.DeleteHousehold(c.Request.Context(), userID.(string), c.Param("id")); err != nil {
+		h.householdError(c, err, "Failed to delete household")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type UpdateMemberRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin member guest child"`
+}
+
+// UpdateMemberRole godoc
+// @Summary Update member role
+// @Description Change a household member's role. Guests stop receiving household push announcements. Only household admins may change roles, and the last admin cannot step down
+// @Tags households
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Household ID"
+// @Param user_id path string true "Member user ID"
+// @Param role body UpdateMemberRoleRequest true "New role"
+// @Success 200 {object} models.HouseholdMember
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /v1/households/{id}/members/{user_id}/role [put]
+func (h *Handlers) UpdateMemberRole(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	var req UpdateMemberRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member, err := h.services.Household.UpdateMemberRole(c.Request.Context(), userID.(string), c.Param("id"), c.Param("user_id"), models.HouseholdRole(req.Role))
+	if err != nil {
+		h.householdError(c, err, "Failed to update member role")
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+// householdError writes the response for an error of the household service
+func (h *Handlers) householdError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidHousehold), errors.Is(err, services.ErrInvalidHouseholdRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrLastHouseholdAdmin):
+		c.JSON(http.StatusConflict, gin.H{"error": "The household needs at least one other admin"})
+	case errors.Is(err, services.ErrNotHouseholdMember):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this household"})
+	case errors.Is(err, services.ErrNotHouseholdAdmin):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only household admins can change the household"})
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Household or member not found"})
+	default:
+		h.logger.Error(message, zap.Error(err), zap.String("household_id", c.Param("id")))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/yakirshlomo/house-helper/services/api/internal/services"
+	"github.com/yakirshlomo/house-helper/services/api/internal/store"
+)
+
+// GetLeaderboard godoc
+// @Summary Get leaderboard
+// @Description Rank a household's members by the points they earned from completed tasks this week (from Monday) or this month, in the household's timezone
+// @Tags points
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Household ID"
+// @Param period query string false "week or month" default(week)
+// @Success 200 {object} models.Leaderboard
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /v1/households/{id}/leaderboard [get]
+func (h *Handlers) GetLeaderboard(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	leaderboard, err := h.services.Points.GetLeaderboard(c.Request.Context(), userID.(string), c.Param("id"), c.Query("period"))
+	if err != nil {
+		h.pointsError(c, err, "Failed to get leaderboard")
+		return
+	}
+
+	c.JSON(http.StatusOK, leaderboard)
+}
+
+// GetMemberPoints godoc
+// @Summary Get member points
+// @Description Get a member's points balance, completion streak and latest points ledger entries
+// @Tags points
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Household ID"
+// @Param user_id path string true "Member user ID"
+// @Success 200 {object} models.MemberPoints
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/households/{id}/members/{user_id}/points [get]
+func (h *Handlers) GetMemberPoints(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	points, err := h.services.Points.GetMemberPoints(c.Request.Context(), userID.(string), c.Param("id"), c.Param("user_id"))
+	if err != nil {
+		h.pointsError(c, err, "Failed to get member points")
+		return
+	}
+
+	c.JSON(http.StatusOK, points)
+}
+
+// pointsError writes the response for an error of the points service
+func (h *Handlers) pointsError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidPeriod):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotHouseholdMember):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this household"})
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+	default:
+		h.logger.Error(message, zap.Error(err), zap.String("household_id", c.Param("id")))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/yakirshlomo/house-helper/services/api/internal/services"
+	"github.com/yakirshlomo/house-helper/services/api/internal/store"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
+)
+
+type RewardRequest struct {
+	Name          string  `json:"name" binding:"required,max=255"`
+	Description   *string `json:"description,omitempty"`
+	Cost          int     `json:"cost" binding:"required,min=1"`
+	Stock         *int    `json:"stock,omitempty" binding:"omitempty,min=0"` // omitted when unlimited
+	CooldownHours int     `json:"cooldown_hours" binding:"min=0"`
+}
+
+type RedemptionRequest struct {
+	Note *string `json:"note,omitempty" binding:"omitempty,max=500"`
+}
+
+// GetRewards godoc
+// @Summary Get rewards
+// @Description Get a household's rewards catalog; any member, guests included, may browse it
+// @Tags rewards
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Household ID"
+// @Success 200 {array} models.Reward
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /v1/households/{id}/rewards [get]
+func (h *Handlers) GetRewards(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	rewards, err := h.services.Reward.ListRewards(c.Request.Context(), userID.(string), c.Param("id"))
+	if err != nil {
+		h.rewardError(c, err, "Failed to get rewards")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rewards": rewards,
+		"total":   len(rewards),
+	})
+}
+
+// CreateReward godoc
+// @Summary Create reward
+// @Description Add a reward to the household's catalog. Only household admins may manage the catalog
+// @Tags rewards
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Household ID"
+// @Param reward body RewardRequest true "Reward data"
+// @Success 201 {object} models.Reward
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /v1/households/{id}/rewards [post]
+func (h *Handlers) CreateReward(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	var req RewardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reward := req.reward(c.Param("id"))
+	if err := h.services.Reward.CreateReward(c.Request.Context(), userID.(string), reward); err != nil {
+		h.rewardError(c, err, "Failed to create reward")
+		return
+	}
+
+	c.JSON(http.StatusCreated, reward)
+}
+
+// UpdateReward godoc
+// @Summary Update reward
+// @Description Replace a reward's name, description, cost, stock and cooldown. Pending redemptions keep the cost they were requested at
+// @Tags rewards
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Household ID"
+// @Param reward_id path string true "Reward ID"
+// @Param reward body RewardRequest true "Reward data"
+// @Success 200 {object} models.Reward
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/households/{id}/rewards/{reward_id} [put]
+func (h *Handlers) UpdateReward(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	var req RewardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reward := req.reward(c.Param("id"))
+	reward.ID = c.Param("reward_id")
+	if err := h.services.Reward.UpdateReward(c.Request.Context(), userID.(string), reward); err != nil {
+		h.rewardError(c, err, "Failed to update reward")
+		return
+	}
+
+	c.JSON(http.StatusOK, reward)
+}
+
+// DeleteReward godoc
+// @Summary Delete reward
+// @Description Remove a reward from the catalog. Its redemptions, and the points they spent, are kept
+// @Tags rewards
+// @Security BearerAuth
+// @Param id path string true "Household ID"
+// @Param reward_id path string true "Reward ID"
+// @Success 204
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/households/{id}/rewards/{reward_id} [delete]
+func (h *Handlers) DeleteReward(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	err := h.services.Reward.DeleteReward(c.Request.Context(), userID.(string), c.Param("id"), c.Param("reward_id"))
+	if err != nil {
+		h.rewardError(c, err, "Failed to delete reward")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RedeemReward godoc
+// @Summary Redeem reward
+// @Description Request a reward for points. The request waits for a household admin to approve it; the points are held against the member's balance until then. Guests cannot redeem rewards
+// @Tags rewards
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Household ID"
+// @Param reward_id path string true "Reward ID"
+// @Param redemption body RedemptionRequest false "Redemption note"
+// @Success 201 {object} models.Redemption
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /v1/households/{id}/rewards/{reward_id}/redeem [post]
+func (h *Handlers) RedeemReward(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	var req RedemptionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	redemption, err := h.services.Reward.Redeem(c.Request.Context(), userID.(string), c.Param("id"), c.Param("reward_id"), req.Note)
+	if err != nil {
+		h.rewardError(c, err, "Failed to redeem reward")
+		return
+	}
+
+	c.JSON(http.StatusCreated, redemption)
+}
+
+// GetRedemptions godoc
+// @Summary Get redemptions
+// @Description Get a household's reward redemptions, newest first. Admins see everyone's; other members only their own
+// @Tags rewards
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Household ID"
+// @Param status query string false "pending, approved or rejected"
+// @Success 200 {array} models.Redemption
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /v1/households/{id}/redemptions [get]
+func (h *Handlers) GetRedemptions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	var status *models.RedemptionStatus
+	if value := c.Query("status"); value != "" {
+		s := models.RedemptionStatus(value)
+		switch s {
+		case models.RedemptionStatusPending, models.RedemptionStatusApproved, models.RedemptionStatusRejected:
+			status = &s
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status filter"})
+			return
+		}
+	}
+
+	redemptions, err := h.services.Reward.ListRedemptions(c.Request.Context(), userID.(string), c.Param("id"), status)
+	if err != nil {
+		h.rewardError(c, err, "Failed to get redemptions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"redemptions": redemptions,
+		"total":       len(redemptions),
+	})
+}
+
+// ApproveRedemption godoc
+// @Summary Approve redemption
+// @Description Approve a pending redemption. The member's points are debited and the reward's stock taken in one transaction. Only household admins may decide redemptions
+// @Tags rewards
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Household ID"
+// @Param redemption_id path string true "Redemption ID"
+// @Param decision body RedemptionRequest false "Decision note"
+// @Success 200 {object} models.Redemption
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /v1/households/{id}/redemptions/{redemption_id}/approve [post]
+func (h *Handlers) ApproveRedemption(c *gin.Context) {
+	h.decideRedemption(c, h.services.Reward.ApproveRedemption, "Failed to approve redemption")
+}
+
+// RejectRedemption godoc
+// @Summary Reject redemption
+// @Description Reject a pending redemption; no points are spent. Only household admins may decide redemptions
+// @Tags rewards
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Household ID"
+// @Param redemption_id path string true "Redemption ID"
+// @Param decision body RedemptionRequest false "Decision note"
+// @Success 200 {object} models.Redemption
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /v1/households/{id}/redemptions/{redemption_id}/reject [post]
+func (h *Handlers) RejectRedemption(c *gin.Context) {
+	h.decideRedemption(c, h.services.Reward.RejectRedemption, "Failed to reject redemption")
+}
+
+func (h *Handlers) decideRedemption(c *gin.Context, decide func(ctx context.Context, userID, householdID, redemptionID string, note *string) (*models.Redemption, error), message string) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	var req RedemptionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	redemption, err := decide(c.Request.Context(), userID.(string), c.Param("id"), c.Param("redemption_id"), req.Note)
+	if err != nil {
+		h.rewardError(c, err, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, redemption)
+}
+
+func (r RewardRequest) reward(householdID string) *models.Reward {
+	return &models.Reward{
+		HouseholdID:   householdID,
+		Name:          r.Name,
+		Description:   r.Description,
+		Cost:          r.Cost,
+		Stock:         r.Stock,
+		CooldownHours: r.CooldownHours,
+	}
+}
+
+// rewardError writes the response for an error of the reward service
+func (h *Handlers) rewardError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidReward):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotHouseholdMember):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this household"})
+	case errors.Is(err, services.ErrNotHouseholdAdmin):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only household admins can manage rewards"})
+	case errors.Is(err, services.ErrRedemptionNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Guests cannot redeem rewards"})
+	case errors.Is(err, services.ErrRewardCooldown),
+		errors.Is(err, store.ErrOutOfStock),
+		errors.Is(err, store.ErrInsufficientPoints):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, store.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "Redemption was decided already"})
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reward not found"})
+	default:
+		h.logger.Error(message, zap.Error(err), zap.String("household_id", c.Param("id")))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
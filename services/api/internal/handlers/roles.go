@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/yakirshlomo/house-helper/services/api/internal/services"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
+)
+
+// RequireCapability stops requests from members of the token's household
+// whose role lacks the capability, such as children reaching bills
+func (h *Handlers) RequireCapability(capability models.Capability) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("user_id")
+		if !exists {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+			return
+		}
+		householdID, exists := c.Get("household_id")
+		if !exists {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Household not found in token"})
+			return
+		}
+
+		err := h.services.Role.Require(c.Request.Context(), householdID.(string), userID.(string), capability)
+		switch {
+		case err == nil:
+			c.Next()
+		case errors.Is(err, services.ErrNotHouseholdMember):
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Not a member of this household"})
+		case errors.Is(err, services.ErrRoleNotAllowed):
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Your household role does not allow this"})
+		default:
+			h.logger.Error("Failed to check household role", zap.Error(err), zap.String("capability", string(capability)))
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check household role"})
+		}
+	}
+}
@@ -1,21 +1,37 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"go.uber.org/zap"
+
+	"github.com/yakirshlomo/house-helper/services/api/internal/services"
+	"github.com/yakirshlomo/house-helper/services/api/internal/store"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
 )
 
 type TaskRequest struct {
 	Title       string  `json:"title" binding:"required"`
 	Description *string `json:"description,omitempty"`
 	DueAt       *string `json:"due_at,omitempty"`
-	Priority    *string `json:"priority,omitempty"`
+	Priority    *string `json:"priority,omitempty" binding:"omitempty,oneof=low medium high urgent"`
 	Tags        []string `json:"tags,omitempty"`
+
+	// Category the task is assigned by; general when empty
+	Category *string `json:"category,omitempty" binding:"omitempty,max=100"`
+
+	// AssignedTo must be a household member; the creator when empty
+	AssignedTo *string `json:"assigned_to,omitempty" binding:"omitempty,uuid"`
+
+	// Points earned on completion, scaled by the modifiers when completed
+	// early or overdue
+	Points          *int     `json:"points,omitempty" binding:"omitempty,min=0,max=1000"`
+	EarlyModifier   *float64 `json:"early_modifier,omitempty" binding:"omitempty,min=0,max=10"`
+	OverdueModifier *float64 `json:"overdue_modifier,omitempty" binding:"omitempty,min=0,max=10"`
 }
 
 type TaskResponse struct {
@@ -31,6 +47,10 @@ type TaskResponse struct {
 	Tags        []string   `json:"tags,omitempty"`
 	HouseholdID string     `json:"household_id"`
 	AssignedTo  *string    `json:"assigned_to,omitempty"`
+
+	Points          int      `json:"points"`
+	EarlyModifier   *float64 `json:"early_modifier,omitempty"`
+	OverdueModifier *float64 `json:"overdue_modifier,omitempty"`
 }
 
 // GetTasks godoc
@@ -39,7 +59,7 @@ type TaskResponse struct {
 // @Tags tasks
 // @Security BearerAuth
 // @Produce json
-// @Param status query string false "Filter by status (pending, completed, cancelled)"
+// @Param status query string false "Filter by status (pending, pending_review, completed, cancelled)"
 // @Param limit query int false "Limit number of tasks" default(50)
 // @Param offset query int false "Offset for pagination" default(0)
 // @Success 200 {array} TaskResponse
@@ -156,25 +176,25 @@ func (h *Handlers) CreateTask(c *gin.Context) {
 		return
 	}
 
-	// Create new task
-	task := TaskResponse{
-		ID:          uuid.New().String(),
-		Title:       req.Title,
-		Description: req.Description,
-		Status:      "pending",
-		Priority:    req.Priority,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
-		Tags:        req.Tags,
-		HouseholdID: householdID.(string),
-		AssignedTo:  stringPtr(userID.(string)),
+	task, err := taskFromRequest(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if task.Category == "" {
+		task.Category = defaultTaskCategory
+	}
+	if task.Priority == "" {
+		task.Priority = models.PriorityMedium
+	}
+	task.Points = models.DefaultTaskPoints
+	if req.Points != nil {
+		task.Points = *req.Points
 	}
 
-	// Parse due date if provided
-	if req.DueAt != nil {
-		if dueTime, err := time.Parse(time.RFC3339, *req.DueAt); err == nil {
-			task.DueAt = &dueTime
-		}
+	if err := h.services.Task.CreateTask(c.Request.Context(), userID.(string), householdID.(string), task); err != nil {
+		h.taskError(c, err, "Failed to create task")
+		return
 	}
 
 	h.logger.Info("Task created",
@@ -183,7 +203,7 @@ func (h *Handlers) CreateTask(c *gin.Context) {
 		zap.String("household_id", householdID.(string)),
 	)
 
-	c.JSON(http.StatusCreated, task)
+	c.JSON(http.StatusCreated, newTaskResponse(task))
 }
 
 // GetTask godoc
@@ -230,9 +250,16 @@ func (h *Handlers) GetTask(c *gin.Context) {
 // @Failure 404 {object} map[string]string
 // @Router /v1/tasks/{id} [put]
 func (h *Handlers) UpdateTask(c *gin.Context) {
-	taskID := c.Param("id")
-	userID, _ := c.Get("user_id")
-	householdID, _ := c.Get("household_id")
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+	householdID, exists := c.Get("household_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Household not found in token"})
+		return
+	}
 
 	var req TaskRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -240,32 +267,79 @@ func (h *Handlers) UpdateTask(c *gin.Context) {
 		return
 	}
 
-	// Mock task update
-	task := TaskResponse{
-		ID:          taskID,
-		Title:       req.Title,
-		Description: req.Description,
-		Status:      "pending", // TODO: Handle status updates
-		Priority:    req.Priority,
-		CreatedAt:   time.Now().Add(-24 * time.Hour),
-		UpdatedAt:   time.Now(),
-		Tags:        req.Tags,
-		HouseholdID: householdID.(string),
-		AssignedTo:  stringPtr(userID.(string)),
+	changes, err := taskFromRequest(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	if req.DueAt != nil {
-		if dueTime, err := time.Parse(time.RFC3339, *req.DueAt); err == nil {
-			task.DueAt = &dueTime
-		}
+	task, err := h.services.Task.UpdateTask(c.Request.Context(), userID.(string), householdID.(string), c.Param("id"), changes, req.Points)
+	if err != nil {
+		h.taskError(c, err, "Failed to update task")
+		return
 	}
 
 	h.logger.Info("Task updated",
-		zap.String("task_id", taskID),
+		zap.String("task_id", task.ID),
 		zap.String("user_id", userID.(string)),
 	)
 
-	c.JSON(http.StatusOK, task)
+	c.JSON(http.StatusOK, newTaskResponse(task))
+}
+
+// defaultTaskCategory is the category of a task created without one
+const defaultTaskCategory = "general"
+
+// taskFromRequest returns the task fields of a create or update request
+func taskFromRequest(req TaskRequest) (*models.Task, error) {
+	task := &models.Task{
+		Title:           req.Title,
+		AssignedTo:      req.AssignedTo,
+		EarlyModifier:   req.EarlyModifier,
+		OverdueModifier: req.OverdueModifier,
+	}
+	if req.Description != nil {
+		task.Description = *req.Description
+	}
+	if req.Category != nil {
+		task.Category = *req.Category
+	}
+	if req.Priority != nil {
+		task.Priority = models.Priority(*req.Priority)
+	}
+	if req.DueAt != nil {
+		dueAt, err := time.Parse(time.RFC3339, *req.DueAt)
+		if err != nil {
+			return nil, errors.New("invalid due_at format, use RFC3339")
+		}
+		task.DueDate = &dueAt
+	}
+	return task, nil
+}
+
+// newTaskResponse returns the response for a stored task
+func newTaskResponse(task *models.Task) TaskResponse {
+	response := TaskResponse{
+		ID:              task.ID,
+		Title:           task.Title,
+		Status:          string(task.Status),
+		CreatedAt:       task.CreatedAt,
+		UpdatedAt:       task.UpdatedAt,
+		CompletedAt:     task.CompletedAt,
+		DueAt:           task.DueDate,
+		HouseholdID:     task.HouseholdID,
+		AssignedTo:      task.AssignedTo,
+		Points:          task.Points,
+		EarlyModifier:   task.EarlyModifier,
+		OverdueModifier: task.OverdueModifier,
+	}
+	if task.Description != "" {
+		response.Description = stringPtr(task.Description)
+	}
+	if task.Priority != "" {
+		response.Priority = stringPtr(string(task.Priority))
+	}
+	return response
 }
 
 // DeleteTask godoc
@@ -289,6 +363,148 @@ func (h *Handlers) DeleteTask(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+type CompleteTaskRequest struct {
+	// Photos of the finished task, added to its attachments
+	AttachmentURLs []string `json:"attachment_urls,omitempty" binding:"omitempty,max=10,dive,url"`
+}
+
+type ReviewTaskRequest struct {
+	Note *string `json:"note,omitempty" binding:"omitempty,max=500"`
+}
+
+// CompleteTask godoc
+// @Summary Complete task
+// @Description Mark a task completed, optionally with photos of the finished task. Tasks completed by children go to pending_review until a household admin approves them. A completed or cancelled task cannot be completed again
+// @Tags tasks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param completion body CompleteTaskRequest false "Completion photos"
+// @Success 200 {object} models.Task
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /v1/tasks/{id}/complete [post]
+func (h *Handlers) CompleteTask(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+	householdID, exists := c.Get("household_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Household not found in token"})
+		return
+	}
+
+	var req CompleteTaskRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	task, err := h.services.Task.CompleteTask(c.Request.Context(), userID.(string), householdID.(string), c.Param("id"), req.AttachmentURLs)
+	if err != nil {
+		h.taskError(c, err, "Failed to complete task")
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
+// ApproveTask godoc
+// @Summary Approve task
+// @Description Approve a task pending review, completing it and crediting the child who did it with its points. Only household admins review tasks
+// @Tags tasks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param review body ReviewTaskRequest false "Review note"
+// @Success 200 {object} models.Task
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /v1/tasks/{id}/approve [post]
+func (h *Handlers) ApproveTask(c *gin.Context) {
+	h.reviewTask(c, true, "Failed to approve task")
+}
+
+// RejectTask godoc
+// @Summary Reject task
+// @Description Reject a task pending review back to pending; its assignee is reminded again. Only household admins review tasks
+// @Tags tasks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param review body ReviewTaskRequest false "Review note"
+// @Success 200 {object} models.Task
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /v1/tasks/{id}/reject [post]
+func (h *Handlers) RejectTask(c *gin.Context) {
+	h.reviewTask(c, false, "Failed to reject task")
+}
+
+func (h *Handlers) reviewTask(c *gin.Context, approved bool, message string) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+	householdID, exists := c.Get("household_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Household not found in token"})
+		return
+	}
+
+	var req ReviewTaskRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	task, err := h.services.Task.ReviewTask(c.Request.Context(), userID.(string), householdID.(string), c.Param("id"), approved, req.Note)
+	if err != nil {
+		h.taskError(c, err, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
+// taskError writes the response for an error of the task service
+func (h *Handlers) taskError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrNotHouseholdMember):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this household"})
+	case errors.Is(err, services.ErrNotHouseholdAdmin):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only household admins can review tasks"})
+	case errors.Is(err, services.ErrTaskNotPendingReview):
+		c.JSON(http.StatusConflict, gin.H{"error": "Task is not pending review"})
+	case errors.Is(err, services.ErrTaskClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": "Task is already completed or cancelled"})
+	case errors.Is(err, services.ErrTaskAssigneeNotMember):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Task must be assigned to a member of the household"})
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+	default:
+		h.logger.Error(message, zap.Error(err), zap.String("task_id", c.Param("id")))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
 // Helper functions
 func stringPtr(s string) *string {
 	return &s
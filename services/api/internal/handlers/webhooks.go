@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/yakirshlomo/house-helper/services/api/internal/services"
+	"github.com/yakirshlomo/house-helper/services/api/internal/store"
+)
+
+type WebhookRequest struct {
+	URL         string   `json:"url" binding:"required,url,max=2048"`
+	Description *string  `json:"description,omitempty" binding:"omitempty,max=255"`
+	EventTypes  []string `json:"event_types" binding:"required,min=1"`
+}
+
+// CreateWebhook godoc
+// @Summary Register webhook
+// @Description Register an endpoint to receive the household's events. The URL must be https and name a public host. Deliveries are signed with the returned secret, which is only shown once. Only household admins may manage webhooks
+// @Tags webhooks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Household ID"
+// @Param webhook body WebhookRequest true "Webhook data"
+// @Success 201 {object} models.WebhookEndpoint
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /v1/households/{id}/webhooks [post]
+func (h *Handlers) CreateWebhook(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	endpoint, err := h.services.Webhook.CreateWebhook(c.Request.Context(), userID.(string), c.Param("id"), req.URL, req.Description, req.EventTypes)
+	if err != nil {
+		h.webhookError(c, err, "Failed to create webhook")
+		return
+	}
+
+	c.JSON(http.StatusCreated, endpoint)
+}
+
+// GetWebhooks godoc
+// @Summary Get webhooks
+// @Description Get the webhook endpoints of a household
+// @Tags webhooks
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Household ID"
+// @Success 200 {array} models.WebhookEndpoint
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /v1/households/{id}/webhooks [get]
+func (h *Handlers) GetWebhooks(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	endpoints, err := h.services.Webhook.ListWebhooks(c.Request.Context(), userID.(string), c.Param("id"))
+	if err != nil {
+		h.webhookError(c, err, "Failed to get webhooks")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"webhooks": endpoints,
+		"total":    len(endpoints),
+	})
+}
+
+// DeleteWebhook godoc
+// @Summary Delete webhook
+// @Description Delete a webhook endpoint. Only household admins may manage webhooks
+// @Tags webhooks
+// @Security BearerAuth
+// @Param id path string true "Household ID"
+// @Param webhook_id path string true "Webhook ID"
+// @Success 204
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/households/{id}/webhooks/{webhook_id} [delete]
+func (h *Handlers) DeleteWebhook(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	err := h.services.Webhook.DeleteWebhook(c.Request.Context(), userID.(string), c.Param("id"), c.Param("webhook_id"))
+	if err != nil {
+		h.webhookError(c, err, "Failed to delete webhook")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// EnableWebhook godoc
+// @Summary Enable webhook
+// @Description Re-enable a webhook endpoint disabled after repeated failed deliveries. Only household admins may manage webhooks
+// @Tags webhooks
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Household ID"
+// @Param webhook_id path string true "Webhook ID"
+// @Success 200 {object} models.WebhookEndpoint
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/households/{id}/webhooks/{webhook_id}/enable [post]
+func (h *Handlers) EnableWebhook(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	endpoint, err := h.services.Webhook.EnableWebhook(c.Request.Context(), userID.(string), c.Param("id"), c.Param("webhook_id"))
+	if err != nil {
+		h.webhookError(c, err, "Failed to enable webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, endpoint)
+}
+
+// GetWebhookDeliveries godoc
+// @Summary Get webhook deliveries
+// @Description Get the latest delivery attempts of a webhook endpoint with their response codes
+// @Tags webhooks
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Household ID"
+// @Param webhook_id path string true "Webhook ID"
+// @Param limit query int false "Limit number of deliveries" default(50)
+// @Success 200 {array} models.WebhookDelivery
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/households/{id}/webhooks/{webhook_id}/deliveries [get]
+func (h *Handlers) GetWebhookDeliveries(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in token"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+		return
+	}
+
+	deliveries, err := h.services.Webhook.ListDeliveries(c.Request.Context(), userID.(string), c.Param("id"), c.Param("webhook_id"), limit)
+	if err != nil {
+		h.webhookError(c, err, "Failed to get webhook deliveries")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": deliveries,
+		"total":      len(deliveries),
+	})
+}
+
+// webhookError writes the response for an error of the webhook service
+func (h *Handlers) webhookError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidWebhook):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotHouseholdMember):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of this household"})
+	case errors.Is(err, services.ErrNotHouseholdAdmin):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only household admins can manage webhooks"})
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+	default:
+		h.logger.Error(message, zap.Error(err), zap.String("household_id", c.Param("id")))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,353 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yakirshlomo/house-helper/services/api/internal/store"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/kafka"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
+)
+
+var (
+	// ErrInvalidAllowance is returned for allowance settings that cannot be
+	// paid out
+	ErrInvalidAllowance = errors.New("invalid allowance")
+
+	// ErrAllowanceNotChild is returned for the allowance of a member who is
+	// not a child
+	ErrAllowanceNotChild = errors.New("allowances are for children")
+
+	// ErrInvalidWeek is returned for a week that is not a date
+	ErrInvalidWeek = errors.New("invalid week")
+
+	// ErrWeekNotOver is returned when paying out a week that has not ended
+	ErrWeekNotOver = errors.New("week is not over")
+)
+
+// ActivityAllowancePaid is published as a household.activity event when a
+// week's allowance is paid
+const ActivityAllowancePaid = "allowance_paid"
+
+// AllowanceService manages children's weekly allowances: their settings, the
+// statement of what a week earned and the ledger of payouts
+type AllowanceService struct {
+	allowanceStore store.AllowanceStore
+	householdStore store.HouseholdStore
+	kafkaProducer  *kafka.Producer
+}
+
+// NewAllowanceService creates a new allowance service
+func NewAllowanceService(allowanceStore store.AllowanceStore, householdStore store.HouseholdStore, kafkaProducer *kafka.Producer) *AllowanceService {
+	return &AllowanceService{
+		allowanceStore: allowanceStore,
+		householdStore: householdStore,
+		kafkaProducer:  kafkaProducer,
+	}
+}
+
+// GetSettings returns a child's allowance settings; admins and the child may
+// read them
+func (s *AllowanceService) GetSettings(ctx context.Context, userID, householdID, childID string) (*models.AllowanceSettings, error) {
+	household, err := s.child(ctx, householdID, userID, childID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := s.allowanceStore.GetSettings(ctx, householdID, childID)
+	if err != nil {
+		return nil, err
+	}
+	settings.Currency = householdCurrency(household)
+	return settings, nil
+}
+
+// UpdateSettings replaces a child's allowance settings
+func (s *AllowanceService) UpdateSettings(ctx context.Context, userID string, settings *models.AllowanceSettings) error {
+	household, err := s.child(ctx, settings.HouseholdID, userID, settings.UserID, true)
+	if err != nil {
+		return err
+	}
+	if err := validateAllowance(settings); err != nil {
+		return err
+	}
+
+	settings.UpdatedBy = userID
+	if err := s.allowanceStore.UpsertSettings(ctx, settings); err != nil {
+		return err
+	}
+	settings.Currency = householdCurrency(household)
+	return nil
+}
+
+// GetStatement returns a child's allowance for the week a date, in the
+// household's timezone, falls in; the current week when week is empty
+func (s *AllowanceService) GetStatement(ctx context.Context, userID, householdID, childID, week string) (*models.AllowanceStatement, error) {
+	household, err := s.child(ctx, householdID, userID, childID, false)
+	if err != nil {
+		return nil, err
+	}
+	return s.statement(ctx, household, childID, week)
+}
+
+// RecordPayout pays out a child's allowance for a week that has ended, the
+// previous one when week is empty, appending the week's statement to the
+// allowance ledger. A week is paid once.
+func (s *AllowanceService) RecordPayout(ctx context.Context, userID, householdID, childID, week string, note *string) (*models.AllowancePayout, error) {
+	household, err := s.child(ctx, householdID, userID, childID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if week == "" {
+		week = time.Now().In(householdTimezone(household)).AddDate(0, 0, -7).Format("2006-01-02")
+	}
+	statement, err := s.statement(ctx, household, childID, week)
+	if err != nil {
+		return nil, err
+	}
+	if statement.Payout != nil {
+		return nil, store.ErrConflict
+	}
+	if time.Now().Before(statement.WeekEnd) {
+		return nil, fmt.Errorf("%w: it ends at %s", ErrWeekNotOver, statement.WeekEnd.Format(time.RFC3339))
+	}
+
+	completed := 0
+	for _, bonus := range statement.Bonuses {
+		completed += bonus.Tasks
+	}
+	payout := &models.AllowancePayout{
+		ID:              uuid.New().String(),
+		HouseholdID:     householdID,
+		UserID:          childID,
+		WeekStart:       statement.WeekStart,
+		BaseAmount:      statement.BaseAmount,
+		BonusAmount:     statement.BonusAmount,
+		DeductionAmount: statement.DeductionAmount,
+		Amount:          statement.Amount,
+		Currency:        statement.Currency,
+		CompletedTasks:  completed,
+		MissedChores:    len(statement.MissedChores),
+		PaidBy:          userID,
+		Note:            note,
+	}
+	if err := s.allowanceStore.CreatePayout(ctx, payout); err != nil {
+		return nil, err
+	}
+
+	s.publishPayout(ctx, userID, payout)
+	return payout, nil
+}
+
+// ListPayouts returns a household's allowance ledger, latest week first,
+// between the weeks from and to fall in when given. Admins see every child's
+// payouts; other members only their own.
+func (s *AllowanceService) ListPayouts(ctx context.Context, userID, householdID string, childID *string, from, to string) ([]*models.AllowancePayout, error) {
+	member, err := s.member(ctx, householdID, userID)
+	if err != nil {
+		return nil, err
+	}
+	household, err := s.householdStore.GetByID(ctx, householdID)
+	if err != nil {
+		return nil, err
+	}
+	location := householdTimezone(household)
+
+	filter := store.AllowancePayoutFilter{UserID: childID}
+	if member.Role != models.HouseholdRoleAdmin {
+		filter.UserID = &userID
+	}
+	if from != "" {
+		start, err := weekStart(from, location)
+		if err != nil {
+			return nil, err
+		}
+		filter.From = &start
+	}
+	if to != "" {
+		end, err := weekStart(to, location)
+		if err != nil {
+			return nil, err
+		}
+		end = end.AddDate(0, 0, 7)
+		filter.To = &end
+	}
+	return s.allowanceStore.GetPayouts(ctx, householdID, filter)
+}
+
+// statement works out a child's allowance for a week
+func (s *AllowanceService) statement(ctx context.Context, household *models.Household, childID, week string) (*models.AllowanceStatement, error) {
+	settings, err := s.allowanceStore.GetSettings(ctx, household.ID, childID)
+	if err != nil {
+		return nil, err
+	}
+
+	start, err := weekStart(week, householdTimezone(household))
+	if err != nil {
+		return nil, err
+	}
+	end := start.AddDate(0, 0, 7)
+
+	bonuses, err := s.allowanceStore.GetCompletedByCategory(ctx, household.ID, childID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	missed, err := s.allowanceStore.GetMissedChores(ctx, household.ID, childID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	statement := &models.AllowanceStatement{
+		HouseholdID:  household.ID,
+		UserID:       childID,
+		WeekStart:    start,
+		WeekEnd:      end,
+		Currency:     householdCurrency(household),
+		BaseAmount:   settings.BaseAmount,
+		Bonuses:      []*models.AllowanceBonus{},
+		MissedChores: missed,
+	}
+	for _, bonus := range bonuses {
+		bonus.Rate = settings.CategoryBonuses[bonus.Category]
+		bonus.Amount = roundCents(bonus.Rate * float64(bonus.Tasks))
+		statement.BonusAmount += bonus.Amount
+		statement.Bonuses = append(statement.Bonuses, bonus)
+	}
+	if statement.MissedChores == nil {
+		statement.MissedChores = []*models.MissedChore{}
+	}
+	statement.BonusAmount = roundCents(statement.BonusAmount)
+	statement.DeductionAmount = roundCents(settings.MissedChoreDeduction * float64(len(missed)))
+	statement.Amount = math.Max(0, roundCents(statement.BaseAmount+statement.BonusAmount-statement.DeductionAmount))
+
+	payout, err := s.allowanceStore.GetPayout(ctx, household.ID, childID, start)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+	statement.Payout = payout
+	return statement, nil
+}
+
+// publishPayout publishes a household.activity event for a recorded payout.
+// The payout is committed already, so the event is best effort.
+func (s *AllowanceService) publishPayout(ctx context.Context, actorID string, payout *models.AllowancePayout) {
+	if s.kafkaProducer == nil {
+		return
+	}
+
+	event := kafka.DomainEvent{
+		ID:          uuid.New().String(),
+		Type:        kafka.EventTypeHouseholdActivity,
+		HouseholdID: payout.HouseholdID,
+		UserID:      actorID,
+		Data: map[string]interface{}{
+			"householdId": payout.HouseholdID,
+			"actorId":     actorID,
+			"activity":    ActivityAllowancePaid,
+			"payoutId":    payout.ID,
+			"memberId":    payout.UserID,
+			"weekStart":   payout.WeekStart.Format("2006-01-02"),
+			"amount":      payout.Amount,
+			"currency":    payout.Currency,
+		},
+	}
+	_ = kafka.NewEventPublisher(s.kafkaProducer).PublishDomainEvent(ctx, kafka.TopicHouseholds, event)
+}
+
+// child checks the caller may see, or with manage change, a child's
+// allowance and returns the household. Admins may do both; a child may see
+// their own.
+func (s *AllowanceService) child(ctx context.Context, householdID, userID, childID string, manage bool) (*models.Household, error) {
+	member, err := s.member(ctx, householdID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role != models.HouseholdRoleAdmin && (manage || userID != childID) {
+		return nil, ErrNotHouseholdAdmin
+	}
+
+	child, err := s.householdStore.GetMember(ctx, householdID, childID)
+	if err != nil {
+		return nil, err
+	}
+	if child.Role != models.HouseholdRoleChild {
+		return nil, ErrAllowanceNotChild
+	}
+	return s.householdStore.GetByID(ctx, householdID)
+}
+
+// member returns the caller's membership of a household
+func (s *AllowanceService) member(ctx context.Context, householdID, userID string) (*models.HouseholdMember, error) {
+	member, err := s.householdStore.GetMember(ctx, householdID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrNotHouseholdMember
+	}
+	return member, err
+}
+
+// weekStart returns the Monday starting the week a date falls in, in the
+// household's timezone; the current week when date is empty
+func weekStart(date string, location *time.Location) (time.Time, error) {
+	day := time.Now().In(location)
+	if date != "" {
+		var err error
+		day, err = time.ParseInLocation("2006-01-02", date, location)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: %q, want YYYY-MM-DD", ErrInvalidWeek, date)
+		}
+	}
+	return periodStart(PeriodWeek, day)
+}
+
+// householdTimezone returns the household's location, UTC when it is not
+// known
+func householdTimezone(household *models.Household) *time.Location {
+	location, err := time.LoadLocation(household.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// householdCurrency returns the currency the household's allowances are in
+func householdCurrency(household *models.Household) string {
+	if household.Currency == "" {
+		return "USD"
+	}
+	return household.Currency
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func validateAllowance(settings *models.AllowanceSettings) error {
+	if settings.BaseAmount < 0 {
+		return fmt.Errorf("%w: base amount cannot be negative", ErrInvalidAllowance)
+	}
+	if settings.MissedChoreDeduction < 0 {
+		return fmt.Errorf("%w: missed chore deduction cannot be negative", ErrInvalidAllowance)
+	}
+
+	bonuses := make(map[string]float64, len(settings.CategoryBonuses))
+	for category, bonus := range settings.CategoryBonuses {
+		category = strings.TrimSpace(category)
+		if category == "" {
+			return fmt.Errorf("%w: bonus category is required", ErrInvalidAllowance)
+		}
+		if bonus < 0 {
+			return fmt.Errorf("%w: bonus for %s cannot be negative", ErrInvalidAllowance, category)
+		}
+		bonuses[category] = roundCents(bonus)
+	}
+	settings.CategoryBonuses = bonuses
+	settings.BaseAmount = roundCents(settings.BaseAmount)
+	settings.MissedChoreDeduction = roundCents(settings.MissedChoreDeduction)
+	return nil
+}
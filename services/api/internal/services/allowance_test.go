@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yakirshlomo/house-helper/services/api/internal/store"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
+)
+
+// fakeCompletion is a task a child completed
+type fakeCompletion struct {
+	category    string
+	completedAt time.Time
+}
+
+// fakeAllowanceStore works out statements from completions and missed
+// chores kept in memory, like the Postgres store does from the task tables
+type fakeAllowanceStore struct {
+	store.AllowanceStore
+	settings    map[string]*models.AllowanceSettings
+	completions []fakeCompletion
+	missed      []*models.MissedChore
+	payouts     []*models.AllowancePayout
+
+	// hidePayouts makes GetPayout miss recorded payouts, as when another
+	// request pays the week between the statement and the insert
+	hidePayouts bool
+}
+
+func newFakeAllowanceStore() *fakeAllowanceStore {
+	return &fakeAllowanceStore{settings: map[string]*models.AllowanceSettings{}}
+}
+
+func (s *fakeAllowanceStore) GetSettings(ctx context.Context, householdID, userID string) (*models.AllowanceSettings, error) {
+	settings, ok := s.settings[householdID+"/"+userID]
+	if !ok {
+		return &models.AllowanceSettings{HouseholdID: householdID, UserID: userID, CategoryBonuses: map[string]float64{}}, nil
+	}
+	stored := *settings
+	return &stored, nil
+}
+
+func (s *fakeAllowanceStore) GetCompletedByCategory(ctx context.Context, householdID, userID string, from, to time.Time) ([]*models.AllowanceBonus, error) {
+	var bonuses []*models.AllowanceBonus
+	byCategory := map[string]*models.AllowanceBonus{}
+	for _, completion := range s.completions {
+		if completion.completedAt.Before(from) || !completion.completedAt.Before(to) {
+			continue
+		}
+		bonus, ok := byCategory[completion.category]
+		if !ok {
+			bonus = &models.AllowanceBonus{Category: completion.category}
+			byCategory[completion.category] = bonus
+			bonuses = append(bonuses, bonus)
+		}
+		bonus.Tasks++
+	}
+	return bonuses, nil
+}
+
+func (s *fakeAllowanceStore) GetMissedChores(ctx context.Context, householdID, userID string, from, to time.Time) ([]*models.MissedChore, error) {
+	var missed []*models.MissedChore
+	for _, chore := range s.missed {
+		if !chore.DetectedAt.Before(from) && chore.DetectedAt.Before(to) {
+			missed = append(missed, chore)
+		}
+	}
+	return missed, nil
+}
+
+func (s *fakeAllowanceStore) CreatePayout(ctx context.Context, payout *models.AllowancePayout) error {
+	for _, paid := range s.payouts {
+		if paid.HouseholdID == payout.HouseholdID && paid.UserID == payout.UserID && paid.WeekStart.Equal(payout.WeekStart) {
+			return store.ErrConflict
+		}
+	}
+	stored := *payout
+	s.payouts = append(s.payouts, &stored)
+	return nil
+}
+
+func (s *fakeAllowanceStore) GetPayout(ctx context.Context, householdID, userID string, weekStart time.Time) (*models.AllowancePayout, error) {
+	if s.hidePayouts {
+		return nil, store.ErrNotFound
+	}
+	for _, paid := range s.payouts {
+		if paid.HouseholdID == householdID && paid.UserID == userID && paid.WeekStart.Equal(weekStart) {
+			return paid, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+// newAllowanceTest returns an allowance service for a household in the
+// timezone with an admin and a child
+func newAllowanceTest(timezone string) (*AllowanceService, *fakeAllowanceStore) {
+	households := newFakeHouseholdStore()
+	households.households["household-1"] = &models.Household{ID: "household-1", Timezone: timezone, Currency: "EUR"}
+	households.addMember("household-1", "admin", models.HouseholdRoleAdmin)
+	households.addMember("household-1", "child", models.HouseholdRoleChild)
+
+	allowances := newFakeAllowanceStore()
+	return NewAllowanceService(allowances, households, nil), allowances
+}
+
+func TestAllowanceStatement(t *testing.T) {
+	monday := time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)
+	missedChore := func(day int) *models.MissedChore {
+		return &models.MissedChore{Category: "kitchen", DetectedAt: monday.AddDate(0, 0, day).Add(18 * time.Hour)}
+	}
+
+	tests := []struct {
+		name          string
+		settings      models.AllowanceSettings
+		completions   []string
+		missed        []*models.MissedChore
+		wantBonus     float64
+		wantDeduction float64
+		wantAmount    float64
+	}{
+		{
+			name:       "base only",
+			settings:   models.AllowanceSettings{BaseAmount: 5},
+			wantAmount: 5,
+		},
+		{
+			name:        "bonus per category",
+			settings:    models.AllowanceSettings{BaseAmount: 5, CategoryBonuses: map[string]float64{"kitchen": 0.5, "garden": 1.25}},
+			completions: []string{"kitchen", "garden", "kitchen", "kitchen", "garden", "laundry"},
+			wantBonus:   4,
+			wantAmount:  9,
+		},
+		{
+			name:          "deduction per missed chore",
+			settings:      models.AllowanceSettings{BaseAmount: 5, CategoryBonuses: map[string]float64{"kitchen": 0.5}, MissedChoreDeduction: 0.75},
+			completions:   []string{"kitchen"},
+			missed:        []*models.MissedChore{missedChore(1), missedChore(3)},
+			wantBonus:     0.5,
+			wantDeduction: 1.5,
+			wantAmount:    4,
+		},
+		{
+			name:          "floored at zero",
+			settings:      models.AllowanceSettings{BaseAmount: 1, MissedChoreDeduction: 2},
+			missed:        []*models.MissedChore{missedChore(0), missedChore(2), missedChore(4)},
+			wantDeduction: 6,
+			wantAmount:    0,
+		},
+		{
+			name:        "rounded to cents",
+			settings:    models.AllowanceSettings{CategoryBonuses: map[string]float64{"kitchen": 0.1}},
+			completions: []string{"kitchen", "kitchen", "kitchen"},
+			wantBonus:   0.3,
+			wantAmount:  0.3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, allowances := newAllowanceTest("UTC")
+			settings := tt.settings
+			allowances.settings["household-1/child"] = &settings
+			for i, category := range tt.completions {
+				allowances.completions = append(allowances.completions, fakeCompletion{category: category, completedAt: monday.Add(time.Duration(i) * time.Hour)})
+			}
+			allowances.missed = tt.missed
+
+			statement, err := s.GetStatement(context.Background(), "admin", "household-1", "child", "2024-03-13")
+			if err != nil {
+				t.Fatalf("GetStatement() error = %v", err)
+			}
+			if statement.BonusAmount != tt.wantBonus || statement.DeductionAmount != tt.wantDeduction || statement.Amount != tt.wantAmount {
+				t.Errorf("GetStatement() bonus = %v, deduction = %v, amount = %v, want %v, %v, %v",
+					statement.BonusAmount, statement.DeductionAmount, statement.Amount, tt.wantBonus, tt.wantDeduction, tt.wantAmount)
+			}
+			if statement.Currency != "EUR" {
+				t.Errorf("GetStatement() currency = %q, want the household's", statement.Currency)
+			}
+		})
+	}
+}
+
+func TestAllowanceWeekInHouseholdTimezone(t *testing.T) {
+	s, allowances := newAllowanceTest("Asia/Tokyo")
+	allowances.settings["household-1/child"] = &models.AllowanceSettings{CategoryBonuses: map[string]float64{"kitchen": 1}}
+
+	// The week of Wednesday 13 March runs from Monday 11 March 00:00 in
+	// Tokyo, 15:00 UTC on the 10th, for seven days
+	allowances.completions = []fakeCompletion{
+		{category: "kitchen", completedAt: time.Date(2024, 3, 10, 14, 30, 0, 0, time.UTC)}, // Sunday 23:30 in Tokyo
+		{category: "kitchen", completedAt: time.Date(2024, 3, 10, 15, 30, 0, 0, time.UTC)}, // Monday 00:30
+		{category: "kitchen", completedAt: time.Date(2024, 3, 17, 14, 59, 0, 0, time.UTC)}, // Sunday 23:59
+		{category: "kitchen", completedAt: time.Date(2024, 3, 17, 15, 0, 0, 0, time.UTC)},  // next Monday 00:00
+	}
+
+	statement, err := s.GetStatement(context.Background(), "child", "household-1", "child", "2024-03-13")
+	if err != nil {
+		t.Fatalf("GetStatement() error = %v", err)
+	}
+
+	wantStart := time.Date(2024, 3, 10, 15, 0, 0, 0, time.UTC)
+	if !statement.WeekStart.Equal(wantStart) || !statement.WeekEnd.Equal(wantStart.AddDate(0, 0, 7)) {
+		t.Errorf("GetStatement() week = %s to %s, want %s to %s", statement.WeekStart, statement.WeekEnd, wantStart, wantStart.AddDate(0, 0, 7))
+	}
+	if statement.BonusAmount != 2 {
+		t.Errorf("GetStatement() bonus = %v, want 2 for the tasks completed within the week", statement.BonusAmount)
+	}
+
+	sunday, err := s.GetStatement(context.Background(), "child", "household-1", "child", "2024-03-17")
+	if err != nil {
+		t.Fatalf("GetStatement() error = %v", err)
+	}
+	if !sunday.WeekStart.Equal(wantStart) {
+		t.Errorf("GetStatement() of a Sunday week start = %s, want %s", sunday.WeekStart, wantStart)
+	}
+}
+
+func TestAllowancePaidOnce(t *testing.T) {
+	ctx := context.Background()
+	s, allowances := newAllowanceTest("UTC")
+	allowances.settings["household-1/child"] = &models.AllowanceSettings{BaseAmount: 5}
+
+	payout, err := s.RecordPayout(ctx, "admin", "household-1", "child", "2024-03-13", nil)
+	if err != nil {
+		t.Fatalf("RecordPayout() error = %v", err)
+	}
+	if payout.Amount != 5 || !payout.WeekStart.Equal(time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("RecordPayout() amount = %v, week = %s, want 5 for the week of 11 March", payout.Amount, payout.WeekStart)
+	}
+
+	if _, err := s.RecordPayout(ctx, "admin", "household-1", "child", "2024-03-15", nil); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("RecordPayout() of a paid week error = %v, want store.ErrConflict", err)
+	}
+
+	allowances.hidePayouts = true
+	if _, err := s.RecordPayout(ctx, "admin", "household-1", "child", "2024-03-11", nil); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("RecordPayout() of a week paid meanwhile error = %v, want store.ErrConflict", err)
+	}
+	if len(allowances.payouts) != 1 {
+		t.Errorf("ledger has %d payouts, want 1", len(allowances.payouts))
+	}
+
+	if _, err := s.RecordPayout(ctx, "admin", "household-1", "child", time.Now().Format("2006-01-02"), nil); !errors.Is(err, ErrWeekNotOver) {
+		t.Errorf("RecordPayout() of the current week error = %v, want ErrWeekNotOver", err)
+	}
+	if _, err := s.RecordPayout(ctx, "child", "household-1", "child", "2024-03-04", nil); !errors.Is(err, ErrNotHouseholdAdmin) {
+		t.Errorf("RecordPayout() by the child error = %v, want ErrNotHouseholdAdmin", err)
+	}
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yakirshlomo/house-helper/services/api/internal/store"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
+)
+
+var (
+	// ErrNotHouseholdAdmin is returned when an action needs a household admin
+	ErrNotHouseholdAdmin = errors.New("not an admin of the household")
+
+	// ErrInvalidAssignmentPreferences is returned for preferences the
+	// assignment engine cannot use
+	ErrInvalidAssignmentPreferences = errors.New("invalid assignment preferences")
+)
+
+// AssignmentService manages what the chore assignment engine knows of
+// household members
+type AssignmentService struct {
+	householdStore store.HouseholdStore
+}
+
+// NewAssignmentService creates a new assignment service
+func NewAssignmentService(householdStore store.HouseholdStore) *AssignmentService {
+	return &AssignmentService{householdStore: householdStore}
+}
+
+// GetPreferences returns a member's assignment preferences; any member of the
+// household may read them
+func (s *AssignmentService) GetPreferences(ctx context.Context, userID, householdID, memberID string) (*models.AssignmentPreferences, error) {
+	if _, err := s.member(ctx, householdID, userID); err != nil {
+		return nil, err
+	}
+	return s.householdStore.GetAssignmentPreferences(ctx, householdID, memberID)
+}
+
+// UpdatePreferences replaces a member's assignment preferences. Admins may
+// edit anyone's; members may edit only their own preferred categories, and
+// keep the weight, date of birth and excluded categories an admin set.
+func (s *AssignmentService) UpdatePreferences(ctx context.Context, userID string, prefs *models.AssignmentPreferences) error {
+	caller, err := s.member(ctx, prefs.HouseholdID, userID)
+	if err != nil {
+		return err
+	}
+	if caller.Role != models.HouseholdRoleAdmin {
+		if prefs.UserID != userID {
+			return ErrNotHouseholdAdmin
+		}
+		if err := s.keepAdminPreferences(ctx, prefs); err != nil {
+			return err
+		}
+	}
+
+	if prefs.DateOfBirth != nil && prefs.DateOfBirth.After(time.Now()) {
+		return fmt.Errorf("%w: date_of_birth is in the future", ErrInvalidAssignmentPreferences)
+	}
+	prefs.ExcludedCategories = normalizeCategories(prefs.ExcludedCategories)
+	prefs.PreferredCategories = normalizeCategories(prefs.PreferredCategories)
+	for _, category := range prefs.PreferredCategories {
+		for _, excluded := range prefs.ExcludedCategories {
+			if category == excluded {
+				return fmt.Errorf("%w: %q is both preferred and excluded", ErrInvalidAssignmentPreferences, category)
+			}
+		}
+	}
+
+	return s.householdStore.UpdateAssignmentPreferences(ctx, prefs)
+}
+
+// keepAdminPreferences fills the preferences only an admin may set from the
+// member's current ones. Leaving them out keeps them; changing them is
+// ErrNotHouseholdAdmin.
+func (s *AssignmentService) keepAdminPreferences(ctx context.Context, prefs *models.AssignmentPreferences) error {
+	current, err := s.householdStore.GetAssignmentPreferences(ctx, prefs.HouseholdID, prefs.UserID)
+	if err != nil {
+		return err
+	}
+
+	if prefs.Weight != nil && (current.Weight == nil || *prefs.Weight != *current.Weight) {
+		return ErrNotHouseholdAdmin
+	}
+	if prefs.DateOfBirth != nil && (current.DateOfBirth == nil || !prefs.DateOfBirth.Equal(*current.DateOfBirth)) {
+		return ErrNotHouseholdAdmin
+	}
+	if prefs.ExcludedCategories != nil && !sameCategories(normalizeCategories(prefs.ExcludedCategories), normalizeCategories(current.ExcludedCategories)) {
+		return ErrNotHouseholdAdmin
+	}
+
+	prefs.Weight = current.Weight
+	prefs.DateOfBirth = current.DateOfBirth
+	prefs.ExcludedCategories = current.ExcludedCategories
+	return nil
+}
+
+// member returns the caller's membership of a household
+func (s *AssignmentService) member(ctx context.Context, householdID, userID string) (*models.HouseholdMember, error) {
+	member, err := s.householdStore.GetMember(ctx, householdID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrNotHouseholdMember
+	}
+	return member, err
+}
+
+// normalizeCategories trims and de-duplicates categories, never returning nil
+func normalizeCategories(categories []string) []string {
+	seen := make(map[string]bool, len(categories))
+	normalized := make([]string, 0, len(categories))
+	for _, category := range categories {
+		category = strings.TrimSpace(category)
+		if category == "" || seen[category] {
+			continue
+		}
+		seen[category] = true
+		normalized = append(normalized, category)
+	}
+	return normalized
+}
+
+// sameCategories reports whether two normalized category lists hold the same
+// categories, in any order
+func sameCategories(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[string]bool, len(a))
+	for _, category := range a {
+		seen[category] = true
+	}
+	for _, category := range b {
+		if !seen[category] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
+)
+
+func TestMembersEditOnlyPreferredCategories(t *testing.T) {
+	ctx := context.Background()
+	households := newFakeHouseholdStore()
+	households.addMember("household-1", "admin", models.HouseholdRoleAdmin)
+	households.addMember("household-1", "member", models.HouseholdRoleMember)
+	s := NewAssignmentService(households)
+
+	weight := 2.0
+	err := s.UpdatePreferences(ctx, "admin", &models.AssignmentPreferences{
+		HouseholdID: "household-1", UserID: "member", Weight: &weight, ExcludedCategories: []string{"cooking"},
+	})
+	if err != nil {
+		t.Fatalf("UpdatePreferences() by an admin error = %v", err)
+	}
+
+	otherWeight := 0.5
+	tests := []struct {
+		name    string
+		prefs   models.AssignmentPreferences
+		wantErr error
+	}{
+		{
+			name:    "weight",
+			prefs:   models.AssignmentPreferences{Weight: &otherWeight},
+			wantErr: ErrNotHouseholdAdmin,
+		},
+		{
+			name:    "excluded categories",
+			prefs:   models.AssignmentPreferences{ExcludedCategories: []string{}},
+			wantErr: ErrNotHouseholdAdmin,
+		},
+		{
+			name:  "unchanged weight and exclusions sent back",
+			prefs: models.AssignmentPreferences{Weight: &weight, ExcludedCategories: []string{"cooking"}, PreferredCategories: []string{"laundry"}},
+		},
+		{
+			name:  "preferred categories only",
+			prefs: models.AssignmentPreferences{PreferredCategories: []string{"garden"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefs := tt.prefs
+			prefs.HouseholdID, prefs.UserID = "household-1", "member"
+			if err := s.UpdatePreferences(ctx, "member", &prefs); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdatePreferences() error = %v, want %v", err, tt.wantErr)
+			}
+
+			stored, _ := households.GetAssignmentPreferences(ctx, "household-1", "member")
+			if stored.Weight == nil || *stored.Weight != weight || !reflect.DeepEqual(stored.ExcludedCategories, []string{"cooking"}) {
+				t.Errorf("stored weight = %v, excluded = %v, want the admin's", stored.Weight, stored.ExcludedCategories)
+			}
+			if tt.wantErr == nil && !reflect.DeepEqual(stored.PreferredCategories, prefs.PreferredCategories) {
+				t.Errorf("stored preferred = %v, want %v", stored.PreferredCategories, prefs.PreferredCategories)
+			}
+		})
+	}
+
+	if err := s.UpdatePreferences(ctx, "member", &models.AssignmentPreferences{HouseholdID: "household-1", UserID: "admin"}); !errors.Is(err, ErrNotHouseholdAdmin) {
+		t.Errorf("UpdatePreferences() of another member error = %v, want ErrNotHouseholdAdmin", err)
+	}
+}
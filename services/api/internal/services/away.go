@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yakirshlomo/house-helper/services/api/internal/store"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/temporal"
+)
+
+// ErrInvalidAwayPeriod is returned for an away period that ends before it
+// starts, has already ended or is for someone outside the household
+var ErrInvalidAwayPeriod = errors.New("invalid away period")
+
+// pauseUntilSignal pauses a recurring task workflow for an away period; a
+// signal without Until resumes it
+type pauseUntilSignal struct {
+	AwayID string     `json:"awayId"`
+	From   time.Time  `json:"from"`
+	Until  *time.Time `json:"until,omitempty"`
+}
+
+// AwayService manages the periods a household or its members are away
+type AwayService struct {
+	awayStore      store.AwayStore
+	taskStore      store.TaskStore
+	householdStore store.HouseholdStore
+	temporalClient *temporal.Client
+}
+
+// NewAwayService creates a new away service
+func NewAwayService(awayStore store.AwayStore, taskStore store.TaskStore, householdStore store.HouseholdStore, temporalClient *temporal.Client) *AwayService {
+	return &AwayService{
+		awayStore:      awayStore,
+		taskStore:      taskStore,
+		householdStore: householdStore,
+		temporalClient: temporalClient,
+	}
+}
+
+// CreateAwayPeriod records that a member, or the whole household when
+// memberID is nil, is away. The household's recurring tasks are paused for a
+// household period. Members may record their own periods; household periods
+// and those of other members need an admin.
+func (s *AwayService) CreateAwayPeriod(ctx context.Context, userID, householdID string, memberID *string, startsAt, endsAt time.Time, note *string) (*models.AwayPeriod, error) {
+	if err := s.requireAccess(ctx, householdID, userID, memberID); err != nil {
+		return nil, err
+	}
+	if !endsAt.After(startsAt) {
+		return nil, fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidAwayPeriod)
+	}
+	if !endsAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: the period has already ended", ErrInvalidAwayPeriod)
+	}
+	if memberID != nil {
+		isMember, err := s.householdStore.IsMember(ctx, householdID, *memberID)
+		if err != nil {
+			return nil, err
+		}
+		if !isMember {
+			return nil, fmt.Errorf("%w: user is not a member of the household", ErrInvalidAwayPeriod)
+		}
+	}
+
+	period := &models.AwayPeriod{
+		ID:          uuid.New().String(),
+		HouseholdID: householdID,
+		UserID:      memberID,
+		StartsAt:    startsAt,
+		EndsAt:      endsAt,
+		Note:        note,
+		CreatedBy:   userID,
+	}
+	if err := s.awayStore.Create(ctx, period); err != nil {
+		return nil, err
+	}
+
+	if period.UserID == nil {
+		s.signalRecurringTasks(ctx, householdID, pauseUntilSignal{
+			AwayID: period.ID,
+			From:   period.StartsAt,
+			Until:  &period.EndsAt,
+		})
+	}
+
+	return period, nil
+}
+
+// ListAwayPeriods returns the current and upcoming away periods of a household
+func (s *AwayService) ListAwayPeriods(ctx context.Context, userID, householdID string) ([]*models.AwayPeriod, error) {
+	if err := s.requireMember(ctx, householdID, userID); err != nil {
+		return nil, err
+	}
+	return s.awayStore.GetByHouseholdID(ctx, householdID, time.Now())
+}
+
+// DeleteAwayPeriod removes an away period, resuming the household's recurring
+// tasks if the whole household was away. Members may remove their own
+// periods; household periods and those of other members need an admin.
+func (s *AwayService) DeleteAwayPeriod(ctx context.Context, userID, householdID, id string) error {
+	if err := s.requireMember(ctx, householdID, userID); err != nil {
+		return err
+	}
+
+	period, err := s.awayStore.GetByID(ctx, householdID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.requireAccess(ctx, householdID, userID, period.UserID); err != nil {
+		return err
+	}
+	if err := s.awayStore.Delete(ctx, householdID, id); err != nil {
+		return err
+	}
+
+	if period.UserID == nil {
+		s.signalRecurringTasks(ctx, householdID, pauseUntilSignal{AwayID: period.ID})
+	}
+
+	return nil
+}
+
+// signalRecurringTasks sends a pause to the workflows of the household's
+// recurring tasks. Tasks whose workflow is not running are skipped, as
+// workflows load the household's away periods when they start; members away
+// on their own are looked up by the workflows when they assign tasks.
+func (s *AwayService) signalRecurringTasks(ctx context.Context, householdID string, signal pauseUntilSignal) {
+	if s.temporalClient == nil {
+		return
+	}
+
+	taskIDs, err := s.taskStore.GetRecurringTaskIDs(ctx, householdID)
+	if err != nil {
+		return
+	}
+	for _, taskID := range taskIDs {
+		_ = s.temporalClient.SignalWorkflow(ctx, recurringTaskWorkflowID(taskID), "", "pause_until", signal)
+	}
+}
+
+func (s *AwayService) requireMember(ctx context.Context, householdID, userID string) error {
+	isMember, err := s.householdStore.IsMember(ctx, householdID, userID)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return ErrNotHouseholdMember
+	}
+	return nil
+}
+
+// requireAccess checks the caller may manage an away period of memberID, or
+// of the whole household when memberID is nil
+func (s *AwayService) requireAccess(ctx context.Context, householdID, userID string, memberID *string) error {
+	member, err := s.householdStore.GetMember(ctx, householdID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrNotHouseholdMember
+	}
+	if err != nil {
+		return err
+	}
+	if member.Role != models.HouseholdRoleAdmin && (memberID == nil || *memberID != userID) {
+		return ErrNotHouseholdAdmin
+	}
+	return nil
+}
+
+// recurringTaskWorkflowID returns the ID of a recurring task's workflow
+func recurringTaskWorkflowID(taskID string) string {
+	return "recurring-task-" + taskID
+}
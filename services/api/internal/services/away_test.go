@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yakirshlomo/house-helper/services/api/internal/store"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
+)
+
+// fakeAwayStore keeps away periods in memory
+type fakeAwayStore struct {
+	store.AwayStore
+	periods map[string]*models.AwayPeriod
+}
+
+func (s *fakeAwayStore) Create(ctx context.Context, period *models.AwayPeriod) error {
+	stored := *period
+	s.periods[period.ID] = &stored
+	return nil
+}
+
+func (s *fakeAwayStore) GetByID(ctx context.Context, householdID, id string) (*models.AwayPeriod, error) {
+	period, ok := s.periods[id]
+	if !ok || period.HouseholdID != householdID {
+		return nil, store.ErrNotFound
+	}
+	stored := *period
+	return &stored, nil
+}
+
+func (s *fakeAwayStore) Delete(ctx context.Context, householdID, id string) error {
+	if _, err := s.GetByID(ctx, householdID, id); err != nil {
+		return err
+	}
+	delete(s.periods, id)
+	return nil
+}
+
+func TestAwayPeriodsOfOthersNeedAdmin(t *testing.T) {
+	ctx := context.Background()
+	households := newFakeHouseholdStore()
+	households.addMember("household-1", "admin", models.HouseholdRoleAdmin)
+	households.addMember("household-1", "member", models.HouseholdRoleMember)
+	s := &AwayService{awayStore: &fakeAwayStore{periods: map[string]*models.AwayPeriod{}}, householdStore: households}
+
+	startsAt := time.Now()
+	endsAt := startsAt.Add(72 * time.Hour)
+	member, admin, stranger := "member", "admin", "stranger"
+
+	tests := []struct {
+		name     string
+		userID   string
+		memberID *string
+		wantErr  error
+	}{
+		{name: "member for themselves", userID: "member", memberID: &member},
+		{name: "member for the household", userID: "member", wantErr: ErrNotHouseholdAdmin},
+		{name: "member for another member", userID: "member", memberID: &admin, wantErr: ErrNotHouseholdAdmin},
+		{name: "admin for the household", userID: "admin"},
+		{name: "admin for another member", userID: "admin", memberID: &member},
+		{name: "non-member for themselves", userID: "stranger", memberID: &stranger, wantErr: ErrNotHouseholdMember},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.CreateAwayPeriod(ctx, tt.userID, "household-1", tt.memberID, startsAt, endsAt, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("CreateAwayPeriod() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	household, err := s.CreateAwayPeriod(ctx, "admin", "household-1", nil, startsAt, endsAt, nil)
+	if err != nil {
+		t.Fatalf("CreateAwayPeriod() error = %v", err)
+	}
+	own, err := s.CreateAwayPeriod(ctx, "member", "household-1", &member, startsAt, endsAt, nil)
+	if err != nil {
+		t.Fatalf("CreateAwayPeriod() error = %v", err)
+	}
+
+	if err := s.DeleteAwayPeriod(ctx, "member", "household-1", household.ID); !errors.Is(err, ErrNotHouseholdAdmin) {
+		t.Errorf("DeleteAwayPeriod() of the household's period by a member error = %v, want ErrNotHouseholdAdmin", err)
+	}
+	if err := s.DeleteAwayPeriod(ctx, "member", "household-1", own.ID); err != nil {
+		t.Errorf("DeleteAwayPeriod() of their own period error = %v", err)
+	}
+	if err := s.DeleteAwayPeriod(ctx, "admin", "household-1", household.ID); err != nil {
+		t.Errorf("DeleteAwayPeriod() of the household's period by an admin error = %v", err)
+	}
+}
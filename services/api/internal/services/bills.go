@@ -0,0 +1,378 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yakirshlomo/house-helper/services/api/internal/store"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
+)
+
+// DefaultBillReminderDays matches the reminder_days default of the bills table
+const DefaultBillReminderDays = 3
+
+var (
+	// ErrBillSettled is returned when paying a bill that is paid or cancelled
+	ErrBillSettled = errors.New("bill is already paid or cancelled")
+
+	// ErrBillNotRecurring is returned when editing the future instances of a
+	// bill that is not part of a series
+	ErrBillNotRecurring = errors.New("bill is not part of a recurring series")
+
+	// ErrBillAmountRequired is returned when paying a bill with an open
+	// amount without giving the amount
+	ErrBillAmountRequired = errors.New("amount is required for a bill with an open amount")
+
+	// ErrBillAssigneeNotMember is returned when a bill is assigned to
+	// someone outside its household
+	ErrBillAssigneeNotMember = errors.New("bill assignee is not a member of the household")
+)
+
+// BillEditScope says which instances of a recurring bill an edit applies to
+type BillEditScope string
+
+const (
+	BillEditThis          BillEditScope = "this"            // Only the instance
+	BillEditThisAndFuture BillEditScope = "this_and_future" // The instance, the unpaid ones after it and the series
+)
+
+// billLifecycleWorkflow is the worker service's workflow that reminds the
+// assignee of a bill, marks it overdue and follows up until it is paid
+const billLifecycleWorkflow = "BillLifecycleWorkflow"
+
+// billLifecycleParams are the parameters of the bill lifecycle workflow
+type billLifecycleParams struct {
+	BillID       string    `json:"billId"`
+	HouseholdID  string    `json:"householdId"`
+	CreatedBy    string    `json:"createdBy"`
+	AssignedTo   string    `json:"assignedTo,omitempty"`
+	Name         string    `json:"name"`
+	Amount       float64   `json:"amount"`
+	Currency     string    `json:"currency"`
+	DueDate      time.Time `json:"dueDate"`
+	ReminderDays *int      `json:"reminderDays,omitempty"`
+	SeriesID     string    `json:"seriesId,omitempty"`
+	AmountType   string    `json:"amountType,omitempty"`
+}
+
+// billPaidSignal tells the bill lifecycle workflow the bill was paid
+type billPaidSignal struct {
+	PaidBy string  `json:"paidBy"`
+	Amount float64 `json:"amount"`
+}
+
+// billUpdatedSignal tells the bill lifecycle workflow the bill was edited,
+// moving its reminder and due date
+type billUpdatedSignal struct {
+	Name         string    `json:"name"`
+	Amount       float64   `json:"amount"`
+	Currency     string    `json:"currency"`
+	DueDate      time.Time `json:"dueDate"`
+	AssignedTo   string    `json:"assignedTo,omitempty"`
+	ReminderDays *int      `json:"reminderDays,omitempty"`
+	AmountType   string    `json:"amountType,omitempty"`
+}
+
+// billWorkflowID returns the ID of a bill's lifecycle workflow
+func billWorkflowID(billID string) string {
+	return "bill-" + billID
+}
+
+// CreateBill creates a bill and starts its lifecycle workflow. A bill with a
+// recurrence rule starts a series, of which it is the first instance. The
+// bill is removed again if the workflow cannot be started, so no bill goes
+// without reminders.
+func (s *BillService) CreateBill(ctx context.Context, bill *models.Bill) error {
+	if err := s.checkAssignee(ctx, bill.HouseholdID, bill.AssignedTo); err != nil {
+		return err
+	}
+	if bill.ID == "" {
+		bill.ID = uuid.New().String()
+	}
+	if bill.Status == "" {
+		bill.Status = models.BillStatusPending
+	}
+	if bill.AmountType == "" {
+		bill.AmountType = models.BillAmountFixed
+	}
+	if bill.ReminderDays == nil {
+		reminderDays := DefaultBillReminderDays
+		bill.ReminderDays = &reminderDays
+	}
+
+	var series *models.BillSeries
+	if bill.RecurrenceRule != nil {
+		series = newBillSeries(bill)
+		if bill.AmountType == models.BillAmountOpen {
+			bill.Amount = 0
+		}
+		bill.IsRecurring = true
+		if err := s.billStore.CreateSeries(ctx, series, bill); err != nil {
+			return err
+		}
+	} else if err := s.billStore.Create(ctx, bill); err != nil {
+		return err
+	}
+
+	err := s.startBillLifecycle(ctx, bill)
+	if err == nil {
+		return nil
+	}
+
+	remove := func() error { return s.billStore.Delete(ctx, bill.ID) }
+	if series != nil {
+		remove = func() error { return s.billStore.EndSeries(ctx, series.ID, 1) }
+	}
+	if removeErr := remove(); removeErr != nil {
+		return fmt.Errorf("failed to start bill lifecycle: %w (and to remove the bill: %v)", err, removeErr)
+	}
+	return fmt.Errorf("failed to start bill lifecycle: %w", err)
+}
+
+// newBillSeries returns the series template of a recurring bill
+func newBillSeries(bill *models.Bill) *models.BillSeries {
+	series := &models.BillSeries{
+		ID:             uuid.New().String(),
+		HouseholdID:    bill.HouseholdID,
+		Name:           bill.Name,
+		Category:       bill.Category,
+		AmountType:     bill.AmountType,
+		Currency:       bill.Currency,
+		RecurrenceRule: *bill.RecurrenceRule,
+		AssignedTo:     bill.AssignedTo,
+		ReminderDays:   bill.ReminderDays,
+		CreatedBy:      bill.CreatedBy,
+	}
+	if bill.Description != "" {
+		description := bill.Description
+		series.Description = &description
+	}
+	if bill.AmountType != models.BillAmountOpen {
+		amount := bill.Amount
+		series.Amount = &amount
+	}
+	return series
+}
+
+// startBillLifecycle starts the workflow that reminds the assignee of a bill
+// and creates the next instance of a recurring one
+func (s *BillService) startBillLifecycle(ctx context.Context, bill *models.Bill) error {
+	// Without Temporal, in local development, bills get no reminders
+	if s.temporalClient == nil {
+		return nil
+	}
+
+	params := billLifecycleParams{
+		BillID:       bill.ID,
+		HouseholdID:  bill.HouseholdID,
+		CreatedBy:    bill.CreatedBy,
+		Name:         bill.Name,
+		Amount:       bill.Amount,
+		Currency:     bill.Currency,
+		DueDate:      bill.DueDate,
+		ReminderDays: bill.ReminderDays,
+		AmountType:   string(bill.AmountType),
+	}
+	if bill.AssignedTo != nil {
+		params.AssignedTo = *bill.AssignedTo
+	}
+	if bill.SeriesID != nil {
+		params.SeriesID = *bill.SeriesID
+	}
+
+	_, err := s.temporalClient.StartWorkflow(ctx, billWorkflowID(bill.ID), billLifecycleWorkflow, params)
+	return err
+}
+
+// checkAssignee checks a bill is assigned to a member of its household, or
+// to nobody
+func (s *BillService) checkAssignee(ctx context.Context, householdID string, assignedTo *string) error {
+	if assignedTo == nil {
+		return nil
+	}
+	isMember, err := s.householdStore.IsMember(ctx, householdID, *assignedTo)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return ErrBillAssigneeNotMember
+	}
+	return nil
+}
+
+// getHouseholdBill returns a bill of the household
+func (s *BillService) getHouseholdBill(ctx context.Context, householdID, billID string) (*models.Bill, error) {
+	bill, err := s.billStore.GetByID(ctx, billID)
+	if err != nil {
+		return nil, err
+	}
+	if bill.HouseholdID != householdID {
+		return nil, store.ErrNotFound
+	}
+	return bill, nil
+}
+
+// UpdateBill applies changes to a bill, or with BillEditThisAndFuture also to
+// the unpaid instances after it and to the series later instances are
+// created from. The recurrence rule only changes with the series.
+func (s *BillService) UpdateBill(ctx context.Context, householdID, billID string, changes *models.Bill, scope BillEditScope) (*models.Bill, error) {
+	bill, err := s.getHouseholdBill(ctx, householdID, billID)
+	if err != nil {
+		return nil, err
+	}
+	if scope == BillEditThisAndFuture && (bill.SeriesID == nil || bill.Sequence == nil) {
+		return nil, ErrBillNotRecurring
+	}
+	if err := s.checkAssignee(ctx, householdID, changes.AssignedTo); err != nil {
+		return nil, err
+	}
+
+	bill.Name = changes.Name
+	bill.Description = changes.Description
+	bill.Amount = changes.Amount
+	bill.Currency = changes.Currency
+	bill.DueDate = changes.DueDate
+	bill.AssignedTo = changes.AssignedTo
+	if changes.Category != "" {
+		bill.Category = changes.Category
+	}
+	if changes.ReminderDays != nil {
+		bill.ReminderDays = changes.ReminderDays
+	}
+	if changes.AmountType != "" {
+		bill.AmountType = changes.AmountType
+	}
+
+	if scope == BillEditThisAndFuture {
+		series, err := s.billStore.GetSeries(ctx, *bill.SeriesID)
+		if err != nil {
+			return nil, err
+		}
+		if changes.RecurrenceRule != nil {
+			series.RecurrenceRule = *changes.RecurrenceRule
+		}
+		bill.RecurrenceRule = &series.RecurrenceRule
+
+		updated := newBillSeries(bill)
+		updated.ID = series.ID
+		updated.CreatedBy = series.CreatedBy
+
+		if err := s.billStore.UpdateSeriesFrom(ctx, updated, *bill.Sequence); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.billStore.Update(ctx, bill); err != nil {
+		return nil, err
+	}
+
+	s.signalBillUpdated(ctx, bill)
+	if scope == BillEditThisAndFuture {
+		instances, err := s.billStore.GetSeriesInstances(ctx, *bill.SeriesID)
+		if err != nil {
+			return nil, err
+		}
+		for _, instance := range instances {
+			if instance.Sequence != nil && *instance.Sequence > *bill.Sequence {
+				s.signalBillUpdated(ctx, instance)
+			}
+		}
+	}
+	return bill, nil
+}
+
+// signalBillUpdated tells the lifecycle workflow of an unpaid bill about an
+// edit. Workflows of settled bills have finished, and one the signal does not
+// reach still finds the bill's status before marking it overdue, so the edit
+// does not fail on it.
+func (s *BillService) signalBillUpdated(ctx context.Context, bill *models.Bill) {
+	if s.temporalClient == nil || (bill.Status != models.BillStatusPending && bill.Status != models.BillStatusOverdue) {
+		return
+	}
+
+	signal := billUpdatedSignal{
+		Name:         bill.Name,
+		Amount:       bill.Amount,
+		Currency:     bill.Currency,
+		DueDate:      bill.DueDate,
+		ReminderDays: bill.ReminderDays,
+		AmountType:   string(bill.AmountType),
+	}
+	if bill.AssignedTo != nil {
+		signal.AssignedTo = *bill.AssignedTo
+	}
+	_ = s.temporalClient.SignalWorkflow(ctx, billWorkflowID(bill.ID), "", "bill_updated", signal)
+}
+
+// DeleteBill deletes a bill, or with BillEditThisAndFuture ends its series
+// and deletes the unpaid instances from this one on
+func (s *BillService) DeleteBill(ctx context.Context, householdID, billID string, scope BillEditScope) error {
+	bill, err := s.getHouseholdBill(ctx, householdID, billID)
+	if err != nil {
+		return err
+	}
+
+	if scope == BillEditThisAndFuture {
+		if bill.SeriesID == nil || bill.Sequence == nil {
+			return ErrBillNotRecurring
+		}
+		return s.billStore.EndSeries(ctx, *bill.SeriesID, *bill.Sequence)
+	}
+	return s.billStore.Delete(ctx, billID)
+}
+
+// GetBillSeries returns a series of the household with its instances
+func (s *BillService) GetBillSeries(ctx context.Context, householdID, seriesID string) (*models.BillSeries, []*models.Bill, error) {
+	series, err := s.billStore.GetSeries(ctx, seriesID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if series.HouseholdID != householdID {
+		return nil, nil, store.ErrNotFound
+	}
+
+	instances, err := s.billStore.GetSeriesInstances(ctx, seriesID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return series, instances, nil
+}
+
+// PayBill marks a bill of the household paid and ends its lifecycle
+// workflow, which creates the next instance of a recurring bill
+func (s *BillService) PayBill(ctx context.Context, userID, householdID, billID string, amount *float64) (*models.Bill, error) {
+	bill, err := s.getHouseholdBill(ctx, householdID, billID)
+	if err != nil {
+		return nil, err
+	}
+	if bill.Status == models.BillStatusPaid || bill.Status == models.BillStatusCancelled {
+		return nil, ErrBillSettled
+	}
+	if bill.AmountType == models.BillAmountOpen && amount == nil {
+		return nil, ErrBillAmountRequired
+	}
+
+	paidAmount := bill.Amount
+	if amount != nil {
+		paidAmount = *amount
+	}
+	if err := s.billStore.MarkPaid(ctx, billID, userID, paidAmount); err != nil {
+		return nil, err
+	}
+
+	// The workflow also checks the bill's status before every reminder, so
+	// a signal that is not delivered, or a workflow that already finished,
+	// does not fail the payment
+	if s.temporalClient != nil {
+		_ = s.temporalClient.SignalWorkflow(ctx, billWorkflowID(billID), "", "bill_paid", billPaidSignal{
+			PaidBy: userID,
+			Amount: paidAmount,
+		})
+	}
+
+	return s.billStore.GetByID(ctx, billID)
+}
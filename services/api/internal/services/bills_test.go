@@ -0,0 +1,22 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
+)
+
+func TestBillAssigneeMustBeMember(t *testing.T) {
+	households := newFakeHouseholdStore()
+	households.addMember("household-1", "user-1", models.HouseholdRoleAdmin)
+	s := &BillService{householdStore: households}
+
+	stranger := "user-2"
+	bill := &models.Bill{HouseholdID: "household-1", Name: "Rent", Amount: 1200, DueDate: time.Now(), AssignedTo: &stranger, CreatedBy: "user-1"}
+	if err := s.CreateBill(context.Background(), bill); !errors.Is(err, ErrBillAssigneeNotMember) {
+		t.Errorf("CreateBill() assigned to a non-member error = %v, want ErrBillAssigneeNotMember", err)
+	}
+}
@@ -0,0 +1,281 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/client"
+
+	"github.com/yakirshlomo/house-helper/services/api/internal/store"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/kafka"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/temporal"
+)
+
+var (
+	// ErrInvalidHousehold is returned for an unknown household timezone
+	ErrInvalidHousehold = errors.New("invalid household")
+
+	// ErrInvalidHouseholdRole is returned for a role households do not have
+	ErrInvalidHouseholdRole = errors.New("invalid household role")
+
+	// ErrLastHouseholdAdmin is returned when a change would leave a
+	// household without an admin
+	ErrLastHouseholdAdmin = errors.New("household needs an admin")
+)
+
+// householdDigestWorkflow is the worker service's workflow that sends each
+// member of a household a morning summary
+const householdDigestWorkflow = "HouseholdDigestWorkflow"
+
+// householdDigestHour is the local hour household digests are sent at
+const householdDigestHour = 7
+
+// householdDigestParams are the parameters of the household digest workflow
+type householdDigestParams struct {
+	HouseholdID string `json:"householdId"`
+}
+
+// digestScheduleID returns the ID of a household's digest schedule
+func digestScheduleID(householdID string) string {
+	return "household-digest-" + householdID
+}
+
+// scheduleClient creates and deletes the schedules of the worker service
+type scheduleClient interface {
+	UpsertSchedule(ctx context.Context, options client.ScheduleOptions) error
+	DeleteSchedule(ctx context.Context, scheduleID string) error
+}
+
+// HouseholdService manages households and their members' roles, and keeps
+// their digest schedules in step with them
+type HouseholdService struct {
+	householdStore store.HouseholdStore
+	schedules      scheduleClient
+	kafkaProducer  *kafka.Producer
+}
+
+// NewHouseholdService creates a new household service
+func NewHouseholdService(householdStore store.HouseholdStore, kafkaProducer *kafka.Producer, temporalClient *temporal.Client) *HouseholdService {
+	s := &HouseholdService{householdStore: householdStore, kafkaProducer: kafkaProducer}
+	if temporalClient != nil {
+		s.schedules = temporalClient
+	}
+	return s
+}
+
+// CreateHousehold creates a household with the user as its admin and
+// schedules its daily digest. The household is removed again if the digest
+// cannot be scheduled, so no household goes without one.
+func (s *HouseholdService) CreateHousehold(ctx context.Context, userID, name string, description *string, timezone, currency string) (*models.Household, error) {
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidHousehold, timezone)
+	}
+	if currency == "" {
+		currency = "USD"
+	}
+
+	household := &models.Household{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Timezone:  timezone,
+		Currency:  currency,
+		CreatedBy: userID,
+	}
+	if description != nil {
+		household.Description = *description
+	}
+	if err := s.householdStore.Create(ctx, household); err != nil {
+		return nil, err
+	}
+
+	if err := s.scheduleDigest(ctx, household); err != nil {
+		_ = s.householdStore.Delete(ctx, household.ID)
+		return nil, fmt.Errorf("failed to schedule household digest: %w", err)
+	}
+
+	return household, nil
+}
+
+// UpdateHousehold changes a household; nil fields are left as they are.
+// The digest schedule follows the household's timezone. Only household
+// admins may change a household.
+func (s *HouseholdService) UpdateHousehold(ctx context.Context, userID, householdID string, name, description, timezone, currency *string) (*models.Household, error) {
+	if err := s.requireAdmin(ctx, householdID, userID); err != nil {
+		return nil, err
+	}
+	if timezone != nil {
+		if _, err := time.LoadLocation(*timezone); *timezone == "" || err != nil {
+			return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidHousehold, *timezone)
+		}
+	}
+
+	household, err := s.householdStore.GetByID(ctx, householdID)
+	if err != nil {
+		return nil, err
+	}
+	if name != nil {
+		household.Name = *name
+	}
+	if description != nil {
+		household.Description = *description
+	}
+	if timezone != nil {
+		household.Timezone = *timezone
+	}
+	if currency != nil {
+		household.Currency = *currency
+	}
+	if err := s.householdStore.Update(ctx, household); err != nil {
+		return nil, err
+	}
+
+	// The schedule is replaced whenever a timezone is sent, not only when it
+	// differs, so a request retried after a failed update repairs it
+	if timezone != nil {
+		if err := s.scheduleDigest(ctx, household); err != nil {
+			return nil, fmt.Errorf("failed to reschedule household digest: %w", err)
+		}
+	}
+
+	return household, nil
+}
+
+// DeleteHousehold deletes a household and its digest schedule. Only
+// household admins may delete a household.
+func (s *HouseholdService) DeleteHousehold(ctx context.Context, userID, householdID string) error {
+	if err := s.requireAdmin(ctx, householdID, userID); err != nil {
+		return err
+	}
+	if err := s.householdStore.Delete(ctx, householdID); err != nil {
+		return err
+	}
+
+	// A schedule left behind sends nothing, the digest workflow skips
+	// deleted households
+	if s.schedules != nil {
+		_ = s.schedules.DeleteSchedule(ctx, digestScheduleID(householdID))
+	}
+	return nil
+}
+
+// UpdateMemberRole changes the role of a household member and publishes a
+// household.member.role_changed event, so the event consumer can move their
+// devices on or off the household push topic. Only household admins may
+// change roles, and the last admin cannot step down.
+func (s *HouseholdService) UpdateMemberRole(ctx context.Context, userID, householdID, memberID string, role models.HouseholdRole) (*models.HouseholdMember, error) {
+	switch role {
+	case models.HouseholdRoleAdmin, models.HouseholdRoleMember, models.HouseholdRoleGuest, models.HouseholdRoleChild:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidHouseholdRole, role)
+	}
+	if err := s.requireAdmin(ctx, householdID, userID); err != nil {
+		return nil, err
+	}
+
+	member, err := s.householdStore.GetMember(ctx, householdID, memberID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role == role {
+		return member, nil
+	}
+	if member.Role == models.HouseholdRoleAdmin {
+		if err := s.requireOtherAdmin(ctx, householdID, memberID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.householdStore.UpdateMemberRole(ctx, householdID, memberID, role); err != nil {
+		return nil, err
+	}
+	previousRole := member.Role
+	member.Role = role
+
+	s.publishRoleChanged(ctx, userID, member, previousRole)
+	return member, nil
+}
+
+// requireOtherAdmin checks the household has an admin besides memberID
+func (s *HouseholdService) requireOtherAdmin(ctx context.Context, householdID, memberID string) error {
+	members, err := s.householdStore.GetMembers(ctx, householdID)
+	if err != nil {
+		return err
+	}
+	for _, other := range members {
+		if other.UserID != memberID && other.Role == models.HouseholdRoleAdmin {
+			return nil
+		}
+	}
+	return ErrLastHouseholdAdmin
+}
+
+// publishRoleChanged publishes a household.member.role_changed event. The
+// role is committed already, so the event is best effort.
+func (s *HouseholdService) publishRoleChanged(ctx context.Context, actorID string, member *models.HouseholdMember, previousRole models.HouseholdRole) {
+	if s.kafkaProducer == nil {
+		return
+	}
+
+	event := kafka.DomainEvent{
+		ID:          uuid.New().String(),
+		Type:        kafka.EventTypeHouseholdMemberRoleChanged,
+		HouseholdID: member.HouseholdID,
+		UserID:      actorID,
+		Data: map[string]interface{}{
+			"householdId":  member.HouseholdID,
+			"memberId":     member.UserID,
+			"role":         string(member.Role),
+			"previousRole": string(previousRole),
+			"actorId":      actorID,
+		},
+	}
+	_ = kafka.NewEventPublisher(s.kafkaProducer).PublishDomainEvent(ctx, kafka.TopicHouseholds, event)
+}
+
+// scheduleDigest creates or replaces the schedule that starts the
+// household's digest every morning in its timezone. A digest missed by more
+// than an hour is skipped rather than sent late.
+func (s *HouseholdService) scheduleDigest(ctx context.Context, household *models.Household) error {
+	if s.schedules == nil {
+		return nil
+	}
+
+	return s.schedules.UpsertSchedule(ctx, client.ScheduleOptions{
+		ID: digestScheduleID(household.ID),
+		Spec: client.ScheduleSpec{
+			Calendars: []client.ScheduleCalendarSpec{{
+				Hour: []client.ScheduleRange{{Start: householdDigestHour}},
+			}},
+			TimeZoneName: household.Timezone,
+		},
+		Action: &client.ScheduleWorkflowAction{
+			ID:       digestScheduleID(household.ID),
+			Workflow: householdDigestWorkflow,
+			Args:     []interface{}{householdDigestParams{HouseholdID: household.ID}},
+		},
+		Overlap:       enums.SCHEDULE_OVERLAP_POLICY_SKIP,
+		CatchupWindow: time.Hour,
+	})
+}
+
+func (s *HouseholdService) requireAdmin(ctx context.Context, householdID, userID string) error {
+	member, err := s.householdStore.GetMember(ctx, householdID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrNotHouseholdMember
+	}
+	if err != nil {
+		return err
+	}
+	if member.Role != models.HouseholdRoleAdmin {
+		return ErrNotHouseholdAdmin
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.temporal.io/sdk/client"
+
+	"github.com/yakirshlomo/house-helper/services/api/internal/store"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
+)
+
+// fakeHouseholdStore keeps households and members in memory. Methods the
+// tests do not need panic through the embedded nil interface.
+type fakeHouseholdStore struct {
+	store.HouseholdStore
+	households  map[string]*models.Household
+	members     map[string]map[string]*models.HouseholdMember
+	preferences map[string]*models.AssignmentPreferences
+}
+
+func newFakeHouseholdStore() *fakeHouseholdStore {
+	return &fakeHouseholdStore{
+		households:  map[string]*models.Household{},
+		members:     map[string]map[string]*models.HouseholdMember{},
+		preferences: map[string]*models.AssignmentPreferences{},
+	}
+}
+
+func (s *fakeHouseholdStore) addMember(householdID, userID string, role models.HouseholdRole) {
+	if s.members[householdID] == nil {
+		s.members[householdID] = map[string]*models.HouseholdMember{}
+	}
+	s.members[householdID][userID] = &models.HouseholdMember{HouseholdID: householdID, UserID: userID, Role: role}
+}
+
+func (s *fakeHouseholdStore) Create(ctx context.Context, household *models.Household) error {
+	stored := *household
+	s.households[household.ID] = &stored
+	s.addMember(household.ID, household.CreatedBy, models.HouseholdRoleAdmin)
+	return nil
+}
+
+func (s *fakeHouseholdStore) GetByID(ctx context.Context, id string) (*models.Household, error) {
+	household, ok := s.households[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	stored := *household
+	return &stored, nil
+}
+
+func (s *fakeHouseholdStore) Update(ctx context.Context, household *models.Household) error {
+	if _, ok := s.households[household.ID]; !ok {
+		return store.ErrNotFound
+	}
+	stored := *household
+	s.households[household.ID] = &stored
+	return nil
+}
+
+func (s *fakeHouseholdStore) Delete(ctx context.Context, id string) error {
+	if _, ok := s.households[id]; !ok {
+		return store.ErrNotFound
+	}
+	delete(s.households, id)
+	return nil
+}
+
+func (s *fakeHouseholdStore) IsMember(ctx context.Context, householdID, userID string) (bool, error) {
+	_, ok := s.members[householdID][userID]
+	return ok, nil
+}
+
+func (s *fakeHouseholdStore) GetMember(ctx context.Context, householdID, userID string) (*models.HouseholdMember, error) {
+	member, ok := s.members[householdID][userID]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return member, nil
+}
+
+func (s *fakeHouseholdStore) GetMembers(ctx context.Context, householdID string) ([]*models.HouseholdMember, error) {
+	var members []*models.HouseholdMember
+	for _, member := range s.members[householdID] {
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+func (s *fakeHouseholdStore) UpdateMemberRole(ctx context.Context, householdID, userID string, role models.HouseholdRole) error {
+	member, ok := s.members[householdID][userID]
+	if !ok {
+		return store.ErrNotFound
+	}
+	member.Role = role
+	return nil
+}
+
+func (s *fakeHouseholdStore) GetAssignmentPreferences(ctx context.Context, householdID, userID string) (*models.AssignmentPreferences, error) {
+	if _, ok := s.members[householdID][userID]; !ok {
+		return nil, store.ErrNotFound
+	}
+	prefs, ok := s.preferences[householdID+"/"+userID]
+	if !ok {
+		return &models.AssignmentPreferences{HouseholdID: householdID, UserID: userID}, nil
+	}
+	stored := *prefs
+	return &stored, nil
+}
+
+func (s *fakeHouseholdStore) UpdateAssignmentPreferences(ctx context.Context, prefs *models.AssignmentPreferences) error {
+	if _, ok := s.members[prefs.HouseholdID][prefs.UserID]; !ok {
+		return store.ErrNotFound
+	}
+	stored := *prefs
+	s.preferences[prefs.HouseholdID+"/"+prefs.UserID] = &stored
+	return nil
+}
+
+// fakeScheduleClient records the schedules the service keeps
+type fakeScheduleClient struct {
+	schedules map[string]client.ScheduleOptions
+	err       error
+}
+
+func (c *fakeScheduleClient) UpsertSchedule(ctx context.Context, options client.ScheduleOptions) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.schedules[options.ID] = options
+	return nil
+}
+
+func (c *fakeScheduleClient) DeleteSchedule(ctx context.Context, scheduleID string) error {
+	delete(c.schedules, scheduleID)
+	return nil
+}
+
+func TestHouseholdDigestSchedule(t *testing.T) {
+	ctx := context.Background()
+	households := newFakeHouseholdStore()
+	schedules := &fakeScheduleClient{schedules: map[string]client.ScheduleOptions{}}
+	s := &HouseholdService{householdStore: households, schedules: schedules}
+
+	household, err := s.CreateHousehold(ctx, "user-1", "Home", nil, "Asia/Jerusalem", "")
+	if err != nil {
+		t.Fatalf("CreateHousehold() error = %v", err)
+	}
+
+	id := digestScheduleID(household.ID)
+	schedule, ok := schedules.schedules[id]
+	if !ok {
+		t.Fatalf("no digest schedule %s after the household was created", id)
+	}
+	if schedule.Spec.TimeZoneName != "Asia/Jerusalem" {
+		t.Errorf("schedule timezone = %q, want Asia/Jerusalem", schedule.Spec.TimeZoneName)
+	}
+	if got := schedule.Spec.Calendars[0].Hour[0].Start; got != householdDigestHour {
+		t.Errorf("schedule hour = %d, want %d", got, householdDigestHour)
+	}
+	action := schedule.Action.(*client.ScheduleWorkflowAction)
+	if action.Workflow != householdDigestWorkflow {
+		t.Errorf("schedule workflow = %v, want %s", action.Workflow, householdDigestWorkflow)
+	}
+	if params := action.Args[0].(householdDigestParams); params.HouseholdID != household.ID {
+		t.Errorf("schedule household = %q, want %q", params.HouseholdID, household.ID)
+	}
+
+	timezone := "America/New_York"
+	if _, err := s.UpdateHousehold(ctx, "user-1", household.ID, nil, nil, &timezone, nil); err != nil {
+		t.Fatalf("UpdateHousehold() error = %v", err)
+	}
+	if got := schedules.schedules[id].Spec.TimeZoneName; got != timezone {
+		t.Errorf("schedule timezone after update = %q, want %q", got, timezone)
+	}
+
+	if err := s.DeleteHousehold(ctx, "user-1", household.ID); err != nil {
+		t.Fatalf("DeleteHousehold() error = %v", err)
+	}
+	if _, ok := schedules.schedules[id]; ok {
+		t.Error("digest schedule left after the household was deleted")
+	}
+}
+
+func TestCreateHouseholdWithoutSchedule(t *testing.T) {
+	ctx := context.Background()
+	households := newFakeHouseholdStore()
+	schedules := &fakeScheduleClient{schedules: map[string]client.ScheduleOptions{}, err: errors.New("unavailable")}
+	s := &HouseholdService{householdStore: households, schedules: schedules}
+
+	if _, err := s.CreateHousehold(ctx, "user-1", "Home", nil, "UTC", ""); err == nil {
+		t.Fatal("CreateHousehold() succeeded without a digest schedule")
+	}
+	if len(households.households) != 0 {
+		t.Errorf("%d households left without a digest schedule", len(households.households))
+	}
+
+	if _, err := s.CreateHousehold(ctx, "user-1", "Home", nil, "Mars/Olympus", ""); !errors.Is(err, ErrInvalidHousehold) {
+		t.Errorf("unknown timezone error = %v, want ErrInvalidHousehold", err)
+	}
+}
+
+func TestUpdateHouseholdRequiresAdmin(t *testing.T) {
+	ctx := context.Background()
+	households := newFakeHouseholdStore()
+	s := &HouseholdService{householdStore: households, schedules: &fakeScheduleClient{schedules: map[string]client.ScheduleOptions{}}}
+
+	household, err := s.CreateHousehold(ctx, "user-1", "Home", nil, "", "")
+	if err != nil {
+		t.Fatalf("CreateHousehold() error = %v", err)
+	}
+	households.addMember(household.ID, "user-2", models.HouseholdRoleMember)
+
+	timezone := "Europe/London"
+	if _, err := s.UpdateHousehold(ctx, "user-2", household.ID, nil, nil, &timezone, nil); !errors.Is(err, ErrNotHouseholdAdmin) {
+		t.Errorf("member update error = %v, want ErrNotHouseholdAdmin", err)
+	}
+	if err := s.DeleteHousehold(ctx, "user-3", household.ID); !errors.Is(err, ErrNotHouseholdMember) {
+		t.Errorf("stranger delete error = %v, want ErrNotHouseholdMember", err)
+	}
+}
+
+func TestUpdateMemberRole(t *testing.T) {
+	ctx := context.Background()
+	households := newFakeHouseholdStore()
+	households.addMember("household-1", "admin", models.HouseholdRoleAdmin)
+	households.addMember("household-1", "member", models.HouseholdRoleMember)
+	s := NewHouseholdService(households, nil, nil)
+
+	if _, err := s.UpdateMemberRole(ctx, "member", "household-1", "member", models.HouseholdRoleAdmin); !errors.Is(err, ErrNotHouseholdAdmin) {
+		t.Errorf("member promoting themselves error = %v, want ErrNotHouseholdAdmin", err)
+	}
+	if _, err := s.UpdateMemberRole(ctx, "admin", "household-1", "member", "owner"); !errors.Is(err, ErrInvalidHouseholdRole) {
+		t.Errorf("unknown role error = %v, want ErrInvalidHouseholdRole", err)
+	}
+	if _, err := s.UpdateMemberRole(ctx, "admin", "household-1", "admin", models.HouseholdRoleMember); !errors.Is(err, ErrLastHouseholdAdmin) {
+		t.Errorf("last admin stepping down error = %v, want ErrLastHouseholdAdmin", err)
+	}
+
+	member, err := s.UpdateMemberRole(ctx, "admin", "household-1", "member", models.HouseholdRoleGuest)
+	if err != nil {
+		t.Fatalf("UpdateMemberRole() error = %v", err)
+	}
+	if member.Role != models.HouseholdRoleGuest || households.members["household-1"]["member"].Role != models.HouseholdRoleGuest {
+		t.Errorf("role = %s, stored %s, want guest", member.Role, households.members["household-1"]["member"].Role)
+	}
+
+	if _, err := s.UpdateMemberRole(ctx, "admin", "household-1", "member", models.HouseholdRoleAdmin); err != nil {
+		t.Fatalf("UpdateMemberRole() error = %v", err)
+	}
+	if _, err := s.UpdateMemberRole(ctx, "admin", "household-1", "admin", models.HouseholdRoleMember); err != nil {
+		t.Errorf("admin stepping down with another admin error = %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yakirshlomo/house-helper/services/api/internal/store"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
+)
+
+// ErrInvalidPeriod is returned for a leaderboard period other than week or month
+var ErrInvalidPeriod = errors.New("invalid leaderboard period")
+
+// Leaderboard periods
+const (
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+)
+
+// memberPointsEntries is how many of a member's latest ledger entries are
+// returned with their balance
+const memberPointsEntries = 50
+
+// PointsService reads members' points, streaks and the household leaderboards
+type PointsService struct {
+	pointsStore    store.PointsStore
+	householdStore store.HouseholdStore
+}
+
+// NewPointsService creates a new points service
+func NewPointsService(pointsStore store.PointsStore, householdStore store.HouseholdStore) *PointsService {
+	return &PointsService{
+		pointsStore:    pointsStore,
+		householdStore: householdStore,
+	}
+}
+
+// GetLeaderboard ranks a household's members by the points they earned this
+// week or month, in the household's timezone. Members tied on points share a
+// rank.
+func (s *PointsService) GetLeaderboard(ctx context.Context, userID, householdID, period string) (*models.Leaderboard, error) {
+	location, err := s.householdLocation(ctx, userID, householdID)
+	if err != nil {
+		return nil, err
+	}
+
+	if period == "" {
+		period = PeriodWeek
+	}
+	since, err := periodStart(period, time.Now().In(location))
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.pointsStore.GetLeaderboard(ctx, householdID, since)
+	if err != nil {
+		return nil, err
+	}
+	for i, entry := range entries {
+		entry.Rank = i + 1
+		if i > 0 && entry.Points == entries[i-1].Points {
+			entry.Rank = entries[i-1].Rank
+		}
+		entry.Streak = currentStreak(entry.Streak, location)
+	}
+
+	return &models.Leaderboard{
+		HouseholdID: householdID,
+		Period:      period,
+		Since:       since,
+		Entries:     entries,
+	}, nil
+}
+
+// GetMemberPoints returns a member's balance, streak and latest ledger entries
+func (s *PointsService) GetMemberPoints(ctx context.Context, userID, householdID, memberID string) (*models.MemberPoints, error) {
+	location, err := s.householdLocation(ctx, userID, householdID)
+	if err != nil {
+		return nil, err
+	}
+	isMember, err := s.householdStore.IsMember(ctx, householdID, memberID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, store.ErrNotFound
+	}
+
+	balance, err := s.pointsStore.GetBalance(ctx, householdID, memberID)
+	if err != nil {
+		return nil, err
+	}
+	streak, err := s.pointsStore.GetStreak(ctx, householdID, memberID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.pointsStore.GetEntries(ctx, householdID, memberID, memberPointsEntries)
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []*models.PointsEntry{}
+	}
+
+	return &models.MemberPoints{
+		HouseholdID: householdID,
+		UserID:      memberID,
+		Balance:     balance,
+		Streak:      currentStreak(streak, location),
+		Entries:     entries,
+	}, nil
+}
+
+// householdLocation checks the user belongs to the household and returns
+// the household's timezone
+func (s *PointsService) householdLocation(ctx context.Context, userID, householdID string) (*time.Location, error) {
+	isMember, err := s.householdStore.IsMember(ctx, householdID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrNotHouseholdMember
+	}
+
+	household, err := s.householdStore.GetByID(ctx, householdID)
+	if err != nil {
+		return nil, err
+	}
+	location, err := time.LoadLocation(household.Timezone)
+	if err != nil {
+		return time.UTC, nil
+	}
+	return location, nil
+}
+
+// periodStart returns the start of the week, from Monday, or month that now
+// falls in
+func periodStart(period string, now time.Time) (time.Time, error) {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch period {
+	case PeriodWeek:
+		return midnight.AddDate(0, 0, -(int(now.Weekday())+6)%7), nil
+	case PeriodMonth:
+		return midnight.AddDate(0, 0, 1-now.Day()), nil
+	default:
+		return time.Time{}, fmt.Errorf("%w: %q, want week or month", ErrInvalidPeriod, period)
+	}
+}
+
+// currentStreak zeroes a streak that was broken: the member completed
+// nothing yesterday or today
+func currentStreak(streak models.PointsStreak, location *time.Location) models.PointsStreak {
+	if streak.LastCompletedOn == nil {
+		return streak
+	}
+
+	now := time.Now().In(location)
+	yesterday := time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, time.UTC)
+	last := streak.LastCompletedOn.UTC()
+	if time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, time.UTC).Before(yesterday) {
+		streak.Current = 0
+	}
+	return streak
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yakirshlomo/house-helper/services/api/internal/store"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/kafka"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
+)
+
+var (
+	// ErrInvalidReward is returned for a reward the catalog cannot hold
+	ErrInvalidReward = errors.New("invalid reward")
+
+	// ErrRedemptionNotAllowed is returned when a guest requests a redemption
+	ErrRedemptionNotAllowed = errors.New("role may not redeem rewards")
+
+	// ErrRewardCooldown is returned when a member requests a reward again
+	// before its cooldown has passed
+	ErrRewardCooldown = errors.New("reward is cooling down")
+)
+
+// Redemption activities published as household.activity events
+const (
+	ActivityRedemptionApproved = "reward_redemption_approved"
+	ActivityRedemptionRejected = "reward_redemption_rejected"
+)
+
+// RewardService manages a household's rewards catalog and the redemptions
+// members request from it
+type RewardService struct {
+	rewardStore    store.RewardStore
+	pointsStore    store.PointsStore
+	householdStore store.HouseholdStore
+	kafkaProducer  *kafka.Producer
+}
+
+// NewRewardService creates a new reward service
+func NewRewardService(rewardStore store.RewardStore, pointsStore store.PointsStore, householdStore store.HouseholdStore, kafkaProducer *kafka.Producer) *RewardService {
+	return &RewardService{
+		rewardStore:    rewardStore,
+		pointsStore:    pointsStore,
+		householdStore: householdStore,
+		kafkaProducer:  kafkaProducer,
+	}
+}
+
+// ListRewards returns a household's catalog; every member, guests included,
+// may browse it
+func (s *RewardService) ListRewards(ctx context.Context, userID, householdID string) ([]*models.Reward, error) {
+	if _, err := s.member(ctx, householdID, userID); err != nil {
+		return nil, err
+	}
+	return s.rewardStore.GetByHouseholdID(ctx, householdID)
+}
+
+// CreateReward adds a reward to the catalog
+func (s *RewardService) CreateReward(ctx context.Context, userID string, reward *models.Reward) error {
+	if err := s.admin(ctx, reward.HouseholdID, userID); err != nil {
+		return err
+	}
+	if err := validateReward(reward); err != nil {
+		return err
+	}
+
+	reward.ID = uuid.New().String()
+	reward.CreatedBy = userID
+	return s.rewardStore.Create(ctx, reward)
+}
+
+// UpdateReward replaces a reward's name, cost, stock and cooldown
+func (s *RewardService) UpdateReward(ctx context.Context, userID string, reward *models.Reward) error {
+	if err := s.admin(ctx, reward.HouseholdID, userID); err != nil {
+		return err
+	}
+	if err := validateReward(reward); err != nil {
+		return err
+	}
+	return s.rewardStore.Update(ctx, reward)
+}
+
+// DeleteReward removes a reward from the catalog. Its redemptions, and the
+// points they spent, are kept.
+func (s *RewardService) DeleteReward(ctx context.Context, userID, householdID, rewardID string) error {
+	if err := s.admin(ctx, householdID, userID); err != nil {
+		return err
+	}
+	return s.rewardStore.Delete(ctx, householdID, rewardID)
+}
+
+// Redeem requests a reward for the caller. The request waits for an admin to
+// approve it; the points are only spent then, but are held against the
+// caller's balance meanwhile.
+func (s *RewardService) Redeem(ctx context.Context, userID, householdID, rewardID string, note *string) (*models.Redemption, error) {
+	member, err := s.member(ctx, householdID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role == models.HouseholdRoleGuest {
+		return nil, ErrRedemptionNotAllowed
+	}
+
+	reward, err := s.rewardStore.GetByID(ctx, householdID, rewardID)
+	if err != nil {
+		return nil, err
+	}
+	if reward.Stock != nil && *reward.Stock <= 0 {
+		return nil, store.ErrOutOfStock
+	}
+
+	if reward.CooldownHours > 0 {
+		last, err := s.rewardStore.GetLastRedemption(ctx, rewardID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if last != nil {
+			next := last.Add(time.Duration(reward.CooldownHours) * time.Hour)
+			if time.Now().Before(next) {
+				return nil, fmt.Errorf("%w: available again at %s", ErrRewardCooldown, next.UTC().Format(time.RFC3339))
+			}
+		}
+	}
+
+	balance, err := s.pointsStore.GetBalance(ctx, householdID, userID)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := s.rewardStore.GetPendingCost(ctx, householdID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if balance-pending < reward.Cost {
+		return nil, store.ErrInsufficientPoints
+	}
+
+	redemption := &models.Redemption{
+		ID:          uuid.New().String(),
+		HouseholdID: householdID,
+		RewardID:    rewardID,
+		UserID:      userID,
+		Cost:        reward.Cost,
+		Status:      models.RedemptionStatusPending,
+		Note:        note,
+	}
+	if err := s.rewardStore.CreateRedemption(ctx, redemption); err != nil {
+		return nil, err
+	}
+	return redemption, nil
+}
+
+// ListRedemptions returns a household's redemptions, newest first. Admins see
+// everyone's; other members only their own.
+func (s *RewardService) ListRedemptions(ctx context.Context, userID, householdID string, status *models.RedemptionStatus) ([]*models.Redemption, error) {
+	member, err := s.member(ctx, householdID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := store.RedemptionFilter{Status: status}
+	if member.Role != models.HouseholdRoleAdmin {
+		filter.UserID = &userID
+	}
+	return s.rewardStore.GetRedemptions(ctx, householdID, filter)
+}
+
+// ApproveRedemption grants a pending redemption, debiting the member's points
+func (s *RewardService) ApproveRedemption(ctx context.Context, userID, householdID, redemptionID string, note *string) (*models.Redemption, error) {
+	if err := s.admin(ctx, householdID, userID); err != nil {
+		return nil, err
+	}
+
+	redemption, err := s.rewardStore.ApproveRedemption(ctx, householdID, redemptionID, userID, note)
+	if err != nil {
+		return nil, err
+	}
+	s.publishDecision(ctx, userID, ActivityRedemptionApproved, redemption)
+	return redemption, nil
+}
+
+// RejectRedemption declines a pending redemption; no points are spent
+func (s *RewardService) RejectRedemption(ctx context.Context, userID, householdID, redemptionID string, note *string) (*models.Redemption, error) {
+	if err := s.admin(ctx, householdID, userID); err != nil {
+		return nil, err
+	}
+
+	redemption, err := s.rewardStore.RejectRedemption(ctx, householdID, redemptionID, userID, note)
+	if err != nil {
+		return nil, err
+	}
+	s.publishDecision(ctx, userID, ActivityRedemptionRejected, redemption)
+	return redemption, nil
+}
+
+// publishDecision publishes a household.activity event for a decided
+// redemption. The decision is committed already, so the event is best effort.
+func (s *RewardService) publishDecision(ctx context.Context, actorID, activity string, redemption *models.Redemption) {
+	if s.kafkaProducer == nil {
+		return
+	}
+
+	event := kafka.DomainEvent{
+		ID:          uuid.New().String(),
+		Type:        kafka.EventTypeHouseholdActivity,
+		HouseholdID: redemption.HouseholdID,
+		UserID:      actorID,
+		Data: map[string]interface{}{
+			"householdId":  redemption.HouseholdID,
+			"actorId":      actorID,
+			"activity":     activity,
+			"rewardId":     redemption.RewardID,
+			"redemptionId": redemption.ID,
+			"memberId":     redemption.UserID,
+			"cost":         redemption.Cost,
+		},
+	}
+	_ = kafka.NewEventPublisher(s.kafkaProducer).PublishDomainEvent(ctx, kafka.TopicHouseholds, event)
+}
+
+// member returns the caller's membership of a household
+func (s *RewardService) member(ctx context.Context, householdID, userID string) (*models.HouseholdMember, error) {
+	member, err := s.householdStore.GetMember(ctx, householdID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrNotHouseholdMember
+	}
+	return member, err
+}
+
+// admin checks the caller is an admin of the household
+func (s *RewardService) admin(ctx context.Context, householdID, userID string) error {
+	member, err := s.member(ctx, householdID, userID)
+	if err != nil {
+		return err
+	}
+	if member.Role != models.HouseholdRoleAdmin {
+		return ErrNotHouseholdAdmin
+	}
+	return nil
+}
+
+func validateReward(reward *models.Reward) error {
+	reward.Name = strings.TrimSpace(reward.Name)
+	if reward.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidReward)
+	}
+	if reward.Cost <= 0 {
+		return fmt.Errorf("%w: cost must be positive", ErrInvalidReward)
+	}
+	if reward.Stock != nil && *reward.Stock < 0 {
+		return fmt.Errorf("%w: stock cannot be negative", ErrInvalidReward)
+	}
+	if reward.CooldownHours < 0 {
+		return fmt.Errorf("%w: cooldown cannot be negative", ErrInvalidReward)
+	}
+	return nil
+}
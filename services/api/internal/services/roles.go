@@ -0,0 +1,39 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/yakirshlomo/house-helper/services/api/internal/store"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
+)
+
+// ErrRoleNotAllowed is returned when the caller's household role lacks the
+// capability an action needs
+var ErrRoleNotAllowed = errors.New("household role not allowed")
+
+// RoleService checks what members' household roles allow them to do
+type RoleService struct {
+	householdStore store.HouseholdStore
+}
+
+// NewRoleService creates a new role service
+func NewRoleService(householdStore store.HouseholdStore) *RoleService {
+	return &RoleService{householdStore: householdStore}
+}
+
+// Require checks the caller is a member of the household whose role has the
+// capability
+func (s *RoleService) Require(ctx context.Context, householdID, userID string, capability models.Capability) error {
+	member, err := s.householdStore.GetMember(ctx, householdID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrNotHouseholdMember
+	}
+	if err != nil {
+		return err
+	}
+	if !member.Role.Can(capability) {
+		return ErrRoleNotAllowed
+	}
+	return nil
+}
@@ -13,10 +13,18 @@ import (
 type Services struct {
 	Auth         *AuthService
 	Task         *TaskService
+	Role         *RoleService
 	Shopping     *ShoppingService
 	Bill         *BillService
 	Timer        *TimerService
 	Notification *NotificationService
+	Webhook      *WebhookService
+	Away         *AwayService
+	Assignment   *AssignmentService
+	Points       *PointsService
+	Reward       *RewardService
+	Allowance    *AllowanceService
+	Household    *HouseholdService
 }
 
 // AuthService handles authentication and user management
@@ -40,17 +48,21 @@ func (s *AuthService) GetUserByID(userID string) (*models.User, error) {
 
 // TaskService handles task operations
 type TaskService struct {
-	taskStore     store.TaskStore
-	eventLog      store.EventLogStore
-	kafkaProducer *kafka.Producer
+	taskStore      store.TaskStore
+	householdStore store.HouseholdStore
+	eventLog       store.EventLogStore
+	kafkaProducer  *kafka.Producer
+	temporalClient *temporal.Client
 }
 
 // NewTaskService creates a new task service
-func NewTaskService(taskStore store.TaskStore, eventLog store.EventLogStore, kafkaProducer *kafka.Producer) *TaskService {
+func NewTaskService(taskStore store.TaskStore, householdStore store.HouseholdStore, eventLog store.EventLogStore, kafkaProducer *kafka.Producer, temporalClient *temporal.Client) *TaskService {
 	return &TaskService{
-		taskStore:     taskStore,
-		eventLog:      eventLog,
-		kafkaProducer: kafkaProducer,
+		taskStore:      taskStore,
+		householdStore: householdStore,
+		eventLog:       eventLog,
+		kafkaProducer:  kafkaProducer,
+		temporalClient: temporalClient,
 	}
 }
 
@@ -72,17 +84,21 @@ func NewShoppingService(shoppingStore store.ShoppingStore, eventLog store.EventL
 
 // BillService handles bill operations
 type BillService struct {
-	billStore     store.BillStore
-	eventLog      store.EventLogStore
-	kafkaProducer *kafka.Producer
+	billStore      store.BillStore
+	householdStore store.HouseholdStore
+	eventLog       store.EventLogStore
+	kafkaProducer  *kafka.Producer
+	temporalClient *temporal.Client
 }
 
 // NewBillService creates a new bill service
-func NewBillService(billStore store.BillStore, eventLog store.EventLogStore, kafkaProducer *kafka.Producer) *BillService {
+func NewBillService(billStore store.BillStore, householdStore store.HouseholdStore, eventLog store.EventLogStore, kafkaProducer *kafka.Producer, temporalClient *temporal.Client) *BillService {
 	return &BillService{
-		billStore:     billStore,
-		eventLog:      eventLog,
-		kafkaProducer: kafkaProducer,
+		billStore:      billStore,
+		householdStore: householdStore,
+		eventLog:       eventLog,
+		kafkaProducer:  kafkaProducer,
+		temporalClient: temporalClient,
 	}
 }
 
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"github.com/yakirshlomo/house-helper/services/api/internal/store"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/kafka"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
+)
+
+// ErrTaskNotPendingReview is returned when reviewing a task that is not
+// waiting for review
+var ErrTaskNotPendingReview = errors.New("task is not pending review")
+
+// ErrTaskClosed is returned when completing a task that is completed or
+// cancelled already
+var ErrTaskClosed = errors.New("task is completed or cancelled")
+
+// ErrTaskAssigneeNotMember is returned when a task is assigned to someone
+// outside its household
+var ErrTaskAssigneeNotMember = errors.New("task assignee is not a member of the household")
+
+// Task activities published as household.activity events
+const (
+	ActivityTaskSubmittedForReview = "task_submitted_for_review"
+	ActivityTaskReviewRejected     = "task_review_rejected"
+)
+
+// taskReviewSignal tells a task's reminder workflow the task was submitted
+// for review, or rejected back to its assignee
+type taskReviewSignal struct {
+	Status     string  `json:"status"` // pending_review or rejected
+	ReviewedBy string  `json:"reviewedBy,omitempty"`
+	Note       *string `json:"note,omitempty"`
+}
+
+// taskReminderWorkflowID returns the ID of the reminder workflow of a
+// recurring task's occurrence
+func taskReminderWorkflowID(occurrenceID string) string {
+	return "task-reminders-" + occurrenceID
+}
+
+// CreateTask creates a pending household task, assigned to the caller
+// unless it names an assignee, with its point value and modifiers
+func (s *TaskService) CreateTask(ctx context.Context, userID, householdID string, task *models.Task) error {
+	if err := s.requireMember(ctx, householdID, userID); err != nil {
+		return err
+	}
+
+	if task.AssignedTo != nil {
+		if err := s.checkAssignee(ctx, householdID, *task.AssignedTo); err != nil {
+			return err
+		}
+	}
+
+	task.ID = uuid.New().String()
+	task.HouseholdID = householdID
+	task.CreatedBy = userID
+	task.Status = models.TaskStatusPending
+	if task.AssignedTo == nil {
+		task.AssignedTo = &userID
+	}
+	return s.taskStore.Create(ctx, task)
+}
+
+// UpdateTask replaces the editable fields of a household task with those of
+// changes. An empty category, priority or assignee, and nil points, keep
+// the task's.
+func (s *TaskService) UpdateTask(ctx context.Context, userID, householdID, taskID string, changes *models.Task, points *int) (*models.Task, error) {
+	if err := s.requireMember(ctx, householdID, userID); err != nil {
+		return nil, err
+	}
+	task, err := s.getHouseholdTask(ctx, householdID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if changes.AssignedTo != nil {
+		if err := s.checkAssignee(ctx, householdID, *changes.AssignedTo); err != nil {
+			return nil, err
+		}
+		task.AssignedTo = changes.AssignedTo
+	}
+
+	task.Title = changes.Title
+	task.Description = changes.Description
+	task.DueDate = changes.DueDate
+	task.EarlyModifier = changes.EarlyModifier
+	task.OverdueModifier = changes.OverdueModifier
+	if changes.Category != "" {
+		task.Category = changes.Category
+	}
+	if changes.Priority != "" {
+		task.Priority = changes.Priority
+	}
+	if points != nil {
+		task.Points = *points
+	}
+
+	if err := s.taskStore.Update(ctx, task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// CompleteTask marks a household task completed by the caller, attaching
+// any photos of the finished task. Tasks completed by children wait in
+// pending_review for an admin, and earn no points until approved.
+func (s *TaskService) CompleteTask(ctx context.Context, userID, householdID, taskID string, attachmentURLs []string) (*models.Task, error) {
+	member, err := s.householdStore.GetMember(ctx, householdID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrNotHouseholdMember
+	}
+	if err != nil {
+		return nil, err
+	}
+	task, err := s.getHouseholdTask(ctx, householdID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	needsReview := member.Role.NeedsReview()
+	err = s.taskStore.MarkComplete(ctx, taskID, userID, needsReview, attachmentURLs)
+	if errors.Is(err, store.ErrConflict) {
+		return nil, ErrTaskClosed
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if needsReview {
+		s.signalReminders(ctx, task, "task_review", taskReviewSignal{Status: string(models.TaskStatusPendingReview)})
+		s.publish(ctx, kafka.TopicHouseholds, kafka.DomainEvent{
+			Type:        kafka.EventTypeHouseholdActivity,
+			HouseholdID: householdID,
+			UserID:      userID,
+			Data: map[string]interface{}{
+				"householdId": householdID,
+				"actorId":     userID,
+				"activity":    ActivityTaskSubmittedForReview,
+				"taskId":      taskID,
+			},
+		})
+	} else {
+		s.signalReminders(ctx, task, "task_completed", nil)
+		s.publishCompleted(ctx, householdID, taskID, userID)
+	}
+
+	return s.taskStore.GetByID(ctx, taskID)
+}
+
+// ReviewTask approves a task a child completed, which completes it and
+// credits the child with its points, or rejects it back to pending so the
+// child is reminded again. Only household admins review tasks.
+func (s *TaskService) ReviewTask(ctx context.Context, userID, householdID, taskID string, approved bool, note *string) (*models.Task, error) {
+	member, err := s.householdStore.GetMember(ctx, householdID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrNotHouseholdMember
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !member.Role.Can(models.CapabilityReviewTasks) {
+		return nil, ErrNotHouseholdAdmin
+	}
+
+	task, err := s.getHouseholdTask(ctx, householdID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	err = s.taskStore.ReviewTask(ctx, taskID, userID, approved, note)
+	if errors.Is(err, store.ErrConflict) {
+		return nil, ErrTaskNotPendingReview
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if approved {
+		s.signalReminders(ctx, task, "task_completed", nil)
+		if task.CompletedBy != nil {
+			s.publishCompleted(ctx, householdID, taskID, *task.CompletedBy)
+		}
+	} else {
+		s.signalReminders(ctx, task, "task_review", taskReviewSignal{Status: "rejected", ReviewedBy: userID, Note: note})
+		data := map[string]interface{}{
+			"householdId": householdID,
+			"actorId":     userID,
+			"activity":    ActivityTaskReviewRejected,
+			"taskId":      taskID,
+		}
+		if task.CompletedBy != nil {
+			data["memberId"] = *task.CompletedBy
+		}
+		s.publish(ctx, kafka.TopicHouseholds, kafka.DomainEvent{
+			Type:        kafka.EventTypeHouseholdActivity,
+			HouseholdID: householdID,
+			UserID:      userID,
+			Data:        data,
+		})
+	}
+
+	return s.taskStore.GetByID(ctx, taskID)
+}
+
+// requireMember checks the caller belongs to the household
+func (s *TaskService) requireMember(ctx context.Context, householdID, userID string) error {
+	isMember, err := s.householdStore.IsMember(ctx, householdID, userID)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return ErrNotHouseholdMember
+	}
+	return nil
+}
+
+// checkAssignee checks a task is assigned to a member of its household
+func (s *TaskService) checkAssignee(ctx context.Context, householdID, assignedTo string) error {
+	isMember, err := s.householdStore.IsMember(ctx, householdID, assignedTo)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return ErrTaskAssigneeNotMember
+	}
+	return nil
+}
+
+// getHouseholdTask returns a task of the household
+func (s *TaskService) getHouseholdTask(ctx context.Context, householdID, taskID string) (*models.Task, error) {
+	task, err := s.taskStore.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if task.HouseholdID != householdID {
+		return nil, store.ErrNotFound
+	}
+	return task, nil
+}
+
+// signalReminders signals the reminder workflow of a recurring task's
+// occurrence. The workflow also checks the task's status before every
+// reminder, so a signal that is not delivered, or a workflow that already
+// finished, does not fail the change.
+func (s *TaskService) signalReminders(ctx context.Context, task *models.Task, signal string, arg interface{}) {
+	if s.temporalClient == nil || task.OccurrenceID == nil {
+		return
+	}
+	_ = s.temporalClient.SignalWorkflow(ctx, taskReminderWorkflowID(*task.OccurrenceID), "", signal, arg)
+}
+
+// publishCompleted publishes the task.completed event that credits the
+// member who completed the task with its points
+func (s *TaskService) publishCompleted(ctx context.Context, householdID, taskID, completedBy string) {
+	s.publish(ctx, kafka.TopicTasks, kafka.DomainEvent{
+		Type:        kafka.EventTypeTaskCompleted,
+		HouseholdID: householdID,
+		UserID:      completedBy,
+		Data: map[string]interface{}{
+			"taskId":      taskID,
+			"householdId": householdID,
+			"status":      string(models.TaskStatusCompleted),
+			"completedBy": completedBy,
+		},
+	})
+}
+
+// publish publishes an event on a best effort basis; the change it reports
+// is committed already
+func (s *TaskService) publish(ctx context.Context, topic string, event kafka.DomainEvent) {
+	if s.kafkaProducer == nil {
+		return
+	}
+	event.ID = uuid.New().String()
+	_ = kafka.NewEventPublisher(s.kafkaProducer).PublishDomainEvent(ctx, topic, event)
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/yakirshlomo/house-helper/services/api/internal/store"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
+)
+
+// fakeTaskStore is an in-memory TaskStore for the task methods under test
+type fakeTaskStore struct {
+	store.TaskStore
+	tasks map[string]*models.Task
+}
+
+func newFakeTaskStore() *fakeTaskStore {
+	return &fakeTaskStore{tasks: make(map[string]*models.Task)}
+}
+
+func (s *fakeTaskStore) Create(ctx context.Context, task *models.Task) error {
+	stored := *task
+	s.tasks[task.ID] = &stored
+	return nil
+}
+
+func (s *fakeTaskStore) GetByID(ctx context.Context, id string) (*models.Task, error) {
+	task, ok := s.tasks[id]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	found := *task
+	return &found, nil
+}
+
+func (s *fakeTaskStore) Update(ctx context.Context, task *models.Task) error {
+	if _, ok := s.tasks[task.ID]; !ok {
+		return store.ErrNotFound
+	}
+	stored := *task
+	s.tasks[task.ID] = &stored
+	return nil
+}
+
+func TestCreateAndUpdateTaskPersistPoints(t *testing.T) {
+	ctx := context.Background()
+	households := newFakeHouseholdStore()
+	households.addMember("household-1", "user-1", models.HouseholdRoleAdmin)
+	households.addMember("household-1", "user-2", models.HouseholdRoleMember)
+	tasks := newFakeTaskStore()
+	s := &TaskService{taskStore: tasks, householdStore: households}
+
+	early, overdue := 1.5, 0.5
+	task := &models.Task{Title: "Dishes", Category: "kitchen", Priority: models.PriorityMedium, Points: 20, EarlyModifier: &early, OverdueModifier: &overdue}
+	if err := s.CreateTask(ctx, "user-1", "household-1", task); err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+
+	stored := tasks.tasks[task.ID]
+	if stored == nil {
+		t.Fatal("CreateTask() did not store the task")
+	}
+	if stored.Points != 20 || *stored.EarlyModifier != 1.5 || *stored.OverdueModifier != 0.5 {
+		t.Errorf("stored points = %d, modifiers = %v/%v, want 20, 1.5/0.5", stored.Points, *stored.EarlyModifier, *stored.OverdueModifier)
+	}
+	if stored.AssignedTo == nil || *stored.AssignedTo != "user-1" || stored.Status != models.TaskStatusPending {
+		t.Errorf("stored assignee = %v, status = %s, want the creator and pending", stored.AssignedTo, stored.Status)
+	}
+
+	points := 5
+	updated, err := s.UpdateTask(ctx, "user-2", "household-1", task.ID, &models.Task{Title: "Dishes and counters"}, &points)
+	if err != nil {
+		t.Fatalf("UpdateTask() error = %v", err)
+	}
+	if updated.Points != 5 || updated.Category != "kitchen" || updated.EarlyModifier != nil {
+		t.Errorf("updated points = %d, category = %q, early modifier = %v, want 5, kitchen, nil", updated.Points, updated.Category, updated.EarlyModifier)
+	}
+	if tasks.tasks[task.ID].Title != "Dishes and counters" {
+		t.Errorf("stored title = %q, want the updated title", tasks.tasks[task.ID].Title)
+	}
+}
+
+func TestTaskAccessAndAssignee(t *testing.T) {
+	ctx := context.Background()
+	households := newFakeHouseholdStore()
+	households.addMember("household-1", "user-1", models.HouseholdRoleAdmin)
+	s := &TaskService{taskStore: newFakeTaskStore(), householdStore: households}
+
+	if err := s.CreateTask(ctx, "user-2", "household-1", &models.Task{Title: "Dishes"}); !errors.Is(err, ErrNotHouseholdMember) {
+		t.Errorf("CreateTask() by a non-member error = %v, want ErrNotHouseholdMember", err)
+	}
+
+	stranger := "user-2"
+	if err := s.CreateTask(ctx, "user-1", "household-1", &models.Task{Title: "Dishes", AssignedTo: &stranger}); !errors.Is(err, ErrTaskAssigneeNotMember) {
+		t.Errorf("CreateTask() assigned to a non-member error = %v, want ErrTaskAssigneeNotMember", err)
+	}
+
+	if _, err := s.UpdateTask(ctx, "user-1", "household-1", "missing", &models.Task{Title: "Dishes"}, nil); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("UpdateTask() of a missing task error = %v, want store.ErrNotFound", err)
+	}
+}
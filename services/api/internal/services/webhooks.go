@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/yakirshlomo/house-helper/services/api/internal/store"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
+)
+
+var (
+	// ErrNotHouseholdMember is returned when the user does not belong to the household
+	ErrNotHouseholdMember = errors.New("not a member of the household")
+
+	// ErrInvalidWebhook is returned for an unusable webhook URL or event type
+	ErrInvalidWebhook = errors.New("invalid webhook")
+)
+
+// WebhookService manages the webhook endpoints of households
+type WebhookService struct {
+	webhookStore   store.WebhookStore
+	householdStore store.HouseholdStore
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService(webhookStore store.WebhookStore, householdStore store.HouseholdStore) *WebhookService {
+	return &WebhookService{
+		webhookStore:   webhookStore,
+		householdStore: householdStore,
+	}
+}
+
+// CreateWebhook registers an endpoint and returns it with its signing secret,
+// which is not returned again. Only household admins may manage endpoints.
+func (s *WebhookService) CreateWebhook(ctx context.Context, userID, householdID, endpointURL string, description *string, eventTypes []string) (*models.WebhookEndpoint, error) {
+	if err := s.requireAdmin(ctx, householdID, userID); err != nil {
+		return nil, err
+	}
+	if err := validateWebhookURL(endpointURL); err != nil {
+		return nil, err
+	}
+	if err := validateWebhookEventTypes(eventTypes); err != nil {
+		return nil, err
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := &models.WebhookEndpoint{
+		ID:          uuid.New().String(),
+		HouseholdID: householdID,
+		URL:         endpointURL,
+		Description: description,
+		Secret:      secret,
+		EventTypes:  eventTypes,
+		CreatedBy:   userID,
+	}
+	if err := s.webhookStore.Create(ctx, endpoint); err != nil {
+		return nil, err
+	}
+
+	return endpoint, nil
+}
+
+// ListWebhooks returns the endpoints of a household
+func (s *WebhookService) ListWebhooks(ctx context.Context, userID, householdID string) ([]*models.WebhookEndpoint, error) {
+	if err := s.requireMember(ctx, householdID, userID); err != nil {
+		return nil, err
+	}
+	return s.webhookStore.GetByHouseholdID(ctx, householdID)
+}
+
+// DeleteWebhook removes an endpoint; no further events are delivered to it
+func (s *WebhookService) DeleteWebhook(ctx context.Context, userID, householdID, id string) error {
+	if err := s.requireAdmin(ctx, householdID, userID); err != nil {
+		return err
+	}
+	return s.webhookStore.Delete(ctx, householdID, id)
+}
+
+// EnableWebhook reactivates an endpoint that was disabled after repeated
+// failed deliveries
+func (s *WebhookService) EnableWebhook(ctx context.Context, userID, householdID, id string) (*models.WebhookEndpoint, error) {
+	if err := s.requireAdmin(ctx, householdID, userID); err != nil {
+		return nil, err
+	}
+	if err := s.webhookStore.Enable(ctx, householdID, id); err != nil {
+		return nil, err
+	}
+	return s.webhookStore.GetByID(ctx, householdID, id)
+}
+
+// ListDeliveries returns the latest delivery attempts of an endpoint
+func (s *WebhookService) ListDeliveries(ctx context.Context, userID, householdID, id string, limit int) ([]*models.WebhookDelivery, error) {
+	if err := s.requireMember(ctx, householdID, userID); err != nil {
+		return nil, err
+	}
+	if _, err := s.webhookStore.GetByID(ctx, householdID, id); err != nil {
+		return nil, err
+	}
+	return s.webhookStore.GetDeliveries(ctx, id, limit)
+}
+
+func (s *WebhookService) requireMember(ctx context.Context, householdID, userID string) error {
+	isMember, err := s.householdStore.IsMember(ctx, householdID, userID)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return ErrNotHouseholdMember
+	}
+	return nil
+}
+
+func (s *WebhookService) requireAdmin(ctx context.Context, householdID, userID string) error {
+	member, err := s.householdStore.GetMember(ctx, householdID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrNotHouseholdMember
+	}
+	if err != nil {
+		return err
+	}
+	if member.Role != models.HouseholdRoleAdmin {
+		return ErrNotHouseholdAdmin
+	}
+	return nil
+}
+
+// validateWebhookURL rejects URLs that are not https or name a host that is
+// plainly not public. The worker checks the address it dials too, since a
+// host name can resolve to anything.
+func validateWebhookURL(endpointURL string) error {
+	u, err := url.Parse(endpointURL)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return fmt.Errorf("%w: url must be an absolute https URL", ErrInvalidWebhook)
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || !strings.Contains(host, ".") {
+		return fmt.Errorf("%w: url must name a public host", ErrInvalidWebhook)
+	}
+	if ip := net.ParseIP(host); ip != nil && (ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast()) {
+		return fmt.Errorf("%w: url must name a public host", ErrInvalidWebhook)
+	}
+	return nil
+}
+
+func validateWebhookEventTypes(eventTypes []string) error {
+	if len(eventTypes) == 0 {
+		return fmt.Errorf("%w: at least one event type is required", ErrInvalidWebhook)
+	}
+
+	known := make(map[string]bool, len(models.WebhookEventTypes))
+	for _, eventType := range models.WebhookEventTypes {
+		known[eventType] = true
+	}
+	for _, eventType := range eventTypes {
+		if !known[eventType] {
+			return fmt.Errorf("%w: unknown event type %q", ErrInvalidWebhook, eventType)
+		}
+	}
+	return nil
+}
+
+// newWebhookSecret returns a random secret deliveries are signed with
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
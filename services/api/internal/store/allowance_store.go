@@ -0,0 +1,239 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
+)
+
+// AllowanceStore keeps children's allowance settings and the append-only
+// allowance ledger of weekly payouts, and reads the tasks and missed chores
+// statements are made of
+type AllowanceStore interface {
+	GetSettings(ctx context.Context, householdID string, userID string) (*models.AllowanceSettings, error)
+	UpsertSettings(ctx context.Context, settings *models.AllowanceSettings) error
+
+	// GetCompletedByCategory counts the tasks a member completed, or that
+	// were completed for them, between from and to by category
+	GetCompletedByCategory(ctx context.Context, householdID string, userID string, from, to time.Time) ([]*models.AllowanceBonus, error)
+
+	// GetMissedChores returns the chores detected missed by a member between
+	// from and to, oldest first
+	GetMissedChores(ctx context.Context, householdID string, userID string, from, to time.Time) ([]*models.MissedChore, error)
+
+	// CreatePayout appends a payout to the ledger; a week that was paid
+	// already is a conflict
+	CreatePayout(ctx context.Context, payout *models.AllowancePayout) error
+	GetPayout(ctx context.Context, householdID string, userID string, weekStart time.Time) (*models.AllowancePayout, error)
+	GetPayouts(ctx context.Context, householdID string, filter AllowancePayoutFilter) ([]*models.AllowancePayout, error)
+}
+
+type AllowancePayoutFilter struct {
+	UserID *string    `json:"userId,omitempty"`
+	From   *time.Time `json:"from,omitempty"` // Weeks starting on or after
+	To     *time.Time `json:"to,omitempty"`   // Weeks starting before
+}
+
+type allowanceStore struct {
+	db *sqlx.DB
+}
+
+func NewAllowanceStore(db *sqlx.DB) AllowanceStore {
+	return &allowanceStore{db: db}
+}
+
+const payoutColumns = `
+	l.id, l.household_id, l.user_id, u.first_name, u.last_name, l.week_start,
+	l.base_amount, l.bonus_amount, l.deduction_amount, l.amount, l.currency,
+	l.completed_tasks, l.missed_chores, l.paid_by, l.note, l.created_at
+`
+
+// weekDate is the DATE a week starting at weekStart is stored under
+func weekDate(weekStart time.Time) string {
+	return weekStart.Format("2006-01-02")
+}
+
+func (s *allowanceStore) GetSettings(ctx context.Context, householdID string, userID string) (*models.AllowanceSettings, error) {
+	query := `
+		SELECT household_id, user_id, base_amount, category_bonuses, missed_chore_deduction,
+		       updated_by, created_at, updated_at
+		FROM allowance_settings
+		WHERE household_id = $1 AND user_id = $2
+	`
+
+	var settings models.AllowanceSettings
+	var bonuses []byte
+	err := s.db.QueryRowContext(ctx, query, householdID, userID).Scan(
+		&settings.HouseholdID, &settings.UserID, &settings.BaseAmount, &bonuses,
+		&settings.MissedChoreDeduction, &settings.UpdatedBy, &settings.CreatedAt, &settings.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get allowance settings: %w", err)
+	}
+	if err := json.Unmarshal(bonuses, &settings.CategoryBonuses); err != nil {
+		return nil, fmt.Errorf("failed to decode category bonuses: %w", err)
+	}
+
+	return &settings, nil
+}
+
+func (s *allowanceStore) UpsertSettings(ctx context.Context, settings *models.AllowanceSettings) error {
+	if settings.CategoryBonuses == nil {
+		settings.CategoryBonuses = map[string]float64{}
+	}
+	bonuses, err := json.Marshal(settings.CategoryBonuses)
+	if err != nil {
+		return fmt.Errorf("failed to encode category bonuses: %w", err)
+	}
+
+	query := `
+		INSERT INTO allowance_settings (
+			household_id, user_id, base_amount, category_bonuses, missed_chore_deduction, updated_by
+		) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (household_id, user_id) DO UPDATE
+		SET base_amount = EXCLUDED.base_amount,
+		    category_bonuses = EXCLUDED.category_bonuses,
+		    missed_chore_deduction = EXCLUDED.missed_chore_deduction,
+		    updated_by = EXCLUDED.updated_by
+		RETURNING created_at, updated_at
+	`
+
+	err = s.db.QueryRowContext(ctx, query,
+		settings.HouseholdID, settings.UserID, settings.BaseAmount, bonuses,
+		settings.MissedChoreDeduction, settings.UpdatedBy,
+	).Scan(&settings.CreatedAt, &settings.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save allowance settings: %w", err)
+	}
+
+	return nil
+}
+
+func (s *allowanceStore) GetCompletedByCategory(ctx context.Context, householdID string, userID string, from, to time.Time) ([]*models.AllowanceBonus, error) {
+	query := `
+		SELECT category, COUNT(*) AS tasks
+		FROM tasks
+		WHERE household_id = $1 AND COALESCE(completed_by, assigned_to) = $2
+			AND status = $3 AND completed_at >= $4 AND completed_at < $5
+			AND deleted_at IS NULL
+		GROUP BY category
+		ORDER BY category ASC
+	`
+
+	var bonuses []*models.AllowanceBonus
+	err := s.db.SelectContext(ctx, &bonuses, query, householdID, userID, models.TaskStatusCompleted, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to count completed tasks: %w", err)
+	}
+
+	return bonuses, nil
+}
+
+func (s *allowanceStore) GetMissedChores(ctx context.Context, householdID string, userID string, from, to time.Time) ([]*models.MissedChore, error) {
+	query := `
+		SELECT id, household_id, user_id, task_id, occurrence_id, title, category,
+		       due_date, reminders, detected_at
+		FROM missed_chores
+		WHERE household_id = $1 AND user_id = $2 AND detected_at >= $3 AND detected_at < $4
+		ORDER BY detected_at ASC
+	`
+
+	var chores []*models.MissedChore
+	err := s.db.SelectContext(ctx, &chores, query, householdID, userID, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get missed chores: %w", err)
+	}
+
+	return chores, nil
+}
+
+func (s *allowanceStore) CreatePayout(ctx context.Context, payout *models.AllowancePayout) error {
+	query := `
+		INSERT INTO allowance_ledger (
+			id, household_id, user_id, week_start, base_amount, bonus_amount,
+			deduction_amount, amount, currency, completed_tasks, missed_chores,
+			paid_by, note
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (household_id, user_id, week_start) DO NOTHING
+		RETURNING created_at
+	`
+
+	err := s.db.QueryRowContext(ctx, query,
+		payout.ID, payout.HouseholdID, payout.UserID, weekDate(payout.WeekStart),
+		payout.BaseAmount, payout.BonusAmount, payout.DeductionAmount, payout.Amount,
+		payout.Currency, payout.CompletedTasks, payout.MissedChores, payout.PaidBy, payout.Note,
+	).Scan(&payout.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrConflict
+		}
+		return fmt.Errorf("failed to record allowance payout: %w", err)
+	}
+
+	return nil
+}
+
+func (s *allowanceStore) GetPayout(ctx context.Context, householdID string, userID string, weekStart time.Time) (*models.AllowancePayout, error) {
+	query := `
+		SELECT ` + payoutColumns + `
+		FROM allowance_ledger l
+		JOIN users u ON u.id = l.user_id
+		WHERE l.household_id = $1 AND l.user_id = $2 AND l.week_start = $3
+	`
+
+	var payout models.AllowancePayout
+	err := s.db.GetContext(ctx, &payout, query, householdID, userID, weekDate(weekStart))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get allowance payout: %w", err)
+	}
+
+	return &payout, nil
+}
+
+func (s *allowanceStore) GetPayouts(ctx context.Context, householdID string, filter AllowancePayoutFilter) ([]*models.AllowancePayout, error) {
+	query := `
+		SELECT ` + payoutColumns + `
+		FROM allowance_ledger l
+		JOIN users u ON u.id = l.user_id
+		WHERE l.household_id = $1
+	`
+	args := []interface{}{householdID}
+	argCount := 1
+
+	if filter.UserID != nil {
+		argCount++
+		query += fmt.Sprintf(" AND l.user_id = $%d", argCount)
+		args = append(args, *filter.UserID)
+	}
+	if filter.From != nil {
+		argCount++
+		query += fmt.Sprintf(" AND l.week_start >= $%d", argCount)
+		args = append(args, weekDate(*filter.From))
+	}
+	if filter.To != nil {
+		argCount++
+		query += fmt.Sprintf(" AND l.week_start < $%d", argCount)
+		args = append(args, weekDate(*filter.To))
+	}
+
+	query += " ORDER BY l.week_start DESC, u.first_name ASC"
+
+	var payouts []*models.AllowancePayout
+	err := s.db.SelectContext(ctx, &payouts, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get allowance payouts: %w", err)
+	}
+
+	return payouts, nil
+}
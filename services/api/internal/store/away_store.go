@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
+)
+
+type AwayStore interface {
+	Create(ctx context.Context, period *models.AwayPeriod) error
+	GetByID(ctx context.Context, householdID string, id string) (*models.AwayPeriod, error)
+
+	// GetByHouseholdID returns the away periods of a household that have not
+	// ended by the given time
+	GetByHouseholdID(ctx context.Context, householdID string, after time.Time) ([]*models.AwayPeriod, error)
+	Delete(ctx context.Context, householdID string, id string) error
+}
+
+type awayStore struct {
+	db *sqlx.DB
+}
+
+func NewAwayStore(db *sqlx.DB) AwayStore {
+	return &awayStore{db: db}
+}
+
+func (s *awayStore) Create(ctx context.Context, period *models.AwayPeriod) error {
+	query := `
+		INSERT INTO away_periods (
+			id, household_id, user_id, starts_at, ends_at, note,
+			created_by, created_at, updated_at
+		) VALUES (
+			:id, :household_id, :user_id, :starts_at, :ends_at, :note,
+			:created_by, :created_at, :updated_at
+		)
+	`
+
+	period.CreatedAt = time.Now()
+	period.UpdatedAt = time.Now()
+
+	_, err := s.db.NamedExecContext(ctx, query, period)
+	if err != nil {
+		return fmt.Errorf("failed to create away period: %w", err)
+	}
+
+	return nil
+}
+
+func (s *awayStore) GetByID(ctx context.Context, householdID string, id string) (*models.AwayPeriod, error) {
+	query := `
+		SELECT
+			id, household_id, user_id, starts_at, ends_at, note,
+			created_by, created_at, updated_at
+		FROM away_periods
+		WHERE id = $1 AND household_id = $2 AND deleted_at IS NULL
+	`
+
+	var period models.AwayPeriod
+	err := s.db.GetContext(ctx, &period, query, id, householdID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get away period: %w", err)
+	}
+
+	return &period, nil
+}
+
+func (s *awayStore) GetByHouseholdID(ctx context.Context, householdID string, after time.Time) ([]*models.AwayPeriod, error) {
+	query := `
+		SELECT
+			id, household_id, user_id, starts_at, ends_at, note,
+			created_by, created_at, updated_at
+		FROM away_periods
+		WHERE household_id = $1 AND ends_at > $2 AND deleted_at IS NULL
+		ORDER BY starts_at ASC
+	`
+
+	var periods []*models.AwayPeriod
+	err := s.db.SelectContext(ctx, &periods, query, householdID, after)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get away periods: %w", err)
+	}
+
+	return periods, nil
+}
+
+func (s *awayStore) Delete(ctx context.Context, householdID string, id string) error {
+	query := `
+		UPDATE away_periods
+		SET deleted_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND household_id = $2 AND deleted_at IS NULL
+	`
+
+	result, err := s.db.ExecContext(ctx, query, id, householdID)
+	if err != nil {
+		return fmt.Errorf("failed to delete away period: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -24,6 +24,14 @@ type BillStore interface {
 	AddPayment(ctx context.Context, payment *models.BillPayment) error
 	GetPayments(ctx context.Context, billID string) ([]*models.BillPayment, error)
 	DeletePayment(ctx context.Context, paymentID string) error
+
+	// Series operations
+	CreateSeries(ctx context.Context, series *models.BillSeries, first *models.Bill) error
+	GetSeries(ctx context.Context, id string) (*models.BillSeries, error)
+	GetHouseholdSeries(ctx context.Context, householdID string) ([]*models.BillSeries, error)
+	GetSeriesInstances(ctx context.Context, seriesID string) ([]*models.Bill, error)
+	UpdateSeriesFrom(ctx context.Context, series *models.BillSeries, fromSequence int) error
+	EndSeries(ctx context.Context, seriesID string, fromSequence int) error
 }
 
 type BillFilter struct {
@@ -44,27 +52,37 @@ func NewBillStore(db *sqlx.DB) BillStore {
 	return &billStore{db: db}
 }
 
-func (s *billStore) Create(ctx context.Context, bill *models.Bill) error {
-	query := `
-		INSERT INTO bills (
-			id, name, description, category, amount, currency,
-			due_date, is_recurring, recurrence_rule, status,
-			household_id, assigned_to, created_by, reminder_days,
-			auto_pay_enabled, payment_method, vendor_info,
-			attachment_urls, created_at, updated_at
-		) VALUES (
-			:id, :name, :description, :category, :amount, :currency,
-			:due_date, :is_recurring, :recurrence_rule, :status,
-			:household_id, :assigned_to, :created_by, :reminder_days,
-			:auto_pay_enabled, :payment_method, :vendor_info,
-			:attachment_urls, :created_at, :updated_at
-		)
-	`
-
+const insertBillQuery = `
+	INSERT INTO bills (
+		id, name, description, category, amount, currency,
+		due_date, is_recurring, recurrence_rule, status,
+		household_id, assigned_to, created_by, reminder_days,
+		auto_pay_enabled, payment_method, vendor_info,
+		attachment_urls, series_id, sequence, amount_type,
+		created_at, updated_at
+	) VALUES (
+		:id, :name, :description, :category, :amount, :currency,
+		:due_date, :is_recurring, :recurrence_rule, :status,
+		:household_id, :assigned_to, :created_by, :reminder_days,
+		:auto_pay_enabled, :payment_method, :vendor_info,
+		:attachment_urls, :series_id, :sequence, :amount_type,
+		:created_at, :updated_at
+	)
+`
+
+// prepareBill sets the timestamps and defaults of a new bill
+func prepareBill(bill *models.Bill) {
 	bill.CreatedAt = time.Now()
 	bill.UpdatedAt = time.Now()
+	if bill.AmountType == "" {
+		bill.AmountType = models.BillAmountFixed
+	}
+}
+
+func (s *billStore) Create(ctx context.Context, bill *models.Bill) error {
+	prepareBill(bill)
 
-	_, err := s.db.NamedExecContext(ctx, query, bill)
+	_, err := s.db.NamedExecContext(ctx, insertBillQuery, bill)
 	if err != nil {
 		return fmt.Errorf("failed to create bill: %w", err)
 	}
@@ -80,7 +98,7 @@ func (s *billStore) GetByID(ctx context.Context, id string) (*models.Bill, error
 			household_id, assigned_to, created_by, reminder_days,
 			auto_pay_enabled, payment_method, vendor_info,
 			attachment_urls, paid_at, paid_by, paid_amount,
-			created_at, updated_at
+			series_id, sequence, amount_type, created_at, updated_at
 		FROM bills 
 		WHERE id = $1 AND deleted_at IS NULL
 	`
@@ -105,7 +123,7 @@ func (s *billStore) GetUserBills(ctx context.Context, userID string, filter Bill
 			b.household_id, b.assigned_to, b.created_by, b.reminder_days,
 			b.auto_pay_enabled, b.payment_method, b.vendor_info,
 			b.attachment_urls, b.paid_at, b.paid_by, b.paid_amount,
-			b.created_at, b.updated_at
+			b.series_id, b.sequence, b.amount_type, b.created_at, b.updated_at
 		FROM bills b
 		JOIN households h ON b.household_id = h.id
 		JOIN household_members hm ON h.id = hm.household_id
@@ -189,6 +207,7 @@ func (s *billStore) Update(ctx context.Context, bill *models.Bill) error {
 			payment_method = :payment_method,
 			vendor_info = :vendor_info,
 			attachment_urls = :attachment_urls,
+			amount_type = :amount_type,
 			updated_at = :updated_at
 		WHERE id = :id AND deleted_at IS NULL
 	`
@@ -381,3 +400,211 @@ func (s *billStore) DeletePayment(ctx context.Context, paymentID string) error {
 
 	return nil
 }
+
+// Series operations
+
+const billSeriesColumns = `
+	id, household_id, name, description, category, amount_type, amount,
+	currency, recurrence_rule, assigned_to, reminder_days, created_by,
+	ended_at, created_at, updated_at
+`
+
+// CreateSeries creates a recurring bill series together with its first
+// instance
+func (s *billStore) CreateSeries(ctx context.Context, series *models.BillSeries, first *models.Bill) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	series.CreatedAt = time.Now()
+	series.UpdatedAt = time.Now()
+
+	query := `
+		INSERT INTO bill_series (
+			id, household_id, name, description, category, amount_type, amount,
+			currency, recurrence_rule, assigned_to, reminder_days, created_by,
+			created_at, updated_at
+		) VALUES (
+			:id, :household_id, :name, :description, :category, :amount_type, :amount,
+			:currency, :recurrence_rule, :assigned_to, :reminder_days, :created_by,
+			:created_at, :updated_at
+		)
+	`
+	if _, err := tx.NamedExecContext(ctx, query, series); err != nil {
+		return fmt.Errorf("failed to create bill series: %w", err)
+	}
+
+	sequence := 1
+	first.SeriesID = &series.ID
+	first.Sequence = &sequence
+	first.AmountType = series.AmountType
+	prepareBill(first)
+
+	if _, err := tx.NamedExecContext(ctx, insertBillQuery, first); err != nil {
+		return fmt.Errorf("failed to create bill: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (s *billStore) GetSeries(ctx context.Context, id string) (*models.BillSeries, error) {
+	query := `SELECT ` + billSeriesColumns + ` FROM bill_series WHERE id = $1 AND deleted_at IS NULL`
+
+	var series models.BillSeries
+	err := s.db.GetContext(ctx, &series, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get bill series: %w", err)
+	}
+
+	return &series, nil
+}
+
+// GetHouseholdSeries returns the series of a household that have not ended
+func (s *billStore) GetHouseholdSeries(ctx context.Context, householdID string) ([]*models.BillSeries, error) {
+	query := `
+		SELECT ` + billSeriesColumns + `
+		FROM bill_series
+		WHERE household_id = $1 AND ended_at IS NULL AND deleted_at IS NULL
+		ORDER BY name ASC
+	`
+
+	var series []*models.BillSeries
+	err := s.db.SelectContext(ctx, &series, query, householdID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get household bill series: %w", err)
+	}
+
+	return series, nil
+}
+
+// GetSeriesInstances returns the instances of a series in order
+func (s *billStore) GetSeriesInstances(ctx context.Context, seriesID string) ([]*models.Bill, error) {
+	query := `
+		SELECT 
+			id, name, description, category, amount, currency,
+			due_date, is_recurring, recurrence_rule, status,
+			household_id, assigned_to, created_by, reminder_days,
+			auto_pay_enabled, payment_method, vendor_info,
+			attachment_urls, paid_at, paid_by, paid_amount,
+			series_id, sequence, amount_type, created_at, updated_at
+		FROM bills
+		WHERE series_id = $1 AND deleted_at IS NULL
+		ORDER BY sequence ASC
+	`
+
+	var bills []*models.Bill
+	err := s.db.SelectContext(ctx, &bills, query, seriesID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bill series instances: %w", err)
+	}
+
+	return bills, nil
+}
+
+// UpdateSeriesFrom updates a series and its unpaid instances from
+// fromSequence on; later instances are created from the updated series. Due
+// dates are left alone, as each instance's follows from the one before.
+func (s *billStore) UpdateSeriesFrom(ctx context.Context, series *models.BillSeries, fromSequence int) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	series.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE bill_series SET
+			name = :name,
+			description = :description,
+			category = :category,
+			amount_type = :amount_type,
+			amount = :amount,
+			currency = :currency,
+			recurrence_rule = :recurrence_rule,
+			assigned_to = :assigned_to,
+			reminder_days = :reminder_days,
+			updated_at = :updated_at
+		WHERE id = :id AND ended_at IS NULL AND deleted_at IS NULL
+	`
+	result, err := tx.NamedExecContext(ctx, query, series)
+	if err != nil {
+		return fmt.Errorf("failed to update bill series: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	// Open amounts are set when an instance is paid
+	query = `
+		UPDATE bills SET
+			name = $3,
+			description = COALESCE($4, ''),
+			category = $5,
+			amount_type = $6,
+			amount = CASE WHEN $6 = 'open' THEN 0 ELSE COALESCE($7, amount) END,
+			currency = $8,
+			recurrence_rule = $9,
+			assigned_to = $10,
+			reminder_days = $11,
+			updated_at = NOW()
+		WHERE series_id = $1 AND sequence >= $2 AND deleted_at IS NULL
+			AND status IN ('pending', 'overdue')
+	`
+	_, err = tx.ExecContext(ctx, query, series.ID, fromSequence, series.Name, series.Description,
+		series.Category, series.AmountType, series.Amount, series.Currency, series.RecurrenceRule,
+		series.AssignedTo, series.ReminderDays)
+	if err != nil {
+		return fmt.Errorf("failed to update bill series instances: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// EndSeries stops a series from creating instances and deletes its unpaid
+// instances from fromSequence on
+func (s *billStore) EndSeries(ctx context.Context, seriesID string, fromSequence int) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE bill_series
+		SET ended_at = COALESCE(ended_at, NOW()), updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+	result, err := tx.ExecContext(ctx, query, seriesID)
+	if err != nil {
+		return fmt.Errorf("failed to end bill series: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	query = `
+		UPDATE bills
+		SET deleted_at = NOW(), updated_at = NOW()
+		WHERE series_id = $1 AND sequence >= $2 AND deleted_at IS NULL
+			AND status IN ('pending', 'overdue')
+	`
+	if _, err := tx.ExecContext(ctx, query, seriesID, fromSequence); err != nil {
+		return fmt.Errorf("failed to delete bill series instances: %w", err)
+	}
+
+	return tx.Commit()
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"time"
 )
 
@@ -22,6 +23,9 @@ type HouseholdStore interface {
 	UpdateMemberRole(ctx context.Context, householdID string, userID string, role models.HouseholdRole) error
 	GetMembers(ctx context.Context, householdID string) ([]*models.HouseholdMember, error)
 	IsMember(ctx context.Context, householdID string, userID string) (bool, error)
+	GetMember(ctx context.Context, householdID string, userID string) (*models.HouseholdMember, error)
+	GetAssignmentPreferences(ctx context.Context, householdID string, userID string) (*models.AssignmentPreferences, error)
+	UpdateAssignmentPreferences(ctx context.Context, prefs *models.AssignmentPreferences) error
 	
 	// Invitation operations
 	CreateInvitation(ctx context.Context, invitation *models.HouseholdInvitation) error
@@ -299,6 +303,84 @@ func (s *householdStore) IsMember(ctx context.Context, householdID string, userI
 }
 
 // Invitation operations
+// GetMember returns a current member of a household
+func (s *householdStore) GetMember(ctx context.Context, householdID string, userID string) (*models.HouseholdMember, error) {
+	query := `
+		SELECT id, household_id, user_id, role, joined_at, left_at, created_at, updated_at
+		FROM household_members
+		WHERE household_id = $1 AND user_id = $2 AND left_at IS NULL
+	`
+
+	var member models.HouseholdMember
+	err := s.db.GetContext(ctx, &member, query, householdID, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get household member: %w", err)
+	}
+
+	return &member, nil
+}
+
+// GetAssignmentPreferences returns the chore assignment preferences of a
+// current household member
+func (s *householdStore) GetAssignmentPreferences(ctx context.Context, householdID string, userID string) (*models.AssignmentPreferences, error) {
+	query := `
+		SELECT household_id, user_id, assignment_weight, date_of_birth,
+		       excluded_categories, preferred_categories
+		FROM household_members
+		WHERE household_id = $1 AND user_id = $2 AND left_at IS NULL
+	`
+
+	var prefs models.AssignmentPreferences
+	var excluded, preferred pq.StringArray
+	err := s.db.QueryRowContext(ctx, query, householdID, userID).Scan(
+		&prefs.HouseholdID, &prefs.UserID, &prefs.Weight, &prefs.DateOfBirth,
+		&excluded, &preferred,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get assignment preferences: %w", err)
+	}
+	prefs.ExcludedCategories = excluded
+	prefs.PreferredCategories = preferred
+
+	return &prefs, nil
+}
+
+// UpdateAssignmentPreferences replaces the chore assignment preferences of a
+// current household member
+func (s *householdStore) UpdateAssignmentPreferences(ctx context.Context, prefs *models.AssignmentPreferences) error {
+	query := `
+		UPDATE household_members
+		SET assignment_weight = $3, date_of_birth = $4,
+		    excluded_categories = $5, preferred_categories = $6, updated_at = NOW()
+		WHERE household_id = $1 AND user_id = $2 AND left_at IS NULL
+	`
+
+	result, err := s.db.ExecContext(ctx, query,
+		prefs.HouseholdID, prefs.UserID, prefs.Weight, prefs.DateOfBirth,
+		pq.StringArray(prefs.ExcludedCategories), pq.StringArray(prefs.PreferredCategories),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update assignment preferences: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
 func (s *householdStore) CreateInvitation(ctx context.Context, invitation *models.HouseholdInvitation) error {
 	query := `
 		INSERT INTO household_invitations (
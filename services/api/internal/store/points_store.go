@@ -0,0 +1,128 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
+)
+
+// PointsStore reads the points ledger, which the event consumer appends to
+// as tasks are completed
+type PointsStore interface {
+	// GetLeaderboard returns the points each current member of a household
+	// earned from completed tasks since the given time, highest first
+	GetLeaderboard(ctx context.Context, householdID string, since time.Time) ([]*models.LeaderboardEntry, error)
+	GetBalance(ctx context.Context, householdID string, userID string) (int, error)
+	GetStreak(ctx context.Context, householdID string, userID string) (models.PointsStreak, error)
+	GetEntries(ctx context.Context, householdID string, userID string, limit int) ([]*models.PointsEntry, error)
+}
+
+type pointsStore struct {
+	db *sqlx.DB
+}
+
+func NewPointsStore(db *sqlx.DB) PointsStore {
+	return &pointsStore{db: db}
+}
+
+func (s *pointsStore) GetLeaderboard(ctx context.Context, householdID string, since time.Time) ([]*models.LeaderboardEntry, error) {
+	query := `
+		SELECT
+			hm.user_id, u.first_name, u.last_name,
+			COALESCE(SUM(pl.points), 0) AS points,
+			COUNT(pl.id) AS completed_tasks,
+			COALESCE(ps.current_streak, 0) AS current_streak,
+			COALESCE(ps.longest_streak, 0) AS longest_streak,
+			ps.last_completed_on
+		FROM household_members hm
+		JOIN users u ON hm.user_id = u.id
+		LEFT JOIN points_ledger pl ON pl.household_id = hm.household_id AND pl.user_id = hm.user_id
+			AND pl.reason = $2 AND pl.occurred_at >= $3
+		LEFT JOIN point_streaks ps ON ps.household_id = hm.household_id AND ps.user_id = hm.user_id
+		WHERE hm.household_id = $1 AND hm.left_at IS NULL AND u.deleted_at IS NULL
+		GROUP BY hm.user_id, u.first_name, u.last_name, ps.current_streak, ps.longest_streak, ps.last_completed_on
+		ORDER BY points DESC, completed_tasks DESC, u.first_name ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, householdID, models.PointsReasonTaskCompleted, since.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leaderboard: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*models.LeaderboardEntry
+	for rows.Next() {
+		var entry models.LeaderboardEntry
+		err := rows.Scan(
+			&entry.UserID, &entry.FirstName, &entry.LastName,
+			&entry.Points, &entry.CompletedTasks,
+			&entry.Streak.Current, &entry.Streak.Longest, &entry.Streak.LastCompletedOn,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan leaderboard entry: %w", err)
+		}
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate leaderboard rows: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (s *pointsStore) GetBalance(ctx context.Context, householdID string, userID string) (int, error) {
+	query := `
+		SELECT COALESCE(SUM(points), 0)
+		FROM points_ledger
+		WHERE household_id = $1 AND user_id = $2
+	`
+
+	var balance int
+	err := s.db.GetContext(ctx, &balance, query, householdID, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get points balance: %w", err)
+	}
+
+	return balance, nil
+}
+
+func (s *pointsStore) GetStreak(ctx context.Context, householdID string, userID string) (models.PointsStreak, error) {
+	query := `
+		SELECT current_streak, longest_streak, last_completed_on
+		FROM point_streaks
+		WHERE household_id = $1 AND user_id = $2
+	`
+
+	var streak models.PointsStreak
+	err := s.db.GetContext(ctx, &streak, query, householdID, userID)
+	if err != nil && err != sql.ErrNoRows {
+		return streak, fmt.Errorf("failed to get streak: %w", err)
+	}
+
+	return streak, nil
+}
+
+func (s *pointsStore) GetEntries(ctx context.Context, householdID string, userID string, limit int) ([]*models.PointsEntry, error) {
+	query := `
+		SELECT
+			id, household_id, user_id, points, reason, task_id, modifier,
+			redemption_id, occurred_at, created_at
+		FROM points_ledger
+		WHERE household_id = $1 AND user_id = $2
+		ORDER BY occurred_at DESC, created_at DESC
+		LIMIT $3
+	`
+
+	var entries []*models.PointsEntry
+	err := s.db.SelectContext(ctx, &entries, query, householdID, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get points entries: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,408 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
+)
+
+var (
+	// ErrOutOfStock is returned when approving a redemption of a reward with
+	// no stock left
+	ErrOutOfStock = errors.New("reward out of stock")
+
+	// ErrInsufficientPoints is returned when approving a redemption the
+	// member's balance does not cover
+	ErrInsufficientPoints = errors.New("insufficient points")
+)
+
+type RewardStore interface {
+	Create(ctx context.Context, reward *models.Reward) error
+	GetByID(ctx context.Context, householdID string, id string) (*models.Reward, error)
+	GetByHouseholdID(ctx context.Context, householdID string) ([]*models.Reward, error)
+	Update(ctx context.Context, reward *models.Reward) error
+	Delete(ctx context.Context, householdID string, id string) error
+
+	// Redemption operations
+	CreateRedemption(ctx context.Context, redemption *models.Redemption) error
+	GetRedemption(ctx context.Context, householdID string, id string) (*models.Redemption, error)
+	GetRedemptions(ctx context.Context, householdID string, filter RedemptionFilter) ([]*models.Redemption, error)
+
+	// GetLastRedemption returns when the member last requested the reward
+	// without being rejected, or nil if they never did
+	GetLastRedemption(ctx context.Context, rewardID string, userID string) (*time.Time, error)
+
+	// GetPendingCost returns the points the member's pending redemptions
+	// would spend
+	GetPendingCost(ctx context.Context, householdID string, userID string) (int, error)
+
+	// ApproveRedemption approves a pending redemption, taking one from the
+	// reward's stock and debiting the member's points in one transaction
+	ApproveRedemption(ctx context.Context, householdID string, id string, decidedBy string, note *string) (*models.Redemption, error)
+	RejectRedemption(ctx context.Context, householdID string, id string, decidedBy string, note *string) (*models.Redemption, error)
+}
+
+type RedemptionFilter struct {
+	UserID *string                  `json:"userId,omitempty"`
+	Status *models.RedemptionStatus `json:"status,omitempty"`
+	Limit  int                      `json:"limit,omitempty"`
+}
+
+type rewardStore struct {
+	db *sqlx.DB
+}
+
+func NewRewardStore(db *sqlx.DB) RewardStore {
+	return &rewardStore{db: db}
+}
+
+const rewardColumns = `
+	id, household_id, name, description, cost, stock, cooldown_hours,
+	created_by, created_at, updated_at
+`
+
+const redemptionColumns = `
+	id, household_id, reward_id, user_id, cost, status, note,
+	decided_by, decision_note, decided_at, created_at, updated_at
+`
+
+func (s *rewardStore) Create(ctx context.Context, reward *models.Reward) error {
+	query := `
+		INSERT INTO rewards (
+			id, household_id, name, description, cost, stock, cooldown_hours,
+			created_by, created_at, updated_at
+		) VALUES (
+			:id, :household_id, :name, :description, :cost, :stock, :cooldown_hours,
+			:created_by, :created_at, :updated_at
+		)
+	`
+
+	reward.CreatedAt = time.Now()
+	reward.UpdatedAt = time.Now()
+
+	_, err := s.db.NamedExecContext(ctx, query, reward)
+	if err != nil {
+		return fmt.Errorf("failed to create reward: %w", err)
+	}
+
+	return nil
+}
+
+func (s *rewardStore) GetByID(ctx context.Context, householdID string, id string) (*models.Reward, error) {
+	query := `SELECT ` + rewardColumns + ` FROM rewards WHERE id = $1 AND household_id = $2 AND deleted_at IS NULL`
+
+	var reward models.Reward
+	err := s.db.GetContext(ctx, &reward, query, id, householdID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get reward: %w", err)
+	}
+
+	return &reward, nil
+}
+
+func (s *rewardStore) GetByHouseholdID(ctx context.Context, householdID string) ([]*models.Reward, error) {
+	query := `
+		SELECT ` + rewardColumns + `
+		FROM rewards
+		WHERE household_id = $1 AND deleted_at IS NULL
+		ORDER BY cost ASC, name ASC
+	`
+
+	var rewards []*models.Reward
+	err := s.db.SelectContext(ctx, &rewards, query, householdID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get household rewards: %w", err)
+	}
+
+	return rewards, nil
+}
+
+func (s *rewardStore) Update(ctx context.Context, reward *models.Reward) error {
+	query := `
+		UPDATE rewards
+		SET
+			name = :name,
+			description = :description,
+			cost = :cost,
+			stock = :stock,
+			cooldown_hours = :cooldown_hours,
+			updated_at = :updated_at
+		WHERE id = :id AND household_id = :household_id AND deleted_at IS NULL
+	`
+
+	reward.UpdatedAt = time.Now()
+
+	result, err := s.db.NamedExecContext(ctx, query, reward)
+	if err != nil {
+		return fmt.Errorf("failed to update reward: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *rewardStore) Delete(ctx context.Context, householdID string, id string) error {
+	query := `
+		UPDATE rewards
+		SET deleted_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND household_id = $2 AND deleted_at IS NULL
+	`
+
+	result, err := s.db.ExecContext(ctx, query, id, householdID)
+	if err != nil {
+		return fmt.Errorf("failed to delete reward: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *rewardStore) CreateRedemption(ctx context.Context, redemption *models.Redemption) error {
+	query := `
+		INSERT INTO reward_redemptions (
+			id, household_id, reward_id, user_id, cost, status, note,
+			created_at, updated_at
+		) VALUES (
+			:id, :household_id, :reward_id, :user_id, :cost, :status, :note,
+			:created_at, :updated_at
+		)
+	`
+
+	redemption.CreatedAt = time.Now()
+	redemption.UpdatedAt = time.Now()
+
+	_, err := s.db.NamedExecContext(ctx, query, redemption)
+	if err != nil {
+		return fmt.Errorf("failed to create redemption: %w", err)
+	}
+
+	return nil
+}
+
+func (s *rewardStore) GetRedemption(ctx context.Context, householdID string, id string) (*models.Redemption, error) {
+	query := `SELECT ` + redemptionColumns + ` FROM reward_redemptions WHERE id = $1 AND household_id = $2`
+
+	var redemption models.Redemption
+	err := s.db.GetContext(ctx, &redemption, query, id, householdID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get redemption: %w", err)
+	}
+
+	return &redemption, nil
+}
+
+func (s *rewardStore) GetRedemptions(ctx context.Context, householdID string, filter RedemptionFilter) ([]*models.Redemption, error) {
+	query := `SELECT ` + redemptionColumns + ` FROM reward_redemptions WHERE household_id = $1`
+
+	args := []interface{}{householdID}
+	argCount := 1
+
+	if filter.UserID != nil {
+		argCount++
+		query += fmt.Sprintf(" AND user_id = $%d", argCount)
+		args = append(args, *filter.UserID)
+	}
+
+	if filter.Status != nil {
+		argCount++
+		query += fmt.Sprintf(" AND status = $%d", argCount)
+		args = append(args, *filter.Status)
+	}
+
+	query += " ORDER BY created_at DESC"
+
+	if filter.Limit > 0 {
+		argCount++
+		query += fmt.Sprintf(" LIMIT $%d", argCount)
+		args = append(args, filter.Limit)
+	}
+
+	var redemptions []*models.Redemption
+	err := s.db.SelectContext(ctx, &redemptions, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get redemptions: %w", err)
+	}
+
+	return redemptions, nil
+}
+
+func (s *rewardStore) GetLastRedemption(ctx context.Context, rewardID string, userID string) (*time.Time, error) {
+	query := `
+		SELECT MAX(created_at)
+		FROM reward_redemptions
+		WHERE reward_id = $1 AND user_id = $2 AND status != $3
+	`
+
+	var last *time.Time
+	err := s.db.GetContext(ctx, &last, query, rewardID, userID, models.RedemptionStatusRejected)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last redemption: %w", err)
+	}
+
+	return last, nil
+}
+
+func (s *rewardStore) GetPendingCost(ctx context.Context, householdID string, userID string) (int, error) {
+	query := `
+		SELECT COALESCE(SUM(cost), 0)
+		FROM reward_redemptions
+		WHERE household_id = $1 AND user_id = $2 AND status = $3
+	`
+
+	var cost int
+	err := s.db.GetContext(ctx, &cost, query, householdID, userID, models.RedemptionStatusPending)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pending redemption cost: %w", err)
+	}
+
+	return cost, nil
+}
+
+func (s *rewardStore) ApproveRedemption(ctx context.Context, householdID string, id string, decidedBy string, note *string) (*models.Redemption, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	redemption, err := lockPendingRedemption(ctx, tx, householdID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Take one from the reward's stock, unless it is unlimited
+	var stock sql.NullInt64
+	err = tx.GetContext(ctx, &stock, `SELECT stock FROM rewards WHERE id = $1 FOR UPDATE`, redemption.RewardID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock reward: %w", err)
+	}
+	if stock.Valid {
+		if stock.Int64 <= 0 {
+			return nil, ErrOutOfStock
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE rewards SET stock = stock - 1 WHERE id = $1`, redemption.RewardID); err != nil {
+			return nil, fmt.Errorf("failed to update reward stock: %w", err)
+		}
+	}
+
+	// Serialize the member's debits so two approvals cannot both spend the
+	// same points
+	_, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1 || ':' || $2))`, householdID, redemption.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock points balance: %w", err)
+	}
+	var balance int
+	err = tx.GetContext(ctx, &balance, `
+		SELECT COALESCE(SUM(points), 0) FROM points_ledger WHERE household_id = $1 AND user_id = $2`,
+		householdID, redemption.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get points balance: %w", err)
+	}
+	if balance < redemption.Cost {
+		return nil, ErrInsufficientPoints
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO points_ledger (household_id, user_id, points, reason, redemption_id, occurred_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		householdID, redemption.UserID, -redemption.Cost, models.PointsReasonRedemption, redemption.ID, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to debit points: %w", err)
+	}
+
+	if err := decideRedemption(ctx, tx, redemption, models.RedemptionStatusApproved, decidedBy, note); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit redemption approval: %w", err)
+	}
+	return redemption, nil
+}
+
+func (s *rewardStore) RejectRedemption(ctx context.Context, householdID string, id string, decidedBy string, note *string) (*models.Redemption, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	redemption, err := lockPendingRedemption(ctx, tx, householdID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := decideRedemption(ctx, tx, redemption, models.RedemptionStatusRejected, decidedBy, note); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit redemption rejection: %w", err)
+	}
+	return redemption, nil
+}
+
+// lockPendingRedemption locks a redemption for a decision; one that was
+// decided already is a conflict
+func lockPendingRedemption(ctx context.Context, tx *sqlx.Tx, householdID, id string) (*models.Redemption, error) {
+	query := `SELECT ` + redemptionColumns + ` FROM reward_redemptions WHERE id = $1 AND household_id = $2 FOR UPDATE`
+
+	var redemption models.Redemption
+	err := tx.GetContext(ctx, &redemption, query, id, householdID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to lock redemption: %w", err)
+	}
+	if redemption.Status != models.RedemptionStatusPending {
+		return nil, ErrConflict
+	}
+
+	return &redemption, nil
+}
+
+func decideRedemption(ctx context.Context, tx *sqlx.Tx, redemption *models.Redemption, status models.RedemptionStatus, decidedBy string, note *string) error {
+	now := time.Now()
+	_, err := tx.ExecContext(ctx, `
+		UPDATE reward_redemptions
+		SET status = $2, decided_by = $3, decision_note = $4, decided_at = $5, updated_at = $5
+		WHERE id = $1`,
+		redemption.ID, status, decidedBy, note, now)
+	if err != nil {
+		return fmt.Errorf("failed to decide redemption: %w", err)
+	}
+
+	redemption.Status = status
+	redemption.DecidedBy = &decidedBy
+	redemption.DecisionNote = note
+	redemption.DecidedAt = &now
+	redemption.UpdatedAt = now
+	return nil
+}
@@ -13,6 +13,11 @@ type Store struct {
 	Bill      BillStore
 	Timer     TimerStore
 	EventLog  EventLogStore
+	Webhook   WebhookStore
+	Away      AwayStore
+	Points    PointsStore
+	Reward    RewardStore
+	Allowance AllowanceStore
 }
 
 // Stores is an alias for Store to maintain compatibility
@@ -24,6 +29,11 @@ type Stores struct {
 	Bills      BillStore
 	Timers     TimerStore
 	EventLog   EventLogStore
+	Webhooks   WebhookStore
+	Away       AwayStore
+	Points     PointsStore
+	Rewards    RewardStore
+	Allowances AllowanceStore
 }
 
 // NewStore creates a new store instance with all sub-stores
@@ -36,5 +46,10 @@ func NewStore(db *sqlx.DB) *Store {
 		Bill:      NewBillStore(db),
 		Timer:     NewTimerStore(db),
 		EventLog:  NewEventLogStore(db),
+		Webhook:   NewWebhookStore(db),
+		Away:      NewAwayStore(db),
+		Points:    NewPointsStore(db),
+		Reward:    NewRewardStore(db),
+		Allowance: NewAllowanceStore(db),
 	}
 }
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
 )
 
@@ -16,11 +17,23 @@ type TaskStore interface {
 	GetUserTasks(ctx context.Context, userID string, filter TaskFilter) ([]*models.Task, error)
 	Update(ctx context.Context, task *models.Task) error
 	Delete(ctx context.Context, id string) error
-	MarkComplete(ctx context.Context, id string, completedBy string) error
+	// MarkComplete marks a task completed by a member, adding any photos of
+	// the finished task to its attachments. A task that needs review waits in
+	// pending_review for an admin instead. A completed or cancelled task is a
+	// conflict.
+	MarkComplete(ctx context.Context, id string, completedBy string, needsReview bool, attachmentURLs []string) error
+
+	// ReviewTask approves a task pending review, completing it, or rejects it
+	// back to pending. A task not pending review is a conflict.
+	ReviewTask(ctx context.Context, id string, reviewedBy string, approved bool, note *string) error
 	MarkIncomplete(ctx context.Context, id string) error
 	GetTasksByStatus(ctx context.Context, userID string, status models.TaskStatus) ([]*models.Task, error)
 	GetTasksByCategory(ctx context.Context, userID string, category string) ([]*models.Task, error)
 	GetOverdueTasks(ctx context.Context, userID string) ([]*models.Task, error)
+
+	// GetRecurringTaskIDs returns the IDs of a household's recurring task
+	// templates, which the recurring task workflows are keyed by
+	GetRecurringTaskIDs(ctx context.Context, householdID string) ([]string, error)
 }
 
 type TaskFilter struct {
@@ -49,11 +62,13 @@ func (s *taskStore) Create(ctx context.Context, task *models.Task) error {
 			id, title, description, category, priority, status,
 			assigned_to, created_by, household_id, due_date, recurrence_rule,
 			estimated_duration, actual_duration, attachment_urls,
+			points, early_modifier, overdue_modifier,
 			created_at, updated_at
 		) VALUES (
 			:id, :title, :description, :category, :priority, :status,
 			:assigned_to, :created_by, :household_id, :due_date, :recurrence_rule,
 			:estimated_duration, :actual_duration, :attachment_urls,
+			:points, :early_modifier, :overdue_modifier,
 			:created_at, :updated_at
 		)
 	`
@@ -75,7 +90,9 @@ func (s *taskStore) GetByID(ctx context.Context, id string) (*models.Task, error
 			id, title, description, category, priority, status,
 			assigned_to, created_by, household_id, due_date, recurrence_rule,
 			estimated_duration, actual_duration, attachment_urls,
-			completed_at, created_at, updated_at
+			points, early_modifier, overdue_modifier, occurrence_id,
+			completed_by, completed_at, reviewed_by, reviewed_at, review_note,
+			created_at, updated_at
 		FROM tasks 
 		WHERE id = $1 AND deleted_at IS NULL
 	`
@@ -98,7 +115,9 @@ func (s *taskStore) GetUserTasks(ctx context.Context, userID string, filter Task
 			id, title, description, category, priority, status,
 			assigned_to, created_by, household_id, due_date, recurrence_rule,
 			estimated_duration, actual_duration, attachment_urls,
-			completed_at, created_at, updated_at
+			points, early_modifier, overdue_modifier, occurrence_id,
+			completed_by, completed_at, reviewed_by, reviewed_at, review_note,
+			created_at, updated_at
 		FROM tasks t
 		JOIN households h ON t.household_id = h.id
 		JOIN household_members hm ON h.id = hm.household_id
@@ -190,6 +209,9 @@ func (s *taskStore) Update(ctx context.Context, task *models.Task) error {
 			estimated_duration = :estimated_duration,
 			actual_duration = :actual_duration,
 			attachment_urls = :attachment_urls,
+			points = :points,
+			early_modifier = :early_modifier,
+			overdue_modifier = :overdue_modifier,
 			updated_at = :updated_at
 		WHERE id = :id AND deleted_at IS NULL
 	`
@@ -237,17 +259,30 @@ func (s *taskStore) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
-func (s *taskStore) MarkComplete(ctx context.Context, id string, completedBy string) error {
+func (s *taskStore) MarkComplete(ctx context.Context, id string, completedBy string, needsReview bool, attachmentURLs []string) error {
 	query := `
 		UPDATE tasks 
 		SET 
 			status = $1,
+			completed_by = $2,
 			completed_at = NOW(),
+			attachment_urls = CASE WHEN cardinality($3::TEXT[]) > 0
+				THEN array_cat(COALESCE(attachment_urls, '{}'), $3::TEXT[])
+				ELSE attachment_urls END,
+			reviewed_by = NULL,
+			reviewed_at = NULL,
+			review_note = NULL,
 			updated_at = NOW()
-		WHERE id = $2 AND deleted_at IS NULL
+		WHERE id = $4 AND status NOT IN ($5, $6) AND deleted_at IS NULL
 	`
 
-	result, err := s.db.ExecContext(ctx, query, models.TaskStatusCompleted, id)
+	status := models.TaskStatusCompleted
+	if needsReview {
+		status = models.TaskStatusPendingReview
+	}
+
+	result, err := s.db.ExecContext(ctx, query, status, completedBy, pq.StringArray(attachmentURLs), id,
+		models.TaskStatusCompleted, models.TaskStatusCancelled)
 	if err != nil {
 		return fmt.Errorf("failed to mark task complete: %w", err)
 	}
@@ -258,7 +293,46 @@ func (s *taskStore) MarkComplete(ctx context.Context, id string, completedBy str
 	}
 
 	if rowsAffected == 0 {
-		return ErrNotFound
+		if _, err := s.GetByID(ctx, id); err != nil {
+			return err
+		}
+		return ErrConflict
+	}
+
+	return nil
+}
+
+func (s *taskStore) ReviewTask(ctx context.Context, id string, reviewedBy string, approved bool, note *string) error {
+	// A rejected task goes back to pending, clearing its completion
+	query := `
+		UPDATE tasks 
+		SET 
+			status = CASE WHEN $1 THEN $2 ELSE $3 END,
+			completed_by = CASE WHEN $1 THEN completed_by END,
+			completed_at = CASE WHEN $1 THEN completed_at END,
+			reviewed_by = $4,
+			reviewed_at = NOW(),
+			review_note = $5,
+			updated_at = NOW()
+		WHERE id = $6 AND status = $7 AND deleted_at IS NULL
+	`
+
+	result, err := s.db.ExecContext(ctx, query, approved, models.TaskStatusCompleted, models.TaskStatusPending,
+		reviewedBy, note, id, models.TaskStatusPendingReview)
+	if err != nil {
+		return fmt.Errorf("failed to review task: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		if _, err := s.GetByID(ctx, id); err != nil {
+			return err
+		}
+		return ErrConflict
 	}
 
 	return nil
@@ -269,6 +343,7 @@ func (s *taskStore) MarkIncomplete(ctx context.Context, id string) error {
 		UPDATE tasks 
 		SET 
 			status = $1,
+			completed_by = NULL,
 			completed_at = NULL,
 			updated_at = NOW()
 		WHERE id = $2 AND deleted_at IS NULL
@@ -317,3 +392,20 @@ func (s *taskStore) GetOverdueTasks(ctx context.Context, userID string) ([]*mode
 	}
 	return s.GetUserTasks(ctx, userID, filter)
 }
+
+func (s *taskStore) GetRecurringTaskIDs(ctx context.Context, householdID string) ([]string, error) {
+	query := `
+		SELECT id
+		FROM tasks
+		WHERE household_id = $1 AND recurrence_rule IS NOT NULL
+			AND recurring_task_id IS NULL AND deleted_at IS NULL
+	`
+
+	var ids []string
+	err := s.db.SelectContext(ctx, &ids, query, householdID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recurring tasks: %w", err)
+	}
+
+	return ids, nil
+}
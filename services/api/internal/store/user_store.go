@@ -225,13 +225,18 @@ func (s *userStore) UpdateLastLogin(ctx context.Context, userID string) error {
 
 // Profile operations
 func (s *userStore) UpdateProfile(ctx context.Context, userID string, profile *models.UserProfile) error {
+	emailDigest := profile.EmailDigest
+	if emailDigest == "" {
+		emailDigest = "off"
+	}
+
 	query := `
 		INSERT INTO user_profiles (
 			user_id, timezone, language, theme, avatar_url,
 			date_format, time_format, notifications_enabled,
-			email_notifications, push_notifications, created_at, updated_at
+			email_notifications, push_notifications, email_digest, created_at, updated_at
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW()
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW()
 		)
 		ON CONFLICT (user_id) 
 		DO UPDATE SET
@@ -244,6 +249,7 @@ func (s *userStore) UpdateProfile(ctx context.Context, userID string, profile *m
 			notifications_enabled = $8,
 			email_notifications = $9,
 			push_notifications = $10,
+			email_digest = $11,
 			updated_at = NOW()
 	`
 
@@ -251,7 +257,7 @@ func (s *userStore) UpdateProfile(ctx context.Context, userID string, profile *m
 		userID, profile.Timezone, profile.Language, profile.Theme,
 		profile.AvatarURL, profile.DateFormat, profile.TimeFormat,
 		profile.NotificationsEnabled, profile.EmailNotifications,
-		profile.PushNotifications,
+		profile.PushNotifications, emailDigest,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update user profile: %w", err)
@@ -265,7 +271,7 @@ func (s *userStore) GetProfile(ctx context.Context, userID string) (*models.User
 		SELECT 
 			timezone, language, theme, avatar_url, date_format, time_format,
 			notifications_enabled, email_notifications, push_notifications,
-			created_at, updated_at
+			email_digest, created_at, updated_at
 		FROM user_profiles 
 		WHERE user_id = $1
 	`
@@ -283,6 +289,7 @@ func (s *userStore) GetProfile(ctx context.Context, userID string) (*models.User
 				TimeFormat:           "24h",
 				NotificationsEnabled: true,
 				EmailNotifications:   true,
+				EmailDigest:          "off",
 				PushNotifications:    true,
 			}, nil
 		}
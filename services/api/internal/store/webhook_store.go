@@ -0,0 +1,155 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/yakirshlomo/house-helper/services/api/pkg/models"
+)
+
+type WebhookStore interface {
+	Create(ctx context.Context, endpoint *models.WebhookEndpoint) error
+	GetByID(ctx context.Context, householdID string, id string) (*models.WebhookEndpoint, error)
+	GetByHouseholdID(ctx context.Context, householdID string) ([]*models.WebhookEndpoint, error)
+	Delete(ctx context.Context, householdID string, id string) error
+
+	// Enable reactivates an endpoint and clears its failure count
+	Enable(ctx context.Context, householdID string, id string) error
+
+	// Delivery operations
+	GetDeliveries(ctx context.Context, endpointID string, limit int) ([]*models.WebhookDelivery, error)
+}
+
+type webhookStore struct {
+	db *sqlx.DB
+}
+
+func NewWebhookStore(db *sqlx.DB) WebhookStore {
+	return &webhookStore{db: db}
+}
+
+func (s *webhookStore) Create(ctx context.Context, endpoint *models.WebhookEndpoint) error {
+	query := `
+		INSERT INTO webhook_endpoints (
+			id, household_id, url, description, secret, event_types,
+			active, created_by, created_at, updated_at
+		) VALUES (
+			:id, :household_id, :url, :description, :secret, :event_types,
+			:active, :created_by, :created_at, :updated_at
+		)
+	`
+
+	endpoint.Active = true
+	endpoint.CreatedAt = time.Now()
+	endpoint.UpdatedAt = time.Now()
+
+	_, err := s.db.NamedExecContext(ctx, query, endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook endpoint: %w", err)
+	}
+
+	return nil
+}
+
+func (s *webhookStore) GetByID(ctx context.Context, householdID string, id string) (*models.WebhookEndpoint, error) {
+	query := `
+		SELECT
+			id, household_id, url, description, event_types, active,
+			failure_count, disabled_at, created_by, created_at, updated_at
+		FROM webhook_endpoints
+		WHERE id = $1 AND household_id = $2 AND deleted_at IS NULL
+	`
+
+	var endpoint models.WebhookEndpoint
+	err := s.db.GetContext(ctx, &endpoint, query, id, householdID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook endpoint: %w", err)
+	}
+
+	return &endpoint, nil
+}
+
+func (s *webhookStore) GetByHouseholdID(ctx context.Context, householdID string) ([]*models.WebhookEndpoint, error) {
+	query := `
+		SELECT
+			id, household_id, url, description, event_types, active,
+			failure_count, disabled_at, created_by, created_at, updated_at
+		FROM webhook_endpoints
+		WHERE household_id = $1 AND deleted_at IS NULL
+		ORDER BY created_at ASC
+	`
+
+	var endpoints []*models.WebhookEndpoint
+	err := s.db.SelectContext(ctx, &endpoints, query, householdID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook endpoints: %w", err)
+	}
+
+	return endpoints, nil
+}
+
+func (s *webhookStore) Delete(ctx context.Context, householdID string, id string) error {
+	query := `
+		UPDATE webhook_endpoints
+		SET deleted_at = NOW(), active = FALSE, updated_at = NOW()
+		WHERE id = $1 AND household_id = $2 AND deleted_at IS NULL
+	`
+
+	return s.execOne(ctx, "delete", query, id, householdID)
+}
+
+func (s *webhookStore) Enable(ctx context.Context, householdID string, id string) error {
+	query := `
+		UPDATE webhook_endpoints
+		SET active = TRUE, failure_count = 0, disabled_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND household_id = $2 AND deleted_at IS NULL
+	`
+
+	return s.execOne(ctx, "enable", query, id, householdID)
+}
+
+func (s *webhookStore) GetDeliveries(ctx context.Context, endpointID string, limit int) ([]*models.WebhookDelivery, error) {
+	query := `
+		SELECT
+			id, endpoint_id, event_id, event_type, attempt, status_code,
+			error, duration_ms, created_at
+		FROM webhook_deliveries
+		WHERE endpoint_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	var deliveries []*models.WebhookDelivery
+	err := s.db.SelectContext(ctx, &deliveries, query, endpointID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// execOne runs an update of a single endpoint, returning ErrNotFound if no
+// endpoint matched
+func (s *webhookStore) execOne(ctx context.Context, action string, query string, args ...interface{}) error {
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to %s webhook endpoint: %w", action, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,2 @@
+-- Remove email digest preference from user profiles
+ALTER TABLE user_profiles DROP COLUMN IF EXISTS email_digest;
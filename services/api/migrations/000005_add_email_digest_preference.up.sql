@@ -0,0 +1,4 @@
+-- Add email digest preference to user profiles
+ALTER TABLE user_profiles
+    ADD COLUMN IF NOT EXISTS email_digest VARCHAR(10) NOT NULL DEFAULT 'off'
+    CHECK (email_digest IN ('off', 'daily', 'weekly'));
@@ -0,0 +1,7 @@
+-- Drop laundry loads and task occurrence columns
+DROP TABLE IF EXISTS laundry_loads;
+
+DROP INDEX IF EXISTS idx_tasks_recurring_task_id;
+ALTER TABLE tasks
+    DROP COLUMN IF EXISTS occurrence_id,
+    DROP COLUMN IF EXISTS recurring_task_id;
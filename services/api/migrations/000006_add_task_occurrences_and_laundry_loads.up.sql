@@ -0,0 +1,25 @@
+-- Link task occurrences created by recurring task workflows to their template
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS recurring_task_id UUID REFERENCES tasks(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS occurrence_id VARCHAR(255) UNIQUE;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_recurring_task_id ON tasks(recurring_task_id);
+
+-- Create laundry loads table, written by the laundry workflow
+CREATE TABLE IF NOT EXISTS laundry_loads (
+    id VARCHAR(255) PRIMARY KEY, -- Laundry ID of the workflow
+    household_id UUID NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id),
+    load_type VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'washing' CHECK (status IN ('washing', 'drying', 'completed', 'cancelled')),
+    settings JSONB,
+    wash_time INTEGER, -- in seconds
+    dry_time INTEGER, -- in seconds
+    total_time INTEGER, -- in seconds
+    started_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_laundry_loads_household_status ON laundry_loads(household_id, status);
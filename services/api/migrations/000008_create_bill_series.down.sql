@@ -0,0 +1,9 @@
+-- Drop bill series
+DROP INDEX IF EXISTS idx_bills_series_sequence;
+ALTER TABLE bills
+    DROP COLUMN IF EXISTS amount_type,
+    DROP COLUMN IF EXISTS sequence,
+    DROP COLUMN IF EXISTS series_id;
+
+DROP TRIGGER IF EXISTS update_bill_series_updated_at ON bill_series;
+DROP TABLE IF EXISTS bill_series;
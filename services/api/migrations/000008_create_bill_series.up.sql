@@ -0,0 +1,30 @@
+-- Create bill series table, the template recurring bill instances are created from
+CREATE TABLE IF NOT EXISTS bill_series (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    household_id UUID NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    category VARCHAR(100) NOT NULL,
+    amount_type VARCHAR(20) NOT NULL DEFAULT 'fixed' CHECK (amount_type IN ('fixed', 'estimated', 'open')),
+    amount DECIMAL(10,2), -- fixed amount, or the estimate until an instance is paid
+    currency VARCHAR(10) NOT NULL DEFAULT 'USD',
+    recurrence_rule TEXT NOT NULL, -- daily, weekly, biweekly, monthly, quarterly or yearly
+    assigned_to UUID REFERENCES users(id),
+    reminder_days INTEGER DEFAULT 3,
+    created_by UUID NOT NULL REFERENCES users(id),
+    ended_at TIMESTAMP, -- no instances are created after the series ended
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+-- Link bill instances to their series
+ALTER TABLE bills
+    ADD COLUMN IF NOT EXISTS series_id UUID REFERENCES bill_series(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS sequence INTEGER, -- position of the instance in its series, from 1
+    ADD COLUMN IF NOT EXISTS amount_type VARCHAR(20) NOT NULL DEFAULT 'fixed' CHECK (amount_type IN ('fixed', 'estimated', 'open'));
+
+CREATE INDEX IF NOT EXISTS idx_bill_series_household_id ON bill_series(household_id) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bills_series_sequence ON bills(series_id, sequence);
+
+CREATE TRIGGER update_bill_series_updated_at BEFORE UPDATE ON bill_series FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,3 @@
+-- Drop away periods
+DROP TRIGGER IF EXISTS update_away_periods_updated_at ON away_periods;
+DROP TABLE IF EXISTS away_periods;
@@ -0,0 +1,19 @@
+-- Create away periods, during which a household's recurring chores and
+-- reminders pause; user_id is NULL when the whole household is away
+CREATE TABLE IF NOT EXISTS away_periods (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    household_id UUID NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    note VARCHAR(255),
+    created_by UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP,
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_away_periods_household_ends_at ON away_periods(household_id, ends_at) WHERE deleted_at IS NULL;
+
+CREATE TRIGGER update_away_periods_updated_at BEFORE UPDATE ON away_periods FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,6 @@
+-- Remove the chore assignment preferences from household members
+ALTER TABLE household_members
+    DROP COLUMN IF EXISTS preferred_categories,
+    DROP COLUMN IF EXISTS excluded_categories,
+    DROP COLUMN IF EXISTS date_of_birth,
+    DROP COLUMN IF EXISTS assignment_weight;
@@ -0,0 +1,6 @@
+-- Add the preferences the chore assignment engine reads to household members
+ALTER TABLE household_members
+    ADD COLUMN IF NOT EXISTS assignment_weight NUMERIC(4, 2) CHECK (assignment_weight > 0),
+    ADD COLUMN IF NOT EXISTS date_of_birth DATE,
+    ADD COLUMN IF NOT EXISTS excluded_categories TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS preferred_categories TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,12 @@
+-- Drop points, streaks and the points ledger
+DROP TRIGGER IF EXISTS update_point_streaks_updated_at ON point_streaks;
+DROP TABLE IF EXISTS point_streaks;
+
+DROP TRIGGER IF EXISTS points_ledger_append_only ON points_ledger;
+DROP FUNCTION IF EXISTS reject_points_ledger_change();
+DROP TABLE IF EXISTS points_ledger;
+
+ALTER TABLE tasks
+    DROP COLUMN IF EXISTS overdue_modifier,
+    DROP COLUMN IF EXISTS early_modifier,
+    DROP COLUMN IF EXISTS points;
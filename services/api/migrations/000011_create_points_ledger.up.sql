@@ -0,0 +1,50 @@
+-- Add point values to tasks; the modifiers scale the points of a task
+-- completed early or overdue, and leave them unchanged when NULL
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS points INTEGER NOT NULL DEFAULT 10 CHECK (points >= 0),
+    ADD COLUMN IF NOT EXISTS early_modifier NUMERIC(4, 2) CHECK (early_modifier >= 0),
+    ADD COLUMN IF NOT EXISTS overdue_modifier NUMERIC(4, 2) CHECK (overdue_modifier >= 0);
+
+-- Create the points ledger. Entries are never changed or removed: balances
+-- and leaderboards are sums over it, and corrections are new entries.
+CREATE TABLE IF NOT EXISTS points_ledger (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    household_id UUID NOT NULL REFERENCES households(id),
+    user_id UUID NOT NULL REFERENCES users(id),
+    points INTEGER NOT NULL,
+    reason VARCHAR(30) NOT NULL CHECK (reason IN ('task_completed')),
+    task_id UUID REFERENCES tasks(id),
+    modifier VARCHAR(20) CHECK (modifier IN ('early', 'overdue')),
+    event_id VARCHAR(255),
+    occurred_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A task earns its points once, however often its completion is delivered
+CREATE UNIQUE INDEX IF NOT EXISTS idx_points_ledger_task_completed ON points_ledger(task_id) WHERE reason = 'task_completed';
+CREATE INDEX IF NOT EXISTS idx_points_ledger_household_occurred_at ON points_ledger(household_id, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_points_ledger_user ON points_ledger(household_id, user_id, occurred_at DESC);
+
+CREATE OR REPLACE FUNCTION reject_points_ledger_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'points_ledger is append-only';
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER points_ledger_append_only BEFORE UPDATE OR DELETE ON points_ledger FOR EACH ROW EXECUTE FUNCTION reject_points_ledger_change();
+
+-- Create streaks of consecutive days, in the household's timezone, on which
+-- a member completed at least one task
+CREATE TABLE IF NOT EXISTS point_streaks (
+    household_id UUID NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    current_streak INTEGER NOT NULL DEFAULT 0,
+    longest_streak INTEGER NOT NULL DEFAULT 0,
+    last_completed_on DATE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (household_id, user_id)
+);
+
+CREATE TRIGGER update_point_streaks_updated_at BEFORE UPDATE ON point_streaks FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
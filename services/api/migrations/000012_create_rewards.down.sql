@@ -0,0 +1,9 @@
+-- Drop rewards and their redemptions. The points ledger is append-only, so
+-- its redemption entries, and the reason allowing them, are kept.
+DROP INDEX IF EXISTS idx_points_ledger_redemption;
+ALTER TABLE points_ledger DROP COLUMN IF EXISTS redemption_id;
+
+DROP TRIGGER IF EXISTS update_reward_redemptions_updated_at ON reward_redemptions;
+DROP TRIGGER IF EXISTS update_rewards_updated_at ON rewards;
+DROP TABLE IF EXISTS reward_redemptions;
+DROP TABLE IF EXISTS rewards;
@@ -0,0 +1,47 @@
+-- Create the rewards household admins offer for points. A NULL stock is
+-- unlimited; the cooldown is how long a member waits between redemptions of
+-- the same reward.
+CREATE TABLE IF NOT EXISTS rewards (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    household_id UUID NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    cost INTEGER NOT NULL CHECK (cost > 0),
+    stock INTEGER CHECK (stock >= 0),
+    cooldown_hours INTEGER NOT NULL DEFAULT 0 CHECK (cooldown_hours >= 0),
+    created_by UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+-- Create the redemption requests members make and admins decide on
+CREATE TABLE IF NOT EXISTS reward_redemptions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    household_id UUID NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+    reward_id UUID NOT NULL REFERENCES rewards(id),
+    user_id UUID NOT NULL REFERENCES users(id),
+    cost INTEGER NOT NULL CHECK (cost > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    note VARCHAR(255),
+    decided_by UUID REFERENCES users(id),
+    decision_note VARCHAR(255),
+    decided_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_rewards_household ON rewards(household_id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_reward_redemptions_household_status ON reward_redemptions(household_id, status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_reward_redemptions_member_reward ON reward_redemptions(user_id, reward_id, created_at DESC);
+
+CREATE TRIGGER update_rewards_updated_at BEFORE UPDATE ON rewards FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_reward_redemptions_updated_at BEFORE UPDATE ON reward_redemptions FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Approved redemptions are debited from the points ledger, once each
+ALTER TABLE points_ledger
+    ADD COLUMN IF NOT EXISTS redemption_id UUID REFERENCES reward_redemptions(id),
+    DROP CONSTRAINT IF EXISTS points_ledger_reason_check,
+    ADD CONSTRAINT points_ledger_reason_check CHECK (reason IN ('task_completed', 'redemption'));
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_points_ledger_redemption ON points_ledger(redemption_id) WHERE redemption_id IS NOT NULL;
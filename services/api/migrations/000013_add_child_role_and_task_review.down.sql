@@ -0,0 +1,23 @@
+-- Return tasks waiting for review to pending, and children to members, so
+-- the original constraints hold again
+DROP INDEX IF EXISTS idx_tasks_pending_review;
+
+UPDATE tasks SET status = 'pending', completed_at = NULL WHERE status = 'pending_review';
+
+ALTER TABLE tasks
+    DROP COLUMN IF EXISTS review_note,
+    DROP COLUMN IF EXISTS reviewed_at,
+    DROP COLUMN IF EXISTS reviewed_by,
+    DROP COLUMN IF EXISTS completed_by,
+    DROP CONSTRAINT IF EXISTS tasks_status_check,
+    ADD CONSTRAINT tasks_status_check CHECK (status IN ('pending', 'in_progress', 'completed', 'cancelled'));
+
+UPDATE household_invitations SET role = 'member' WHERE role = 'child';
+ALTER TABLE household_invitations
+    DROP CONSTRAINT IF EXISTS household_invitations_role_check,
+    ADD CONSTRAINT household_invitations_role_check CHECK (role IN ('admin', 'member', 'guest'));
+
+UPDATE household_members SET role = 'member' WHERE role = 'child';
+ALTER TABLE household_members
+    DROP CONSTRAINT IF EXISTS household_members_role_check,
+    ADD CONSTRAINT household_members_role_check CHECK (role IN ('admin', 'member', 'guest'));
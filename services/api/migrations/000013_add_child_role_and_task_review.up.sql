@@ -0,0 +1,21 @@
+-- Add the child role, whose tasks need an admin's review before they count as
+-- completed
+ALTER TABLE household_members
+    DROP CONSTRAINT IF EXISTS household_members_role_check,
+    ADD CONSTRAINT household_members_role_check CHECK (role IN ('admin', 'member', 'guest', 'child'));
+
+ALTER TABLE household_invitations
+    DROP CONSTRAINT IF EXISTS household_invitations_role_check,
+    ADD CONSTRAINT household_invitations_role_check CHECK (role IN ('admin', 'member', 'guest', 'child'));
+
+-- Tasks completed by children wait in pending_review until an admin approves
+-- them, or rejects them back to pending
+ALTER TABLE tasks
+    DROP CONSTRAINT IF EXISTS tasks_status_check,
+    ADD CONSTRAINT tasks_status_check CHECK (status IN ('pending', 'in_progress', 'pending_review', 'completed', 'cancelled')),
+    ADD COLUMN IF NOT EXISTS completed_by UUID REFERENCES users(id),
+    ADD COLUMN IF NOT EXISTS reviewed_by UUID REFERENCES users(id),
+    ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS review_note TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_pending_review ON tasks(household_id) WHERE status = 'pending_review' AND deleted_at IS NULL;
@@ -0,0 +1,9 @@
+-- Drop allowances, the allowance ledger and missed chores
+DROP TRIGGER IF EXISTS allowance_ledger_append_only ON allowance_ledger;
+DROP FUNCTION IF EXISTS reject_allowance_ledger_change();
+DROP TABLE IF EXISTS allowance_ledger;
+
+DROP TRIGGER IF EXISTS update_allowance_settings_updated_at ON allowance_settings;
+DROP TABLE IF EXISTS allowance_settings;
+
+DROP TABLE IF EXISTS missed_chores;
@@ -0,0 +1,65 @@
+-- Create the chores the reminder workflows found missed: a task still open
+-- an interval after its assignee's last reminder. A miss is recorded once per
+-- occurrence, against the member who was reminded.
+CREATE TABLE IF NOT EXISTS missed_chores (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    household_id UUID NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    occurrence_id VARCHAR(255) NOT NULL UNIQUE,
+    title VARCHAR(255) NOT NULL,
+    category VARCHAR(100) NOT NULL,
+    due_date TIMESTAMP,
+    reminders INTEGER NOT NULL DEFAULT 0,
+    detected_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_missed_chores_member ON missed_chores(household_id, user_id, detected_at);
+
+-- Create each child's allowance: a weekly base amount in the household's
+-- currency, a bonus per completed task by category and a deduction per
+-- missed chore
+CREATE TABLE IF NOT EXISTS allowance_settings (
+    household_id UUID NOT NULL REFERENCES households(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    base_amount DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (base_amount >= 0),
+    category_bonuses JSONB NOT NULL DEFAULT '{}',
+    missed_chore_deduction DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (missed_chore_deduction >= 0),
+    updated_by UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (household_id, user_id)
+);
+
+CREATE TRIGGER update_allowance_settings_updated_at BEFORE UPDATE ON allowance_settings FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Create the allowance ledger of weekly payouts. Entries are never changed
+-- or removed, and a week is paid once.
+CREATE TABLE IF NOT EXISTS allowance_ledger (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    household_id UUID NOT NULL REFERENCES households(id),
+    user_id UUID NOT NULL REFERENCES users(id),
+    week_start DATE NOT NULL,
+    base_amount DECIMAL(10,2) NOT NULL,
+    bonus_amount DECIMAL(10,2) NOT NULL,
+    deduction_amount DECIMAL(10,2) NOT NULL,
+    amount DECIMAL(10,2) NOT NULL CHECK (amount >= 0),
+    currency VARCHAR(10) NOT NULL,
+    completed_tasks INTEGER NOT NULL DEFAULT 0,
+    missed_chores INTEGER NOT NULL DEFAULT 0,
+    paid_by UUID NOT NULL REFERENCES users(id),
+    note VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (household_id, user_id, week_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_allowance_ledger_household_week ON allowance_ledger(household_id, week_start DESC);
+
+CREATE OR REPLACE FUNCTION reject_allowance_ledger_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'allowance_ledger is append-only';
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER allowance_ledger_append_only BEFORE UPDATE OR DELETE ON allowance_ledger FOR EACH ROW EXECUTE FUNCTION reject_allowance_ledger_change();
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"log"
+	"time"
 
 	"github.com/segmentio/kafka-go"
 )
@@ -17,19 +18,21 @@ type Config struct {
 // Producer wraps kafka writer
 type Producer struct {
 	writer *kafka.Writer
+	topic  string
 }
 
 // NewProducer creates a new Kafka producer
 func NewProducer(config Config) *Producer {
+	// The topic is set per message so the producer can also publish to the
+	// topics the event consumer reads
 	writer := &kafka.Writer{
 		Addr:         kafka.TCP(config.Brokers...),
-		Topic:        config.Topic,
 		Balancer:     &kafka.LeastBytes{},
 		RequiredAcks: kafka.RequireOne,
 		Async:        true,
 	}
 
-	return &Producer{writer: writer}
+	return &Producer{writer: writer, topic: config.Topic}
 }
 
 // Close closes the producer
@@ -39,12 +42,18 @@ func (p *Producer) Close() error {
 
 // SendMessage sends a message to Kafka
 func (p *Producer) SendMessage(ctx context.Context, key string, value interface{}) error {
+	return p.SendMessageTo(ctx, p.topic, key, value)
+}
+
+// SendMessageTo sends a message to the given topic
+func (p *Producer) SendMessageTo(ctx context.Context, topic string, key string, value interface{}) error {
 	valueBytes, err := json.Marshal(value)
 	if err != nil {
 		return err
 	}
 
 	message := kafka.Message{
+		Topic: topic,
 		Key:   []byte(key),
 		Value: valueBytes,
 	}
@@ -89,6 +98,15 @@ const (
 	EventTypeBillPaid       = "bill.paid"
 	EventTypeTimerStarted   = "timer.started"
 	EventTypeTimerCompleted = "timer.completed"
+
+	EventTypeHouseholdActivity          = "household.activity"
+	EventTypeHouseholdMemberRoleChanged = "household.member.role_changed"
+)
+
+// Topics the event consumer reads
+const (
+	TopicTasks      = "house-helper.tasks"
+	TopicHouseholds = "house-helper.households"
 )
 
 // Event represents a domain event
@@ -115,3 +133,31 @@ func (ep *EventPublisher) PublishEvent(ctx context.Context, event Event) error {
 	log.Printf("Publishing event: %s", event.Type)
 	return ep.producer.SendMessage(ctx, event.ID, event)
 }
+
+// DomainEvent is an event in the format the event consumer reads
+type DomainEvent struct {
+	ID          string                 `json:"id"`
+	Type        string                 `json:"type"`
+	Source      string                 `json:"source"`
+	HouseholdID string                 `json:"householdId"`
+	UserID      string                 `json:"userId,omitempty"`
+	Timestamp   time.Time              `json:"timestamp"`
+	Version     string                 `json:"version"`
+	Data        map[string]interface{} `json:"data"`
+}
+
+// PublishDomainEvent publishes an event for the event consumer, keyed by
+// household so a household's events stay in order
+func (ep *EventPublisher) PublishDomainEvent(ctx context.Context, topic string, event DomainEvent) error {
+	if event.Source == "" {
+		event.Source = "api"
+	}
+	if event.Version == "" {
+		event.Version = "1.0"
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+	log.Printf("Publishing event: %s", event.Type)
+	return ep.producer.SendMessageTo(ctx, topic, event.HouseholdID, event)
+}
@@ -32,6 +32,7 @@ type UserProfile struct {
 	TimeFormat           string `json:"timeFormat" db:"time_format"`
 	NotificationsEnabled bool   `json:"notificationsEnabled" db:"notifications_enabled"`
 	EmailNotifications   bool   `json:"emailNotifications" db:"email_notifications"`
+	EmailDigest          string `json:"emailDigest" db:"email_digest"`
 	PushNotifications    bool   `json:"pushNotifications" db:"push_notifications"`
 	CreatedAt            time.Time `json:"createdAt" db:"created_at"`
 	UpdatedAt            time.Time `json:"updatedAt" db:"updated_at"`
@@ -56,8 +57,36 @@ const (
 	HouseholdRoleAdmin  HouseholdRole = "admin"
 	HouseholdRoleMember HouseholdRole = "member"
 	HouseholdRoleGuest  HouseholdRole = "guest"
+	HouseholdRoleChild  HouseholdRole = "child"
 )
 
+// Capability is an action some household roles may not take
+type Capability string
+
+const (
+	CapabilityBills       Capability = "bills"        // view and manage bills
+	CapabilityDeleteTasks Capability = "delete_tasks" // delete tasks
+	CapabilityReviewTasks Capability = "review_tasks" // approve or reject tasks pending review
+)
+
+// Can reports whether the role has a capability. Children may not touch
+// bills or delete tasks, and only admins review tasks.
+func (r HouseholdRole) Can(capability Capability) bool {
+	switch capability {
+	case CapabilityReviewTasks:
+		return r == HouseholdRoleAdmin
+	case CapabilityBills, CapabilityDeleteTasks:
+		return r != HouseholdRoleChild
+	}
+	return false
+}
+
+// NeedsReview reports whether tasks the role completes wait for an admin's
+// review before they count as completed
+func (r HouseholdRole) NeedsReview() bool {
+	return r == HouseholdRoleChild
+}
+
 // HouseholdMember represents a user's membership in a household
 type HouseholdMember struct {
 	ID          string         `json:"id" db:"id"`
@@ -76,6 +105,18 @@ type HouseholdMember struct {
 	Phone       string `json:"phone,omitempty"`
 }
 
+// AssignmentPreferences are what the chore assignment engine knows of a
+// household member. Weight overrides the share derived from the member's role
+// and age.
+type AssignmentPreferences struct {
+	HouseholdID         string     `json:"householdId" db:"household_id"`
+	UserID              string     `json:"userId" db:"user_id"`
+	Weight              *float64   `json:"weight,omitempty" db:"assignment_weight"`
+	DateOfBirth         *time.Time `json:"dateOfBirth,omitempty" db:"date_of_birth"`
+	ExcludedCategories  []string   `json:"excludedCategories" db:"excluded_categories"`
+	PreferredCategories []string   `json:"preferredCategories" db:"preferred_categories"`
+}
+
 // InvitationStatus represents the status of a household invitation
 type InvitationStatus string
 
@@ -116,12 +157,16 @@ const (
 type TaskStatus string
 
 const (
-	TaskStatusPending    TaskStatus = "pending"
-	TaskStatusInProgress TaskStatus = "in_progress"
-	TaskStatusCompleted  TaskStatus = "completed"
-	TaskStatusCancelled  TaskStatus = "cancelled"
+	TaskStatusPending       TaskStatus = "pending"
+	TaskStatusInProgress    TaskStatus = "in_progress"
+	TaskStatusPendingReview TaskStatus = "pending_review" // completed by a child, awaiting an admin
+	TaskStatusCompleted     TaskStatus = "completed"
+	TaskStatusCancelled     TaskStatus = "cancelled"
 )
 
+// DefaultTaskPoints is the point value of a task created without one
+const DefaultTaskPoints = 10
+
 // Task represents a household task
 type Task struct {
 	ID                string         `json:"id" db:"id"`
@@ -137,8 +182,16 @@ type Task struct {
 	RecurrenceRule    *string        `json:"recurrenceRule,omitempty" db:"recurrence_rule"`
 	EstimatedDuration *int           `json:"estimatedDuration,omitempty" db:"estimated_duration"` // in minutes
 	ActualDuration    *int           `json:"actualDuration,omitempty" db:"actual_duration"`       // in minutes
+	Points            int            `json:"points" db:"points"`
+	EarlyModifier     *float64       `json:"earlyModifier,omitempty" db:"early_modifier"`     // scales points when completed early
+	OverdueModifier   *float64       `json:"overdueModifier,omitempty" db:"overdue_modifier"` // scales points when completed overdue
 	AttachmentURLs    pq.StringArray `json:"attachmentUrls,omitempty" db:"attachment_urls"`
+	OccurrenceID      *string        `json:"occurrenceId,omitempty" db:"occurrence_id"` // set on occurrences of recurring tasks
+	CompletedBy       *string        `json:"completedBy,omitempty" db:"completed_by"`
 	CompletedAt       *time.Time     `json:"completedAt,omitempty" db:"completed_at"`
+	ReviewedBy        *string        `json:"reviewedBy,omitempty" db:"reviewed_by"`
+	ReviewedAt        *time.Time     `json:"reviewedAt,omitempty" db:"reviewed_at"`
+	ReviewNote        *string        `json:"reviewNote,omitempty" db:"review_note"`
 	CreatedAt         time.Time      `json:"createdAt" db:"created_at"`
 	UpdatedAt         time.Time      `json:"updatedAt" db:"updated_at"`
 }
@@ -206,6 +259,15 @@ const (
 	BillStatusCancelled BillStatus = "cancelled"
 )
 
+// BillAmountType says how the amount of a recurring bill's instances is set
+type BillAmountType string
+
+const (
+	BillAmountFixed     BillAmountType = "fixed"     // Every instance has the series' amount
+	BillAmountEstimated BillAmountType = "estimated" // Estimated from the amounts paid for earlier instances
+	BillAmountOpen      BillAmountType = "open"      // Set when the instance is paid
+)
+
 // Bill represents a household bill
 type Bill struct {
 	ID              string         `json:"id" db:"id"`
@@ -229,10 +291,34 @@ type Bill struct {
 	PaidAt          *time.Time     `json:"paidAt,omitempty" db:"paid_at"`
 	PaidBy          *string        `json:"paidBy,omitempty" db:"paid_by"`
 	PaidAmount      *float64       `json:"paidAmount,omitempty" db:"paid_amount"`
+	SeriesID        *string        `json:"seriesId,omitempty" db:"series_id"`
+	Sequence        *int           `json:"sequence,omitempty" db:"sequence"` // Position in the series, from 1
+	AmountType      BillAmountType `json:"amountType" db:"amount_type"`
 	CreatedAt       time.Time      `json:"createdAt" db:"created_at"`
 	UpdatedAt       time.Time      `json:"updatedAt" db:"updated_at"`
 }
 
+// BillSeries is the template the instances of a recurring bill are created
+// from. The next instance is created when the current one is paid or reaches
+// its due date.
+type BillSeries struct {
+	ID             string         `json:"id" db:"id"`
+	HouseholdID    string         `json:"householdId" db:"household_id"`
+	Name           string         `json:"name" db:"name"`
+	Description    *string        `json:"description,omitempty" db:"description"`
+	Category       string         `json:"category" db:"category"`
+	AmountType     BillAmountType `json:"amountType" db:"amount_type"`
+	Amount         *float64       `json:"amount,omitempty" db:"amount"` // Fixed amount, or the estimate until an instance is paid
+	Currency       string         `json:"currency" db:"currency"`
+	RecurrenceRule string         `json:"recurrenceRule" db:"recurrence_rule"`
+	AssignedTo     *string        `json:"assignedTo,omitempty" db:"assigned_to"`
+	ReminderDays   *int           `json:"reminderDays,omitempty" db:"reminder_days"`
+	CreatedBy      string         `json:"createdBy" db:"created_by"`
+	EndedAt        *time.Time     `json:"endedAt,omitempty" db:"ended_at"`
+	CreatedAt      time.Time      `json:"createdAt" db:"created_at"`
+	UpdatedAt      time.Time      `json:"updatedAt" db:"updated_at"`
+}
+
 // BillPayment represents a payment made for a bill
 type BillPayment struct {
 	ID            string    `json:"id" db:"id"`
@@ -298,3 +384,232 @@ type TimerSession struct {
 	CreatedAt      time.Time  `json:"createdAt" db:"created_at"`
 	UpdatedAt      time.Time  `json:"updatedAt" db:"updated_at"`
 }
+
+// WebhookEventTypes lists the events a webhook endpoint can subscribe to
+var WebhookEventTypes = []string{
+	"task.created", "task.updated", "task.completed", "task.deleted",
+	"shopping.item.added", "shopping.item.updated", "shopping.item.purchased", "shopping.item.deleted",
+	"bill.created", "bill.updated", "bill.paid", "bill.overdue", "bill.deleted",
+	"timer.started", "timer.paused", "timer.resumed", "timer.completed", "timer.stopped",
+	"laundry.started", "laundry.wash.complete", "laundry.dry.started", "laundry.dry.complete", "laundry.completed",
+	"household.updated", "household.member.added", "household.member.removed",
+}
+
+// WebhookEndpoint is an endpoint a household's events are delivered to
+type WebhookEndpoint struct {
+	ID           string         `json:"id" db:"id"`
+	HouseholdID  string         `json:"householdId" db:"household_id"`
+	URL          string         `json:"url" db:"url"`
+	Description  *string        `json:"description,omitempty" db:"description"`
+	Secret       string         `json:"secret,omitempty" db:"secret"` // only returned when created
+	EventTypes   pq.StringArray `json:"eventTypes" db:"event_types"`
+	Active       bool           `json:"active" db:"active"`
+	FailureCount int            `json:"failureCount" db:"failure_count"`
+	DisabledAt   *time.Time     `json:"disabledAt,omitempty" db:"disabled_at"`
+	CreatedBy    string         `json:"createdBy" db:"created_by"`
+	CreatedAt    time.Time      `json:"createdAt" db:"created_at"`
+	UpdatedAt    time.Time      `json:"updatedAt" db:"updated_at"`
+}
+
+// WebhookDelivery is one attempt to deliver an event to a webhook endpoint
+type WebhookDelivery struct {
+	ID         string    `json:"id" db:"id"`
+	EndpointID string    `json:"endpointId" db:"endpoint_id"`
+	EventID    string    `json:"eventId" db:"event_id"`
+	EventType  string    `json:"eventType" db:"event_type"`
+	Attempt    int       `json:"attempt" db:"attempt"`
+	StatusCode *int      `json:"statusCode,omitempty" db:"status_code"` // nil when no response was received
+	Error      *string   `json:"error,omitempty" db:"error"`
+	DurationMs int       `json:"durationMs" db:"duration_ms"`
+	CreatedAt  time.Time `json:"createdAt" db:"created_at"`
+}
+
+// AwayPeriod is a period a household, or one of its members, is away.
+// Recurring chores and reminders pause while the household is away, and
+// away members are not auto-assigned tasks.
+type AwayPeriod struct {
+	ID          string    `json:"id" db:"id"`
+	HouseholdID string    `json:"householdId" db:"household_id"`
+	UserID      *string   `json:"userId,omitempty" db:"user_id"` // nil when the whole household is away
+	StartsAt    time.Time `json:"startsAt" db:"starts_at"`
+	EndsAt      time.Time `json:"endsAt" db:"ends_at"`
+	Note        *string   `json:"note,omitempty" db:"note"`
+	CreatedBy   string    `json:"createdBy" db:"created_by"`
+	CreatedAt   time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt   time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// PointsReason is why points were added to or taken from a member's balance
+type PointsReason string
+
+const (
+	PointsReasonTaskCompleted PointsReason = "task_completed"
+	PointsReasonRedemption    PointsReason = "redemption"
+)
+
+// PointsEntry is an entry of the append-only points ledger
+type PointsEntry struct {
+	ID           string       `json:"id" db:"id"`
+	HouseholdID  string       `json:"householdId" db:"household_id"`
+	UserID       string       `json:"userId" db:"user_id"`
+	Points       int          `json:"points" db:"points"`
+	Reason       PointsReason `json:"reason" db:"reason"`
+	TaskID       *string      `json:"taskId,omitempty" db:"task_id"`
+	Modifier     *string      `json:"modifier,omitempty" db:"modifier"` // early or overdue
+	RedemptionID *string      `json:"redemptionId,omitempty" db:"redemption_id"`
+	OccurredAt   time.Time    `json:"occurredAt" db:"occurred_at"`
+	CreatedAt    time.Time    `json:"createdAt" db:"created_at"`
+}
+
+// PointsStreak is a member's run of consecutive days with a completed task
+type PointsStreak struct {
+	Current         int        `json:"current" db:"current_streak"`
+	Longest         int        `json:"longest" db:"longest_streak"`
+	LastCompletedOn *time.Time `json:"lastCompletedOn,omitempty" db:"last_completed_on"`
+}
+
+// MemberPoints is a member's points balance, streak and latest ledger entries
+type MemberPoints struct {
+	HouseholdID string         `json:"householdId"`
+	UserID      string         `json:"userId"`
+	Balance     int            `json:"balance"`
+	Streak      PointsStreak   `json:"streak"`
+	Entries     []*PointsEntry `json:"entries"`
+}
+
+// LeaderboardEntry is a member's standing on a household leaderboard
+type LeaderboardEntry struct {
+	Rank           int          `json:"rank"`
+	UserID         string       `json:"userId" db:"user_id"`
+	FirstName      string       `json:"firstName" db:"first_name"`
+	LastName       string       `json:"lastName" db:"last_name"`
+	Points         int          `json:"points" db:"points"`
+	CompletedTasks int          `json:"completedTasks" db:"completed_tasks"`
+	Streak         PointsStreak `json:"streak"`
+}
+
+// Leaderboard ranks a household's members by the points they earned in a
+// period
+type Leaderboard struct {
+	HouseholdID string              `json:"householdId"`
+	Period      string              `json:"period"` // week or month
+	Since       time.Time           `json:"since"`
+	Entries     []*LeaderboardEntry `json:"entries"`
+}
+
+// Reward is something household admins offer members for their points
+type Reward struct {
+	ID            string    `json:"id" db:"id"`
+	HouseholdID   string    `json:"householdId" db:"household_id"`
+	Name          string    `json:"name" db:"name"`
+	Description   *string   `json:"description,omitempty" db:"description"`
+	Cost          int       `json:"cost" db:"cost"`
+	Stock         *int      `json:"stock,omitempty" db:"stock"` // nil when unlimited
+	CooldownHours int       `json:"cooldownHours" db:"cooldown_hours"`
+	CreatedBy     string    `json:"createdBy" db:"created_by"`
+	CreatedAt     time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt     time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// RedemptionStatus is where a reward redemption request stands
+type RedemptionStatus string
+
+const (
+	RedemptionStatusPending  RedemptionStatus = "pending"
+	RedemptionStatusApproved RedemptionStatus = "approved"
+	RedemptionStatusRejected RedemptionStatus = "rejected"
+)
+
+// Redemption is a member's request to spend points on a reward. Approving it
+// debits the points ledger.
+type Redemption struct {
+	ID           string           `json:"id" db:"id"`
+	HouseholdID  string           `json:"householdId" db:"household_id"`
+	RewardID     string           `json:"rewardId" db:"reward_id"`
+	UserID       string           `json:"userId" db:"user_id"`
+	Cost         int              `json:"cost" db:"cost"`
+	Status       RedemptionStatus `json:"status" db:"status"`
+	Note         *string          `json:"note,omitempty" db:"note"`
+	DecidedBy    *string          `json:"decidedBy,omitempty" db:"decided_by"`
+	DecisionNote *string          `json:"decisionNote,omitempty" db:"decision_note"`
+	DecidedAt    *time.Time       `json:"decidedAt,omitempty" db:"decided_at"`
+	CreatedAt    time.Time        `json:"createdAt" db:"created_at"`
+	UpdatedAt    time.Time        `json:"updatedAt" db:"updated_at"`
+}
+
+// AllowanceSettings is a child's weekly allowance, in the household's
+// currency: a base amount, a bonus per completed task by category and a
+// deduction per missed chore
+type AllowanceSettings struct {
+	HouseholdID          string             `json:"householdId" db:"household_id"`
+	UserID               string             `json:"userId" db:"user_id"`
+	Currency             string             `json:"currency" db:"-"`
+	BaseAmount           float64            `json:"baseAmount" db:"base_amount"`
+	CategoryBonuses      map[string]float64 `json:"categoryBonuses" db:"-"` // Bonus per completed task by category
+	MissedChoreDeduction float64            `json:"missedChoreDeduction" db:"missed_chore_deduction"`
+	UpdatedBy            string             `json:"updatedBy" db:"updated_by"`
+	CreatedAt            time.Time          `json:"createdAt" db:"created_at"`
+	UpdatedAt            time.Time          `json:"updatedAt" db:"updated_at"`
+}
+
+// MissedChore is a task its assignee left open after every reminder, as
+// detected by the reminder workflows
+type MissedChore struct {
+	ID           string     `json:"id" db:"id"`
+	HouseholdID  string     `json:"householdId" db:"household_id"`
+	UserID       string     `json:"userId" db:"user_id"`
+	TaskID       string     `json:"taskId" db:"task_id"`
+	OccurrenceID string     `json:"occurrenceId" db:"occurrence_id"`
+	Title        string     `json:"title" db:"title"`
+	Category     string     `json:"category" db:"category"`
+	DueDate      *time.Time `json:"dueDate,omitempty" db:"due_date"`
+	Reminders    int        `json:"reminders" db:"reminders"`
+	DetectedAt   time.Time  `json:"detectedAt" db:"detected_at"`
+}
+
+// AllowanceBonus is what a child earned for the tasks of one category they
+// completed in a week
+type AllowanceBonus struct {
+	Category string  `json:"category" db:"category"`
+	Tasks    int     `json:"tasks" db:"tasks"`
+	Rate     float64 `json:"rate"`
+	Amount   float64 `json:"amount"`
+}
+
+// AllowanceStatement is a child's allowance for a week, from Monday in the
+// household's timezone. Payout is set once the week was paid.
+type AllowanceStatement struct {
+	HouseholdID     string            `json:"householdId"`
+	UserID          string            `json:"userId"`
+	WeekStart       time.Time         `json:"weekStart"`
+	WeekEnd         time.Time         `json:"weekEnd"`
+	Currency        string            `json:"currency"`
+	BaseAmount      float64           `json:"baseAmount"`
+	Bonuses         []*AllowanceBonus `json:"bonuses"`
+	BonusAmount     float64           `json:"bonusAmount"`
+	MissedChores    []*MissedChore    `json:"missedChores"`
+	DeductionAmount float64           `json:"deductionAmount"`
+	Amount          float64           `json:"amount"` // Never below zero
+	Payout          *AllowancePayout  `json:"payout,omitempty"`
+}
+
+// AllowancePayout is an entry of the append-only allowance ledger, recording
+// a week's statement as paid
+type AllowancePayout struct {
+	ID              string    `json:"id" db:"id"`
+	HouseholdID     string    `json:"householdId" db:"household_id"`
+	UserID          string    `json:"userId" db:"user_id"`
+	FirstName       string    `json:"firstName,omitempty" db:"first_name"`
+	LastName        string    `json:"lastName,omitempty" db:"last_name"`
+	WeekStart       time.Time `json:"weekStart" db:"week_start"`
+	BaseAmount      float64   `json:"baseAmount" db:"base_amount"`
+	BonusAmount     float64   `json:"bonusAmount" db:"bonus_amount"`
+	DeductionAmount float64   `json:"deductionAmount" db:"deduction_amount"`
+	Amount          float64   `json:"amount" db:"amount"`
+	Currency        string    `json:"currency" db:"currency"`
+	CompletedTasks  int       `json:"completedTasks" db:"completed_tasks"`
+	MissedChores    int       `json:"missedChores" db:"missed_chores"`
+	PaidBy          string    `json:"paidBy" db:"paid_by"`
+	Note            *string   `json:"note,omitempty" db:"note"`
+	CreatedAt       time.Time `json:"createdAt" db:"created_at"`
+}
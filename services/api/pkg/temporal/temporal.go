@@ -2,10 +2,13 @@ package temporal
 
 import (
 	"context"
+	"errors"
 	"log"
 	"time"
 
+	"go.temporal.io/api/serviceerror"
 	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/worker"
 )
 
@@ -38,6 +41,49 @@ func NewClient(config Config) (*Client, error) {
 	}, nil
 }
 
+// StartWorkflow starts a workflow of the worker service by its type name on
+// the client's task queue
+func (c *Client) StartWorkflow(ctx context.Context, workflowID, workflowType string, args ...interface{}) (client.WorkflowRun, error) {
+	options := client.StartWorkflowOptions{
+		ID:        workflowID,
+		TaskQueue: c.taskQueue,
+	}
+	return c.ExecuteWorkflow(ctx, options, workflowType, args...)
+}
+
+// UpsertSchedule creates a schedule that starts a workflow of the worker
+// service on the client's task queue. An existing schedule with the same ID
+// gets the new spec and action instead.
+func (c *Client) UpsertSchedule(ctx context.Context, options client.ScheduleOptions) error {
+	if action, ok := options.Action.(*client.ScheduleWorkflowAction); ok {
+		action.TaskQueue = c.taskQueue
+	}
+
+	_, err := c.ScheduleClient().Create(ctx, options)
+	if !errors.Is(err, temporal.ErrScheduleAlreadyRunning) {
+		return err
+	}
+	return c.ScheduleClient().GetHandle(ctx, options.ID).Update(ctx, client.ScheduleUpdateOptions{
+		DoUpdate: func(input client.ScheduleUpdateInput) (*client.ScheduleUpdate, error) {
+			schedule := input.Description.Schedule
+			schedule.Spec = &options.Spec
+			schedule.Action = options.Action
+			return &client.ScheduleUpdate{Schedule: &schedule}, nil
+		},
+	})
+}
+
+// DeleteSchedule deletes a schedule; a schedule that does not exist is
+// already deleted
+func (c *Client) DeleteSchedule(ctx context.Context, scheduleID string) error {
+	err := c.ScheduleClient().GetHandle(ctx, scheduleID).Delete(ctx)
+	var notFound *serviceerror.NotFound
+	if errors.As(err, &notFound) {
+		return nil
+	}
+	return err
+}
+
 // StartWorker starts a Temporal worker
 func (c *Client) StartWorker(workflows []interface{}, activities []interface{}) error {
 	w := worker.New(c.Client, c.taskQueue, worker.Options{})
@@ -4,6 +4,7 @@ go 1.24.0
 
 require (
 	github.com/IBM/sarama v1.43.3
+	github.com/lib/pq v1.12.3
 	go.uber.org/zap v1.27.0
 )
 
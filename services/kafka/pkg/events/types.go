@@ -14,6 +14,7 @@ const (
 	EventTaskUpdated   EventType = "task.updated"
 	EventTaskCompleted EventType = "task.completed"
 	EventTaskDeleted   EventType = "task.deleted"
+	EventTaskEscalated EventType = "task.escalated"
 
 	// Shopping events
 	EventShoppingItemAdded    EventType = "shopping.item.added"
@@ -48,6 +49,7 @@ const (
 	EventHouseholdUpdated      EventType = "household.updated"
 	EventHouseholdMemberAdded  EventType = "household.member.added"
 	EventHouseholdMemberRemoved EventType = "household.member.removed"
+	EventHouseholdMemberRoleChanged EventType = "household.member.role_changed"
 	EventHouseholdActivity     EventType = "household.activity"
 
 	// User events
@@ -202,6 +204,7 @@ type HouseholdEventData struct {
 	HouseholdID string `json:"householdId"`
 	Name        string `json:"name"`
 	MemberID    string `json:"memberId,omitempty"`
+	Role        string `json:"role,omitempty"`
 	Activity    string `json:"activity,omitempty"`
 	ActorID     string `json:"actorId,omitempty"`
 }
@@ -0,0 +1,99 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// StatusError is returned when the notifier answers with a non-2xx status
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("notifier returned %d: %s", e.StatusCode, e.Body)
+}
+
+// Retryable reports whether the request may succeed if sent again
+func (e *StatusError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// Client calls the notifier service over HTTP
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a new notifier client
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// TopicResult reports how many of a member's devices changed subscription
+type TopicResult struct {
+	Topic   string `json:"topic"`
+	Updated int    `json:"updated"`
+	Failed  int    `json:"failed"`
+	Removed int    `json:"removed"`
+}
+
+// JoinHouseholdTopic subscribes a member's Android devices to the household topic
+func (c *Client) JoinHouseholdTopic(ctx context.Context, householdID, userID string) (*TopicResult, error) {
+	return c.householdMember(ctx, http.MethodPut, householdID, userID)
+}
+
+// LeaveHouseholdTopic unsubscribes a member's Android devices from the household topic
+func (c *Client) LeaveHouseholdTopic(ctx context.Context, householdID, userID string) (*TopicResult, error) {
+	return c.householdMember(ctx, http.MethodDelete, householdID, userID)
+}
+
+func (c *Client) householdMember(ctx context.Context, method, householdID, userID string) (*TopicResult, error) {
+	path := "/v1/households/" + url.PathEscape(householdID) + "/members/" + url.PathEscape(userID)
+
+	var result TopicResult
+	if err := c.do(ctx, method, path, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// do sends a request without a body and decodes the JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call notifier: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read notifier response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &StatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(respBody))}
+	}
+
+	if out != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("failed to decode notifier response: %w", err)
+		}
+	}
+	return nil
+}
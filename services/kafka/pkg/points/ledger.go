@@ -0,0 +1,175 @@
+package points
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// ErrTaskNotFound is returned for a completion of a task that does not exist
+var ErrTaskNotFound = errors.New("task not found")
+
+// StatusCompleted is the status of a task that earns its points. A task a
+// child completed stays pending_review until an admin approves it.
+const StatusCompleted = "completed"
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Ledger appends the points members earn to the household points ledger
+type Ledger struct {
+	db *sql.DB
+}
+
+// Open connects to the Postgres database holding the points ledger
+func Open(ctx context.Context, databaseURL string) (*Ledger, error) {
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return &Ledger{db: db}, nil
+}
+
+// Close closes the database connection
+func (l *Ledger) Close() error {
+	return l.db.Close()
+}
+
+// Completion is a task.completed event
+type Completion struct {
+	EventID     string
+	TaskID      string
+	CompletedBy string    // The assignee is credited when empty
+	CompletedAt time.Time // Used when the task has no completion time
+}
+
+// Award is what a member earned for a completion
+type Award struct {
+	HouseholdID string
+	UserID      string
+	Points      int
+	Modifier    string
+	Streak      Streak
+}
+
+// AwardCompletion credits the member who completed a task with its points
+// and extends their streak. A task earns its points once, so a completion
+// delivered again, of a task that is not completed (still pending review,
+// rejected back to pending or cancelled), or of a task nobody can be
+// credited for, returns nil.
+func (l *Ledger) AwardCompletion(ctx context.Context, completion Completion) (*Award, error) {
+	if !uuidPattern.MatchString(completion.TaskID) {
+		return nil, ErrTaskNotFound
+	}
+
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var task Task
+	var award Award
+	var assignee, status, timezone string
+	var completedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, `
+		SELECT t.household_id, COALESCE(t.assigned_to::TEXT, ''), t.status, t.points,
+		       t.early_modifier, t.overdue_modifier, t.due_date, t.completed_at,
+		       COALESCE(h.timezone, 'UTC')
+		FROM tasks t
+		JOIN households h ON h.id = t.household_id
+		WHERE t.id = $1 AND t.deleted_at IS NULL`, completion.TaskID,
+	).Scan(&award.HouseholdID, &assignee, &status, &task.Points,
+		&task.EarlyModifier, &task.OverdueModifier, &task.DueDate, &completedAt, &timezone)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query task: %w", err)
+	}
+	if status != StatusCompleted {
+		// A child's task earns its points once an admin approves it, and a
+		// task reopened or cancelled since the event earns none
+		return nil, nil
+	}
+
+	award.UserID = completion.CompletedBy
+	if !uuidPattern.MatchString(award.UserID) {
+		award.UserID = assignee
+	}
+	if award.UserID == "" {
+		return nil, nil
+	}
+
+	at := completion.CompletedAt
+	if completedAt.Valid {
+		at = completedAt.Time
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	award.Points, award.Modifier = Earned(task, at)
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO points_ledger (household_id, user_id, points, reason, task_id, modifier, event_id, occurred_at)
+		VALUES ($1, $2, $3, 'task_completed', $4, NULLIF($5, ''), NULLIF($6, ''), $7)
+		ON CONFLICT (task_id) WHERE reason = 'task_completed' DO NOTHING`,
+		award.HouseholdID, award.UserID, award.Points, completion.TaskID, award.Modifier, completion.EventID, at.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to append to points ledger: %w", err)
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if inserted == 0 {
+		// The task was awarded already
+		return nil, nil
+	}
+
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		location = time.UTC
+	}
+
+	var lastCompletedOn sql.NullTime
+	err = tx.QueryRowContext(ctx, `
+		SELECT current_streak, longest_streak, last_completed_on
+		FROM point_streaks
+		WHERE household_id = $1 AND user_id = $2
+		FOR UPDATE`, award.HouseholdID, award.UserID,
+	).Scan(&award.Streak.Current, &award.Streak.Longest, &lastCompletedOn)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to query streak: %w", err)
+	}
+	if lastCompletedOn.Valid {
+		day := lastCompletedOn.Time
+		award.Streak.LastCompletedOn = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	award.Streak = award.Streak.Extend(at.In(location))
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO point_streaks (household_id, user_id, current_streak, longest_streak, last_completed_on)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (household_id, user_id) DO UPDATE
+		SET current_streak = EXCLUDED.current_streak,
+		    longest_streak = EXCLUDED.longest_streak,
+		    last_completed_on = EXCLUDED.last_completed_on`,
+		award.HouseholdID, award.UserID, award.Streak.Current, award.Streak.Longest, award.Streak.LastCompletedOn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update streak: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit points award: %w", err)
+	}
+	return &award, nil
+}
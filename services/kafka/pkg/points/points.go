@@ -0,0 +1,83 @@
+// Package points awards household members points for completed tasks and
+// keeps their completion streaks.
+package points
+
+import (
+	"math"
+	"time"
+)
+
+// Modifiers applied to the points of a completed task
+const (
+	// ModifierEarly scales the points of a task completed at least
+	// EarlyWindow before it is due
+	ModifierEarly = "early"
+
+	// ModifierOverdue scales the points of a task completed after it is due
+	ModifierOverdue = "overdue"
+)
+
+// EarlyWindow is how long before its due date a task must be completed to
+// count as early
+const EarlyWindow = 24 * time.Hour
+
+// Task is what the points of a completed task are computed from
+type Task struct {
+	Points          int
+	EarlyModifier   *float64 // nil leaves early completions unchanged
+	OverdueModifier *float64 // nil leaves overdue completions unchanged
+	DueDate         *time.Time
+}
+
+// Earned returns the points a task completed at completedAt earns and the
+// modifier applied, if any
+func Earned(task Task, completedAt time.Time) (int, string) {
+	if task.DueDate == nil {
+		return task.Points, ""
+	}
+
+	switch {
+	case task.OverdueModifier != nil && completedAt.After(*task.DueDate):
+		return scale(task.Points, *task.OverdueModifier), ModifierOverdue
+	case task.EarlyModifier != nil && !completedAt.After(task.DueDate.Add(-EarlyWindow)):
+		return scale(task.Points, *task.EarlyModifier), ModifierEarly
+	default:
+		return task.Points, ""
+	}
+}
+
+func scale(points int, modifier float64) int {
+	return int(math.Round(float64(points) * modifier))
+}
+
+// Streak is a run of consecutive days on which a member completed at least
+// one task
+type Streak struct {
+	Current         int
+	Longest         int
+	LastCompletedOn time.Time // Midnight UTC of the day, zero before the first completion
+}
+
+// Extend returns the streak after a completion on day, a date in the
+// household's timezone. Completions delivered late, for a day before the
+// last one, leave the streak unchanged.
+func (s Streak) Extend(day time.Time) Streak {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch {
+	case s.LastCompletedOn.IsZero():
+		s.Current = 1
+	case !day.After(s.LastCompletedOn):
+		return s
+	case day.Equal(s.LastCompletedOn.AddDate(0, 0, 1)):
+		s.Current++
+	default:
+		s.Current = 1
+	}
+
+	s.LastCompletedOn = day
+	if s.Current > s.Longest {
+		s.Longest = s.Current
+	}
+	return s
+}
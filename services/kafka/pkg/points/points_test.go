@@ -0,0 +1,173 @@
+package points
+
+import (
+	"testing"
+	"time"
+)
+
+func float64Ptr(f float64) *float64 {
+	return &f
+}
+
+func TestEarned(t *testing.T) {
+	due := time.Date(2024, 3, 10, 18, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		task         Task
+		completedAt  time.Time
+		wantPoints   int
+		wantModifier string
+	}{
+		{
+			name:        "no due date",
+			task:        Task{Points: 10, EarlyModifier: float64Ptr(2), OverdueModifier: float64Ptr(0.5)},
+			completedAt: due,
+			wantPoints:  10,
+		},
+		{
+			name:         "early",
+			task:         Task{Points: 10, EarlyModifier: float64Ptr(1.5), DueDate: &due},
+			completedAt:  due.Add(-48 * time.Hour),
+			wantPoints:   15,
+			wantModifier: ModifierEarly,
+		},
+		{
+			name:         "exactly the early window before due",
+			task:         Task{Points: 10, EarlyModifier: float64Ptr(1.5), DueDate: &due},
+			completedAt:  due.Add(-EarlyWindow),
+			wantPoints:   15,
+			wantModifier: ModifierEarly,
+		},
+		{
+			name:        "inside the early window",
+			task:        Task{Points: 10, EarlyModifier: float64Ptr(1.5), DueDate: &due},
+			completedAt: due.Add(-EarlyWindow + time.Minute),
+			wantPoints:  10,
+		},
+		{
+			name:        "early without an early modifier",
+			task:        Task{Points: 10, OverdueModifier: float64Ptr(0.5), DueDate: &due},
+			completedAt: due.Add(-48 * time.Hour),
+			wantPoints:  10,
+		},
+		{
+			name:        "on the due date",
+			task:        Task{Points: 10, EarlyModifier: float64Ptr(1.5), OverdueModifier: float64Ptr(0.5), DueDate: &due},
+			completedAt: due,
+			wantPoints:  10,
+		},
+		{
+			name:         "overdue",
+			task:         Task{Points: 10, OverdueModifier: float64Ptr(0.5), DueDate: &due},
+			completedAt:  due.Add(time.Second),
+			wantPoints:   5,
+			wantModifier: ModifierOverdue,
+		},
+		{
+			name:        "overdue without an overdue modifier",
+			task:        Task{Points: 10, EarlyModifier: float64Ptr(1.5), DueDate: &due},
+			completedAt: due.Add(time.Hour),
+			wantPoints:  10,
+		},
+		{
+			name:         "scaled points are rounded",
+			task:         Task{Points: 5, OverdueModifier: float64Ptr(0.5), DueDate: &due},
+			completedAt:  due.Add(time.Hour),
+			wantPoints:   3,
+			wantModifier: ModifierOverdue,
+		},
+		{
+			name:         "due date in another timezone",
+			task:         Task{Points: 10, OverdueModifier: float64Ptr(0.5), DueDate: &due},
+			completedAt:  time.Date(2024, 3, 10, 15, 0, 1, 0, time.FixedZone("UTC-3", -3*60*60)),
+			wantPoints:   5,
+			wantModifier: ModifierOverdue,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points, modifier := Earned(tt.task, tt.completedAt)
+			if points != tt.wantPoints || modifier != tt.wantModifier {
+				t.Errorf("Earned() = %d, %q, want %d, %q", points, modifier, tt.wantPoints, tt.wantModifier)
+			}
+		})
+	}
+}
+
+func TestStreakExtend(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load timezone: %v", err)
+	}
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name   string
+		streak Streak
+		at     time.Time
+		want   Streak
+	}{
+		{
+			name: "first completion",
+			at:   time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC),
+			want: Streak{Current: 1, Longest: 1, LastCompletedOn: day(2024, 3, 10)},
+		},
+		{
+			name:   "next day",
+			streak: Streak{Current: 2, Longest: 2, LastCompletedOn: day(2024, 3, 10)},
+			at:     time.Date(2024, 3, 11, 23, 59, 0, 0, time.UTC),
+			want:   Streak{Current: 3, Longest: 3, LastCompletedOn: day(2024, 3, 11)},
+		},
+		{
+			name:   "same day",
+			streak: Streak{Current: 2, Longest: 4, LastCompletedOn: day(2024, 3, 10)},
+			at:     time.Date(2024, 3, 10, 20, 0, 0, 0, time.UTC),
+			want:   Streak{Current: 2, Longest: 4, LastCompletedOn: day(2024, 3, 10)},
+		},
+		{
+			name:   "missed a day",
+			streak: Streak{Current: 3, Longest: 3, LastCompletedOn: day(2024, 3, 10)},
+			at:     time.Date(2024, 3, 12, 8, 0, 0, 0, time.UTC),
+			want:   Streak{Current: 1, Longest: 3, LastCompletedOn: day(2024, 3, 12)},
+		},
+		{
+			name:   "delivered late for an earlier day",
+			streak: Streak{Current: 3, Longest: 3, LastCompletedOn: day(2024, 3, 10)},
+			at:     time.Date(2024, 3, 9, 8, 0, 0, 0, time.UTC),
+			want:   Streak{Current: 3, Longest: 3, LastCompletedOn: day(2024, 3, 10)},
+		},
+		{
+			name:   "across a month end",
+			streak: Streak{Current: 1, Longest: 1, LastCompletedOn: day(2024, 2, 29)},
+			at:     time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC),
+			want:   Streak{Current: 2, Longest: 2, LastCompletedOn: day(2024, 3, 1)},
+		},
+		{
+			// 01:30 UTC on the 11th is still the evening of the 10th in New York
+			name:   "before midnight in the household timezone",
+			streak: Streak{Current: 1, Longest: 1, LastCompletedOn: day(2024, 3, 9)},
+			at:     time.Date(2024, 3, 11, 1, 30, 0, 0, time.UTC).In(newYork),
+			want:   Streak{Current: 2, Longest: 2, LastCompletedOn: day(2024, 3, 10)},
+		},
+		{
+			// 04:30 UTC on the 11th is after midnight in New York
+			name:   "after midnight in the household timezone",
+			streak: Streak{Current: 1, Longest: 1, LastCompletedOn: day(2024, 3, 10)},
+			at:     time.Date(2024, 3, 11, 4, 30, 0, 0, time.UTC).In(newYork),
+			want:   Streak{Current: 2, Longest: 2, LastCompletedOn: day(2024, 3, 11)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.streak.Extend(tt.at)
+			if got != tt.want {
+				t.Errorf("Extend() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
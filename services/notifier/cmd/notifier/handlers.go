@@ -1,35 +1,51 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"time"
 
-	"github.com/house-helper/notifier/pkg/notifications"
-	"github.com/house-helper/notifier/pkg/sse"
-	"github.com/house-helper/notifier/pkg/websocket"
+	"github.com/househelper/notifier/pkg/delivery"
+	"github.com/househelper/notifier/pkg/devices"
+	"github.com/househelper/notifier/pkg/liveactivity"
+	"github.com/househelper/notifier/pkg/notifications"
+	"github.com/househelper/notifier/pkg/presence"
+	"github.com/househelper/notifier/pkg/sse"
+	"github.com/househelper/notifier/pkg/topics"
+	"github.com/househelper/notifier/pkg/websocket"
 )
 
 // FCM notification request
 type FCMTokenRequest struct {
-	Token   string                 `json:"token"`
-	Title   string                 `json:"title"`
-	Body    string                 `json:"body"`
-	ImageURL string                `json:"imageUrl,omitempty"`
-	Data    map[string]string      `json:"data,omitempty"`
+	UserID      string `json:"userId,omitempty"`
+	CollapseKey string `json:"collapseKey,omitempty"`
+
+	Token    string            `json:"token"`
+	Title    string            `json:"title"`
+	Body     string            `json:"body"`
+	ImageURL string            `json:"imageUrl,omitempty"`
+	Data     map[string]string `json:"data,omitempty"`
 }
 
 type FCMTopicRequest struct {
-	Topic   string                 `json:"topic"`
-	Title   string                 `json:"title"`
-	Body    string                 `json:"body"`
-	ImageURL string                `json:"imageUrl,omitempty"`
-	Data    map[string]string      `json:"data,omitempty"`
+	Topic    string            `json:"topic"`
+	Title    string            `json:"title"`
+	Body     string            `json:"body"`
+	ImageURL string            `json:"imageUrl,omitempty"`
+	Data     map[string]string `json:"data,omitempty"`
 }
 
 // APNS notification request
 type APNSRequest struct {
+	UserID          string            `json:"userId,omitempty"`
+	CollapseKey     string            `json:"collapseKey,omitempty"`
 	DeviceToken     string            `json:"deviceToken"`
 	BundleID        string            `json:"bundleId"`
 	Title           string            `json:"title"`
@@ -44,23 +60,51 @@ type APNSRequest struct {
 	TargetContentID string            `json:"targetContentId,omitempty"`
 }
 
+// Queued notification request
+type NotifyRequest struct {
+	notifications.Notification
+
+	// CollapseKey merges notifications with the same key that are still
+	// queued, e.g. "shopping-list-42"
+	CollapseKey string `json:"collapseKey,omitempty"`
+}
+
+// Email notification request
+type EmailRequest struct {
+	notifications.EmailMessage
+
+	HouseholdID   string `json:"householdId,omitempty"`
+	HouseholdName string `json:"householdName,omitempty"`
+
+	// Category of the notification, e.g. "overdue_task"
+	Category string `json:"category,omitempty"`
+
+	// Digest is the recipient's digest preference; digestible categories are
+	// batched instead of sent immediately when it is daily or weekly
+	Digest notifications.DigestMode `json:"digest,omitempty"`
+}
+
+// Web Push subscription request
+type WebPushSubscriptionRequest struct {
+	UserID       string                      `json:"userId"`
+	Subscription devices.WebPushSubscription `json:"subscription"`
+}
+
 // Broadcast request
 type BroadcastRequest struct {
 	Type string      `json:"type"`
 	Data interface{} `json:"data"`
+
+	// Resource scopes WebSocket delivery to clients subscribed to it (e.g. "list:42")
+	Resource string `json:"resource,omitempty"`
 }
 
-// handleFCMTokenNotification handles FCM token-based notifications
-func handleFCMTokenNotification(fcmService *notifications.FCMService) http.HandlerFunc {
+// handleFCMTokenNotification sends a notification to an FCM token and
+// answers with FCM's message ID
+func handleFCMTokenNotification(fcmService notifications.FCMProvider) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
-		}
-
-		var req FCMTokenRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+		req, ok := decodeFCMTokenRequest(w, r)
+		if !ok {
 			return
 		}
 
@@ -84,8 +128,53 @@ func handleFCMTokenNotification(fcmService *notifications.FCMService) http.Handl
 	}
 }
 
+// handleQueuedFCMTokenNotification queues a notification for an FCM token
+// and answers with its receipt
+func handleQueuedFCMTokenNotification(queue *delivery.Queue) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := decodeFCMTokenRequest(w, r)
+		if !ok {
+			return
+		}
+
+		device := devices.Device{
+			UserID:   req.UserID,
+			Platform: devices.PlatformAndroid,
+			Token:    req.Token,
+		}
+		n := notifications.Notification{
+			UserID:   req.UserID,
+			Title:    req.Title,
+			Body:     req.Body,
+			ImageURL: req.ImageURL,
+			Data:     req.Data,
+		}
+
+		enqueueDevice(w, r, queue, device, n, req.CollapseKey)
+	}
+}
+
+// decodeFCMTokenRequest decodes a POSTed FCM token request, writing the
+// error response when it is invalid
+func decodeFCMTokenRequest(w http.ResponseWriter, r *http.Request) (FCMTokenRequest, bool) {
+	var req FCMTokenRequest
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return req, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return req, false
+	}
+	if req.Token == "" {
+		http.Error(w, "token is required", http.StatusBadRequest)
+		return req, false
+	}
+	return req, true
+}
+
 // handleFCMTopicNotification handles FCM topic-based notifications
-func handleFCMTopicNotification(fcmService *notifications.FCMService) http.HandlerFunc {
+func handleFCMTopicNotification(fcmService notifications.FCMProvider) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -118,20 +207,72 @@ func handleFCMTopicNotification(fcmService *notifications.FCMService) http.Handl
 	}
 }
 
-// handleAPNSNotification handles APNS notifications
-func handleAPNSNotification(apnsService *notifications.APNSService) http.HandlerFunc {
+// handleHouseholdMember subscribes a member's Android devices to the
+// household topic on PUT and unsubscribes them on DELETE
+func handleHouseholdMember(manager *topics.Manager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
+		householdID := r.PathValue("id")
+		userID := r.PathValue("userId")
+
+		var result *topics.Result
+		var err error
+		switch r.Method {
+		case http.MethodPut:
+			result, err = manager.Join(r.Context(), householdID, userID)
+		case http.MethodDelete:
+			result, err = manager.Leave(r.Context(), householdID, userID)
+		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
+		if err != nil {
+			log.Printf("Failed to update household topic of user %s: %v", userID, err)
+			http.Error(w, "Failed to update topic subscription", http.StatusBadGateway)
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+	}
+}
 
-		var req APNSRequest
+// handleHouseholdAnnouncement sends a notification to the household topic
+func handleHouseholdAnnouncement(manager *topics.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req FCMTopicRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, "Invalid request body", http.StatusBadRequest)
 			return
 		}
 
+		payload := notifications.NotificationPayload{
+			Title:    req.Title,
+			Body:     req.Body,
+			ImageURL: req.ImageURL,
+			Data:     req.Data,
+		}
+
+		householdID := r.PathValue("id")
+		messageID, err := manager.Announce(r.Context(), householdID, payload)
+		if err != nil {
+			log.Printf("Failed to send announcement to household %s: %v", householdID, err)
+			http.Error(w, "Failed to send announcement", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{
+			"topic":     topics.HouseholdTopic(householdID),
+			"messageId": messageID,
+		})
+	}
+}
+
+// handleAPNSNotification sends a notification to an APNs device token and
+// answers with the APNs ID and status
+func handleAPNSNotification(apnsService notifications.APNSProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := decodeAPNSRequest(w, r)
+		if !ok {
+			return
+		}
+
 		payload := notifications.APNSPayload{
 			Title:           req.Title,
 			Body:            req.Body,
@@ -163,8 +304,71 @@ func handleAPNSNotification(apnsService *notifications.APNSService) http.Handler
 	}
 }
 
+// handleQueuedAPNSNotification queues a notification for an APNs device
+// token and answers with its receipt
+func handleQueuedAPNSNotification(queue *delivery.Queue) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req, ok := decodeAPNSRequest(w, r)
+		if !ok {
+			return
+		}
+
+		device := devices.Device{
+			UserID:   req.UserID,
+			Platform: devices.PlatformIOS,
+			Token:    req.DeviceToken,
+			BundleID: req.BundleID,
+		}
+		n := notifications.Notification{
+			UserID:          req.UserID,
+			Title:           req.Title,
+			Body:            req.Body,
+			Data:            req.CustomData,
+			Badge:           req.Badge,
+			Sound:           req.Sound,
+			Category:        req.Category,
+			ThreadID:        req.ThreadID,
+			MutableContent:  req.MutableContent,
+			ContentState:    req.ContentState,
+			TargetContentID: req.TargetContentID,
+		}
+
+		enqueueDevice(w, r, queue, device, n, req.CollapseKey)
+	}
+}
+
+// decodeAPNSRequest decodes a POSTed APNs request, writing the error
+// response when it is invalid
+func decodeAPNSRequest(w http.ResponseWriter, r *http.Request) (APNSRequest, bool) {
+	var req APNSRequest
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return req, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return req, false
+	}
+	if req.DeviceToken == "" || req.BundleID == "" {
+		http.Error(w, "deviceToken and bundleId are required", http.StatusBadRequest)
+		return req, false
+	}
+	return req, true
+}
+
+// enqueueDevice queues a notification for one device and writes its receipt
+func enqueueDevice(w http.ResponseWriter, r *http.Request, queue *delivery.Queue, device devices.Device, n notifications.Notification, collapseKey string) {
+	receipt, err := queue.EnqueueDevice(r.Context(), device, n, collapseKey)
+	if err != nil {
+		log.Printf("Failed to queue %s notification: %v", device.Platform, err)
+		http.Error(w, "Failed to queue notification", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, receipt)
+}
+
 // handleAPNSSilentNotification handles APNS silent notifications
-func handleAPNSSilentNotification(apnsService *notifications.APNSService) http.HandlerFunc {
+func handleAPNSSilentNotification(apnsService notifications.APNSProvider) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -223,6 +427,7 @@ func handleUserBroadcast(wsHub *websocket.Hub, sseHub *sse.SSEHub) http.HandlerF
 		// Send via WebSocket
 		wsMessage := websocket.Message{
 			Type:      req.Type,
+			Resource:  req.Resource,
 			UserID:    userID,
 			Data:      req.Data,
 			Timestamp: time.Now(),
@@ -260,6 +465,7 @@ func handleHouseholdBroadcast(wsHub *websocket.Hub, sseHub *sse.SSEHub) http.Han
 		// Send via WebSocket
 		wsMessage := websocket.Message{
 			Type:        req.Type,
+			Resource:    req.Resource,
 			HouseholdID: householdID,
 			Data:        req.Data,
 			Timestamp:   time.Now(),
@@ -272,4 +478,510 @@ func handleHouseholdBroadcast(wsHub *websocket.Hub, sseHub *sse.SSEHub) http.Han
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("Message sent"))
 	}
-}
\ No newline at end of file
+}
+
+// handlePresenceSnapshot returns the presence of every member of a household
+func handlePresenceSnapshot(tracker *presence.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		householdID := r.PathValue("id")
+		if householdID == "" {
+			http.Error(w, "household id is required", http.StatusBadRequest)
+			return
+		}
+
+		response := map[string]interface{}{
+			"householdId": householdID,
+			"members":     tracker.Snapshot(householdID),
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// handlePresenceCommand updates what a member is doing, e.g. viewing a
+// shopping list or being in the store
+func handlePresenceCommand(tracker *presence.Tracker) websocket.CommandHandler {
+	return func(ctx context.Context, req websocket.CommandRequest) (interface{}, error) {
+		var update presence.Context
+		if len(req.Data) > 0 {
+			if err := json.Unmarshal(req.Data, &update); err != nil {
+				return nil, err
+			}
+		}
+		if err := update.Validate(); err != nil {
+			return nil, err
+		}
+
+		tracker.SetContext(req.UserID, req.HouseholdID, update)
+		return nil, nil
+	}
+}
+
+// announcePresence broadcasts presence changes to the member's household
+func announcePresence(wsHub *websocket.Hub, sseHub *sse.SSEHub) func(presence.Presence) {
+	return func(p presence.Presence) {
+		wsHub.BroadcastToHousehold(p.HouseholdID, websocket.Message{
+			Type:        "presence.changed",
+			HouseholdID: p.HouseholdID,
+			Data:        p,
+			Timestamp:   time.Now(),
+		})
+		sseHub.SendToHousehold(p.HouseholdID, "presence.changed", p)
+	}
+}
+
+// handleEmailNotification sends an email or queues it for the recipient's digest
+func handleEmailNotification(emailService *notifications.EmailService, digests *notifications.DigestQueue) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req EmailRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if req.To.Email == "" {
+			http.Error(w, "to.email is required", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if (req.Digest == notifications.DigestDaily || req.Digest == notifications.DigestWeekly) && notifications.IsDigestCategory(req.Category) {
+			err := digests.Add(r.Context(), req.To, req.Digest, notifications.DigestItem{
+				HouseholdID:   req.HouseholdID,
+				HouseholdName: req.HouseholdName,
+				Category:      req.Category,
+				Title:         req.Title,
+				Detail:        req.Body,
+				OccurredAt:    time.Now(),
+			})
+			if errors.Is(err, notifications.ErrInvalidDigestItem) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				log.Printf("Failed to queue digest item: %v", err)
+				http.Error(w, "Failed to queue digest item", http.StatusInternalServerError)
+				return
+			}
+
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(map[string]interface{}{"queued": true, "digest": req.Digest})
+			return
+		}
+
+		messageID, err := emailService.Send(r.Context(), req.EmailMessage)
+		if errors.Is(err, notifications.ErrEmailSuppressed) {
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(map[string]interface{}{"suppressed": true})
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to send email notification: %v", err)
+			http.Error(w, "Failed to send notification", http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]string{"messageId": messageID})
+	}
+}
+
+// handleEmailUnsubscribe handles unsubscribe links. A GET only asks the
+// reader to confirm, since link scanners and prefetchers follow links in
+// mail; the address is suppressed by the POST of that confirmation or of
+// one-click unsubscribe (RFC 8058) in mail clients.
+func handleEmailUnsubscribe(emailService *notifications.EmailService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		token := r.URL.Query().Get("token")
+		if r.Method == http.MethodGet {
+			email, category, err := emailService.UnsubscribeTarget(token)
+			if err != nil {
+				http.Error(w, "Invalid unsubscribe link", http.StatusBadRequest)
+				return
+			}
+
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprintf(w, `<html><body><form method="post" action="?token=%s"><p>Stop sending %s to %s?</p><button type="submit">Unsubscribe</button></form></body></html>`,
+				html.EscapeString(url.QueryEscape(token)), unsubscribeWhat(category), html.EscapeString(email))
+			return
+		}
+
+		email, category, err := emailService.Unsubscribe(r.Context(), token)
+		if errors.Is(err, notifications.ErrInvalidUnsubscribeToken) {
+			http.Error(w, "Invalid unsubscribe link", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to unsubscribe: %v", err)
+			http.Error(w, "Failed to unsubscribe", http.StatusInternalServerError)
+			return
+		}
+
+		if r.PostFormValue("List-Unsubscribe") == "One-Click" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, "<html><body><p>%s will no longer receive %s.</p></body></html>", html.EscapeString(email), unsubscribeWhat(category))
+	}
+}
+
+// unsubscribeWhat names the emails an unsubscribe category covers
+func unsubscribeWhat(category string) string {
+	if category == notifications.EmailCategoryDigest {
+		return "House Helper digests"
+	}
+	return "House Helper emails"
+}
+
+// handleEmailBounce records bounces reported by the mail server. Reports
+// must be signed with the bounce secret.
+func handleEmailBounce(emailService *notifications.EmailService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		err = emailService.VerifyBounce(r.Header.Get(notifications.BounceTimestampHeader), r.Header.Get(notifications.BounceSignatureHeader), body, time.Now())
+		if err != nil {
+			http.Error(w, "Invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		var bounce notifications.Bounce
+		if err := json.Unmarshal(body, &bounce); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if bounce.Email == "" {
+			http.Error(w, "email is required", http.StatusBadRequest)
+			return
+		}
+
+		if err := emailService.HandleBounce(r.Context(), bounce); err != nil {
+			log.Printf("Failed to handle bounce for %s: %v", bounce.Email, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// writeJSON writes a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// handleDevices registers, lists and removes native push devices
+func handleDevices(registry devices.Registry, householdTopics *topics.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			userID := r.URL.Query().Get("userId")
+			if userID == "" {
+				http.Error(w, "userId is required", http.StatusBadRequest)
+				return
+			}
+
+			list, err := registry.List(r.Context(), userID)
+			if err != nil {
+				log.Printf("Failed to list devices: %v", err)
+				http.Error(w, "Failed to list devices", http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{"devices": list})
+
+		case http.MethodPost:
+			var device devices.Device
+			if err := json.NewDecoder(r.Body).Decode(&device); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if device.Platform == devices.PlatformWeb {
+				http.Error(w, "Use /webpush/subscriptions for web devices", http.StatusBadRequest)
+				return
+			}
+
+			registered, err := registry.Register(r.Context(), device)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if householdTopics != nil {
+				if err := householdTopics.DeviceRegistered(r.Context(), *registered); err != nil {
+					log.Printf("Failed to subscribe device %s to household topics: %v", registered.ID, err)
+				}
+			}
+			writeJSON(w, http.StatusCreated, registered)
+
+		case http.MethodDelete:
+			userID := r.URL.Query().Get("userId")
+			deviceID := r.URL.Query().Get("deviceId")
+			if userID == "" || deviceID == "" {
+				http.Error(w, "userId and deviceId are required", http.StatusBadRequest)
+				return
+			}
+
+			if householdTopics != nil {
+				unsubscribeDevice(r.Context(), registry, householdTopics, userID, deviceID)
+			}
+			removeDevice(w, r, registry, userID, deviceID)
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// handleWebPushSubscriptions registers and removes browser push subscriptions
+func handleWebPushSubscriptions(registry devices.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req WebPushSubscriptionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if r.Method == http.MethodDelete {
+			if req.UserID == "" || req.Subscription.Endpoint == "" {
+				http.Error(w, "userId and subscription.endpoint are required", http.StatusBadRequest)
+				return
+			}
+			removeDevice(w, r, registry, req.UserID, devices.IDFor(req.Subscription.Endpoint))
+			return
+		}
+
+		subscription := req.Subscription
+		registered, err := registry.Register(r.Context(), devices.Device{
+			UserID:   req.UserID,
+			Platform: devices.PlatformWeb,
+			WebPush:  &subscription,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, http.StatusCreated, registered)
+	}
+}
+
+// removeDevice unregisters a device and writes the response
+func removeDevice(w http.ResponseWriter, r *http.Request, registry devices.Registry, userID, deviceID string) {
+	err := registry.Remove(r.Context(), userID, deviceID)
+	if errors.Is(err, devices.ErrNotFound) {
+		http.Error(w, "Device not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to remove device: %v", err)
+		http.Error(w, "Failed to remove device", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// unsubscribeDevice removes a device that is about to be unregistered from
+// its user's household topics
+func unsubscribeDevice(ctx context.Context, registry devices.Registry, householdTopics *topics.Manager, userID, deviceID string) {
+	userDevices, err := registry.List(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to list devices: %v", err)
+		return
+	}
+	for _, device := range userDevices {
+		if device.ID != deviceID {
+			continue
+		}
+		if err := householdTopics.DeviceRemoved(ctx, device); err != nil {
+			log.Printf("Failed to unsubscribe device %s from household topics: %v", deviceID, err)
+		}
+		return
+	}
+}
+
+// handleVAPIDPublicKey returns the key browsers use to subscribe
+func handleVAPIDPublicKey(webPushService *notifications.WebPushService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"publicKey": webPushService.PublicKey()})
+	}
+}
+
+// handleNotify queues a notification for every registered device of a user
+func handleNotify(queue *delivery.Queue) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req NotifyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.UserID == "" {
+			http.Error(w, "userId is required", http.StatusBadRequest)
+			return
+		}
+
+		// Callers that retry send an Idempotency-Key so a retry does not notify twice
+		receipts, err := queue.EnqueueOnce(r.Context(), r.Header.Get("Idempotency-Key"), req.Notification, req.CollapseKey)
+		switch {
+		case errors.Is(err, notifications.ErrNoDevices):
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "no_devices"})
+		case err != nil:
+			log.Printf("Failed to queue notification: %v", err)
+			http.Error(w, "Failed to queue notification", http.StatusInternalServerError)
+		default:
+			writeJSON(w, http.StatusAccepted, map[string]interface{}{"receipts": receipts})
+		}
+	}
+}
+
+// handleReceipt returns the delivery state of a queued notification
+func handleReceipt(queue *delivery.Queue) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		receipt, err := queue.Receipt(r.Context(), r.PathValue("id"))
+		switch {
+		case errors.Is(err, delivery.ErrNotFound):
+			http.Error(w, "Receipt not found", http.StatusNotFound)
+		case err != nil:
+			log.Printf("Failed to get receipt: %v", err)
+			http.Error(w, "Failed to get receipt", http.StatusInternalServerError)
+		default:
+			writeJSON(w, http.StatusOK, receipt)
+		}
+	}
+}
+
+// handleLiveActivityTokens registers and removes per-activity push tokens
+func handleLiveActivityTokens(store liveactivity.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var token liveactivity.Token
+		if r.Method == http.MethodPost || r.Method == http.MethodDelete {
+			if err := json.NewDecoder(r.Body).Decode(&token); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+
+		switch r.Method {
+		case http.MethodPost:
+			if err := store.RegisterToken(r.Context(), token); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		case http.MethodDelete:
+			if token.ActivityID == "" || token.Token == "" {
+				http.Error(w, "activityId and token are required", http.StatusBadRequest)
+				return
+			}
+			if err := store.RemoveToken(r.Context(), token.ActivityID, token.Token); err != nil {
+				log.Printf("Failed to remove live activity token: %v", err)
+				http.Error(w, "Failed to remove token", http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// handleLiveActivityStartTokens registers and removes push-to-start tokens
+func handleLiveActivityStartTokens(store liveactivity.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var token liveactivity.StartToken
+		if r.Method == http.MethodPost || r.Method == http.MethodDelete {
+			if err := json.NewDecoder(r.Body).Decode(&token); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+
+		switch r.Method {
+		case http.MethodPost:
+			if err := store.RegisterStartToken(r.Context(), token); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		case http.MethodDelete:
+			if token.UserID == "" || token.Token == "" {
+				http.Error(w, "userId and token are required", http.StatusBadRequest)
+				return
+			}
+			if err := store.RemoveStartToken(r.Context(), token.UserID, token.Token); err != nil {
+				log.Printf("Failed to remove live activity start token: %v", err)
+				http.Error(w, "Failed to remove token", http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// handleLiveActivityEvent starts, updates or ends a live activity
+func handleLiveActivityEvent(manager *liveactivity.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var update liveactivity.Update
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		update.ActivityID = r.PathValue("id")
+
+		result, err := manager.Publish(r.Context(), update)
+		switch {
+		case errors.Is(err, liveactivity.ErrNoTokens):
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "no_tokens", "result": result})
+		case errors.Is(err, liveactivity.ErrDeliveryFailed):
+			writeJSON(w, http.StatusBadGateway, map[string]interface{}{"error": "delivery_failed", "result": result})
+		case err != nil && result == nil:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case result.Deferred:
+			writeJSON(w, http.StatusAccepted, result)
+		default:
+			writeJSON(w, http.StatusOK, result)
+		}
+	}
+}
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/househelper/notifier/pkg/delivery"
+	"github.com/househelper/notifier/pkg/devices"
+	"github.com/househelper/notifier/pkg/notifications"
+	"github.com/househelper/notifier/pkg/notifications/notificationstest"
+	"github.com/househelper/notifier/pkg/topics"
+)
+
+const testBundleID = "app.househelper.mobile"
+
+// testNotifier wires the push handlers to fake FCM and APNs servers the same
+// way main does
+type testNotifier struct {
+	fcm      *notificationstest.FCMServer
+	apns     *notificationstest.APNSServer
+	registry devices.Registry
+	mux      *http.ServeMux
+}
+
+func newTestNotifier(t *testing.T) *testNotifier {
+	t.Helper()
+
+	fcmServer := notificationstest.NewFCMServer()
+	t.Cleanup(fcmServer.Close)
+	apnsServer := notificationstest.NewAPNSServer()
+	t.Cleanup(apnsServer.Close)
+
+	fcmService, err := fcmServer.Service(context.Background())
+	if err != nil {
+		t.Fatalf("failed to create FCM service: %v", err)
+	}
+	apnsService := apnsServer.Service()
+
+	registry := devices.NewMemoryRegistry()
+	dispatcher := notifications.NewDispatcher(registry)
+	dispatcher.RegisterChannel(notifications.NewFCMChannel(fcmService))
+	dispatcher.RegisterChannel(notifications.NewAPNSChannel(apnsService))
+
+	config := delivery.DefaultConfig()
+	config.PollInterval = 10 * time.Millisecond
+	config.BaseBackoff = 10 * time.Millisecond
+	config.CollapseWindow = 0
+	queue := delivery.NewQueue(delivery.NewMemoryStore(), delivery.NewMemoryLimiter(), dispatcher, config)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go queue.Run(ctx)
+
+	householdTopics := topics.NewManager(topics.NewMemoryStore(), registry, fcmService)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/notify/fcm/token", handleFCMTokenNotification(fcmService))
+	mux.HandleFunc("/notify/fcm/token/queue", handleQueuedFCMTokenNotification(queue))
+	mux.HandleFunc("/notify/fcm/topic", handleFCMTopicNotification(fcmService))
+	mux.HandleFunc("/v1/households/{id}/members/{userId}", handleHouseholdMember(householdTopics))
+	mux.HandleFunc("POST /v1/households/{id}/announcements", handleHouseholdAnnouncement(householdTopics))
+	mux.HandleFunc("/notify/apns", handleAPNSNotification(apnsService))
+	mux.HandleFunc("/notify/apns/queue", handleQueuedAPNSNotification(queue))
+	mux.HandleFunc("/notify/apns/silent", handleAPNSSilentNotification(apnsService))
+	mux.HandleFunc("/devices", handleDevices(registry, householdTopics))
+	mux.HandleFunc("/notify", handleNotify(queue))
+	mux.HandleFunc("GET /notify/receipts/{id}", handleReceipt(queue))
+
+	return &testNotifier{fcm: fcmServer, apns: apnsServer, registry: registry, mux: mux}
+}
+
+// do sends a request to the notifier and decodes the JSON response into out
+func (n *testNotifier) do(t *testing.T, method, path string, body, out interface{}) int {
+	t.Helper()
+
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			t.Fatalf("failed to marshal request: %v", err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	n.mux.ServeHTTP(rec, httptest.NewRequest(method, path, bytes.NewReader(data)))
+
+	if out != nil && rec.Code < 300 {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: failed to decode %q: %v", method, path, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+// waitForReceipt polls a receipt until it reaches a final status
+func (n *testNotifier) waitForReceipt(t *testing.T, id string) delivery.Receipt {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var receipt delivery.Receipt
+		if code := n.do(t, http.MethodGet, "/notify/receipts/"+id, nil, &receipt); code != http.StatusOK {
+			t.Fatalf("GET receipt %s = %d", id, code)
+		}
+		switch receipt.Status {
+		case delivery.StatusSent, delivery.StatusFailed, delivery.StatusUnregistered:
+			return receipt
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("receipt %s still %s", id, receipt.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (n *testNotifier) registerDevice(t *testing.T, device devices.Device) devices.Device {
+	t.Helper()
+	var registered devices.Device
+	if code := n.do(t, http.MethodPost, "/devices", device, &registered); code != http.StatusCreated {
+		t.Fatalf("POST /devices = %d", code)
+	}
+	return registered
+}
+
+func TestFCMTokenNotification(t *testing.T) {
+	n := newTestNotifier(t)
+
+	var response map[string]string
+	code := n.do(t, http.MethodPost, "/notify/fcm/token", FCMTokenRequest{Token: "android-token", Title: "Task Completed"}, &response)
+	if code != http.StatusOK || response["messageId"] == "" {
+		t.Fatalf("POST /notify/fcm/token = %d %v, want 200 with the message ID", code, response)
+	}
+
+	messages := n.fcm.Messages()
+	if len(messages) != 1 || messages[0].Token != "android-token" {
+		t.Errorf("messages = %+v, want one sent to the token", messages)
+	}
+}
+
+func TestFCMTokenNotificationIsDelivered(t *testing.T) {
+	n := newTestNotifier(t)
+
+	var receipt delivery.Receipt
+	code := n.do(t, http.MethodPost, "/notify/fcm/token/queue", FCMTokenRequest{
+		UserID: "user-1",
+		Token:  "android-token",
+		Title:  "Task Completed",
+		Body:   "Dana completed the laundry task",
+		Data:   map[string]string{"taskId": "task-1"},
+	}, &receipt)
+	if code != http.StatusAccepted {
+		t.Fatalf("POST /notify/fcm/token/queue = %d, want 202", code)
+	}
+
+	if final := n.waitForReceipt(t, receipt.ID); final.Status != delivery.StatusSent {
+		t.Fatalf("receipt = %+v, want sent", final)
+	}
+
+	messages := n.fcm.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("FCM received %d messages, want 1", len(messages))
+	}
+	if messages[0].Token != "android-token" || messages[0].Title != "Task Completed" || messages[0].Data["taskId"] != "task-1" {
+		t.Errorf("message = %+v", messages[0])
+	}
+}
+
+func TestFCMTooManyRequestsIsRetried(t *testing.T) {
+	n := newTestNotifier(t)
+	n.fcm.Throttle(1, time.Second)
+
+	var receipt delivery.Receipt
+	n.do(t, http.MethodPost, "/notify/fcm/token/queue", FCMTokenRequest{Token: "android-token", Title: "Bill due"}, &receipt)
+
+	final := n.waitForReceipt(t, receipt.ID)
+	if final.Status != delivery.StatusSent || final.Attempts != 2 {
+		t.Fatalf("receipt = %+v, want sent on the second attempt", final)
+	}
+	if final.SentAt.Sub(final.CreatedAt) < time.Second {
+		t.Errorf("retried after %s, want at least the Retry-After of 1s", final.SentAt.Sub(final.CreatedAt))
+	}
+}
+
+func TestFCMInvalidPayloadIsNotRetried(t *testing.T) {
+	n := newTestNotifier(t)
+
+	var receipt delivery.Receipt
+	n.do(t, http.MethodPost, "/notify/fcm/token/queue", FCMTokenRequest{Token: "android-token", Title: "Bill due", ImageURL: "not a url"}, &receipt)
+
+	final := n.waitForReceipt(t, receipt.ID)
+	if final.Status != delivery.StatusFailed || final.Attempts != 1 {
+		t.Fatalf("receipt = %+v, want failed after one attempt", final)
+	}
+	if messages := n.fcm.Messages(); len(messages) != 0 {
+		t.Errorf("FCM received %d messages, want none", len(messages))
+	}
+}
+
+func TestUnregisteredDevicesAreRemoved(t *testing.T) {
+	n := newTestNotifier(t)
+	n.registerDevice(t, devices.Device{UserID: "user-1", Platform: devices.PlatformAndroid, Token: "android-gone"})
+	n.registerDevice(t, devices.Device{UserID: "user-1", Platform: devices.PlatformIOS, Token: "ios-gone", BundleID: testBundleID})
+	n.fcm.Unregister("android-gone")
+	n.apns.Unregister("ios-gone")
+
+	var queued struct {
+		Receipts []delivery.Receipt `json:"receipts"`
+	}
+	code := n.do(t, http.MethodPost, "/notify", NotifyRequest{
+		Notification: notifications.Notification{UserID: "user-1", Title: "Laundry done"},
+	}, &queued)
+	if code != http.StatusAccepted || len(queued.Receipts) != 2 {
+		t.Fatalf("POST /notify = %d with %d receipts, want 202 with 2", code, len(queued.Receipts))
+	}
+
+	for _, receipt := range queued.Receipts {
+		if final := n.waitForReceipt(t, receipt.ID); final.Status != delivery.StatusUnregistered {
+			t.Errorf("%s receipt = %+v, want unregistered", receipt.Platform, final)
+		}
+	}
+
+	remaining, _ := n.registry.List(context.Background(), "user-1")
+	if len(remaining) != 0 {
+		t.Errorf("registry still has %d devices", len(remaining))
+	}
+	if code := n.do(t, http.MethodPost, "/notify", NotifyRequest{
+		Notification: notifications.Notification{UserID: "user-1"},
+	}, nil); code != http.StatusNotFound {
+		t.Errorf("POST /notify without devices = %d, want 404", code)
+	}
+}
+
+func TestFCMTopicNotification(t *testing.T) {
+	n := newTestNotifier(t)
+
+	var response map[string]string
+	code := n.do(t, http.MethodPost, "/notify/fcm/topic", FCMTopicRequest{Topic: "news", Title: "New feature"}, &response)
+	if code != http.StatusOK || response["messageId"] == "" {
+		t.Fatalf("POST /notify/fcm/topic = %d %v", code, response)
+	}
+
+	messages := n.fcm.Messages()
+	if len(messages) != 1 || messages[0].Topic != "news" {
+		t.Errorf("messages = %+v, want one sent to the news topic", messages)
+	}
+}
+
+func TestHouseholdTopicMembership(t *testing.T) {
+	n := newTestNotifier(t)
+	n.registerDevice(t, devices.Device{UserID: "user-1", Platform: devices.PlatformAndroid, Token: "android-1"})
+
+	var result topics.Result
+	if code := n.do(t, http.MethodPut, "/v1/households/h1/members/user-1", nil, &result); code != http.StatusOK {
+		t.Fatalf("PUT member = %d", code)
+	}
+	if result.Topic != "household-h1" || result.Updated != 1 {
+		t.Errorf("join result = %+v", result)
+	}
+
+	// Devices registered after joining are subscribed too
+	later := n.registerDevice(t, devices.Device{UserID: "user-1", Platform: devices.PlatformAndroid, Token: "android-2"})
+	if got := n.fcm.Subscribers("household-h1"); len(got) != 2 {
+		t.Fatalf("subscribers = %v, want both devices", got)
+	}
+
+	if code := n.do(t, http.MethodPost, "/v1/households/h1/announcements", FCMTopicRequest{Title: "House meeting"}, nil); code != http.StatusOK {
+		t.Fatalf("POST announcement = %d", code)
+	}
+	if messages := n.fcm.Messages(); len(messages) != 1 || messages[0].Topic != "household-h1" {
+		t.Errorf("messages = %+v, want the announcement sent to the household topic", messages)
+	}
+
+	// Removing a device unsubscribes its token
+	if code := n.do(t, http.MethodDelete, "/devices?userId=user-1&deviceId="+later.ID, nil, nil); code != http.StatusNoContent {
+		t.Fatalf("DELETE device = %d", code)
+	}
+	if got := n.fcm.Subscribers("household-h1"); len(got) != 1 || got[0] != "android-1" {
+		t.Errorf("subscribers after device removal = %v", got)
+	}
+
+	if code := n.do(t, http.MethodDelete, "/v1/households/h1/members/user-1", nil, nil); code != http.StatusOK {
+		t.Fatalf("DELETE member = %d", code)
+	}
+	if got := n.fcm.Subscribers("household-h1"); len(got) != 0 {
+		t.Errorf("subscribers after leaving = %v", got)
+	}
+}
+
+func TestAPNSNotification(t *testing.T) {
+	n := newTestNotifier(t)
+
+	var response map[string]interface{}
+	code := n.do(t, http.MethodPost, "/notify/apns", APNSRequest{DeviceToken: "ios-token", BundleID: testBundleID, Title: "Timer Finished"}, &response)
+	if code != http.StatusOK || response["sent"] != true || response["statusCode"] != float64(http.StatusOK) || response["apnsId"] == "" {
+		t.Fatalf("POST /notify/apns = %d %v, want 200 with the APNs result", code, response)
+	}
+
+	if pushes := n.apns.Notifications(); len(pushes) != 1 || pushes[0].Topic != testBundleID {
+		t.Errorf("pushes = %+v, want one for the bundle", pushes)
+	}
+}
+
+func TestAPNSNotificationIsDelivered(t *testing.T) {
+	n := newTestNotifier(t)
+	badge := 3
+
+	var receipt delivery.Receipt
+	code := n.do(t, http.MethodPost, "/notify/apns/queue", APNSRequest{
+		DeviceToken:     "ios-token",
+		BundleID:        testBundleID,
+		Title:           "Timer Finished",
+		Body:            "Your laundry timer has finished",
+		Badge:           &badge,
+		CustomData:      map[string]string{"timerId": "timer-1"},
+		ContentState:    map[string]string{"status": "finished"},
+		TargetContentID: "timer-1",
+	}, &receipt)
+	if code != http.StatusAccepted {
+		t.Fatalf("POST /notify/apns/queue = %d, want 202", code)
+	}
+
+	if final := n.waitForReceipt(t, receipt.ID); final.Status != delivery.StatusSent {
+		t.Fatalf("receipt = %+v, want sent", final)
+	}
+
+	pushes := n.apns.Notifications()
+	if len(pushes) != 1 {
+		t.Fatalf("APNs received %d pushes, want 1", len(pushes))
+	}
+	push := pushes[0]
+	if title, body := push.Alert(); title != "Timer Finished" || body != "Your laundry timer has finished" {
+		t.Errorf("alert = %q / %q", title, body)
+	}
+	if push.Topic != testBundleID || push.Payload["timerId"] != "timer-1" {
+		t.Errorf("push = %+v", push)
+	}
+	contentState, _ := push.Payload["content-state"].(map[string]interface{})
+	if contentState["status"] != "finished" || push.Payload["target-content-id"] != "timer-1" {
+		t.Errorf("content-state = %v, target-content-id = %v", push.Payload["content-state"], push.Payload["target-content-id"])
+	}
+}
+
+func TestAPNSTooManyRequestsIsRetried(t *testing.T) {
+	n := newTestNotifier(t)
+	n.apns.Throttle(2)
+
+	var receipt delivery.Receipt
+	n.do(t, http.MethodPost, "/notify/apns/queue", APNSRequest{DeviceToken: "ios-token", BundleID: testBundleID, Title: "Chore assigned"}, &receipt)
+
+	final := n.waitForReceipt(t, receipt.ID)
+	if final.Status != delivery.StatusSent || final.Attempts != 3 {
+		t.Fatalf("receipt = %+v, want sent on the third attempt", final)
+	}
+}
+
+func TestAPNSSilentNotification(t *testing.T) {
+	n := newTestNotifier(t)
+
+	var response struct {
+		Sent bool `json:"sent"`
+	}
+	code := n.do(t, http.MethodPost, "/notify/apns/silent", map[string]interface{}{
+		"deviceToken": "ios-token",
+		"bundleId":    testBundleID,
+		"customData":  map[string]string{"sync": "shopping"},
+	}, &response)
+	if code != http.StatusOK || !response.Sent {
+		t.Fatalf("POST /notify/apns/silent = %d %+v", code, response)
+	}
+
+	pushes := n.apns.Notifications()
+	if len(pushes) != 1 {
+		t.Fatalf("APNs received %d pushes, want 1", len(pushes))
+	}
+	aps, _ := pushes[0].Payload["aps"].(map[string]interface{})
+	if aps["content-available"] != float64(1) || pushes[0].Payload["sync"] != "shopping" {
+		t.Errorf("silent payload = %v", pushes[0].Payload)
+	}
+
+	n.apns.Unregister("ios-gone")
+	code = n.do(t, http.MethodPost, "/notify/apns/silent", map[string]interface{}{
+		"deviceToken": "ios-gone",
+		"bundleId":    testBundleID,
+	}, nil)
+	if code != http.StatusInternalServerError {
+		t.Errorf("silent push to unregistered token = %d, want 500", code)
+	}
+}
+
+func TestUnsubscribeLinkAsksForConfirmation(t *testing.T) {
+	emailService, err := notifications.NewEmailService(notifications.EmailConfig{
+		From:          "notifications@house-helper.local",
+		BaseURL:       "http://notifier.test",
+		SigningSecret: "secret",
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to create email service: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/email/unsubscribe", handleEmailUnsubscribe(emailService))
+	link := strings.TrimPrefix(emailService.UnsubscribeURL("alice@example.com", notifications.EmailCategoryAll), "http://notifier.test")
+	msg := notifications.EmailMessage{To: notifications.EmailRecipient{Email: "alice@example.com"}, Title: "Hello"}
+
+	// A scanner following the link must not unsubscribe anyone
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, link, nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `method="post"`) {
+		t.Fatalf("GET unsubscribe = %d %q, want a confirmation form", rec.Code, rec.Body.String())
+	}
+	if _, err := emailService.Send(context.Background(), msg); errors.Is(err, notifications.ErrEmailSuppressed) {
+		t.Fatal("address suppressed by a GET")
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, link+"x", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("POST with tampered token = %d, want 400", rec.Code)
+	}
+
+	// One-click unsubscribe from the mail client
+	req := httptest.NewRequest(http.MethodPost, link, strings.NewReader("List-Unsubscribe=One-Click"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("one-click POST = %d", rec.Code)
+	}
+	if _, err := emailService.Send(context.Background(), msg); !errors.Is(err, notifications.ErrEmailSuppressed) {
+		t.Errorf("expected suppression after one-click unsubscribe, got %v", err)
+	}
+}
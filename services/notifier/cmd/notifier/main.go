@@ -6,12 +6,19 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
-	"github.com/house-helper/notifier/pkg/notifications"
-	"github.com/house-helper/notifier/pkg/sse"
-	"github.com/house-helper/notifier/pkg/websocket"
+	"github.com/househelper/notifier/pkg/backplane"
+	"github.com/househelper/notifier/pkg/delivery"
+	"github.com/househelper/notifier/pkg/devices"
+	"github.com/househelper/notifier/pkg/liveactivity"
+	"github.com/househelper/notifier/pkg/notifications"
+	"github.com/househelper/notifier/pkg/presence"
+	"github.com/househelper/notifier/pkg/sse"
+	"github.com/househelper/notifier/pkg/topics"
+	"github.com/househelper/notifier/pkg/websocket"
 )
 
 func main() {
@@ -59,12 +66,190 @@ func main() {
 		}
 	}
 
-	// Initialize WebSocket hub
-	wsHub := websocket.NewHub()
-	go wsHub.Run()
+	// Initialize the device registry shared by all push channels
+	var registry devices.Registry = devices.NewMemoryRegistry()
+	if config.RedisURL != "" {
+		redisRegistry, err := devices.NewRedisRegistry(context.Background(), config.RedisURL)
+		if err != nil {
+			log.Printf("Failed to initialize Redis device registry, using memory: %v", err)
+		} else {
+			registry = redisRegistry
+		}
+	}
+
+	// Initialize Web Push
+	var webPushService *notifications.WebPushService
+	var vapidKeys notifications.VAPIDKeys
+	if config.VAPIDPublicKey == "" && config.VAPIDPrivateKey == "" && config.RedisURL != "" {
+		vapidKeys, err = notifications.LoadSharedVAPIDKeys(context.Background(), config.RedisURL)
+	} else {
+		vapidKeys, err = notifications.LoadVAPIDKeys(config.VAPIDPublicKey, config.VAPIDPrivateKey, config.VAPIDKeysPath)
+	}
+	if err != nil {
+		log.Printf("Failed to load VAPID keys: %v", err)
+	} else {
+		webPushService, err = notifications.NewWebPushService(vapidKeys, config.VAPIDSubject)
+		if err != nil {
+			log.Printf("Failed to initialize Web Push service: %v", err)
+		} else {
+			log.Println("Web Push service initialized successfully")
+		}
+	}
+
+	// Route notifications to each device through its platform's channel
+	dispatcher := notifications.NewDispatcher(registry)
+	if fcmService != nil {
+		dispatcher.RegisterChannel(notifications.NewFCMChannel(fcmService))
+	}
+	if apnsService != nil {
+		dispatcher.RegisterChannel(notifications.NewAPNSChannel(apnsService))
+	}
+	if webPushService != nil {
+		dispatcher.RegisterChannel(notifications.NewWebPushChannel(webPushService))
+	}
+
+	// Household members' Android devices follow their household's FCM topic
+	var householdTopics *topics.Manager
+	if fcmService != nil {
+		var topicStore topics.Store = topics.NewMemoryStore()
+		if config.RedisURL != "" {
+			redisStore, err := topics.NewRedisStore(context.Background(), config.RedisURL)
+			if err != nil {
+				log.Printf("Failed to initialize Redis topic store, using memory: %v", err)
+			} else {
+				topicStore = redisStore
+			}
+		}
+		householdTopics = topics.NewManager(topicStore, registry, fcmService)
+	}
+
+	// Live Activities are driven by workflows through APNs
+	var liveActivities *liveactivity.Manager
+	var liveActivityStore liveactivity.Store = liveactivity.NewMemoryStore()
+	if apnsService != nil {
+		if config.RedisURL != "" {
+			redisStore, err := liveactivity.NewRedisStore(context.Background(), config.RedisURL)
+			if err != nil {
+				log.Printf("Failed to initialize Redis live activity store, using memory: %v", err)
+			} else {
+				liveActivityStore = redisStore
+			}
+		}
+
+		liveActivities = liveactivity.NewManager(liveActivityStore, apnsService, liveactivity.Config{
+			MinInterval:        time.Duration(config.LiveActivityMinIntervalSec) * time.Second,
+			HighPriorityBudget: config.LiveActivityHighPriorityBudget,
+		})
+	}
+
+	// Background loops stop when the service shuts down
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// Queue outbound pushes so provider failures are retried instead of lost
+	var deliveryStore delivery.Store = delivery.NewMemoryStore()
+	var deliveryLimiter delivery.Limiter = delivery.NewMemoryLimiter()
+	if config.RedisURL != "" {
+		redisStore, err := delivery.NewRedisStore(context.Background(), config.RedisURL)
+		if err != nil {
+			log.Printf("Failed to initialize Redis delivery queue, using memory: %v", err)
+		} else {
+			deliveryStore = redisStore
+		}
+
+		redisLimiter, err := delivery.NewRedisLimiter(context.Background(), config.RedisURL)
+		if err != nil {
+			log.Printf("Failed to initialize Redis rate limiter, using memory: %v", err)
+		} else {
+			deliveryLimiter = redisLimiter
+		}
+	} else {
+		log.Println("REDIS_URL not set, queued notifications will not survive a restart")
+	}
+
+	deliveryConfig := delivery.DefaultConfig()
+	deliveryConfig.Workers = config.DeliveryWorkers
+	deliveryConfig.MaxAttempts = config.DeliveryMaxAttempts
+	deliveryConfig.CollapseWindow = time.Duration(config.DeliveryCollapseWindowSec) * time.Second
+	deliveryConfig.UserRateLimit = config.DeliveryUserRateLimit
+	deliveryQueue := delivery.NewQueue(deliveryStore, deliveryLimiter, dispatcher, deliveryConfig)
+
+	go deliveryQueue.Run(backgroundCtx)
+
+	// Initialize the email channel if an SMTP server is configured
+	var emailService *notifications.EmailService
+	var digestQueue *notifications.DigestQueue
+	if config.SMTPHost != "" {
+		var suppressions notifications.SuppressionList
+		if config.RedisURL != "" {
+			suppressions, err = notifications.NewRedisSuppressionList(context.Background(), config.RedisURL)
+			if err != nil {
+				log.Printf("Failed to initialize Redis suppression list, using memory: %v", err)
+				suppressions = nil
+			}
+		}
+
+		emailService, err = notifications.NewEmailService(notifications.EmailConfig{
+			Host:          config.SMTPHost,
+			Port:          config.SMTPPort,
+			Username:      config.SMTPUsername,
+			Password:      config.SMTPPassword,
+			From:          config.EmailFrom,
+			FromName:      config.EmailFromName,
+			BounceAddress: config.EmailBounceAddress,
+			BaseURL:       config.PublicURL,
+			SigningSecret: config.EmailSigningSecret,
+			BounceSecret:  config.EmailBounceSecret,
+		}, suppressions)
+		if err != nil {
+			log.Printf("Failed to initialize email service: %v", err)
+		} else {
+			var digestStore notifications.DigestStore = notifications.NewMemoryDigestStore()
+			if config.RedisURL != "" {
+				redisDigests, err := notifications.NewRedisDigestStore(context.Background(), config.RedisURL)
+				if err != nil {
+					log.Printf("Failed to initialize Redis digest store, using memory: %v", err)
+				} else {
+					digestStore = redisDigests
+				}
+			}
+			digestQueue = notifications.NewDigestQueueWithStore(emailService, digestStore, config.EmailDigestHour, time.UTC)
+			log.Println("Email service initialized successfully")
+		}
+	}
 
-	// Initialize SSE hub
-	sseHub := sse.NewSSEHub()
+	// Initialize the backplane so broadcasts reach clients on every replica
+	var bp backplane.Backplane
+	if config.RedisURL != "" {
+		bp, err = backplane.NewRedisBackplane(context.Background(), config.RedisURL, config.BackplaneChannel)
+		if err != nil {
+			log.Printf("Failed to initialize Redis backplane, broadcasts will stay local: %v", err)
+			bp = nil
+		} else {
+			log.Println("Redis backplane initialized successfully")
+		}
+	}
+
+	// Initialize WebSocket and SSE hubs
+	var wsHub *websocket.Hub
+	var sseHub *sse.SSEHub
+	if bp != nil {
+		wsHub = websocket.NewHubWithBackplane(bp)
+		sseHub = sse.NewSSEHubWithBackplane(bp)
+	} else {
+		wsHub = websocket.NewHub()
+		sseHub = sse.NewSSEHub()
+	}
+
+	// Track household presence from WebSocket connections
+	presenceTracker := presence.NewTracker(config.ReplicaID, bp)
+	presenceTracker.OnChange(announcePresence(wsHub, sseHub))
+	wsHub.SetObserver(presenceTracker)
+	wsHub.HandleCommand("presence.update", handlePresenceCommand(presenceTracker))
+
+	go presenceTracker.Run(backgroundCtx)
+
+	go wsHub.Run()
 	go sseHub.Run()
 
 	// Setup HTTP server
@@ -105,18 +290,55 @@ func main() {
 	// Push notification endpoints
 	if fcmService != nil {
 		mux.HandleFunc("/notify/fcm/token", handleFCMTokenNotification(fcmService))
+		mux.HandleFunc("/notify/fcm/token/queue", handleQueuedFCMTokenNotification(deliveryQueue))
 		mux.HandleFunc("/notify/fcm/topic", handleFCMTopicNotification(fcmService))
+		mux.HandleFunc("/v1/households/{id}/members/{userId}", handleHouseholdMember(householdTopics))
+		mux.HandleFunc("POST /v1/households/{id}/announcements", handleHouseholdAnnouncement(householdTopics))
 	}
 
 	if apnsService != nil {
 		mux.HandleFunc("/notify/apns", handleAPNSNotification(apnsService))
+		mux.HandleFunc("/notify/apns/queue", handleQueuedAPNSNotification(deliveryQueue))
 		mux.HandleFunc("/notify/apns/silent", handleAPNSSilentNotification(apnsService))
 	}
 
+	// Live Activity endpoints
+	if liveActivities != nil {
+		mux.HandleFunc("/live-activities/tokens", handleLiveActivityTokens(liveActivityStore))
+		mux.HandleFunc("/live-activities/start-tokens", handleLiveActivityStartTokens(liveActivityStore))
+		mux.HandleFunc("POST /live-activities/{id}/events", handleLiveActivityEvent(liveActivities))
+	}
+
 	// Real-time message endpoints
 	mux.HandleFunc("/broadcast/user", handleUserBroadcast(wsHub, sseHub))
 	mux.HandleFunc("/broadcast/household", handleHouseholdBroadcast(wsHub, sseHub))
 
+	// Device registration and dispatch endpoints
+	mux.HandleFunc("/devices", handleDevices(registry, householdTopics))
+	mux.HandleFunc("/notify", handleNotify(deliveryQueue))
+	mux.HandleFunc("GET /notify/receipts/{id}", handleReceipt(deliveryQueue))
+
+	// Web Push endpoints
+	if webPushService != nil {
+		mux.HandleFunc("/webpush/vapid-public-key", handleVAPIDPublicKey(webPushService))
+		mux.HandleFunc("/webpush/subscriptions", handleWebPushSubscriptions(registry))
+	}
+
+	// Email endpoints
+	if emailService != nil {
+		mux.HandleFunc("/notify/email", handleEmailNotification(emailService, digestQueue))
+		mux.HandleFunc("/email/unsubscribe", handleEmailUnsubscribe(emailService))
+		mux.HandleFunc("/email/bounces", handleEmailBounce(emailService))
+		if config.EmailBounceSecret == "" {
+			log.Println("EMAIL_BOUNCE_SECRET is not set, bounce reports will be rejected")
+		}
+
+		go digestQueue.Run(backgroundCtx)
+	}
+
+	// Presence endpoints
+	mux.HandleFunc("GET /v1/households/{id}/presence", handlePresenceSnapshot(presenceTracker))
+
 	// Create HTTP server
 	server := &http.Server{
 		Addr:         config.Port,
@@ -150,6 +372,11 @@ func main() {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	// Close backplane
+	if bp != nil {
+		bp.Close()
+	}
+
 	// Close APNS service
 	if apnsService != nil {
 		apnsService.Close()
@@ -167,6 +394,32 @@ type Config struct {
 	APNSKeyID          string
 	APNSTeamID         string
 	APNSProduction     bool
+	RedisURL           string
+	BackplaneChannel   string
+	ReplicaID          string
+	PublicURL          string
+	SMTPHost           string
+	SMTPPort           string
+	SMTPUsername       string
+	SMTPPassword       string
+	EmailFrom          string
+	EmailFromName      string
+	EmailBounceAddress string
+	EmailSigningSecret string
+	EmailBounceSecret  string
+	EmailDigestHour    int
+	VAPIDPublicKey     string
+	VAPIDPrivateKey    string
+	VAPIDKeysPath      string
+	VAPIDSubject       string
+
+	LiveActivityMinIntervalSec     int
+	LiveActivityHighPriorityBudget int
+
+	DeliveryWorkers           int
+	DeliveryMaxAttempts       int
+	DeliveryCollapseWindowSec int
+	DeliveryUserRateLimit     int
 }
 
 // loadConfig loads configuration from environment variables
@@ -179,6 +432,32 @@ func loadConfig() Config {
 		APNSKeyID:          getEnv("APNS_KEY_ID", ""),
 		APNSTeamID:         getEnv("APNS_TEAM_ID", ""),
 		APNSProduction:     getEnv("APNS_PRODUCTION", "false") == "true",
+		RedisURL:           getEnv("REDIS_URL", ""),
+		BackplaneChannel:   getEnv("BACKPLANE_CHANNEL", backplane.DefaultChannel),
+		ReplicaID:          getEnv("POD_NAME", hostname()),
+		PublicURL:          getEnv("PUBLIC_URL", "http://localhost:8083"),
+		SMTPHost:           getEnv("SMTP_HOST", ""),
+		SMTPPort:           getEnv("SMTP_PORT", "1025"),
+		SMTPUsername:       getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:       getEnv("SMTP_PASSWORD", ""),
+		EmailFrom:          getEnv("EMAIL_FROM", "notifications@house-helper.local"),
+		EmailFromName:      getEnv("EMAIL_FROM_NAME", "House Helper"),
+		EmailBounceAddress: getEnv("EMAIL_BOUNCE_ADDRESS", ""),
+		EmailSigningSecret: getEnv("EMAIL_SIGNING_SECRET", ""),
+		EmailBounceSecret:  getEnv("EMAIL_BOUNCE_SECRET", ""),
+		EmailDigestHour:    getEnvInt("EMAIL_DIGEST_HOUR", 7),
+		VAPIDPublicKey:     getEnv("VAPID_PUBLIC_KEY", ""),
+		VAPIDPrivateKey:    getEnv("VAPID_PRIVATE_KEY", ""),
+		VAPIDKeysPath:      getEnv("VAPID_KEYS_PATH", "data/vapid-keys.json"),
+		VAPIDSubject:       getEnv("VAPID_SUBJECT", "mailto:notifications@house-helper.local"),
+
+		LiveActivityMinIntervalSec:     getEnvInt("LIVE_ACTIVITY_MIN_INTERVAL_SECONDS", 15),
+		LiveActivityHighPriorityBudget: getEnvInt("LIVE_ACTIVITY_HIGH_PRIORITY_BUDGET", 10),
+
+		DeliveryWorkers:           getEnvInt("DELIVERY_WORKERS", 2),
+		DeliveryMaxAttempts:       getEnvInt("DELIVERY_MAX_ATTEMPTS", 8),
+		DeliveryCollapseWindowSec: getEnvInt("DELIVERY_COLLAPSE_WINDOW_SECONDS", 60),
+		DeliveryUserRateLimit:     getEnvInt("DELIVERY_USER_RATE_LIMIT", 20),
 	}
 }
 
@@ -190,6 +469,25 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvInt gets an integer environment variable with a default value
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if intValue, err := strconv.Atoi(value); err == nil {
+			return intValue
+		}
+	}
+	return defaultValue
+}
+
+// hostname returns the host name used to identify this replica
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return "notifier"
+	}
+	return name
+}
+
 // corsMiddleware adds CORS headers
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,68 @@
+package backplane
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// DefaultChannel is the pub/sub channel shared by all notifier replicas
+const DefaultChannel = "house-helper:notifier:broadcast"
+
+// Message is the envelope exchanged between notifier replicas
+type Message struct {
+	// Hub identifies the hub that should deliver the message (e.g. "websocket", "sse")
+	Hub string `json:"hub"`
+
+	// UserID targets all connections of a single user
+	UserID string `json:"userId,omitempty"`
+
+	// HouseholdID targets all connections of a household
+	HouseholdID string `json:"householdId,omitempty"`
+
+	// Payload is the hub specific message, already encoded as JSON
+	Payload json.RawMessage `json:"payload"`
+}
+
+// Handler is invoked for every message received from the backplane
+type Handler func(msg Message)
+
+// Backplane fans broadcast messages out to every notifier replica. Each
+// replica subscribes its hubs and delivers received messages to its local
+// clients only.
+type Backplane interface {
+	// Publish sends a message to all replicas, including the publishing one
+	Publish(ctx context.Context, msg Message) error
+
+	// Subscribe registers a handler for messages addressed to the given hub
+	Subscribe(hub string, handler Handler)
+
+	// Close releases the underlying connections
+	Close() error
+}
+
+// handlerSet keeps subscribed handlers grouped by hub
+type handlerSet struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+func newHandlerSet() *handlerSet {
+	return &handlerSet{handlers: make(map[string][]Handler)}
+}
+
+func (s *handlerSet) add(hub string, handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[hub] = append(s.handlers[hub], handler)
+}
+
+func (s *handlerSet) dispatch(msg Message) {
+	s.mu.RLock()
+	handlers := append([]Handler(nil), s.handlers[msg.Hub]...)
+	s.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(msg)
+	}
+}
@@ -0,0 +1,56 @@
+package backplane
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrClosed is returned when publishing on a closed backplane
+var ErrClosed = errors.New("backplane is closed")
+
+// MemoryBackplane is an in-process backplane. Several hubs sharing one
+// instance behave like replicas connected to the same Redis channel, which
+// makes it suitable for tests and single-node development.
+type MemoryBackplane struct {
+	*MemorySequencer
+
+	handlers *handlerSet
+	mu       sync.RWMutex
+	closed   bool
+}
+
+// NewMemoryBackplane creates a new in-memory backplane
+func NewMemoryBackplane() *MemoryBackplane {
+	return &MemoryBackplane{
+		MemorySequencer: NewMemorySequencer(),
+		handlers:        newHandlerSet(),
+	}
+}
+
+// Publish delivers the message synchronously to all subscribed handlers
+func (b *MemoryBackplane) Publish(ctx context.Context, msg Message) error {
+	b.mu.RLock()
+	closed := b.closed
+	b.mu.RUnlock()
+
+	if closed {
+		return ErrClosed
+	}
+
+	b.handlers.dispatch(msg)
+	return nil
+}
+
+// Subscribe registers a handler for messages addressed to the given hub
+func (b *MemoryBackplane) Subscribe(hub string, handler Handler) {
+	b.handlers.add(hub, handler)
+}
+
+// Close marks the backplane as closed
+func (b *MemoryBackplane) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return nil
+}
@@ -0,0 +1,118 @@
+package backplane
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisBackplane distributes messages between replicas using Redis pub/sub
+type RedisBackplane struct {
+	client   *redis.Client
+	pubsub   *redis.PubSub
+	channel  string
+	handlers *handlerSet
+}
+
+// NewRedisBackplane connects to Redis and subscribes to the given channel
+func NewRedisBackplane(ctx context.Context, redisURL, channel string) (*RedisBackplane, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse redis url: %w", err)
+	}
+
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	pubsub := client.Subscribe(ctx, channel)
+
+	// Wait for the subscription to be confirmed so that no message published
+	// after this constructor returns is missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		client.Close()
+		return nil, fmt.Errorf("failed to subscribe to channel %s: %w", channel, err)
+	}
+
+	b := &RedisBackplane{
+		client:   client,
+		pubsub:   pubsub,
+		channel:  channel,
+		handlers: newHandlerSet(),
+	}
+
+	go b.listen()
+
+	return b, nil
+}
+
+// Publish sends the message to every replica subscribed to the channel
+func (b *RedisBackplane) Publish(ctx context.Context, msg Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal backplane message: %w", err)
+	}
+
+	if err := b.client.Publish(ctx, b.channel, data).Err(); err != nil {
+		return fmt.Errorf("failed to publish backplane message: %w", err)
+	}
+
+	return nil
+}
+
+// Subscribe registers a handler for messages addressed to the given hub
+func (b *RedisBackplane) Subscribe(hub string, handler Handler) {
+	b.handlers.add(hub, handler)
+}
+
+// NextSequence increments the shared sequence for the key using INCR
+func (b *RedisBackplane) NextSequence(ctx context.Context, key string) (uint64, error) {
+	seq, err := b.client.Incr(ctx, b.sequenceKey(key)).Uint64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment sequence %s: %w", key, err)
+	}
+	return seq, nil
+}
+
+// CurrentSequence returns the last sequence handed out for the key
+func (b *RedisBackplane) CurrentSequence(ctx context.Context, key string) (uint64, error) {
+	seq, err := b.client.Get(ctx, b.sequenceKey(key)).Uint64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read sequence %s: %w", key, err)
+	}
+	return seq, nil
+}
+
+func (b *RedisBackplane) sequenceKey(key string) string {
+	return b.channel + ":seq:" + key
+}
+
+// Close unsubscribes and closes the Redis connection
+func (b *RedisBackplane) Close() error {
+	if err := b.pubsub.Close(); err != nil {
+		log.Printf("Failed to close redis subscription: %v", err)
+	}
+	return b.client.Close()
+}
+
+// listen dispatches messages received from Redis to the local hubs
+func (b *RedisBackplane) listen() {
+	for raw := range b.pubsub.Channel() {
+		var msg Message
+		if err := json.Unmarshal([]byte(raw.Payload), &msg); err != nil {
+			log.Printf("Failed to decode backplane message: %v", err)
+			continue
+		}
+		b.handlers.dispatch(msg)
+	}
+}
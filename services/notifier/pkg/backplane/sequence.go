@@ -0,0 +1,43 @@
+package backplane
+
+import (
+	"context"
+	"sync"
+)
+
+// Sequencer hands out monotonic sequence numbers per key. Backplanes that
+// span several replicas implement it so that every replica agrees on the
+// numbering of a stream.
+type Sequencer interface {
+	// NextSequence increments and returns the sequence for the key
+	NextSequence(ctx context.Context, key string) (uint64, error)
+
+	// CurrentSequence returns the last sequence handed out for the key
+	CurrentSequence(ctx context.Context, key string) (uint64, error)
+}
+
+// MemorySequencer is a process-local Sequencer
+type MemorySequencer struct {
+	mu        sync.Mutex
+	sequences map[string]uint64
+}
+
+// NewMemorySequencer creates a new in-memory sequencer
+func NewMemorySequencer() *MemorySequencer {
+	return &MemorySequencer{sequences: make(map[string]uint64)}
+}
+
+// NextSequence increments and returns the sequence for the key
+func (s *MemorySequencer) NextSequence(ctx context.Context, key string) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sequences[key]++
+	return s.sequences[key], nil
+}
+
+// CurrentSequence returns the last sequence handed out for the key
+func (s *MemorySequencer) CurrentSequence(ctx context.Context, key string) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sequences[key], nil
+}
@@ -0,0 +1,106 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Limiter enforces a fixed-window rate limit per key
+type Limiter interface {
+	// Take counts one event for key. It returns the zero time when the event
+	// is allowed, or the time the current window ends when it is not.
+	Take(ctx context.Context, key string, limit int, window time.Duration, now time.Time) (time.Time, error)
+}
+
+// MemoryLimiter is a process-local Limiter
+type MemoryLimiter struct {
+	mu      sync.Mutex
+	windows map[string]limiterWindow
+}
+
+type limiterWindow struct {
+	ends  time.Time
+	count int
+}
+
+// NewMemoryLimiter creates a new in-memory limiter
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{windows: make(map[string]limiterWindow)}
+}
+
+// Take counts one event for key
+func (l *MemoryLimiter) Take(ctx context.Context, key string, limit int, window time.Duration, now time.Time) (time.Time, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w := l.windows[key]
+	if !now.Before(w.ends) {
+		w = limiterWindow{ends: now.Truncate(window).Add(window)}
+	}
+	if w.count >= limit {
+		return w.ends, nil
+	}
+	w.count++
+	l.windows[key] = w
+
+	// Drop windows that have ended so idle keys do not accumulate
+	if len(l.windows) > 1024 {
+		for k, other := range l.windows {
+			if !now.Before(other.ends) {
+				delete(l.windows, k)
+			}
+		}
+	}
+	return time.Time{}, nil
+}
+
+// RedisLimiter shares rate limits between replicas
+type RedisLimiter struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisLimiter connects to Redis
+func NewRedisLimiter(ctx context.Context, redisURL string) (*RedisLimiter, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse redis url: %w", err)
+	}
+
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return &RedisLimiter{client: client, prefix: "house-helper:notifier:ratelimit"}, nil
+}
+
+// Take counts one event for key
+func (l *RedisLimiter) Take(ctx context.Context, key string, limit int, window time.Duration, now time.Time) (time.Time, error) {
+	start := now.Truncate(window)
+	ends := start.Add(window)
+	windowKey := l.prefix + ":" + key + ":" + strconv.FormatInt(start.Unix(), 10)
+
+	pipe := l.client.TxPipeline()
+	count := pipe.Incr(ctx, windowKey)
+	pipe.PExpireAt(ctx, windowKey, ends)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return time.Time{}, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+
+	if count.Val() > int64(limit) {
+		return ends, nil
+	}
+	return time.Time{}, nil
+}
+
+// Close closes the Redis connection
+func (l *RedisLimiter) Close() error {
+	return l.client.Close()
+}
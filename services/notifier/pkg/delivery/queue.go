@@ -0,0 +1,334 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/househelper/notifier/pkg/devices"
+	"github.com/househelper/notifier/pkg/notifications"
+)
+
+// Config tunes the delivery queue
+type Config struct {
+	// Workers is the number of workers per provider
+	Workers int
+
+	// BatchSize is the number of jobs a worker claims at once
+	BatchSize int
+
+	// PollInterval is how often idle workers look for due jobs
+	PollInterval time.Duration
+
+	// Lease is how long a claimed job is hidden from other workers
+	Lease time.Duration
+
+	// MaxAttempts is the number of sends before a job is marked failed
+	MaxAttempts int
+
+	// BaseBackoff and MaxBackoff bound the exponential retry delay
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+
+	// CollapseWindow delays jobs with a collapse key so that notifications
+	// with the same key arriving within it are sent as one push
+	CollapseWindow time.Duration
+
+	// UserRateLimit is the number of pushes a user may receive per
+	// UserRateWindow; further pushes wait for the next window
+	UserRateLimit  int
+	UserRateWindow time.Duration
+}
+
+// DefaultConfig returns the queue defaults
+func DefaultConfig() Config {
+	return Config{
+		Workers:        2,
+		BatchSize:      10,
+		PollInterval:   time.Second,
+		Lease:          time.Minute,
+		MaxAttempts:    8,
+		BaseBackoff:    2 * time.Second,
+		MaxBackoff:     10 * time.Minute,
+		CollapseWindow: time.Minute,
+		UserRateLimit:  20,
+		UserRateWindow: time.Minute,
+	}
+}
+
+// Queue is a durable outbound queue for push notifications. Each provider
+// has its own workers so a throttled provider does not hold up the others.
+type Queue struct {
+	store      Store
+	limiter    Limiter
+	dispatcher *notifications.Dispatcher
+	config     Config
+
+	mu   sync.Mutex
+	wake map[devices.Platform]chan struct{}
+}
+
+// NewQueue creates a new delivery queue sending through the dispatcher's channels
+func NewQueue(store Store, limiter Limiter, dispatcher *notifications.Dispatcher, config Config) *Queue {
+	return &Queue{
+		store:      store,
+		limiter:    limiter,
+		dispatcher: dispatcher,
+		config:     config,
+		wake:       make(map[devices.Platform]chan struct{}),
+	}
+}
+
+// Enqueue queues the notification for every registered device of the user
+func (q *Queue) Enqueue(ctx context.Context, n notifications.Notification, collapseKey string) ([]Receipt, error) {
+	return q.EnqueueOnce(ctx, "", n, collapseKey)
+}
+
+// EnqueueOnce is Enqueue for callers that retry: a repeated idempotency key
+// returns the receipts of the first request, marked as duplicates, instead
+// of queueing the notification again. Keys are remembered as long as receipts.
+func (q *Queue) EnqueueOnce(ctx context.Context, idempotencyKey string, n notifications.Notification, collapseKey string) ([]Receipt, error) {
+	userDevices, err := q.dispatcher.Devices(ctx, n.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if len(userDevices) == 0 {
+		return nil, notifications.ErrNoDevices
+	}
+
+	receipts := make([]Receipt, 0, len(userDevices))
+	for _, device := range userDevices {
+		var jobID string
+		if idempotencyKey != "" {
+			jobID = idempotentJobID(idempotencyKey, device.ID)
+			existing, err := q.store.Receipt(ctx, jobID)
+			if err == nil {
+				existing.Duplicate = true
+				receipts = append(receipts, *existing)
+				continue
+			}
+			if !errors.Is(err, ErrNotFound) {
+				return receipts, err
+			}
+		}
+
+		receipt, err := q.enqueue(ctx, jobID, device, n, collapseKey)
+		if err != nil {
+			return receipts, err
+		}
+		receipts = append(receipts, *receipt)
+	}
+	return receipts, nil
+}
+
+// EnqueueDevice queues the notification for a single device, which need not
+// be registered
+func (q *Queue) EnqueueDevice(ctx context.Context, device devices.Device, n notifications.Notification, collapseKey string) (*Receipt, error) {
+	return q.enqueue(ctx, "", device, n, collapseKey)
+}
+
+// enqueue stores a job for the device, under a new ID unless jobID is set
+func (q *Queue) enqueue(ctx context.Context, jobID string, device devices.Device, n notifications.Notification, collapseKey string) (*Receipt, error) {
+	if device.ID == "" {
+		device.ID = devices.IDFor(device.Address())
+	}
+	if device.UserID == "" {
+		device.UserID = n.UserID
+	}
+	if jobID == "" {
+		jobID = uuid.NewString()
+	}
+
+	now := time.Now()
+	job := Job{
+		ID:           jobID,
+		Device:       device,
+		Notification: n,
+		CollapseKey:  collapseKey,
+		NotBefore:    now,
+		EnqueuedAt:   now,
+	}
+	if collapseKey != "" {
+		job.NotBefore = now.Add(q.config.CollapseWindow)
+	}
+
+	receipt, err := q.store.Enqueue(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	if collapseKey == "" {
+		q.notify(device.Platform)
+	}
+	return receipt, nil
+}
+
+// idempotentJobID derives the job ID of a device's copy of an idempotent request
+func idempotentJobID(idempotencyKey, deviceID string) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(idempotencyKey+"/"+deviceID)).String()
+}
+
+// Receipt returns the delivery state of a queued notification
+func (q *Queue) Receipt(ctx context.Context, id string) (*Receipt, error) {
+	return q.store.Receipt(ctx, id)
+}
+
+// Run starts the workers of every provider and blocks until ctx is done
+func (q *Queue) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, platform := range q.dispatcher.Platforms() {
+		wake := q.wakeChannel(platform)
+		for i := 0; i < q.config.Workers; i++ {
+			wg.Add(1)
+			go func(platform devices.Platform) {
+				defer wg.Done()
+				q.work(ctx, platform, wake)
+			}(platform)
+		}
+	}
+	wg.Wait()
+}
+
+// work claims and sends due jobs of one provider until ctx is done
+func (q *Queue) work(ctx context.Context, platform devices.Platform, wake <-chan struct{}) {
+	ticker := time.NewTicker(q.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for q.runBatch(ctx, platform) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-wake:
+		}
+	}
+}
+
+// runBatch sends one batch of due jobs and reports whether it was full
+func (q *Queue) runBatch(ctx context.Context, platform devices.Platform) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	jobs, err := q.store.Claim(ctx, platform, time.Now(), q.config.BatchSize, q.config.Lease)
+	if err != nil {
+		log.Printf("Failed to claim %s delivery jobs: %v", platform, err)
+		return false
+	}
+
+	for _, job := range jobs {
+		q.process(ctx, job)
+	}
+	return len(jobs) == q.config.BatchSize
+}
+
+// process sends a claimed job and records the outcome
+func (q *Queue) process(ctx context.Context, job Job) {
+	now := time.Now()
+
+	if key := rateLimitKey(job.Device); q.config.UserRateLimit > 0 && key != "" {
+		retryAt, err := q.limiter.Take(ctx, key, q.config.UserRateLimit, q.config.UserRateWindow, now)
+		if err != nil {
+			log.Printf("Failed to check rate limit of %s: %v", key, err)
+		} else if !retryAt.IsZero() {
+			q.retry(ctx, job, retryAt, "user rate limit reached")
+			return
+		}
+	}
+
+	job.Attempts++
+	err := q.dispatcher.Deliver(ctx, job.Device, job.Notification)
+
+	var providerErr *notifications.ProviderError
+	switch {
+	case err == nil:
+		q.finish(ctx, job, StatusSent, "")
+
+	case errors.Is(err, notifications.ErrDeviceUnregistered):
+		q.finish(ctx, job, StatusUnregistered, err.Error())
+
+	case errors.As(err, &providerErr) && !providerErr.Retryable(),
+		errors.Is(err, notifications.ErrInvalidPayload):
+		// Dead-lettered right away, another attempt would fail the same way
+		q.finish(ctx, job, StatusFailed, err.Error())
+
+	case job.Attempts >= q.config.MaxAttempts:
+		q.finish(ctx, job, StatusFailed, fmt.Sprintf("giving up after %d attempts: %v", job.Attempts, err))
+
+	default:
+		delay := q.backoff(job.Attempts)
+		if providerErr != nil && providerErr.RetryAfter > delay {
+			delay = providerErr.RetryAfter
+		}
+		log.Printf("Retrying %s delivery %s in %s: %v", job.Device.Platform, job.ID, delay, err)
+		q.retry(ctx, job, now.Add(delay), err.Error())
+	}
+}
+
+// rateLimitKey returns the key a device's pushes are rate limited under: its
+// user's, or the device's own when no user is known, so pushes to unknown
+// users do not share one limit. It is empty for a device with neither.
+func rateLimitKey(device devices.Device) string {
+	switch {
+	case device.UserID != "":
+		return "user:" + device.UserID
+	case device.Token != "":
+		return "device:" + device.Token
+	case device.ID != "":
+		return "device:" + device.ID
+	}
+	return ""
+}
+
+// backoff returns the delay before the given attempt is retried: the
+// exponential delay with up to 20% jitter, capped at MaxBackoff
+func (q *Queue) backoff(attempt int) time.Duration {
+	delay := q.config.BaseBackoff
+	for i := 1; i < attempt && delay < q.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > q.config.MaxBackoff {
+		delay = q.config.MaxBackoff
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/5+1))
+}
+
+func (q *Queue) retry(ctx context.Context, job Job, at time.Time, reason string) {
+	if err := q.store.Retry(ctx, job, at, reason); err != nil {
+		log.Printf("Failed to reschedule delivery %s: %v", job.ID, err)
+	}
+}
+
+func (q *Queue) finish(ctx context.Context, job Job, status Status, reason string) {
+	if err := q.store.Finish(ctx, job, status, reason); err != nil {
+		log.Printf("Failed to record delivery %s as %s: %v", job.ID, status, err)
+	}
+}
+
+// notify wakes a worker of the platform so new jobs are sent without
+// waiting for the next poll
+func (q *Queue) notify(platform devices.Platform) {
+	select {
+	case q.wakeChannel(platform) <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) wakeChannel(platform devices.Platform) chan struct{} {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	wake, ok := q.wake[platform]
+	if !ok {
+		wake = make(chan struct{}, 1)
+		q.wake[platform] = wake
+	}
+	return wake
+}
@@ -0,0 +1,305 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/househelper/notifier/pkg/devices"
+	"github.com/househelper/notifier/pkg/notifications"
+)
+
+type fakeChannel struct {
+	mu   sync.Mutex
+	sent []notifications.Notification
+	errs []error
+}
+
+func (c *fakeChannel) Platform() devices.Platform {
+	return devices.PlatformAndroid
+}
+
+func (c *fakeChannel) Send(ctx context.Context, device devices.Device, n notifications.Notification) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		if err != nil {
+			return err
+		}
+	}
+	c.sent = append(c.sent, n)
+	return nil
+}
+
+func (c *fakeChannel) notifications() []notifications.Notification {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]notifications.Notification(nil), c.sent...)
+}
+
+func newTestQueue(t *testing.T, config Config) (*Queue, devices.Registry, *fakeChannel) {
+	t.Helper()
+	registry := devices.NewMemoryRegistry()
+	channel := &fakeChannel{}
+	dispatcher := notifications.NewDispatcher(registry)
+	dispatcher.RegisterChannel(channel)
+	return NewQueue(NewMemoryStore(), NewMemoryLimiter(), dispatcher, config), registry, channel
+}
+
+func testConfig() Config {
+	config := DefaultConfig()
+	config.CollapseWindow = 0
+	config.BaseBackoff = time.Second
+	return config
+}
+
+var testDevice = devices.Device{UserID: "user-1", Platform: devices.PlatformAndroid, Token: "token-1"}
+
+func receiptOf(t *testing.T, queue *Queue, id string) *Receipt {
+	t.Helper()
+	receipt, err := queue.Receipt(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Receipt(%s) error = %v", id, err)
+	}
+	return receipt
+}
+
+func TestCollapseKeyMergesQueuedNotifications(t *testing.T) {
+	queue, _, channel := newTestQueue(t, testConfig())
+	ctx := context.Background()
+
+	titles := []string{"Milk added", "Eggs added", "Bread added", "Milk checked", "Eggs checked"}
+	var receipts []*Receipt
+	for _, title := range titles {
+		receipt, err := queue.EnqueueDevice(ctx, testDevice, notifications.Notification{UserID: "user-1", Title: title}, "shopping-list-42")
+		if err != nil {
+			t.Fatalf("EnqueueDevice() error = %v", err)
+		}
+		receipts = append(receipts, receipt)
+	}
+
+	for _, receipt := range receipts[1:] {
+		if receipt.ID != receipts[0].ID {
+			t.Fatalf("receipt %s, want every notification merged into %s", receipt.ID, receipts[0].ID)
+		}
+	}
+	if last := receipts[len(receipts)-1]; last.Collapsed != len(titles)-1 {
+		t.Errorf("collapsed = %d, want %d", last.Collapsed, len(titles)-1)
+	}
+
+	queue.runBatch(ctx, devices.PlatformAndroid)
+
+	sent := channel.notifications()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d pushes, want 1", len(sent))
+	}
+	if sent[0].Title != "Eggs checked" {
+		t.Errorf("sent %q, want the latest notification", sent[0].Title)
+	}
+	if receipt := receiptOf(t, queue, receipts[0].ID); receipt.Status != StatusSent || receipt.SentAt == nil {
+		t.Errorf("receipt = %+v, want sent", receipt)
+	}
+}
+
+func TestIdempotencyKeyQueuesOnce(t *testing.T) {
+	queue, registry, channel := newTestQueue(t, testConfig())
+	ctx := context.Background()
+
+	if _, err := registry.Register(ctx, testDevice); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	n := notifications.Notification{UserID: "user-1", Title: "Task Reminder"}
+	first, err := queue.EnqueueOnce(ctx, "run-1/activity-5", n, "")
+	if err != nil {
+		t.Fatalf("EnqueueOnce() error = %v", err)
+	}
+	queue.runBatch(ctx, devices.PlatformAndroid)
+
+	// The caller retries after losing the response
+	retried, err := queue.EnqueueOnce(ctx, "run-1/activity-5", n, "")
+	if err != nil {
+		t.Fatalf("EnqueueOnce() retry error = %v", err)
+	}
+	if len(retried) != 1 || retried[0].ID != first[0].ID || !retried[0].Duplicate {
+		t.Fatalf("retry receipts = %+v, want the first receipt marked duplicate", retried)
+	}
+	if retried[0].Status != StatusSent {
+		t.Errorf("retry receipt status = %s, want the first delivery's status", retried[0].Status)
+	}
+
+	queue.runBatch(ctx, devices.PlatformAndroid)
+	if sent := channel.notifications(); len(sent) != 1 {
+		t.Errorf("sent %d pushes, want 1", len(sent))
+	}
+
+	// Another key is another notification
+	if _, err := queue.EnqueueOnce(ctx, "run-1/activity-9", n, ""); err != nil {
+		t.Fatalf("EnqueueOnce() error = %v", err)
+	}
+	queue.runBatch(ctx, devices.PlatformAndroid)
+	if sent := channel.notifications(); len(sent) != 2 {
+		t.Errorf("sent %d pushes, want 2", len(sent))
+	}
+}
+
+func TestRetryRespectsRetryAfter(t *testing.T) {
+	queue, _, channel := newTestQueue(t, testConfig())
+	channel.errs = []error{&notifications.ProviderError{
+		Platform:   devices.PlatformAndroid,
+		StatusCode: 429,
+		RetryAfter: time.Minute,
+		Err:        errors.New("quota exceeded"),
+	}}
+	ctx := context.Background()
+
+	receipt, err := queue.EnqueueDevice(ctx, testDevice, notifications.Notification{UserID: "user-1", Title: "Timer done"}, "")
+	if err != nil {
+		t.Fatalf("EnqueueDevice() error = %v", err)
+	}
+
+	start := time.Now()
+	queue.runBatch(ctx, devices.PlatformAndroid)
+
+	retrying := receiptOf(t, queue, receipt.ID)
+	if retrying.Status != StatusRetrying || retrying.Attempts != 1 {
+		t.Fatalf("receipt = %+v, want retrying after one attempt", retrying)
+	}
+	if retrying.NextTry == nil || retrying.NextTry.Before(start.Add(time.Minute)) {
+		t.Errorf("next try = %v, want at least a minute away", retrying.NextTry)
+	}
+
+	// Nothing is due until the provider's Retry-After has passed
+	queue.runBatch(ctx, devices.PlatformAndroid)
+	if sent := channel.notifications(); len(sent) != 0 {
+		t.Errorf("sent %d pushes before Retry-After", len(sent))
+	}
+}
+
+func TestNonRetryableErrorFailsJob(t *testing.T) {
+	queue, _, channel := newTestQueue(t, testConfig())
+	channel.errs = []error{&notifications.ProviderError{
+		Platform:   devices.PlatformAndroid,
+		StatusCode: 400,
+		Err:        errors.New("invalid argument"),
+	}}
+	ctx := context.Background()
+
+	receipt, err := queue.EnqueueDevice(ctx, testDevice, notifications.Notification{UserID: "user-1"}, "")
+	if err != nil {
+		t.Fatalf("EnqueueDevice() error = %v", err)
+	}
+	queue.runBatch(ctx, devices.PlatformAndroid)
+
+	if failed := receiptOf(t, queue, receipt.ID); failed.Status != StatusFailed || failed.LastError == "" {
+		t.Errorf("receipt = %+v, want failed with the provider error", failed)
+	}
+}
+
+func TestInvalidPayloadFailsJob(t *testing.T) {
+	queue, _, channel := newTestQueue(t, testConfig())
+	channel.errs = []error{fmt.Errorf("%w: invalid image URL", notifications.ErrInvalidPayload)}
+	ctx := context.Background()
+
+	receipt, err := queue.EnqueueDevice(ctx, testDevice, notifications.Notification{UserID: "user-1"}, "")
+	if err != nil {
+		t.Fatalf("EnqueueDevice() error = %v", err)
+	}
+	queue.runBatch(ctx, devices.PlatformAndroid)
+
+	if failed := receiptOf(t, queue, receipt.ID); failed.Status != StatusFailed || failed.Attempts != 1 {
+		t.Errorf("receipt = %+v, want failed after one attempt", failed)
+	}
+}
+
+func TestUserRateLimitDefersWithoutCountingAttempts(t *testing.T) {
+	config := testConfig()
+	config.UserRateLimit = 1
+	config.UserRateWindow = time.Hour
+	queue, _, channel := newTestQueue(t, config)
+	ctx := context.Background()
+
+	var ids []string
+	for _, title := range []string{"Bill due", "Chore assigned"} {
+		receipt, err := queue.EnqueueDevice(ctx, testDevice, notifications.Notification{UserID: "user-1", Title: title}, "")
+		if err != nil {
+			t.Fatalf("EnqueueDevice() error = %v", err)
+		}
+		ids = append(ids, receipt.ID)
+	}
+
+	queue.runBatch(ctx, devices.PlatformAndroid)
+
+	if sent := channel.notifications(); len(sent) != 1 {
+		t.Fatalf("sent %d pushes, want 1 within the limit", len(sent))
+	}
+
+	var deferred int
+	for _, id := range ids {
+		receipt := receiptOf(t, queue, id)
+		if receipt.Status == StatusRetrying {
+			deferred++
+			if receipt.Attempts != 0 {
+				t.Errorf("deferred receipt attempts = %d, want 0", receipt.Attempts)
+			}
+		}
+	}
+	if deferred != 1 {
+		t.Errorf("deferred %d notifications, want 1", deferred)
+	}
+}
+
+func TestUserRateLimitKeysDevicesWithoutUser(t *testing.T) {
+	config := testConfig()
+	config.UserRateLimit = 1
+	config.UserRateWindow = time.Hour
+	queue, _, channel := newTestQueue(t, config)
+	ctx := context.Background()
+
+	// Devices of unknown users are limited each on their own
+	for _, token := range []string{"token-2", "token-3"} {
+		device := devices.Device{Platform: devices.PlatformAndroid, Token: token}
+		if _, err := queue.EnqueueDevice(ctx, device, notifications.Notification{Title: "Bill due"}, ""); err != nil {
+			t.Fatalf("EnqueueDevice() error = %v", err)
+		}
+	}
+
+	queue.runBatch(ctx, devices.PlatformAndroid)
+
+	if sent := channel.notifications(); len(sent) != 2 {
+		t.Fatalf("sent %d pushes, want 2 to separate devices", len(sent))
+	}
+}
+
+func TestUnregisteredDeviceIsRemoved(t *testing.T) {
+	queue, registry, channel := newTestQueue(t, testConfig())
+	channel.errs = []error{notifications.ErrDeviceUnregistered}
+	ctx := context.Background()
+
+	if _, err := registry.Register(ctx, testDevice); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	receipts, err := queue.Enqueue(ctx, notifications.Notification{UserID: "user-1", Title: "Laundry done"}, "")
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if len(receipts) != 1 {
+		t.Fatalf("queued %d jobs, want 1", len(receipts))
+	}
+
+	queue.runBatch(ctx, devices.PlatformAndroid)
+
+	if receipt := receiptOf(t, queue, receipts[0].ID); receipt.Status != StatusUnregistered {
+		t.Errorf("status = %s, want unregistered", receipt.Status)
+	}
+	if _, err := queue.Enqueue(ctx, notifications.Notification{UserID: "user-1"}, ""); !errors.Is(err, notifications.ErrNoDevices) {
+		t.Errorf("Enqueue() after removal error = %v, want ErrNoDevices", err)
+	}
+}
@@ -0,0 +1,510 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/househelper/notifier/pkg/devices"
+	"github.com/househelper/notifier/pkg/notifications"
+	"github.com/redis/go-redis/v9"
+)
+
+// receiptTTL is how long receipts can be looked up after their last change
+const receiptTTL = 24 * time.Hour
+
+// ErrNotFound is returned when a receipt does not exist or has expired
+var ErrNotFound = errors.New("receipt not found")
+
+// Status is the delivery state of a job
+type Status string
+
+const (
+	StatusQueued       Status = "queued"
+	StatusRetrying     Status = "retrying"
+	StatusSent         Status = "sent"
+	StatusFailed       Status = "failed"
+	StatusUnregistered Status = "unregistered"
+)
+
+// Job is one notification waiting to be pushed to one device
+type Job struct {
+	ID           string                     `json:"id"`
+	Device       devices.Device             `json:"device"`
+	Notification notifications.Notification `json:"notification"`
+
+	// CollapseKey merges jobs for the same device that are still queued
+	CollapseKey string `json:"collapseKey,omitempty"`
+
+	Attempts   int       `json:"attempts"`
+	NotBefore  time.Time `json:"notBefore"`
+	EnqueuedAt time.Time `json:"enqueuedAt"`
+}
+
+// collapseField identifies the queued job a new job with the same key merges into
+func (j *Job) collapseField() string {
+	if j.CollapseKey == "" {
+		return ""
+	}
+	return j.Device.UserID + ":" + j.Device.ID + ":" + j.CollapseKey
+}
+
+// Receipt reports the delivery state of a job
+type Receipt struct {
+	ID          string           `json:"id"`
+	UserID      string           `json:"userId"`
+	DeviceID    string           `json:"deviceId"`
+	Platform    devices.Platform `json:"platform"`
+	CollapseKey string           `json:"collapseKey,omitempty"`
+	Status      Status           `json:"status"`
+	Attempts    int              `json:"attempts"`
+
+	// Collapsed counts the later notifications merged into this one
+	Collapsed int `json:"collapsed,omitempty"`
+
+	// Duplicate is set when a retried request was answered with this receipt
+	// instead of queueing the notification again
+	Duplicate bool `json:"duplicate,omitempty"`
+
+	LastError string     `json:"lastError,omitempty"`
+	NextTry   *time.Time `json:"nextTry,omitempty"`
+	SentAt    *time.Time `json:"sentAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+// newReceipt creates the receipt of a freshly queued job
+func newReceipt(job Job) Receipt {
+	return Receipt{
+		ID:          job.ID,
+		UserID:      job.Device.UserID,
+		DeviceID:    job.Device.ID,
+		Platform:    job.Device.Platform,
+		CollapseKey: job.CollapseKey,
+		Status:      StatusQueued,
+		CreatedAt:   job.EnqueuedAt,
+		UpdatedAt:   job.EnqueuedAt,
+	}
+}
+
+// Store persists queued jobs and their receipts
+type Store interface {
+	// Enqueue stores a job. If a queued job for the same device has the same
+	// collapse key, it takes the new notification instead and its receipt
+	// is returned.
+	Enqueue(ctx context.Context, job Job) (*Receipt, error)
+
+	// Claim leases up to limit jobs of a platform that are due. A claimed job
+	// no longer collapses and is handed out again if the lease expires
+	// before it is retried or finished.
+	Claim(ctx context.Context, platform devices.Platform, now time.Time, limit int, lease time.Duration) ([]Job, error)
+
+	// Retry puts a claimed job back in the queue until at
+	Retry(ctx context.Context, job Job, at time.Time, reason string) error
+
+	// Finish removes a claimed job and records its final status
+	Finish(ctx context.Context, job Job, status Status, reason string) error
+
+	// Receipt returns the receipt of a job
+	Receipt(ctx context.Context, id string) (*Receipt, error)
+}
+
+// MemoryStore is a process-local Store; queued jobs are lost on restart
+type MemoryStore struct {
+	mu       sync.Mutex
+	jobs     map[string]Job
+	due      map[string]time.Time
+	collapse map[string]string
+	receipts map[string]Receipt
+}
+
+// NewMemoryStore creates a new in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		jobs:     make(map[string]Job),
+		due:      make(map[string]time.Time),
+		collapse: make(map[string]string),
+		receipts: make(map[string]Receipt),
+	}
+}
+
+// Enqueue stores a job or collapses it into a queued one
+func (s *MemoryStore) Enqueue(ctx context.Context, job Job) (*Receipt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if field := job.collapseField(); field != "" {
+		if id, ok := s.collapse[field]; ok {
+			existing := s.jobs[id]
+			existing.Notification = job.Notification
+			s.jobs[id] = existing
+
+			receipt := s.receipts[id]
+			receipt.Collapsed++
+			receipt.UpdatedAt = job.EnqueuedAt
+			s.receipts[id] = receipt
+			return &receipt, nil
+		}
+		s.collapse[field] = job.ID
+	}
+
+	s.jobs[job.ID] = job
+	s.due[job.ID] = job.NotBefore
+
+	receipt := newReceipt(job)
+	s.receipts[job.ID] = receipt
+	return &receipt, nil
+}
+
+// Claim leases due jobs of a platform
+func (s *MemoryStore) Claim(ctx context.Context, platform devices.Platform, now time.Time, limit int, lease time.Duration) ([]Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var claimed []Job
+	for id, at := range s.due {
+		job := s.jobs[id]
+		if job.Device.Platform == platform && !at.After(now) {
+			claimed = append(claimed, job)
+		}
+	}
+	sort.Slice(claimed, func(i, j int) bool {
+		return s.due[claimed[i].ID].Before(s.due[claimed[j].ID])
+	})
+	if len(claimed) > limit {
+		claimed = claimed[:limit]
+	}
+
+	for _, job := range claimed {
+		s.due[job.ID] = now.Add(lease)
+		if field := job.collapseField(); field != "" && s.collapse[field] == job.ID {
+			delete(s.collapse, field)
+		}
+	}
+	return claimed, nil
+}
+
+// Retry puts a claimed job back in the queue
+func (s *MemoryStore) Retry(ctx context.Context, job Job, at time.Time, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[job.ID]; !ok {
+		return nil
+	}
+	s.jobs[job.ID] = job
+	s.due[job.ID] = at
+
+	receipt := s.receipts[job.ID]
+	receipt.Status = StatusRetrying
+	receipt.Attempts = job.Attempts
+	receipt.LastError = reason
+	receipt.NextTry = &at
+	receipt.UpdatedAt = time.Now()
+	s.receipts[job.ID] = receipt
+	return nil
+}
+
+// Finish removes a claimed job and records its final status
+func (s *MemoryStore) Finish(ctx context.Context, job Job, status Status, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.jobs, job.ID)
+	delete(s.due, job.ID)
+
+	now := time.Now()
+	receipt := s.receipts[job.ID]
+	finish(&receipt, job, status, reason, now)
+	s.receipts[job.ID] = receipt
+	return nil
+}
+
+// Receipt returns the receipt of a job
+func (s *MemoryStore) Receipt(ctx context.Context, id string) (*Receipt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	receipt, ok := s.receipts[id]
+	if !ok || time.Since(receipt.UpdatedAt) > receiptTTL {
+		return nil, ErrNotFound
+	}
+	return &receipt, nil
+}
+
+// finish records the final status of a job on its receipt
+func finish(receipt *Receipt, job Job, status Status, reason string, now time.Time) {
+	receipt.Status = status
+	receipt.Attempts = job.Attempts
+	receipt.LastError = reason
+	receipt.NextTry = nil
+	receipt.UpdatedAt = now
+	if status == StatusSent {
+		receipt.SentAt = &now
+	}
+}
+
+// maxTxRetries bounds optimistic transaction retries under contention
+const maxTxRetries = 10
+
+// RedisStore keeps the queue in Redis so it survives restarts and is
+// shared by all replicas
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore connects to Redis
+func NewRedisStore(ctx context.Context, redisURL string) (*RedisStore, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse redis url: %w", err)
+	}
+
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return &RedisStore{client: client, prefix: "house-helper:notifier:delivery"}, nil
+}
+
+// Enqueue stores a job or collapses it into a queued one
+func (s *RedisStore) Enqueue(ctx context.Context, job Job) (*Receipt, error) {
+	field := job.collapseField()
+	if field == "" {
+		receipt := newReceipt(job)
+		_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			return s.store(ctx, pipe, job, &receipt)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to enqueue job: %w", err)
+		}
+		return &receipt, nil
+	}
+
+	var receipt *Receipt
+	err := s.transaction(ctx, func(tx *redis.Tx) error {
+		var existing *Job
+		id, err := tx.HGet(ctx, s.collapseKey(), field).Result()
+		if err == nil {
+			existing, err = s.job(ctx, tx, id)
+		}
+		if errors.Is(err, redis.Nil) {
+			fresh := newReceipt(job)
+			receipt = &fresh
+			_, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.HSet(ctx, s.collapseKey(), field, job.ID)
+				return s.store(ctx, pipe, job, receipt)
+			})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		existing.Notification = job.Notification
+
+		receipt, err = s.Receipt(ctx, id)
+		if err != nil {
+			return err
+		}
+		receipt.Collapsed++
+		receipt.UpdatedAt = job.EnqueuedAt
+
+		jobData, err := json.Marshal(existing)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, s.jobsKey(), id, jobData)
+			return s.saveReceipt(ctx, pipe, receipt)
+		})
+		return err
+	}, s.collapseKey(), s.jobsKey())
+	if err != nil {
+		return nil, fmt.Errorf("failed to enqueue job: %w", err)
+	}
+	return receipt, nil
+}
+
+// Claim leases due jobs of a platform
+func (s *RedisStore) Claim(ctx context.Context, platform devices.Platform, now time.Time, limit int, lease time.Duration) ([]Job, error) {
+	dueKey := s.dueKey(platform)
+
+	var claimed []Job
+	err := s.transaction(ctx, func(tx *redis.Tx) error {
+		claimed = nil
+
+		ids, err := tx.ZRangeByScore(ctx, dueKey, &redis.ZRangeBy{
+			Min:   "-inf",
+			Max:   strconv.FormatInt(now.UnixMilli(), 10),
+			Count: int64(limit),
+		}).Result()
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		for _, id := range ids {
+			job, err := s.job(ctx, tx, id)
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			claimed = append(claimed, *job)
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			leaseUntil := float64(now.Add(lease).UnixMilli())
+			for _, id := range ids {
+				pipe.ZAdd(ctx, dueKey, redis.Z{Score: leaseUntil, Member: id})
+			}
+			for _, job := range claimed {
+				if field := job.collapseField(); field != "" {
+					pipe.HDel(ctx, s.collapseKey(), field)
+				}
+			}
+			return nil
+		})
+		return err
+	}, dueKey, s.collapseKey())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim jobs: %w", err)
+	}
+	return claimed, nil
+}
+
+// Retry puts a claimed job back in the queue
+func (s *RedisStore) Retry(ctx context.Context, job Job, at time.Time, reason string) error {
+	receipt, err := s.Receipt(ctx, job.ID)
+	if err != nil {
+		return err
+	}
+	receipt.Status = StatusRetrying
+	receipt.Attempts = job.Attempts
+	receipt.LastError = reason
+	receipt.NextTry = &at
+	receipt.UpdatedAt = time.Now()
+
+	job.NotBefore = at
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		return s.store(ctx, pipe, job, receipt)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to retry job: %w", err)
+	}
+	return nil
+}
+
+// Finish removes a claimed job and records its final status
+func (s *RedisStore) Finish(ctx context.Context, job Job, status Status, reason string) error {
+	receipt, err := s.Receipt(ctx, job.ID)
+	if errors.Is(err, ErrNotFound) {
+		fresh := newReceipt(job)
+		receipt, err = &fresh, nil
+	}
+	if err != nil {
+		return err
+	}
+	finish(receipt, job, status, reason, time.Now())
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, s.dueKey(job.Device.Platform), job.ID)
+		pipe.HDel(ctx, s.jobsKey(), job.ID)
+		return s.saveReceipt(ctx, pipe, receipt)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to finish job: %w", err)
+	}
+	return nil
+}
+
+// Receipt returns the receipt of a job
+func (s *RedisStore) Receipt(ctx context.Context, id string) (*Receipt, error) {
+	raw, err := s.client.Get(ctx, s.receiptKey(id)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get receipt: %w", err)
+	}
+
+	var receipt Receipt
+	if err := json.Unmarshal([]byte(raw), &receipt); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal receipt: %w", err)
+	}
+	return &receipt, nil
+}
+
+// Close closes the Redis connection
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+// store queues a job and saves its receipt in a pipeline
+func (s *RedisStore) store(ctx context.Context, pipe redis.Pipeliner, job Job, receipt *Receipt) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+	pipe.HSet(ctx, s.jobsKey(), job.ID, data)
+	pipe.ZAdd(ctx, s.dueKey(job.Device.Platform), redis.Z{Score: float64(job.NotBefore.UnixMilli()), Member: job.ID})
+	return s.saveReceipt(ctx, pipe, receipt)
+}
+
+func (s *RedisStore) saveReceipt(ctx context.Context, pipe redis.Pipeliner, receipt *Receipt) error {
+	data, err := json.Marshal(receipt)
+	if err != nil {
+		return fmt.Errorf("failed to marshal receipt: %w", err)
+	}
+	pipe.Set(ctx, s.receiptKey(receipt.ID), data, receiptTTL)
+	return nil
+}
+
+func (s *RedisStore) job(ctx context.Context, tx *redis.Tx, id string) (*Job, error) {
+	raw, err := tx.HGet(ctx, s.jobsKey(), id).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var job Job
+	if err := json.Unmarshal([]byte(raw), &job); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job: %w", err)
+	}
+	return &job, nil
+}
+
+// transaction runs fn optimistically, retrying when a watched key changes
+func (s *RedisStore) transaction(ctx context.Context, fn func(tx *redis.Tx) error, keys ...string) error {
+	for i := 0; i < maxTxRetries; i++ {
+		err := s.client.Watch(ctx, fn, keys...)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return redis.TxFailedErr
+}
+
+func (s *RedisStore) jobsKey() string {
+	return s.prefix + ":jobs"
+}
+
+func (s *RedisStore) dueKey(platform devices.Platform) string {
+	return s.prefix + ":due:" + string(platform)
+}
+
+func (s *RedisStore) collapseKey() string {
+	return s.prefix + ":collapse"
+}
+
+func (s *RedisStore) receiptKey(id string) string {
+	return s.prefix + ":receipt:" + id
+}
@@ -0,0 +1,279 @@
+package devices
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Platform is the push platform of a device
+type Platform string
+
+const (
+	PlatformAndroid Platform = "android"
+	PlatformIOS     Platform = "ios"
+	PlatformWeb     Platform = "web"
+)
+
+// ErrNotFound is returned when a device is not registered
+var ErrNotFound = errors.New("device not found")
+
+// WebPushSubscription is a browser push subscription (PushSubscription.toJSON())
+type WebPushSubscription struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+// Device is a push target registered by a user
+type Device struct {
+	ID        string               `json:"id"`
+	UserID    string               `json:"userId"`
+	Platform  Platform             `json:"platform"`
+	Token     string               `json:"token,omitempty"`
+	BundleID  string               `json:"bundleId,omitempty"`
+	WebPush   *WebPushSubscription `json:"webPush,omitempty"`
+	CreatedAt time.Time            `json:"createdAt"`
+	UpdatedAt time.Time            `json:"updatedAt"`
+}
+
+// Validate checks that the device carries the fields its platform needs
+func (d *Device) Validate() error {
+	if d.UserID == "" {
+		return errors.New("userId is required")
+	}
+
+	switch d.Platform {
+	case PlatformAndroid, PlatformIOS:
+		if d.Token == "" {
+			return errors.New("token is required")
+		}
+		if d.Platform == PlatformIOS && d.BundleID == "" {
+			return errors.New("bundleId is required for ios devices")
+		}
+	case PlatformWeb:
+		if d.WebPush == nil || d.WebPush.Endpoint == "" {
+			return errors.New("subscription endpoint is required")
+		}
+		if d.WebPush.Keys.P256dh == "" || d.WebPush.Keys.Auth == "" {
+			return errors.New("subscription keys are required")
+		}
+	default:
+		return fmt.Errorf("unsupported platform %q", d.Platform)
+	}
+
+	return nil
+}
+
+// Address returns the value identifying the device at its push provider
+func (d *Device) Address() string {
+	if d.WebPush != nil {
+		return d.WebPush.Endpoint
+	}
+	return d.Token
+}
+
+// IDFor derives a stable device ID from a push token or endpoint
+func IDFor(address string) string {
+	sum := sha256.Sum256([]byte(address))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Registry stores the devices of each user
+type Registry interface {
+	// Register adds or refreshes a device
+	Register(ctx context.Context, device Device) (*Device, error)
+
+	// List returns the devices of a user
+	List(ctx context.Context, userID string) ([]Device, error)
+
+	// Remove unregisters a device
+	Remove(ctx context.Context, userID, deviceID string) error
+}
+
+// prepare fills derived fields before a device is stored
+func prepare(device Device, existing *Device) Device {
+	now := time.Now()
+	device.ID = IDFor(device.Address())
+	device.CreatedAt = now
+	if existing != nil {
+		device.CreatedAt = existing.CreatedAt
+	}
+	device.UpdatedAt = now
+	return device
+}
+
+// MemoryRegistry is a process-local Registry
+type MemoryRegistry struct {
+	mu      sync.RWMutex
+	devices map[string]map[string]Device
+}
+
+// NewMemoryRegistry creates a new in-memory registry
+func NewMemoryRegistry() *MemoryRegistry {
+	return &MemoryRegistry{devices: make(map[string]map[string]Device)}
+}
+
+// Register adds or refreshes a device
+func (r *MemoryRegistry) Register(ctx context.Context, device Device) (*Device, error) {
+	if err := device.Validate(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userDevices, ok := r.devices[device.UserID]
+	if !ok {
+		userDevices = make(map[string]Device)
+		r.devices[device.UserID] = userDevices
+	}
+
+	var existing *Device
+	if d, ok := userDevices[IDFor(device.Address())]; ok {
+		existing = &d
+	}
+
+	device = prepare(device, existing)
+	userDevices[device.ID] = device
+
+	return &device, nil
+}
+
+// List returns the devices of a user
+func (r *MemoryRegistry) List(ctx context.Context, userID string) ([]Device, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	devices := make([]Device, 0, len(r.devices[userID]))
+	for _, device := range r.devices[userID] {
+		devices = append(devices, device)
+	}
+	sortDevices(devices)
+
+	return devices, nil
+}
+
+// Remove unregisters a device
+func (r *MemoryRegistry) Remove(ctx context.Context, userID, deviceID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.devices[userID][deviceID]; !ok {
+		return ErrNotFound
+	}
+	delete(r.devices[userID], deviceID)
+
+	return nil
+}
+
+// RedisRegistry stores devices in Redis so all replicas share them
+type RedisRegistry struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisRegistry connects to Redis
+func NewRedisRegistry(ctx context.Context, redisURL string) (*RedisRegistry, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse redis url: %w", err)
+	}
+
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return &RedisRegistry{client: client, prefix: "house-helper:notifier:devices"}, nil
+}
+
+// Register adds or refreshes a device
+func (r *RedisRegistry) Register(ctx context.Context, device Device) (*Device, error) {
+	if err := device.Validate(); err != nil {
+		return nil, err
+	}
+
+	key := r.userKey(device.UserID)
+
+	var existing *Device
+	raw, err := r.client.HGet(ctx, key, IDFor(device.Address())).Result()
+	if err == nil {
+		var d Device
+		if json.Unmarshal([]byte(raw), &d) == nil {
+			existing = &d
+		}
+	} else if !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to get device: %w", err)
+	}
+
+	device = prepare(device, existing)
+	data, err := json.Marshal(device)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal device: %w", err)
+	}
+
+	if err := r.client.HSet(ctx, key, device.ID, data).Err(); err != nil {
+		return nil, fmt.Errorf("failed to register device: %w", err)
+	}
+
+	return &device, nil
+}
+
+// List returns the devices of a user
+func (r *RedisRegistry) List(ctx context.Context, userID string) ([]Device, error) {
+	values, err := r.client.HGetAll(ctx, r.userKey(userID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+
+	devices := make([]Device, 0, len(values))
+	for _, raw := range values {
+		var device Device
+		if err := json.Unmarshal([]byte(raw), &device); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal device: %w", err)
+		}
+		devices = append(devices, device)
+	}
+	sortDevices(devices)
+
+	return devices, nil
+}
+
+// Remove unregisters a device
+func (r *RedisRegistry) Remove(ctx context.Context, userID, deviceID string) error {
+	removed, err := r.client.HDel(ctx, r.userKey(userID), deviceID).Result()
+	if err != nil {
+		return fmt.Errorf("failed to remove device: %w", err)
+	}
+	if removed == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Close closes the Redis connection
+func (r *RedisRegistry) Close() error {
+	return r.client.Close()
+}
+
+func (r *RedisRegistry) userKey(userID string) string {
+	return r.prefix + ":" + userID
+}
+
+func sortDevices(devices []Device) {
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].CreatedAt.Before(devices[j].CreatedAt)
+	})
+}
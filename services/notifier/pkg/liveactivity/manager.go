@@ -0,0 +1,378 @@
+package liveactivity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/househelper/notifier/pkg/notifications"
+	"github.com/sideshow/apns2"
+)
+
+// Event is the ActivityKit push event of an update
+type Event string
+
+const (
+	EventStart  Event = "start"
+	EventUpdate Event = "update"
+	EventEnd    Event = "end"
+)
+
+// flushTimeout bounds the delivery of a coalesced update
+const flushTimeout = 30 * time.Second
+
+var (
+	// ErrNoTokens is returned when no push token can reach the activity
+	ErrNoTokens = errors.New("no live activity tokens registered")
+
+	// ErrDeliveryFailed is returned when no token accepted the update
+	ErrDeliveryFailed = errors.New("live activity delivery failed")
+)
+
+// Update is a change to a live activity requested by a workflow
+type Update struct {
+	ActivityID   string                 `json:"activityId"`
+	UserID       string                 `json:"userId"`
+	Event        Event                  `json:"event"`
+	ContentState map[string]interface{} `json:"contentState"`
+
+	// StaleDate marks the content as outdated if no newer update arrives
+	StaleDate time.Time `json:"staleDate,omitempty"`
+
+	// DismissalDate removes an ended activity from the lock screen
+	DismissalDate time.Time `json:"dismissalDate,omitempty"`
+
+	// AttributesType and Attributes let a start event push-to-start the
+	// activity when the app has not started it on the device
+	AttributesType string                 `json:"attributesType,omitempty"`
+	Attributes     map[string]interface{} `json:"attributes,omitempty"`
+
+	AlertTitle string `json:"alertTitle,omitempty"`
+	AlertBody  string `json:"alertBody,omitempty"`
+
+	// Urgent asks for high priority delivery, honoured while the activity
+	// has budget left
+	Urgent bool `json:"urgent,omitempty"`
+}
+
+// Validate checks that the update can be delivered
+func (u *Update) Validate() error {
+	if u.ActivityID == "" {
+		return errors.New("activityId is required")
+	}
+
+	switch u.Event {
+	case EventStart:
+		if u.UserID == "" {
+			return errors.New("userId is required to start an activity")
+		}
+	case EventUpdate, EventEnd:
+	default:
+		return fmt.Errorf("unsupported event %q", u.Event)
+	}
+
+	return nil
+}
+
+func (u *Update) hasAlert() bool {
+	return u.AlertTitle != "" || u.AlertBody != ""
+}
+
+// Result reports how an update was delivered
+type Result struct {
+	Sent    int `json:"sent"`
+	Removed int `json:"removed"`
+
+	// Deferred is set when the throttle coalesced the update into a later push
+	Deferred bool `json:"deferred,omitempty"`
+
+	Priority int `json:"priority,omitempty"`
+}
+
+// Sender delivers live activity pushes; *notifications.APNSService implements it
+type Sender interface {
+	SendLiveActivity(ctx context.Context, bundleID, pushToken string, payload notifications.LiveActivityPayload) (*apns2.Response, error)
+}
+
+// Config tunes update throttling
+type Config struct {
+	// MinInterval is the minimum time between two pushes to one activity;
+	// updates arriving sooner are coalesced into a single push
+	MinInterval time.Duration
+
+	// HighPriorityBudget is the number of priority 10 pushes an activity may
+	// receive per hour; urgent updates beyond it go out at priority 5
+	HighPriorityBudget int
+}
+
+// DefaultConfig returns throttling that stays well inside APNs budgets
+func DefaultConfig() Config {
+	return Config{
+		MinInterval:        15 * time.Second,
+		HighPriorityBudget: 10,
+	}
+}
+
+// throttle is the per-activity delivery state
+type throttle struct {
+	lastSent     time.Time
+	highPriority []time.Time
+	pending      *Update
+	timer        *time.Timer
+}
+
+// Manager sends live activity updates and keeps them within APNs budgets.
+// Throttling state is per replica; the budgets leave room for that.
+type Manager struct {
+	store  Store
+	sender Sender
+	config Config
+
+	mu        sync.Mutex
+	throttles map[string]*throttle
+}
+
+// NewManager creates a new live activity manager
+func NewManager(store Store, sender Sender, config Config) *Manager {
+	return &Manager{
+		store:     store,
+		sender:    sender,
+		config:    config,
+		throttles: make(map[string]*throttle),
+	}
+}
+
+// Publish delivers an update. Start and end events go out immediately;
+// updates within MinInterval of the previous push are coalesced.
+func (m *Manager) Publish(ctx context.Context, update Update) (*Result, error) {
+	if err := update.Validate(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	m.mu.Lock()
+	t, ok := m.throttles[update.ActivityID]
+	if !ok {
+		t = &throttle{}
+		m.throttles[update.ActivityID] = t
+	}
+
+	if update.Event == EventUpdate {
+		if wait := t.lastSent.Add(m.config.MinInterval).Sub(now); !t.lastSent.IsZero() && wait > 0 {
+			t.pending = coalesce(t.pending, update)
+			if t.timer == nil {
+				activityID := update.ActivityID
+				t.timer = time.AfterFunc(wait, func() { m.flush(activityID) })
+			}
+			m.mu.Unlock()
+			return &Result{Deferred: true}, nil
+		}
+	} else {
+		// Start and end supersede anything still waiting
+		if t.timer != nil {
+			t.timer.Stop()
+			t.timer = nil
+		}
+		t.pending = nil
+	}
+
+	t.lastSent = now
+	priority := m.priority(t, update, now)
+	if update.Event == EventEnd {
+		delete(m.throttles, update.ActivityID)
+	}
+	m.mu.Unlock()
+
+	result, err := m.send(ctx, update, priority)
+	if update.Event == EventEnd && result != nil && result.Sent > 0 {
+		if err := m.store.RemoveActivity(ctx, update.ActivityID); err != nil {
+			log.Printf("Failed to remove tokens of ended live activity %s: %v", update.ActivityID, err)
+		}
+	}
+	return result, err
+}
+
+// flush sends the coalesced update of an activity
+func (m *Manager) flush(activityID string) {
+	now := time.Now()
+
+	m.mu.Lock()
+	t, ok := m.throttles[activityID]
+	if !ok || t.pending == nil {
+		m.mu.Unlock()
+		return
+	}
+	update := *t.pending
+	t.pending = nil
+	t.timer = nil
+	t.lastSent = now
+	priority := m.priority(t, update, now)
+	m.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+
+	if _, err := m.send(ctx, update, priority); err != nil {
+		log.Printf("Failed to send coalesced live activity update %s: %v", activityID, err)
+	}
+}
+
+// priority picks the APNs priority of a push and charges the budget.
+// Callers must hold m.mu.
+func (m *Manager) priority(t *throttle, update Update, now time.Time) int {
+	if update.Event == EventUpdate && !update.Urgent && !update.hasAlert() {
+		return apns2.PriorityLow
+	}
+
+	recent := t.highPriority[:0]
+	for _, sent := range t.highPriority {
+		if now.Sub(sent) < time.Hour {
+			recent = append(recent, sent)
+		}
+	}
+	t.highPriority = recent
+
+	if len(t.highPriority) >= m.config.HighPriorityBudget {
+		return apns2.PriorityLow
+	}
+	t.highPriority = append(t.highPriority, now)
+	return apns2.PriorityHigh
+}
+
+// send pushes an update to every token of its activity
+func (m *Manager) send(ctx context.Context, update Update, priority int) (*Result, error) {
+	payload := notifications.LiveActivityPayload{
+		Event:         string(update.Event),
+		ContentState:  update.ContentState,
+		Timestamp:     time.Now(),
+		StaleDate:     update.StaleDate,
+		DismissalDate: update.DismissalDate,
+		AlertTitle:    update.AlertTitle,
+		AlertBody:     update.AlertBody,
+		Priority:      priority,
+	}
+
+	targets, err := m.targets(ctx, update, &payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, ErrNoTokens
+	}
+
+	result := &Result{Priority: priority}
+	var lastErr error
+	for _, target := range targets {
+		response, err := m.sender.SendLiveActivity(ctx, target.bundleID, target.token, payload)
+		if err == nil {
+			result.Sent++
+			continue
+		}
+
+		if response != nil && isUnregistered(response.Reason) {
+			if err := target.remove(ctx); err != nil {
+				log.Printf("Failed to remove live activity token: %v", err)
+			}
+			result.Removed++
+			continue
+		}
+		lastErr = err
+	}
+
+	if result.Sent == 0 {
+		if lastErr != nil {
+			return result, fmt.Errorf("%w: %v", ErrDeliveryFailed, lastErr)
+		}
+		return result, ErrNoTokens
+	}
+	return result, nil
+}
+
+// target is one push token an update is sent to
+type target struct {
+	bundleID string
+	token    string
+	remove   func(ctx context.Context) error
+}
+
+// targets resolves the tokens of an update. A start event for an activity
+// the app already started becomes an update; otherwise it uses the user's
+// push-to-start tokens.
+func (m *Manager) targets(ctx context.Context, update Update, payload *notifications.LiveActivityPayload) ([]target, error) {
+	tokens, err := m.store.Tokens(ctx, update.ActivityID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) > 0 || update.Event != EventStart {
+		if update.Event == EventStart {
+			payload.Event = string(EventUpdate)
+		}
+
+		targets := make([]target, 0, len(tokens))
+		for _, token := range tokens {
+			token := token
+			targets = append(targets, target{
+				bundleID: token.BundleID,
+				token:    token.Token,
+				remove: func(ctx context.Context) error {
+					return m.store.RemoveToken(ctx, token.ActivityID, token.Token)
+				},
+			})
+		}
+		return targets, nil
+	}
+
+	if update.AttributesType == "" {
+		return nil, nil
+	}
+
+	startTokens, err := m.store.StartTokens(ctx, update.UserID, update.AttributesType)
+	if err != nil {
+		return nil, err
+	}
+
+	payload.AttributesType = update.AttributesType
+	payload.Attributes = update.Attributes
+	if payload.Attributes == nil {
+		payload.Attributes = map[string]interface{}{}
+	}
+
+	targets := make([]target, 0, len(startTokens))
+	for _, token := range startTokens {
+		token := token
+		targets = append(targets, target{
+			bundleID: token.BundleID,
+			token:    token.Token,
+			remove: func(ctx context.Context) error {
+				return m.store.RemoveStartToken(ctx, token.UserID, token.Token)
+			},
+		})
+	}
+	return targets, nil
+}
+
+// coalesce merges a new update into the pending one; the latest content
+// wins but an alert or urgency already waiting is kept
+func coalesce(pending *Update, update Update) *Update {
+	if pending != nil {
+		if !update.hasAlert() {
+			update.AlertTitle = pending.AlertTitle
+			update.AlertBody = pending.AlertBody
+		}
+		update.Urgent = update.Urgent || pending.Urgent
+	}
+	return &update
+}
+
+func isUnregistered(reason string) bool {
+	switch reason {
+	case apns2.ReasonUnregistered, apns2.ReasonBadDeviceToken, apns2.ReasonDeviceTokenNotForTopic, apns2.ReasonExpiredToken:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+package liveactivity
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/househelper/notifier/pkg/notifications"
+	"github.com/sideshow/apns2"
+)
+
+type sentPush struct {
+	token   string
+	payload notifications.LiveActivityPayload
+}
+
+type fakeSender struct {
+	mu   sync.Mutex
+	sent []sentPush
+	dead map[string]bool
+}
+
+func (s *fakeSender) SendLiveActivity(ctx context.Context, bundleID, pushToken string, payload notifications.LiveActivityPayload) (*apns2.Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dead[pushToken] {
+		return &apns2.Response{StatusCode: 410, Reason: apns2.ReasonUnregistered}, errors.New("APNS error: Unregistered")
+	}
+	s.sent = append(s.sent, sentPush{token: pushToken, payload: payload})
+	return &apns2.Response{StatusCode: 200}, nil
+}
+
+func (s *fakeSender) pushes() []sentPush {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]sentPush(nil), s.sent...)
+}
+
+func newTestManager(t *testing.T, config Config) (*Manager, *MemoryStore, *fakeSender) {
+	t.Helper()
+	store := NewMemoryStore()
+	sender := &fakeSender{dead: make(map[string]bool)}
+	return NewManager(store, sender, config), store, sender
+}
+
+func registerToken(t *testing.T, store Store, activityID, token string) {
+	t.Helper()
+	err := store.RegisterToken(context.Background(), Token{
+		ActivityID: activityID,
+		UserID:     "user-1",
+		BundleID:   "com.househelper.app",
+		Token:      token,
+	})
+	if err != nil {
+		t.Fatalf("RegisterToken() error = %v", err)
+	}
+}
+
+func TestPublishCoalescesUpdatesWithinMinInterval(t *testing.T) {
+	manager, store, sender := newTestManager(t, Config{MinInterval: 50 * time.Millisecond, HighPriorityBudget: 10})
+	registerToken(t, store, "timer-1", "token-1")
+	ctx := context.Background()
+
+	if _, err := manager.Publish(ctx, Update{ActivityID: "timer-1", UserID: "user-1", Event: EventStart}); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	updates := []Update{
+		{ActivityID: "timer-1", Event: EventUpdate, ContentState: map[string]interface{}{"status": "paused"}, AlertTitle: "Timer paused"},
+		{ActivityID: "timer-1", Event: EventUpdate, ContentState: map[string]interface{}{"status": "running"}},
+	}
+	for i, update := range updates {
+		result, err := manager.Publish(ctx, update)
+		if err != nil {
+			t.Fatalf("update %d: %v", i, err)
+		}
+		if !result.Deferred {
+			t.Fatalf("update %d should have been deferred", i)
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(sender.pushes()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	pushes := sender.pushes()
+	if len(pushes) != 2 {
+		t.Fatalf("sent %d pushes, want start plus one coalesced update", len(pushes))
+	}
+
+	// The app started the activity itself, so start goes out as an update
+	if pushes[0].payload.Event != string(EventUpdate) {
+		t.Errorf("start event = %q, want update", pushes[0].payload.Event)
+	}
+
+	coalesced := pushes[1].payload
+	if coalesced.ContentState["status"] != "running" {
+		t.Errorf("coalesced status = %v, want latest state", coalesced.ContentState["status"])
+	}
+	if coalesced.AlertTitle != "Timer paused" {
+		t.Errorf("coalesced alert = %q, want the pending alert kept", coalesced.AlertTitle)
+	}
+	if coalesced.Priority != apns2.PriorityHigh {
+		t.Errorf("alerting update priority = %d, want high", coalesced.Priority)
+	}
+}
+
+func TestPublishDowngradesPriorityWhenBudgetIsSpent(t *testing.T) {
+	manager, store, sender := newTestManager(t, Config{HighPriorityBudget: 2})
+	registerToken(t, store, "timer-1", "token-1")
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := manager.Publish(ctx, Update{ActivityID: "timer-1", Event: EventUpdate, Urgent: true}); err != nil {
+			t.Fatalf("update %d: %v", i, err)
+		}
+	}
+	if _, err := manager.Publish(ctx, Update{ActivityID: "timer-1", Event: EventUpdate}); err != nil {
+		t.Fatalf("routine update: %v", err)
+	}
+
+	want := []int{apns2.PriorityHigh, apns2.PriorityHigh, apns2.PriorityLow, apns2.PriorityLow}
+	pushes := sender.pushes()
+	if len(pushes) != len(want) {
+		t.Fatalf("sent %d pushes, want %d", len(pushes), len(want))
+	}
+	for i, push := range pushes {
+		if push.payload.Priority != want[i] {
+			t.Errorf("push %d priority = %d, want %d", i, push.payload.Priority, want[i])
+		}
+	}
+}
+
+func TestPublishEndRemovesTokensAndDropsPendingUpdate(t *testing.T) {
+	manager, store, sender := newTestManager(t, Config{MinInterval: time.Hour, HighPriorityBudget: 10})
+	registerToken(t, store, "laundry-1", "token-1")
+	registerToken(t, store, "laundry-1", "token-dead")
+	sender.dead["token-dead"] = true
+	ctx := context.Background()
+
+	result, err := manager.Publish(ctx, Update{ActivityID: "laundry-1", Event: EventUpdate})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if result.Sent != 1 || result.Removed != 1 {
+		t.Fatalf("result = %+v, want one sent and the unregistered token removed", result)
+	}
+
+	if result, _ := manager.Publish(ctx, Update{ActivityID: "laundry-1", Event: EventUpdate}); !result.Deferred {
+		t.Fatal("second update should have been deferred")
+	}
+
+	dismissal := time.Now().Add(15 * time.Minute)
+	if _, err := manager.Publish(ctx, Update{ActivityID: "laundry-1", Event: EventEnd, DismissalDate: dismissal}); err != nil {
+		t.Fatalf("end: %v", err)
+	}
+
+	pushes := sender.pushes()
+	if len(pushes) != 2 || pushes[1].payload.Event != string(EventEnd) {
+		t.Fatalf("pushes = %+v, want the update then the end event", pushes)
+	}
+	if !pushes[1].payload.DismissalDate.Equal(dismissal) {
+		t.Errorf("dismissal date = %v, want %v", pushes[1].payload.DismissalDate, dismissal)
+	}
+
+	tokens, _ := store.Tokens(ctx, "laundry-1")
+	if len(tokens) != 0 {
+		t.Errorf("ended activity still has %d tokens", len(tokens))
+	}
+	if _, err := manager.Publish(ctx, Update{ActivityID: "laundry-1", Event: EventUpdate}); !errors.Is(err, ErrNoTokens) {
+		t.Errorf("update after end error = %v, want ErrNoTokens", err)
+	}
+}
+
+func TestPublishStartUsesPushToStartTokens(t *testing.T) {
+	manager, store, sender := newTestManager(t, DefaultConfig())
+	ctx := context.Background()
+
+	start := Update{
+		ActivityID:     "timer-1",
+		UserID:         "user-1",
+		Event:          EventStart,
+		AttributesType: "TimerAttributes",
+		ContentState:   map[string]interface{}{"status": "running"},
+	}
+	if _, err := manager.Publish(ctx, start); !errors.Is(err, ErrNoTokens) {
+		t.Fatalf("start without tokens error = %v, want ErrNoTokens", err)
+	}
+
+	err := store.RegisterStartToken(ctx, StartToken{
+		UserID:         "user-1",
+		BundleID:       "com.househelper.app",
+		AttributesType: "TimerAttributes",
+		Token:          "start-token",
+	})
+	if err != nil {
+		t.Fatalf("RegisterStartToken() error = %v", err)
+	}
+
+	if _, err := manager.Publish(ctx, start); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	pushes := sender.pushes()
+	if len(pushes) != 1 {
+		t.Fatalf("sent %d pushes, want 1", len(pushes))
+	}
+	payload := pushes[0].payload
+	if pushes[0].token != "start-token" || payload.Event != string(EventStart) || payload.AttributesType != "TimerAttributes" {
+		t.Errorf("push-to-start = %+v, want start event with attributes type", pushes[0])
+	}
+}
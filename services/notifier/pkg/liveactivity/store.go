@@ -0,0 +1,339 @@
+package liveactivity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenTTL bounds how long activity tokens are kept: an activity runs for
+// at most 8 hours and stays on the lock screen for up to 4 more
+const tokenTTL = 12 * time.Hour
+
+// Token is the push token ActivityKit issued for one running activity
+type Token struct {
+	// ActivityID is the ID of the timer or laundry load the activity tracks
+	ActivityID string    `json:"activityId"`
+	UserID     string    `json:"userId"`
+	BundleID   string    `json:"bundleId"`
+	Token      string    `json:"token"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// Validate checks that the token can be used to reach the activity
+func (t *Token) Validate() error {
+	switch {
+	case t.ActivityID == "":
+		return errors.New("activityId is required")
+	case t.UserID == "":
+		return errors.New("userId is required")
+	case t.BundleID == "":
+		return errors.New("bundleId is required")
+	case t.Token == "":
+		return errors.New("token is required")
+	}
+	return nil
+}
+
+// StartToken is a push-to-start token that lets the server start an
+// activity of the given attributes type on a user's device
+type StartToken struct {
+	UserID         string    `json:"userId"`
+	BundleID       string    `json:"bundleId"`
+	AttributesType string    `json:"attributesType"`
+	Token          string    `json:"token"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// Validate checks that the token can be used to start an activity
+func (t *StartToken) Validate() error {
+	switch {
+	case t.UserID == "":
+		return errors.New("userId is required")
+	case t.BundleID == "":
+		return errors.New("bundleId is required")
+	case t.AttributesType == "":
+		return errors.New("attributesType is required")
+	case t.Token == "":
+		return errors.New("token is required")
+	}
+	return nil
+}
+
+// Store keeps the push tokens of live activities
+type Store interface {
+	// RegisterToken adds or refreshes the push token of an activity
+	RegisterToken(ctx context.Context, token Token) error
+
+	// Tokens returns the push tokens of an activity
+	Tokens(ctx context.Context, activityID string) ([]Token, error)
+
+	// RemoveToken forgets one push token of an activity
+	RemoveToken(ctx context.Context, activityID, token string) error
+
+	// RemoveActivity forgets every push token of an activity
+	RemoveActivity(ctx context.Context, activityID string) error
+
+	// RegisterStartToken adds or refreshes a push-to-start token
+	RegisterStartToken(ctx context.Context, token StartToken) error
+
+	// StartTokens returns a user's push-to-start tokens for an attributes type
+	StartTokens(ctx context.Context, userID, attributesType string) ([]StartToken, error)
+
+	// RemoveStartToken forgets a push-to-start token
+	RemoveStartToken(ctx context.Context, userID, token string) error
+}
+
+// MemoryStore is a process-local Store
+type MemoryStore struct {
+	mu          sync.RWMutex
+	tokens      map[string]map[string]Token
+	startTokens map[string]map[string]StartToken
+}
+
+// NewMemoryStore creates a new in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		tokens:      make(map[string]map[string]Token),
+		startTokens: make(map[string]map[string]StartToken),
+	}
+}
+
+// RegisterToken adds or refreshes the push token of an activity
+func (s *MemoryStore) RegisterToken(ctx context.Context, token Token) error {
+	if err := token.Validate(); err != nil {
+		return err
+	}
+	token.UpdatedAt = time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tokens[token.ActivityID] == nil {
+		s.tokens[token.ActivityID] = make(map[string]Token)
+	}
+	s.tokens[token.ActivityID][token.Token] = token
+	return nil
+}
+
+// Tokens returns the push tokens of an activity
+func (s *MemoryStore) Tokens(ctx context.Context, activityID string) ([]Token, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tokens := make([]Token, 0, len(s.tokens[activityID]))
+	for _, token := range s.tokens[activityID] {
+		if time.Since(token.UpdatedAt) < tokenTTL {
+			tokens = append(tokens, token)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].Token < tokens[j].Token })
+	return tokens, nil
+}
+
+// RemoveToken forgets one push token of an activity
+func (s *MemoryStore) RemoveToken(ctx context.Context, activityID, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.tokens[activityID], token)
+	if len(s.tokens[activityID]) == 0 {
+		delete(s.tokens, activityID)
+	}
+	return nil
+}
+
+// RemoveActivity forgets every push token of an activity
+func (s *MemoryStore) RemoveActivity(ctx context.Context, activityID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.tokens, activityID)
+	return nil
+}
+
+// RegisterStartToken adds or refreshes a push-to-start token
+func (s *MemoryStore) RegisterStartToken(ctx context.Context, token StartToken) error {
+	if err := token.Validate(); err != nil {
+		return err
+	}
+	token.UpdatedAt = time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.startTokens[token.UserID] == nil {
+		s.startTokens[token.UserID] = make(map[string]StartToken)
+	}
+	s.startTokens[token.UserID][token.Token] = token
+	return nil
+}
+
+// StartTokens returns a user's push-to-start tokens for an attributes type
+func (s *MemoryStore) StartTokens(ctx context.Context, userID, attributesType string) ([]StartToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var tokens []StartToken
+	for _, token := range s.startTokens[userID] {
+		if token.AttributesType == attributesType {
+			tokens = append(tokens, token)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].Token < tokens[j].Token })
+	return tokens, nil
+}
+
+// RemoveStartToken forgets a push-to-start token
+func (s *MemoryStore) RemoveStartToken(ctx context.Context, userID, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.startTokens[userID], token)
+	return nil
+}
+
+// RedisStore stores tokens in Redis so all replicas share them
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore connects to Redis
+func NewRedisStore(ctx context.Context, redisURL string) (*RedisStore, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse redis url: %w", err)
+	}
+
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return &RedisStore{client: client, prefix: "house-helper:notifier:live-activities"}, nil
+}
+
+// RegisterToken adds or refreshes the push token of an activity
+func (s *RedisStore) RegisterToken(ctx context.Context, token Token) error {
+	if err := token.Validate(); err != nil {
+		return err
+	}
+	token.UpdatedAt = time.Now()
+
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to marshal token: %w", err)
+	}
+
+	key := s.activityKey(token.ActivityID)
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, key, token.Token, data)
+	pipe.Expire(ctx, key, tokenTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to register token: %w", err)
+	}
+	return nil
+}
+
+// Tokens returns the push tokens of an activity
+func (s *RedisStore) Tokens(ctx context.Context, activityID string) ([]Token, error) {
+	values, err := s.client.HGetAll(ctx, s.activityKey(activityID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tokens: %w", err)
+	}
+
+	tokens := make([]Token, 0, len(values))
+	for _, raw := range values {
+		var token Token
+		if err := json.Unmarshal([]byte(raw), &token); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal token: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].Token < tokens[j].Token })
+	return tokens, nil
+}
+
+// RemoveToken forgets one push token of an activity
+func (s *RedisStore) RemoveToken(ctx context.Context, activityID, token string) error {
+	if err := s.client.HDel(ctx, s.activityKey(activityID), token).Err(); err != nil {
+		return fmt.Errorf("failed to remove token: %w", err)
+	}
+	return nil
+}
+
+// RemoveActivity forgets every push token of an activity
+func (s *RedisStore) RemoveActivity(ctx context.Context, activityID string) error {
+	if err := s.client.Del(ctx, s.activityKey(activityID)).Err(); err != nil {
+		return fmt.Errorf("failed to remove activity: %w", err)
+	}
+	return nil
+}
+
+// RegisterStartToken adds or refreshes a push-to-start token
+func (s *RedisStore) RegisterStartToken(ctx context.Context, token StartToken) error {
+	if err := token.Validate(); err != nil {
+		return err
+	}
+	token.UpdatedAt = time.Now()
+
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to marshal start token: %w", err)
+	}
+
+	if err := s.client.HSet(ctx, s.startKey(token.UserID), token.Token, data).Err(); err != nil {
+		return fmt.Errorf("failed to register start token: %w", err)
+	}
+	return nil
+}
+
+// StartTokens returns a user's push-to-start tokens for an attributes type
+func (s *RedisStore) StartTokens(ctx context.Context, userID, attributesType string) ([]StartToken, error) {
+	values, err := s.client.HGetAll(ctx, s.startKey(userID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list start tokens: %w", err)
+	}
+
+	var tokens []StartToken
+	for _, raw := range values {
+		var token StartToken
+		if err := json.Unmarshal([]byte(raw), &token); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal start token: %w", err)
+		}
+		if token.AttributesType == attributesType {
+			tokens = append(tokens, token)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].Token < tokens[j].Token })
+	return tokens, nil
+}
+
+// RemoveStartToken forgets a push-to-start token
+func (s *RedisStore) RemoveStartToken(ctx context.Context, userID, token string) error {
+	if err := s.client.HDel(ctx, s.startKey(userID), token).Err(); err != nil {
+		return fmt.Errorf("failed to remove start token: %w", err)
+	}
+	return nil
+}
+
+// Close closes the Redis connection
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+func (s *RedisStore) activityKey(activityID string) string {
+	return s.prefix + ":activity:" + activityID
+}
+
+func (s *RedisStore) startKey(userID string) string {
+	return s.prefix + ":start:" + userID
+}
@@ -57,6 +57,11 @@ func NewAPNSServiceWithToken(keyPath, keyID, teamID string, production bool) (*A
 	return &APNSService{client: client}, nil
 }
 
+// NewAPNSServiceWithClient creates a new APNS service around a configured client
+func NewAPNSServiceWithClient(client *apns2.Client) *APNSService {
+	return &APNSService{client: client}
+}
+
 // APNSPayload represents an APNS notification payload
 type APNSPayload struct {
 	Title           string            `json:"title"`
@@ -81,7 +86,7 @@ func (a *APNSService) SendNotification(ctx context.Context, bundleID, deviceToke
 	// Build the payload
 	p := payload.NewPayload()
 	if apnsPayload.Title != "" {
-		p.Alert(apnsPayload.Title)
+		p.AlertTitle(apnsPayload.Title)
 	}
 	if apnsPayload.Body != "" {
 		p.AlertBody(apnsPayload.Body)
@@ -162,33 +167,73 @@ func (a *APNSService) SendSilentNotification(ctx context.Context, bundleID, devi
 	return response, nil
 }
 
-// SendLiveActivity sends a live activity notification
-func (a *APNSService) SendLiveActivity(ctx context.Context, bundleID, deviceToken string, activityPayload APNSPayload) (*apns2.Response, error) {
+// LiveActivityPayload represents an ActivityKit push update
+type LiveActivityPayload struct {
+	// Event is "start", "update" or "end"
+	Event        string                 `json:"event"`
+	ContentState map[string]interface{} `json:"contentState,omitempty"`
+	Timestamp    time.Time              `json:"timestamp"`
+
+	// StaleDate marks the content as outdated on the device if no newer update arrives
+	StaleDate time.Time `json:"staleDate,omitempty"`
+
+	// DismissalDate removes an ended activity from the lock screen
+	DismissalDate time.Time `json:"dismissalDate,omitempty"`
+
+	// AttributesType and Attributes are required to push-to-start an activity
+	AttributesType string                 `json:"attributesType,omitempty"`
+	Attributes     map[string]interface{} `json:"attributes,omitempty"`
+
+	AlertTitle string `json:"alertTitle,omitempty"`
+	AlertBody  string `json:"alertBody,omitempty"`
+
+	// Priority is apns2.PriorityHigh or apns2.PriorityLow; high priority
+	// updates count against the activity's update budget
+	Priority int `json:"priority,omitempty"`
+}
+
+// SendLiveActivity starts, updates or ends a live activity
+func (a *APNSService) SendLiveActivity(ctx context.Context, bundleID, pushToken string, activityPayload LiveActivityPayload) (*apns2.Response, error) {
+	priority := activityPayload.Priority
+	if priority == 0 {
+		priority = apns2.PriorityHigh
+	}
+
 	notification := &apns2.Notification{
-		DeviceToken: deviceToken,
+		DeviceToken: pushToken,
 		Topic:       bundleID + ".push-type.liveactivity",
-		Priority:    apns2.PriorityHigh,
+		Priority:    priority,
 		PushType:    apns2.PushTypeLiveActivity,
 	}
 
-	p := payload.NewPayload()
+	timestamp := activityPayload.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
 
-	// Add live activity specific fields
-	if activityPayload.Title != "" {
-		p.Alert(activityPayload.Title)
+	p := payload.NewPayload().
+		SetEvent(payload.ELiveActivityEvent(activityPayload.Event)).
+		SetTimestamp(timestamp.Unix())
+
+	if activityPayload.ContentState != nil {
+		p.SetContentState(activityPayload.ContentState)
 	}
-	if activityPayload.Body != "" {
-		p.AlertBody(activityPayload.Body)
+	if !activityPayload.StaleDate.IsZero() {
+		p.SetStaleDate(activityPayload.StaleDate.Unix())
 	}
-	if len(activityPayload.ContentState) > 0 {
-		p.Custom("content-state", activityPayload.ContentState)
+	if !activityPayload.DismissalDate.IsZero() {
+		p.SetDismissalDate(activityPayload.DismissalDate.Unix())
 	}
-	if activityPayload.TargetContentID != "" {
-		p.Custom("target-content-id", activityPayload.TargetContentID)
+	if activityPayload.AttributesType != "" {
+		p.SetAttributesType(activityPayload.AttributesType)
+		p.SetAttributes(activityPayload.Attributes)
+	}
+	if activityPayload.AlertTitle != "" {
+		p.AlertTitle(activityPayload.AlertTitle)
+	}
+	if activityPayload.AlertBody != "" {
+		p.AlertBody(activityPayload.AlertBody)
 	}
-
-	// Add timestamp
-	p.Custom("timestamp", time.Now().Unix())
 
 	notification.Payload = p
 
@@ -202,7 +247,7 @@ func (a *APNSService) SendLiveActivity(ctx context.Context, bundleID, deviceToke
 		return response, fmt.Errorf("APNS error: %s", response.Reason)
 	}
 
-	log.Printf("Successfully sent APNS live activity to %s", deviceToken)
+	log.Printf("Successfully sent APNS live activity %s to %s", activityPayload.Event, pushToken)
 	return response, nil
 }
 
@@ -0,0 +1,269 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// DigestMode controls whether a user receives emails immediately or batched
+type DigestMode string
+
+const (
+	DigestOff    DigestMode = "off"
+	DigestDaily  DigestMode = "daily"
+	DigestWeekly DigestMode = "weekly"
+)
+
+// Period returns the human readable period of the mode
+func (m DigestMode) Period() string {
+	if m == DigestWeekly {
+		return "weekly"
+	}
+	return "daily"
+}
+
+// Digest item categories, in the order they appear in the email
+const (
+	DigestOverdueTask    = "overdue_task"
+	DigestUpcomingBill   = "upcoming_bill"
+	DigestShoppingChange = "shopping_change"
+)
+
+var digestSectionTitles = map[string]string{
+	DigestOverdueTask:    "Overdue tasks",
+	DigestUpcomingBill:   "Upcoming bills",
+	DigestShoppingChange: "Shopping list changes",
+}
+
+var digestSectionOrder = []string{DigestOverdueTask, DigestUpcomingBill, DigestShoppingChange}
+
+// DigestItem is a single line of a digest
+type DigestItem struct {
+	HouseholdID   string    `json:"householdId"`
+	HouseholdName string    `json:"householdName,omitempty"`
+	Category      string    `json:"category"`
+	Title         string    `json:"title"`
+	Detail        string    `json:"detail,omitempty"`
+	OccurredAt    time.Time `json:"occurredAt"`
+}
+
+// DigestHousehold groups the digest items of one household
+type DigestHousehold struct {
+	Name     string
+	Sections []DigestSection
+}
+
+// DigestSection groups the digest items of one category
+type DigestSection struct {
+	Title string
+	Items []DigestItem
+}
+
+// IsDigestCategory reports whether items of the category can be batched
+func IsDigestCategory(category string) bool {
+	_, ok := digestSectionTitles[category]
+	return ok
+}
+
+// Flush claims: a running flush is given up after flushClaimTTL, so another
+// replica can retry it, and a completed one is kept for completedFlushTTL
+const (
+	flushClaimTTL     = 10 * time.Minute
+	completedFlushTTL = 8 * 24 * time.Hour
+)
+
+// ErrInvalidDigestItem is returned for items that cannot go in a digest
+var ErrInvalidDigestItem = errors.New("invalid digest item")
+
+// DigestQueue batches notification emails into daily or weekly digests
+type DigestQueue struct {
+	email *EmailService
+	store DigestStore
+
+	// Local hour at which digests are sent
+	hour     int
+	location *time.Location
+}
+
+// NewDigestQueue creates a digest queue sending at the given hour that
+// keeps pending digests in memory
+func NewDigestQueue(email *EmailService, hour int, location *time.Location) *DigestQueue {
+	return NewDigestQueueWithStore(email, NewMemoryDigestStore(), hour, location)
+}
+
+// NewDigestQueueWithStore creates a digest queue sending at the given hour
+// that keeps pending digests in the store. Replicas sharing a store send
+// each digest once.
+func NewDigestQueueWithStore(email *EmailService, store DigestStore, hour int, location *time.Location) *DigestQueue {
+	if location == nil {
+		location = time.UTC
+	}
+
+	return &DigestQueue{
+		email:    email,
+		store:    store,
+		hour:     hour,
+		location: location,
+	}
+}
+
+// Add queues an item for the recipient's next digest
+func (q *DigestQueue) Add(ctx context.Context, recipient EmailRecipient, mode DigestMode, item DigestItem) error {
+	if mode != DigestDaily && mode != DigestWeekly {
+		return fmt.Errorf("%w: digest mode must be daily or weekly", ErrInvalidDigestItem)
+	}
+	if !IsDigestCategory(item.Category) {
+		return fmt.Errorf("%w: unsupported digest category %s", ErrInvalidDigestItem, item.Category)
+	}
+	if item.OccurredAt.IsZero() {
+		item.OccurredAt = time.Now()
+	}
+
+	return q.store.Add(ctx, recipient, mode, item)
+}
+
+// Flush sends the pending digests of recipients using the mode and returns
+// the number of emails sent. Digests that fail to send are queued again for
+// the next flush.
+func (q *DigestQueue) Flush(ctx context.Context, mode DigestMode) int {
+	sent, _ := q.flush(ctx, mode)
+	return sent
+}
+
+// flush sends the pending digests of the mode and returns the number of
+// emails sent and of digests queued again after failing
+func (q *DigestQueue) flush(ctx context.Context, mode DigestMode) (sent, failed int) {
+	due, err := q.store.Take(ctx, mode)
+	if err != nil {
+		log.Printf("Failed to take %s digests: %v", mode, err)
+		failed++
+	}
+
+	for _, batch := range due {
+		_, err := q.email.SendDigest(ctx, batch.Recipient, mode, groupDigestItems(batch.Items))
+		if errors.Is(err, ErrEmailSuppressed) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to send %s digest to %s: %v", mode, batch.Recipient.Email, err)
+			if err := q.store.Requeue(ctx, batch); err != nil {
+				log.Printf("Failed to requeue %s digest of %s: %v", mode, batch.Recipient.Email, err)
+			}
+			failed++
+			continue
+		}
+		sent++
+	}
+
+	return sent, failed
+}
+
+// Run sends daily digests at the configured hour and weekly digests on Mondays
+func (q *DigestQueue) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			q.tick(ctx, now.In(q.location))
+		}
+	}
+}
+
+// tick flushes the digests that are due at the given local time
+func (q *DigestQueue) tick(ctx context.Context, now time.Time) {
+	if now.Hour() != q.hour {
+		return
+	}
+
+	today := now.Format("2006-01-02")
+	q.flushOnce(ctx, DigestDaily, today)
+	if now.Weekday() == time.Monday {
+		q.flushOnce(ctx, DigestWeekly, today)
+	}
+}
+
+// flushOnce flushes the digests of the mode unless this or another replica
+// flushed them today already. A flush with failures is retried on the
+// following ticks of the hour.
+func (q *DigestQueue) flushOnce(ctx context.Context, mode DigestMode, today string) {
+	key := string(mode) + ":" + today
+	claimed, err := q.store.ClaimFlush(ctx, key, flushClaimTTL)
+	if err != nil {
+		log.Printf("Failed to claim %s digest flush: %v", mode, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	sent, failed := q.flush(ctx, mode)
+	if failed == 0 {
+		err = q.store.CompleteFlush(ctx, key, completedFlushTTL)
+	} else {
+		err = q.store.ReleaseFlush(ctx, key)
+	}
+	if err != nil {
+		log.Printf("Failed to record %s digest flush: %v", mode, err)
+	}
+	if sent > 0 {
+		log.Printf("Sent %d %s digests", sent, mode)
+	}
+}
+
+// groupDigestItems groups items by household and category
+func groupDigestItems(items []DigestItem) []DigestHousehold {
+	type household struct {
+		name     string
+		sections map[string][]DigestItem
+	}
+
+	households := make(map[string]*household)
+	var order []string
+	for _, item := range items {
+		h, ok := households[item.HouseholdID]
+		if !ok {
+			h = &household{name: item.HouseholdName, sections: make(map[string][]DigestItem)}
+			households[item.HouseholdID] = h
+			order = append(order, item.HouseholdID)
+		}
+		if h.name == "" {
+			h.name = item.HouseholdName
+		}
+		h.sections[item.Category] = append(h.sections[item.Category], item)
+	}
+
+	result := make([]DigestHousehold, 0, len(order))
+	for _, id := range order {
+		h := households[id]
+		name := h.name
+		if name == "" {
+			name = "Your household"
+		}
+
+		digest := DigestHousehold{Name: name}
+		for _, category := range digestSectionOrder {
+			sectionItems := h.sections[category]
+			if len(sectionItems) == 0 {
+				continue
+			}
+			sort.SliceStable(sectionItems, func(i, j int) bool {
+				return sectionItems[i].OccurredAt.Before(sectionItems[j].OccurredAt)
+			})
+			digest.Sections = append(digest.Sections, DigestSection{
+				Title: digestSectionTitles[category],
+				Items: sectionItems,
+			})
+		}
+		result = append(result, digest)
+	}
+
+	return result
+}
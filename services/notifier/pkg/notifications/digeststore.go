@@ -0,0 +1,320 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DigestBatch is the pending digest of a recipient
+type DigestBatch struct {
+	Recipient EmailRecipient `json:"recipient"`
+	Mode      DigestMode     `json:"mode"`
+	Items     []DigestItem   `json:"items,omitempty"`
+}
+
+// DigestStore keeps pending digests until they are sent
+type DigestStore interface {
+	// Add appends an item to the recipient's pending digest, which moves to
+	// the given mode
+	Add(ctx context.Context, recipient EmailRecipient, mode DigestMode, item DigestItem) error
+
+	// Take removes and returns the pending digests of the mode
+	Take(ctx context.Context, mode DigestMode) ([]DigestBatch, error)
+
+	// Requeue puts back a digest that failed to send, ahead of any items
+	// added for the recipient since it was taken
+	Requeue(ctx context.Context, batch DigestBatch) error
+
+	// ClaimFlush claims the flush named key for ttl. It returns false when
+	// the flush is claimed already, running or completed.
+	ClaimFlush(ctx context.Context, key string, ttl time.Duration) (bool, error)
+
+	// CompleteFlush keeps the claim of a flush that sent every digest for
+	// ttl, so it is not run again
+	CompleteFlush(ctx context.Context, key string, ttl time.Duration) error
+
+	// ReleaseFlush drops the claim of a flush so it can be retried
+	ReleaseFlush(ctx context.Context, key string) error
+}
+
+// MemoryDigestStore is a process-local DigestStore; pending digests are
+// lost on restart
+type MemoryDigestStore struct {
+	mu      sync.Mutex
+	batches map[string]*DigestBatch
+	claims  map[string]time.Time
+}
+
+// NewMemoryDigestStore creates a new in-memory digest store
+func NewMemoryDigestStore() *MemoryDigestStore {
+	return &MemoryDigestStore{
+		batches: make(map[string]*DigestBatch),
+		claims:  make(map[string]time.Time),
+	}
+}
+
+// Add appends an item to the recipient's pending digest
+func (s *MemoryDigestStore) Add(ctx context.Context, recipient EmailRecipient, mode DigestMode, item DigestItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := strings.ToLower(recipient.Email)
+	batch, ok := s.batches[key]
+	if !ok {
+		batch = &DigestBatch{Recipient: recipient}
+		s.batches[key] = batch
+	}
+	batch.Mode = mode
+	batch.Items = append(batch.Items, item)
+	return nil
+}
+
+// Take removes and returns the pending digests of the mode
+func (s *MemoryDigestStore) Take(ctx context.Context, mode DigestMode) ([]DigestBatch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []DigestBatch
+	for key, batch := range s.batches {
+		if batch.Mode == mode {
+			due = append(due, *batch)
+			delete(s.batches, key)
+		}
+	}
+	return due, nil
+}
+
+// Requeue puts back a digest that failed to send
+func (s *MemoryDigestStore) Requeue(ctx context.Context, batch DigestBatch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := strings.ToLower(batch.Recipient.Email)
+	if queued, ok := s.batches[key]; ok {
+		queued.Items = append(batch.Items, queued.Items...)
+		return nil
+	}
+	s.batches[key] = &batch
+	return nil
+}
+
+// ClaimFlush claims the flush named key for ttl
+func (s *MemoryDigestStore) ClaimFlush(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if until, ok := s.claims[key]; ok && now.Before(until) {
+		return false, nil
+	}
+	s.claims[key] = now.Add(ttl)
+	return true, nil
+}
+
+// CompleteFlush keeps the claim of a completed flush for ttl
+func (s *MemoryDigestStore) CompleteFlush(ctx context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.claims[key] = time.Now().Add(ttl)
+	return nil
+}
+
+// ReleaseFlush drops the claim of a flush
+func (s *MemoryDigestStore) ReleaseFlush(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.claims, key)
+	return nil
+}
+
+// takeDigestScript atomically removes a recipient's pending digest if it
+// is of the requested mode, so items added meanwhile are never lost
+var takeDigestScript = redis.NewScript(`
+local recipient = redis.call('HGET', KEYS[1], ARGV[1])
+if not recipient then
+	return false
+end
+if cjson.decode(recipient).mode ~= ARGV[2] then
+	return false
+end
+local items = redis.call('LRANGE', KEYS[2], 0, -1)
+redis.call('DEL', KEYS[2])
+redis.call('HDEL', KEYS[1], ARGV[1])
+return {recipient, items}
+`)
+
+// RedisDigestStore keeps pending digests in Redis so they survive restarts
+// and every replica adds to the same digest
+type RedisDigestStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisDigestStore connects to Redis
+func NewRedisDigestStore(ctx context.Context, redisURL string) (*RedisDigestStore, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse redis url: %w", err)
+	}
+
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return &RedisDigestStore{client: client, prefix: "house-helper:notifier:digest"}, nil
+}
+
+// Add appends an item to the recipient's pending digest
+func (s *RedisDigestStore) Add(ctx context.Context, recipient EmailRecipient, mode DigestMode, item DigestItem) error {
+	key := strings.ToLower(recipient.Email)
+	recipientData, err := json.Marshal(DigestBatch{Recipient: recipient, Mode: mode})
+	if err != nil {
+		return fmt.Errorf("failed to marshal digest recipient: %w", err)
+	}
+	itemData, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("failed to marshal digest item: %w", err)
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, s.recipientsKey(), key, recipientData)
+		pipe.RPush(ctx, s.itemsKey(key), itemData)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to queue digest item: %w", err)
+	}
+	return nil
+}
+
+// Take removes and returns the pending digests of the mode
+func (s *RedisDigestStore) Take(ctx context.Context, mode DigestMode) ([]DigestBatch, error) {
+	keys, err := s.client.HKeys(ctx, s.recipientsKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list digest recipients: %w", err)
+	}
+
+	var due []DigestBatch
+	for _, key := range keys {
+		result, err := takeDigestScript.Run(ctx, s.client, []string{s.recipientsKey(), s.itemsKey(key)}, key, string(mode)).Slice()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return due, fmt.Errorf("failed to take digest of %s: %w", key, err)
+		}
+
+		batch, err := decodeDigestBatch(result)
+		if err != nil {
+			return due, err
+		}
+		due = append(due, *batch)
+	}
+	return due, nil
+}
+
+// Requeue puts back a digest that failed to send
+func (s *RedisDigestStore) Requeue(ctx context.Context, batch DigestBatch) error {
+	key := strings.ToLower(batch.Recipient.Email)
+	recipientData, err := json.Marshal(DigestBatch{Recipient: batch.Recipient, Mode: batch.Mode})
+	if err != nil {
+		return fmt.Errorf("failed to marshal digest recipient: %w", err)
+	}
+
+	// LPUSH prepends one by one, so the items go in back to front
+	items := make([]interface{}, 0, len(batch.Items))
+	for i := len(batch.Items) - 1; i >= 0; i-- {
+		data, err := json.Marshal(batch.Items[i])
+		if err != nil {
+			return fmt.Errorf("failed to marshal digest item: %w", err)
+		}
+		items = append(items, data)
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSetNX(ctx, s.recipientsKey(), key, recipientData)
+		if len(items) > 0 {
+			pipe.LPush(ctx, s.itemsKey(key), items...)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to requeue digest: %w", err)
+	}
+	return nil
+}
+
+// ClaimFlush claims the flush named key for ttl
+func (s *RedisDigestStore) ClaimFlush(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	ok, err := s.client.SetNX(ctx, s.flushKey(key), "running", ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim digest flush: %w", err)
+	}
+	return ok, nil
+}
+
+// CompleteFlush keeps the claim of a completed flush for ttl
+func (s *RedisDigestStore) CompleteFlush(ctx context.Context, key string, ttl time.Duration) error {
+	if err := s.client.Set(ctx, s.flushKey(key), "done", ttl).Err(); err != nil {
+		return fmt.Errorf("failed to complete digest flush: %w", err)
+	}
+	return nil
+}
+
+// ReleaseFlush drops the claim of a flush
+func (s *RedisDigestStore) ReleaseFlush(ctx context.Context, key string) error {
+	if err := s.client.Del(ctx, s.flushKey(key)).Err(); err != nil {
+		return fmt.Errorf("failed to release digest flush: %w", err)
+	}
+	return nil
+}
+
+// Close closes the Redis connection
+func (s *RedisDigestStore) Close() error {
+	return s.client.Close()
+}
+
+func (s *RedisDigestStore) recipientsKey() string {
+	return s.prefix + ":recipients"
+}
+
+func (s *RedisDigestStore) itemsKey(recipient string) string {
+	return s.prefix + ":items:" + recipient
+}
+
+func (s *RedisDigestStore) flushKey(key string) string {
+	return s.prefix + ":flush:" + key
+}
+
+// decodeDigestBatch decodes the recipient and items returned by the take
+// script
+func decodeDigestBatch(result []interface{}) (*DigestBatch, error) {
+	if len(result) != 2 {
+		return nil, fmt.Errorf("unexpected digest take result of length %d", len(result))
+	}
+	recipientData, _ := result[0].(string)
+	rawItems, _ := result[1].([]interface{})
+
+	var batch DigestBatch
+	if err := json.Unmarshal([]byte(recipientData), &batch); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal digest recipient: %w", err)
+	}
+	for _, raw := range rawItems {
+		data, _ := raw.(string)
+		var item DigestItem
+		if err := json.Unmarshal([]byte(data), &item); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal digest item: %w", err)
+		}
+		batch.Items = append(batch.Items, item)
+	}
+	return &batch, nil
+}
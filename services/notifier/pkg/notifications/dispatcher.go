@@ -0,0 +1,408 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"firebase.google.com/go/v4/errorutils"
+	"firebase.google.com/go/v4/messaging"
+	"github.com/househelper/notifier/pkg/devices"
+	"github.com/sideshow/apns2"
+)
+
+// ErrNoDevices is returned when the user has no registered devices
+var ErrNoDevices = errors.New("user has no registered devices")
+
+// ErrDeliveryFailed is returned when no device could be reached
+var ErrDeliveryFailed = errors.New("notification could not be delivered to any device")
+
+// ErrDeviceUnregistered is returned by channels when the provider reports
+// that the device token or subscription is no longer valid
+var ErrDeviceUnregistered = errors.New("device is no longer registered")
+
+// ErrInvalidPayload is returned by channels for a notification that cannot
+// be encoded or that the provider SDK rejects before sending it. Sending it
+// again cannot succeed.
+var ErrInvalidPayload = errors.New("notification payload is invalid")
+
+// ProviderError is a delivery failure reported by a push provider
+type ProviderError struct {
+	Platform   devices.Platform
+	StatusCode int
+
+	// RetryAfter is the delay the provider asked for, if any
+	RetryAfter time.Duration
+
+	Err error
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("%s provider returned %d: %v", e.Platform, e.StatusCode, e.Err)
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether the provider is throttling or temporarily failing
+func (e *ProviderError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// ParseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// Notification is a push notification sent to every device of a user
+type Notification struct {
+	UserID      string            `json:"userId"`
+	Title       string            `json:"title"`
+	Body        string            `json:"body"`
+	ImageURL    string            `json:"imageUrl,omitempty"`
+	ClickAction string            `json:"clickAction,omitempty"`
+	Data        map[string]string `json:"data,omitempty"`
+
+	// Urgency is a Web Push urgency ("very-low", "low", "normal", "high")
+	Urgency string `json:"urgency,omitempty"`
+
+	// TTL is how long, in seconds, providers keep an undelivered notification
+	TTL int `json:"ttl,omitempty"`
+
+	// APNs presentation options; Sound defaults to "default"
+	Badge          *int   `json:"badge,omitempty"`
+	Sound          string `json:"sound,omitempty"`
+	Category       string `json:"category,omitempty"`
+	ThreadID       string `json:"threadId,omitempty"`
+	MutableContent bool   `json:"mutableContent,omitempty"`
+
+	// ContentState and TargetContentID are passed to APNs as content-state
+	// and target-content-id
+	ContentState    map[string]string `json:"contentState,omitempty"`
+	TargetContentID string            `json:"targetContentId,omitempty"`
+}
+
+// Channel delivers notifications to devices of one platform
+type Channel interface {
+	Platform() devices.Platform
+	Send(ctx context.Context, device devices.Device, n Notification) error
+}
+
+// DispatchResult summarizes the delivery of a notification
+type DispatchResult struct {
+	Delivered int      `json:"delivered"`
+	Failed    int      `json:"failed"`
+	Removed   int      `json:"removed"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// Dispatcher fans notifications out to all registered devices of a user
+// through the channel of each device's platform
+type Dispatcher struct {
+	registry devices.Registry
+	channels map[devices.Platform]Channel
+}
+
+// NewDispatcher creates a new dispatcher
+func NewDispatcher(registry devices.Registry) *Dispatcher {
+	return &Dispatcher{
+		registry: registry,
+		channels: make(map[devices.Platform]Channel),
+	}
+}
+
+// RegisterChannel adds a delivery channel. It must be called before Dispatch.
+func (d *Dispatcher) RegisterChannel(channel Channel) {
+	d.channels[channel.Platform()] = channel
+}
+
+// Dispatch sends the notification to every device of the user. Devices the
+// provider reports as unregistered are removed from the registry.
+func (d *Dispatcher) Dispatch(ctx context.Context, n Notification) (*DispatchResult, error) {
+	userDevices, err := d.registry.List(ctx, n.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+	if len(userDevices) == 0 {
+		return &DispatchResult{}, ErrNoDevices
+	}
+
+	result := &DispatchResult{}
+	for _, device := range userDevices {
+		err := d.Deliver(ctx, device, n)
+		switch {
+		case errors.Is(err, ErrDeviceUnregistered):
+			result.Removed++
+		case err != nil:
+			log.Printf("Failed to send %s notification to device %s: %v", device.Platform, device.ID, err)
+			result.Failed++
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", device.Platform, err))
+		default:
+			result.Delivered++
+		}
+	}
+
+	if result.Delivered == 0 {
+		if result.Failed == 0 {
+			return result, ErrNoDevices
+		}
+		return result, ErrDeliveryFailed
+	}
+
+	return result, nil
+}
+
+// isEncodingError reports whether err is a failure to encode a payload as
+// JSON, rather than to send it
+func isEncodingError(err error) bool {
+	var typeErr *json.UnsupportedTypeError
+	var valueErr *json.UnsupportedValueError
+	var marshalerErr *json.MarshalerError
+	return errors.As(err, &typeErr) || errors.As(err, &valueErr) || errors.As(err, &marshalerErr)
+}
+
+// Devices returns the registered devices of a user
+func (d *Dispatcher) Devices(ctx context.Context, userID string) ([]devices.Device, error) {
+	userDevices, err := d.registry.List(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+	return userDevices, nil
+}
+
+// Platforms returns the platforms that have a channel
+func (d *Dispatcher) Platforms() []devices.Platform {
+	platforms := make([]devices.Platform, 0, len(d.channels))
+	for platform := range d.channels {
+		platforms = append(platforms, platform)
+	}
+	return platforms
+}
+
+// Deliver sends the notification to one device through its platform's
+// channel. A device the provider reports as unregistered is removed from the
+// registry and ErrDeviceUnregistered is returned.
+func (d *Dispatcher) Deliver(ctx context.Context, device devices.Device, n Notification) error {
+	channel, ok := d.channels[device.Platform]
+	if !ok {
+		return fmt.Errorf("no channel configured for %s", device.Platform)
+	}
+
+	err := channel.Send(ctx, device, n)
+	if errors.Is(err, ErrDeviceUnregistered) {
+		log.Printf("Removing unregistered %s device %s of user %s", device.Platform, device.ID, device.UserID)
+		if err := d.registry.Remove(ctx, device.UserID, device.ID); err != nil && !errors.Is(err, devices.ErrNotFound) {
+			log.Printf("Failed to remove device %s: %v", device.ID, err)
+		}
+	}
+	return err
+}
+
+// FCMChannel delivers notifications to Android devices through FCM
+type FCMChannel struct {
+	service FCMProvider
+}
+
+// NewFCMChannel creates a new FCM channel
+func NewFCMChannel(service FCMProvider) *FCMChannel {
+	return &FCMChannel{service: service}
+}
+
+// Platform returns the platform served by the channel
+func (c *FCMChannel) Platform() devices.Platform {
+	return devices.PlatformAndroid
+}
+
+// Send delivers the notification to an FCM token
+func (c *FCMChannel) Send(ctx context.Context, device devices.Device, n Notification) error {
+	_, err := c.service.SendToToken(ctx, device.Token, NotificationPayload{
+		Title:       n.Title,
+		Body:        n.Body,
+		ImageURL:    n.ImageURL,
+		ClickAction: n.ClickAction,
+		Data:        n.Data,
+	})
+	if isFCMUnregistered(err) {
+		return ErrDeviceUnregistered
+	}
+	if err != nil {
+		return fcmProviderError(err)
+	}
+	return nil
+}
+
+// fcmProviderError attaches the HTTP status and Retry-After of an FCM error.
+// A message the SDK rejected without sending it is an invalid payload.
+func fcmProviderError(err error) error {
+	for cause := err; cause != nil; cause = errors.Unwrap(cause) {
+		if response := errorutils.HTTPResponse(cause); response != nil {
+			return &ProviderError{
+				Platform:   devices.PlatformAndroid,
+				StatusCode: response.StatusCode,
+				RetryAfter: ParseRetryAfter(response.Header.Get("Retry-After"), time.Now()),
+				Err:        err,
+			}
+		}
+		if messaging.IsQuotaExceeded(cause) {
+			return &ProviderError{Platform: devices.PlatformAndroid, StatusCode: http.StatusTooManyRequests, Err: err}
+		}
+		if messaging.IsUnavailable(cause) || messaging.IsInternal(cause) {
+			return &ProviderError{Platform: devices.PlatformAndroid, StatusCode: http.StatusServiceUnavailable, Err: err}
+		}
+		if isFirebaseError(cause) {
+			// The request failed in transit
+			return err
+		}
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	// The SDK validates a message before sending it and reports any failure
+	// once sent as a Firebase error, so this is a message FCM never accepts
+	return fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+}
+
+// isFirebaseError reports whether err is an error the Firebase SDK returns
+// for a request it sent, which all carry one of these codes
+func isFirebaseError(err error) bool {
+	for _, is := range []func(error) bool{
+		errorutils.IsInvalidArgument, errorutils.IsFailedPrecondition, errorutils.IsOutOfRange,
+		errorutils.IsUnauthenticated, errorutils.IsPermissionDenied, errorutils.IsNotFound,
+		errorutils.IsConflict, errorutils.IsAborted, errorutils.IsAlreadyExists,
+		errorutils.IsResourceExhausted, errorutils.IsCancelled, errorutils.IsDataLoss,
+		errorutils.IsUnknown, errorutils.IsInternal, errorutils.IsUnavailable,
+		errorutils.IsDeadlineExceeded,
+	} {
+		if is(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// isFCMUnregistered unwraps err looking for an FCM unregistered token error
+func isFCMUnregistered(err error) bool {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if messaging.IsUnregistered(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// APNSChannel delivers notifications to iOS devices through APNs
+type APNSChannel struct {
+	service APNSProvider
+}
+
+// NewAPNSChannel creates a new APNs channel
+func NewAPNSChannel(service APNSProvider) *APNSChannel {
+	return &APNSChannel{service: service}
+}
+
+// Platform returns the platform served by the channel
+func (c *APNSChannel) Platform() devices.Platform {
+	return devices.PlatformIOS
+}
+
+// Send delivers the notification to an APNs device token
+func (c *APNSChannel) Send(ctx context.Context, device devices.Device, n Notification) error {
+	sound := n.Sound
+	if sound == "" {
+		sound = "default"
+	}
+
+	response, err := c.service.SendNotification(ctx, device.BundleID, device.Token, APNSPayload{
+		Title:           n.Title,
+		Body:            n.Body,
+		Badge:           n.Badge,
+		Sound:           sound,
+		Category:        n.Category,
+		ThreadID:        n.ThreadID,
+		CustomData:      n.Data,
+		MutableContent:  n.MutableContent,
+		ContentState:    n.ContentState,
+		TargetContentID: n.TargetContentID,
+	})
+	if response == nil {
+		if isEncodingError(err) {
+			return fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+		}
+		return err
+	}
+
+	if !response.Sent() {
+		switch response.Reason {
+		case apns2.ReasonUnregistered, apns2.ReasonBadDeviceToken, apns2.ReasonDeviceTokenNotForTopic:
+			return ErrDeviceUnregistered
+		}
+		return &ProviderError{
+			Platform:   devices.PlatformIOS,
+			StatusCode: response.StatusCode,
+			Err:        fmt.Errorf("apns rejected notification: %s", response.Reason),
+		}
+	}
+
+	return nil
+}
+
+// WebPushChannel delivers notifications to browsers through Web Push
+type WebPushChannel struct {
+	service *WebPushService
+}
+
+// NewWebPushChannel creates a new Web Push channel
+func NewWebPushChannel(service *WebPushService) *WebPushChannel {
+	return &WebPushChannel{service: service}
+}
+
+// Platform returns the platform served by the channel
+func (c *WebPushChannel) Platform() devices.Platform {
+	return devices.PlatformWeb
+}
+
+// Send encrypts and delivers the notification to a browser subscription
+func (c *WebPushChannel) Send(ctx context.Context, device devices.Device, n Notification) error {
+	if device.WebPush == nil {
+		return ErrDeviceUnregistered
+	}
+
+	// The service worker renders this payload with showNotification
+	payload, err := json.Marshal(map[string]interface{}{
+		"title": n.Title,
+		"body":  n.Body,
+		"image": n.ImageURL,
+		"url":   n.ClickAction,
+		"data":  n.Data,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: failed to marshal web push payload: %v", ErrInvalidPayload, err)
+	}
+
+	err = c.service.Send(ctx, *device.WebPush, payload, WebPushOptions{
+		TTL:     time.Duration(n.TTL) * time.Second,
+		Urgency: n.Urgency,
+	})
+	if errors.Is(err, ErrSubscriptionExpired) {
+		return ErrDeviceUnregistered
+	}
+	return err
+}
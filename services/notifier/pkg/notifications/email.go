@@ -0,0 +1,417 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"embed"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//go:embed templates/*.tmpl
+var emailTemplates embed.FS
+
+// Email categories used for unsubscribe links
+const (
+	// EmailCategoryAll unsubscribes the address from every email
+	EmailCategoryAll = "all"
+
+	// EmailCategoryDigest unsubscribes the address from digests only
+	EmailCategoryDigest = "digest"
+)
+
+// ErrEmailSuppressed is returned when the recipient unsubscribed or bounced
+var ErrEmailSuppressed = errors.New("email address is suppressed")
+
+// ErrInvalidUnsubscribeToken is returned for tampered or malformed tokens
+var ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe token")
+
+// ErrInvalidBounceSignature is returned for bounce reports that are not
+// signed with the bounce secret, or were signed too long ago
+var ErrInvalidBounceSignature = errors.New("invalid bounce signature")
+
+// Headers the mail server signs bounce reports with. The signature is
+// "v1=" followed by the hex HMAC-SHA256, keyed with the bounce secret, of
+// "<unix seconds>.<body>".
+const (
+	BounceTimestampHeader = "X-Bounce-Timestamp"
+	BounceSignatureHeader = "X-Bounce-Signature"
+)
+
+// bounceTolerance is how old a bounce report's timestamp may be
+const bounceTolerance = 5 * time.Minute
+
+// EmailConfig holds SMTP and link settings for the email channel
+type EmailConfig struct {
+	Host          string
+	Port          string
+	Username      string
+	Password      string
+	From          string
+	FromName      string
+	BounceAddress string
+	BaseURL       string
+	SigningSecret string
+	BounceSecret  string
+}
+
+// EmailRecipient identifies who receives an email
+type EmailRecipient struct {
+	UserID string `json:"userId,omitempty"`
+	Email  string `json:"email"`
+	Name   string `json:"name,omitempty"`
+}
+
+// EmailMessage is a single notification email
+type EmailMessage struct {
+	To        EmailRecipient `json:"to"`
+	Subject   string         `json:"subject"`
+	Title     string         `json:"title"`
+	Body      string         `json:"body"`
+	ActionURL string         `json:"actionUrl,omitempty"`
+}
+
+// sendMailFunc matches smtp.SendMail
+type sendMailFunc func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+
+// EmailService sends notification emails and digests over SMTP
+type EmailService struct {
+	config       EmailConfig
+	suppressions SuppressionList
+	html         *htmltemplate.Template
+	text         *texttemplate.Template
+	sendMail     sendMailFunc
+}
+
+// NewEmailService creates a new email service. Without credentials it
+// talks plain SMTP, which is what MailHog and similar local servers expect.
+func NewEmailService(config EmailConfig, suppressions SuppressionList) (*EmailService, error) {
+	if config.From == "" {
+		return nil, errors.New("email sender address is required")
+	}
+	if config.SigningSecret == "" {
+		return nil, errors.New("email signing secret is required")
+	}
+
+	html, err := htmltemplate.ParseFS(emailTemplates, "templates/*.html.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse html email templates: %w", err)
+	}
+
+	text, err := texttemplate.ParseFS(emailTemplates, "templates/*.txt.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse text email templates: %w", err)
+	}
+
+	if suppressions == nil {
+		suppressions = NewMemorySuppressionList()
+	}
+
+	return &EmailService{
+		config:       config,
+		suppressions: suppressions,
+		html:         html,
+		text:         text,
+		sendMail:     smtp.SendMail,
+	}, nil
+}
+
+// Send renders and sends a notification email
+func (s *EmailService) Send(ctx context.Context, msg EmailMessage) (string, error) {
+	if err := s.checkSuppressed(ctx, msg.To.Email, EmailCategoryAll); err != nil {
+		return "", err
+	}
+
+	unsubscribeURL := s.UnsubscribeURL(msg.To.Email, EmailCategoryAll)
+	data := map[string]interface{}{
+		"Name":           msg.To.Name,
+		"Title":          msg.Title,
+		"Body":           msg.Body,
+		"ActionURL":      msg.ActionURL,
+		"UnsubscribeURL": unsubscribeURL,
+	}
+
+	subject := msg.Subject
+	if subject == "" {
+		subject = msg.Title
+	}
+
+	return s.send(msg.To, subject, "notification", data, unsubscribeURL)
+}
+
+// SendDigest renders and sends a digest email
+func (s *EmailService) SendDigest(ctx context.Context, to EmailRecipient, mode DigestMode, households []DigestHousehold) (string, error) {
+	if err := s.checkSuppressed(ctx, to.Email, EmailCategoryDigest); err != nil {
+		return "", err
+	}
+
+	unsubscribeURL := s.UnsubscribeURL(to.Email, EmailCategoryDigest)
+	data := map[string]interface{}{
+		"Name":           to.Name,
+		"Period":         mode.Period(),
+		"Households":     households,
+		"UnsubscribeURL": unsubscribeURL,
+	}
+
+	subject := fmt.Sprintf("Your %s House Helper digest", mode.Period())
+	return s.send(to, subject, "digest", data, unsubscribeURL)
+}
+
+// UnsubscribeURL returns a signed one-click unsubscribe link
+func (s *EmailService) UnsubscribeURL(email, category string) string {
+	return fmt.Sprintf("%s/email/unsubscribe?token=%s", strings.TrimRight(s.config.BaseURL, "/"), url.QueryEscape(s.unsubscribeToken(email, category)))
+}
+
+// UnsubscribeTarget verifies an unsubscribe token and returns the address
+// and category it unsubscribes, without suppressing anything
+func (s *EmailService) UnsubscribeTarget(token string) (string, string, error) {
+	return s.verifyUnsubscribeToken(token)
+}
+
+// Unsubscribe verifies an unsubscribe token and suppresses the address
+func (s *EmailService) Unsubscribe(ctx context.Context, token string) (string, string, error) {
+	email, category, err := s.verifyUnsubscribeToken(token)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := s.suppressions.Suppress(ctx, email, category, "unsubscribed"); err != nil {
+		return "", "", fmt.Errorf("failed to unsubscribe %s: %w", email, err)
+	}
+
+	return email, category, nil
+}
+
+// VerifyBounce checks the timestamp and signature headers of a bounce
+// report. Without a bounce secret every report is rejected, so nobody can
+// suppress addresses by posting made-up bounces.
+func (s *EmailService) VerifyBounce(timestamp, signature string, body []byte, now time.Time) error {
+	if s.config.BounceSecret == "" {
+		return ErrInvalidBounceSignature
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidBounceSignature
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > bounceTolerance || age < -bounceTolerance {
+		return ErrInvalidBounceSignature
+	}
+
+	encoded, ok := strings.CutPrefix(signature, "v1=")
+	if !ok {
+		return ErrInvalidBounceSignature
+	}
+	decoded, err := hex.DecodeString(encoded)
+	if err != nil || !hmac.Equal(decoded, s.bounceMAC(seconds, body)) {
+		return ErrInvalidBounceSignature
+	}
+	return nil
+}
+
+// HandleBounce records a bounce reported by the mail server. Hard bounces
+// and complaints suppress the address immediately, soft bounces after
+// repeated failures.
+func (s *EmailService) HandleBounce(ctx context.Context, bounce Bounce) error {
+	switch bounce.Type {
+	case BounceHard, BounceComplaint:
+		log.Printf("Suppressing %s after %s bounce: %s", bounce.Email, bounce.Type, bounce.Reason)
+		return s.suppressions.Suppress(ctx, bounce.Email, EmailCategoryAll, string(bounce.Type))
+
+	case BounceSoft:
+		count, err := s.suppressions.RecordSoftBounce(ctx, bounce.Email)
+		if err != nil {
+			return err
+		}
+		if count >= maxSoftBounces {
+			log.Printf("Suppressing %s after %d soft bounces: %s", bounce.Email, count, bounce.Reason)
+			return s.suppressions.Suppress(ctx, bounce.Email, EmailCategoryAll, "soft_bounce")
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown bounce type %q", bounce.Type)
+	}
+}
+
+func (s *EmailService) checkSuppressed(ctx context.Context, email, category string) error {
+	if email == "" {
+		return errors.New("recipient email is required")
+	}
+
+	suppressed, err := s.suppressions.IsSuppressed(ctx, email, category)
+	if err != nil {
+		return fmt.Errorf("failed to check suppression list: %w", err)
+	}
+	if suppressed {
+		return ErrEmailSuppressed
+	}
+
+	return nil
+}
+
+// send renders the named template pair and delivers the message
+func (s *EmailService) send(to EmailRecipient, subject, template string, data interface{}, unsubscribeURL string) (string, error) {
+	var htmlBody, textBody bytes.Buffer
+	if err := s.html.ExecuteTemplate(&htmlBody, template+".html.tmpl", data); err != nil {
+		return "", fmt.Errorf("failed to render html email: %w", err)
+	}
+	if err := s.text.ExecuteTemplate(&textBody, template+".txt.tmpl", data); err != nil {
+		return "", fmt.Errorf("failed to render text email: %w", err)
+	}
+
+	messageID := fmt.Sprintf("<%s@%s>", uuid.NewString(), s.domain())
+	message, err := s.buildMessage(to, subject, messageID, unsubscribeURL, textBody.Bytes(), htmlBody.Bytes())
+	if err != nil {
+		return "", err
+	}
+
+	var auth smtp.Auth
+	if s.config.Username != "" {
+		auth = smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+	}
+
+	// The envelope sender receives bounces
+	envelopeFrom := s.config.From
+	if s.config.BounceAddress != "" {
+		envelopeFrom = s.config.BounceAddress
+	}
+
+	addr := net.JoinHostPort(s.config.Host, s.config.Port)
+	if err := s.sendMail(addr, auth, envelopeFrom, []string{to.Email}, message); err != nil {
+		return "", fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return messageID, nil
+}
+
+// buildMessage assembles a multipart/alternative MIME message
+func (s *EmailService) buildMessage(to EmailRecipient, subject, messageID, unsubscribeURL string, text, html []byte) ([]byte, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", part.contentType)
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+
+		w, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create email part: %w", err)
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write(part.content); err != nil {
+			return nil, fmt.Errorf("failed to write email part: %w", err)
+		}
+		if err := qp.Close(); err != nil {
+			return nil, fmt.Errorf("failed to write email part: %w", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish email body: %w", err)
+	}
+
+	from := mail.Address{Name: s.config.FromName, Address: s.config.From}
+	recipient := mail.Address{Name: to.Name, Address: to.Email}
+
+	var message bytes.Buffer
+	headers := [][2]string{
+		{"From", from.String()},
+		{"To", recipient.String()},
+		{"Subject", mime.QEncoding.Encode("utf-8", subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", messageID},
+		{"MIME-Version", "1.0"},
+		{"List-Unsubscribe", "<" + unsubscribeURL + ">"},
+		{"List-Unsubscribe-Post", "List-Unsubscribe=One-Click"},
+		{"Content-Type", "multipart/alternative; boundary=" + writer.Boundary()},
+	}
+	for _, header := range headers {
+		fmt.Fprintf(&message, "%s: %s\r\n", header[0], header[1])
+	}
+	message.WriteString("\r\n")
+	message.Write(body.Bytes())
+
+	return message.Bytes(), nil
+}
+
+// domain returns the sender domain used for message IDs
+func (s *EmailService) domain() string {
+	if _, domain, ok := strings.Cut(s.config.From, "@"); ok {
+		return domain
+	}
+	return "house-helper.local"
+}
+
+// unsubscribeToken signs the address and category
+func (s *EmailService) unsubscribeToken(email, category string) string {
+	payload := strings.ToLower(email) + "\n" + category
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + base64.RawURLEncoding.EncodeToString(s.sign(payload))
+}
+
+// verifyUnsubscribeToken returns the address and category of a valid token
+func (s *EmailService) verifyUnsubscribeToken(token string) (string, string, error) {
+	encodedPayload, encodedSignature, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	if !hmac.Equal(signature, s.sign(string(payload))) {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+
+	email, category, ok := strings.Cut(string(payload), "\n")
+	if !ok {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+
+	return email, category, nil
+}
+
+func (s *EmailService) bounceMAC(seconds int64, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(s.config.BounceSecret))
+	mac.Write([]byte(strconv.FormatInt(seconds, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+func (s *EmailService) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, []byte(s.config.SigningSecret))
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,310 @@
+package notifications
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime/quotedprintable"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type sentMail struct {
+	addr    string
+	from    string
+	to      []string
+	message string
+}
+
+func newTestEmailService(t *testing.T) (*EmailService, *[]sentMail) {
+	t.Helper()
+
+	service, err := NewEmailService(EmailConfig{
+		Host:          "localhost",
+		Port:          "1025",
+		From:          "notifications@house-helper.local",
+		FromName:      "House Helper",
+		BounceAddress: "bounces@house-helper.local",
+		BaseURL:       "http://notifier.test",
+		SigningSecret: "secret",
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to create email service: %v", err)
+	}
+
+	var sent []sentMail
+	service.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		sent = append(sent, sentMail{addr: addr, from: from, to: to, message: string(msg)})
+		return nil
+	}
+
+	return service, &sent
+}
+
+func tokenFromMessage(t *testing.T, message string) string {
+	t.Helper()
+
+	for _, line := range strings.Split(message, "\r\n") {
+		if strings.HasPrefix(line, "List-Unsubscribe: <") {
+			link, err := url.Parse(strings.TrimSuffix(strings.TrimPrefix(line, "List-Unsubscribe: <"), ">"))
+			if err != nil {
+				t.Fatalf("invalid unsubscribe link: %v", err)
+			}
+			return link.Query().Get("token")
+		}
+	}
+	t.Fatalf("no List-Unsubscribe header in message")
+	return ""
+}
+
+func TestSendRendersMultipartMessage(t *testing.T) {
+	service, sent := newTestEmailService(t)
+
+	_, err := service.Send(context.Background(), EmailMessage{
+		To:    EmailRecipient{Email: "alice@example.com", Name: "Alice"},
+		Title: "Dishes are overdue",
+		Body:  "Please take care of the dishes",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(*sent) != 1 {
+		t.Fatalf("expected one email, got %d", len(*sent))
+	}
+	mail := (*sent)[0]
+	if mail.addr != "localhost:1025" || mail.from != "bounces@house-helper.local" {
+		t.Errorf("unexpected envelope %s from %s", mail.addr, mail.from)
+	}
+	for _, want := range []string{
+		"Subject: Dishes are overdue",
+		"multipart/alternative",
+		"text/plain; charset=utf-8",
+		"text/html; charset=utf-8",
+		"List-Unsubscribe-Post: List-Unsubscribe=One-Click",
+		"Hi Alice",
+	} {
+		if !strings.Contains(mail.message, want) {
+			t.Errorf("expected %q in message", want)
+		}
+	}
+}
+
+func TestUnsubscribeSuppressesFurtherEmails(t *testing.T) {
+	service, sent := newTestEmailService(t)
+	ctx := context.Background()
+	msg := EmailMessage{To: EmailRecipient{Email: "alice@example.com"}, Title: "Hello"}
+
+	if _, err := service.Send(ctx, msg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	token := tokenFromMessage(t, (*sent)[0].message)
+	if _, _, err := service.Unsubscribe(ctx, token+"x"); !errors.Is(err, ErrInvalidUnsubscribeToken) {
+		t.Errorf("expected tampered token to be rejected, got %v", err)
+	}
+
+	email, category, err := service.Unsubscribe(ctx, token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if email != "alice@example.com" || category != EmailCategoryAll {
+		t.Errorf("unexpected unsubscribe %s %s", email, category)
+	}
+
+	if _, err := service.Send(ctx, msg); !errors.Is(err, ErrEmailSuppressed) {
+		t.Errorf("expected suppressed error, got %v", err)
+	}
+}
+
+func TestBouncesSuppressAddress(t *testing.T) {
+	service, _ := newTestEmailService(t)
+	ctx := context.Background()
+	msg := EmailMessage{To: EmailRecipient{Email: "bob@example.com"}, Title: "Hello"}
+
+	for i := 0; i < maxSoftBounces-1; i++ {
+		if err := service.HandleBounce(ctx, Bounce{Email: "bob@example.com", Type: BounceSoft}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := service.Send(ctx, msg); err != nil {
+		t.Errorf("expected email to be sent before the soft bounce limit, got %v", err)
+	}
+
+	service.HandleBounce(ctx, Bounce{Email: "bob@example.com", Type: BounceSoft})
+	if _, err := service.Send(ctx, msg); !errors.Is(err, ErrEmailSuppressed) {
+		t.Errorf("expected suppression after repeated soft bounces, got %v", err)
+	}
+
+	service.HandleBounce(ctx, Bounce{Email: "carol@example.com", Type: BounceHard})
+	msg.To.Email = "CAROL@example.com"
+	if _, err := service.Send(ctx, msg); !errors.Is(err, ErrEmailSuppressed) {
+		t.Errorf("expected suppression after hard bounce, got %v", err)
+	}
+}
+
+func TestDigestGroupsItemsPerHousehold(t *testing.T) {
+	service, sent := newTestEmailService(t)
+	queue := NewDigestQueue(service, 7, nil)
+	alice := EmailRecipient{Email: "alice@example.com", Name: "Alice"}
+
+	queue.Add(context.Background(), alice, DigestDaily, DigestItem{HouseholdID: "h1", HouseholdName: "Home", Category: DigestShoppingChange, Title: "Milk added"})
+	queue.Add(context.Background(), alice, DigestDaily, DigestItem{HouseholdID: "h1", HouseholdName: "Home", Category: DigestOverdueTask, Title: "Dishes"})
+	queue.Add(context.Background(), alice, DigestDaily, DigestItem{HouseholdID: "h2", HouseholdName: "Cabin", Category: DigestUpcomingBill, Title: "Electricity", Detail: "due Friday"})
+	queue.Add(context.Background(), EmailRecipient{Email: "bob@example.com"}, DigestWeekly, DigestItem{HouseholdID: "h1", Category: DigestOverdueTask, Title: "Trash"})
+
+	if err := queue.Add(context.Background(), alice, DigestDaily, DigestItem{Category: "timer_finished"}); err == nil {
+		t.Errorf("expected unsupported category to be rejected")
+	}
+
+	if sentCount := queue.Flush(context.Background(), DigestDaily); sentCount != 1 {
+		t.Fatalf("expected one daily digest, got %d", sentCount)
+	}
+
+	decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader((*sent)[0].message)))
+	if err != nil {
+		t.Fatalf("failed to decode digest: %v", err)
+	}
+	message := string(decoded)
+	home := strings.Index(message, "== Home ==")
+	overdue := strings.Index(message, "Overdue tasks")
+	shopping := strings.Index(message, "Shopping list changes")
+	cabin := strings.Index(message, "== Cabin ==")
+	if home < 0 || overdue < home || shopping < overdue || cabin < shopping {
+		t.Errorf("unexpected digest layout:\n%s", message)
+	}
+	if strings.Contains(message, "Trash") {
+		t.Errorf("expected weekly items to stay queued")
+	}
+
+	if sentCount := queue.Flush(context.Background(), DigestDaily); sentCount != 0 {
+		t.Errorf("expected daily queue to be empty, got %d", sentCount)
+	}
+}
+
+func TestFailedDigestIsQueuedAgain(t *testing.T) {
+	service, sent := newTestEmailService(t)
+	queue := NewDigestQueue(service, 7, nil)
+	alice := EmailRecipient{Email: "alice@example.com", Name: "Alice"}
+	send := service.sendMail
+
+	queue.Add(context.Background(), alice, DigestDaily, DigestItem{HouseholdID: "h1", HouseholdName: "Home", Category: DigestOverdueTask, Title: "Dishes"})
+
+	service.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		return errors.New("421 service not available")
+	}
+	if sentCount := queue.Flush(context.Background(), DigestDaily); sentCount != 0 {
+		t.Fatalf("expected the digest to fail, got %d sent", sentCount)
+	}
+
+	// Items queued after the failure go out with the failed ones
+	queue.Add(context.Background(), alice, DigestDaily, DigestItem{HouseholdID: "h1", HouseholdName: "Home", Category: DigestOverdueTask, Title: "Laundry"})
+
+	service.sendMail = send
+	if sentCount := queue.Flush(context.Background(), DigestDaily); sentCount != 1 {
+		t.Fatalf("expected the digest to be retried, got %d sent", sentCount)
+	}
+
+	decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader((*sent)[0].message)))
+	if err != nil {
+		t.Fatalf("failed to decode digest: %v", err)
+	}
+	if message := string(decoded); !strings.Contains(message, "Dishes") || !strings.Contains(message, "Laundry") {
+		t.Errorf("expected both items in the digest:\n%s", message)
+	}
+}
+
+func TestBounceReportsMustBeSigned(t *testing.T) {
+	service, _ := newTestEmailService(t)
+	service.config.BounceSecret = "bounce-secret"
+	now := time.Unix(1_800_000_000, 0)
+	body := []byte(`{"email":"bob@example.com","type":"hard"}`)
+
+	sign := func(secret string, at time.Time, body []byte) (string, string) {
+		timestamp := strconv.FormatInt(at.Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(timestamp + "."))
+		mac.Write(body)
+		return timestamp, "v1=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	timestamp, signature := sign("bounce-secret", now, body)
+	if err := service.VerifyBounce(timestamp, signature, body, now); err != nil {
+		t.Errorf("expected signed bounce to be accepted, got %v", err)
+	}
+
+	for name, verify := range map[string]func() error{
+		"unsigned": func() error { return service.VerifyBounce("", "", body, now) },
+		"wrong secret": func() error {
+			timestamp, signature := sign("guess", now, body)
+			return service.VerifyBounce(timestamp, signature, body, now)
+		},
+		"altered body": func() error {
+			return service.VerifyBounce(timestamp, signature, []byte(`{"email":"carol@example.com","type":"hard"}`), now)
+		},
+		"replayed": func() error {
+			return service.VerifyBounce(timestamp, signature, body, now.Add(time.Hour))
+		},
+	} {
+		if err := verify(); !errors.Is(err, ErrInvalidBounceSignature) {
+			t.Errorf("%s: expected ErrInvalidBounceSignature, got %v", name, err)
+		}
+	}
+
+	service.config.BounceSecret = ""
+	if err := service.VerifyBounce(timestamp, signature, body, now); !errors.Is(err, ErrInvalidBounceSignature) {
+		t.Errorf("expected bounces to be rejected without a secret, got %v", err)
+	}
+}
+
+func TestReplicasSendEachDigestOnce(t *testing.T) {
+	service, sent := newTestEmailService(t)
+	store := NewMemoryDigestStore()
+	replicas := []*DigestQueue{
+		NewDigestQueueWithStore(service, store, 7, nil),
+		NewDigestQueueWithStore(service, store, 7, nil),
+	}
+	ctx := context.Background()
+	alice := EmailRecipient{Email: "alice@example.com", Name: "Alice"}
+	morning := time.Date(2026, 3, 3, 7, 0, 0, 0, time.UTC)
+	send := service.sendMail
+
+	// Items added through either replica end up in one digest
+	replicas[0].Add(ctx, alice, DigestDaily, DigestItem{HouseholdID: "h1", Category: DigestOverdueTask, Title: "Dishes"})
+	replicas[1].Add(ctx, alice, DigestDaily, DigestItem{HouseholdID: "h1", Category: DigestOverdueTask, Title: "Laundry"})
+
+	// A failed flush is released so the next tick retries it
+	service.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		return errors.New("421 service not available")
+	}
+	replicas[0].tick(ctx, morning)
+	service.sendMail = send
+
+	replicas[1].tick(ctx, morning.Add(time.Minute))
+	replicas[0].Add(ctx, alice, DigestDaily, DigestItem{HouseholdID: "h1", Category: DigestOverdueTask, Title: "Trash"})
+	replicas[0].tick(ctx, morning.Add(2*time.Minute))
+
+	if len(*sent) != 1 {
+		t.Fatalf("expected one digest, got %d", len(*sent))
+	}
+	decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader((*sent)[0].message)))
+	if err != nil {
+		t.Fatalf("failed to decode digest: %v", err)
+	}
+	if message := string(decoded); !strings.Contains(message, "Dishes") || !strings.Contains(message, "Laundry") {
+		t.Errorf("expected both items in the digest:\n%s", message)
+	}
+
+	// The item queued after today's flush waits for tomorrow
+	replicas[1].tick(ctx, morning.Add(24*time.Hour))
+	if len(*sent) != 2 {
+		t.Errorf("expected the next digest the following morning, got %d digests", len(*sent))
+	}
+}
@@ -31,6 +31,22 @@ func NewFCMService(credentialsPath string) (*FCMService, error) {
 	return &FCMService{client: client}, nil
 }
 
+// NewFCMServiceWithOptions creates a new FCM service for a project using
+// explicit client options, e.g. an HTTP client that reaches a fake server
+func NewFCMServiceWithOptions(ctx context.Context, projectID string, opts ...option.ClientOption) (*FCMService, error) {
+	app, err := firebase.NewApp(ctx, &firebase.Config{ProjectID: projectID}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Firebase app: %w", err)
+	}
+
+	client, err := app.Messaging(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messaging client: %w", err)
+	}
+
+	return &FCMService{client: client}, nil
+}
+
 // NotificationPayload represents a push notification
 type NotificationPayload struct {
 	Title       string            `json:"title"`
@@ -0,0 +1,145 @@
+package notificationstest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/househelper/notifier/pkg/notifications"
+	"github.com/sideshow/apns2"
+)
+
+// APNSNotification is a push accepted by the fake APNs server
+type APNSNotification struct {
+	DeviceToken string
+	Topic       string
+	PushType    string
+	Priority    int
+	CollapseID  string
+	Payload     map[string]interface{}
+}
+
+// Alert returns the alert title and body of the notification
+func (n APNSNotification) Alert() (title, body string) {
+	aps, _ := n.Payload["aps"].(map[string]interface{})
+	alert, _ := aps["alert"].(map[string]interface{})
+	title, _ = alert["title"].(string)
+	body, _ = alert["body"].(string)
+	return title, body
+}
+
+// APNSServer mimics the APNs provider API over HTTP/2
+type APNSServer struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	nextID        int
+	notifications []APNSNotification
+	unregistered  map[string]bool
+	throttled     int
+}
+
+// NewAPNSServer starts a fake APNs server over TLS with HTTP/2; call Close when done
+func NewAPNSServer() *APNSServer {
+	s := &APNSServer{unregistered: make(map[string]bool)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /3/device/{token}", s.handlePush)
+
+	s.Server = httptest.NewUnstartedServer(mux)
+	s.Server.EnableHTTP2 = true
+	s.Server.StartTLS()
+	return s
+}
+
+// Service returns an APNSService that talks to the fake server
+func (s *APNSServer) Service() *notifications.APNSService {
+	return notifications.NewAPNSServiceWithClient(&apns2.Client{
+		Host:       s.URL,
+		HTTPClient: s.Client(),
+	})
+}
+
+// Unregister makes the server answer 410 Unregistered for the token
+func (s *APNSServer) Unregister(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unregistered[token] = true
+}
+
+// Throttle makes the next n pushes fail with 429 TooManyRequests
+func (s *APNSServer) Throttle(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.throttled = n
+}
+
+// Notifications returns the pushes accepted so far
+func (s *APNSServer) Notifications() []APNSNotification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]APNSNotification(nil), s.notifications...)
+}
+
+func (s *APNSServer) handlePush(w http.ResponseWriter, r *http.Request) {
+	if r.ProtoMajor != 2 {
+		writeAPNSError(w, http.StatusMethodNotAllowed, "BadMethod")
+		return
+	}
+
+	token := r.PathValue("token")
+	topic := r.Header.Get("apns-topic")
+	if topic == "" {
+		writeAPNSError(w, http.StatusBadRequest, "MissingTopic")
+		return
+	}
+
+	var payload map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeAPNSError(w, http.StatusBadRequest, "PayloadEmpty")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.throttled > 0 {
+		s.throttled--
+		writeAPNSError(w, http.StatusTooManyRequests, apns2.ReasonTooManyRequests)
+		return
+	}
+	if s.unregistered[token] {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusGone)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"reason":    apns2.ReasonUnregistered,
+			"timestamp": time.Now().UnixMilli(),
+		})
+		return
+	}
+
+	priority, _ := strconv.Atoi(r.Header.Get("apns-priority"))
+	s.notifications = append(s.notifications, APNSNotification{
+		DeviceToken: token,
+		Topic:       topic,
+		PushType:    r.Header.Get("apns-push-type"),
+		Priority:    priority,
+		CollapseID:  r.Header.Get("apns-collapse-id"),
+		Payload:     payload,
+	})
+	s.nextID++
+
+	w.Header().Set("apns-id", fmt.Sprintf("00000000-0000-0000-0000-%012d", s.nextID))
+	w.WriteHeader(http.StatusOK)
+}
+
+// writeAPNSError writes an error in the format of the APNs provider API
+func writeAPNSError(w http.ResponseWriter, status int, reason string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"reason": reason})
+}
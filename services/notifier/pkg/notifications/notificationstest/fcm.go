@@ -0,0 +1,234 @@
+// Package notificationstest provides in-process fakes of the FCM v1 and APNs
+// HTTP/2 APIs so push code can be tested without network access.
+package notificationstest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/househelper/notifier/pkg/notifications"
+	"google.golang.org/api/option"
+)
+
+// ProjectID is the Firebase project the fake FCM server serves
+const ProjectID = "house-helper-test"
+
+// FCMMessage is a message accepted by the fake FCM server
+type FCMMessage struct {
+	Token    string
+	Topic    string
+	Title    string
+	Body     string
+	ImageURL string
+	Data     map[string]string
+}
+
+// FCMServer mimics the FCM v1 send endpoint and the Instance ID topic
+// management endpoints
+type FCMServer struct {
+	*httptest.Server
+
+	mu           sync.Mutex
+	nextID       int
+	messages     []FCMMessage
+	topics       map[string]map[string]bool
+	unregistered map[string]bool
+	throttled    int
+	retryAfter   time.Duration
+}
+
+// NewFCMServer starts a fake FCM server; call Close when done
+func NewFCMServer() *FCMServer {
+	s := &FCMServer{
+		topics:       make(map[string]map[string]bool),
+		unregistered: make(map[string]bool),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/projects/{project}/messages:send", s.handleSend)
+	mux.HandleFunc("POST /iid/v1:batchAdd", s.handleTopics(true))
+	mux.HandleFunc("POST /iid/v1:batchRemove", s.handleTopics(false))
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Service returns an FCMService that talks to the fake server
+func (s *FCMServer) Service(ctx context.Context) (*notifications.FCMService, error) {
+	return notifications.NewFCMServiceWithOptions(ctx, ProjectID, s.ClientOptions()...)
+}
+
+// ClientOptions route every Firebase request to the fake server
+func (s *FCMServer) ClientOptions() []option.ClientOption {
+	target, _ := url.Parse(s.URL)
+	client := &http.Client{Transport: &rewriteTransport{target: target, base: s.Client().Transport}}
+	return []option.ClientOption{option.WithHTTPClient(client)}
+}
+
+// Unregister makes the server answer UNREGISTERED for the token, as FCM
+// does after an app is uninstalled
+func (s *FCMServer) Unregister(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unregistered[token] = true
+}
+
+// Throttle makes the next n sends fail with 429 QUOTA_EXCEEDED and the given Retry-After
+func (s *FCMServer) Throttle(n int, retryAfter time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.throttled = n
+	s.retryAfter = retryAfter
+}
+
+// Messages returns the messages accepted so far
+func (s *FCMServer) Messages() []FCMMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]FCMMessage(nil), s.messages...)
+}
+
+// Subscribers returns the tokens subscribed to a topic
+func (s *FCMServer) Subscribers(topic string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tokens []string
+	for token, subscribed := range s.topics[topic] {
+		if subscribed {
+			tokens = append(tokens, token)
+		}
+	}
+	sort.Strings(tokens)
+	return tokens
+}
+
+func (s *FCMServer) handleSend(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Message struct {
+			Token        string `json:"token"`
+			Topic        string `json:"topic"`
+			Notification struct {
+				Title string `json:"title"`
+				Body  string `json:"body"`
+				Image string `json:"image"`
+			} `json:"notification"`
+			Data map[string]string `json:"data"`
+		} `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeFCMError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "INVALID_ARGUMENT", err.Error())
+		return
+	}
+	message := req.Message
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.PathValue("project") != ProjectID {
+		writeFCMError(w, http.StatusForbidden, "PERMISSION_DENIED", "SENDER_ID_MISMATCH", "unknown project")
+		return
+	}
+	if s.throttled > 0 {
+		s.throttled--
+		w.Header().Set("Retry-After", strconv.Itoa(int(s.retryAfter.Seconds())))
+		writeFCMError(w, http.StatusTooManyRequests, "RESOURCE_EXHAUSTED", "QUOTA_EXCEEDED", "quota exceeded")
+		return
+	}
+	if message.Token == "" && message.Topic == "" {
+		writeFCMError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "INVALID_ARGUMENT", "token or topic is required")
+		return
+	}
+	if s.unregistered[message.Token] {
+		writeFCMError(w, http.StatusNotFound, "NOT_FOUND", "UNREGISTERED", "Requested entity was not found.")
+		return
+	}
+
+	s.messages = append(s.messages, FCMMessage{
+		Token:    message.Token,
+		Topic:    message.Topic,
+		Title:    message.Notification.Title,
+		Body:     message.Notification.Body,
+		ImageURL: message.Notification.Image,
+		Data:     message.Data,
+	})
+	s.nextID++
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"name": fmt.Sprintf("projects/%s/messages/%d", ProjectID, s.nextID),
+	})
+}
+
+func (s *FCMServer) handleTopics(subscribe bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			To     string   `json:"to"`
+			Tokens []string `json:"registration_tokens"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "INVALID_ARGUMENT"})
+			return
+		}
+		topic := strings.TrimPrefix(req.To, "/topics/")
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.topics[topic] == nil {
+			s.topics[topic] = make(map[string]bool)
+		}
+		results := make([]map[string]string, len(req.Tokens))
+		for i, token := range req.Tokens {
+			results[i] = map[string]string{}
+			if s.unregistered[token] {
+				results[i]["error"] = "NOT_FOUND"
+				continue
+			}
+			s.topics[topic][token] = subscribe
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"results": results})
+	}
+}
+
+// writeFCMError writes an error in the format of the FCM v1 API
+func writeFCMError(w http.ResponseWriter, status int, code, errorCode, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    status,
+			"message": message,
+			"status":  code,
+			"details": []map[string]string{{
+				"@type":     "type.googleapis.com/google.firebase.fcm.v1.FcmError",
+				"errorCode": errorCode,
+			}},
+		},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// rewriteTransport sends requests for Google hosts to the fake server
+type rewriteTransport struct {
+	target *url.URL
+	base   http.RoundTripper
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	req.Host = t.target.Host
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,31 @@
+package notifications
+
+import (
+	"context"
+
+	"firebase.google.com/go/v4/messaging"
+	"github.com/sideshow/apns2"
+)
+
+// FCMProvider sends through Firebase Cloud Messaging. FCMService is the
+// production implementation; tests point it at a fake server.
+type FCMProvider interface {
+	SendToToken(ctx context.Context, token string, payload NotificationPayload) (string, error)
+	SendToTokens(ctx context.Context, tokens []string, payload NotificationPayload) (*messaging.BatchResponse, error)
+	SendToTopic(ctx context.Context, topic string, payload NotificationPayload) (string, error)
+	SubscribeToTopic(ctx context.Context, tokens []string, topic string) (*messaging.TopicManagementResponse, error)
+	UnsubscribeFromTopic(ctx context.Context, tokens []string, topic string) (*messaging.TopicManagementResponse, error)
+}
+
+// APNSProvider sends through the Apple Push Notification service.
+// APNSService is the production implementation.
+type APNSProvider interface {
+	SendNotification(ctx context.Context, bundleID, deviceToken string, apnsPayload APNSPayload) (*apns2.Response, error)
+	SendSilentNotification(ctx context.Context, bundleID, deviceToken string, customData map[string]string) (*apns2.Response, error)
+	SendLiveActivity(ctx context.Context, bundleID, pushToken string, activityPayload LiveActivityPayload) (*apns2.Response, error)
+}
+
+var (
+	_ FCMProvider  = (*FCMService)(nil)
+	_ APNSProvider = (*APNSService)(nil)
+)
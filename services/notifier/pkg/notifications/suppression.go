@@ -0,0 +1,153 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Soft bounces before an address is suppressed
+const maxSoftBounces = 3
+
+// Soft bounce counters are reset after this period without bounces
+const softBounceWindow = 30 * 24 * time.Hour
+
+// BounceType classifies a delivery failure reported by the mail server
+type BounceType string
+
+const (
+	BounceHard      BounceType = "hard"
+	BounceSoft      BounceType = "soft"
+	BounceComplaint BounceType = "complaint"
+)
+
+// Bounce is a delivery failure notification
+type Bounce struct {
+	Email  string     `json:"email"`
+	Type   BounceType `json:"type"`
+	Reason string     `json:"reason,omitempty"`
+}
+
+// SuppressionList records addresses that must not receive email, either
+// for every category or for a single one such as digests
+type SuppressionList interface {
+	Suppress(ctx context.Context, email, category, reason string) error
+	IsSuppressed(ctx context.Context, email, category string) (bool, error)
+	RecordSoftBounce(ctx context.Context, email string) (int, error)
+}
+
+// MemorySuppressionList is a process-local SuppressionList
+type MemorySuppressionList struct {
+	mu          sync.Mutex
+	suppressed  map[string]string
+	softBounces map[string]int
+}
+
+// NewMemorySuppressionList creates a new in-memory suppression list
+func NewMemorySuppressionList() *MemorySuppressionList {
+	return &MemorySuppressionList{
+		suppressed:  make(map[string]string),
+		softBounces: make(map[string]int),
+	}
+}
+
+// Suppress stops emails of the category to the address
+func (l *MemorySuppressionList) Suppress(ctx context.Context, email, category, reason string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.suppressed[suppressionKey(email, category)] = reason
+	return nil
+}
+
+// IsSuppressed reports whether the address opted out of the category or all email
+func (l *MemorySuppressionList) IsSuppressed(ctx context.Context, email, category string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.suppressed[suppressionKey(email, EmailCategoryAll)]; ok {
+		return true, nil
+	}
+	_, ok := l.suppressed[suppressionKey(email, category)]
+	return ok, nil
+}
+
+// RecordSoftBounce increments and returns the soft bounce count of the address
+func (l *MemorySuppressionList) RecordSoftBounce(ctx context.Context, email string) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := strings.ToLower(email)
+	l.softBounces[key]++
+	return l.softBounces[key], nil
+}
+
+// RedisSuppressionList stores suppressions in Redis so all replicas share them
+type RedisSuppressionList struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisSuppressionList connects to Redis
+func NewRedisSuppressionList(ctx context.Context, redisURL string) (*RedisSuppressionList, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse redis url: %w", err)
+	}
+
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return &RedisSuppressionList{client: client, prefix: "house-helper:notifier:email"}, nil
+}
+
+// Suppress stops emails of the category to the address
+func (l *RedisSuppressionList) Suppress(ctx context.Context, email, category, reason string) error {
+	if err := l.client.HSet(ctx, l.prefix+":suppressed", suppressionKey(email, category), reason).Err(); err != nil {
+		return fmt.Errorf("failed to suppress %s: %w", email, err)
+	}
+	return nil
+}
+
+// IsSuppressed reports whether the address opted out of the category or all email
+func (l *RedisSuppressionList) IsSuppressed(ctx context.Context, email, category string) (bool, error) {
+	fields := []string{suppressionKey(email, EmailCategoryAll), suppressionKey(email, category)}
+	values, err := l.client.HMGet(ctx, l.prefix+":suppressed", fields...).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check suppression of %s: %w", email, err)
+	}
+
+	for _, value := range values {
+		if value != nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RecordSoftBounce increments and returns the soft bounce count of the address
+func (l *RedisSuppressionList) RecordSoftBounce(ctx context.Context, email string) (int, error) {
+	key := l.prefix + ":soft-bounces:" + strings.ToLower(email)
+
+	count, err := l.client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to record soft bounce for %s: %w", email, err)
+	}
+	l.client.Expire(ctx, key, softBounceWindow)
+
+	return int(count), nil
+}
+
+// Close closes the Redis connection
+func (l *RedisSuppressionList) Close() error {
+	return l.client.Close()
+}
+
+func suppressionKey(email, category string) string {
+	return strings.ToLower(email) + "|" + category
+}
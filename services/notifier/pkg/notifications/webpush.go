@@ -0,0 +1,215 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+	"github.com/househelper/notifier/pkg/devices"
+	"github.com/redis/go-redis/v9"
+)
+
+// Default time a push service keeps an undelivered message
+const defaultWebPushTTL = 24 * time.Hour
+
+// ErrSubscriptionExpired is returned when the push service reports that the
+// subscription no longer exists (404 or 410)
+var ErrSubscriptionExpired = errors.New("web push subscription expired")
+
+// Web Push urgencies (RFC 8030 section 5.3)
+const (
+	UrgencyVeryLow = "very-low"
+	UrgencyLow     = "low"
+	UrgencyNormal  = "normal"
+	UrgencyHigh    = "high"
+)
+
+// VAPIDKeys is the application server key pair (RFC 8292)
+type VAPIDKeys struct {
+	PublicKey  string `json:"publicKey"`
+	PrivateKey string `json:"privateKey"`
+}
+
+// vapidKeysKey is the Redis key of the key pair shared by all replicas
+const vapidKeysKey = "house-helper:notifier:webpush:vapid-keys"
+
+// LoadVAPIDKeys returns the configured key pair. Without configured keys,
+// the pair stored at path is used, or a new pair is generated and stored
+// there. Only a single replica can use keys from a local path; replicas
+// must be configured with keys or share them with LoadSharedVAPIDKeys.
+func LoadVAPIDKeys(publicKey, privateKey, path string) (VAPIDKeys, error) {
+	if publicKey != "" && privateKey != "" {
+		return VAPIDKeys{PublicKey: publicKey, PrivateKey: privateKey}, nil
+	}
+	if publicKey != "" || privateKey != "" {
+		return VAPIDKeys{}, errors.New("both the VAPID public and private key must be configured")
+	}
+
+	if data, err := os.ReadFile(path); err == nil {
+		var keys VAPIDKeys
+		if err := json.Unmarshal(data, &keys); err != nil {
+			return VAPIDKeys{}, fmt.Errorf("failed to parse VAPID keys from %s: %w", path, err)
+		}
+		return keys, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return VAPIDKeys{}, fmt.Errorf("failed to read VAPID keys from %s: %w", path, err)
+	}
+
+	keys, err := generateVAPIDKeys()
+	if err != nil {
+		return VAPIDKeys{}, err
+	}
+
+	data, err := json.Marshal(keys)
+	if err != nil {
+		return VAPIDKeys{}, fmt.Errorf("failed to marshal VAPID keys: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return VAPIDKeys{}, fmt.Errorf("failed to create VAPID key directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return VAPIDKeys{}, fmt.Errorf("failed to store VAPID keys: %w", err)
+	}
+
+	log.Printf("Generated new VAPID keys and stored them in %s", path)
+	return keys, nil
+}
+
+// LoadSharedVAPIDKeys returns the key pair stored in Redis. The first
+// replica to start generates it; the others load the pair it stored, so
+// subscriptions made through any replica can be pushed to by all of them.
+func LoadSharedVAPIDKeys(ctx context.Context, redisURL string) (VAPIDKeys, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return VAPIDKeys{}, fmt.Errorf("failed to parse redis url: %w", err)
+	}
+	client := redis.NewClient(opts)
+	defer client.Close()
+
+	generated, err := generateVAPIDKeys()
+	if err != nil {
+		return VAPIDKeys{}, err
+	}
+	data, err := json.Marshal(generated)
+	if err != nil {
+		return VAPIDKeys{}, fmt.Errorf("failed to marshal VAPID keys: %w", err)
+	}
+
+	stored, err := client.SetNX(ctx, vapidKeysKey, data, 0).Result()
+	if err != nil {
+		return VAPIDKeys{}, fmt.Errorf("failed to store VAPID keys: %w", err)
+	}
+	if stored {
+		log.Println("Generated new VAPID keys and stored them in Redis")
+		return generated, nil
+	}
+
+	raw, err := client.Get(ctx, vapidKeysKey).Bytes()
+	if err != nil {
+		return VAPIDKeys{}, fmt.Errorf("failed to load VAPID keys: %w", err)
+	}
+	var keys VAPIDKeys
+	if err := json.Unmarshal(raw, &keys); err != nil {
+		return VAPIDKeys{}, fmt.Errorf("failed to parse VAPID keys from Redis: %w", err)
+	}
+	return keys, nil
+}
+
+// generateVAPIDKeys creates a new key pair
+func generateVAPIDKeys() (VAPIDKeys, error) {
+	private, public, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		return VAPIDKeys{}, fmt.Errorf("failed to generate VAPID keys: %w", err)
+	}
+	return VAPIDKeys{PublicKey: public, PrivateKey: private}, nil
+}
+
+// WebPushOptions controls how the push service handles a message
+type WebPushOptions struct {
+	TTL     time.Duration
+	Urgency string
+	Topic   string
+}
+
+// WebPushService sends encrypted Web Push messages (RFC 8291) signed with VAPID
+type WebPushService struct {
+	keys       VAPIDKeys
+	subscriber string
+	httpClient *http.Client
+}
+
+// NewWebPushService creates a new Web Push service. The subscriber is a
+// contact URL or email address included in the VAPID claims.
+func NewWebPushService(keys VAPIDKeys, subscriber string) (*WebPushService, error) {
+	if keys.PublicKey == "" || keys.PrivateKey == "" {
+		return nil, errors.New("VAPID keys are required")
+	}
+
+	return &WebPushService{
+		keys:       keys,
+		subscriber: subscriber,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// PublicKey returns the VAPID public key browsers subscribe with
+func (w *WebPushService) PublicKey() string {
+	return w.keys.PublicKey
+}
+
+// Send encrypts and delivers a payload to a subscription
+func (w *WebPushService) Send(ctx context.Context, subscription devices.WebPushSubscription, payload []byte, opts WebPushOptions) error {
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = defaultWebPushTTL
+	}
+	urgency := opts.Urgency
+	if urgency == "" {
+		urgency = UrgencyNormal
+	}
+
+	sub := &webpush.Subscription{
+		Endpoint: subscription.Endpoint,
+		Keys: webpush.Keys{
+			P256dh: subscription.Keys.P256dh,
+			Auth:   subscription.Keys.Auth,
+		},
+	}
+
+	resp, err := webpush.SendNotificationWithContext(ctx, payload, sub, &webpush.Options{
+		HTTPClient:      w.httpClient,
+		Subscriber:      w.subscriber,
+		VAPIDPublicKey:  w.keys.PublicKey,
+		VAPIDPrivateKey: w.keys.PrivateKey,
+		TTL:             int(ttl.Seconds()),
+		Urgency:         webpush.Urgency(urgency),
+		Topic:           opts.Topic,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send web push: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrSubscriptionExpired
+	case resp.StatusCode >= 300:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &ProviderError{
+			Platform:   devices.PlatformWeb,
+			StatusCode: resp.StatusCode,
+			RetryAfter: ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+			Err:        fmt.Errorf("web push rejected: %s", string(body)),
+		}
+	}
+
+	return nil
+}
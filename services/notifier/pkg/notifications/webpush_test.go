@@ -0,0 +1,149 @@
+package notifications
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/househelper/notifier/pkg/devices"
+)
+
+func newTestSubscription(t *testing.T, endpoint string) devices.WebPushSubscription {
+	t.Helper()
+
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate subscription key: %v", err)
+	}
+	auth := make([]byte, 16)
+	rand.Read(auth)
+
+	var sub devices.WebPushSubscription
+	sub.Endpoint = endpoint
+	sub.Keys.P256dh = base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes())
+	sub.Keys.Auth = base64.RawURLEncoding.EncodeToString(auth)
+	return sub
+}
+
+func newTestWebPushService(t *testing.T) *WebPushService {
+	t.Helper()
+
+	keys, err := LoadVAPIDKeys("", "", filepath.Join(t.TempDir(), "vapid.json"))
+	if err != nil {
+		t.Fatalf("failed to load VAPID keys: %v", err)
+	}
+	service, err := NewWebPushService(keys, "mailto:test@example.com")
+	if err != nil {
+		t.Fatalf("failed to create web push service: %v", err)
+	}
+	return service
+}
+
+func TestLoadVAPIDKeysPersistsGeneratedKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "vapid.json")
+
+	first, err := LoadVAPIDKeys("", "", path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := LoadVAPIDKeys("", "", path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first != second {
+		t.Errorf("expected stored keys to be reused")
+	}
+
+	configured, _ := LoadVAPIDKeys("public", "private", path)
+	if configured.PublicKey != "public" {
+		t.Errorf("expected configured keys to take precedence")
+	}
+}
+
+func TestLoadVAPIDKeysRejectsHalfConfiguredKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vapid.json")
+
+	if _, err := LoadVAPIDKeys("public", "", path); err == nil {
+		t.Errorf("expected a public key without a private key to be rejected")
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected no keys to be generated, got %v", err)
+	}
+}
+
+func TestWebPushSendsEncryptedPayloadWithHeaders(t *testing.T) {
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	service := newTestWebPushService(t)
+	err := service.Send(context.Background(), newTestSubscription(t, server.URL), []byte(`{"title":"Laundry done"}`), WebPushOptions{
+		TTL:     time.Hour,
+		Urgency: UrgencyHigh,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.Header.Get("Content-Encoding") != "aes128gcm" {
+		t.Errorf("expected aes128gcm encoding, got %q", got.Header.Get("Content-Encoding"))
+	}
+	if got.Header.Get("TTL") != "3600" || got.Header.Get("Urgency") != UrgencyHigh {
+		t.Errorf("unexpected TTL/Urgency headers %q %q", got.Header.Get("TTL"), got.Header.Get("Urgency"))
+	}
+	if !strings.HasPrefix(got.Header.Get("Authorization"), "vapid t=") {
+		t.Errorf("expected VAPID authorization, got %q", got.Header.Get("Authorization"))
+	}
+}
+
+func TestDispatcherRemovesExpiredSubscriptions(t *testing.T) {
+	gone := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer gone.Close()
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ok.Close()
+
+	ctx := context.Background()
+	registry := devices.NewMemoryRegistry()
+	for _, endpoint := range []string{gone.URL, ok.URL} {
+		sub := newTestSubscription(t, endpoint)
+		if _, err := registry.Register(ctx, devices.Device{UserID: "alice", Platform: devices.PlatformWeb, WebPush: &sub}); err != nil {
+			t.Fatalf("failed to register subscription: %v", err)
+		}
+	}
+
+	dispatcher := NewDispatcher(registry)
+	dispatcher.RegisterChannel(NewWebPushChannel(newTestWebPushService(t)))
+
+	result, err := dispatcher.Dispatch(ctx, Notification{UserID: "alice", Title: "Bill due"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Delivered != 1 || result.Removed != 1 {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	remaining, _ := registry.List(ctx, "alice")
+	if len(remaining) != 1 || remaining[0].WebPush.Endpoint != ok.URL {
+		t.Errorf("expected only the live subscription to remain, got %+v", remaining)
+	}
+
+	if _, err := dispatcher.Dispatch(ctx, Notification{UserID: "bob"}); !errors.Is(err, ErrNoDevices) {
+		t.Errorf("expected ErrNoDevices, got %v", err)
+	}
+}
@@ -0,0 +1,435 @@
+package presence
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/househelper/notifier/pkg/backplane"
+)
+
+// backplaneHub identifies presence updates on the backplane
+const backplaneHub = "presence"
+
+const (
+	// Members without client activity for this long are shown as away
+	awayAfter = 5 * time.Minute
+
+	// How often local presence is re-announced and away transitions checked
+	sweepInterval = 30 * time.Second
+
+	// Client activity is recorded at most this often per member
+	activityThrottle = sweepInterval
+
+	// Entries are kept on the backplane for this long after they were last
+	// announced. Connected entries are re-announced every sweep, so older
+	// ones belong to a replica that went away, and disconnected ones to a
+	// member that left. Both are forgotten.
+	staleAfter = 3 * sweepInterval
+)
+
+// Status is the presence status of a household member
+type Status string
+
+const (
+	StatusOnline  Status = "online"
+	StatusAway    Status = "away"
+	StatusOffline Status = "offline"
+)
+
+// Activities a member can report alongside their status
+const (
+	ActivityViewingList = "viewing_list"
+	ActivityInStore     = "in_store"
+)
+
+// Presence is the aggregated presence of a member across all connections
+type Presence struct {
+	UserID      string    `json:"userId"`
+	HouseholdID string    `json:"householdId"`
+	Status      Status    `json:"status"`
+	Activity    string    `json:"activity,omitempty"`
+	ListID      string    `json:"listId,omitempty"`
+	LastSeen    time.Time `json:"lastSeen"`
+}
+
+// Context describes what a member is currently doing
+type Context struct {
+	Activity string `json:"activity,omitempty"`
+	ListID   string `json:"listId,omitempty"`
+}
+
+// Validate checks that the context describes a known activity
+func (c Context) Validate() error {
+	switch c.Activity {
+	case "", ActivityInStore:
+		return nil
+	case ActivityViewingList:
+		if c.ListID == "" {
+			return fmt.Errorf("listId is required for activity %s", c.Activity)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown activity %q", c.Activity)
+	}
+}
+
+// replicaState is the presence of a member as seen by a single replica
+type replicaState struct {
+	Replica     string    `json:"replica"`
+	UserID      string    `json:"userId"`
+	HouseholdID string    `json:"householdId"`
+	Connections int       `json:"connections"`
+	Activity    string    `json:"activity,omitempty"`
+	ListID      string    `json:"listId,omitempty"`
+	LastActive  time.Time `json:"lastActive"`
+	ReportedAt  time.Time `json:"reportedAt"`
+
+	// Expired is set when another replica forgets this replica's connected
+	// entry, so that only the first replica to notice announces it
+	Expired bool `json:"expired,omitempty"`
+}
+
+// memberKey identifies a member within a household
+type memberKey struct {
+	householdID string
+	userID      string
+}
+
+// Tracker keeps track of household member presence. Each replica tracks its
+// own connections and shares them over the backplane, so every replica can
+// serve a complete snapshot.
+type Tracker struct {
+	replicaID string
+	backplane backplane.Backplane
+
+	mu sync.Mutex
+
+	// Presence per member and replica
+	states map[memberKey]map[string]*replicaState
+
+	// Last presence announced to the household per member
+	announced map[memberKey]Presence
+
+	// Called when a member's presence changes
+	onChange func(Presence)
+
+	now func() time.Time
+}
+
+// NewTracker creates a presence tracker for this replica. The backplane is
+// optional and only needed when running several replicas.
+func NewTracker(replicaID string, bp backplane.Backplane) *Tracker {
+	t := &Tracker{
+		replicaID: replicaID,
+		backplane: bp,
+		states:    make(map[memberKey]map[string]*replicaState),
+		announced: make(map[memberKey]Presence),
+		now:       time.Now,
+	}
+
+	if bp != nil {
+		bp.Subscribe(backplaneHub, t.handleBackplaneMessage)
+	}
+
+	return t
+}
+
+// OnChange registers the callback invoked when a member's presence changes
+func (t *Tracker) OnChange(fn func(Presence)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onChange = fn
+}
+
+// ClientConnected records a new connection for the member
+func (t *Tracker) ClientConnected(userID, householdID string) {
+	t.updateLocal(userID, householdID, func(state *replicaState) {
+		state.Connections++
+	})
+}
+
+// ClientDisconnected records a closed connection for the member
+func (t *Tracker) ClientDisconnected(userID, householdID string) {
+	t.updateLocal(userID, householdID, func(state *replicaState) {
+		if state.Connections > 0 {
+			state.Connections--
+		}
+		if state.Connections == 0 {
+			state.Activity = ""
+			state.ListID = ""
+		}
+	})
+}
+
+// ClientActive records activity from the member, bringing them back from away
+func (t *Tracker) ClientActive(userID, householdID string) {
+	key := memberKey{householdID: householdID, userID: userID}
+
+	t.mu.Lock()
+	state := t.states[key][t.replicaID]
+	recent := state != nil && t.now().Sub(state.LastActive) < activityThrottle
+	t.mu.Unlock()
+
+	if recent {
+		return
+	}
+	t.updateLocal(userID, householdID, func(state *replicaState) {})
+}
+
+// SetContext records what the member is currently doing
+func (t *Tracker) SetContext(userID, householdID string, ctx Context) {
+	t.updateLocal(userID, householdID, func(state *replicaState) {
+		state.Activity = ctx.Activity
+		state.ListID = ctx.ListID
+	})
+}
+
+// Snapshot returns the presence of every known member of the household
+func (t *Tracker) Snapshot(householdID string) []Presence {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	members := make([]Presence, 0)
+	for key := range t.states {
+		if key.householdID == householdID {
+			members = append(members, t.aggregate(key, now))
+		}
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].UserID < members[j].UserID
+	})
+
+	return members
+}
+
+// Run periodically re-announces local presence, detects members going away
+// and forgets entries that were not re-announced within staleAfter
+func (t *Tracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.sweep()
+		}
+	}
+}
+
+// sweep is a single iteration of Run
+func (t *Tracker) sweep() {
+	t.mu.Lock()
+	now := t.now()
+
+	var reports []replicaState
+	var changes []Presence
+	for key, replicas := range t.states {
+		expired := false
+		for replica, state := range replicas {
+			if replica == t.replicaID && state.Connections > 0 {
+				state.ReportedAt = now
+				reports = append(reports, *state)
+				continue
+			}
+			if now.Sub(state.ReportedAt) <= staleAfter {
+				continue
+			}
+			delete(replicas, replica)
+			if replica != t.replicaID && state.Connections > 0 {
+				tombstone := *state
+				tombstone.Connections = 0
+				tombstone.Expired = true
+				reports = append(reports, tombstone)
+				expired = true
+			}
+		}
+
+		if len(replicas) == 0 {
+			delete(t.states, key)
+		}
+
+		// Members tracked by this replica are announced for going away, and
+		// any member for an entry that expired, as its replica cannot
+		if _, ok := replicas[t.replicaID]; ok || expired {
+			if presence, changed := t.checkChange(key, now); changed {
+				changes = append(changes, presence)
+			}
+		}
+		if len(replicas) == 0 {
+			delete(t.announced, key)
+		}
+	}
+	onChange := t.onChange
+	t.mu.Unlock()
+
+	for _, state := range reports {
+		t.publish(state)
+	}
+	t.notify(onChange, changes)
+}
+
+// updateLocal applies a change to this replica's view of the member and
+// announces it to other replicas and to the household
+func (t *Tracker) updateLocal(userID, householdID string, update func(state *replicaState)) {
+	if userID == "" || householdID == "" {
+		return
+	}
+
+	key := memberKey{householdID: householdID, userID: userID}
+
+	t.mu.Lock()
+	now := t.now()
+	replicas, ok := t.states[key]
+	if !ok {
+		replicas = make(map[string]*replicaState)
+		t.states[key] = replicas
+	}
+	state, ok := replicas[t.replicaID]
+	if !ok {
+		state = &replicaState{Replica: t.replicaID, UserID: userID, HouseholdID: householdID}
+		replicas[t.replicaID] = state
+	}
+
+	update(state)
+	state.LastActive = now
+	state.ReportedAt = now
+	report := *state
+
+	var changes []Presence
+	if presence, changed := t.checkChange(key, now); changed {
+		changes = append(changes, presence)
+	}
+	onChange := t.onChange
+	t.mu.Unlock()
+
+	t.publish(report)
+	t.notify(onChange, changes)
+}
+
+// handleBackplaneMessage applies presence reported by another replica
+func (t *Tracker) handleBackplaneMessage(msg backplane.Message) {
+	var state replicaState
+	if err := json.Unmarshal(msg.Payload, &state); err != nil {
+		log.Printf("Failed to decode presence update: %v", err)
+		return
+	}
+	if state.Replica == t.replicaID {
+		return
+	}
+
+	key := memberKey{householdID: state.HouseholdID, userID: state.UserID}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	replicas, ok := t.states[key]
+	if state.Expired {
+		// The replica that forgot the entry announces the change
+		if _, tracked := replicas[state.Replica]; tracked {
+			delete(replicas, state.Replica)
+			t.announced[key] = t.aggregate(key, t.now())
+		}
+		return
+	}
+	if !ok {
+		replicas = make(map[string]*replicaState)
+		t.states[key] = replicas
+	}
+	state.ReportedAt = t.now()
+	replicas[state.Replica] = &state
+
+	// The reporting replica announces the change to the household
+	t.announced[key] = t.aggregate(key, t.now())
+}
+
+// publish shares this replica's view of a member with the other replicas
+func (t *Tracker) publish(state replicaState) {
+	if t.backplane == nil {
+		return
+	}
+
+	payload, err := json.Marshal(state)
+	if err != nil {
+		log.Printf("Failed to encode presence update: %v", err)
+		return
+	}
+
+	err = t.backplane.Publish(context.Background(), backplane.Message{
+		Hub:         backplaneHub,
+		UserID:      state.UserID,
+		HouseholdID: state.HouseholdID,
+		Payload:     payload,
+	})
+	if err != nil {
+		log.Printf("Failed to publish presence update: %v", err)
+	}
+}
+
+func (t *Tracker) notify(onChange func(Presence), changes []Presence) {
+	if onChange == nil {
+		return
+	}
+	for _, presence := range changes {
+		onChange(presence)
+	}
+}
+
+// checkChange compares the member's presence with the last announced one.
+// Must be called with t.mu held.
+func (t *Tracker) checkChange(key memberKey, now time.Time) (Presence, bool) {
+	current := t.aggregate(key, now)
+	previous, ok := t.announced[key]
+	if ok && previous.Status == current.Status && previous.Activity == current.Activity && previous.ListID == current.ListID {
+		return current, false
+	}
+
+	t.announced[key] = current
+	return current, true
+}
+
+// aggregate merges the member's presence across replicas. Must be called
+// with t.mu held.
+func (t *Tracker) aggregate(key memberKey, now time.Time) Presence {
+	presence := Presence{
+		UserID:      key.userID,
+		HouseholdID: key.householdID,
+		Status:      StatusOffline,
+	}
+
+	connections := 0
+	var mostRecent *replicaState
+	for _, state := range t.states[key] {
+		if state.LastActive.After(presence.LastSeen) {
+			presence.LastSeen = state.LastActive
+		}
+		if state.Connections == 0 {
+			continue
+		}
+		connections += state.Connections
+		if mostRecent == nil || state.LastActive.After(mostRecent.LastActive) {
+			mostRecent = state
+		}
+	}
+
+	if connections == 0 {
+		return presence
+	}
+
+	presence.Status = StatusOnline
+	if now.Sub(presence.LastSeen) > awayAfter {
+		presence.Status = StatusAway
+	}
+	presence.Activity = mostRecent.Activity
+	presence.ListID = mostRecent.ListID
+
+	return presence
+}
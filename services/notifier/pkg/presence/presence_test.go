@@ -0,0 +1,152 @@
+package presence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/househelper/notifier/pkg/backplane"
+)
+
+func TestPresenceIsSharedAcrossReplicas(t *testing.T) {
+	bp := backplane.NewMemoryBackplane()
+	replicaA := NewTracker("replica-a", bp)
+	replicaB := NewTracker("replica-b", bp)
+
+	var changes []Presence
+	replicaA.OnChange(func(p Presence) { changes = append(changes, p) })
+
+	replicaA.ClientConnected("alice", "household-1")
+	replicaA.SetContext("alice", "household-1", Context{Activity: ActivityInStore})
+
+	snapshot := replicaB.Snapshot("household-1")
+	if len(snapshot) != 1 {
+		t.Fatalf("expected one member, got %d", len(snapshot))
+	}
+	if snapshot[0].Status != StatusOnline || snapshot[0].Activity != ActivityInStore {
+		t.Errorf("unexpected presence on other replica %+v", snapshot[0])
+	}
+
+	// A second connection on another replica keeps alice online
+	replicaB.ClientConnected("alice", "household-1")
+	replicaA.ClientDisconnected("alice", "household-1")
+	if p := replicaA.Snapshot("household-1")[0]; p.Status != StatusOnline {
+		t.Errorf("expected alice to stay online, got %s", p.Status)
+	}
+
+	replicaB.ClientDisconnected("alice", "household-1")
+	if p := replicaA.Snapshot("household-1")[0]; p.Status != StatusOffline || p.Activity != "" {
+		t.Errorf("expected alice offline without activity, got %+v", p)
+	}
+
+	if len(changes) != 2 || changes[0].Status != StatusOnline || changes[1].Activity != ActivityInStore {
+		t.Errorf("unexpected changes announced by replica a: %+v", changes)
+	}
+}
+
+func TestMembersGoAwayWhenIdle(t *testing.T) {
+	now := time.Now()
+	tracker := NewTracker("replica-a", nil)
+	tracker.now = func() time.Time { return now }
+
+	var changes []Presence
+	tracker.OnChange(func(p Presence) { changes = append(changes, p) })
+
+	tracker.ClientConnected("alice", "household-1")
+
+	now = now.Add(awayAfter + time.Second)
+	tracker.sweep()
+
+	if p := tracker.Snapshot("household-1")[0]; p.Status != StatusAway {
+		t.Errorf("expected alice to be away, got %s", p.Status)
+	}
+
+	tracker.ClientActive("alice", "household-1")
+	if p := tracker.Snapshot("household-1")[0]; p.Status != StatusOnline {
+		t.Errorf("expected alice to be back online, got %s", p.Status)
+	}
+
+	want := []Status{StatusOnline, StatusAway, StatusOnline}
+	if len(changes) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), changes)
+	}
+	for i, status := range want {
+		if changes[i].Status != status {
+			t.Errorf("change %d: expected %s, got %s", i, status, changes[i].Status)
+		}
+	}
+}
+
+func TestStaleReplicaIsAnnouncedOfflineOnce(t *testing.T) {
+	now := time.Now()
+	clock := func() time.Time { return now }
+
+	bp := backplane.NewMemoryBackplane()
+	replicaA := NewTracker("replica-a", bp)
+	replicaB := NewTracker("replica-b", bp)
+	replicaC := NewTracker("replica-c", bp)
+	for _, tracker := range []*Tracker{replicaA, replicaB, replicaC} {
+		tracker.now = clock
+	}
+
+	var changes []Presence
+	replicaB.OnChange(func(p Presence) { changes = append(changes, p) })
+	replicaC.OnChange(func(p Presence) { changes = append(changes, p) })
+
+	// Replica a stops reporting without disconnecting alice
+	replicaA.ClientConnected("alice", "household-1")
+
+	now = now.Add(staleAfter + time.Second)
+	replicaB.sweep()
+	replicaC.sweep()
+
+	if len(changes) != 1 || changes[0].UserID != "alice" || changes[0].Status != StatusOffline {
+		t.Fatalf("expected alice announced offline once, got %+v", changes)
+	}
+	for _, tracker := range []*Tracker{replicaB, replicaC} {
+		if snapshot := tracker.Snapshot("household-1"); len(snapshot) != 0 {
+			t.Errorf("%s still has %+v", tracker.replicaID, snapshot)
+		}
+		if len(tracker.states) != 0 || len(tracker.announced) != 0 {
+			t.Errorf("%s kept %d states and %d announcements", tracker.replicaID, len(tracker.states), len(tracker.announced))
+		}
+	}
+}
+
+func TestDisconnectedMembersAreForgotten(t *testing.T) {
+	now := time.Now()
+	bp := backplane.NewMemoryBackplane()
+	replicaA := NewTracker("replica-a", bp)
+	replicaB := NewTracker("replica-b", bp)
+	replicaA.now = func() time.Time { return now }
+	replicaB.now = func() time.Time { return now }
+
+	replicaA.ClientConnected("alice", "household-1")
+	replicaA.ClientDisconnected("alice", "household-1")
+	replicaA.ClientConnected("bob", "household-1")
+
+	now = now.Add(staleAfter + time.Second)
+	replicaA.sweep()
+	replicaB.sweep()
+
+	for _, tracker := range []*Tracker{replicaA, replicaB} {
+		snapshot := tracker.Snapshot("household-1")
+		if len(snapshot) != 1 || snapshot[0].UserID != "bob" {
+			t.Errorf("%s snapshot = %+v, want only bob", tracker.replicaID, snapshot)
+		}
+		if len(tracker.states) != 1 || len(tracker.announced) != 1 {
+			t.Errorf("%s kept %d states and %d announcements, want 1", tracker.replicaID, len(tracker.states), len(tracker.announced))
+		}
+	}
+}
+
+func TestContextValidation(t *testing.T) {
+	if err := (Context{Activity: ActivityViewingList}).Validate(); err == nil {
+		t.Errorf("expected viewing a list without listId to fail")
+	}
+	if err := (Context{Activity: "cooking"}).Validate(); err == nil {
+		t.Errorf("expected unknown activity to fail")
+	}
+	if err := (Context{Activity: ActivityViewingList, ListID: "42"}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -6,10 +6,17 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
+
+	"github.com/househelper/notifier/pkg/backplane"
 )
 
+// backplaneHub identifies SSE messages on the backplane
+const backplaneHub = "sse"
+
 // SSEHub manages Server-Sent Events connections
 type SSEHub struct {
 	// Connected clients
@@ -29,6 +36,15 @@ type SSEHub struct {
 
 	// Mutex for thread-safe operations
 	mu sync.RWMutex
+
+	// Backplane used to fan messages out to other replicas (optional)
+	backplane backplane.Backplane
+
+	// Sequencer assigning monotonic event IDs per household and user stream
+	sequencer backplane.Sequencer
+
+	// Recent messages per stream for clients resuming with Last-Event-ID
+	replay *replayStore
 }
 
 // SSEClient represents an SSE client connection
@@ -50,11 +66,18 @@ type SSEClient struct {
 
 	// Hub reference
 	hub *SSEHub
+
+	// Serializes writes and guards the cursor
+	mu sync.Mutex
+
+	// Last delivered position in the household and user streams
+	cursor cursor
 }
 
 // SSEMessage represents a server-sent event message
 type SSEMessage struct {
 	ID          string      `json:"id,omitempty"`
+	Sequence    uint64      `json:"sequence,omitempty"`
 	Event       string      `json:"event"`
 	Data        interface{} `json:"data"`
 	UserID      string      `json:"userId,omitempty"`
@@ -69,10 +92,24 @@ func NewSSEHub() *SSEHub {
 		userClients: make(map[string][]*SSEClient),
 		register:    make(chan *SSEClient),
 		unregister:  make(chan *SSEClient),
-		broadcast:   make(chan SSEMessage),
+		broadcast:   make(chan SSEMessage, 256),
+		sequencer:   backplane.NewMemorySequencer(),
+		replay:      newReplayStore(),
 	}
 }
 
+// NewSSEHubWithBackplane creates an SSE hub whose messages are published to
+// the backplane and delivered by every replica to its local clients
+func NewSSEHubWithBackplane(bp backplane.Backplane) *SSEHub {
+	h := NewSSEHub()
+	h.backplane = bp
+	if sequencer, ok := bp.(backplane.Sequencer); ok {
+		h.sequencer = sequencer
+	}
+	bp.Subscribe(backplaneHub, h.handleBackplaneMessage)
+	return h
+}
+
 // Run starts the SSE hub
 func (h *SSEHub) Run() {
 	for {
@@ -87,27 +124,7 @@ func (h *SSEHub) Run() {
 			log.Printf("SSE client registered: user=%s, household=%s", client.userID, client.householdID)
 
 		case client := <-h.unregister:
-			h.mu.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.done)
-
-				// Remove from user clients mapping
-				if client.userID != "" {
-					clients := h.userClients[client.userID]
-					for i, c := range clients {
-						if c == client {
-							h.userClients[client.userID] = append(clients[:i], clients[i+1:]...)
-							break
-						}
-					}
-					if len(h.userClients[client.userID]) == 0 {
-						delete(h.userClients, client.userID)
-					}
-				}
-			}
-			h.mu.Unlock()
-			log.Printf("SSE client unregistered: user=%s, household=%s", client.userID, client.householdID)
+			h.removeClient(client)
 
 		case message := <-h.broadcast:
 			h.sendToClients(message)
@@ -115,16 +132,39 @@ func (h *SSEHub) Run() {
 	}
 }
 
+// removeClient unregisters a client and signals its stream to stop
+func (h *SSEHub) removeClient(client *SSEClient) {
+	h.mu.Lock()
+	if _, ok := h.clients[client]; ok {
+		delete(h.clients, client)
+		close(client.done)
+
+		// Remove from user clients mapping
+		if client.userID != "" {
+			clients := h.userClients[client.userID]
+			for i, c := range clients {
+				if c == client {
+					h.userClients[client.userID] = append(clients[:i], clients[i+1:]...)
+					break
+				}
+			}
+			if len(h.userClients[client.userID]) == 0 {
+				delete(h.userClients, client.userID)
+			}
+		}
+	}
+	h.mu.Unlock()
+	log.Printf("SSE client unregistered: user=%s, household=%s", client.userID, client.householdID)
+}
+
 // sendToClients sends a message to relevant clients
 func (h *SSEHub) sendToClients(message SSEMessage) {
 	h.mu.RLock()
-	defer h.mu.RUnlock()
-
 	var targetClients []*SSEClient
 
 	if message.UserID != "" {
 		// Send to specific user
-		targetClients = h.userClients[message.UserID]
+		targetClients = append(targetClients, h.userClients[message.UserID]...)
 	} else if message.HouseholdID != "" {
 		// Send to all users in household
 		for client := range h.clients {
@@ -138,6 +178,7 @@ func (h *SSEHub) sendToClients(message SSEMessage) {
 			targetClients = append(targetClients, client)
 		}
 	}
+	h.mu.RUnlock()
 
 	for _, client := range targetClients {
 		select {
@@ -145,9 +186,9 @@ func (h *SSEHub) sendToClients(message SSEMessage) {
 			// Client is disconnected
 			continue
 		default:
-			if err := client.sendMessage(message); err != nil {
+			if err := client.deliver(message); err != nil {
 				log.Printf("Error sending SSE message to user %s: %v", client.userID, err)
-				h.unregister <- client
+				h.removeClient(client)
 			}
 		}
 	}
@@ -155,29 +196,84 @@ func (h *SSEHub) sendToClients(message SSEMessage) {
 
 // SendToUser sends a message to a specific user
 func (h *SSEHub) SendToUser(userID string, event string, data interface{}) {
-	message := SSEMessage{
-		ID:        fmt.Sprintf("%d", time.Now().UnixNano()),
+	h.publish(h.sequence(SSEMessage{
 		Event:     event,
 		Data:      data,
 		UserID:    userID,
 		Timestamp: time.Now(),
-	}
-
-	select {
-	case h.broadcast <- message:
-	default:
-		log.Printf("SSE broadcast channel is full, dropping message")
-	}
+	}))
 }
 
 // SendToHousehold sends a message to all users in a household
 func (h *SSEHub) SendToHousehold(householdID string, event string, data interface{}) {
-	message := SSEMessage{
-		ID:          fmt.Sprintf("%d", time.Now().UnixNano()),
+	h.publish(h.sequence(SSEMessage{
 		Event:       event,
 		Data:        data,
 		HouseholdID: householdID,
 		Timestamp:   time.Now(),
+	}))
+}
+
+// sequence assigns the next ID of the message's stream. Messages that cannot
+// be sequenced are still delivered but cannot be replayed.
+func (h *SSEHub) sequence(message SSEMessage) SSEMessage {
+	key := message.streamKey()
+	if key == "" {
+		return message
+	}
+
+	seq, err := h.sequencer.NextSequence(context.Background(), key)
+	if err != nil {
+		log.Printf("Failed to assign SSE sequence for %s: %v", key, err)
+		return message
+	}
+
+	message.Sequence = seq
+	message.ID = strconv.FormatUint(seq, 10)
+	return message
+}
+
+// publish hands the message to the backplane, falling back to local delivery
+// when no backplane is configured or publishing fails
+func (h *SSEHub) publish(message SSEMessage) {
+	if h.backplane != nil {
+		payload, err := json.Marshal(message)
+		if err != nil {
+			log.Printf("Error marshaling SSE message: %v", err)
+			return
+		}
+
+		err = h.backplane.Publish(context.Background(), backplane.Message{
+			Hub:         backplaneHub,
+			UserID:      message.UserID,
+			HouseholdID: message.HouseholdID,
+			Payload:     payload,
+		})
+		if err == nil {
+			return
+		}
+		log.Printf("Failed to publish to backplane, delivering locally: %v", err)
+	}
+
+	h.enqueue(message)
+}
+
+// handleBackplaneMessage delivers a message to the clients connected to this replica
+func (h *SSEHub) handleBackplaneMessage(msg backplane.Message) {
+	var message SSEMessage
+	if err := json.Unmarshal(msg.Payload, &message); err != nil {
+		log.Printf("Failed to decode SSE backplane message: %v", err)
+		return
+	}
+
+	h.enqueue(message)
+}
+
+// enqueue records the message for replay and passes it to the hub loop for
+// local delivery
+func (h *SSEHub) enqueue(message SSEMessage) {
+	if message.Sequence != 0 {
+		h.replay.add(message.streamKey(), message)
 	}
 
 	select {
@@ -206,18 +302,26 @@ func (h *SSEHub) ServeSSE(w http.ResponseWriter, r *http.Request, userID, househ
 		hub:         h,
 	}
 
-	// Register client
+	// Register the client and replay missed messages before live delivery
+	// can interleave with the replay
+	client.mu.Lock()
 	h.register <- client
 
 	// Send initial connection message
 	connectionMsg := SSEMessage{
-		ID:        fmt.Sprintf("%d", time.Now().UnixNano()),
 		Event:     "connected",
 		Data:      map[string]string{"status": "connected"},
 		Timestamp: time.Now(),
 	}
 	client.sendMessage(connectionMsg)
 
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+	h.resume(client, lastEventID)
+	client.mu.Unlock()
+
 	// Keep connection alive and handle disconnection
 	flusher, ok := w.(http.Flusher)
 	if !ok {
@@ -239,12 +343,14 @@ func (h *SSEHub) ServeSSE(w http.ResponseWriter, r *http.Request, userID, househ
 		case <-ticker.C:
 			// Send heartbeat
 			heartbeat := SSEMessage{
-				ID:        fmt.Sprintf("%d", time.Now().UnixNano()),
 				Event:     "heartbeat",
 				Data:      map[string]interface{}{"timestamp": time.Now().Unix()},
 				Timestamp: time.Now(),
 			}
-			if err := client.sendMessage(heartbeat); err != nil {
+			client.mu.Lock()
+			err := client.sendMessage(heartbeat)
+			client.mu.Unlock()
+			if err != nil {
 				h.unregister <- client
 				return
 			}
@@ -253,6 +359,160 @@ func (h *SSEHub) ServeSSE(w http.ResponseWriter, r *http.Request, userID, househ
 	}
 }
 
+// resume positions the client in its streams and replays the messages sent
+// after lastEventID. When the gap can no longer be replayed a reset event is
+// sent so the client refetches its state. Must be called with client.mu held.
+func (h *SSEHub) resume(client *SSEClient, lastEventID string) {
+	current := h.currentCursor(client)
+	if lastEventID == "" {
+		client.cursor = current
+		return
+	}
+
+	last, err := parseCursor(lastEventID)
+	if err != nil {
+		h.reset(client, current, "invalid_event_id")
+		return
+	}
+
+	if last.Household > current.Household || last.User > current.User {
+		h.reset(client, current, "sequence_unknown")
+		return
+	}
+
+	streams := []struct {
+		key     string
+		last    uint64
+		current uint64
+	}{
+		{householdStreamKey(client.householdID), last.Household, current.Household},
+		{userStreamKey(client.userID), last.User, current.User},
+	}
+
+	var missed []SSEMessage
+	for _, stream := range streams {
+		if stream.key == "" {
+			continue
+		}
+
+		messages, complete, known := h.replay.since(stream.key, stream.last)
+		if !known {
+			// This replica has not seen the stream yet, so nothing can be
+			// replayed unless nothing was sent since the client's position
+			complete = stream.last == stream.current
+		}
+		if !complete {
+			h.reset(client, current, "replay_unavailable")
+			return
+		}
+		missed = append(missed, messages...)
+	}
+
+	sort.SliceStable(missed, func(i, j int) bool {
+		return missed[i].Timestamp.Before(missed[j].Timestamp)
+	})
+
+	client.cursor = last
+	for _, message := range missed {
+		if err := client.deliverLocked(message); err != nil {
+			log.Printf("Error replaying SSE message to user %s: %v", client.userID, err)
+			return
+		}
+	}
+
+	log.Printf("SSE client resumed: user=%s, household=%s, replayed=%d", client.userID, client.householdID, len(missed))
+}
+
+// reset tells the client that missed messages cannot be replayed and moves
+// it to the current position of its streams
+func (h *SSEHub) reset(client *SSEClient, current cursor, reason string) {
+	client.cursor = current
+
+	resetMsg := SSEMessage{
+		ID:        current.String(),
+		Event:     "reset",
+		Data:      map[string]string{"reason": reason},
+		Timestamp: time.Now(),
+	}
+	if err := client.sendMessage(resetMsg); err != nil {
+		log.Printf("Error sending SSE reset to user %s: %v", client.userID, err)
+	}
+}
+
+// currentCursor returns the latest position of the client's streams
+func (h *SSEHub) currentCursor(client *SSEClient) cursor {
+	var current cursor
+	ctx := context.Background()
+
+	if key := householdStreamKey(client.householdID); key != "" {
+		seq, err := h.sequencer.CurrentSequence(ctx, key)
+		if err != nil {
+			log.Printf("Failed to read SSE sequence for %s: %v", key, err)
+		}
+		current.Household = seq
+	}
+
+	if key := userStreamKey(client.userID); key != "" {
+		seq, err := h.sequencer.CurrentSequence(ctx, key)
+		if err != nil {
+			log.Printf("Failed to read SSE sequence for %s: %v", key, err)
+		}
+		current.User = seq
+	}
+
+	return current
+}
+
+// streamKey returns the replay stream the message belongs to
+func (m SSEMessage) streamKey() string {
+	if m.UserID != "" {
+		return userStreamKey(m.UserID)
+	}
+	return householdStreamKey(m.HouseholdID)
+}
+
+func householdStreamKey(householdID string) string {
+	if householdID == "" {
+		return ""
+	}
+	return "household:" + householdID
+}
+
+func userStreamKey(userID string) string {
+	if userID == "" {
+		return ""
+	}
+	return "user:" + userID
+}
+
+// deliver sends a live message unless it was already replayed
+func (c *SSEClient) deliver(message SSEMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.deliverLocked(message)
+}
+
+// deliverLocked advances the client's cursor and writes the message with the
+// cursor as event ID. Must be called with c.mu held.
+func (c *SSEClient) deliverLocked(message SSEMessage) error {
+	if message.Sequence != 0 {
+		if message.UserID != "" {
+			if message.Sequence <= c.cursor.User {
+				return nil
+			}
+			c.cursor.User = message.Sequence
+		} else {
+			if message.Sequence <= c.cursor.Household {
+				return nil
+			}
+			c.cursor.Household = message.Sequence
+		}
+		message.ID = c.cursor.String()
+	}
+
+	return c.sendMessage(message)
+}
+
 // sendMessage sends an SSE message to the client
 func (c *SSEClient) sendMessage(message SSEMessage) error {
 	data, err := json.Marshal(message.Data)
@@ -281,4 +541,4 @@ func (c *SSEClient) sendMessage(message SSEMessage) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,129 @@
+package sse
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// replayBufferSize is the number of messages kept per stream for resuming clients
+const replayBufferSize = 256
+
+// cursor is the position of a client in its household and user streams.
+// It is sent to clients as the SSE event ID ("<household>-<user>") and
+// comes back in the Last-Event-ID header when the browser reconnects.
+type cursor struct {
+	Household uint64
+	User      uint64
+}
+
+// String formats the cursor as an SSE event ID
+func (c cursor) String() string {
+	return fmt.Sprintf("%d-%d", c.Household, c.User)
+}
+
+// parseCursor parses an SSE event ID produced by cursor.String
+func parseCursor(id string) (cursor, error) {
+	household, user, ok := strings.Cut(id, "-")
+	if !ok {
+		return cursor{}, fmt.Errorf("invalid event id %q", id)
+	}
+
+	h, err := strconv.ParseUint(household, 10, 64)
+	if err != nil {
+		return cursor{}, fmt.Errorf("invalid household sequence in event id %q: %w", id, err)
+	}
+
+	u, err := strconv.ParseUint(user, 10, 64)
+	if err != nil {
+		return cursor{}, fmt.Errorf("invalid user sequence in event id %q: %w", id, err)
+	}
+
+	return cursor{Household: h, User: u}, nil
+}
+
+// replayBuffer keeps the most recent messages of one stream ordered by sequence
+type replayBuffer struct {
+	messages []SSEMessage
+
+	// floor is the highest sequence that can no longer be replayed: either
+	// evicted from the buffer or sent before this replica saw the stream
+	floor uint64
+}
+
+func newReplayBuffer(first uint64) *replayBuffer {
+	return &replayBuffer{floor: first - 1}
+}
+
+// add inserts a message keeping the buffer ordered and bounded
+func (b *replayBuffer) add(message SSEMessage) {
+	i := sort.Search(len(b.messages), func(i int) bool {
+		return b.messages[i].Sequence >= message.Sequence
+	})
+	if i < len(b.messages) && b.messages[i].Sequence == message.Sequence {
+		return
+	}
+
+	b.messages = append(b.messages, SSEMessage{})
+	copy(b.messages[i+1:], b.messages[i:])
+	b.messages[i] = message
+
+	if len(b.messages) > replayBufferSize {
+		b.floor = b.messages[0].Sequence
+		b.messages = b.messages[1:]
+	}
+}
+
+// since returns the messages sent after the given sequence, or false when
+// some of them are no longer available
+func (b *replayBuffer) since(sequence uint64) ([]SSEMessage, bool) {
+	if sequence < b.floor {
+		return nil, false
+	}
+
+	i := sort.Search(len(b.messages), func(i int) bool {
+		return b.messages[i].Sequence > sequence
+	})
+
+	return append([]SSEMessage(nil), b.messages[i:]...), true
+}
+
+// replayStore holds the replay buffers of all streams seen by this replica
+type replayStore struct {
+	mu      sync.Mutex
+	buffers map[string]*replayBuffer
+}
+
+func newReplayStore() *replayStore {
+	return &replayStore{buffers: make(map[string]*replayBuffer)}
+}
+
+// add records a sequenced message in its stream buffer
+func (s *replayStore) add(key string, message SSEMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	buffer, ok := s.buffers[key]
+	if !ok {
+		buffer = newReplayBuffer(message.Sequence)
+		s.buffers[key] = buffer
+	}
+	buffer.add(message)
+}
+
+// since returns the buffered messages after the sequence. The second value
+// reports whether the stream is known to this replica at all.
+func (s *replayStore) since(key string, sequence uint64) ([]SSEMessage, bool, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	buffer, ok := s.buffers[key]
+	if !ok {
+		return nil, true, false
+	}
+
+	messages, complete := buffer.since(sequence)
+	return messages, complete, true
+}
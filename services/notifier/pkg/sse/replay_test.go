@@ -0,0 +1,104 @@
+package sse
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestClient(h *SSEHub, userID, householdID string) (*SSEClient, *httptest.ResponseRecorder) {
+	recorder := httptest.NewRecorder()
+	return &SSEClient{
+		userID:      userID,
+		householdID: householdID,
+		writer:      recorder,
+		done:        make(chan struct{}),
+		hub:         h,
+	}, recorder
+}
+
+func TestParseCursor(t *testing.T) {
+	c, err := parseCursor("42-7")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Household != 42 || c.User != 7 {
+		t.Errorf("unexpected cursor %+v", c)
+	}
+	if c.String() != "42-7" {
+		t.Errorf("expected round trip, got %s", c.String())
+	}
+
+	if _, err := parseCursor("1700000000000000000"); err == nil {
+		t.Errorf("expected error for legacy event id")
+	}
+}
+
+func TestResumeReplaysMissedMessages(t *testing.T) {
+	hub := NewSSEHub()
+	hub.SendToHousehold("household-1", "task.created", map[string]string{"title": "Dishes"})
+	hub.SendToHousehold("household-1", "task.updated", map[string]string{"title": "Laundry"})
+	hub.SendToUser("alice", "timer.finished", map[string]string{"timer": "Oven"})
+	hub.SendToHousehold("household-2", "task.created", map[string]string{"title": "Other"})
+
+	client, recorder := newTestClient(hub, "alice", "household-1")
+	hub.resume(client, "1-0")
+
+	body := recorder.Body.String()
+	if strings.Contains(body, "Dishes") {
+		t.Errorf("expected already delivered message to be skipped, got %q", body)
+	}
+	for _, want := range []string{"id: 2-0\nevent: task.updated", "id: 2-1\nevent: timer.finished"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in replay, got %q", want, body)
+		}
+	}
+	if strings.Contains(body, "Other") {
+		t.Errorf("expected other household to be excluded, got %q", body)
+	}
+	if client.cursor != (cursor{Household: 2, User: 1}) {
+		t.Errorf("unexpected cursor after replay %+v", client.cursor)
+	}
+
+	// Live delivery of an already replayed message is skipped
+	recorder.Body.Reset()
+	if err := client.deliver(SSEMessage{Event: "task.updated", HouseholdID: "household-1", Sequence: 2}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if recorder.Body.Len() != 0 {
+		t.Errorf("expected duplicate to be dropped, got %q", recorder.Body.String())
+	}
+}
+
+func TestResumeSendsResetWhenGapIsTooLarge(t *testing.T) {
+	hub := NewSSEHub()
+	for i := 0; i < replayBufferSize+10; i++ {
+		hub.SendToHousehold("household-1", "shopping.updated", i)
+	}
+
+	client, recorder := newTestClient(hub, "alice", "household-1")
+	hub.resume(client, "1-0")
+
+	body := recorder.Body.String()
+	if !strings.Contains(body, "event: reset") {
+		t.Errorf("expected reset event, got %q", body)
+	}
+	if client.cursor.Household != uint64(replayBufferSize+10) {
+		t.Errorf("expected cursor to move to the latest sequence, got %+v", client.cursor)
+	}
+}
+
+func TestResumeWithoutLastEventIDStartsAtCurrentPosition(t *testing.T) {
+	hub := NewSSEHub()
+	hub.SendToHousehold("household-1", "task.created", nil)
+
+	client, recorder := newTestClient(hub, "alice", "household-1")
+	hub.resume(client, "")
+
+	if recorder.Body.Len() != 0 {
+		t.Errorf("expected nothing to be replayed, got %q", recorder.Body.String())
+	}
+	if client.cursor != (cursor{Household: 1}) {
+		t.Errorf("unexpected cursor %+v", client.cursor)
+	}
+}
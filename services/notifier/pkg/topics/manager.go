@@ -0,0 +1,168 @@
+package topics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"firebase.google.com/go/v4/messaging"
+	"github.com/househelper/notifier/pkg/devices"
+	"github.com/househelper/notifier/pkg/notifications"
+)
+
+// reasonNotFound is the topic management error for a token FCM no longer knows
+const reasonNotFound = "NOT_FOUND"
+
+// Provider manages FCM topic subscriptions and sends to topics; it is
+// satisfied by notifications.FCMService
+type Provider interface {
+	SubscribeToTopic(ctx context.Context, tokens []string, topic string) (*messaging.TopicManagementResponse, error)
+	UnsubscribeFromTopic(ctx context.Context, tokens []string, topic string) (*messaging.TopicManagementResponse, error)
+	SendToTopic(ctx context.Context, topic string, payload notifications.NotificationPayload) (string, error)
+}
+
+// HouseholdTopic returns the FCM topic of a household's announcements
+func HouseholdTopic(householdID string) string {
+	return "household-" + householdID
+}
+
+// Result summarizes a topic subscription change
+type Result struct {
+	Topic   string `json:"topic"`
+	Updated int    `json:"updated"`
+	Failed  int    `json:"failed"`
+
+	// Removed counts devices dropped from the registry because FCM no
+	// longer knows their token
+	Removed int `json:"removed"`
+}
+
+// Manager keeps the Android devices of household members subscribed to
+// their household's topic
+type Manager struct {
+	store    Store
+	registry devices.Registry
+	provider Provider
+}
+
+// NewManager creates a new topic manager
+func NewManager(store Store, registry devices.Registry, provider Provider) *Manager {
+	return &Manager{store: store, registry: registry, provider: provider}
+}
+
+// Join subscribes the user's Android devices to the household topic
+func (m *Manager) Join(ctx context.Context, householdID, userID string) (*Result, error) {
+	if err := m.store.AddMembership(ctx, userID, householdID); err != nil {
+		return nil, err
+	}
+	return m.update(ctx, householdID, userID, m.provider.SubscribeToTopic)
+}
+
+// Leave unsubscribes the user's Android devices from the household topic
+func (m *Manager) Leave(ctx context.Context, householdID, userID string) (*Result, error) {
+	if err := m.store.RemoveMembership(ctx, userID, householdID); err != nil {
+		return nil, err
+	}
+	return m.update(ctx, householdID, userID, m.provider.UnsubscribeFromTopic)
+}
+
+// DeviceRegistered subscribes a newly registered Android device to the
+// topics of every household its user follows
+func (m *Manager) DeviceRegistered(ctx context.Context, device devices.Device) error {
+	return m.updateDevice(ctx, device, m.provider.SubscribeToTopic)
+}
+
+// DeviceRemoved unsubscribes an Android device from its user's household
+// topics so a token left on a signed out phone stops receiving announcements
+func (m *Manager) DeviceRemoved(ctx context.Context, device devices.Device) error {
+	return m.updateDevice(ctx, device, m.provider.UnsubscribeFromTopic)
+}
+
+// Announce sends a notification to every subscribed device of the household
+func (m *Manager) Announce(ctx context.Context, householdID string, payload notifications.NotificationPayload) (string, error) {
+	return m.provider.SendToTopic(ctx, HouseholdTopic(householdID), payload)
+}
+
+type topicOperation func(ctx context.Context, tokens []string, topic string) (*messaging.TopicManagementResponse, error)
+
+// update applies op to every Android device of the user
+func (m *Manager) update(ctx context.Context, householdID, userID string, op topicOperation) (*Result, error) {
+	userDevices, err := m.registry.List(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+
+	var android []devices.Device
+	for _, device := range userDevices {
+		if device.Platform == devices.PlatformAndroid {
+			android = append(android, device)
+		}
+	}
+
+	result := &Result{Topic: HouseholdTopic(householdID)}
+	if len(android) == 0 {
+		return result, nil
+	}
+
+	tokens := make([]string, len(android))
+	for i, device := range android {
+		tokens[i] = device.Token
+	}
+
+	response, err := op(ctx, tokens, result.Topic)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Updated = response.SuccessCount
+	result.Failed = response.FailureCount
+	for _, info := range response.Errors {
+		if info.Index < 0 || info.Index >= len(android) {
+			continue
+		}
+		if info.Reason != reasonNotFound {
+			log.Printf("Failed to update topic %s for device %s: %s", result.Topic, android[info.Index].ID, info.Reason)
+			continue
+		}
+		if m.removeDevice(ctx, android[info.Index]) {
+			result.Removed++
+		}
+	}
+	return result, nil
+}
+
+// updateDevice applies op to one device for every household its user follows
+func (m *Manager) updateDevice(ctx context.Context, device devices.Device, op topicOperation) error {
+	if device.Platform != devices.PlatformAndroid {
+		return nil
+	}
+
+	households, err := m.store.Households(ctx, device.UserID)
+	if err != nil {
+		return err
+	}
+
+	for _, householdID := range households {
+		response, err := op(ctx, []string{device.Token}, HouseholdTopic(householdID))
+		if err != nil {
+			return err
+		}
+		if len(response.Errors) > 0 && response.Errors[0].Reason == reasonNotFound {
+			m.removeDevice(ctx, device)
+			return nil
+		}
+	}
+	return nil
+}
+
+// removeDevice drops a device whose token FCM no longer knows
+func (m *Manager) removeDevice(ctx context.Context, device devices.Device) bool {
+	log.Printf("Removing unregistered android device %s of user %s", device.ID, device.UserID)
+	err := m.registry.Remove(ctx, device.UserID, device.ID)
+	if err != nil && !errors.Is(err, devices.ErrNotFound) {
+		log.Printf("Failed to remove device %s: %v", device.ID, err)
+		return false
+	}
+	return true
+}
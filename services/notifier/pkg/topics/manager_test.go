@@ -0,0 +1,110 @@
+package topics
+
+import (
+	"context"
+	"testing"
+
+	"firebase.google.com/go/v4/messaging"
+	"github.com/househelper/notifier/pkg/devices"
+	"github.com/househelper/notifier/pkg/notifications"
+)
+
+type fakeProvider struct {
+	subscribed map[string]map[string]bool
+	dead       map[string]bool
+	sent       []string
+}
+
+func newFakeProvider() *fakeProvider {
+	return &fakeProvider{subscribed: make(map[string]map[string]bool), dead: make(map[string]bool)}
+}
+
+func (p *fakeProvider) SubscribeToTopic(ctx context.Context, tokens []string, topic string) (*messaging.TopicManagementResponse, error) {
+	return p.apply(tokens, topic, true), nil
+}
+
+func (p *fakeProvider) UnsubscribeFromTopic(ctx context.Context, tokens []string, topic string) (*messaging.TopicManagementResponse, error) {
+	return p.apply(tokens, topic, false), nil
+}
+
+func (p *fakeProvider) SendToTopic(ctx context.Context, topic string, payload notifications.NotificationPayload) (string, error) {
+	p.sent = append(p.sent, topic)
+	return "message-1", nil
+}
+
+func (p *fakeProvider) apply(tokens []string, topic string, subscribe bool) *messaging.TopicManagementResponse {
+	response := &messaging.TopicManagementResponse{}
+	for i, token := range tokens {
+		if p.dead[token] {
+			response.FailureCount++
+			response.Errors = append(response.Errors, &messaging.ErrorInfo{Index: i, Reason: reasonNotFound})
+			continue
+		}
+		if p.subscribed[topic] == nil {
+			p.subscribed[topic] = make(map[string]bool)
+		}
+		p.subscribed[topic][token] = subscribe
+		response.SuccessCount++
+	}
+	return response
+}
+
+func registerDevice(t *testing.T, registry devices.Registry, device devices.Device) devices.Device {
+	t.Helper()
+	registered, err := registry.Register(context.Background(), device)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	return *registered
+}
+
+func TestJoinAndLeaveHousehold(t *testing.T) {
+	registry := devices.NewMemoryRegistry()
+	provider := newFakeProvider()
+	manager := NewManager(NewMemoryStore(), registry, provider)
+	ctx := context.Background()
+
+	registerDevice(t, registry, devices.Device{UserID: "user-1", Platform: devices.PlatformAndroid, Token: "android-1"})
+	registerDevice(t, registry, devices.Device{UserID: "user-1", Platform: devices.PlatformAndroid, Token: "android-dead"})
+	registerDevice(t, registry, devices.Device{UserID: "user-1", Platform: devices.PlatformIOS, Token: "ios-1", BundleID: "com.househelper.app"})
+	provider.dead["android-dead"] = true
+
+	result, err := manager.Join(ctx, "h1", "user-1")
+	if err != nil {
+		t.Fatalf("Join() error = %v", err)
+	}
+	if result.Topic != "household-h1" || result.Updated != 1 || result.Removed != 1 {
+		t.Fatalf("join result = %+v, want one subscribed and the dead token removed", result)
+	}
+	if provider.subscribed["household-h1"]["ios-1"] {
+		t.Error("ios device was subscribed to an FCM topic")
+	}
+
+	remaining, _ := registry.List(ctx, "user-1")
+	if len(remaining) != 2 {
+		t.Errorf("registry has %d devices, want the dead token removed", len(remaining))
+	}
+
+	// A phone registered after joining follows the household too
+	later := registerDevice(t, registry, devices.Device{UserID: "user-1", Platform: devices.PlatformAndroid, Token: "android-2"})
+	if err := manager.DeviceRegistered(ctx, later); err != nil {
+		t.Fatalf("DeviceRegistered() error = %v", err)
+	}
+	if !provider.subscribed["household-h1"]["android-2"] {
+		t.Error("device registered after joining was not subscribed")
+	}
+
+	if _, err := manager.Leave(ctx, "h1", "user-1"); err != nil {
+		t.Fatalf("Leave() error = %v", err)
+	}
+	for _, token := range []string{"android-1", "android-2"} {
+		if provider.subscribed["household-h1"][token] {
+			t.Errorf("%s still subscribed after leaving", token)
+		}
+	}
+
+	households, _ := manager.store.Households(ctx, "user-1")
+	if len(households) != 0 {
+		t.Errorf("user still follows %v after leaving", households)
+	}
+}
@@ -0,0 +1,130 @@
+package topics
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Store remembers which households each user receives announcements from,
+// so devices registered later can be subscribed to the same topics
+type Store interface {
+	// AddMembership records that the user follows the household
+	AddMembership(ctx context.Context, userID, householdID string) error
+
+	// RemoveMembership forgets that the user follows the household
+	RemoveMembership(ctx context.Context, userID, householdID string) error
+
+	// Households returns the households the user follows
+	Households(ctx context.Context, userID string) ([]string, error)
+}
+
+// MemoryStore is a process-local Store
+type MemoryStore struct {
+	mu          sync.RWMutex
+	memberships map[string]map[string]struct{}
+}
+
+// NewMemoryStore creates a new in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{memberships: make(map[string]map[string]struct{})}
+}
+
+// AddMembership records that the user follows the household
+func (s *MemoryStore) AddMembership(ctx context.Context, userID, householdID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	households, ok := s.memberships[userID]
+	if !ok {
+		households = make(map[string]struct{})
+		s.memberships[userID] = households
+	}
+	households[householdID] = struct{}{}
+	return nil
+}
+
+// RemoveMembership forgets that the user follows the household
+func (s *MemoryStore) RemoveMembership(ctx context.Context, userID, householdID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.memberships[userID], householdID)
+	if len(s.memberships[userID]) == 0 {
+		delete(s.memberships, userID)
+	}
+	return nil
+}
+
+// Households returns the households the user follows
+func (s *MemoryStore) Households(ctx context.Context, userID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	households := make([]string, 0, len(s.memberships[userID]))
+	for householdID := range s.memberships[userID] {
+		households = append(households, householdID)
+	}
+	sort.Strings(households)
+	return households, nil
+}
+
+// RedisStore stores memberships in Redis so all replicas share them
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore connects to Redis
+func NewRedisStore(ctx context.Context, redisURL string) (*RedisStore, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse redis url: %w", err)
+	}
+
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return &RedisStore{client: client, prefix: "house-helper:notifier:topics"}, nil
+}
+
+// AddMembership records that the user follows the household
+func (s *RedisStore) AddMembership(ctx context.Context, userID, householdID string) error {
+	if err := s.client.SAdd(ctx, s.userKey(userID), householdID).Err(); err != nil {
+		return fmt.Errorf("failed to add membership: %w", err)
+	}
+	return nil
+}
+
+// RemoveMembership forgets that the user follows the household
+func (s *RedisStore) RemoveMembership(ctx context.Context, userID, householdID string) error {
+	if err := s.client.SRem(ctx, s.userKey(userID), householdID).Err(); err != nil {
+		return fmt.Errorf("failed to remove membership: %w", err)
+	}
+	return nil
+}
+
+// Households returns the households the user follows
+func (s *RedisStore) Households(ctx context.Context, userID string) ([]string, error) {
+	households, err := s.client.SMembers(ctx, s.userKey(userID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list memberships: %w", err)
+	}
+	sort.Strings(households)
+	return households, nil
+}
+
+// Close closes the Redis connection
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+func (s *RedisStore) userKey(userID string) string {
+	return s.prefix + ":user:" + userID
+}
@@ -1,15 +1,21 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/househelper/notifier/pkg/backplane"
 )
 
+// backplaneHub identifies WebSocket messages on the backplane
+const backplaneHub = "websocket"
+
 // Hub maintains the set of active clients and broadcasts messages to them
 type Hub struct {
 	// Registered clients
@@ -27,6 +33,23 @@ type Hub struct {
 	// User to client mapping for targeted messages
 	userClients map[string][]*Client
 	mu          sync.RWMutex
+
+	// Backplane used to fan broadcasts out to other replicas (optional)
+	backplane backplane.Backplane
+
+	// Handlers for client commands
+	commands map[string]CommandHandler
+
+	// Notified about client lifecycle and activity (optional)
+	observer ConnectionObserver
+}
+
+// ConnectionObserver is notified when clients connect, disconnect or send
+// messages, e.g. to track presence
+type ConnectionObserver interface {
+	ClientConnected(userID, householdID string)
+	ClientDisconnected(userID, householdID string)
+	ClientActive(userID, householdID string)
 }
 
 // Client represents a WebSocket client
@@ -45,11 +68,29 @@ type Client struct {
 
 	// The hub
 	hub *Hub
+
+	// Guards the fields below
+	mu sync.Mutex
+
+	// Whether the send channel has been closed
+	closed bool
+
+	// Whether the client acknowledges delivered messages
+	acks bool
+
+	// Resources the client subscribed to (e.g. "list:42")
+	subscriptions map[string]bool
+
+	// Delivered messages awaiting acknowledgement
+	pending map[string]*pendingMessage
 }
 
 // Message represents a WebSocket message
 type Message struct {
+	ID          string      `json:"id,omitempty"`
 	Type        string      `json:"type"`
+	RequestID   string      `json:"requestId,omitempty"`
+	Resource    string      `json:"resource,omitempty"`
 	UserID      string      `json:"userId,omitempty"`
 	HouseholdID string      `json:"householdId,omitempty"`
 	Data        interface{} `json:"data"`
@@ -66,8 +107,24 @@ const (
 	// Send pings to peer with this period. Must be less than pongWait
 	pingPeriod = (pongWait * 9) / 10
 
-	// Maximum message size allowed from peer
-	maxMessageSize = 512
+	// Maximum message size allowed from peer. Commands carry small JSON
+	// payloads, so this leaves room for batched quick actions
+	maxMessageSize = 16 * 1024
+
+	// Time a client has to acknowledge a message before it is redelivered
+	ackTimeout = 10 * time.Second
+
+	// How often unacknowledged messages are checked
+	ackCheckPeriod = ackTimeout / 2
+
+	// Number of redeliveries before a message is given up
+	maxRedeliveries = 3
+
+	// Maximum number of unacknowledged messages tracked per client
+	maxPendingAcks = 256
+
+	// Time allowed for a command handler to complete
+	commandTimeout = 10 * time.Second
 )
 
 var upgrader = websocket.Upgrader{
@@ -88,9 +145,25 @@ func NewHub() *Hub {
 		register:    make(chan *Client),
 		unregister:  make(chan *Client),
 		userClients: make(map[string][]*Client),
+		commands:    make(map[string]CommandHandler),
 	}
 }
 
+// NewHubWithBackplane creates a WebSocket hub whose broadcasts are published
+// to the backplane and delivered by every replica to its local clients
+func NewHubWithBackplane(bp backplane.Backplane) *Hub {
+	h := NewHub()
+	h.backplane = bp
+	bp.Subscribe(backplaneHub, h.handleBackplaneMessage)
+	return h
+}
+
+// SetObserver registers the observer notified about client lifecycle events.
+// It must be called before Run.
+func (h *Hub) SetObserver(observer ConnectionObserver) {
+	h.observer = observer
+}
+
 // Run starts the hub and handles client management
 func (h *Hub) Run() {
 	for {
@@ -104,103 +177,175 @@ func (h *Hub) Run() {
 			h.mu.Unlock()
 			log.Printf("Client registered: user=%s, household=%s", client.userID, client.householdID)
 
-		case client := <-h.unregister:
-			h.mu.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
-
-				// Remove from user clients mapping
-				if client.userID != "" {
-					clients := h.userClients[client.userID]
-					for i, c := range clients {
-						if c == client {
-							h.userClients[client.userID] = append(clients[:i], clients[i+1:]...)
-							break
-						}
-					}
-					if len(h.userClients[client.userID]) == 0 {
-						delete(h.userClients, client.userID)
-					}
-				}
+			if h.observer != nil {
+				h.observer.ClientConnected(client.userID, client.householdID)
 			}
-			h.mu.Unlock()
-			log.Printf("Client unregistered: user=%s, household=%s", client.userID, client.householdID)
+
+		case client := <-h.unregister:
+			h.removeClient(client)
 
 		case message := <-h.broadcast:
 			h.mu.RLock()
+			var slow []*Client
 			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					delete(h.clients, client)
+				if !client.enqueue(message) {
+					slow = append(slow, client)
 				}
 			}
 			h.mu.RUnlock()
+			h.drop(slow)
 		}
 	}
 }
 
 // BroadcastToUser sends a message to all connections for a specific user
 func (h *Hub) BroadcastToUser(userID string, message Message) {
-	h.mu.RLock()
-	clients := h.userClients[userID]
-	h.mu.RUnlock()
+	if message.ID == "" {
+		message.ID = uuid.NewString()
+	}
 
-	if len(clients) == 0 {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error marshaling message: %v", err)
 		return
 	}
 
+	h.publish(backplane.Message{Hub: backplaneHub, UserID: userID, Payload: data})
+}
+
+// BroadcastToHousehold sends a message to all users in a household
+func (h *Hub) BroadcastToHousehold(householdID string, message Message) {
+	if message.ID == "" {
+		message.ID = uuid.NewString()
+	}
+
 	data, err := json.Marshal(message)
 	if err != nil {
 		log.Printf("Error marshaling message: %v", err)
 		return
 	}
 
-	for _, client := range clients {
-		select {
-		case client.send <- data:
-		default:
-			close(client.send)
-			h.mu.Lock()
-			delete(h.clients, client)
-			h.mu.Unlock()
+	h.publish(backplane.Message{Hub: backplaneHub, HouseholdID: householdID, Payload: data})
+}
+
+// publish hands the message to the backplane, falling back to local delivery
+// when no backplane is configured or publishing fails
+func (h *Hub) publish(msg backplane.Message) {
+	if h.backplane != nil {
+		err := h.backplane.Publish(context.Background(), msg)
+		if err == nil {
+			return
 		}
+		log.Printf("Failed to publish to backplane, delivering locally: %v", err)
 	}
+
+	h.handleBackplaneMessage(msg)
 }
 
-// BroadcastToHousehold sends a message to all users in a household
-func (h *Hub) BroadcastToHousehold(householdID string, message Message) {
+// handleBackplaneMessage delivers a message to the clients connected to this replica
+func (h *Hub) handleBackplaneMessage(msg backplane.Message) {
+	var header struct {
+		ID       string `json:"id"`
+		Resource string `json:"resource"`
+	}
+	if err := json.Unmarshal(msg.Payload, &header); err != nil {
+		log.Printf("Failed to decode WebSocket backplane message: %v", err)
+		return
+	}
+
+	var clients []*Client
 	h.mu.RLock()
-	clients := make([]*Client, 0)
-	for client := range h.clients {
-		if client.householdID == householdID {
-			clients = append(clients, client)
+	if msg.UserID != "" {
+		clients = append(clients, h.userClients[msg.UserID]...)
+	} else if msg.HouseholdID != "" {
+		for client := range h.clients {
+			if client.householdID == msg.HouseholdID {
+				clients = append(clients, client)
+			}
 		}
 	}
 	h.mu.RUnlock()
 
-	if len(clients) == 0 {
-		return
+	var slow []*Client
+	for _, client := range clients {
+		if !client.wants(header.Resource) {
+			continue
+		}
+		if !client.enqueue(msg.Payload) {
+			slow = append(slow, client)
+			continue
+		}
+		client.track(header.ID, msg.Payload)
 	}
+	h.drop(slow)
+}
 
-	data, err := json.Marshal(message)
-	if err != nil {
-		log.Printf("Error marshaling message: %v", err)
-		return
+// drop disconnects clients whose send buffer is full
+func (h *Hub) drop(clients []*Client) {
+	for _, client := range clients {
+		h.removeClient(client)
 	}
+}
 
-	for _, client := range clients {
-		select {
-		case client.send <- data:
-		default:
-			close(client.send)
-			h.mu.Lock()
-			delete(h.clients, client)
-			h.mu.Unlock()
+// removeClient unregisters a client and closes its send channel
+func (h *Hub) removeClient(client *Client) {
+	h.mu.Lock()
+	_, registered := h.clients[client]
+	if registered {
+		delete(h.clients, client)
+		client.close()
+
+		// Remove from user clients mapping
+		if client.userID != "" {
+			clients := h.userClients[client.userID]
+			for i, c := range clients {
+				if c == client {
+					h.userClients[client.userID] = append(clients[:i], clients[i+1:]...)
+					break
+				}
+			}
+			if len(h.userClients[client.userID]) == 0 {
+				delete(h.userClients, client.userID)
+			}
 		}
 	}
+	h.mu.Unlock()
+
+	if !registered {
+		return
+	}
+	log.Printf("Client unregistered: user=%s, household=%s", client.userID, client.householdID)
+
+	if h.observer != nil {
+		h.observer.ClientDisconnected(client.userID, client.householdID)
+	}
+}
+
+// enqueue queues data on the client's send buffer, reporting false when the
+// buffer is full
+func (c *Client) enqueue(data []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return true
+	}
+
+	select {
+	case c.send <- data:
+		return true
+	default:
+		return false
+	}
+}
+
+// close closes the send channel once
+func (c *Client) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		c.closed = true
+		close(c.send)
+	}
 }
 
 // ServeWS handles websocket requests from the peer
@@ -212,11 +357,14 @@ func (h *Hub) ServeWS(w http.ResponseWriter, r *http.Request, userID, householdI
 	}
 
 	client := &Client{
-		hub:         h,
-		conn:        conn,
-		send:        make(chan []byte, 256),
-		userID:      userID,
-		householdID: householdID,
+		hub:           h,
+		conn:          conn,
+		send:          make(chan []byte, 256),
+		userID:        userID,
+		householdID:   householdID,
+		acks:          r.URL.Query().Get("acks") == "true",
+		subscriptions: make(map[string]bool),
+		pending:       make(map[string]*pendingMessage),
 	}
 
 	client.hub.register <- client
@@ -250,19 +398,20 @@ func (c *Client) readPump() {
 			break
 		}
 
-		// Handle incoming messages (e.g., heartbeat, typing indicators)
-		var msg Message
-		if err := json.Unmarshal(message, &msg); err == nil {
-			log.Printf("Received message from user %s: %s", c.userID, msg.Type)
+		if c.hub.observer != nil {
+			c.hub.observer.ClientActive(c.userID, c.householdID)
 		}
+		c.handleClientMessage(message)
 	}
 }
 
 // writePump pumps messages from the hub to the websocket connection
 func (c *Client) writePump() {
 	ticker := time.NewTicker(pingPeriod)
+	ackTicker := time.NewTicker(ackCheckPeriod)
 	defer func() {
 		ticker.Stop()
+		ackTicker.Stop()
 		c.conn.Close()
 	}()
 
@@ -298,6 +447,14 @@ func (c *Client) writePump() {
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
+
+		case now := <-ackTicker.C:
+			for _, message := range c.redeliver(now) {
+				c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+				if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+					return
+				}
+			}
 		}
 	}
 }
@@ -0,0 +1,95 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/househelper/notifier/pkg/backplane"
+)
+
+// addLocalClient registers a client without a network connection
+func addLocalClient(h *Hub, userID, householdID string) *Client {
+	client := &Client{
+		hub:           h,
+		send:          make(chan []byte, 8),
+		userID:        userID,
+		householdID:   householdID,
+		subscriptions: make(map[string]bool),
+		pending:       make(map[string]*pendingMessage),
+	}
+
+	h.mu.Lock()
+	h.clients[client] = true
+	h.userClients[userID] = append(h.userClients[userID], client)
+	h.mu.Unlock()
+
+	return client
+}
+
+func receive(t *testing.T, client *Client) Message {
+	t.Helper()
+
+	select {
+	case data := <-client.send:
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("failed to decode message: %v", err)
+		}
+		return msg
+	case <-time.After(time.Second):
+		t.Fatalf("no message delivered to user %s", client.userID)
+	}
+	return Message{}
+}
+
+func TestBroadcastToHouseholdReachesAllReplicas(t *testing.T) {
+	bp := backplane.NewMemoryBackplane()
+	replicaA := NewHubWithBackplane(bp)
+	replicaB := NewHubWithBackplane(bp)
+
+	alice := addLocalClient(replicaA, "alice", "household-1")
+	bob := addLocalClient(replicaB, "bob", "household-1")
+	carol := addLocalClient(replicaB, "carol", "household-2")
+
+	replicaA.BroadcastToHousehold("household-1", Message{Type: "task.updated", HouseholdID: "household-1"})
+
+	if msg := receive(t, alice); msg.Type != "task.updated" {
+		t.Errorf("expected task.updated for alice, got %s", msg.Type)
+	}
+	if msg := receive(t, bob); msg.Type != "task.updated" {
+		t.Errorf("expected task.updated for bob, got %s", msg.Type)
+	}
+	if len(carol.send) != 0 {
+		t.Errorf("expected no message for another household")
+	}
+}
+
+func TestBroadcastToUserReachesAllReplicas(t *testing.T) {
+	bp := backplane.NewMemoryBackplane()
+	replicaA := NewHubWithBackplane(bp)
+	replicaB := NewHubWithBackplane(bp)
+
+	phone := addLocalClient(replicaA, "alice", "household-1")
+	laptop := addLocalClient(replicaB, "alice", "household-1")
+	bob := addLocalClient(replicaB, "bob", "household-1")
+
+	replicaB.BroadcastToUser("alice", Message{Type: "timer.finished", UserID: "alice"})
+
+	receive(t, phone)
+	receive(t, laptop)
+	if len(bob.send) != 0 {
+		t.Errorf("expected no message for another user")
+	}
+}
+
+func TestBroadcastWithoutBackplaneDeliversLocally(t *testing.T) {
+	hub := NewHub()
+	client := addLocalClient(hub, "alice", "household-1")
+
+	hub.BroadcastToHousehold("household-1", Message{Type: "shopping.updated"})
+
+	if msg := receive(t, client); msg.Type != "shopping.updated" {
+		t.Errorf("expected shopping.updated, got %s", msg.Type)
+	}
+}
@@ -0,0 +1,275 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Client message types
+const (
+	TypeSubscribe   = "subscribe"
+	TypeUnsubscribe = "unsubscribe"
+	TypePing        = "ping"
+	TypeAck         = "ack"
+	TypeCommand     = "command"
+)
+
+// Server message types
+const (
+	TypePong     = "pong"
+	TypeResponse = "response"
+	TypeError    = "error"
+)
+
+// Resource kinds clients can subscribe to
+var subscribableResources = map[string]bool{
+	"list":  true,
+	"task":  true,
+	"timer": true,
+}
+
+// ClientMessage is a message sent by a client over the socket.
+//
+//	{"type":"subscribe","requestId":"1","resource":"list:42"}
+//	{"type":"ping","requestId":"2","clientTime":1700000000000}
+//	{"type":"ack","messageId":"6f1c..."}
+//	{"type":"command","requestId":"3","command":"shopping.item.check","data":{...}}
+type ClientMessage struct {
+	Type       string          `json:"type"`
+	RequestID  string          `json:"requestId,omitempty"`
+	Resource   string          `json:"resource,omitempty"`
+	MessageID  string          `json:"messageId,omitempty"`
+	Command    string          `json:"command,omitempty"`
+	ClientTime int64           `json:"clientTime,omitempty"`
+	Data       json.RawMessage `json:"data,omitempty"`
+}
+
+// PongData is returned for ping requests so clients can estimate clock skew
+type PongData struct {
+	ClientTime int64 `json:"clientTime,omitempty"`
+	ServerTime int64 `json:"serverTime"`
+}
+
+// ResponseData is returned for requests carrying a correlation ID
+type ResponseData struct {
+	OK     bool        `json:"ok"`
+	Error  string      `json:"error,omitempty"`
+	Result interface{} `json:"result,omitempty"`
+}
+
+// CommandRequest is passed to command handlers
+type CommandRequest struct {
+	UserID      string
+	HouseholdID string
+	Command     string
+	Data        json.RawMessage
+}
+
+// CommandHandler executes a client command and returns the result sent back
+// in the response
+type CommandHandler func(ctx context.Context, req CommandRequest) (interface{}, error)
+
+// ErrUnknownCommand is returned for commands without a registered handler
+var ErrUnknownCommand = errors.New("unknown command")
+
+// HandleCommand registers the handler for a client command
+func (h *Hub) HandleCommand(command string, handler CommandHandler) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.commands[command] = handler
+}
+
+// ParseResource validates a resource key such as "list:42"
+func ParseResource(resource string) (kind, id string, err error) {
+	kind, id, ok := strings.Cut(resource, ":")
+	if !ok || id == "" {
+		return "", "", fmt.Errorf("invalid resource %q", resource)
+	}
+	if !subscribableResources[kind] {
+		return "", "", fmt.Errorf("unsupported resource type %q", kind)
+	}
+	return kind, id, nil
+}
+
+// handleClientMessage processes a message received from the client
+func (c *Client) handleClientMessage(raw []byte) {
+	var msg ClientMessage
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		c.reply(Message{Type: TypeError, Data: ResponseData{Error: "invalid message"}, Timestamp: time.Now()})
+		return
+	}
+
+	switch msg.Type {
+	case TypeSubscribe:
+		if _, _, err := ParseResource(msg.Resource); err != nil {
+			c.respond(msg.RequestID, nil, err)
+			return
+		}
+		c.subscribe(msg.Resource)
+		c.respond(msg.RequestID, nil, nil)
+
+	case TypeUnsubscribe:
+		c.unsubscribe(msg.Resource)
+		c.respond(msg.RequestID, nil, nil)
+
+	case TypePing:
+		c.reply(Message{
+			Type:      TypePong,
+			RequestID: msg.RequestID,
+			Data: PongData{
+				ClientTime: msg.ClientTime,
+				ServerTime: time.Now().UnixMilli(),
+			},
+			Timestamp: time.Now(),
+		})
+
+	case TypeAck:
+		c.acknowledge(msg.MessageID)
+
+	case TypeCommand:
+		c.hub.mu.RLock()
+		handler, ok := c.hub.commands[msg.Command]
+		c.hub.mu.RUnlock()
+
+		if !ok {
+			c.respond(msg.RequestID, nil, fmt.Errorf("%w: %s", ErrUnknownCommand, msg.Command))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+		defer cancel()
+
+		result, err := handler(ctx, CommandRequest{
+			UserID:      c.userID,
+			HouseholdID: c.householdID,
+			Command:     msg.Command,
+			Data:        msg.Data,
+		})
+		if err != nil {
+			log.Printf("Command %s from user %s failed: %v", msg.Command, c.userID, err)
+		}
+		c.respond(msg.RequestID, result, err)
+
+	default:
+		c.respond(msg.RequestID, nil, fmt.Errorf("unsupported message type %q", msg.Type))
+	}
+}
+
+// respond sends a response correlated with the client's request ID
+func (c *Client) respond(requestID string, result interface{}, err error) {
+	data := ResponseData{OK: err == nil, Result: result}
+	if err != nil {
+		data.Error = err.Error()
+	}
+
+	c.reply(Message{
+		Type:      TypeResponse,
+		RequestID: requestID,
+		Data:      data,
+		Timestamp: time.Now(),
+	})
+}
+
+// reply queues a message for this client only. Replies are not tracked for
+// acknowledgement.
+func (c *Client) reply(message Message) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error marshaling message: %v", err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+
+	select {
+	case c.send <- data:
+	default:
+		log.Printf("Send buffer full for user %s, dropping %s", c.userID, message.Type)
+	}
+}
+
+func (c *Client) subscribe(resource string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subscriptions[resource] = true
+}
+
+func (c *Client) unsubscribe(resource string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.subscriptions, resource)
+}
+
+// wants reports whether a message for the resource should reach this client.
+// Messages that are not scoped to a resource go to everyone.
+func (c *Client) wants(resource string) bool {
+	if resource == "" {
+		return true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.subscriptions[resource]
+}
+
+// pendingMessage is a delivered message awaiting acknowledgement
+type pendingMessage struct {
+	data     []byte
+	sentAt   time.Time
+	attempts int
+}
+
+// track records a delivered message for redelivery until it is acknowledged
+func (c *Client) track(messageID string, data []byte) {
+	if !c.acks || messageID == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.pending) >= maxPendingAcks {
+		log.Printf("Too many unacknowledged messages for user %s, not tracking %s", c.userID, messageID)
+		return
+	}
+	c.pending[messageID] = &pendingMessage{data: data, sentAt: time.Now(), attempts: 1}
+}
+
+// acknowledge removes a message from the pending set
+func (c *Client) acknowledge(messageID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, messageID)
+}
+
+// redeliver returns unacknowledged messages that timed out and drops the
+// ones that exhausted their attempts
+func (c *Client) redeliver(now time.Time) [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var due [][]byte
+	for id, pending := range c.pending {
+		if now.Sub(pending.sentAt) < ackTimeout {
+			continue
+		}
+		if pending.attempts > maxRedeliveries {
+			log.Printf("Message %s was never acknowledged by user %s", id, c.userID)
+			delete(c.pending, id)
+			continue
+		}
+		pending.attempts++
+		pending.sentAt = now
+		due = append(due, pending.data)
+	}
+	return due
+}
@@ -0,0 +1,134 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func decodeResponse(t *testing.T, msg Message) ResponseData {
+	t.Helper()
+
+	raw, err := json.Marshal(msg.Data)
+	if err != nil {
+		t.Fatalf("failed to encode data: %v", err)
+	}
+	var data ResponseData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return data
+}
+
+func TestSubscribeScopesResourceMessages(t *testing.T) {
+	hub := NewHub()
+	subscriber := addLocalClient(hub, "alice", "household-1")
+	other := addLocalClient(hub, "bob", "household-1")
+
+	subscriber.handleClientMessage([]byte(`{"type":"subscribe","requestId":"r1","resource":"list:42"}`))
+	resp := receive(t, subscriber)
+	if resp.Type != TypeResponse || resp.RequestID != "r1" || !decodeResponse(t, resp).OK {
+		t.Fatalf("unexpected subscribe response %+v", resp)
+	}
+
+	hub.BroadcastToHousehold("household-1", Message{Type: "shopping.item.checked", Resource: "list:42"})
+
+	if msg := receive(t, subscriber); msg.Type != "shopping.item.checked" {
+		t.Errorf("expected resource message for subscriber, got %s", msg.Type)
+	}
+	if len(other.send) != 0 {
+		t.Errorf("expected no resource message for unsubscribed client")
+	}
+
+	subscriber.handleClientMessage([]byte(`{"type":"unsubscribe","resource":"list:42"}`))
+	receive(t, subscriber)
+	hub.BroadcastToHousehold("household-1", Message{Type: "shopping.item.checked", Resource: "list:42"})
+	if len(subscriber.send) != 0 {
+		t.Errorf("expected no message after unsubscribe")
+	}
+}
+
+func TestSubscribeRejectsUnknownResource(t *testing.T) {
+	hub := NewHub()
+	client := addLocalClient(hub, "alice", "household-1")
+
+	client.handleClientMessage([]byte(`{"type":"subscribe","requestId":"r1","resource":"bill:1"}`))
+
+	resp := decodeResponse(t, receive(t, client))
+	if resp.OK || resp.Error == "" {
+		t.Errorf("expected error response, got %+v", resp)
+	}
+}
+
+func TestPingReturnsServerTime(t *testing.T) {
+	hub := NewHub()
+	client := addLocalClient(hub, "alice", "household-1")
+
+	before := time.Now().UnixMilli()
+	client.handleClientMessage([]byte(`{"type":"ping","requestId":"p1","clientTime":123}`))
+
+	msg := receive(t, client)
+	if msg.Type != TypePong || msg.RequestID != "p1" {
+		t.Fatalf("unexpected pong %+v", msg)
+	}
+	data := msg.Data.(map[string]interface{})
+	if data["clientTime"].(float64) != 123 {
+		t.Errorf("expected client time to be echoed, got %v", data["clientTime"])
+	}
+	if int64(data["serverTime"].(float64)) < before {
+		t.Errorf("expected current server time, got %v", data["serverTime"])
+	}
+}
+
+func TestCommandResponseCarriesCorrelationID(t *testing.T) {
+	hub := NewHub()
+	hub.HandleCommand("shopping.item.check", func(ctx context.Context, req CommandRequest) (interface{}, error) {
+		var payload struct {
+			ItemID string `json:"itemId"`
+		}
+		if err := json.Unmarshal(req.Data, &payload); err != nil {
+			return nil, err
+		}
+		return map[string]string{"itemId": payload.ItemID, "checkedBy": req.UserID}, nil
+	})
+	client := addLocalClient(hub, "alice", "household-1")
+
+	client.handleClientMessage([]byte(`{"type":"command","requestId":"c1","command":"shopping.item.check","data":{"itemId":"milk"}}`))
+
+	msg := receive(t, client)
+	resp := decodeResponse(t, msg)
+	if msg.RequestID != "c1" || !resp.OK {
+		t.Fatalf("unexpected command response %+v", msg)
+	}
+	result := resp.Result.(map[string]interface{})
+	if result["itemId"] != "milk" || result["checkedBy"] != "alice" {
+		t.Errorf("unexpected command result %+v", result)
+	}
+
+	client.handleClientMessage([]byte(`{"type":"command","requestId":"c2","command":"unknown"}`))
+	if resp := decodeResponse(t, receive(t, client)); resp.OK {
+		t.Errorf("expected unknown command to fail")
+	}
+}
+
+func TestUnacknowledgedMessagesAreRedelivered(t *testing.T) {
+	hub := NewHub()
+	client := addLocalClient(hub, "alice", "household-1")
+	client.acks = true
+
+	hub.BroadcastToUser("alice", Message{Type: "timer.finished"})
+	msg := receive(t, client)
+	if msg.ID == "" {
+		t.Fatalf("expected message ID for acknowledgement")
+	}
+
+	if due := client.redeliver(time.Now().Add(ackTimeout)); len(due) != 1 {
+		t.Fatalf("expected one redelivery, got %d", len(due))
+	}
+
+	client.handleClientMessage([]byte(`{"type":"ack","messageId":"` + msg.ID + `"}`))
+	if due := client.redeliver(time.Now().Add(2 * ackTimeout)); len(due) != 0 {
+		t.Errorf("expected no redelivery after ack, got %d", len(due))
+	}
+}
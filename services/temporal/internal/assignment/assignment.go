@@ -0,0 +1,183 @@
+// Package assignment picks who a recurring task's occurrences are assigned
+// to, spreading chores fairly across a household's members.
+package assignment
+
+// Strategies for picking the assignee of an occurrence
+const (
+	// RoundRobin takes members in turn
+	RoundRobin = "round_robin"
+
+	// LeastLoaded picks the member with the fewest estimated minutes of open
+	// tasks
+	LeastLoaded = "least_loaded"
+
+	// Weighted picks the member furthest below their share of the open
+	// minutes, a member's share growing with their weight
+	Weighted = "weighted"
+)
+
+// DefaultEstimatedMinutes is the load a task without an estimated duration
+// adds to its assignee
+const DefaultEstimatedMinutes = 30
+
+// Valid reports whether strategy is known; empty means round-robin
+func Valid(strategy string) bool {
+	switch strategy {
+	case "", RoundRobin, LeastLoaded, Weighted:
+		return true
+	}
+	return false
+}
+
+// Member is a household member an occurrence may be assigned to
+type Member struct {
+	UserID      string
+	Role        string  // admin, member, guest or child
+	Age         int     // In years; zero when unknown
+	Weight      float64 // Share of the chores; zero derives it from role and age
+	LoadMinutes int     // Estimated minutes of the member's open tasks
+	Away        bool    // Away when the occurrence is due
+
+	ExcludedCategories  []string // Categories never assigned to the member
+	PreferredCategories []string // Categories the member is picked for first
+}
+
+// Task is what is known of the task being assigned
+type Task struct {
+	Category         string
+	EstimatedMinutes int
+}
+
+// minutes returns the load the task adds to its assignee
+func (t Task) minutes() int {
+	if t.EstimatedMinutes > 0 {
+		return t.EstimatedMinutes
+	}
+	return DefaultEstimatedMinutes
+}
+
+// weight returns the member's share of the chores: an explicit weight, or
+// one derived from their role and age so guests and young children get less
+func (m Member) weight() float64 {
+	if m.Weight > 0 {
+		return m.Weight
+	}
+
+	weight := 1.0
+	if m.Role == "guest" {
+		weight = 0.5
+	}
+	switch {
+	case m.Age <= 0:
+	case m.Age < 8:
+		weight *= 0.25
+	case m.Age < 12:
+		weight *= 0.5
+	case m.Age < 16:
+		weight *= 0.75
+	}
+	return weight
+}
+
+// Next returns the assignee of the occurrence at index of a series, or ""
+// when no member may be assigned the task. Members are in rotation order.
+func Next(strategy string, members []Member, task Task, index int) string {
+	if len(members) == 0 {
+		return ""
+	}
+
+	candidates := eligible(members, task)
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	// Ties, and round-robin, go to the first candidate in rotation order
+	// starting at the occurrence's turn
+	start := index % len(members)
+	order := func(i int) int { return (candidates[i] - start + len(members)) % len(members) }
+
+	best := 0
+	for i := 1; i < len(candidates); i++ {
+		if better(strategy, members[candidates[i]], members[candidates[best]], task) ||
+			(!better(strategy, members[candidates[best]], members[candidates[i]], task) && order(i) < order(best)) {
+			best = i
+		}
+	}
+	return members[candidates[best]].UserID
+}
+
+// Preview returns the assignees of n occurrences starting with the one at
+// index, assuming each adds the task's minutes to its assignee's load
+func Preview(strategy string, members []Member, task Task, index, n int) []string {
+	pool := make([]Member, len(members))
+	copy(pool, members)
+
+	assignees := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		assignee := Next(strategy, pool, task, index+i)
+		assignees = append(assignees, assignee)
+		for j := range pool {
+			if pool[j].UserID == assignee {
+				pool[j].LoadMinutes += task.minutes()
+			}
+		}
+	}
+	return assignees
+}
+
+// better reports whether a should be picked over b
+func better(strategy string, a, b Member, task Task) bool {
+	switch strategy {
+	case LeastLoaded:
+		return a.LoadMinutes < b.LoadMinutes
+	case Weighted:
+		share := func(m Member) float64 {
+			return float64(m.LoadMinutes+task.minutes()) / m.weight()
+		}
+		return share(a) < share(b)-1e-9
+	default:
+		return false
+	}
+}
+
+// eligible returns the indexes of the members the task may go to: those who
+// are not away and have not excluded its category, narrowed to those who
+// prefer it if any do. When everyone is away the task still goes to one of
+// them rather than to no one.
+func eligible(members []Member, task Task) []int {
+	var allowed, present, preferred []int
+	for i, m := range members {
+		if contains(m.ExcludedCategories, task.Category) {
+			continue
+		}
+		allowed = append(allowed, i)
+		if m.Away {
+			continue
+		}
+		present = append(present, i)
+		if contains(m.PreferredCategories, task.Category) {
+			preferred = append(preferred, i)
+		}
+	}
+
+	switch {
+	case len(preferred) > 0:
+		return preferred
+	case len(present) > 0:
+		return present
+	default:
+		return allowed
+	}
+}
+
+func contains(values []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package assignment
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPreview(t *testing.T) {
+	household := func() []Member {
+		return []Member{
+			{UserID: "dad", Role: "admin"},
+			{UserID: "mom", Role: "admin", LoadMinutes: 60},
+			{UserID: "kid", Role: "member", Age: 10},
+		}
+	}
+	dishes := Task{Category: "kitchen", EstimatedMinutes: 30}
+
+	tests := []struct {
+		name     string
+		strategy string
+		members  func() []Member
+		task     Task
+		index    int
+		want     []string
+	}{
+		{
+			name:    "round-robin takes turns from the series' position",
+			members: household,
+			task:    dishes,
+			index:   1,
+			want:    []string{"mom", "kid", "dad", "mom"},
+		},
+		{
+			name:     "least-loaded evens out open minutes",
+			strategy: LeastLoaded,
+			members:  household,
+			task:     dishes,
+			want:     []string{"dad", "kid", "kid", "dad", "mom", "kid"},
+		},
+		{
+			name:     "weighted gives a ten year old half the share",
+			strategy: Weighted,
+			members:  household,
+			task:     dishes,
+			want:     []string{"dad", "kid", "dad", "dad", "mom", "kid"},
+		},
+		{
+			name:     "explicit weights override role and age",
+			strategy: Weighted,
+			members: func() []Member {
+				return []Member{{UserID: "a", Weight: 2}, {UserID: "b", Weight: 1}}
+			},
+			task: dishes,
+			want: []string{"a", "b", "a", "a", "a", "b"},
+		},
+		{
+			name:     "away members and excluded categories are passed over",
+			strategy: LeastLoaded,
+			members: func() []Member {
+				m := household()
+				m[0].Away = true
+				m[2].ExcludedCategories = []string{"kitchen"}
+				return m
+			},
+			task: dishes,
+			want: []string{"mom", "mom"},
+		},
+		{
+			name: "members who prefer the category come first",
+			members: func() []Member {
+				m := household()
+				m[2].PreferredCategories = []string{"kitchen"}
+				return m
+			},
+			task: dishes,
+			want: []string{"kid", "kid"},
+		},
+		{
+			name: "when everyone is away the rotation continues",
+			members: func() []Member {
+				m := household()
+				for i := range m {
+					m[i].Away = true
+				}
+				return m
+			},
+			task:  dishes,
+			index: 2,
+			want:  []string{"kid", "dad"},
+		},
+		{
+			name: "nobody may take an excluded category",
+			members: func() []Member {
+				return []Member{{UserID: "a", ExcludedCategories: []string{"kitchen"}}}
+			},
+			task: dishes,
+			want: []string{""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			members := tt.members()
+			got := Preview(tt.strategy, members, tt.task, tt.index, len(tt.want))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Preview() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(members, tt.members()) {
+				t.Errorf("Preview() changed the members' loads")
+			}
+		})
+	}
+}
+
+func TestValid(t *testing.T) {
+	for _, strategy := range []string{"", RoundRobin, LeastLoaded, Weighted} {
+		if !Valid(strategy) {
+			t.Errorf("Valid(%q) = false", strategy)
+		}
+	}
+	if Valid("random") {
+		t.Error(`Valid("random") = true`)
+	}
+}
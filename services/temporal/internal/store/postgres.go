@@ -0,0 +1,996 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/househelper/temporal/internal/assignment"
+	"github.com/lib/pq"
+)
+
+// PostgresStore implements Store on the API service's database
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore wraps an open database
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// OpenPostgres connects to the database at databaseURL
+func OpenPostgres(ctx context.Context, databaseURL string) (*PostgresStore, error) {
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	db.SetMaxOpenConns(10)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(5 * time.Minute)
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	return &PostgresStore{db: db}, nil
+}
+
+// Close closes the database
+func (s *PostgresStore) Close() error {
+	return s.db.Close()
+}
+
+// StartTimer marks the timer running and opens a session
+func (s *PostgresStore) StartTimer(ctx context.Context, timerID string) error {
+	if !validUUID(timerID) {
+		return ErrNotFound
+	}
+
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE timers
+			SET status = 'running',
+			    started_at = COALESCE(started_at, NOW()),
+			    completed_at = NULL,
+			    updated_at = NOW()
+			WHERE id = $1 AND deleted_at IS NULL`, timerID)
+		if err != nil {
+			return fmt.Errorf("failed to update timer: %w", err)
+		}
+		if err := requireRow(result); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO timer_sessions (timer_id, started_at)
+			SELECT $1, NOW()
+			WHERE NOT EXISTS (
+				SELECT 1 FROM timer_sessions
+				WHERE timer_id = $1 AND ended_at IS NULL AND deleted_at IS NULL
+			)`, timerID)
+		if err != nil {
+			return fmt.Errorf("failed to open timer session: %w", err)
+		}
+		return nil
+	})
+}
+
+// CompleteTimer marks the timer finished and closes its open session
+func (s *PostgresStore) CompleteTimer(ctx context.Context, timerID, status string, elapsed time.Duration) error {
+	if status != TimerCompleted && status != TimerStopped {
+		return fmt.Errorf("%w: %s", ErrInvalidStatus, status)
+	}
+	if !validUUID(timerID) {
+		return ErrNotFound
+	}
+
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE timers
+			SET status = $2,
+			    completed_at = COALESCE(completed_at, NOW()),
+			    updated_at = NOW()
+			WHERE id = $1 AND deleted_at IS NULL`, timerID, status)
+		if err != nil {
+			return fmt.Errorf("failed to update timer: %w", err)
+		}
+		if err := requireRow(result); err != nil {
+			return err
+		}
+
+		// Wall time the session was open but not counted as elapsed was spent paused
+		_, err = tx.ExecContext(ctx, `
+			UPDATE timer_sessions
+			SET ended_at = NOW(),
+			    duration = $2,
+			    paused_duration = GREATEST(EXTRACT(EPOCH FROM (NOW() - started_at))::INTEGER - $2, 0),
+			    updated_at = NOW()
+			WHERE timer_id = $1 AND ended_at IS NULL AND deleted_at IS NULL`, timerID, int(elapsed.Seconds()))
+		if err != nil {
+			return fmt.Errorf("failed to close timer session: %w", err)
+		}
+		return nil
+	})
+}
+
+// StartLaundry records a load
+func (s *PostgresStore) StartLaundry(ctx context.Context, load LaundryLoad) error {
+	if !validUUID(load.HouseholdID) || !validUUID(load.UserID) {
+		return ErrNotFound
+	}
+
+	settings, err := json.Marshal(load.Settings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal laundry settings: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO laundry_loads (id, household_id, user_id, load_type, status, settings, started_at)
+		VALUES ($1, $2, $3, $4, 'washing', $5, NOW())
+		ON CONFLICT (id) DO NOTHING`,
+		load.ID, load.HouseholdID, load.UserID, load.LoadType, settings)
+	if err != nil {
+		return fmt.Errorf("failed to insert laundry load: %w", err)
+	}
+	return nil
+}
+
+// CompleteLaundry records the times of a finished load
+func (s *PostgresStore) CompleteLaundry(ctx context.Context, laundryID string, result LaundryResult) error {
+	if result.Status != "completed" && result.Status != "cancelled" {
+		return fmt.Errorf("%w: %s", ErrInvalidStatus, result.Status)
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE laundry_loads
+		SET status = $2,
+		    wash_time = $3,
+		    dry_time = $4,
+		    total_time = $5,
+		    completed_at = COALESCE(completed_at, NOW()),
+		    updated_at = NOW()
+		WHERE id = $1`,
+		laundryID, result.Status,
+		int(result.WashTime.Seconds()), int(result.DryTime.Seconds()), int(result.TotalTime.Seconds()))
+	if err != nil {
+		return fmt.Errorf("failed to update laundry load: %w", err)
+	}
+	return requireRow(res)
+}
+
+// CreateTaskOccurrence creates a task from its recurring template, copying
+// the template's category, priority and estimated duration
+func (s *PostgresStore) CreateTaskOccurrence(ctx context.Context, occurrence TaskOccurrence) (string, bool, error) {
+	if !validUUID(occurrence.RecurringTaskID) {
+		return "", false, ErrNotFound
+	}
+
+	var taskID string
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO tasks (
+			title, description, category, priority, status, assigned_to, created_by,
+			household_id, due_date, recurrence_rule, estimated_duration, recurring_task_id, occurrence_id,
+			points, early_modifier, overdue_modifier
+		)
+		SELECT COALESCE(NULLIF($2, ''), t.title), COALESCE(NULLIF($3, ''), t.description),
+		       t.category, t.priority, 'pending', COALESCE($4::UUID, t.assigned_to),
+		       COALESCE($5::UUID, t.created_by), COALESCE($6::UUID, t.household_id),
+		       $7, NULL, t.estimated_duration, t.id, $8,
+		       t.points, t.early_modifier, t.overdue_modifier
+		FROM tasks t
+		WHERE t.id = $1 AND t.deleted_at IS NULL
+		ON CONFLICT (occurrence_id) DO NOTHING
+		RETURNING id`,
+		occurrence.RecurringTaskID, occurrence.Title, occurrence.Description, nullUUID(occurrence.AssignedTo),
+		nullUUID(occurrence.CreatedBy), nullUUID(occurrence.HouseholdID), occurrence.DueDate.UTC(), occurrence.OccurrenceID,
+	).Scan(&taskID)
+	if err == nil {
+		return taskID, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", false, fmt.Errorf("failed to insert task occurrence: %w", err)
+	}
+
+	// Nothing was inserted: either the occurrence exists already or the
+	// template is gone
+	err = s.db.QueryRowContext(ctx, `SELECT id FROM tasks WHERE occurrence_id = $1`, occurrence.OccurrenceID).Scan(&taskID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, ErrNotFound
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to look up task occurrence: %w", err)
+	}
+	return taskID, false, nil
+}
+
+// UpdateTaskStatus sets the status of a task
+func (s *PostgresStore) UpdateTaskStatus(ctx context.Context, taskID, status string) error {
+	switch status {
+	case TaskPending, TaskInProgress, TaskPendingReview, TaskCompleted, TaskCancelled:
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidStatus, status)
+	}
+
+	column := "occurrence_id"
+	if validUUID(taskID) {
+		column = "id"
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE tasks
+		SET status = $2,
+		    completed_at = CASE WHEN $3 THEN COALESCE(completed_at, NOW()) ELSE NULL END,
+		    updated_at = NOW()
+		WHERE `+column+` = $1 AND deleted_at IS NULL`, taskID, status, status == TaskCompleted)
+	if err != nil {
+		return fmt.Errorf("failed to update task: %w", err)
+	}
+	return requireRow(result)
+}
+
+// OccurrenceStatus returns the status of an occurrence's task
+func (s *PostgresStore) OccurrenceStatus(ctx context.Context, occurrenceID string) (string, error) {
+	var status string
+	var deleted bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT status, deleted_at IS NOT NULL
+		FROM tasks
+		WHERE occurrence_id = $1`, occurrenceID).Scan(&status, &deleted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query task occurrence: %w", err)
+	}
+	if deleted {
+		return TaskCancelled, nil
+	}
+	return status, nil
+}
+
+// OccurrenceAssignee returns who an occurrence's task is assigned to
+func (s *PostgresStore) OccurrenceAssignee(ctx context.Context, occurrenceID string) (string, error) {
+	var assignee sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+		SELECT assigned_to
+		FROM tasks
+		WHERE occurrence_id = $1`, occurrenceID).Scan(&assignee)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query task occurrence: %w", err)
+	}
+	return assignee.String, nil
+}
+
+// AssignmentPool loads a recurring task and those of the given members still
+// in its household. A member's load is the estimated minutes of their open
+// tasks in the household, counting tasks without an estimate at
+// assignment.DefaultEstimatedMinutes.
+func (s *PostgresStore) AssignmentPool(ctx context.Context, recurringTaskID string, members []string, at time.Time) (AssignmentPool, error) {
+	var pool AssignmentPool
+	if !validUUID(recurringTaskID) {
+		return pool, ErrNotFound
+	}
+
+	var householdID string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT household_id, category, COALESCE(estimated_duration, 0),
+		       (SELECT COUNT(*) FROM tasks o WHERE o.recurring_task_id = t.id)
+		FROM tasks t
+		WHERE id = $1 AND deleted_at IS NULL`, recurringTaskID,
+	).Scan(&householdID, &pool.Task.Category, &pool.Task.EstimatedMinutes, &pool.Occurrences)
+	if errors.Is(err, sql.ErrNoRows) {
+		return pool, ErrNotFound
+	}
+	if err != nil {
+		return pool, fmt.Errorf("failed to query recurring task: %w", err)
+	}
+
+	var ids []string
+	for _, member := range members {
+		if validUUID(member) {
+			ids = append(ids, member)
+		}
+	}
+	if len(ids) == 0 {
+		return pool, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT m.user_id, m.role,
+		       COALESCE(DATE_PART('year', AGE($3::DATE, m.date_of_birth))::INT, 0),
+		       COALESCE(m.assignment_weight, 0),
+		       m.excluded_categories, m.preferred_categories,
+		       COALESCE((
+		           SELECT SUM(COALESCE(t.estimated_duration, $4))
+		           FROM tasks t
+		           WHERE t.assigned_to = m.user_id AND t.household_id = m.household_id
+		             AND t.status IN ('pending', 'in_progress') AND t.deleted_at IS NULL
+		       ), 0),
+		       EXISTS (
+		           SELECT 1
+		           FROM away_periods a
+		           WHERE a.household_id = m.household_id AND (a.user_id IS NULL OR a.user_id = m.user_id)
+		             AND a.deleted_at IS NULL AND a.starts_at <= $3 AND a.ends_at > $3
+		       )
+		FROM household_members m
+		WHERE m.household_id = $1 AND m.left_at IS NULL AND m.user_id = ANY($2::UUID[])`,
+		householdID, pq.Array(ids), at.UTC(), assignment.DefaultEstimatedMinutes)
+	if err != nil {
+		return pool, fmt.Errorf("failed to query assignment members: %w", err)
+	}
+	defer rows.Close()
+
+	byID := make(map[string]assignment.Member, len(ids))
+	for rows.Next() {
+		var member assignment.Member
+		var excluded, preferred pq.StringArray
+		err := rows.Scan(&member.UserID, &member.Role, &member.Age, &member.Weight,
+			&excluded, &preferred, &member.LoadMinutes, &member.Away)
+		if err != nil {
+			return pool, fmt.Errorf("failed to scan assignment member: %w", err)
+		}
+		member.ExcludedCategories = excluded
+		member.PreferredCategories = preferred
+		byID[member.UserID] = member
+	}
+	if err := rows.Err(); err != nil {
+		return pool, fmt.Errorf("failed to query assignment members: %w", err)
+	}
+
+	for _, id := range ids {
+		if member, ok := byID[id]; ok {
+			pool.Members = append(pool.Members, member)
+		}
+	}
+	return pool, nil
+}
+
+// ReassignOccurrence assigns an occurrence's open task to another member
+func (s *PostgresStore) ReassignOccurrence(ctx context.Context, occurrenceID, userID string) (string, error) {
+	if !validUUID(userID) {
+		return "", ErrNotFound
+	}
+
+	var taskID string
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE tasks
+		SET assigned_to = $2, updated_at = NOW()
+		WHERE occurrence_id = $1 AND deleted_at IS NULL AND status IN ('pending', 'in_progress')
+		RETURNING id`, occurrenceID, userID).Scan(&taskID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to reassign task occurrence: %w", err)
+	}
+	return taskID, nil
+}
+
+// RecordMissedChore records that an occurrence's open task was missed by its
+// assignee. A miss is recorded once per occurrence, so retries and a task
+// reassigned after the miss do not record it again.
+func (s *PostgresStore) RecordMissedChore(ctx context.Context, chore MissedChore) (bool, error) {
+	if !validUUID(chore.UserID) {
+		return false, nil
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO missed_chores (household_id, user_id, task_id, occurrence_id, title, category, due_date, reminders)
+		SELECT household_id, $2, id, occurrence_id, title, category, due_date, $3
+		FROM tasks
+		WHERE occurrence_id = $1 AND deleted_at IS NULL AND status IN ('pending', 'in_progress')
+		ON CONFLICT (occurrence_id) DO NOTHING`, chore.OccurrenceID, chore.UserID, chore.Reminders)
+	if err != nil {
+		return false, fmt.Errorf("failed to record missed chore: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// HouseholdAdmins returns the IDs of a household's current admins, longest
+// serving first
+func (s *PostgresStore) HouseholdAdmins(ctx context.Context, householdID string) ([]string, error) {
+	if !validUUID(householdID) {
+		return nil, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT user_id
+		FROM household_members
+		WHERE household_id = $1 AND role = 'admin' AND left_at IS NULL
+		ORDER BY joined_at`, householdID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query household admins: %w", err)
+	}
+	defer rows.Close()
+
+	var admins []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan household admin: %w", err)
+		}
+		admins = append(admins, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query household admins: %w", err)
+	}
+	return admins, nil
+}
+
+// AwayMembers returns the IDs of a household's members who are away at the
+// given time, on their own or with the whole household
+func (s *PostgresStore) AwayMembers(ctx context.Context, householdID string, at time.Time) ([]string, error) {
+	if !validUUID(householdID) {
+		return nil, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT m.user_id
+		FROM household_members m
+		JOIN away_periods a ON a.household_id = m.household_id
+			AND (a.user_id IS NULL OR a.user_id = m.user_id)
+		WHERE m.household_id = $1 AND m.left_at IS NULL
+			AND a.deleted_at IS NULL AND a.starts_at <= $2 AND a.ends_at > $2`,
+		householdID, at.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query away members: %w", err)
+	}
+	defer rows.Close()
+
+	var members []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan away member: %w", err)
+		}
+		members = append(members, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query away members: %w", err)
+	}
+	return members, nil
+}
+
+// AwayUntil returns when a member who is away at the given time returns; it
+// is zero when the member is not away
+func (s *PostgresStore) AwayUntil(ctx context.Context, householdID, userID string, at time.Time) (time.Time, error) {
+	if !validUUID(householdID) || !validUUID(userID) {
+		return time.Time{}, nil
+	}
+
+	var until sql.NullTime
+	err := s.db.QueryRowContext(ctx, `
+		SELECT MAX(ends_at)
+		FROM away_periods
+		WHERE household_id = $1 AND (user_id IS NULL OR user_id = $2)
+			AND deleted_at IS NULL AND starts_at <= $3 AND ends_at > $3`,
+		householdID, userID, at.UTC()).Scan(&until)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to query away period: %w", err)
+	}
+	if !until.Valid {
+		return time.Time{}, nil
+	}
+	return until.Time, nil
+}
+
+// HouseholdAwayPeriods returns the periods the whole household is away that
+// have not ended at the given time
+func (s *PostgresStore) HouseholdAwayPeriods(ctx context.Context, householdID string, at time.Time) ([]HouseholdAwayPeriod, error) {
+	if !validUUID(householdID) {
+		return nil, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, starts_at, ends_at
+		FROM away_periods
+		WHERE household_id = $1 AND user_id IS NULL
+			AND deleted_at IS NULL AND ends_at > $2
+		ORDER BY starts_at`,
+		householdID, at.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query away periods: %w", err)
+	}
+	defer rows.Close()
+
+	var periods []HouseholdAwayPeriod
+	for rows.Next() {
+		var period HouseholdAwayPeriod
+		if err := rows.Scan(&period.ID, &period.StartsAt, &period.EndsAt); err != nil {
+			return nil, fmt.Errorf("failed to scan away period: %w", err)
+		}
+		periods = append(periods, period)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query away periods: %w", err)
+	}
+	return periods, nil
+}
+
+// BillStatus returns the status of a bill
+func (s *PostgresStore) BillStatus(ctx context.Context, billID string) (string, error) {
+	if !validUUID(billID) {
+		return "", ErrNotFound
+	}
+
+	var status string
+	var deleted bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT status, deleted_at IS NOT NULL
+		FROM bills
+		WHERE id = $1`, billID).Scan(&status, &deleted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query bill: %w", err)
+	}
+	if deleted {
+		return BillCancelled, nil
+	}
+	return status, nil
+}
+
+// MarkBillOverdue flips a pending bill that has reached its due date to
+// overdue, so a bill whose due date was moved later stays pending
+func (s *PostgresStore) MarkBillOverdue(ctx context.Context, billID string) (bool, error) {
+	if !validUUID(billID) {
+		return false, ErrNotFound
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE bills
+		SET status = 'overdue', updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL AND status = 'pending' AND due_date <= NOW()`, billID)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark bill overdue: %w", err)
+	}
+	if err := requireRow(result); errors.Is(err, ErrNotFound) {
+		// Retries find the bill already overdue
+		status, err := s.BillStatus(ctx, billID)
+		if err != nil {
+			return false, err
+		}
+		return status == BillOverdue, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// billInstanceColumns are the columns scanned by scanBillInstance
+const billInstanceColumns = `id, series_id, sequence, household_id, created_by,
+		COALESCE(assigned_to::TEXT, ''), name, amount, amount_type, currency, due_date, reminder_days`
+
+// scanBillInstance scans a row of billInstanceColumns
+func scanBillInstance(row *sql.Row) (BillInstance, error) {
+	var instance BillInstance
+	var reminderDays sql.NullInt64
+	err := row.Scan(&instance.ID, &instance.SeriesID, &instance.Sequence, &instance.HouseholdID, &instance.CreatedBy,
+		&instance.AssignedTo, &instance.Name, &instance.Amount, &instance.AmountType, &instance.Currency,
+		&instance.DueDate, &reminderDays)
+	if err != nil {
+		return BillInstance{}, err
+	}
+	if reminderDays.Valid {
+		days := int(reminderDays.Int64)
+		instance.ReminderDays = &days
+	}
+	return instance, nil
+}
+
+// CreateNextBillInstance creates the next instance of a bill's series from
+// the series template. It is due one recurrence after the bill; an
+// estimated amount is the average paid for the last three instances.
+func (s *PostgresStore) CreateNextBillInstance(ctx context.Context, billID string) (BillInstance, bool, error) {
+	if !validUUID(billID) {
+		return BillInstance{}, false, ErrNotFound
+	}
+
+	var next BillInstance
+	var created bool
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var (
+			seriesID, amountType, rule string
+			sequence                   int
+			due, firstDue              time.Time
+			amount                     sql.NullFloat64
+		)
+		// Locking the series serializes instances created for it. Monthly
+		// series stay on the day of the month of their first instance.
+		err := tx.QueryRowContext(ctx, `
+			SELECT s.id, b.sequence, b.due_date, s.amount_type, s.amount, s.recurrence_rule,
+			       COALESCE((
+			           SELECT f.due_date FROM bills f
+			           WHERE f.series_id = s.id AND f.sequence IS NOT NULL
+			           ORDER BY f.sequence
+			           LIMIT 1
+			       ), b.due_date)
+			FROM bills b
+			JOIN bill_series s ON s.id = b.series_id
+			WHERE b.id = $1 AND b.sequence IS NOT NULL
+			  AND s.ended_at IS NULL AND s.deleted_at IS NULL
+			FOR UPDATE OF s`, billID).Scan(&seriesID, &sequence, &due, &amountType, &amount, &rule, &firstDue)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to query bill series: %w", err)
+		}
+
+		next, err = scanBillInstance(tx.QueryRowContext(ctx, `
+			SELECT `+billInstanceColumns+`
+			FROM bills
+			WHERE series_id = $1 AND sequence = $2`, seriesID, sequence+1))
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to look up next bill instance: %w", err)
+		}
+
+		nextDue, err := nextBillDueDate(rule, due, firstDue.Day())
+		if err != nil {
+			return err
+		}
+
+		switch amountType {
+		case BillAmountOpen:
+			amount = sql.NullFloat64{Valid: true}
+		case BillAmountEstimated:
+			var estimate sql.NullFloat64
+			err := tx.QueryRowContext(ctx, `
+				SELECT AVG(paid_amount)
+				FROM (
+					SELECT paid_amount
+					FROM bills
+					WHERE series_id = $1 AND paid_amount IS NOT NULL
+					ORDER BY sequence DESC
+					LIMIT 3
+				) recent`, seriesID).Scan(&estimate)
+			if err != nil {
+				return fmt.Errorf("failed to estimate bill amount: %w", err)
+			}
+			if estimate.Valid {
+				amount = estimate
+			}
+		}
+		if !amount.Valid {
+			amount = sql.NullFloat64{Valid: true}
+		}
+
+		next, err = scanBillInstance(tx.QueryRowContext(ctx, `
+			INSERT INTO bills (
+				name, description, category, amount, currency, due_date, is_recurring, recurrence_rule,
+				status, household_id, assigned_to, created_by, reminder_days, series_id, sequence, amount_type
+			)
+			SELECT s.name, COALESCE(s.description, ''), s.category, ROUND($2::NUMERIC, 2), s.currency, $3, TRUE,
+			       s.recurrence_rule, 'pending', s.household_id, s.assigned_to, s.created_by, s.reminder_days,
+			       s.id, $4, s.amount_type
+			FROM bill_series s
+			WHERE s.id = $1
+			RETURNING `+billInstanceColumns, seriesID, amount.Float64, nextDue.UTC(), sequence+1))
+		if err != nil {
+			return fmt.Errorf("failed to insert bill instance: %w", err)
+		}
+		created = true
+		return nil
+	})
+	if err != nil {
+		return BillInstance{}, false, err
+	}
+	return next, created, nil
+}
+
+// HouseholdDigest gathers a household's digest. Overdue tasks and bills due
+// within the week match the API's overdue and upcoming queries, but for the
+// whole household and bounded by the household's day rather than the
+// current time.
+func (s *PostgresStore) HouseholdDigest(ctx context.Context, householdID string, now time.Time) (HouseholdDigest, error) {
+	if !validUUID(householdID) {
+		return HouseholdDigest{}, ErrNotFound
+	}
+
+	digest := HouseholdDigest{HouseholdID: householdID}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT name, COALESCE(timezone, 'UTC')
+		FROM households
+		WHERE id = $1 AND deleted_at IS NULL`, householdID).Scan(&digest.Name, &digest.Timezone)
+	if errors.Is(err, sql.ErrNoRows) {
+		return HouseholdDigest{}, ErrNotFound
+	}
+	if err != nil {
+		return HouseholdDigest{}, fmt.Errorf("failed to query household: %w", err)
+	}
+
+	loc, err := time.LoadLocation(digest.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	year, month, day := now.In(loc).Date()
+	digest.Date = time.Date(year, month, day, 0, 0, 0, 0, loc)
+	tomorrow := digest.Date.AddDate(0, 0, 1)
+	weekEnd := digest.Date.AddDate(0, 0, 7)
+
+	if digest.Members, err = s.digestMembers(ctx, householdID); err != nil {
+		return HouseholdDigest{}, err
+	}
+
+	digest.TasksToday, err = s.digestTasks(ctx, loc, `
+		WHERE household_id = $1 AND deleted_at IS NULL
+		  AND status IN ('pending', 'in_progress')
+		  AND due_date >= $2 AND due_date < $3`, householdID, digest.Date.UTC(), tomorrow.UTC())
+	if err != nil {
+		return HouseholdDigest{}, err
+	}
+	digest.OverdueTasks, err = s.digestTasks(ctx, loc, `
+		WHERE household_id = $1 AND deleted_at IS NULL
+		  AND status = 'pending' AND due_date < $2`, householdID, digest.Date.UTC())
+	if err != nil {
+		return HouseholdDigest{}, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, name, amount, currency, status, due_date
+		FROM bills
+		WHERE household_id = $1 AND deleted_at IS NULL
+		  AND status IN ('pending', 'overdue') AND due_date < $2
+		ORDER BY due_date
+		LIMIT 100`, householdID, weekEnd.UTC())
+	if err != nil {
+		return HouseholdDigest{}, fmt.Errorf("failed to query upcoming bills: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var bill DigestBill
+		if err := rows.Scan(&bill.ID, &bill.Name, &bill.Amount, &bill.Currency, &bill.Status, &bill.DueDate); err != nil {
+			return HouseholdDigest{}, fmt.Errorf("failed to scan bill: %w", err)
+		}
+		bill.DueDate = bill.DueDate.In(loc)
+		digest.BillsDue = append(digest.BillsDue, bill)
+	}
+	if err := rows.Err(); err != nil {
+		return HouseholdDigest{}, fmt.Errorf("failed to query upcoming bills: %w", err)
+	}
+
+	err = s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM shopping_items i
+		JOIN shopping_lists l ON l.id = i.list_id
+		WHERE l.household_id = $1 AND l.deleted_at IS NULL
+		  AND i.deleted_at IS NULL AND NOT COALESCE(i.is_purchased, FALSE)`, householdID).Scan(&digest.ShoppingItems)
+	if err != nil {
+		return HouseholdDigest{}, fmt.Errorf("failed to count shopping items: %w", err)
+	}
+
+	return digest, nil
+}
+
+// digestMembers returns a household's current members with their
+// notification preferences, longest serving first. Members without a
+// profile get the profile defaults.
+func (s *PostgresStore) digestMembers(ctx context.Context, householdID string) ([]DigestMember, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT m.user_id, u.first_name, u.email, m.role, COALESCE(p.language, 'en'),
+		       COALESCE(p.notifications_enabled, TRUE), COALESCE(p.push_notifications, TRUE),
+		       COALESCE(p.email_notifications, TRUE), COALESCE(p.email_digest, 'off')
+		FROM household_members m
+		JOIN users u ON u.id = m.user_id
+		LEFT JOIN user_profiles p ON p.user_id = m.user_id
+		WHERE m.household_id = $1 AND m.left_at IS NULL AND u.deleted_at IS NULL
+		ORDER BY m.joined_at`, householdID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query household members: %w", err)
+	}
+	defer rows.Close()
+
+	var members []DigestMember
+	for rows.Next() {
+		var m DigestMember
+		err := rows.Scan(&m.UserID, &m.FirstName, &m.Email, &m.Role, &m.Language,
+			&m.NotificationsEnabled, &m.PushNotifications, &m.EmailNotifications, &m.EmailDigest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan household member: %w", err)
+		}
+		members = append(members, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query household members: %w", err)
+	}
+	return members, nil
+}
+
+// digestTasks returns the tasks matching where, earliest due first, with
+// their due dates in loc
+func (s *PostgresStore) digestTasks(ctx context.Context, loc *time.Location, where string, args ...interface{}) ([]DigestTask, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, title, COALESCE(assigned_to::TEXT, ''), due_date
+		FROM tasks `+where+`
+		ORDER BY due_date
+		LIMIT 100`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var tasks []DigestTask
+	for rows.Next() {
+		var task DigestTask
+		if err := rows.Scan(&task.ID, &task.Title, &task.AssignedTo, &task.DueDate); err != nil {
+			return nil, fmt.Errorf("failed to scan task: %w", err)
+		}
+		task.DueDate = task.DueDate.In(loc)
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query tasks: %w", err)
+	}
+	return tasks, nil
+}
+
+// WebhookEndpoints returns the IDs of a household's active endpoints
+// subscribed to eventType
+func (s *PostgresStore) WebhookEndpoints(ctx context.Context, householdID, eventType string) ([]string, error) {
+	if !validUUID(householdID) {
+		return nil, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id
+		FROM webhook_endpoints
+		WHERE household_id = $1 AND $2 = ANY(event_types)
+		  AND active AND deleted_at IS NULL
+		ORDER BY created_at`, householdID, eventType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook endpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook endpoint: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query webhook endpoints: %w", err)
+	}
+	return ids, nil
+}
+
+// WebhookEndpoint returns an active endpoint
+func (s *PostgresStore) WebhookEndpoint(ctx context.Context, endpointID string) (WebhookEndpoint, error) {
+	if !validUUID(endpointID) {
+		return WebhookEndpoint{}, ErrNotFound
+	}
+
+	endpoint := WebhookEndpoint{ID: endpointID}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT url, secret
+		FROM webhook_endpoints
+		WHERE id = $1 AND active AND deleted_at IS NULL`, endpointID).Scan(&endpoint.URL, &endpoint.Secret)
+	if errors.Is(err, sql.ErrNoRows) {
+		return WebhookEndpoint{}, ErrNotFound
+	}
+	if err != nil {
+		return WebhookEndpoint{}, fmt.Errorf("failed to query webhook endpoint: %w", err)
+	}
+	return endpoint, nil
+}
+
+// RecordWebhookAttempt logs a delivery attempt
+func (s *PostgresStore) RecordWebhookAttempt(ctx context.Context, attempt WebhookAttempt) error {
+	var statusCode, attemptError interface{}
+	if attempt.StatusCode != 0 {
+		statusCode = attempt.StatusCode
+	}
+	if attempt.Error != "" {
+		attemptError = attempt.Error
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (endpoint_id, event_id, event_type, attempt, status_code, error, duration_ms)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		attempt.EndpointID, attempt.EventID, attempt.EventType, attempt.Attempt,
+		statusCode, attemptError, attempt.Duration.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("failed to insert webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// ResetWebhookFailures clears the consecutive failures of an endpoint
+func (s *PostgresStore) ResetWebhookFailures(ctx context.Context, endpointID string) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE webhook_endpoints
+		SET failure_count = 0, updated_at = NOW()
+		WHERE id = $1 AND failure_count > 0`, endpointID)
+	if err != nil {
+		return fmt.Errorf("failed to reset webhook failures: %w", err)
+	}
+	return nil
+}
+
+// RecordWebhookFailure counts a failed delivery and disables the endpoint at
+// threshold consecutive failures
+func (s *PostgresStore) RecordWebhookFailure(ctx context.Context, endpointID string, threshold int) (bool, error) {
+	if !validUUID(endpointID) {
+		return false, ErrNotFound
+	}
+
+	var disabled bool
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE webhook_endpoints
+		SET failure_count = failure_count + 1,
+		    active = active AND failure_count + 1 < $2,
+		    disabled_at = CASE WHEN active AND failure_count + 1 >= $2 THEN NOW() ELSE disabled_at END,
+		    updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING NOT active`, endpointID, threshold).Scan(&disabled)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, ErrNotFound
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to record webhook failure: %w", err)
+	}
+	return disabled, nil
+}
+
+// withTx runs fn in a transaction
+func (s *PostgresStore) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// requireRow returns ErrNotFound if the statement changed no rows
+func requireRow(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to read affected rows: %w", err)
+	}
+	if rows == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// validUUID reports whether id can be compared with a UUID column
+func validUUID(id string) bool {
+	_, err := uuid.Parse(id)
+	return err == nil
+}
+
+// nullUUID returns id, or nil so the column falls back to its default when
+// id is empty or not a UUID
+func nullUUID(id string) interface{} {
+	if !validUUID(id) {
+		return nil
+	}
+	return id
+}
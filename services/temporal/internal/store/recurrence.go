@@ -0,0 +1,45 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// nextBillDueDate returns the due date of the bill instance after one due
+// at due. Monthly rules fall on anchorDay, the day of the month the series
+// started on, clamped to the month's length; a series started on the 30th
+// is due on February 28 and on March 30 again.
+func nextBillDueDate(rule string, due time.Time, anchorDay int) (time.Time, error) {
+	switch rule {
+	case "daily":
+		return due.AddDate(0, 0, 1), nil
+	case "weekly":
+		return due.AddDate(0, 0, 7), nil
+	case "biweekly":
+		return due.AddDate(0, 0, 14), nil
+	case "monthly":
+		return addMonths(due, 1, anchorDay), nil
+	case "quarterly":
+		return addMonths(due, 3, anchorDay), nil
+	case "yearly":
+		return addMonths(due, 12, anchorDay), nil
+	default:
+		return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidRecurrence, rule)
+	}
+}
+
+// addMonths adds months to t and moves it to day, or to the last day of
+// the month when the month is shorter
+func addMonths(t time.Time, months, day int) time.Time {
+	hour, min, sec := t.Clock()
+	year, month, _ := time.Date(t.Year(), t.Month()+time.Month(months), 1, 0, 0, 0, 0, t.Location()).Date()
+	if last := daysIn(year, month); day > last {
+		day = last
+	}
+	return time.Date(year, month, day, hour, min, sec, t.Nanosecond(), t.Location())
+}
+
+// daysIn returns the number of days of a month
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNextBillDueDate(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 9, 30, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		rule   string
+		due    time.Time
+		anchor int
+		want   time.Time
+	}{
+		{"daily", date(2026, 12, 31), 31, date(2027, 1, 1)},
+		{"weekly", date(2026, 3, 2), 2, date(2026, 3, 9)},
+		{"biweekly", date(2026, 3, 2), 2, date(2026, 3, 16)},
+		{"monthly", date(2026, 1, 15), 15, date(2026, 2, 15)},
+		{"monthly", date(2026, 1, 31), 31, date(2026, 2, 28)},
+		{"monthly", date(2026, 2, 28), 31, date(2026, 3, 31)},
+		{"monthly", date(2026, 1, 30), 30, date(2026, 2, 28)},
+		// A series started on the 30th goes back to the 30th after February
+		{"monthly", date(2026, 2, 28), 30, date(2026, 3, 30)},
+		{"monthly", date(2026, 3, 30), 30, date(2026, 4, 30)},
+		// A series started on the 28th stays there
+		{"monthly", date(2026, 2, 28), 28, date(2026, 3, 28)},
+		{"quarterly", date(2026, 11, 30), 30, date(2027, 2, 28)},
+		{"quarterly", date(2027, 2, 28), 30, date(2027, 5, 30)},
+		{"yearly", date(2028, 2, 29), 29, date(2029, 2, 28)},
+		{"yearly", date(2029, 2, 28), 29, date(2030, 2, 28)},
+		{"yearly", date(2031, 2, 28), 29, date(2032, 2, 29)},
+	}
+	for _, tt := range tests {
+		got, err := nextBillDueDate(tt.rule, tt.due, tt.anchor)
+		if err != nil {
+			t.Fatalf("nextBillDueDate(%s, %s, %d) error = %v", tt.rule, tt.due, tt.anchor, err)
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("nextBillDueDate(%s, %s, %d) = %s, want %s", tt.rule, tt.due.Format("2006-01-02"), tt.anchor, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+		}
+	}
+
+	if _, err := nextBillDueDate("FREQ=MONTHLY", date(2026, 1, 1), 1); !errors.Is(err, ErrInvalidRecurrence) {
+		t.Errorf("unsupported rule error = %v, want ErrInvalidRecurrence", err)
+	}
+}
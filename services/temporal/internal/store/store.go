@@ -0,0 +1,289 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/househelper/temporal/internal/assignment"
+)
+
+var (
+	// ErrNotFound is returned when a timer, task or laundry load does not exist
+	ErrNotFound = errors.New("not found")
+
+	// ErrInvalidStatus is returned for a status the schema does not allow
+	ErrInvalidStatus = errors.New("invalid status")
+
+	// ErrInvalidRecurrence is returned for a bill series recurrence rule
+	// that is not supported
+	ErrInvalidRecurrence = errors.New("invalid recurrence rule")
+)
+
+// Task statuses allowed by the tasks table
+const (
+	TaskPending       = "pending"
+	TaskInProgress    = "in_progress"
+	TaskPendingReview = "pending_review" // Completed by a child, awaiting an admin
+	TaskCompleted     = "completed"
+	TaskCancelled     = "cancelled"
+)
+
+// Bill statuses allowed by the bills table
+const (
+	BillPending   = "pending"
+	BillPaid      = "paid"
+	BillOverdue   = "overdue"
+	BillCancelled = "cancelled"
+)
+
+// RoleChild is the household role that may not view bills
+const RoleChild = "child"
+
+// Amount types of a bill series
+const (
+	BillAmountFixed     = "fixed"
+	BillAmountEstimated = "estimated"
+	BillAmountOpen      = "open"
+)
+
+// Timer statuses a workflow may finish with
+const (
+	TimerCompleted = "completed"
+	TimerStopped   = "stopped"
+)
+
+// LaundryLoad is a load tracked by the laundry workflow
+type LaundryLoad struct {
+	ID          string
+	HouseholdID string
+	UserID      string
+	LoadType    string
+	Settings    interface{}
+}
+
+// LaundryResult holds the times of a finished load
+type LaundryResult struct {
+	Status    string
+	WashTime  time.Duration
+	DryTime   time.Duration
+	TotalTime time.Duration
+}
+
+// TaskOccurrence is a task created from a recurring task template
+type TaskOccurrence struct {
+	OccurrenceID    string
+	RecurringTaskID string
+	Title           string
+	Description     string
+	AssignedTo      string
+	CreatedBy       string
+	HouseholdID     string
+	DueDate         time.Time
+}
+
+// AssignmentPool is what the assignee of a recurring task's next occurrence
+// is picked from
+type AssignmentPool struct {
+	Task        assignment.Task
+	Members     []assignment.Member // In the order they were asked for
+	Occurrences int                 // Occurrences created so far
+}
+
+// BillInstance is an instance of a recurring bill series
+type BillInstance struct {
+	ID           string
+	SeriesID     string
+	Sequence     int
+	HouseholdID  string
+	CreatedBy    string
+	AssignedTo   string
+	Name         string
+	Amount       float64
+	AmountType   string
+	Currency     string
+	DueDate      time.Time
+	ReminderDays *int
+}
+
+// HouseholdDigest is what a household's morning digest reports. Times are
+// in the household's timezone.
+type HouseholdDigest struct {
+	HouseholdID string
+	Name        string
+	Timezone    string
+	Date        time.Time // Start of the day the digest is for
+	Members     []DigestMember
+
+	TasksToday    []DigestTask // Open tasks due today
+	OverdueTasks  []DigestTask // Pending tasks due before today
+	BillsDue      []DigestBill // Unpaid bills due within the week
+	ShoppingItems int          // Items left to buy on the household's lists
+}
+
+// DigestMember is a household member with their notification preferences
+type DigestMember struct {
+	UserID               string
+	FirstName            string
+	Email                string
+	Role                 string // admin, member, guest or child
+	Language             string
+	NotificationsEnabled bool
+	PushNotifications    bool
+	EmailNotifications   bool
+	EmailDigest          string // off, daily or weekly
+}
+
+// SeesBills reports whether the member's role may view bills
+func (m DigestMember) SeesBills() bool {
+	return m.Role != RoleChild
+}
+
+// DigestTask is a task listed in a digest
+type DigestTask struct {
+	ID         string
+	Title      string
+	AssignedTo string
+	DueDate    time.Time
+}
+
+// DigestBill is a bill listed in a digest
+type DigestBill struct {
+	ID       string
+	Name     string
+	Amount   float64
+	Currency string
+	Status   string
+	DueDate  time.Time
+}
+
+// WebhookEndpoint is an active endpoint a household's events are delivered to
+type WebhookEndpoint struct {
+	ID     string
+	URL    string
+	Secret string
+}
+
+// WebhookAttempt is one attempt to deliver an event to an endpoint
+type WebhookAttempt struct {
+	EndpointID string
+	EventID    string
+	EventType  string
+	Attempt    int
+	StatusCode int // zero when no response was received
+	Error      string
+	Duration   time.Duration
+}
+
+// HouseholdAwayPeriod is a period the whole household is away
+type HouseholdAwayPeriod struct {
+	ID       string
+	StartsAt time.Time
+	EndsAt   time.Time
+}
+
+// MissedChore is a task whose assignee ignored every reminder
+type MissedChore struct {
+	OccurrenceID string
+	UserID       string // The assignee who was reminded
+	Reminders    int
+}
+
+// Store persists the data the workflows drive
+type Store interface {
+	// StartTimer marks the timer running and opens a session unless one is
+	// already open, so retries do not create duplicates
+	StartTimer(ctx context.Context, timerID string) error
+
+	// CompleteTimer marks the timer completed or stopped and closes its open
+	// session with the elapsed time
+	CompleteTimer(ctx context.Context, timerID, status string, elapsed time.Duration) error
+
+	// StartLaundry records a load; starting a known load is a no-op
+	StartLaundry(ctx context.Context, load LaundryLoad) error
+
+	// CompleteLaundry records the times of a finished load
+	CompleteLaundry(ctx context.Context, laundryID string, result LaundryResult) error
+
+	// CreateTaskOccurrence creates the task of an occurrence and returns its
+	// ID; created is false if the occurrence already had a task
+	CreateTaskOccurrence(ctx context.Context, occurrence TaskOccurrence) (taskID string, created bool, err error)
+
+	// UpdateTaskStatus sets the status of a task, identified by its ID or
+	// occurrence ID
+	UpdateTaskStatus(ctx context.Context, taskID, status string) error
+
+	// OccurrenceStatus returns the status of an occurrence's task; deleted
+	// tasks are reported as cancelled
+	OccurrenceStatus(ctx context.Context, occurrenceID string) (string, error)
+
+	// OccurrenceAssignee returns who an occurrence's task is assigned to;
+	// unassigned tasks return an empty ID
+	OccurrenceAssignee(ctx context.Context, occurrenceID string) (string, error)
+
+	// AssignmentPool loads a recurring task and those of the given members
+	// still in its household, with their open load and whether they are away
+	// at the given time
+	AssignmentPool(ctx context.Context, recurringTaskID string, members []string, at time.Time) (AssignmentPool, error)
+
+	// ReassignOccurrence assigns an occurrence's open task to another member
+	// and returns the task's ID; finished or deleted tasks are not found
+	ReassignOccurrence(ctx context.Context, occurrenceID, userID string) (taskID string, err error)
+
+	// RecordMissedChore records that an occurrence's open task was missed by
+	// its assignee; recorded is false when the task was finished or deleted,
+	// or the miss was recorded already
+	RecordMissedChore(ctx context.Context, chore MissedChore) (recorded bool, err error)
+
+	// HouseholdAdmins returns the IDs of a household's current admins
+	HouseholdAdmins(ctx context.Context, householdID string) ([]string, error)
+
+	// AwayMembers returns the IDs of a household's members who are away at the
+	// given time, on their own or with the whole household
+	AwayMembers(ctx context.Context, householdID string, at time.Time) ([]string, error)
+
+	// AwayUntil returns when a member who is away at the given time returns;
+	// it is zero when the member is not away
+	AwayUntil(ctx context.Context, householdID, userID string, at time.Time) (time.Time, error)
+
+	// HouseholdAwayPeriods returns the periods the whole household is away
+	// that have not ended at the given time, earliest first
+	HouseholdAwayPeriods(ctx context.Context, householdID string, at time.Time) ([]HouseholdAwayPeriod, error)
+
+	// BillStatus returns the status of a bill; deleted bills are reported as
+	// cancelled
+	BillStatus(ctx context.Context, billID string) (string, error)
+
+	// MarkBillOverdue flips a pending bill that is due to overdue; marked is
+	// false when the bill was paid or cancelled, or its due date moved later,
+	// in the meantime
+	MarkBillOverdue(ctx context.Context, billID string) (marked bool, err error)
+
+	// CreateNextBillInstance creates the instance of a bill's series after the
+	// bill and returns it; created is false if it existed already. Bills that
+	// are not part of a series, or whose series ended, are not found.
+	CreateNextBillInstance(ctx context.Context, billID string) (next BillInstance, created bool, err error)
+
+	// HouseholdDigest gathers a household's digest for the day now falls on
+	// in the household's timezone; deleted households are not found
+	HouseholdDigest(ctx context.Context, householdID string, now time.Time) (HouseholdDigest, error)
+
+	// WebhookEndpoints returns the IDs of a household's active endpoints
+	// subscribed to eventType
+	WebhookEndpoints(ctx context.Context, householdID, eventType string) ([]string, error)
+
+	// WebhookEndpoint returns an endpoint; endpoints that were deleted or
+	// disabled are not found
+	WebhookEndpoint(ctx context.Context, endpointID string) (WebhookEndpoint, error)
+
+	// RecordWebhookAttempt logs a delivery attempt
+	RecordWebhookAttempt(ctx context.Context, attempt WebhookAttempt) error
+
+	// ResetWebhookFailures clears the consecutive failures of an endpoint
+	// after a successful delivery
+	ResetWebhookFailures(ctx context.Context, endpointID string) error
+
+	// RecordWebhookFailure counts a delivery that failed for good and disables
+	// the endpoint once threshold consecutive deliveries have failed
+	RecordWebhookFailure(ctx context.Context, endpointID string, threshold int) (disabled bool, err error)
+}
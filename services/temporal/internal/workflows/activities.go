@@ -2,24 +2,35 @@ package workflows
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/househelper/temporal/internal/store"
+	"github.com/househelper/temporal/pkg/events"
+	"github.com/househelper/temporal/pkg/notifier"
 	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
 )
 
 // Activity request and response types
 
 // StartTimerRequest represents the request to start a timer
 type StartTimerRequest struct {
-	TimerID string `json:"timerId"`
-	UserID  string `json:"userId"`
-	Name    string `json:"name"`
+	TimerID     string `json:"timerId"`
+	UserID      string `json:"userId"`
+	HouseholdID string `json:"householdId"`
+	Name        string `json:"name"`
 }
 
 // CompleteTimerRequest represents the request to complete a timer
 type CompleteTimerRequest struct {
 	TimerID     string        `json:"timerId"`
 	UserID      string        `json:"userId"`
+	HouseholdID string        `json:"householdId"`
+	Name        string        `json:"name"`
 	ElapsedTime time.Duration `json:"elapsedTime"`
 	Status      string        `json:"status"`
 }
@@ -35,12 +46,14 @@ type StartLaundryRequest struct {
 
 // CompleteLaundryRequest represents the request to complete laundry tracking
 type CompleteLaundryRequest struct {
-	LaundryID string        `json:"laundryId"`
-	UserID    string        `json:"userId"`
-	WashTime  time.Duration `json:"washTime"`
-	DryTime   time.Duration `json:"dryTime"`
-	TotalTime time.Duration `json:"totalTime"`
-	Status    string        `json:"status"`
+	LaundryID   string        `json:"laundryId"`
+	UserID      string        `json:"userId"`
+	HouseholdID string        `json:"householdId"`
+	LoadType    string        `json:"loadType"`
+	WashTime    time.Duration `json:"washTime"`
+	DryTime     time.Duration `json:"dryTime"`
+	TotalTime   time.Duration `json:"totalTime"`
+	Status      string        `json:"status"`
 }
 
 // NotificationRequest represents a notification to be sent
@@ -50,6 +63,7 @@ type NotificationRequest struct {
 	Title       string            `json:"title"`
 	Body        string            `json:"body"`
 	Data        map[string]string `json:"data"`
+	Priority    string            `json:"priority,omitempty"` // low, normal or high
 }
 
 // UpdateTaskRequest represents a request to update a task
@@ -71,108 +85,264 @@ type UpdateDeviceStateRequest struct {
 
 // Activities
 
-// StartTimerActivity records the start of a timer
-func StartTimerActivity(ctx context.Context, req StartTimerRequest) error {
+// Activities implements the activities that read and write household data:
+// they persist to Postgres, publish domain events to Kafka and push real-time
+// updates to connected clients through the notifier
+type Activities struct {
+	Store    store.Store
+	Notifier *notifier.Client
+
+	// Events is optional; without it no domain events are published
+	Events events.Publisher
+
+	// WebhookClient sends webhook deliveries; nil uses a client with a
+	// 10 second timeout that does not follow redirects
+	WebhookClient *http.Client
+}
+
+// StartTimerActivity marks the timer running and opens a timer session
+func (a *Activities) StartTimerActivity(ctx context.Context, req StartTimerRequest) error {
 	logger := activity.GetLogger(ctx)
 	logger.Info("Starting timer", "timerId", req.TimerID, "userId", req.UserID)
 
-	// Here you would typically:
-	// 1. Insert timer record into database
-	// 2. Update user's active timers
-	// 3. Send real-time update to connected clients
-
-	// Simulate database operation
-	activity.RecordHeartbeat(ctx, "Creating timer record")
+	if err := a.Store.StartTimer(ctx, req.TimerID); err != nil {
+		return storeError(err, "timer "+req.TimerID)
+	}
+
+	data := map[string]interface{}{
+		"timerId":     req.TimerID,
+		"userId":      req.UserID,
+		"householdId": req.HouseholdID,
+		"name":        req.Name,
+		"status":      "running",
+	}
+	if err := a.publish(ctx, events.TimerStarted, req.HouseholdID, req.UserID, data); err != nil {
+		return err
+	}
+	a.broadcast(ctx, req.HouseholdID, "timer_started", data)
 
-	// Mock implementation - in real scenario, this would interact with your API service
 	logger.Info("Timer started successfully", "timerId", req.TimerID)
 	return nil
 }
 
-// CompleteTimerActivity records the completion of a timer
-func CompleteTimerActivity(ctx context.Context, req CompleteTimerRequest) error {
+// CompleteTimerActivity closes the timer session and records how the timer ended
+func (a *Activities) CompleteTimerActivity(ctx context.Context, req CompleteTimerRequest) error {
 	logger := activity.GetLogger(ctx)
 	logger.Info("Completing timer", "timerId", req.TimerID, "status", req.Status, "elapsed", req.ElapsedTime)
 
-	// Here you would typically:
-	// 1. Update timer record in database with final status and elapsed time
-	// 2. Remove from user's active timers
-	// 3. Send completion event to analytics
-	// 4. Update household activity feed
-
-	activity.RecordHeartbeat(ctx, "Updating timer record")
-
-	logger.Info("Timer completed successfully", "timerId", req.TimerID)
+	status := store.TimerCompleted
+	eventType := events.TimerCompleted
+	if req.Status != store.TimerCompleted {
+		status = store.TimerStopped
+		eventType = events.TimerStopped
+	}
+
+	if err := a.Store.CompleteTimer(ctx, req.TimerID, status, req.ElapsedTime); err != nil {
+		return storeError(err, "timer "+req.TimerID)
+	}
+
+	data := map[string]interface{}{
+		"timerId":     req.TimerID,
+		"userId":      req.UserID,
+		"householdId": req.HouseholdID,
+		"name":        req.Name,
+		"elapsedTime": req.ElapsedTime,
+		"status":      status,
+	}
+	if err := a.publish(ctx, eventType, req.HouseholdID, req.UserID, data); err != nil {
+		return err
+	}
+	a.broadcast(ctx, req.HouseholdID, "timer_"+status, data)
+
+	logger.Info("Timer completed successfully", "timerId", req.TimerID, "status", status)
 	return nil
 }
 
-// StartLaundryActivity records the start of laundry tracking
-func StartLaundryActivity(ctx context.Context, req StartLaundryRequest) error {
+// StartLaundryActivity records a new laundry load
+func (a *Activities) StartLaundryActivity(ctx context.Context, req StartLaundryRequest) error {
 	logger := activity.GetLogger(ctx)
 	logger.Info("Starting laundry tracking", "laundryId", req.LaundryID, "loadType", req.LoadType)
 
-	// Here you would typically:
-	// 1. Insert laundry record into database
-	// 2. Update household laundry queue
-	// 3. Send real-time update to household members
-
-	activity.RecordHeartbeat(ctx, "Creating laundry record")
+	err := a.Store.StartLaundry(ctx, store.LaundryLoad{
+		ID:          req.LaundryID,
+		HouseholdID: req.HouseholdID,
+		UserID:      req.UserID,
+		LoadType:    req.LoadType,
+		Settings:    req.Settings,
+	})
+	if err != nil {
+		return storeError(err, "laundry load "+req.LaundryID)
+	}
+
+	data := map[string]interface{}{
+		"laundryId":   req.LaundryID,
+		"userId":      req.UserID,
+		"householdId": req.HouseholdID,
+		"loadType":    req.LoadType,
+		"status":      "washing",
+	}
+	if err := a.publish(ctx, events.LaundryStarted, req.HouseholdID, req.UserID, data); err != nil {
+		return err
+	}
+	a.broadcast(ctx, req.HouseholdID, "laundry_started", data)
 
 	logger.Info("Laundry tracking started successfully", "laundryId", req.LaundryID)
 	return nil
 }
 
-// CompleteLaundryActivity records the completion of laundry
-func CompleteLaundryActivity(ctx context.Context, req CompleteLaundryRequest) error {
+// CompleteLaundryActivity records the wash and dry times of a finished load
+func (a *Activities) CompleteLaundryActivity(ctx context.Context, req CompleteLaundryRequest) error {
 	logger := activity.GetLogger(ctx)
 	logger.Info("Completing laundry", "laundryId", req.LaundryID, "washTime", req.WashTime, "dryTime", req.DryTime)
 
-	// Here you would typically:
-	// 1. Update laundry record with completion times
-	// 2. Remove from active laundry queue
-	// 3. Update household statistics
-	// 4. Add to completed activities
-
-	activity.RecordHeartbeat(ctx, "Updating laundry record")
+	err := a.Store.CompleteLaundry(ctx, req.LaundryID, store.LaundryResult{
+		Status:    req.Status,
+		WashTime:  req.WashTime,
+		DryTime:   req.DryTime,
+		TotalTime: req.TotalTime,
+	})
+	if err != nil {
+		return storeError(err, "laundry load "+req.LaundryID)
+	}
+
+	data := map[string]interface{}{
+		"laundryId":   req.LaundryID,
+		"userId":      req.UserID,
+		"householdId": req.HouseholdID,
+		"loadType":    req.LoadType,
+		"status":      req.Status,
+		"washTime":    req.WashTime,
+		"dryTime":     req.DryTime,
+	}
+	if err := a.publish(ctx, events.LaundryCompleted, req.HouseholdID, req.UserID, data); err != nil {
+		return err
+	}
+	a.broadcast(ctx, req.HouseholdID, "laundry_completed", data)
 
 	logger.Info("Laundry completed successfully", "laundryId", req.LaundryID)
 	return nil
 }
 
-// SendNotificationActivity sends a push notification
-func SendNotificationActivity(ctx context.Context, req NotificationRequest) error {
+// UpdateTaskActivity updates a task status
+func (a *Activities) UpdateTaskActivity(ctx context.Context, req UpdateTaskRequest) error {
 	logger := activity.GetLogger(ctx)
-	logger.Info("Sending notification", "userId", req.UserID, "title", req.Title)
-
-	// Here you would typically:
-	// 1. Look up user's device tokens
-	// 2. Send push notification via FCM/APNS
-	// 3. Store notification in database for history
-	// 4. Send real-time notification via WebSocket
+	logger.Info("Updating task", "taskId", req.TaskID, "status", req.Status)
 
-	activity.RecordHeartbeat(ctx, "Sending push notification")
+	if err := a.Store.UpdateTaskStatus(ctx, req.TaskID, req.Status); err != nil {
+		return storeError(err, "task "+req.TaskID)
+	}
+
+	eventType := events.TaskUpdated
+	if req.Status == store.TaskCompleted {
+		eventType = events.TaskCompleted
+	}
+
+	data := map[string]interface{}{
+		"taskId":      req.TaskID,
+		"householdId": req.HouseholdID,
+		"status":      req.Status,
+		"completedBy": req.CompletedBy,
+	}
+	if err := a.publish(ctx, eventType, req.HouseholdID, req.UserID, data); err != nil {
+		return err
+	}
+	a.broadcast(ctx, req.HouseholdID, "task_updated", data)
 
-	// Mock notification sending
-	time.Sleep(100 * time.Millisecond) // Simulate network call
+	logger.Info("Task updated successfully", "taskId", req.TaskID)
+	return nil
+}
 
-	logger.Info("Notification sent successfully", "userId", req.UserID)
+// publish sends a domain event. The event ID is derived from the workflow
+// run and activity so a retried activity publishes the same event again
+// rather than a new one, letting consumers drop the duplicate.
+func (a *Activities) publish(ctx context.Context, eventType, householdID, userID string, data map[string]interface{}) error {
+	if a.Events == nil {
+		return nil
+	}
+
+	event := events.Event{
+		ID:          activityKey(ctx),
+		Type:        eventType,
+		HouseholdID: householdID,
+		UserID:      userID,
+		Data:        data,
+	}
+	if err := a.Events.Publish(ctx, event); err != nil {
+		return fmt.Errorf("failed to publish %s: %w", eventType, err)
+	}
 	return nil
 }
 
-// UpdateTaskActivity updates a task status
-func UpdateTaskActivity(ctx context.Context, req UpdateTaskRequest) error {
-	logger := activity.GetLogger(ctx)
-	logger.Info("Updating task", "taskId", req.TaskID, "status", req.Status)
+// broadcast pushes a real-time update to the household's connected clients.
+// Clients resync on reconnect, so a failed broadcast is logged rather than
+// retried.
+func (a *Activities) broadcast(ctx context.Context, householdID, messageType string, data map[string]interface{}) {
+	if a.Notifier == nil || householdID == "" {
+		return
+	}
+
+	err := a.Notifier.BroadcastToHousehold(ctx, householdID, notifier.Broadcast{Type: messageType, Data: data})
+	if err != nil {
+		activity.GetLogger(ctx).Warn("Failed to broadcast update", "type", messageType, "householdId", householdID, "error", err)
+	}
+}
 
-	// Here you would typically:
-	// 1. Update task status in database
-	// 2. Update household task statistics
-	// 3. Send real-time update to household members
-	// 4. Trigger any dependent workflows
+// activityKey identifies the current activity across its retries; it is
+// the ID of published events and the idempotency key of notifications
+func activityKey(ctx context.Context) string {
+	info := activity.GetInfo(ctx)
+	name := info.WorkflowExecution.ID + "/" + info.WorkflowExecution.RunID + "/" + info.ActivityID
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(name)).String()
+}
 
-	activity.RecordHeartbeat(ctx, "Updating task record")
+// storeError turns the store's permanent failures into non-retryable errors
+func storeError(err error, subject string) error {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return temporal.NewNonRetryableApplicationError(subject+" not found", "NotFound", err)
+	case errors.Is(err, store.ErrInvalidStatus):
+		return temporal.NewNonRetryableApplicationError(err.Error(), "InvalidStatus", err)
+	case errors.Is(err, store.ErrInvalidRecurrence):
+		return temporal.NewNonRetryableApplicationError(err.Error(), "InvalidRecurrence", err)
+	default:
+		return err
+	}
+}
 
-	logger.Info("Task updated successfully", "taskId", req.TaskID)
+// SendNotificationActivity queues a push notification for the user's devices
+// through the notifier. The notifier dedupes on the activity's key, so a
+// retry after a lost response does not notify the user twice.
+func (a *Activities) SendNotificationActivity(ctx context.Context, req NotificationRequest) error {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Sending notification", "userId", req.UserID, "title", req.Title)
+
+	if req.UserID == "" {
+		return temporal.NewNonRetryableApplicationError("notification has no recipient", "NoRecipient", nil)
+	}
+
+	receipts, err := a.Notifier.Notify(ctx, activityKey(ctx), notifier.Notification{
+		UserID:  req.UserID,
+		Title:   req.Title,
+		Body:    req.Body,
+		Data:    req.Data,
+		Urgency: req.Priority,
+	})
+
+	var statusErr *notifier.StatusError
+	if errors.As(err, &statusErr) {
+		if statusErr.NoDevices() {
+			return temporal.NewNonRetryableApplicationError("user has no registered devices", "NoDevices", err)
+		}
+		if !statusErr.Retryable() {
+			return temporal.NewNonRetryableApplicationError("notification rejected", "NotificationRejected", err)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	duplicate := len(receipts) > 0 && receipts[0].Duplicate
+	logger.Info("Notification queued", "userId", req.UserID, "devices", len(receipts), "duplicate", duplicate)
 	return nil
 }
 
@@ -211,22 +381,3 @@ func LogActivityActivity(ctx context.Context, activityData map[string]interface{
 	logger.Info("Activity logged successfully")
 	return nil
 }
-
-// SendWebhookActivity sends webhook notifications to external services
-func SendWebhookActivity(ctx context.Context, webhookURL string, payload map[string]interface{}) error {
-	logger := activity.GetLogger(ctx)
-	logger.Info("Sending webhook", "url", webhookURL)
-
-	// Here you would typically:
-	// 1. Make HTTP POST request to webhook URL
-	// 2. Handle retries and error responses
-	// 3. Log webhook delivery status
-
-	activity.RecordHeartbeat(ctx, "Sending webhook request")
-
-	// Mock webhook sending
-	time.Sleep(300 * time.Millisecond) // Simulate HTTP request
-
-	logger.Info("Webhook sent successfully", "url", webhookURL)
-	return nil
-}
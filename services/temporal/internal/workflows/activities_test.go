@@ -0,0 +1,871 @@
+package workflows
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/househelper/temporal/internal/assignment"
+	"github.com/househelper/temporal/internal/store"
+	"github.com/househelper/temporal/pkg/events"
+	"github.com/househelper/temporal/pkg/notifier"
+	"github.com/househelper/temporal/pkg/webhooks"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+type fakeStore struct {
+	timers      map[string]string
+	occurrences map[string]string
+	assignees   map[string]string
+	admins      map[string][]string
+	away        map[string]time.Time                   // Return time by member
+	awayPeriods map[string][]store.HouseholdAwayPeriod // Whole household away periods by household
+	bills       map[string]string
+	nextBills   map[string]store.BillInstance // Next instance by bill, for series bills
+	digests     map[string]store.HouseholdDigest
+	pools       map[string]store.AssignmentPool // Assignment pool by recurring task
+	missed      map[string]store.MissedChore    // Missed chores by occurrence
+
+	webhooks        map[string]store.WebhookEndpoint
+	webhookAttempts []store.WebhookAttempt
+	webhookFailures map[string]int
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		timers:          make(map[string]string),
+		occurrences:     make(map[string]string),
+		assignees:       make(map[string]string),
+		admins:          make(map[string][]string),
+		away:            make(map[string]time.Time),
+		awayPeriods:     make(map[string][]store.HouseholdAwayPeriod),
+		bills:           make(map[string]string),
+		nextBills:       make(map[string]store.BillInstance),
+		digests:         make(map[string]store.HouseholdDigest),
+		pools:           make(map[string]store.AssignmentPool),
+		missed:          make(map[string]store.MissedChore),
+		webhooks:        make(map[string]store.WebhookEndpoint),
+		webhookFailures: make(map[string]int),
+	}
+}
+
+func (s *fakeStore) StartTimer(ctx context.Context, timerID string) error {
+	if _, ok := s.timers[timerID]; !ok {
+		return store.ErrNotFound
+	}
+	s.timers[timerID] = "running"
+	return nil
+}
+
+func (s *fakeStore) CompleteTimer(ctx context.Context, timerID, status string, elapsed time.Duration) error {
+	if _, ok := s.timers[timerID]; !ok {
+		return store.ErrNotFound
+	}
+	s.timers[timerID] = status
+	return nil
+}
+
+func (s *fakeStore) StartLaundry(ctx context.Context, load store.LaundryLoad) error {
+	return nil
+}
+
+func (s *fakeStore) CompleteLaundry(ctx context.Context, laundryID string, result store.LaundryResult) error {
+	return nil
+}
+
+func (s *fakeStore) CreateTaskOccurrence(ctx context.Context, occurrence store.TaskOccurrence) (string, bool, error) {
+	if _, ok := s.occurrences[occurrence.OccurrenceID]; ok {
+		return "task-" + occurrence.OccurrenceID, false, nil
+	}
+	s.occurrences[occurrence.OccurrenceID] = store.TaskPending
+	s.assignees[occurrence.OccurrenceID] = occurrence.AssignedTo
+	return "task-" + occurrence.OccurrenceID, true, nil
+}
+
+func (s *fakeStore) OccurrenceAssignee(ctx context.Context, occurrenceID string) (string, error) {
+	if _, ok := s.occurrences[occurrenceID]; !ok {
+		return "", store.ErrNotFound
+	}
+	return s.assignees[occurrenceID], nil
+}
+
+func (s *fakeStore) AssignmentPool(ctx context.Context, recurringTaskID string, members []string, at time.Time) (store.AssignmentPool, error) {
+	pool, ok := s.pools[recurringTaskID]
+	if !ok {
+		return store.AssignmentPool{}, store.ErrNotFound
+	}
+	for i, member := range pool.Members {
+		pool.Members[i].Away = s.away[member.UserID].After(at)
+	}
+	return pool, nil
+}
+
+func (s *fakeStore) UpdateTaskStatus(ctx context.Context, taskID, status string) error {
+	return nil
+}
+
+func (s *fakeStore) OccurrenceStatus(ctx context.Context, occurrenceID string) (string, error) {
+	status, ok := s.occurrences[occurrenceID]
+	if !ok {
+		return "", store.ErrNotFound
+	}
+	return status, nil
+}
+
+func (s *fakeStore) ReassignOccurrence(ctx context.Context, occurrenceID, userID string) (string, error) {
+	status, ok := s.occurrences[occurrenceID]
+	if !ok || (status != store.TaskPending && status != store.TaskInProgress) {
+		return "", store.ErrNotFound
+	}
+	s.assignees[occurrenceID] = userID
+	return "task-" + occurrenceID, nil
+}
+
+func (s *fakeStore) RecordMissedChore(ctx context.Context, chore store.MissedChore) (bool, error) {
+	status, ok := s.occurrences[chore.OccurrenceID]
+	if !ok || (status != store.TaskPending && status != store.TaskInProgress) {
+		return false, nil
+	}
+	if _, ok := s.missed[chore.OccurrenceID]; ok {
+		return false, nil
+	}
+	s.missed[chore.OccurrenceID] = chore
+	return true, nil
+}
+
+func (s *fakeStore) HouseholdAdmins(ctx context.Context, householdID string) ([]string, error) {
+	return s.admins[householdID], nil
+}
+
+func (s *fakeStore) AwayMembers(ctx context.Context, householdID string, at time.Time) ([]string, error) {
+	var members []string
+	for userID, until := range s.away {
+		if until.After(at) {
+			members = append(members, userID)
+		}
+	}
+	return members, nil
+}
+
+func (s *fakeStore) AwayUntil(ctx context.Context, householdID, userID string, at time.Time) (time.Time, error) {
+	if until := s.away[userID]; until.After(at) {
+		return until, nil
+	}
+	return time.Time{}, nil
+}
+
+func (s *fakeStore) HouseholdAwayPeriods(ctx context.Context, householdID string, at time.Time) ([]store.HouseholdAwayPeriod, error) {
+	var periods []store.HouseholdAwayPeriod
+	for _, period := range s.awayPeriods[householdID] {
+		if period.EndsAt.After(at) {
+			periods = append(periods, period)
+		}
+	}
+	return periods, nil
+}
+
+func (s *fakeStore) BillStatus(ctx context.Context, billID string) (string, error) {
+	status, ok := s.bills[billID]
+	if !ok {
+		return "", store.ErrNotFound
+	}
+	return status, nil
+}
+
+func (s *fakeStore) MarkBillOverdue(ctx context.Context, billID string) (bool, error) {
+	status, ok := s.bills[billID]
+	if !ok {
+		return false, store.ErrNotFound
+	}
+	if status == store.BillPending {
+		s.bills[billID] = store.BillOverdue
+	}
+	return s.bills[billID] == store.BillOverdue, nil
+}
+
+func (s *fakeStore) CreateNextBillInstance(ctx context.Context, billID string) (store.BillInstance, bool, error) {
+	next, ok := s.nextBills[billID]
+	if !ok {
+		return store.BillInstance{}, false, store.ErrNotFound
+	}
+	_, exists := s.bills[next.ID]
+	s.bills[next.ID] = store.BillPending
+	return next, !exists, nil
+}
+
+func (s *fakeStore) HouseholdDigest(ctx context.Context, householdID string, now time.Time) (store.HouseholdDigest, error) {
+	digest, ok := s.digests[householdID]
+	if !ok {
+		return store.HouseholdDigest{}, store.ErrNotFound
+	}
+	return digest, nil
+}
+
+func (s *fakeStore) WebhookEndpoints(ctx context.Context, householdID, eventType string) ([]string, error) {
+	var ids []string
+	for id := range s.webhooks {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (s *fakeStore) WebhookEndpoint(ctx context.Context, endpointID string) (store.WebhookEndpoint, error) {
+	endpoint, ok := s.webhooks[endpointID]
+	if !ok {
+		return store.WebhookEndpoint{}, store.ErrNotFound
+	}
+	return endpoint, nil
+}
+
+func (s *fakeStore) RecordWebhookAttempt(ctx context.Context, attempt store.WebhookAttempt) error {
+	s.webhookAttempts = append(s.webhookAttempts, attempt)
+	return nil
+}
+
+func (s *fakeStore) ResetWebhookFailures(ctx context.Context, endpointID string) error {
+	s.webhookFailures[endpointID] = 0
+	return nil
+}
+
+func (s *fakeStore) RecordWebhookFailure(ctx context.Context, endpointID string, threshold int) (bool, error) {
+	s.webhookFailures[endpointID]++
+	if s.webhookFailures[endpointID] >= threshold {
+		delete(s.webhooks, endpointID)
+		return true, nil
+	}
+	return false, nil
+}
+
+type fakePublisher struct {
+	events []events.Event
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, event events.Event) error {
+	p.events = append(p.events, event)
+	return nil
+}
+
+func newActivityEnvironment(a *Activities) *testsuite.TestActivityEnvironment {
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivity(a)
+	return env
+}
+
+func TestTimerActivitiesPersistAndPublish(t *testing.T) {
+	db := newFakeStore()
+	db.timers["timer-1"] = "created"
+	publisher := &fakePublisher{}
+	env := newActivityEnvironment(&Activities{Store: db, Events: publisher})
+
+	if _, err := env.ExecuteActivity(activities.StartTimerActivity, StartTimerRequest{TimerID: "timer-1", HouseholdID: "h1"}); err != nil {
+		t.Fatalf("StartTimerActivity() error = %v", err)
+	}
+	if db.timers["timer-1"] != "running" {
+		t.Errorf("timer status = %q, want running", db.timers["timer-1"])
+	}
+
+	_, err := env.ExecuteActivity(activities.CompleteTimerActivity, CompleteTimerRequest{TimerID: "timer-1", HouseholdID: "h1", Status: "stopped"})
+	if err != nil {
+		t.Fatalf("CompleteTimerActivity() error = %v", err)
+	}
+	if db.timers["timer-1"] != store.TimerStopped {
+		t.Errorf("timer status = %q, want stopped", db.timers["timer-1"])
+	}
+
+	if len(publisher.events) != 2 {
+		t.Fatalf("published %d events, want 2", len(publisher.events))
+	}
+	if publisher.events[0].Type != events.TimerStarted || publisher.events[1].Type != events.TimerStopped {
+		t.Errorf("published %s and %s, want timer.started and timer.stopped", publisher.events[0].Type, publisher.events[1].Type)
+	}
+	if publisher.events[0].HouseholdID != "h1" || publisher.events[0].ID == "" {
+		t.Errorf("event = %+v, want the household and an ID", publisher.events[0])
+	}
+
+	// A timer that does not exist will not appear on retry
+	_, err = env.ExecuteActivity(activities.StartTimerActivity, StartTimerRequest{TimerID: "missing"})
+	var appErr *temporal.ApplicationError
+	if !errors.As(err, &appErr) || !appErr.NonRetryable() {
+		t.Errorf("missing timer error = %v, want a non-retryable application error", err)
+	}
+}
+
+func TestTaskOccurrenceActivities(t *testing.T) {
+	db := newFakeStore()
+	publisher := &fakePublisher{}
+	env := newActivityEnvironment(&Activities{Store: db, Events: publisher})
+
+	req := CreateTaskOccurrenceRequest{
+		TaskID:      "task-1",
+		Occurrence:  TaskOccurrence{OccurrenceID: "task-1_1", DueDate: time.Now()},
+		Name:        "Take out trash",
+		HouseholdID: "h1",
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := env.ExecuteActivity(activities.CreateTaskOccurrenceActivity, req); err != nil {
+			t.Fatalf("CreateTaskOccurrenceActivity() error = %v", err)
+		}
+	}
+	if len(publisher.events) != 1 || publisher.events[0].Type != events.TaskCreated {
+		t.Fatalf("published %v, want a single task.created for a repeated occurrence", publisher.events)
+	}
+
+	checks := []struct {
+		status string
+		want   bool
+	}{
+		{store.TaskPending, false},
+		{store.TaskInProgress, false},
+		{store.TaskPendingReview, false},
+		{store.TaskCompleted, true},
+		{store.TaskCancelled, true},
+	}
+	for _, check := range checks {
+		db.occurrences["task-1_1"] = check.status
+		value, err := env.ExecuteActivity(activities.CheckTaskCompletionActivity, CheckTaskCompletionRequest{OccurrenceID: "task-1_1"})
+		if err != nil {
+			t.Fatalf("CheckTaskCompletionActivity() error = %v", err)
+		}
+		var done bool
+		if err := value.Get(&done); err != nil {
+			t.Fatal(err)
+		}
+		if done != check.want {
+			t.Errorf("status %s: done = %v, want %v", check.status, done, check.want)
+		}
+	}
+
+	// Reminders stop for an occurrence whose task is gone
+	value, err := env.ExecuteActivity(activities.CheckTaskCompletionActivity, CheckTaskCompletionRequest{OccurrenceID: "task-1_9"})
+	if err != nil {
+		t.Fatalf("CheckTaskCompletionActivity() error = %v", err)
+	}
+	var done bool
+	if err := value.Get(&done); err != nil || !done {
+		t.Errorf("missing occurrence done = %v, want true", done)
+	}
+}
+
+func TestAssignTaskOccurrence(t *testing.T) {
+	db := newFakeStore()
+	publisher := &fakePublisher{}
+	env := newActivityEnvironment(&Activities{Store: db, Events: publisher})
+
+	due := time.Now().Add(time.Hour)
+	db.away["u1"] = due.Add(24 * time.Hour)
+	db.pools["task-1"] = store.AssignmentPool{
+		Task: assignment.Task{Category: "kitchen", EstimatedMinutes: 20},
+		Members: []assignment.Member{
+			{UserID: "u1"},
+			{UserID: "u2", LoadMinutes: 90},
+			{UserID: "u3", LoadMinutes: 40},
+		},
+	}
+
+	req := CreateTaskOccurrenceRequest{
+		TaskID:      "task-1",
+		Occurrence:  TaskOccurrence{OccurrenceID: "task-1_1", DueDate: due},
+		HouseholdID: "h1",
+		Assignment:  &OccurrenceAssignment{Strategy: assignment.LeastLoaded, Members: []string{"u1", "u2", "u3"}},
+	}
+	value, err := env.ExecuteActivity(activities.CreateTaskOccurrenceActivity, req)
+	if err != nil {
+		t.Fatalf("CreateTaskOccurrenceActivity() error = %v", err)
+	}
+	var assignee string
+	if err := value.Get(&assignee); err != nil {
+		t.Fatal(err)
+	}
+
+	// u1 has nothing open but is away, so the least loaded of the others
+	// gets the task
+	if assignee != "u3" || db.assignees["task-1_1"] != "u3" {
+		t.Errorf("assigned %q, stored %q, want u3", assignee, db.assignees["task-1_1"])
+	}
+
+	// A retry returns the stored assignee even if the loads have moved on
+	db.pools["task-1"].Members[2].LoadMinutes = 500
+	value, err = env.ExecuteActivity(activities.CreateTaskOccurrenceActivity, req)
+	if err != nil {
+		t.Fatalf("CreateTaskOccurrenceActivity() retry error = %v", err)
+	}
+	if err := value.Get(&assignee); err != nil || assignee != "u3" {
+		t.Errorf("retry assigned %q (%v), want u3", assignee, err)
+	}
+	if len(publisher.events) != 1 {
+		t.Errorf("published %d events, want 1", len(publisher.events))
+	}
+}
+
+func TestEscalationActivities(t *testing.T) {
+	db := newFakeStore()
+	db.occurrences["task-1_1"] = store.TaskPending
+	db.occurrences["task-1_2"] = store.TaskCompleted
+	publisher := &fakePublisher{}
+	env := newActivityEnvironment(&Activities{Store: db, Events: publisher})
+
+	value, err := env.ExecuteActivity(activities.ReassignTaskActivity, ReassignTaskRequest{OccurrenceID: "task-1_1", From: "u1", To: "u2"})
+	if err != nil {
+		t.Fatalf("ReassignTaskActivity() error = %v", err)
+	}
+	var taskID string
+	if err := value.Get(&taskID); err != nil || taskID != "task-task-1_1" {
+		t.Errorf("task ID = %q, want task-task-1_1", taskID)
+	}
+	if db.assignees["task-1_1"] != "u2" {
+		t.Errorf("assignee = %q, want u2", db.assignees["task-1_1"])
+	}
+
+	// A task finished in the meantime stays with its assignee
+	_, err = env.ExecuteActivity(activities.ReassignTaskActivity, ReassignTaskRequest{OccurrenceID: "task-1_2", From: "u1", To: "u2"})
+	var appErr *temporal.ApplicationError
+	if !errors.As(err, &appErr) || appErr.Type() != "TaskClosed" {
+		t.Errorf("closed task error = %v, want TaskClosed", err)
+	}
+
+	_, err = env.ExecuteActivity(activities.RecordEscalationActivity, TaskEscalation{
+		RecurringTaskID: "task-1",
+		OccurrenceID:    "task-1_1",
+		HouseholdID:     "h1",
+		Tier:            TierAdmins,
+		AssignedTo:      "u1",
+		Reminders:       2,
+		Notified:        []string{"u3"},
+	})
+	if err != nil {
+		t.Fatalf("RecordEscalationActivity() error = %v", err)
+	}
+	if len(publisher.events) != 1 || publisher.events[0].Type != events.TaskEscalated {
+		t.Fatalf("published %v, want a task.escalated event", publisher.events)
+	}
+	event := publisher.events[0]
+	if event.HouseholdID != "h1" || event.UserID != "u1" || event.Data["tier"] != TierAdmins {
+		t.Errorf("escalation event = %+v", event)
+	}
+	if _, ok := event.Data["taskId"]; ok {
+		t.Errorf("escalation event has a task ID it was not given: %+v", event.Data)
+	}
+}
+
+func TestRecordMissedChoreActivity(t *testing.T) {
+	db := newFakeStore()
+	db.occurrences["task-1_1"] = store.TaskPending
+	db.occurrences["task-1_2"] = store.TaskPendingReview
+	env := newActivityEnvironment(&Activities{Store: db})
+
+	tests := []struct {
+		occurrenceID string
+		want         bool
+	}{
+		{"task-1_1", true},
+		{"task-1_1", false}, // Recorded once per occurrence
+		{"task-1_2", false}, // Submitted for review, so not missed
+		{"task-1_3", false},
+	}
+	for _, tt := range tests {
+		value, err := env.ExecuteActivity(activities.RecordMissedChoreActivity, MissedChoreRequest{
+			OccurrenceID: tt.occurrenceID,
+			HouseholdID:  "h1",
+			AssignedTo:   "u1",
+			Reminders:    3,
+		})
+		if err != nil {
+			t.Fatalf("RecordMissedChoreActivity(%s) error = %v", tt.occurrenceID, err)
+		}
+		var recorded bool
+		if err := value.Get(&recorded); err != nil || recorded != tt.want {
+			t.Errorf("RecordMissedChoreActivity(%s) = %v, want %v", tt.occurrenceID, recorded, tt.want)
+		}
+	}
+	if chore := db.missed["task-1_1"]; chore.UserID != "u1" || chore.Reminders != 3 {
+		t.Errorf("missed chore = %+v", chore)
+	}
+}
+
+func TestAwayActivities(t *testing.T) {
+	now := time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)
+	db := newFakeStore()
+	db.away["u1"] = now.AddDate(0, 0, 7)
+	db.away["u2"] = now.Add(-time.Hour)
+	env := newActivityEnvironment(&Activities{Store: db})
+
+	value, err := env.ExecuteActivity(activities.AwayMembersActivity, AwayMembersRequest{HouseholdID: "h1", At: now})
+	if err != nil {
+		t.Fatalf("AwayMembersActivity() error = %v", err)
+	}
+	var away []string
+	if err := value.Get(&away); err != nil || len(away) != 1 || away[0] != "u1" {
+		t.Errorf("away members = %v, want [u1]", away)
+	}
+
+	value, err = env.ExecuteActivity(activities.MemberAwayUntilActivity, MemberAwayRequest{HouseholdID: "h1", UserID: "u1", At: now})
+	if err != nil {
+		t.Fatalf("MemberAwayUntilActivity() error = %v", err)
+	}
+	var until time.Time
+	if err := value.Get(&until); err != nil || !until.Equal(now.AddDate(0, 0, 7)) {
+		t.Errorf("u1 away until %s, want %s", until, now.AddDate(0, 0, 7))
+	}
+
+	// A member who is back, or a task nobody is assigned to, is not away
+	for _, userID := range []string{"u2", ""} {
+		value, err = env.ExecuteActivity(activities.MemberAwayUntilActivity, MemberAwayRequest{HouseholdID: "h1", UserID: userID, At: now})
+		if err != nil {
+			t.Fatalf("MemberAwayUntilActivity(%q) error = %v", userID, err)
+		}
+		if err := value.Get(&until); err != nil || !until.IsZero() {
+			t.Errorf("%q away until %s, want zero", userID, until)
+		}
+	}
+
+	// Periods the household came back from are not pauses
+	db.awayPeriods["h1"] = []store.HouseholdAwayPeriod{
+		{ID: "away-1", StartsAt: now.AddDate(0, 0, -7), EndsAt: now.AddDate(0, 0, -1)},
+		{ID: "away-2", StartsAt: now.AddDate(0, 0, 2), EndsAt: now.AddDate(0, 0, 5)},
+	}
+	value, err = env.ExecuteActivity(activities.HouseholdPausesActivity, HouseholdPausesRequest{HouseholdID: "h1", At: now})
+	if err != nil {
+		t.Fatalf("HouseholdPausesActivity() error = %v", err)
+	}
+	var pauses []PauseWindow
+	if err := value.Get(&pauses); err != nil || len(pauses) != 1 || pauses[0].AwayID != "away-2" ||
+		!pauses[0].From.Equal(now.AddDate(0, 0, 2)) || !pauses[0].Until.Equal(now.AddDate(0, 0, 5)) {
+		t.Errorf("pauses = %+v, want away-2", pauses)
+	}
+}
+
+func TestBillActivities(t *testing.T) {
+	db := newFakeStore()
+	db.bills["bill-1"] = store.BillPending
+	db.bills["bill-2"] = store.BillPaid
+	publisher := &fakePublisher{}
+	env := newActivityEnvironment(&Activities{Store: db, Events: publisher})
+
+	req := MarkBillOverdueRequest{BillID: "bill-1", HouseholdID: "h1", AssignedTo: "u1", Name: "Electricity", Amount: 150.75, Currency: "USD"}
+	value, err := env.ExecuteActivity(activities.MarkBillOverdueActivity, req)
+	if err != nil {
+		t.Fatalf("MarkBillOverdueActivity() error = %v", err)
+	}
+	var overdue bool
+	if err := value.Get(&overdue); err != nil || !overdue {
+		t.Errorf("overdue = %v, want true", overdue)
+	}
+	if db.bills["bill-1"] != store.BillOverdue {
+		t.Errorf("bill status = %q, want overdue", db.bills["bill-1"])
+	}
+	if len(publisher.events) != 1 || publisher.events[0].Type != events.BillOverdue {
+		t.Fatalf("published %v, want a bill.overdue event", publisher.events)
+	}
+	if event := publisher.events[0]; event.HouseholdID != "h1" || event.UserID != "u1" || event.Data["status"] != store.BillOverdue {
+		t.Errorf("overdue event = %+v", event)
+	}
+
+	// A bill paid in the meantime is left alone
+	req.BillID = "bill-2"
+	value, err = env.ExecuteActivity(activities.MarkBillOverdueActivity, req)
+	if err != nil {
+		t.Fatalf("MarkBillOverdueActivity() error = %v", err)
+	}
+	if err := value.Get(&overdue); err != nil || overdue {
+		t.Errorf("overdue = %v for a paid bill, want false", overdue)
+	}
+	if len(publisher.events) != 1 {
+		t.Errorf("published %d events, want no event for a paid bill", len(publisher.events))
+	}
+
+	for billID, want := range map[string]bool{"bill-1": false, "bill-2": true, "missing": true} {
+		value, err := env.ExecuteActivity(activities.CheckBillSettledActivity, CheckBillSettledRequest{BillID: billID})
+		if err != nil {
+			t.Fatalf("CheckBillSettledActivity(%s) error = %v", billID, err)
+		}
+		var settled bool
+		if err := value.Get(&settled); err != nil || settled != want {
+			t.Errorf("CheckBillSettledActivity(%s) = %v, want %v", billID, settled, want)
+		}
+	}
+}
+
+func TestCreateNextBillInstanceActivity(t *testing.T) {
+	db := newFakeStore()
+	reminderDays := 5
+	db.nextBills["bill-1"] = store.BillInstance{
+		ID: "bill-2", SeriesID: "series-1", Sequence: 2, HouseholdID: "h1", CreatedBy: "u1", Name: "Rent",
+		Amount: 1200, AmountType: store.BillAmountFixed, Currency: "USD", DueDate: time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+		ReminderDays: &reminderDays,
+	}
+	publisher := &fakePublisher{}
+	env := newActivityEnvironment(&Activities{Store: db, Events: publisher})
+
+	req := CreateNextBillInstanceRequest{BillID: "bill-1", SeriesID: "series-1", HouseholdID: "h1"}
+	for i := 0; i < 2; i++ {
+		value, err := env.ExecuteActivity(activities.CreateNextBillInstanceActivity, req)
+		if err != nil {
+			t.Fatalf("CreateNextBillInstanceActivity() error = %v", err)
+		}
+		var next *BillLifecycleWorkflowParams
+		if err := value.Get(&next); err != nil || next == nil {
+			t.Fatalf("next = %v, %v, want the next instance", next, err)
+		}
+		if next.BillID != "bill-2" || next.SeriesID != "series-1" || next.Amount != 1200 || next.ReminderDays == nil || *next.ReminderDays != 5 {
+			t.Errorf("next = %+v", next)
+		}
+	}
+
+	// Only the call creating the instance publishes it
+	if len(publisher.events) != 1 || publisher.events[0].Type != events.BillCreated {
+		t.Fatalf("published %v, want one bill.created event", publisher.events)
+	}
+	if data := publisher.events[0].Data; data["billId"] != "bill-2" || data["seriesId"] != "series-1" {
+		t.Errorf("created event data = %v", data)
+	}
+
+	// An ended series has no next instance
+	req.BillID = "bill-2"
+	value, err := env.ExecuteActivity(activities.CreateNextBillInstanceActivity, req)
+	if err != nil {
+		t.Fatalf("CreateNextBillInstanceActivity() error = %v for an ended series", err)
+	}
+	var next *BillLifecycleWorkflowParams
+	if value.HasValue() {
+		if err := value.Get(&next); err != nil || next != nil {
+			t.Errorf("next = %+v, %v for an ended series, want nil", next, err)
+		}
+	}
+}
+
+func TestDigestActivities(t *testing.T) {
+	db := newFakeStore()
+	db.digests["h1"] = store.HouseholdDigest{HouseholdID: "h1", Name: "Home", ShoppingItems: 4}
+
+	var emails []notifier.Email
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var email notifier.Email
+		json.NewDecoder(r.Body).Decode(&email)
+		if email.To.Email == "bounced@example.com" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		emails = append(emails, email)
+		w.Write([]byte(`{"messageId":"m1"}`))
+	}))
+	defer server.Close()
+
+	env := newActivityEnvironment(&Activities{Store: db, Notifier: notifier.NewClient(server.URL)})
+
+	value, err := env.ExecuteActivity(activities.LoadHouseholdDigestActivity, LoadHouseholdDigestRequest{HouseholdID: "h1"})
+	if err != nil {
+		t.Fatalf("LoadHouseholdDigestActivity() error = %v", err)
+	}
+	var digest *store.HouseholdDigest
+	if err := value.Get(&digest); err != nil || digest == nil || digest.ShoppingItems != 4 {
+		t.Fatalf("digest = %+v, %v", digest, err)
+	}
+
+	// A deleted household has no digest
+	value, err = env.ExecuteActivity(activities.LoadHouseholdDigestActivity, LoadHouseholdDigestRequest{HouseholdID: "gone"})
+	if err != nil {
+		t.Fatalf("LoadHouseholdDigestActivity() error = %v for a deleted household", err)
+	}
+	if value.HasValue() {
+		digest = nil
+		if err := value.Get(&digest); err != nil || digest != nil {
+			t.Errorf("digest = %+v, %v for a deleted household, want nil", digest, err)
+		}
+	}
+
+	req := DigestEmailRequest{UserID: "u1", Email: "dana@example.com", HouseholdID: "h1", Subject: "Your day at Home", Title: "Good morning, Dana", Body: "4 items on the shopping list"}
+	if _, err := env.ExecuteActivity(activities.SendDigestEmailActivity, req); err != nil {
+		t.Fatalf("SendDigestEmailActivity() error = %v", err)
+	}
+	if len(emails) != 1 || emails[0].To.Email != "dana@example.com" || emails[0].Category != "household_digest" {
+		t.Errorf("emails = %+v", emails)
+	}
+
+	// A rejected email is not retried
+	req.Email = "bounced@example.com"
+	_, err = env.ExecuteActivity(activities.SendDigestEmailActivity, req)
+	var appErr *temporal.ApplicationError
+	if !errors.As(err, &appErr) || !appErr.NonRetryable() {
+		t.Errorf("SendDigestEmailActivity() error = %v, want a non-retryable error", err)
+	}
+}
+
+func TestSendNotificationActivity(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n notifier.Notification
+		json.NewDecoder(r.Body).Decode(&n)
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+
+		switch n.UserID {
+		case "no-devices":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"no_devices"}`))
+		case "unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"receipts":[{"id":"r1","status":"queued"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	env := newActivityEnvironment(&Activities{Notifier: notifier.NewClient(server.URL)})
+
+	if _, err := env.ExecuteActivity(activities.SendNotificationActivity, NotificationRequest{UserID: "user-1", Title: "Timer Completed"}); err != nil {
+		t.Fatalf("SendNotificationActivity() error = %v", err)
+	}
+	if keys[0] == "" {
+		t.Error("notification sent without an idempotency key")
+	}
+
+	// Another activity sending the same content is another notification
+	if _, err := env.ExecuteActivity(activities.SendNotificationActivity, NotificationRequest{UserID: "user-1", Title: "Timer Completed"}); err != nil {
+		t.Fatalf("SendNotificationActivity() error = %v", err)
+	}
+	if keys[1] == keys[0] {
+		t.Errorf("idempotency key %q reused by another activity", keys[1])
+	}
+
+	_, err := env.ExecuteActivity(activities.SendNotificationActivity, NotificationRequest{UserID: "no-devices"})
+	var appErr *temporal.ApplicationError
+	if !errors.As(err, &appErr) || !appErr.NonRetryable() || appErr.Type() != "NoDevices" {
+		t.Errorf("no devices error = %v, want a non-retryable NoDevices error", err)
+	}
+
+	_, err = env.ExecuteActivity(activities.SendNotificationActivity, NotificationRequest{UserID: "unavailable"})
+	if err == nil || (errors.As(err, &appErr) && appErr.NonRetryable()) {
+		t.Errorf("unavailable notifier error = %v, want a retryable error", err)
+	}
+}
+
+func TestDeliverWebhookActivity(t *testing.T) {
+	var received []*http.Request
+	var bodies [][]byte
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, r)
+		bodies = append(bodies, body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	db := newFakeStore()
+	db.webhooks["endpoint-1"] = store.WebhookEndpoint{ID: "endpoint-1", URL: server.URL, Secret: "whsec_test"}
+	db.webhookFailures["endpoint-1"] = 2
+	env := newActivityEnvironment(&Activities{Store: db, WebhookClient: server.Client()})
+
+	req := DeliverWebhookRequest{
+		EndpointID: "endpoint-1",
+		Event:      WebhookEvent{ID: "event-1", Type: "task.completed", HouseholdID: "h1", Timestamp: time.Now()},
+	}
+	if _, err := env.ExecuteActivity(activities.DeliverWebhookActivity, req); err != nil {
+		t.Fatalf("DeliverWebhookActivity() error = %v", err)
+	}
+
+	r := received[0]
+	if r.Header.Get(webhooks.EventHeader) != "task.completed" || r.Header.Get(webhooks.DeliveryHeader) != "event-1" {
+		t.Errorf("headers = %v, want the event type and ID", r.Header)
+	}
+	err := webhooks.Verify("whsec_test", r.Header.Get(webhooks.TimestampHeader), r.Header.Get(webhooks.SignatureHeader),
+		bodies[0], time.Now(), webhooks.DefaultTolerance)
+	if err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+	if len(db.webhookAttempts) != 1 || db.webhookAttempts[0].StatusCode != http.StatusOK || db.webhookAttempts[0].Attempt != 1 {
+		t.Errorf("attempts = %+v, want one logged 200", db.webhookAttempts)
+	}
+	if db.webhookFailures["endpoint-1"] != 0 {
+		t.Errorf("failures = %d after a delivery, want 0", db.webhookFailures["endpoint-1"])
+	}
+
+	status = http.StatusServiceUnavailable
+	_, err = env.ExecuteActivity(activities.DeliverWebhookActivity, req)
+	var appErr *temporal.ApplicationError
+	if err == nil || (errors.As(err, &appErr) && appErr.NonRetryable()) {
+		t.Errorf("503 error = %v, want a retryable error", err)
+	}
+	if last := db.webhookAttempts[len(db.webhookAttempts)-1]; last.StatusCode != http.StatusServiceUnavailable || last.Error == "" {
+		t.Errorf("attempt = %+v, want the 503 logged", last)
+	}
+
+	status = http.StatusGone
+	_, err = env.ExecuteActivity(activities.DeliverWebhookActivity, req)
+	if !errors.As(err, &appErr) || !appErr.NonRetryable() || appErr.Type() != "WebhookRejected" {
+		t.Errorf("410 error = %v, want a non-retryable WebhookRejected error", err)
+	}
+
+	// Deliveries stop once the endpoint is deleted or disabled
+	delete(db.webhooks, "endpoint-1")
+	_, err = env.ExecuteActivity(activities.DeliverWebhookActivity, req)
+	if !errors.As(err, &appErr) || !appErr.NonRetryable() || appErr.Type() != "WebhookEndpointGone" {
+		t.Errorf("deleted endpoint error = %v, want a non-retryable WebhookEndpointGone error", err)
+	}
+	if len(received) != 3 {
+		t.Errorf("endpoint received %d requests, want 3", len(received))
+	}
+}
+
+func TestDeliverWebhookActivityRefusesPrivateAddresses(t *testing.T) {
+	var received int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received++
+	}))
+	defer server.Close()
+
+	// The default client only dials public addresses, and the test server
+	// listens on loopback
+	db := newFakeStore()
+	db.webhooks["endpoint-1"] = store.WebhookEndpoint{ID: "endpoint-1", URL: server.URL, Secret: "whsec_test"}
+	env := newActivityEnvironment(&Activities{Store: db})
+
+	_, err := env.ExecuteActivity(activities.DeliverWebhookActivity, DeliverWebhookRequest{
+		EndpointID: "endpoint-1",
+		Event:      WebhookEvent{ID: "event-1", Type: "task.completed", HouseholdID: "h1", Timestamp: time.Now()},
+	})
+	var appErr *temporal.ApplicationError
+	if !errors.As(err, &appErr) || !appErr.NonRetryable() || appErr.Type() != "WebhookRejected" {
+		t.Errorf("loopback error = %v, want a non-retryable WebhookRejected error", err)
+	}
+	if received != 0 {
+		t.Errorf("endpoint received %d requests, want 0", received)
+	}
+	if len(db.webhookAttempts) != 1 || db.webhookAttempts[0].Error != errWebhookAddressBlocked.Error() {
+		t.Errorf("attempts = %+v, want the refusal logged without the address", db.webhookAttempts)
+	}
+}
+
+func TestPublicAddress(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.0.0.5", false},
+		{"172.16.3.4", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false}, // Cloud metadata
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"::ffff:10.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := publicAddress(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("publicAddress(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
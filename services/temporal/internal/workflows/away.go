@@ -0,0 +1,229 @@
+package workflows
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/workflow"
+)
+
+// PauseUntilSignal pauses a recurring task while its household is away. The
+// signal carries a PauseWindow; a window without an end resumes the series.
+const PauseUntilSignal = "pause_until"
+
+// Away policies of a recurring task, deciding what happens to occurrences
+// that fall inside an away period
+const (
+	// AwaySkip drops the occurrences
+	AwaySkip = "skip"
+
+	// AwayShift moves the first occurrence to the household's return and
+	// drops the rest
+	AwayShift = "shift"
+)
+
+// PauseWindow is a period a household is away, during which a recurring task
+// creates no occurrences
+type PauseWindow struct {
+	AwayID string    `json:"awayId"`
+	From   time.Time `json:"from"`  // Now when zero
+	Until  time.Time `json:"until"` // Zero resumes the series
+
+	// Shifted is set once an occurrence was moved to the window's end
+	Shifted bool `json:"shifted,omitempty"`
+}
+
+// applyPause adds a window to the pauses of a series, replacing the window of
+// the same away period; a window that does not end removes it
+func applyPause(pauses []PauseWindow, window PauseWindow, now time.Time) []PauseWindow {
+	if window.From.IsZero() {
+		window.From = now
+	}
+
+	kept := make([]PauseWindow, 0, len(pauses)+1)
+	for _, pause := range pauses {
+		if pause.AwayID != window.AwayID {
+			kept = append(kept, pause)
+		}
+	}
+	if window.Until.After(window.From) {
+		kept = append(kept, window)
+	}
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].From.Before(kept[j].From) })
+	return kept
+}
+
+// hasPause reports whether the pauses include the window of an away period
+func hasPause(pauses []PauseWindow, awayID string) bool {
+	for _, pause := range pauses {
+		if pause.AwayID == awayID {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneEndedPauses drops the windows that end before the next due date, as
+// due dates only move forward
+func pruneEndedPauses(pauses []PauseWindow, due time.Time) []PauseWindow {
+	kept := pauses[:0]
+	for _, pause := range pauses {
+		if pause.Until.After(due) {
+			kept = append(kept, pause)
+		}
+	}
+	return kept
+}
+
+// pauseAt returns the index of the window a due date falls inside, or -1
+func pauseAt(pauses []PauseWindow, due time.Time) int {
+	for i, pause := range pauses {
+		if !due.Before(pause.From) && due.Before(pause.Until) {
+			return i
+		}
+	}
+	return -1
+}
+
+// withoutMembers returns the members that are not excluded, keeping their
+// order; keep is retained even when excluded
+func withoutMembers(members, excluded []string, keep string) []string {
+	if len(excluded) == 0 {
+		return members
+	}
+
+	skip := make(map[string]bool, len(excluded))
+	for _, member := range excluded {
+		skip[member] = member != keep
+	}
+
+	var kept []string
+	for _, member := range members {
+		if !skip[member] {
+			kept = append(kept, member)
+		}
+	}
+	return kept
+}
+
+// availableMember picks the round-robin assignee of an occurrence, passing
+// over members who are away when it is due. If every member is away the
+// round-robin pick is kept.
+func availableMember(ctx workflow.Context, params RecurringTaskWorkflowParams, index int, due time.Time) string {
+	members := params.AssignedMembers
+	pick := members[index%len(members)]
+
+	var a *Activities
+	var away []string
+	err := workflow.ExecuteActivity(ctx, a.AwayMembersActivity, AwayMembersRequest{
+		HouseholdID: params.HouseholdID,
+		At:          due,
+	}).Get(ctx, &away)
+	if err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to list away members", "householdId", params.HouseholdID, "error", err)
+		return pick
+	}
+
+	isAway := make(map[string]bool, len(away))
+	for _, member := range away {
+		isAway[member] = true
+	}
+	for i := range members {
+		if member := members[(index+i)%len(members)]; !isAway[member] {
+			return member
+		}
+	}
+	return pick
+}
+
+// awayUntil returns when the assignee of a reminder returns, or zero when
+// they are not away
+func awayUntil(ctx workflow.Context, params TaskReminderWorkflowParams) time.Time {
+	var a *Activities
+	var until time.Time
+	err := workflow.ExecuteActivity(ctx, a.MemberAwayUntilActivity, MemberAwayRequest{
+		HouseholdID: params.HouseholdID,
+		UserID:      params.AssignedTo,
+		At:          workflow.Now(ctx),
+	}).Get(ctx, &until)
+	if err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to check if the assignee is away", "occurrenceId", params.OccurrenceID, "error", err)
+		return time.Time{}
+	}
+	return until
+}
+
+// householdPauses returns the pauses of the household's current and upcoming
+// away periods, which a series started after they were created was not
+// signalled
+func householdPauses(ctx workflow.Context, householdID string) []PauseWindow {
+	var a *Activities
+	var pauses []PauseWindow
+	err := workflow.ExecuteActivity(ctx, a.HouseholdPausesActivity, HouseholdPausesRequest{
+		HouseholdID: householdID,
+		At:          workflow.Now(ctx),
+	}).Get(ctx, &pauses)
+	if err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to load away periods", "householdId", householdID, "error", err)
+		return nil
+	}
+	return pauses
+}
+
+// AwayMembersRequest asks for the members of a household away at a time
+type AwayMembersRequest struct {
+	HouseholdID string    `json:"householdId"`
+	At          time.Time `json:"at"`
+}
+
+// HouseholdPausesRequest asks for the away periods of a household that have
+// not ended at a time
+type HouseholdPausesRequest struct {
+	HouseholdID string    `json:"householdId"`
+	At          time.Time `json:"at"`
+}
+
+// MemberAwayRequest asks whether a member is away at a time
+type MemberAwayRequest struct {
+	HouseholdID string    `json:"householdId"`
+	UserID      string    `json:"userId"`
+	At          time.Time `json:"at"`
+}
+
+// AwayMembersActivity returns the IDs of a household's members who are away
+// at the requested time
+func (a *Activities) AwayMembersActivity(ctx context.Context, req AwayMembersRequest) ([]string, error) {
+	members, err := a.Store.AwayMembers(ctx, req.HouseholdID, req.At)
+	if err != nil {
+		return nil, err
+	}
+	activity.GetLogger(ctx).Info("Listed away members", "householdId", req.HouseholdID, "away", len(members))
+	return members, nil
+}
+
+// MemberAwayUntilActivity returns when a member who is away at the requested
+// time returns, or zero when they are not away
+func (a *Activities) MemberAwayUntilActivity(ctx context.Context, req MemberAwayRequest) (time.Time, error) {
+	if req.UserID == "" {
+		return time.Time{}, nil
+	}
+	return a.Store.AwayUntil(ctx, req.HouseholdID, req.UserID, req.At)
+}
+
+// HouseholdPausesActivity returns a pause for every period the whole
+// household is away that has not ended at the requested time
+func (a *Activities) HouseholdPausesActivity(ctx context.Context, req HouseholdPausesRequest) ([]PauseWindow, error) {
+	periods, err := a.Store.HouseholdAwayPeriods(ctx, req.HouseholdID, req.At)
+	if err != nil {
+		return nil, err
+	}
+
+	pauses := make([]PauseWindow, 0, len(periods))
+	for _, period := range periods {
+		pauses = append(pauses, PauseWindow{AwayID: period.ID, From: period.StartsAt, Until: period.EndsAt})
+	}
+	activity.GetLogger(ctx).Info("Listed away periods", "householdId", req.HouseholdID, "periods", len(pauses))
+	return pauses, nil
+}
@@ -0,0 +1,536 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/househelper/temporal/internal/store"
+	"github.com/househelper/temporal/pkg/events"
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// DefaultBillReminderDays matches the reminder_days default of the bills table
+const DefaultBillReminderDays = 3
+
+// maxBillFollowUpInterval caps the growing gap between follow-ups
+const maxBillFollowUpInterval = 7 * 24 * time.Hour
+
+// defaultBillFollowUps applies when a bill has no follow-up settings
+var defaultBillFollowUps = BillFollowUpSettings{
+	Interval:      24 * time.Hour,
+	MaxFollowUps:  4,
+	EscalateAfter: 2,
+}
+
+// BillLifecycleWorkflowParams represents parameters for the bill lifecycle
+// workflow
+type BillLifecycleWorkflowParams struct {
+	BillID      string    `json:"billId"`
+	HouseholdID string    `json:"householdId"`
+	CreatedBy   string    `json:"createdBy"`
+	AssignedTo  string    `json:"assignedTo,omitempty"` // The creator is reminded when empty
+	Name        string    `json:"name"`
+	Amount      float64   `json:"amount"`
+	Currency    string    `json:"currency"`
+	DueDate     time.Time `json:"dueDate"`
+
+	// ReminderDays is how many days before the due date the reminder is
+	// sent; nil uses DefaultBillReminderDays
+	ReminderDays *int `json:"reminderDays,omitempty"`
+
+	FollowUps BillFollowUpSettings `json:"followUps"`
+
+	// SeriesID is set for an instance of a recurring bill series, whose next
+	// instance the workflow creates
+	SeriesID   string `json:"seriesId,omitempty"`
+	AmountType string `json:"amountType,omitempty"` // fixed, estimated or open
+}
+
+// BillFollowUpSettings configures the follow-ups of an overdue bill. Each
+// follow-up waits twice as long as the one before, up to a week.
+type BillFollowUpSettings struct {
+	Interval      time.Duration `json:"interval"`      // Wait before the first follow-up
+	MaxFollowUps  int           `json:"maxFollowUps"`  // Follow-ups sent before giving up
+	EscalateAfter int           `json:"escalateAfter"` // Follow-ups before the admins are told too, 0 never
+}
+
+// withDefaults returns the default settings when none are set
+func (s BillFollowUpSettings) withDefaults() BillFollowUpSettings {
+	if s == (BillFollowUpSettings{}) {
+		return defaultBillFollowUps
+	}
+	return s
+}
+
+// BillPaidSignal is sent by the API when a bill is paid
+type BillPaidSignal struct {
+	PaidBy string  `json:"paidBy"`
+	Amount float64 `json:"amount"`
+}
+
+// BillUpdatedSignal is sent by the API when a pending bill is edited. It
+// carries the bill as it now is.
+type BillUpdatedSignal struct {
+	Name         string    `json:"name"`
+	Amount       float64   `json:"amount"`
+	Currency     string    `json:"currency"`
+	DueDate      time.Time `json:"dueDate"`
+	AssignedTo   string    `json:"assignedTo,omitempty"`
+	ReminderDays *int      `json:"reminderDays,omitempty"`
+	AmountType   string    `json:"amountType,omitempty"`
+}
+
+// BillLifecycleWorkflow follows a bill from creation until it is paid. It
+// reminds the assignee ReminderDays before the due date, marks the bill
+// overdue at the due date and follows up with increasing urgency, bringing in
+// the household admins after FollowUps.EscalateAfter follow-ups. A bill_paid
+// signal, or the bill being paid or deleted, ends the workflow. A
+// bill_updated signal moves the reminder and the due date and redirects
+// later notifications to the new assignee. For an
+// instance of a recurring series, the next instance is created, with a
+// lifecycle workflow of its own, when the bill is paid or reaches its due
+// date, whichever comes first.
+func BillLifecycleWorkflow(ctx workflow.Context, params BillLifecycleWorkflowParams) error {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("Starting bill lifecycle workflow", "billId", params.BillID, "dueDate", params.DueDate)
+
+	// Setup activity options
+	activityOptions := workflow.ActivityOptions{
+		StartToCloseTimeout: time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumInterval:    time.Minute,
+			MaximumAttempts:    3,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, activityOptions)
+
+	version := billLifecycleWorkflowVersion(ctx)
+
+	var a *Activities
+	followUps := params.FollowUps.withDefaults()
+	reminderDays := DefaultBillReminderDays
+	if params.ReminderDays != nil {
+		reminderDays = *params.ReminderDays
+	}
+	recipient := params.AssignedTo
+	if recipient == "" {
+		recipient = params.CreatedBy
+	}
+
+	paid := false
+	paidChannel := workflow.GetSignalChannel(ctx, "bill_paid")
+	receivePaid := func(c workflow.ReceiveChannel) {
+		var signal BillPaidSignal
+		c.Receive(ctx, &signal)
+		paid = true
+		logger.Info("Bill paid signal received", "billId", params.BillID, "paidBy", signal.PaidBy)
+	}
+
+	updatedChannel := workflow.GetSignalChannel(ctx, "bill_updated")
+	applyUpdate := func(signal BillUpdatedSignal) {
+		params.Name = signal.Name
+		params.Amount = signal.Amount
+		params.Currency = signal.Currency
+		params.DueDate = signal.DueDate
+		params.AssignedTo = signal.AssignedTo
+		params.ReminderDays = signal.ReminderDays
+		params.AmountType = signal.AmountType
+
+		reminderDays = DefaultBillReminderDays
+		if params.ReminderDays != nil {
+			reminderDays = *params.ReminderDays
+		}
+		recipient = params.AssignedTo
+		if recipient == "" {
+			recipient = params.CreatedBy
+		}
+		logger.Info("Bill updated signal received", "billId", params.BillID, "dueDate", params.DueDate, "assignedTo", recipient)
+	}
+	// receiveUpdates applies the updates sent so far
+	receiveUpdates := func() {
+		if version < billLifecycleUpdateVersion {
+			return
+		}
+		var signal BillUpdatedSignal
+		for updatedChannel.ReceiveAsync(&signal) {
+			applyUpdate(signal)
+			signal = BillUpdatedSignal{}
+		}
+	}
+
+	// waitUntil sleeps until the time deadline returns, which an update may
+	// move, and reports whether the bill was paid meanwhile
+	waitUntil := func(deadline func() time.Time) bool {
+		for {
+			receiveUpdates()
+			t := deadline()
+			if paid || !t.After(workflow.Now(ctx)) {
+				return paid
+			}
+			updated := false
+			timerCtx, cancelTimer := workflow.WithCancel(ctx)
+			selector := workflow.NewSelector(ctx)
+			selector.AddFuture(workflow.NewTimer(timerCtx, t.Sub(workflow.Now(ctx))), func(f workflow.Future) {})
+			selector.AddReceive(paidChannel, func(c workflow.ReceiveChannel, more bool) {
+				receivePaid(c)
+			})
+			if version >= billLifecycleUpdateVersion {
+				selector.AddReceive(updatedChannel, func(c workflow.ReceiveChannel, more bool) {
+					var signal BillUpdatedSignal
+					c.Receive(ctx, &signal)
+					applyUpdate(signal)
+					updated = true
+				})
+			}
+			selector.Select(ctx)
+			cancelTimer()
+			if !updated {
+				return paid
+			}
+		}
+	}
+	remindAt := func() time.Time {
+		return params.DueDate.AddDate(0, 0, -reminderDays)
+	}
+	dueAt := func() time.Time {
+		return params.DueDate
+	}
+
+	// billSettled also asks the store, so a bill paid without a signal or
+	// deleted stops the workflow
+	billSettled := func() bool {
+		if paid || paidChannel.ReceiveAsync(nil) {
+			paid = true
+			return true
+		}
+		var settled bool
+		err := workflow.ExecuteActivity(ctx, a.CheckBillSettledActivity, CheckBillSettledRequest{
+			BillID: params.BillID,
+		}).Get(ctx, &settled)
+		if err != nil {
+			logger.Warn("Failed to check bill status", "billId", params.BillID, "error", err)
+			return false
+		}
+		return settled
+	}
+
+	notification := func(title, body, notificationType string) NotificationRequest {
+		return NotificationRequest{
+			UserID:      recipient,
+			HouseholdID: params.HouseholdID,
+			Title:       title,
+			Body:        body,
+			Data: map[string]string{
+				"billId":   params.BillID,
+				"type":     notificationType,
+				"amount":   strconv.FormatFloat(params.Amount, 'f', 2, 64),
+				"currency": params.Currency,
+				"dueDate":  params.DueDate.Format(time.RFC3339),
+			},
+		}
+	}
+
+	// nextInstance creates the next instance of a series bill, once
+	nextCreated := false
+	nextInstance := func() {
+		if nextCreated || version < billLifecycleSeriesVersion || params.SeriesID == "" {
+			return
+		}
+		nextCreated = true
+		startNextBillInstance(ctx, params)
+	}
+
+	// Remind the assignee ahead of the due date; a bill created too close to
+	// its due date is reminded right away
+	if waitUntil(remindAt) {
+		logger.Info("Bill paid before its reminder", "billId", params.BillID)
+		nextInstance()
+		return nil
+	}
+	if workflow.Now(ctx).Before(params.DueDate) && !billSettled() {
+		notifyTier(ctx, EscalationTier{Channel: ChannelPush, Priority: PriorityNormal}, notification(
+			fmt.Sprintf("Bill Reminder: %s", params.Name),
+			fmt.Sprintf("%s of %s is due %s", params.Name, billAmountText(params), params.DueDate.Format("Jan 2")),
+			"bill_reminder",
+		))
+		logger.Info("Sent bill reminder", "billId", params.BillID, "userId", recipient)
+	}
+
+	// An update received while the next instance was created may have moved
+	// the due date again
+	for {
+		if waitUntil(dueAt) {
+			logger.Info("Bill paid before its due date", "billId", params.BillID)
+			nextInstance()
+			return nil
+		}
+		nextInstance()
+		receiveUpdates()
+		if !params.DueDate.After(workflow.Now(ctx)) {
+			break
+		}
+	}
+
+	var overdue bool
+	err := workflow.ExecuteActivity(ctx, a.MarkBillOverdueActivity, MarkBillOverdueRequest{
+		BillID:      params.BillID,
+		HouseholdID: params.HouseholdID,
+		AssignedTo:  recipient,
+		Name:        params.Name,
+		Amount:      params.Amount,
+		Currency:    params.Currency,
+		DueDate:     params.DueDate,
+	}).Get(ctx, &overdue)
+	if err != nil {
+		return fmt.Errorf("failed to mark bill overdue: %w", err)
+	}
+	if !overdue {
+		logger.Info("Bill settled before its due date", "billId", params.BillID)
+		return nil
+	}
+
+	overdueTier := EscalationTier{Channel: ChannelPush, Priority: PriorityHigh}
+	notifyTier(ctx, overdueTier, notification(
+		fmt.Sprintf("Bill Overdue: %s", params.Name),
+		fmt.Sprintf("%s of %s was due %s", params.Name, billAmountText(params), params.DueDate.Format("Jan 2")),
+		"bill_overdue",
+	))
+
+	interval := followUps.Interval
+	for followUp := 1; followUp <= followUps.MaxFollowUps; followUp++ {
+		next := workflow.Now(ctx).Add(interval)
+		if waitUntil(func() time.Time { return next }) || billSettled() {
+			logger.Info("Overdue bill paid", "billId", params.BillID, "followUps", followUp-1)
+			return nil
+		}
+
+		req := notification(
+			fmt.Sprintf("Bill Still Unpaid: %s", params.Name),
+			fmt.Sprintf("%s of %s is overdue since %s", params.Name, billAmountText(params), params.DueDate.Format("Jan 2")),
+			"bill_follow_up",
+		)
+		req.Data["followUp"] = strconv.Itoa(followUp)
+		notifyTier(ctx, overdueTier, req)
+		logger.Info("Sent bill follow-up", "billId", params.BillID, "count", followUp)
+
+		if followUp == followUps.EscalateAfter {
+			escalateBillToAdmins(ctx, params, recipient, followUp)
+		}
+
+		interval *= 2
+		if interval > maxBillFollowUpInterval {
+			interval = maxBillFollowUpInterval
+		}
+	}
+
+	logger.Info("Bill follow-ups exhausted", "billId", params.BillID)
+	return nil
+}
+
+// billAmountText formats the amount of a bill for notifications
+func billAmountText(params BillLifecycleWorkflowParams) string {
+	if params.AmountType == store.BillAmountOpen {
+		// The amount of an open bill is only known once it is paid
+		return "an open amount"
+	}
+	return fmt.Sprintf("%.2f %s", params.Amount, params.Currency)
+}
+
+// startNextBillInstance creates the instance of a series after the bill and
+// starts its lifecycle workflow. The new workflow outlives this one, which
+// only waits for it to start.
+func startNextBillInstance(ctx workflow.Context, params BillLifecycleWorkflowParams) {
+	logger := workflow.GetLogger(ctx)
+
+	var a *Activities
+	var next *BillLifecycleWorkflowParams
+	err := workflow.ExecuteActivity(ctx, a.CreateNextBillInstanceActivity, CreateNextBillInstanceRequest{
+		BillID:      params.BillID,
+		SeriesID:    params.SeriesID,
+		HouseholdID: params.HouseholdID,
+	}).Get(ctx, &next)
+	if err != nil {
+		logger.Error("Failed to create next bill instance", "billId", params.BillID, "seriesId", params.SeriesID, "error", err)
+		return
+	}
+	if next == nil {
+		logger.Info("Bill series ended", "billId", params.BillID, "seriesId", params.SeriesID)
+		return
+	}
+	next.FollowUps = params.FollowUps
+
+	childCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{
+		WorkflowID:        fmt.Sprintf("bill-%s", next.BillID),
+		ParentClosePolicy: enums.PARENT_CLOSE_POLICY_ABANDON,
+	})
+	child := workflow.ExecuteChildWorkflow(childCtx, BillLifecycleWorkflow, *next)
+	if err := child.GetChildWorkflowExecution().Get(ctx, nil); err != nil {
+		logger.Warn("Failed to start next bill lifecycle", "billId", next.BillID, "error", err)
+		return
+	}
+	logger.Info("Created next bill instance", "billId", params.BillID, "nextBillId", next.BillID, "dueDate", next.DueDate)
+}
+
+// escalateBillToAdmins tells the household admins, other than the assignee,
+// that an overdue bill is still unpaid
+func escalateBillToAdmins(ctx workflow.Context, params BillLifecycleWorkflowParams, assignee string, followUps int) {
+	var a *Activities
+	var admins []string
+	err := workflow.ExecuteActivity(ctx, a.ListHouseholdAdminsActivity, params.HouseholdID).Get(ctx, &admins)
+	if err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to list household admins", "householdId", params.HouseholdID, "error", err)
+		return
+	}
+
+	for _, admin := range admins {
+		if admin == assignee {
+			continue
+		}
+		notifyTier(ctx, EscalationTier{Channel: ChannelPush, Priority: PriorityHigh}, NotificationRequest{
+			UserID:      admin,
+			HouseholdID: params.HouseholdID,
+			Title:       fmt.Sprintf("Bill Overdue: %s", params.Name),
+			Body:        fmt.Sprintf("%s of %s is still unpaid after %d follow-ups", params.Name, billAmountText(params), followUps),
+			Data: map[string]string{
+				"billId":     params.BillID,
+				"type":       "bill_escalated",
+				"assignedTo": assignee,
+				"dueDate":    params.DueDate.Format(time.RFC3339),
+			},
+		})
+	}
+}
+
+// CheckBillSettledRequest represents a request to check if a bill still
+// needs reminders
+type CheckBillSettledRequest struct {
+	BillID string `json:"billId"`
+}
+
+// MarkBillOverdueRequest represents a request to mark a bill overdue
+type MarkBillOverdueRequest struct {
+	BillID      string    `json:"billId"`
+	HouseholdID string    `json:"householdId"`
+	AssignedTo  string    `json:"assignedTo"`
+	Name        string    `json:"name"`
+	Amount      float64   `json:"amount"`
+	Currency    string    `json:"currency"`
+	DueDate     time.Time `json:"dueDate"`
+}
+
+// CreateNextBillInstanceRequest represents a request to create the next
+// instance of a bill series
+type CreateNextBillInstanceRequest struct {
+	BillID      string `json:"billId"`
+	SeriesID    string `json:"seriesId"`
+	HouseholdID string `json:"householdId"`
+}
+
+// CheckBillSettledActivity reports whether a bill no longer needs reminders:
+// it is paid, cancelled or gone
+func (a *Activities) CheckBillSettledActivity(ctx context.Context, req CheckBillSettledRequest) (bool, error) {
+	logger := activity.GetLogger(ctx)
+
+	status, err := a.Store.BillStatus(ctx, req.BillID)
+	if errors.Is(err, store.ErrNotFound) {
+		logger.Warn("Bill not found, treating it as settled", "billId", req.BillID)
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	settled := status == store.BillPaid || status == store.BillCancelled
+	logger.Info("Bill status checked", "billId", req.BillID, "status", status, "settled", settled)
+	return settled, nil
+}
+
+// MarkBillOverdueActivity marks a pending bill overdue and publishes a
+// bill.overdue event. It returns false, publishing nothing, when the bill was
+// paid or cancelled in the meantime.
+func (a *Activities) MarkBillOverdueActivity(ctx context.Context, req MarkBillOverdueRequest) (bool, error) {
+	marked, err := a.Store.MarkBillOverdue(ctx, req.BillID)
+	if err != nil {
+		return false, storeError(err, "bill "+req.BillID)
+	}
+	if !marked {
+		return false, nil
+	}
+
+	data := map[string]interface{}{
+		"billId":      req.BillID,
+		"householdId": req.HouseholdID,
+		"name":        req.Name,
+		"amount":      req.Amount,
+		"currency":    req.Currency,
+		"status":      store.BillOverdue,
+		"dueDate":     req.DueDate,
+		"assignedTo":  req.AssignedTo,
+	}
+	if err := a.publish(ctx, events.BillOverdue, req.HouseholdID, req.AssignedTo, data); err != nil {
+		return false, err
+	}
+	a.broadcast(ctx, req.HouseholdID, "bill_updated", data)
+
+	activity.GetLogger(ctx).Info("Bill marked overdue", "billId", req.BillID)
+	return true, nil
+}
+
+// CreateNextBillInstanceActivity creates the instance of a bill's series
+// after the bill and returns the parameters of its lifecycle workflow, or nil
+// when the series ended. Only a newly created instance publishes a
+// bill.created event, so a retry finds the instance and publishes nothing.
+func (a *Activities) CreateNextBillInstanceActivity(ctx context.Context, req CreateNextBillInstanceRequest) (*BillLifecycleWorkflowParams, error) {
+	logger := activity.GetLogger(ctx)
+
+	next, created, err := a.Store.CreateNextBillInstance(ctx, req.BillID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, storeError(err, "bill "+req.BillID)
+	}
+
+	if created {
+		data := map[string]interface{}{
+			"billId":      next.ID,
+			"seriesId":    next.SeriesID,
+			"sequence":    next.Sequence,
+			"householdId": next.HouseholdID,
+			"name":        next.Name,
+			"amount":      next.Amount,
+			"amountType":  next.AmountType,
+			"currency":    next.Currency,
+			"status":      store.BillPending,
+			"dueDate":     next.DueDate,
+			"assignedTo":  next.AssignedTo,
+		}
+		if err := a.publish(ctx, events.BillCreated, next.HouseholdID, next.CreatedBy, data); err != nil {
+			return nil, err
+		}
+		a.broadcast(ctx, next.HouseholdID, "bill_created", data)
+		logger.Info("Bill instance created", "billId", next.ID, "seriesId", next.SeriesID, "sequence", next.Sequence)
+	}
+
+	return &BillLifecycleWorkflowParams{
+		BillID:       next.ID,
+		HouseholdID:  next.HouseholdID,
+		CreatedBy:    next.CreatedBy,
+		AssignedTo:   next.AssignedTo,
+		Name:         next.Name,
+		Amount:       next.Amount,
+		Currency:     next.Currency,
+		DueDate:      next.DueDate,
+		ReminderDays: next.ReminderDays,
+		SeriesID:     next.SeriesID,
+		AmountType:   next.AmountType,
+	}, nil
+}
@@ -0,0 +1,364 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/househelper/temporal/internal/store"
+	"github.com/househelper/temporal/pkg/notifier"
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// DefaultDigestHour is the local hour household digests are sent at
+const DefaultDigestHour = 7
+
+// ChannelEmail delivers a digest by email, to members with a daily email
+// digest
+const ChannelEmail = "email"
+
+// digestListLimit is how many tasks or bills a digest names before summing
+// up the rest
+const digestListLimit = 3
+
+// HouseholdDigestWorkflowParams represents parameters for the household
+// digest workflow
+type HouseholdDigestWorkflowParams struct {
+	HouseholdID string `json:"householdId"`
+}
+
+// HouseholdDigestWorkflow sends each member of a household a morning summary
+// of their tasks for the day, the household's overdue tasks, the bills due
+// within the week and the size of the shopping list, in the member's
+// language. It is started every morning by the household's digest schedule.
+// Members with nothing to report get no digest.
+func HouseholdDigestWorkflow(ctx workflow.Context, params HouseholdDigestWorkflowParams) error {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("Starting household digest workflow", "householdId", params.HouseholdID)
+
+	// Setup activity options
+	activityOptions := workflow.ActivityOptions{
+		StartToCloseTimeout: time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumInterval:    time.Minute,
+			MaximumAttempts:    3,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, activityOptions)
+
+	var a *Activities
+	var digest *store.HouseholdDigest
+	err := workflow.ExecuteActivity(ctx, a.LoadHouseholdDigestActivity, LoadHouseholdDigestRequest{
+		HouseholdID: params.HouseholdID,
+		Now:         workflow.Now(ctx),
+	}).Get(ctx, &digest)
+	if err != nil {
+		return fmt.Errorf("failed to load household digest: %w", err)
+	}
+	if digest == nil {
+		logger.Info("Household not found, no digest sent", "householdId", params.HouseholdID)
+		return nil
+	}
+
+	sent := 0
+	for _, member := range digest.Members {
+		message, ok := renderDigest(*digest, member)
+		if !ok {
+			continue
+		}
+		for _, channel := range digestChannels(member) {
+			sendDigest(ctx, channel, *digest, member, message)
+		}
+		sent++
+	}
+
+	logger.Info("Household digest sent", "householdId", params.HouseholdID, "members", sent)
+	return nil
+}
+
+// digestChannels returns the channels a member gets the digest through: a
+// push if they allow push notifications and an email if they chose a daily
+// email digest. Members who allow neither, or muted notifications, see it
+// in the app.
+func digestChannels(member store.DigestMember) []string {
+	var channels []string
+	if member.NotificationsEnabled && member.PushNotifications {
+		channels = append(channels, ChannelPush)
+	}
+	if member.NotificationsEnabled && member.EmailNotifications && member.EmailDigest == "daily" && member.Email != "" {
+		channels = append(channels, ChannelEmail)
+	}
+	if len(channels) == 0 {
+		channels = append(channels, ChannelInApp)
+	}
+	return channels
+}
+
+// sendDigest delivers a member's digest through one channel. Failures are
+// logged; one undelivered digest does not stop the others.
+func sendDigest(ctx workflow.Context, channel string, digest store.HouseholdDigest, member store.DigestMember, message digestMessage) {
+	if channel != ChannelEmail {
+		notifyTier(ctx, EscalationTier{Channel: channel, Priority: PriorityLow}, NotificationRequest{
+			UserID:      member.UserID,
+			HouseholdID: digest.HouseholdID,
+			Title:       message.Title,
+			Body:        message.Body,
+			Data: map[string]string{
+				"type":        "household_digest",
+				"householdId": digest.HouseholdID,
+				"date":        digest.Date.Format("2006-01-02"),
+			},
+		})
+		return
+	}
+
+	var a *Activities
+	err := workflow.ExecuteActivity(ctx, a.SendDigestEmailActivity, DigestEmailRequest{
+		UserID:        member.UserID,
+		Email:         member.Email,
+		Name:          member.FirstName,
+		HouseholdID:   digest.HouseholdID,
+		HouseholdName: digest.Name,
+		Subject:       message.Subject,
+		Title:         message.Title,
+		Body:          message.Body,
+	}).Get(ctx, nil)
+	if err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to send digest email", "userId", member.UserID, "error", err)
+	}
+}
+
+// digestMessage is a member's rendered digest
+type digestMessage struct {
+	Subject string
+	Title   string
+	Body    string
+}
+
+// digestLocale holds the strings of a digest in one language. Counted
+// strings come as one and other forms and take the count and the list.
+type digestLocale struct {
+	subject      string // Takes the household's name
+	title        string // Takes the member's first name
+	tasksToday   [2]string
+	noTasksToday string
+	overdue      [2]string
+	billsDue     [2]string
+	shopping     [2]string // Takes the count only
+	more         string    // Takes the number of items not named
+	dateFormat   string
+	separator    string
+}
+
+// digestLocales are the languages digests are rendered in; others get
+// English
+var digestLocales = map[string]digestLocale{
+	"en": {
+		subject:      "Your day at %s",
+		title:        "Good morning, %s",
+		tasksToday:   [2]string{"You have 1 task today: %[2]s", "You have %[1]d tasks today: %[2]s"},
+		noTasksToday: "Nothing on your list today",
+		overdue:      [2]string{"1 overdue task: %[2]s", "%[1]d overdue tasks: %[2]s"},
+		billsDue:     [2]string{"1 bill due this week: %[2]s", "%[1]d bills due this week: %[2]s"},
+		shopping:     [2]string{"1 item on the shopping list", "%d items on the shopping list"},
+		more:         "and %d more",
+		dateFormat:   "Jan 2",
+		separator:    ", ",
+	},
+	"he": {
+		subject:      "היום שלך ב%s",
+		title:        "בוקר טוב, %s",
+		tasksToday:   [2]string{"יש לך משימה אחת היום: %[2]s", "יש לך %[1]d משימות היום: %[2]s"},
+		noTasksToday: "אין לך משימות היום",
+		overdue:      [2]string{"משימה אחת באיחור: %[2]s", "%[1]d משימות באיחור: %[2]s"},
+		billsDue:     [2]string{"חשבון אחד לתשלום השבוע: %[2]s", "%[1]d חשבונות לתשלום השבוע: %[2]s"},
+		shopping:     [2]string{"פריט אחד ברשימת הקניות", "%d פריטים ברשימת הקניות"},
+		more:         "ועוד %d",
+		dateFormat:   "2/1",
+		separator:    ", ",
+	},
+}
+
+// localeFor returns the digest strings for a language tag such as "en-US"
+func localeFor(language string) digestLocale {
+	tag := strings.ToLower(strings.SplitN(language, "-", 2)[0])
+	if tag == "iw" {
+		tag = "he"
+	}
+	if locale, ok := digestLocales[tag]; ok {
+		return locale
+	}
+	return digestLocales["en"]
+}
+
+// counted picks the one or other form of a counted string
+func counted(forms [2]string, n int, list string) string {
+	if n == 1 {
+		return fmt.Sprintf(forms[0], n, list)
+	}
+	return fmt.Sprintf(forms[1], n, list)
+}
+
+// nameList joins names, naming at most digestListLimit of them
+func (l digestLocale) nameList(names []string) string {
+	if len(names) <= digestListLimit {
+		return strings.Join(names, l.separator)
+	}
+	return strings.Join(names[:digestListLimit], l.separator) + " " + fmt.Sprintf(l.more, len(names)-digestListLimit)
+}
+
+// renderDigest renders a member's digest in their language, without the
+// bills for a member whose role may not view them. ok is false when there is
+// nothing to report.
+func renderDigest(digest store.HouseholdDigest, member store.DigestMember) (message digestMessage, ok bool) {
+	locale := localeFor(member.Language)
+
+	var tasks []string
+	for _, task := range digest.TasksToday {
+		if task.AssignedTo == member.UserID {
+			tasks = append(tasks, task.Title)
+		}
+	}
+	billsDue := digest.BillsDue
+	if !member.SeesBills() {
+		billsDue = nil
+	}
+	if len(tasks) == 0 && len(digest.OverdueTasks) == 0 && len(billsDue) == 0 && digest.ShoppingItems == 0 {
+		return digestMessage{}, false
+	}
+
+	var lines []string
+	if len(tasks) > 0 {
+		lines = append(lines, counted(locale.tasksToday, len(tasks), locale.nameList(tasks)))
+	} else {
+		lines = append(lines, locale.noTasksToday)
+	}
+	if n := len(digest.OverdueTasks); n > 0 {
+		overdue := make([]string, 0, n)
+		for _, task := range digest.OverdueTasks {
+			overdue = append(overdue, task.Title)
+		}
+		lines = append(lines, counted(locale.overdue, n, locale.nameList(overdue)))
+	}
+	if n := len(billsDue); n > 0 {
+		bills := make([]string, 0, n)
+		for _, bill := range billsDue {
+			bills = append(bills, fmt.Sprintf("%s %.2f %s (%s)", bill.Name, bill.Amount, bill.Currency, bill.DueDate.Format(locale.dateFormat)))
+		}
+		lines = append(lines, counted(locale.billsDue, n, locale.nameList(bills)))
+	}
+	if n := digest.ShoppingItems; n > 0 {
+		form := locale.shopping[1]
+		if n == 1 {
+			form = locale.shopping[0]
+		}
+		lines = append(lines, strings.Replace(form, "%d", fmt.Sprint(n), 1))
+	}
+
+	return digestMessage{
+		Subject: fmt.Sprintf(locale.subject, digest.Name),
+		Title:   fmt.Sprintf(locale.title, member.FirstName),
+		Body:    strings.Join(lines, "\n"),
+	}, true
+}
+
+// DigestScheduleID returns the ID of a household's digest schedule
+func DigestScheduleID(householdID string) string {
+	return "household-digest-" + householdID
+}
+
+// DigestScheduleOptions returns the schedule that starts a household's
+// digest every day at hour in the household's timezone. A digest missed by
+// more than an hour, e.g. while the worker was down, is skipped rather than
+// sent late.
+func DigestScheduleOptions(householdID, timezone string, hour int, taskQueue string) client.ScheduleOptions {
+	return client.ScheduleOptions{
+		ID: DigestScheduleID(householdID),
+		Spec: client.ScheduleSpec{
+			Calendars: []client.ScheduleCalendarSpec{{
+				Hour: []client.ScheduleRange{{Start: hour}},
+			}},
+			TimeZoneName: timezone,
+		},
+		Action: &client.ScheduleWorkflowAction{
+			ID:        DigestScheduleID(householdID),
+			Workflow:  HouseholdDigestWorkflow,
+			Args:      []interface{}{HouseholdDigestWorkflowParams{HouseholdID: householdID}},
+			TaskQueue: taskQueue,
+		},
+		Overlap:       enums.SCHEDULE_OVERLAP_POLICY_SKIP,
+		CatchupWindow: time.Hour,
+	}
+}
+
+// LoadHouseholdDigestRequest represents a request to gather a household's
+// digest
+type LoadHouseholdDigestRequest struct {
+	HouseholdID string    `json:"householdId"`
+	Now         time.Time `json:"now"`
+}
+
+// DigestEmailRequest represents a request to email a member their digest
+type DigestEmailRequest struct {
+	UserID        string `json:"userId"`
+	Email         string `json:"email"`
+	Name          string `json:"name"`
+	HouseholdID   string `json:"householdId"`
+	HouseholdName string `json:"householdName"`
+	Subject       string `json:"subject"`
+	Title         string `json:"title"`
+	Body          string `json:"body"`
+}
+
+// LoadHouseholdDigestActivity gathers a household's digest for the day;
+// it returns nil for a household that was deleted
+func (a *Activities) LoadHouseholdDigestActivity(ctx context.Context, req LoadHouseholdDigestRequest) (*store.HouseholdDigest, error) {
+	digest, err := a.Store.HouseholdDigest(ctx, req.HouseholdID, req.Now)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	activity.GetLogger(ctx).Info("Household digest loaded", "householdId", req.HouseholdID,
+		"members", len(digest.Members), "tasksToday", len(digest.TasksToday), "billsDue", len(digest.BillsDue))
+	return &digest, nil
+}
+
+// SendDigestEmailActivity emails a member their digest through the
+// notifier
+func (a *Activities) SendDigestEmailActivity(ctx context.Context, req DigestEmailRequest) error {
+	if req.Email == "" {
+		return temporal.NewNonRetryableApplicationError("digest email has no recipient", "NoRecipient", nil)
+	}
+
+	suppressed, err := a.Notifier.SendEmail(ctx, notifier.Email{
+		To:            notifier.EmailRecipient{UserID: req.UserID, Email: req.Email, Name: req.Name},
+		Subject:       req.Subject,
+		Title:         req.Title,
+		Body:          req.Body,
+		HouseholdID:   req.HouseholdID,
+		HouseholdName: req.HouseholdName,
+		Category:      "household_digest",
+	})
+
+	var statusErr *notifier.StatusError
+	if errors.As(err, &statusErr) && !statusErr.Retryable() {
+		return temporal.NewNonRetryableApplicationError("digest email rejected", "EmailRejected", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	activity.GetLogger(ctx).Info("Digest email sent", "userId", req.UserID, "suppressed", suppressed)
+	return nil
+}
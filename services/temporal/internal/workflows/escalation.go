@@ -0,0 +1,229 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+
+	"github.com/househelper/temporal/internal/store"
+	"github.com/househelper/temporal/pkg/events"
+	"github.com/househelper/temporal/pkg/notifier"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Escalation tiers of a task's reminders
+const (
+	TierAssignee = "assignee"
+	TierAdmins   = "admins"
+	TierReassign = "reassign"
+)
+
+// Channels an escalation tier notifies through
+const (
+	ChannelPush  = "push"   // push notification to the recipient's devices
+	ChannelInApp = "in_app" // real-time message to the recipient's open clients
+)
+
+// Notification priorities; the notifier delivers high priority pushes
+// immediately
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+)
+
+// EscalationTier sets how the notifications of one tier are delivered
+type EscalationTier struct {
+	Channel  string `json:"channel,omitempty"`  // push or in_app
+	Priority string `json:"priority,omitempty"` // low, normal or high
+}
+
+// EscalationPolicy configures the tiers of a task's reminders. The assignee
+// is reminded first; after ReminderSettings.EscalateAfter ignored reminders
+// the household admins are notified, and once MaxReminders are ignored the
+// task is optionally reassigned to the next member in rotation.
+type EscalationPolicy struct {
+	Assignee EscalationTier `json:"assignee"`
+	Admins   EscalationTier `json:"admins"`
+	Reassign bool           `json:"reassign"`
+
+	// Reassigned notifies the member the task was reassigned to
+	Reassigned EscalationTier `json:"reassigned"`
+}
+
+// withDefaults fills in unset channels and priorities; each tier is more
+// urgent than the one before
+func (p EscalationPolicy) withDefaults() EscalationPolicy {
+	p.Assignee = p.Assignee.withDefaults(PriorityNormal)
+	p.Admins = p.Admins.withDefaults(PriorityHigh)
+	p.Reassigned = p.Reassigned.withDefaults(PriorityHigh)
+	return p
+}
+
+func (t EscalationTier) withDefaults(priority string) EscalationTier {
+	if t.Channel == "" {
+		t.Channel = ChannelPush
+	}
+	if t.Priority == "" {
+		t.Priority = priority
+	}
+	return t
+}
+
+// ReassignTaskRequest represents a request to hand an occurrence's task to
+// another member
+type ReassignTaskRequest struct {
+	OccurrenceID string `json:"occurrenceId"`
+	HouseholdID  string `json:"householdId"`
+	From         string `json:"from"`
+	To           string `json:"to"`
+}
+
+// TaskEscalation records an escalation in the household's event log
+type TaskEscalation struct {
+	TaskID          string   `json:"taskId,omitempty"` // The occurrence's task, when known
+	RecurringTaskID string   `json:"recurringTaskId"`
+	OccurrenceID    string   `json:"occurrenceId"`
+	HouseholdID     string   `json:"householdId"`
+	Name            string   `json:"name"`
+	Tier            string   `json:"tier"`
+	AssignedTo      string   `json:"assignedTo"`
+	Reminders       int      `json:"reminders"`
+	Notified        []string `json:"notified,omitempty"`
+	ReassignedTo    string   `json:"reassignedTo,omitempty"`
+}
+
+// MissedChoreRequest records a task whose assignee ignored every reminder
+type MissedChoreRequest struct {
+	OccurrenceID string `json:"occurrenceId"`
+	HouseholdID  string `json:"householdId"`
+	AssignedTo   string `json:"assignedTo"`
+	Reminders    int    `json:"reminders"`
+}
+
+// notifyTier sends a notification through the tier's channel. Failures are
+// logged; an undelivered reminder does not stop the escalation.
+func notifyTier(ctx workflow.Context, tier EscalationTier, req NotificationRequest) {
+	var a *Activities
+	req.Priority = tier.Priority
+
+	activityFn := a.SendNotificationActivity
+	if tier.Channel == ChannelInApp {
+		activityFn = a.SendInAppNotificationActivity
+	}
+
+	err := workflow.ExecuteActivity(ctx, activityFn, req).Get(ctx, nil)
+	if err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to send notification",
+			"userId", req.UserID, "channel", tier.Channel, "error", err)
+	}
+}
+
+// nextInRotation returns the member after assignee in the rotation, or ""
+// when there is nobody else to hand the task to
+func nextInRotation(rotation []string, assignee string) string {
+	for i, member := range rotation {
+		if member == assignee {
+			if next := rotation[(i+1)%len(rotation)]; next != assignee {
+				return next
+			}
+			return ""
+		}
+	}
+	if len(rotation) > 0 && rotation[0] != assignee {
+		return rotation[0]
+	}
+	return ""
+}
+
+// ListHouseholdAdminsActivity returns the IDs of a household's admins
+func (a *Activities) ListHouseholdAdminsActivity(ctx context.Context, householdID string) ([]string, error) {
+	return a.Store.HouseholdAdmins(ctx, householdID)
+}
+
+// SendInAppNotificationActivity shows a notification in the user's open
+// clients instead of pushing it to their devices
+func (a *Activities) SendInAppNotificationActivity(ctx context.Context, req NotificationRequest) error {
+	if req.UserID == "" {
+		return temporal.NewNonRetryableApplicationError("notification has no recipient", "NoRecipient", nil)
+	}
+
+	return a.Notifier.BroadcastToUser(ctx, req.UserID, notifier.Broadcast{
+		Type:     "notification",
+		Resource: "task",
+		Data: map[string]interface{}{
+			"householdId": req.HouseholdID,
+			"title":       req.Title,
+			"body":        req.Body,
+			"priority":    req.Priority,
+			"data":        req.Data,
+		},
+	})
+}
+
+// ReassignTaskActivity assigns an occurrence's task to the next member in
+// rotation and returns the task's ID. It fails with TaskClosed when the task
+// was finished or deleted in the meantime.
+func (a *Activities) ReassignTaskActivity(ctx context.Context, req ReassignTaskRequest) (string, error) {
+	taskID, err := a.Store.ReassignOccurrence(ctx, req.OccurrenceID, req.To)
+	if errors.Is(err, store.ErrNotFound) {
+		return "", temporal.NewNonRetryableApplicationError("task occurrence is closed", "TaskClosed", err)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	a.broadcast(ctx, req.HouseholdID, "task_updated", map[string]interface{}{
+		"taskId":       taskID,
+		"occurrenceId": req.OccurrenceID,
+		"householdId":  req.HouseholdID,
+		"assignedTo":   req.To,
+	})
+
+	activity.GetLogger(ctx).Info("Task reassigned", "occurrenceId", req.OccurrenceID, "from", req.From, "to", req.To)
+	return taskID, nil
+}
+
+// RecordEscalationActivity publishes a task.escalated event, which the event
+// log keeps so admins can see whose chores were escalated
+func (a *Activities) RecordEscalationActivity(ctx context.Context, escalation TaskEscalation) error {
+	data := map[string]interface{}{
+		"recurringTaskId": escalation.RecurringTaskID,
+		"occurrenceId":    escalation.OccurrenceID,
+		"householdId":     escalation.HouseholdID,
+		"title":           escalation.Name,
+		"tier":            escalation.Tier,
+		"assignedTo":      escalation.AssignedTo,
+		"reminders":       escalation.Reminders,
+	}
+	if escalation.TaskID != "" {
+		data["taskId"] = escalation.TaskID
+	}
+	if len(escalation.Notified) > 0 {
+		data["notified"] = escalation.Notified
+	}
+	if escalation.ReassignedTo != "" {
+		data["reassignedTo"] = escalation.ReassignedTo
+	}
+
+	return a.publish(ctx, events.TaskEscalated, escalation.HouseholdID, escalation.AssignedTo, data)
+}
+
+// RecordMissedChoreActivity records that the assignee missed a task, which
+// the allowance ledger deducts for. It returns false when the task was
+// finished in the meantime or the miss was recorded already.
+func (a *Activities) RecordMissedChoreActivity(ctx context.Context, req MissedChoreRequest) (bool, error) {
+	recorded, err := a.Store.RecordMissedChore(ctx, store.MissedChore{
+		OccurrenceID: req.OccurrenceID,
+		UserID:       req.AssignedTo,
+		Reminders:    req.Reminders,
+	})
+	if err != nil {
+		return false, err
+	}
+	if recorded {
+		activity.GetLogger(ctx).Info("Missed chore recorded", "occurrenceId", req.OccurrenceID, "assignedTo", req.AssignedTo)
+	}
+	return recorded, nil
+}
@@ -33,6 +33,7 @@ type LaundrySettings struct {
 	DryLevel         string        `json:"dryLevel"`    // low, medium, high, extra
 	FabricSoftener   bool          `json:"fabricSoftener"`
 	ExtraRinse       bool          `json:"extraRinse"`
+	LiveActivity     bool          `json:"liveActivity"` // Mirror the load in an iOS Live Activity
 }
 
 // LaundryState represents the current state of laundry
@@ -69,8 +70,14 @@ func LaundryWorkflow(ctx workflow.Context, params LaundryWorkflowParams) error {
 	}
 	ctx = workflow.WithActivityOptions(ctx, activityOptions)
 
+	// Loads started before Live Activities were added never mirror one
+	if laundryWorkflowVersion(ctx) < laundryLiveActivityVersion {
+		params.Settings.LiveActivity = false
+	}
+
 	// Start laundry tracking
-	err := workflow.ExecuteActivity(ctx, StartLaundryActivity, StartLaundryRequest{
+	var a *Activities
+	err := workflow.ExecuteActivity(ctx, a.StartLaundryActivity, StartLaundryRequest{
 		LaundryID:   params.LaundryID,
 		UserID:      params.UserID,
 		HouseholdID: params.HouseholdID,
@@ -95,15 +102,21 @@ func LaundryWorkflow(ctx workflow.Context, params LaundryWorkflowParams) error {
 		}
 	}
 
+	if params.Settings.LiveActivity {
+		sendLiveActivity(ctx, laundryLiveActivity(ctx, params, &state, LiveActivityEnd))
+	}
+
 	// Complete laundry workflow
 	state.Status = "completed"
-	err = workflow.ExecuteActivity(ctx, CompleteLaundryActivity, CompleteLaundryRequest{
-		LaundryID: params.LaundryID,
-		UserID:    params.UserID,
-		WashTime:  state.WashFinished.Sub(state.WashStarted),
-		DryTime:   state.DryFinished.Sub(state.DryStarted),
-		TotalTime: time.Since(state.WashStarted),
-		Status:    state.Status,
+	err = workflow.ExecuteActivity(ctx, a.CompleteLaundryActivity, CompleteLaundryRequest{
+		LaundryID:   params.LaundryID,
+		UserID:      params.UserID,
+		HouseholdID: params.HouseholdID,
+		LoadType:    params.LoadType,
+		WashTime:    state.WashFinished.Sub(state.WashStarted),
+		DryTime:     state.DryFinished.Sub(state.DryStarted),
+		TotalTime:   workflow.Now(ctx).Sub(state.WashStarted),
+		Status:      state.Status,
 	}).Get(ctx, nil)
 	if err != nil {
 		logger.Warn("Failed to complete laundry tracking", "error", err)
@@ -116,13 +129,18 @@ func LaundryWorkflow(ctx workflow.Context, params LaundryWorkflowParams) error {
 // runWashCycle handles the washing phase
 func runWashCycle(ctx workflow.Context, params LaundryWorkflowParams, state *LaundryState) error {
 	logger := workflow.GetLogger(ctx)
+	var a *Activities
 
 	state.Status = "washing"
 	state.WashStarted = workflow.Now(ctx)
 
+	if params.Settings.LiveActivity {
+		sendLiveActivity(ctx, laundryLiveActivity(ctx, params, state, LiveActivityStart))
+	}
+
 	// Send wash start notification
 	if params.Settings.NotifyOnStart {
-		err := workflow.ExecuteActivity(ctx, SendNotificationActivity, NotificationRequest{
+		err := workflow.ExecuteActivity(ctx, a.SendNotificationActivity, NotificationRequest{
 			UserID:      params.UserID,
 			HouseholdID: params.HouseholdID,
 			Title:       "Laundry Started",
@@ -161,9 +179,18 @@ func runWashCycle(ctx workflow.Context, params LaundryWorkflowParams, state *Lau
 
 	selector.Select(ctx)
 
+	// Loads without a dry cycle end their activity when the workflow completes
+	if params.Settings.LiveActivity && params.DryTime > 0 {
+		req := laundryLiveActivity(ctx, params, state, LiveActivityUpdate)
+		req.AlertTitle = "Wash Cycle Complete"
+		req.AlertBody = "Move your laundry to the dryer"
+		req.Urgent = true
+		sendLiveActivity(ctx, req)
+	}
+
 	// Send wash completion notification
 	if params.Settings.NotifyOnWashDone {
-		err := workflow.ExecuteActivity(ctx, SendNotificationActivity, NotificationRequest{
+		err := workflow.ExecuteActivity(ctx, a.SendNotificationActivity, NotificationRequest{
 			UserID:      params.UserID,
 			HouseholdID: params.HouseholdID,
 			Title:       "Wash Cycle Complete",
@@ -181,7 +208,9 @@ func runWashCycle(ctx workflow.Context, params LaundryWorkflowParams, state *Lau
 
 	// Start reminder timer if enabled
 	if params.Settings.NotifyReminders && params.Settings.ReminderInterval > 0 {
-		go runWashReminders(ctx, params, state)
+		workflow.Go(ctx, func(ctx workflow.Context) {
+			runWashReminders(ctx, params, state)
+		})
 	}
 
 	return nil
@@ -190,6 +219,7 @@ func runWashCycle(ctx workflow.Context, params LaundryWorkflowParams, state *Lau
 // runDryCycle handles the drying phase
 func runDryCycle(ctx workflow.Context, params LaundryWorkflowParams, state *LaundryState) error {
 	logger := workflow.GetLogger(ctx)
+	var a *Activities
 
 	// Wait for signal to start drying (laundry moved to dryer)
 	dryStartChannel := workflow.GetSignalChannel(ctx, "start_dry")
@@ -214,24 +244,30 @@ func runDryCycle(ctx workflow.Context, params LaundryWorkflowParams, state *Laun
 
 	selector.Select(ctx)
 
-	// Start drying phase
+	// Start drying phase; the Live Activity shows it, so no push is sent
 	state.Status = "drying"
 	state.DryStarted = workflow.Now(ctx)
 
-	// Send dry start notification
-	err := workflow.ExecuteActivity(ctx, SendNotificationActivity, NotificationRequest{
-		UserID:      params.UserID,
-		HouseholdID: params.HouseholdID,
-		Title:       "Dry Cycle Started",
-		Body:        fmt.Sprintf("Dry cycle started for %s load", params.LoadType),
-		Data: map[string]string{
-			"laundryId": params.LaundryID,
-			"type":      "dry_started",
-			"loadType":  params.LoadType,
-		},
-	}).Get(ctx, nil)
-	if err != nil {
-		logger.Warn("Failed to send dry start notification", "error", err)
+	if params.Settings.LiveActivity {
+		sendLiveActivity(ctx, laundryLiveActivity(ctx, params, state, LiveActivityUpdate))
+	}
+
+	// Loads started before Live Activities were added announce it with a push
+	if laundryWorkflowVersion(ctx) < laundryLiveActivityVersion {
+		err := workflow.ExecuteActivity(ctx, a.SendNotificationActivity, NotificationRequest{
+			UserID:      params.UserID,
+			HouseholdID: params.HouseholdID,
+			Title:       "Dry Cycle Started",
+			Body:        fmt.Sprintf("Dry cycle started for %s load", params.LoadType),
+			Data: map[string]string{
+				"laundryId": params.LaundryID,
+				"type":      "dry_started",
+				"loadType":  params.LoadType,
+			},
+		}).Get(ctx, nil)
+		if err != nil {
+			logger.Warn("Failed to send dry start notification", "error", err)
+		}
 	}
 
 	// Wait for dry cycle to complete
@@ -259,7 +295,7 @@ func runDryCycle(ctx workflow.Context, params LaundryWorkflowParams, state *Laun
 
 	// Send dry completion notification
 	if params.Settings.NotifyOnDryDone {
-		err = workflow.ExecuteActivity(ctx, SendNotificationActivity, NotificationRequest{
+		err := workflow.ExecuteActivity(ctx, a.SendNotificationActivity, NotificationRequest{
 			UserID:      params.UserID,
 			HouseholdID: params.HouseholdID,
 			Title:       "Laundry Complete",
@@ -277,7 +313,9 @@ func runDryCycle(ctx workflow.Context, params LaundryWorkflowParams, state *Laun
 
 	// Start dry completion reminders if enabled
 	if params.Settings.NotifyReminders && params.Settings.ReminderInterval > 0 {
-		go runDryReminders(ctx, params, state)
+		workflow.Go(ctx, func(ctx workflow.Context) {
+			runDryReminders(ctx, params, state)
+		})
 	}
 
 	return nil
@@ -286,50 +324,43 @@ func runDryCycle(ctx workflow.Context, params LaundryWorkflowParams, state *Laun
 // runWashReminders sends periodic reminders to move laundry to dryer
 func runWashReminders(ctx workflow.Context, params LaundryWorkflowParams, state *LaundryState) {
 	logger := workflow.GetLogger(ctx)
+	var a *Activities
 
 	for state.RemindersLeft > 0 && state.Status == "wash_done" {
-		// Wait for reminder interval
-		reminderTimer := workflow.NewTimer(ctx, params.Settings.ReminderInterval)
-
-		selector := workflow.NewSelector(ctx)
-		selector.AddFuture(reminderTimer, func(f workflow.Future) {
-			// Send reminder
-			err := workflow.ExecuteActivity(ctx, SendNotificationActivity, NotificationRequest{
-				UserID:      params.UserID,
-				HouseholdID: params.HouseholdID,
-				Title:       "Laundry Reminder",
-				Body:        "Don't forget to move your laundry to the dryer",
-				Data: map[string]string{
-					"laundryId": params.LaundryID,
-					"type":      "wash_reminder",
-					"loadType":  params.LoadType,
-				},
-			}).Get(ctx, nil)
-			if err != nil {
-				logger.Warn("Failed to send wash reminder", "error", err)
-			}
-
-			state.RemindersLeft--
-			state.LastReminder = workflow.Now(ctx)
-			logger.Info("Sent wash reminder", "laundryId", params.LaundryID, "remindersLeft", state.RemindersLeft)
+		// Wait for reminder interval, stopping early once the dry cycle starts
+		moved, err := workflow.AwaitWithTimeout(ctx, params.Settings.ReminderInterval, func() bool {
+			return state.Status != "wash_done"
 		})
+		if err != nil || moved {
+			return
+		}
 
-		// Listen for dry start signal to stop reminders
-		dryStartChannel := workflow.GetSignalChannel(ctx, "start_dry")
-		selector.AddReceive(dryStartChannel, func(c workflow.ReceiveChannel, more bool) {
-			if more {
-				// Stop reminders as dry cycle is starting
-				return
-			}
-		})
+		// Send reminder
+		err = workflow.ExecuteActivity(ctx, a.SendNotificationActivity, NotificationRequest{
+			UserID:      params.UserID,
+			HouseholdID: params.HouseholdID,
+			Title:       "Laundry Reminder",
+			Body:        "Don't forget to move your laundry to the dryer",
+			Data: map[string]string{
+				"laundryId": params.LaundryID,
+				"type":      "wash_reminder",
+				"loadType":  params.LoadType,
+			},
+		}).Get(ctx, nil)
+		if err != nil {
+			logger.Warn("Failed to send wash reminder", "error", err)
+		}
 
-		selector.Select(ctx)
+		state.RemindersLeft--
+		state.LastReminder = workflow.Now(ctx)
+		logger.Info("Sent wash reminder", "laundryId", params.LaundryID, "remindersLeft", state.RemindersLeft)
 	}
 }
 
 // runDryReminders sends periodic reminders to remove laundry from dryer
 func runDryReminders(ctx workflow.Context, params LaundryWorkflowParams, state *LaundryState) {
 	logger := workflow.GetLogger(ctx)
+	var a *Activities
 
 	remindersLeft := params.Settings.MaxReminders
 
@@ -340,7 +371,7 @@ func runDryReminders(ctx workflow.Context, params LaundryWorkflowParams, state *
 		selector := workflow.NewSelector(ctx)
 		selector.AddFuture(reminderTimer, func(f workflow.Future) {
 			// Send reminder
-			err := workflow.ExecuteActivity(ctx, SendNotificationActivity, NotificationRequest{
+			err := workflow.ExecuteActivity(ctx, a.SendNotificationActivity, NotificationRequest{
 				UserID:      params.UserID,
 				HouseholdID: params.HouseholdID,
 				Title:       "Laundry Reminder",
@@ -372,3 +403,48 @@ func runDryReminders(ctx workflow.Context, params LaundryWorkflowParams, state *
 		selector.Select(ctx)
 	}
 }
+
+// laundryLiveActivity describes the load's current phase as a Live Activity
+// event. Running phases carry the instant they end so the device can count down.
+func laundryLiveActivity(ctx workflow.Context, params LaundryWorkflowParams, state *LaundryState, event string) LiveActivityRequest {
+	now := workflow.Now(ctx)
+
+	contentState := map[string]interface{}{
+		"phase":    state.Status,
+		"loadType": params.LoadType,
+	}
+
+	req := LiveActivityRequest{
+		ActivityID:   params.LaundryID,
+		UserID:       params.UserID,
+		Event:        event,
+		ContentState: contentState,
+	}
+
+	var endsAt time.Time
+	switch state.Status {
+	case "washing":
+		endsAt = state.WashStarted.Add(params.WashTime)
+	case "drying":
+		endsAt = state.DryStarted.Add(params.DryTime)
+	}
+	if !endsAt.IsZero() {
+		contentState["endsAt"] = endsAt.Unix()
+		req.StaleDate = endsAt.Add(liveActivityStaleGrace)
+	}
+
+	switch event {
+	case LiveActivityStart:
+		req.AttributesType = LaundryActivityAttributes
+		req.Attributes = map[string]interface{}{
+			"laundryId": params.LaundryID,
+			"loadType":  params.LoadType,
+			"hasDryer":  params.DryTime > 0,
+		}
+	case LiveActivityEnd:
+		req.DismissalDate = now.Add(liveActivityDismissal)
+		req.Urgent = true
+	}
+
+	return req
+}
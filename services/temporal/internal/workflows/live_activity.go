@@ -0,0 +1,109 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/househelper/temporal/pkg/notifier"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// ActivityKit attributes types declared by the iOS app
+const (
+	TimerActivityAttributes   = "TimerActivityAttributes"
+	LaundryActivityAttributes = "LaundryActivityAttributes"
+)
+
+// Live activity events
+const (
+	LiveActivityStart  = "start"
+	LiveActivityUpdate = "update"
+	LiveActivityEnd    = "end"
+)
+
+const (
+	// liveActivityStaleGrace is how long past its expected end a running
+	// activity is shown before the device marks it stale
+	liveActivityStaleGrace = time.Minute
+
+	// liveActivityDismissal is how long an ended activity stays on the lock screen
+	liveActivityDismissal = 15 * time.Minute
+)
+
+// LiveActivityRequest represents a start, update or end of an iOS Live Activity
+type LiveActivityRequest struct {
+	ActivityID     string                 `json:"activityId"`
+	UserID         string                 `json:"userId"`
+	Event          string                 `json:"event"`
+	ContentState   map[string]interface{} `json:"contentState"`
+	StaleDate      time.Time              `json:"staleDate"`
+	DismissalDate  time.Time              `json:"dismissalDate"`
+	AttributesType string                 `json:"attributesType,omitempty"`
+	Attributes     map[string]interface{} `json:"attributes,omitempty"`
+	AlertTitle     string                 `json:"alertTitle,omitempty"`
+	AlertBody      string                 `json:"alertBody,omitempty"`
+	Urgent         bool                   `json:"urgent,omitempty"`
+}
+
+// LiveActivityActivities drives Live Activities through the notifier service
+type LiveActivityActivities struct {
+	Notifier *notifier.Client
+}
+
+// UpdateLiveActivity sends a live activity event to the notifier, which
+// throttles it and pushes it through APNs
+func (a *LiveActivityActivities) UpdateLiveActivity(ctx context.Context, req LiveActivityRequest) error {
+	logger := activity.GetLogger(ctx)
+
+	event := notifier.LiveActivityEvent{
+		UserID:         req.UserID,
+		Event:          req.Event,
+		ContentState:   req.ContentState,
+		AttributesType: req.AttributesType,
+		Attributes:     req.Attributes,
+		AlertTitle:     req.AlertTitle,
+		AlertBody:      req.AlertBody,
+		Urgent:         req.Urgent,
+	}
+	if !req.StaleDate.IsZero() {
+		event.StaleDate = &req.StaleDate
+	}
+	if !req.DismissalDate.IsZero() {
+		event.DismissalDate = &req.DismissalDate
+	}
+
+	result, err := a.Notifier.SendLiveActivityEvent(ctx, req.ActivityID, event)
+
+	var statusErr *notifier.StatusError
+	if errors.As(err, &statusErr) {
+		if statusErr.StatusCode == http.StatusNotFound {
+			// The user has no Live Activity for this timer or load
+			logger.Info("No live activity registered", "activityId", req.ActivityID, "event", req.Event)
+			return nil
+		}
+		if !statusErr.Retryable() {
+			return temporal.NewNonRetryableApplicationError("live activity rejected", "LiveActivityRejected", err)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Live activity event sent", "activityId", req.ActivityID, "event", req.Event,
+		"sent", result.Sent, "deferred", result.Deferred)
+	return nil
+}
+
+// sendLiveActivity runs UpdateLiveActivity; a failed update never fails the workflow
+func sendLiveActivity(ctx workflow.Context, req LiveActivityRequest) {
+	var a *LiveActivityActivities
+	err := workflow.ExecuteActivity(ctx, a.UpdateLiveActivity, req).Get(ctx, nil)
+	if err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to send live activity event",
+			"activityId", req.ActivityID, "event", req.Event, "error", err)
+	}
+}
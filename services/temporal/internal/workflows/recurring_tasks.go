@@ -2,9 +2,14 @@ package workflows
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/househelper/temporal/internal/assignment"
+	"github.com/househelper/temporal/internal/store"
+	"github.com/househelper/temporal/pkg/events"
+	"go.temporal.io/api/enums/v1"
 	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
@@ -22,8 +27,30 @@ type RecurringTaskWorkflowParams struct {
 	DueDuration      time.Duration    `json:"dueDuration"`
 	ReminderSettings ReminderSettings `json:"reminderSettings"`
 	AutoAssign       bool             `json:"autoAssign"`
+	AwayPolicy       string           `json:"awayPolicy,omitempty"` // skip (default) or shift
+
+	// AssignmentStrategy picks the assignee of each occurrence when
+	// AutoAssign is set: round_robin (default), least_loaded or weighted
+	AssignmentStrategy string `json:"assignmentStrategy,omitempty"`
+
+	// Away periods the series is paused for, carried forward when the
+	// workflow continues as new
+	Pauses []PauseWindow `json:"pauses,omitempty"`
+
+	// Progress carried forward when the workflow continues as new
+	OccurrenceCount int        `json:"occurrenceCount,omitempty"`
+	NextDueDate     *time.Time `json:"nextDueDate,omitempty"`
 }
 
+// A recurring task continues as new after this many occurrences, or sooner
+// when its history grows past recurringTaskMaxHistoryLength events or the
+// server suggests it, so years of daily chores stay within Temporal's
+// history limits
+const (
+	recurringTaskOccurrencesPerRun = 100
+	recurringTaskMaxHistoryLength  = 10000
+)
+
 // RecurrenceRule defines how often a task repeats
 type RecurrenceRule struct {
 	Type           string     `json:"type"`       // daily, weekly, monthly, custom
@@ -37,11 +64,12 @@ type RecurrenceRule struct {
 
 // ReminderSettings defines reminder configuration
 type ReminderSettings struct {
-	Enabled          bool          `json:"enabled"`
-	InitialDelay     time.Duration `json:"initialDelay"`
-	ReminderInterval time.Duration `json:"reminderInterval"`
-	MaxReminders     int           `json:"maxReminders"`
-	EscalateAfter    int           `json:"escalateAfter"`
+	Enabled          bool             `json:"enabled"`
+	InitialDelay     time.Duration    `json:"initialDelay"`
+	ReminderInterval time.Duration    `json:"reminderInterval"`
+	MaxReminders     int              `json:"maxReminders"`
+	EscalateAfter    int              `json:"escalateAfter"` // Ignored reminders before admins are notified; 0 never
+	Escalation       EscalationPolicy `json:"escalation"`
 }
 
 // TaskOccurrence represents a single occurrence of a recurring task
@@ -72,8 +100,42 @@ func RecurringTaskWorkflow(ctx workflow.Context, params RecurringTaskWorkflowPar
 	}
 	ctx = workflow.WithActivityOptions(ctx, activityOptions)
 
-	occurrenceCount := 0
+	// Series started before it could continue as new run in a single history
+	version := recurringTaskWorkflowVersion(ctx)
+
+	occurrenceCount := params.OccurrenceCount
 	nextDueDate := params.RecurrenceRule.StartDate
+	if params.NextDueDate != nil {
+		nextDueDate = *params.NextDueDate
+	}
+	runOccurrences := 0
+
+	pauses := params.Pauses
+	pauseChannel := workflow.GetSignalChannel(ctx, PauseUntilSignal)
+	pause := func(window PauseWindow) {
+		pauses = applyPause(pauses, window, workflow.Now(ctx))
+		logger.Info("Recurring task pause updated", "taskId", params.TaskID,
+			"awayId", window.AwayID, "until", window.Until)
+	}
+	receivePauses := func() {
+		var window PauseWindow
+		for pauseChannel.ReceiveAsync(&window) {
+			pause(window)
+			window = PauseWindow{}
+		}
+	}
+
+	// Pauses are only signalled to running series, so a new series starts
+	// with the away periods created before it; later runs carry them
+	if version >= recurringTaskPausesVersion && params.NextDueDate == nil {
+		for _, window := range householdPauses(ctx, params.HouseholdID) {
+			if !hasPause(pauses, window.AwayID) {
+				pause(window)
+			}
+		}
+	}
+
+	var a *Activities
 
 	// Continue until end conditions are met
 	for {
@@ -85,10 +147,47 @@ func RecurringTaskWorkflow(ctx workflow.Context, params RecurringTaskWorkflowPar
 			break
 		}
 
+		// Hand the remaining occurrences to a fresh run
+		info := workflow.GetInfo(ctx)
+		if version >= recurringTaskContinueAsNewVersion && (runOccurrences >= recurringTaskOccurrencesPerRun ||
+			info.GetCurrentHistoryLength() >= recurringTaskMaxHistoryLength ||
+			info.GetContinueAsNewSuggested()) {
+			logger.Info("Continuing recurring task as new", "taskId", params.TaskID,
+				"occurrences", occurrenceCount, "nextDueDate", nextDueDate)
+			params.OccurrenceCount = occurrenceCount
+			params.NextDueDate = &nextDueDate
+			if version >= recurringTaskPausesVersion {
+				receivePauses()
+			}
+			if version >= recurringTaskAwayVersion {
+				params.Pauses = pauses
+			}
+			return workflow.NewContinueAsNewError(ctx, RecurringTaskWorkflow, params)
+		}
+
+		// Occurrences due while the household is away are skipped, or the
+		// first of them is shifted to its return
+		dueDate := nextDueDate
+		shifted := -1
+		if version >= recurringTaskAwayVersion {
+			receivePauses()
+			pauses = pruneEndedPauses(pauses, nextDueDate)
+			if i := pauseAt(pauses, nextDueDate); i >= 0 {
+				if params.AwayPolicy != AwayShift || pauses[i].Shifted {
+					logger.Info("Skipping occurrence while the household is away", "taskId", params.TaskID,
+						"dueDate", nextDueDate, "awayId", pauses[i].AwayID)
+					nextDueDate = calculateNextDueDate(params.RecurrenceRule, nextDueDate)
+					continue
+				}
+				dueDate = pauses[i].Until
+				shifted = i
+			}
+		}
+
 		// Create task occurrence
 		occurrence := TaskOccurrence{
 			OccurrenceID: fmt.Sprintf("%s_%d", params.TaskID, occurrenceCount+1),
-			DueDate:      nextDueDate,
+			DueDate:      dueDate,
 			Status:       "pending",
 			CreatedAt:    workflow.Now(ctx),
 		}
@@ -105,31 +204,82 @@ func RecurringTaskWorkflow(ctx workflow.Context, params RecurringTaskWorkflowPar
 		currentTime := workflow.Now(ctx)
 		createTime := occurrence.DueDate.Add(-params.DueDuration) // Create task X time before due date
 
-		if createTime.After(currentTime) {
+		if createTime.After(currentTime) && version < recurringTaskAwayVersion {
 			timer := workflow.NewTimer(ctx, createTime.Sub(currentTime))
 			err := timer.Get(ctx, nil)
 			if err != nil {
 				return fmt.Errorf("timer failed: %w", err)
 			}
+		} else if createTime.After(currentTime) {
+			// A pause arriving while waiting reconsiders the occurrence
+			var timerErr error
+			timerFired := false
+			timerCtx, cancelTimer := workflow.WithCancel(ctx)
+			selector := workflow.NewSelector(ctx)
+			selector.AddFuture(workflow.NewTimer(timerCtx, createTime.Sub(currentTime)), func(f workflow.Future) {
+				timerErr = f.Get(ctx, nil)
+				timerFired = true
+			})
+			selector.AddReceive(pauseChannel, func(c workflow.ReceiveChannel, more bool) {
+				var window PauseWindow
+				c.Receive(ctx, &window)
+				pause(window)
+			})
+			selector.Select(ctx)
+			cancelTimer()
+			if timerErr != nil {
+				return fmt.Errorf("timer failed: %w", timerErr)
+			}
+			if !timerFired {
+				continue
+			}
+		}
+
+		// The assignment engine picks the assignee when the task is created
+		var assign *OccurrenceAssignment
+		if params.AutoAssign && len(params.AssignedMembers) > 0 {
+			switch {
+			case version >= recurringTaskAssignmentVersion:
+				occurrence.AssignedTo = ""
+				assign = &OccurrenceAssignment{
+					Strategy: params.AssignmentStrategy,
+					Members:  params.AssignedMembers,
+					Index:    occurrenceCount,
+				}
+			case version >= recurringTaskAwayVersion:
+				occurrence.AssignedTo = availableMember(ctx, params, occurrenceCount, occurrence.DueDate)
+			}
+		}
+		if shifted >= 0 {
+			pauses[shifted].Shifted = true
 		}
 
 		// Create the task occurrence
-		err := workflow.ExecuteActivity(ctx, CreateTaskOccurrenceActivity, CreateTaskOccurrenceRequest{
+		created := workflow.ExecuteActivity(ctx, a.CreateTaskOccurrenceActivity, CreateTaskOccurrenceRequest{
 			TaskID:      params.TaskID,
 			Occurrence:  occurrence,
 			Name:        params.Name,
 			Description: params.Description,
 			UserID:      params.UserID,
 			HouseholdID: params.HouseholdID,
-		}).Get(ctx, nil)
+			Assignment:  assign,
+		})
+		var err error
+		if assign != nil {
+			err = created.Get(ctx, &occurrence.AssignedTo)
+		} else {
+			err = created.Get(ctx, nil)
+		}
 		if err != nil {
 			logger.Error("Failed to create task occurrence", "error", err)
 			// Continue with next occurrence despite error
 		} else {
 			// Start reminder workflow for this occurrence
 			if params.ReminderSettings.Enabled {
+				// Reminders outlive this run when it continues as new
 				childWorkflowOptions := workflow.ChildWorkflowOptions{
-					WorkflowID: fmt.Sprintf("task-reminders-%s", occurrence.OccurrenceID),
+					WorkflowID:        fmt.Sprintf("task-reminders-%s", occurrence.OccurrenceID),
+					ParentClosePolicy: enums.PARENT_CLOSE_POLICY_ABANDON,
 				}
 				childCtx := workflow.WithChildOptions(ctx, childWorkflowOptions)
 
@@ -143,15 +293,25 @@ func RecurringTaskWorkflow(ctx workflow.Context, params RecurringTaskWorkflowPar
 					Name:             params.Name,
 					ReminderSettings: params.ReminderSettings,
 				}
+				if params.AutoAssign {
+					reminderParams.Rotation = params.AssignedMembers
+				}
 
-				workflow.ExecuteChildWorkflow(childCtx, TaskReminderWorkflow, reminderParams)
-				// Note: Not waiting for child workflow to complete as it runs independently
+				// Not waiting for the child to complete as it runs independently,
+				// only for it to start so it is not lost if this run ends
+				child := workflow.ExecuteChildWorkflow(childCtx, TaskReminderWorkflow, reminderParams)
+				if version >= recurringTaskContinueAsNewVersion {
+					if err := child.GetChildWorkflowExecution().Get(ctx, nil); err != nil {
+						logger.Warn("Failed to start task reminders", "occurrenceId", occurrence.OccurrenceID, "error", err)
+					}
+				}
 			}
 		}
 
 		// Calculate next due date
 		nextDueDate = calculateNextDueDate(params.RecurrenceRule, nextDueDate)
 		occurrenceCount++
+		runOccurrences++
 
 		// Listen for workflow cancellation
 		selector := workflow.NewSelector(ctx)
@@ -243,6 +403,24 @@ type TaskReminderWorkflowParams struct {
 	DueDate          time.Time        `json:"dueDate"`
 	Name             string           `json:"name"`
 	ReminderSettings ReminderSettings `json:"reminderSettings"`
+
+	// Rotation lists the members the task may be reassigned to, in order
+	Rotation []string `json:"rotation,omitempty"`
+}
+
+// Task review statuses sent with the task_review signal
+const (
+	TaskReviewPending  = "pending_review"
+	TaskReviewRejected = "rejected"
+)
+
+// TaskReviewSignal tells a task's reminder workflow the task was submitted for
+// an admin's review, as tasks completed by children are, or rejected back to
+// the assignee
+type TaskReviewSignal struct {
+	Status     string  `json:"status"`
+	ReviewedBy string  `json:"reviewedBy,omitempty"`
+	Note       *string `json:"note,omitempty"`
 }
 
 // TaskReminderWorkflow handles sending reminders for a specific task occurrence
@@ -262,6 +440,10 @@ func TaskReminderWorkflow(ctx workflow.Context, params TaskReminderWorkflowParam
 	}
 	ctx = workflow.WithActivityOptions(ctx, activityOptions)
 
+	// Reminders started before escalation tiers were added repeat the same
+	// reminder to the assignee
+	version := taskReminderWorkflowVersion(ctx)
+
 	// Wait for initial delay before first reminder
 	firstReminderTime := params.DueDate.Add(-params.ReminderSettings.InitialDelay)
 	currentTime := workflow.Now(ctx)
@@ -274,12 +456,22 @@ func TaskReminderWorkflow(ctx workflow.Context, params TaskReminderWorkflowParam
 		}
 	}
 
+	if version < taskReminderEscalationVersion {
+		return sendTaskReminders(ctx, params)
+	}
+	return escalateTaskReminders(ctx, params, version)
+}
+
+// sendTaskReminders reminds the assignee up to MaxReminders times
+func sendTaskReminders(ctx workflow.Context, params TaskReminderWorkflowParams) error {
+	logger := workflow.GetLogger(ctx)
+	var a *Activities
 	reminderCount := 0
 
 	for reminderCount < params.ReminderSettings.MaxReminders {
 		// Check if task is completed
 		var isCompleted bool
-		err := workflow.ExecuteActivity(ctx, CheckTaskCompletionActivity, CheckTaskCompletionRequest{
+		err := workflow.ExecuteActivity(ctx, a.CheckTaskCompletionActivity, CheckTaskCompletionRequest{
 			OccurrenceID: params.OccurrenceID,
 		}).Get(ctx, &isCompleted)
 		if err != nil {
@@ -295,7 +487,7 @@ func TaskReminderWorkflow(ctx workflow.Context, params TaskReminderWorkflowParam
 			reminderType = "escalated_reminder"
 		}
 
-		err = workflow.ExecuteActivity(ctx, SendNotificationActivity, NotificationRequest{
+		err = workflow.ExecuteActivity(ctx, a.SendNotificationActivity, NotificationRequest{
 			UserID:      params.AssignedTo,
 			HouseholdID: params.HouseholdID,
 			Title:       fmt.Sprintf("Task Reminder: %s", params.Name),
@@ -346,6 +538,324 @@ func TaskReminderWorkflow(ctx workflow.Context, params TaskReminderWorkflowParam
 	return nil
 }
 
+// escalateTaskReminders reminds the assignee until the task is done. After
+// EscalateAfter ignored reminders the household admins are notified, and once
+// MaxReminders are ignored the task is reassigned if the policy allows it.
+// Every escalation is recorded in the event log. From taskReminderAwayVersion
+// reminders are held while the assignee is away, and the task is not
+// reassigned to members who are away. From taskReminderReviewVersion
+// reminders are held while the task waits for an admin's review, and resume
+// if it is rejected. From taskReminderMissedVersion a task still open an
+// interval after the last reminder is recorded as missed by the assignee.
+func escalateTaskReminders(ctx workflow.Context, params TaskReminderWorkflowParams, version workflow.Version) error {
+	logger := workflow.GetLogger(ctx)
+	settings := params.ReminderSettings
+	policy := settings.Escalation.withDefaults()
+	var a *Activities
+
+	completed := false
+	completionChannel := workflow.GetSignalChannel(ctx, "task_completed")
+
+	// review is the latest task_review signal; reviewRequested is set once the
+	// admins were asked to review the current submission
+	var review *TaskReviewSignal
+	reviewRequested := false
+	reviewChannel := workflow.GetSignalChannel(ctx, "task_review")
+	receiveReview := func(c workflow.ReceiveChannel, more bool) {
+		var signal TaskReviewSignal
+		c.Receive(ctx, &signal)
+		review = &signal
+	}
+	taskDone := func() bool {
+		if completed {
+			return true
+		}
+		var done bool
+		err := workflow.ExecuteActivity(ctx, a.CheckTaskCompletionActivity, CheckTaskCompletionRequest{
+			OccurrenceID: params.OccurrenceID,
+		}).Get(ctx, &done)
+		if err != nil {
+			logger.Warn("Failed to check task completion", "error", err)
+			return false
+		}
+		return done
+	}
+
+	reminders := 0
+	for {
+		if taskDone() {
+			logger.Info("Task completed, stopping reminders", "occurrenceId", params.OccurrenceID, "reminders", reminders)
+			return nil
+		}
+
+		if version >= taskReminderReviewVersion {
+			for {
+				var signal TaskReviewSignal
+				if !reviewChannel.ReceiveAsync(&signal) {
+					break
+				}
+				review = &signal
+			}
+			if review != nil && review.Status == TaskReviewPending {
+				if !reviewRequested {
+					requestReview(ctx, params, policy.Admins)
+					reviewRequested = true
+				}
+				logger.Info("Task is pending review, holding reminders", "occurrenceId", params.OccurrenceID)
+				selector := workflow.NewSelector(ctx)
+				selector.AddReceive(completionChannel, func(c workflow.ReceiveChannel, more bool) {
+					c.Receive(ctx, nil)
+					completed = true
+				})
+				selector.AddReceive(reviewChannel, receiveReview)
+				selector.Select(ctx)
+				continue
+			}
+			reviewRequested = false
+		}
+
+		if version >= taskReminderAwayVersion {
+			if until := awayUntil(ctx, params); !until.IsZero() {
+				logger.Info("Assignee is away, holding reminders", "occurrenceId", params.OccurrenceID, "until", until)
+				waitCtx, cancelWait := workflow.WithCancel(ctx)
+				selector := workflow.NewSelector(ctx)
+				selector.AddFuture(workflow.NewTimer(waitCtx, until.Sub(workflow.Now(ctx))), func(f workflow.Future) {})
+				selector.AddReceive(completionChannel, func(c workflow.ReceiveChannel, more bool) {
+					c.Receive(ctx, nil)
+					completed = true
+				})
+				if version >= taskReminderReviewVersion {
+					selector.AddReceive(reviewChannel, receiveReview)
+				}
+				selector.Select(ctx)
+				cancelWait()
+				continue
+			}
+		}
+
+		escalated := settings.EscalateAfter > 0 && reminders >= settings.EscalateAfter
+		if escalated && reminders == settings.EscalateAfter {
+			escalateToAdmins(ctx, params, policy.Admins, reminders)
+		}
+		if reminders >= settings.MaxReminders {
+			break
+		}
+
+		reminderType := "reminder"
+		if escalated {
+			reminderType = "escalated_reminder"
+		}
+		reminder := NotificationRequest{
+			UserID:      params.AssignedTo,
+			HouseholdID: params.HouseholdID,
+			Title:       fmt.Sprintf("Task Reminder: %s", params.Name),
+			Body:        fmt.Sprintf("Don't forget to complete your task: %s (Due: %s)", params.Name, params.DueDate.Format("Jan 2, 3:04 PM")),
+			Data: map[string]string{
+				"taskId":       params.TaskID,
+				"occurrenceId": params.OccurrenceID,
+				"type":         reminderType,
+				"dueDate":      params.DueDate.Format(time.RFC3339),
+			},
+		}
+		if review != nil && review.Status == TaskReviewRejected {
+			// The first reminder after a rejection tells the assignee why
+			reminder.Title = fmt.Sprintf("Task Sent Back: %s", params.Name)
+			reminder.Body = fmt.Sprintf("%s needs another try (Due: %s)", params.Name, params.DueDate.Format("Jan 2, 3:04 PM"))
+			if review.Note != nil && *review.Note != "" {
+				reminder.Body = fmt.Sprintf("%s needs another try: %s", params.Name, *review.Note)
+			}
+			reminder.Data["type"] = "task_rejected"
+			review = nil
+		}
+		notifyTier(ctx, policy.Assignee, reminder)
+		reminders++
+		logger.Info("Sent task reminder", "occurrenceId", params.OccurrenceID, "count", reminders, "tier", TierAssignee)
+
+		// Give the assignee an interval to react, unless no tier is left
+		if version < taskReminderMissedVersion && reminders >= settings.MaxReminders && !policy.Reassign && settings.EscalateAfter != reminders {
+			return nil
+		}
+
+		timerCtx, cancelTimer := workflow.WithCancel(ctx)
+		selector := workflow.NewSelector(ctx)
+		selector.AddFuture(workflow.NewTimer(timerCtx, settings.ReminderInterval), func(f workflow.Future) {})
+		selector.AddReceive(completionChannel, func(c workflow.ReceiveChannel, more bool) {
+			c.Receive(ctx, nil)
+			completed = true
+		})
+		if version >= taskReminderReviewVersion {
+			selector.AddReceive(reviewChannel, receiveReview)
+		}
+		selector.Select(ctx)
+		cancelTimer()
+	}
+
+	if version >= taskReminderMissedVersion && reminders > 0 {
+		recordMissedChore(ctx, params, reminders)
+	}
+
+	if !policy.Reassign {
+		return nil
+	}
+
+	rotation := params.Rotation
+	if version >= taskReminderAwayVersion && len(rotation) > 0 {
+		var away []string
+		err := workflow.ExecuteActivity(ctx, a.AwayMembersActivity, AwayMembersRequest{
+			HouseholdID: params.HouseholdID,
+			At:          workflow.Now(ctx),
+		}).Get(ctx, &away)
+		if err != nil {
+			logger.Warn("Failed to list away members", "householdId", params.HouseholdID, "error", err)
+		}
+		rotation = withoutMembers(rotation, away, params.AssignedTo)
+	}
+
+	next := nextInRotation(rotation, params.AssignedTo)
+	if next == "" {
+		logger.Info("No member to reassign the task to", "occurrenceId", params.OccurrenceID)
+		return nil
+	}
+
+	var taskID string
+	err := workflow.ExecuteActivity(ctx, a.ReassignTaskActivity, ReassignTaskRequest{
+		OccurrenceID: params.OccurrenceID,
+		HouseholdID:  params.HouseholdID,
+		From:         params.AssignedTo,
+		To:           next,
+	}).Get(ctx, &taskID)
+	if err != nil {
+		logger.Warn("Failed to reassign task", "occurrenceId", params.OccurrenceID, "error", err)
+		return nil
+	}
+
+	recordEscalation(ctx, TaskEscalation{
+		TaskID:          taskID,
+		RecurringTaskID: params.TaskID,
+		OccurrenceID:    params.OccurrenceID,
+		HouseholdID:     params.HouseholdID,
+		Name:            params.Name,
+		Tier:            TierReassign,
+		AssignedTo:      params.AssignedTo,
+		Reminders:       reminders,
+		ReassignedTo:    next,
+	})
+
+	notifyTier(ctx, policy.Reassigned, NotificationRequest{
+		UserID:      next,
+		HouseholdID: params.HouseholdID,
+		Title:       fmt.Sprintf("Task Reassigned: %s", params.Name),
+		Body:        fmt.Sprintf("%s was reassigned to you (Due: %s)", params.Name, params.DueDate.Format("Jan 2, 3:04 PM")),
+		Data: map[string]string{
+			"taskId":       params.TaskID,
+			"occurrenceId": params.OccurrenceID,
+			"type":         "task_reassigned",
+			"dueDate":      params.DueDate.Format(time.RFC3339),
+		},
+	})
+
+	logger.Info("Task reassigned after ignored reminders", "occurrenceId", params.OccurrenceID, "from", params.AssignedTo, "to", next)
+	return nil
+}
+
+// escalateToAdmins tells the household admins, other than the assignee, that
+// a task's reminders are being ignored
+func escalateToAdmins(ctx workflow.Context, params TaskReminderWorkflowParams, tier EscalationTier, reminders int) {
+	var a *Activities
+	var admins []string
+	err := workflow.ExecuteActivity(ctx, a.ListHouseholdAdminsActivity, params.HouseholdID).Get(ctx, &admins)
+	if err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to list household admins", "householdId", params.HouseholdID, "error", err)
+		return
+	}
+
+	var notified []string
+	for _, admin := range admins {
+		if admin == params.AssignedTo {
+			continue
+		}
+		notifyTier(ctx, tier, NotificationRequest{
+			UserID:      admin,
+			HouseholdID: params.HouseholdID,
+			Title:       fmt.Sprintf("Task Overdue: %s", params.Name),
+			Body:        fmt.Sprintf("%s is still not done after %d reminders (Due: %s)", params.Name, reminders, params.DueDate.Format("Jan 2, 3:04 PM")),
+			Data: map[string]string{
+				"taskId":       params.TaskID,
+				"occurrenceId": params.OccurrenceID,
+				"type":         "task_escalated",
+				"assignedTo":   params.AssignedTo,
+				"dueDate":      params.DueDate.Format(time.RFC3339),
+			},
+		})
+		notified = append(notified, admin)
+	}
+
+	recordEscalation(ctx, TaskEscalation{
+		RecurringTaskID: params.TaskID,
+		OccurrenceID:    params.OccurrenceID,
+		HouseholdID:     params.HouseholdID,
+		Name:            params.Name,
+		Tier:            TierAdmins,
+		AssignedTo:      params.AssignedTo,
+		Reminders:       reminders,
+		Notified:        notified,
+	})
+}
+
+// requestReview asks the household admins, other than the assignee, to
+// review a task the assignee submitted
+func requestReview(ctx workflow.Context, params TaskReminderWorkflowParams, tier EscalationTier) {
+	var a *Activities
+	var admins []string
+	err := workflow.ExecuteActivity(ctx, a.ListHouseholdAdminsActivity, params.HouseholdID).Get(ctx, &admins)
+	if err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to list household admins", "householdId", params.HouseholdID, "error", err)
+		return
+	}
+
+	for _, admin := range admins {
+		if admin == params.AssignedTo {
+			continue
+		}
+		notifyTier(ctx, tier, NotificationRequest{
+			UserID:      admin,
+			HouseholdID: params.HouseholdID,
+			Title:       fmt.Sprintf("Task Awaiting Review: %s", params.Name),
+			Body:        fmt.Sprintf("%s was marked done and needs your approval", params.Name),
+			Data: map[string]string{
+				"taskId":       params.TaskID,
+				"occurrenceId": params.OccurrenceID,
+				"type":         "task_review_requested",
+				"assignedTo":   params.AssignedTo,
+			},
+		})
+	}
+}
+
+// recordMissedChore records that the assignee ignored every reminder
+func recordMissedChore(ctx workflow.Context, params TaskReminderWorkflowParams, reminders int) {
+	var a *Activities
+	err := workflow.ExecuteActivity(ctx, a.RecordMissedChoreActivity, MissedChoreRequest{
+		OccurrenceID: params.OccurrenceID,
+		HouseholdID:  params.HouseholdID,
+		AssignedTo:   params.AssignedTo,
+		Reminders:    reminders,
+	}).Get(ctx, nil)
+	if err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to record missed chore", "occurrenceId", params.OccurrenceID, "error", err)
+	}
+}
+
+// recordEscalation writes an escalation to the event log
+func recordEscalation(ctx workflow.Context, escalation TaskEscalation) {
+	var a *Activities
+	err := workflow.ExecuteActivity(ctx, a.RecordEscalationActivity, escalation).Get(ctx, nil)
+	if err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to record escalation",
+			"occurrenceId", escalation.OccurrenceID, "tier", escalation.Tier, "error", err)
+	}
+}
+
 // CreateTaskOccurrenceRequest represents a request to create a task occurrence
 type CreateTaskOccurrenceRequest struct {
 	TaskID      string         `json:"taskId"`
@@ -354,6 +864,17 @@ type CreateTaskOccurrenceRequest struct {
 	Description string         `json:"description"`
 	UserID      string         `json:"userId"`
 	HouseholdID string         `json:"householdId"`
+
+	// Assignment, when set, has the activity pick the occurrence's assignee
+	Assignment *OccurrenceAssignment `json:"assignment,omitempty"`
+}
+
+// OccurrenceAssignment asks for an occurrence's assignee to be picked by the
+// assignment engine from the series' members
+type OccurrenceAssignment struct {
+	Strategy string   `json:"strategy,omitempty"`
+	Members  []string `json:"members"`
+	Index    int      `json:"index"` // Position of the occurrence in the series
 }
 
 // CheckTaskCompletionRequest represents a request to check if a task is completed
@@ -361,36 +882,81 @@ type CheckTaskCompletionRequest struct {
 	OccurrenceID string `json:"occurrenceId"`
 }
 
-// CreateTaskOccurrenceActivity creates a new task occurrence in the database
-func CreateTaskOccurrenceActivity(ctx context.Context, req CreateTaskOccurrenceRequest) error {
+// CreateTaskOccurrenceActivity creates the task of an occurrence from the
+// recurring task template and returns its assignee, picked by the assignment
+// engine when requested. Creating an occurrence that already has a task is a
+// no-op returning the task's assignee, so retries do not duplicate it.
+func (a *Activities) CreateTaskOccurrenceActivity(ctx context.Context, req CreateTaskOccurrenceRequest) (string, error) {
 	logger := activity.GetLogger(ctx)
 	logger.Info("Creating task occurrence", "occurrenceId", req.Occurrence.OccurrenceID)
 
-	// Here you would typically:
-	// 1. Insert task occurrence into database
-	// 2. Send notifications to assigned user
-	// 3. Update household task board
-	// 4. Send real-time updates to connected clients
+	assignee := req.Occurrence.AssignedTo
+	if req.Assignment != nil {
+		pool, err := a.Store.AssignmentPool(ctx, req.TaskID, req.Assignment.Members, req.Occurrence.DueDate)
+		if err != nil {
+			return "", storeError(err, "recurring task "+req.TaskID)
+		}
+		assignee = assignment.Next(req.Assignment.Strategy, pool.Members, pool.Task, req.Assignment.Index)
+		logger.Info("Assigned task occurrence", "occurrenceId", req.Occurrence.OccurrenceID,
+			"strategy", req.Assignment.Strategy, "assignedTo", assignee)
+	}
 
-	activity.RecordHeartbeat(ctx, "Creating task occurrence record")
+	taskID, created, err := a.Store.CreateTaskOccurrence(ctx, store.TaskOccurrence{
+		OccurrenceID:    req.Occurrence.OccurrenceID,
+		RecurringTaskID: req.TaskID,
+		Title:           req.Name,
+		Description:     req.Description,
+		AssignedTo:      assignee,
+		CreatedBy:       req.UserID,
+		HouseholdID:     req.HouseholdID,
+		DueDate:         req.Occurrence.DueDate,
+	})
+	if err != nil {
+		return "", storeError(err, "recurring task "+req.TaskID)
+	}
+	if !created {
+		logger.Info("Task occurrence already exists", "occurrenceId", req.Occurrence.OccurrenceID, "taskId", taskID)
+		assignee, err = a.Store.OccurrenceAssignee(ctx, req.Occurrence.OccurrenceID)
+		if err != nil {
+			return "", storeError(err, "task occurrence "+req.Occurrence.OccurrenceID)
+		}
+		return assignee, nil
+	}
 
-	logger.Info("Task occurrence created successfully", "occurrenceId", req.Occurrence.OccurrenceID)
-	return nil
+	data := map[string]interface{}{
+		"taskId":       taskID,
+		"occurrenceId": req.Occurrence.OccurrenceID,
+		"householdId":  req.HouseholdID,
+		"title":        req.Name,
+		"status":       store.TaskPending,
+		"assignedTo":   assignee,
+		"dueDate":      req.Occurrence.DueDate,
+	}
+	if err := a.publish(ctx, events.TaskCreated, req.HouseholdID, req.UserID, data); err != nil {
+		return "", err
+	}
+	a.broadcast(ctx, req.HouseholdID, "task_created", data)
+
+	logger.Info("Task occurrence created successfully", "occurrenceId", req.Occurrence.OccurrenceID, "taskId", taskID)
+	return assignee, nil
 }
 
-// CheckTaskCompletionActivity checks if a task occurrence is completed
-func CheckTaskCompletionActivity(ctx context.Context, req CheckTaskCompletionRequest) (bool, error) {
+// CheckTaskCompletionActivity reports whether an occurrence no longer needs
+// reminders: its task is completed, cancelled or gone
+func (a *Activities) CheckTaskCompletionActivity(ctx context.Context, req CheckTaskCompletionRequest) (bool, error) {
 	logger := activity.GetLogger(ctx)
 	logger.Info("Checking task completion", "occurrenceId", req.OccurrenceID)
 
-	// Here you would typically:
-	// 1. Query database for task completion status
-	// 2. Return true if completed, false otherwise
-
-	activity.RecordHeartbeat(ctx, "Checking task status")
+	status, err := a.Store.OccurrenceStatus(ctx, req.OccurrenceID)
+	if errors.Is(err, store.ErrNotFound) {
+		logger.Warn("Task occurrence not found, treating it as done", "occurrenceId", req.OccurrenceID)
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
 
-	// Mock implementation - in real scenario, this would query the database
-	// For demo purposes, randomly return false (task not completed)
-	logger.Info("Task completion checked", "occurrenceId", req.OccurrenceID)
-	return false, nil
+	done := status == store.TaskCompleted || status == store.TaskCancelled
+	logger.Info("Task completion checked", "occurrenceId", req.OccurrenceID, "status", status, "done", done)
+	return done, nil
 }
@@ -31,6 +31,7 @@ type TimerSettings struct {
 	BreakInterval   int           `json:"breakInterval"` // For Pomodoro
 	Repetitions     int           `json:"repetitions"`   // Number of cycles
 	NotificationMsg string        `json:"notificationMsg"`
+	LiveActivity    bool          `json:"liveActivity"` // Mirror the timer in an iOS Live Activity
 }
 
 // TimerState represents the current state of a timer
@@ -71,11 +72,18 @@ func TimerWorkflow(ctx workflow.Context, params TimerWorkflowParams) error {
 	}
 	ctx = workflow.WithActivityOptions(ctx, activityOptions)
 
+	// Timers started before Live Activities were added never mirror one
+	if timerWorkflowVersion(ctx) < timerLiveActivityVersion {
+		params.Settings.LiveActivity = false
+	}
+
 	// Start timer activity
-	err := workflow.ExecuteActivity(ctx, StartTimerActivity, StartTimerRequest{
-		TimerID: params.TimerID,
-		UserID:  params.UserID,
-		Name:    params.Name,
+	var a *Activities
+	err := workflow.ExecuteActivity(ctx, a.StartTimerActivity, StartTimerRequest{
+		TimerID:     params.TimerID,
+		UserID:      params.UserID,
+		HouseholdID: params.HouseholdID,
+		Name:        params.Name,
 	}).Get(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to start timer: %w", err)
@@ -83,9 +91,16 @@ func TimerWorkflow(ctx workflow.Context, params TimerWorkflowParams) error {
 
 	state.Status = "running"
 
+	if params.Type == "pomodoro" {
+		state.RemainingTime = withPomodoroDefaults(params.Settings).WorkDuration
+	}
+	if params.Settings.LiveActivity {
+		sendLiveActivity(ctx, timerLiveActivity(ctx, params, &state, LiveActivityStart))
+	}
+
 	// Send start notification if enabled
 	if params.Settings.NotifyOnStart {
-		err = workflow.ExecuteActivity(ctx, SendNotificationActivity, NotificationRequest{
+		err = workflow.ExecuteActivity(ctx, a.SendNotificationActivity, NotificationRequest{
 			UserID:      params.UserID,
 			HouseholdID: params.HouseholdID,
 			Title:       "Timer Started",
@@ -116,11 +131,19 @@ func TimerWorkflow(ctx workflow.Context, params TimerWorkflowParams) error {
 		return fmt.Errorf("timer execution failed: %w", err)
 	}
 
-	// Complete timer
-	state.Status = "completed"
-	err = workflow.ExecuteActivity(ctx, CompleteTimerActivity, CompleteTimerRequest{
+	if state.Status != "stopped" {
+		state.Status = "completed"
+	}
+	if params.Settings.LiveActivity {
+		sendLiveActivity(ctx, timerLiveActivity(ctx, params, &state, LiveActivityEnd))
+	}
+
+	// Complete timer, recording whether it ran out or was stopped
+	err = workflow.ExecuteActivity(ctx, a.CompleteTimerActivity, CompleteTimerRequest{
 		TimerID:     params.TimerID,
 		UserID:      params.UserID,
+		HouseholdID: params.HouseholdID,
+		Name:        params.Name,
 		ElapsedTime: state.ElapsedTime,
 		Status:      state.Status,
 	}).Get(ctx, nil)
@@ -130,7 +153,7 @@ func TimerWorkflow(ctx workflow.Context, params TimerWorkflowParams) error {
 
 	// Send completion notification
 	if params.Settings.NotifyOnFinish {
-		err = workflow.ExecuteActivity(ctx, SendNotificationActivity, NotificationRequest{
+		err = workflow.ExecuteActivity(ctx, a.SendNotificationActivity, NotificationRequest{
 			UserID:      params.UserID,
 			HouseholdID: params.HouseholdID,
 			Title:       "Timer Completed",
@@ -151,85 +174,61 @@ func TimerWorkflow(ctx workflow.Context, params TimerWorkflowParams) error {
 
 // runCountdownTimer implements countdown timer logic
 func runCountdownTimer(ctx workflow.Context, params TimerWorkflowParams, state *TimerState) error {
-	logger := workflow.GetLogger(ctx)
-
-	selector := workflow.NewSelector(ctx)
-	timerFuture := workflow.NewTimer(ctx, params.Duration)
+	waitTimerPeriod(ctx, params, state, params.Duration)
 
-	// Listen for timer completion
-	selector.AddFuture(timerFuture, func(f workflow.Future) {
-		// Timer completed naturally
-		state.Status = "completed"
-		state.RemainingTime = 0
-		state.ElapsedTime = params.Duration
-	})
-
-	// Listen for pause/resume signals
-	pauseChannel := workflow.GetSignalChannel(ctx, "pause_timer")
-	resumeChannel := workflow.GetSignalChannel(ctx, "resume_timer")
-	stopChannel := workflow.GetSignalChannel(ctx, "stop_timer")
-
-	selector.AddReceive(pauseChannel, func(c workflow.ReceiveChannel, more bool) {
-		if more {
-			state.Status = "paused"
-			state.LastPauseStart = workflow.Now(ctx)
-			logger.Info("Timer paused", "timerId", params.TimerID)
-		}
-	})
-
-	selector.AddReceive(resumeChannel, func(c workflow.ReceiveChannel, more bool) {
-		if more && state.Status == "paused" {
-			pauseDuration := workflow.Now(ctx).Sub(state.LastPauseStart)
-			state.PausedTime += pauseDuration
-			state.Status = "running"
-			logger.Info("Timer resumed", "timerId", params.TimerID, "pausedFor", pauseDuration)
-		}
-	})
-
-	selector.AddReceive(stopChannel, func(c workflow.ReceiveChannel, more bool) {
-		if more {
-			state.Status = "stopped"
-			logger.Info("Timer stopped", "timerId", params.TimerID)
-		}
-	})
-
-	// Wait for timer completion or signal
-	selector.Select(ctx)
+	if state.Status == "stopped" {
+		state.ElapsedTime = params.Duration - state.RemainingTime
+		return nil
+	}
 
+	state.Status = "completed"
+	state.RemainingTime = 0
+	state.ElapsedTime = params.Duration
 	return nil
 }
 
-// runPomodoroTimer implements Pomodoro technique timer
-func runPomodoroTimer(ctx workflow.Context, params TimerWorkflowParams, state *TimerState) error {
-	logger := workflow.GetLogger(ctx)
-
-	workDuration := params.Settings.WorkDuration
-	if workDuration == 0 {
-		workDuration = 25 * time.Minute // Default Pomodoro work period
+// withPomodoroDefaults fills in the classic Pomodoro values for unset settings
+func withPomodoroDefaults(settings TimerSettings) TimerSettings {
+	if settings.WorkDuration == 0 {
+		settings.WorkDuration = 25 * time.Minute // Default Pomodoro work period
 	}
-
-	shortBreak := params.Settings.ShortBreak
-	if shortBreak == 0 {
-		shortBreak = 5 * time.Minute // Default short break
+	if settings.ShortBreak == 0 {
+		settings.ShortBreak = 5 * time.Minute // Default short break
 	}
-
-	longBreak := params.Settings.LongBreak
-	if longBreak == 0 {
-		longBreak = 15 * time.Minute // Default long break
+	if settings.LongBreak == 0 {
+		settings.LongBreak = 15 * time.Minute // Default long break
 	}
-
-	breakInterval := params.Settings.BreakInterval
-	if breakInterval == 0 {
-		breakInterval = 4 // Long break every 4 cycles
+	if settings.BreakInterval == 0 {
+		settings.BreakInterval = 4 // Long break every 4 cycles
 	}
-
-	maxCycles := params.Settings.Repetitions
-	if maxCycles == 0 {
-		maxCycles = 4 // Default 4 Pomodoro cycles
+	if settings.Repetitions == 0 {
+		settings.Repetitions = 4 // Default 4 Pomodoro cycles
 	}
+	return settings
+}
+
+// runPomodoroTimer implements Pomodoro technique timer
+func runPomodoroTimer(ctx workflow.Context, params TimerWorkflowParams, state *TimerState) error {
+	logger := workflow.GetLogger(ctx)
+
+	settings := withPomodoroDefaults(params.Settings)
+	workDuration := settings.WorkDuration
+	shortBreak := settings.ShortBreak
+	longBreak := settings.LongBreak
+	breakInterval := settings.BreakInterval
+	maxCycles := settings.Repetitions
 
 	for state.CurrentCycle <= maxCycles {
 		// Work period
+		if state.IsBreak && params.Settings.LiveActivity {
+			state.IsBreak = false
+			state.RemainingTime = workDuration
+			req := timerLiveActivity(ctx, params, state, LiveActivityUpdate)
+			req.AlertTitle = params.Name
+			req.AlertBody = "Work time!"
+			req.Urgent = true
+			sendLiveActivity(ctx, req)
+		}
 		state.IsBreak = false
 		logger.Info("Starting work period", "cycle", state.CurrentCycle)
 
@@ -256,6 +255,15 @@ func runPomodoroTimer(ctx workflow.Context, params TimerWorkflowParams, state *T
 
 			logger.Info("Starting break period", "cycle", state.CurrentCycle, "duration", breakDuration)
 
+			if params.Settings.LiveActivity {
+				state.RemainingTime = breakDuration
+				req := timerLiveActivity(ctx, params, state, LiveActivityUpdate)
+				req.AlertTitle = params.Name
+				req.AlertBody = breakMsg
+				req.Urgent = true
+				sendLiveActivity(ctx, req)
+			}
+
 			err = runTimerPeriod(ctx, params, state, breakDuration, breakMsg)
 			if err != nil || state.Status == "stopped" {
 				return err
@@ -282,27 +290,40 @@ func runStopwatchTimer(ctx workflow.Context, params TimerWorkflowParams, state *
 	startTime := workflow.Now(ctx)
 
 	selector.AddReceive(stopChannel, func(c workflow.ReceiveChannel, more bool) {
-		if more {
-			state.Status = "stopped"
-			state.ElapsedTime = workflow.Now(ctx).Sub(startTime) - state.PausedTime
-			logger.Info("Stopwatch stopped", "timerId", params.TimerID, "elapsed", state.ElapsedTime)
+		c.Receive(ctx, nil)
+		if state.Status == "paused" {
+			state.PausedTime += workflow.Now(ctx).Sub(state.LastPauseStart)
 		}
+		state.Status = "stopped"
+		state.ElapsedTime = workflow.Now(ctx).Sub(startTime) - state.PausedTime
+		logger.Info("Stopwatch stopped", "timerId", params.TimerID, "elapsed", state.ElapsedTime)
 	})
 
 	selector.AddReceive(pauseChannel, func(c workflow.ReceiveChannel, more bool) {
-		if more && state.Status == "running" {
+		c.Receive(ctx, nil)
+		if state.Status == "running" {
 			state.Status = "paused"
 			state.LastPauseStart = workflow.Now(ctx)
+			state.ElapsedTime = state.LastPauseStart.Sub(startTime) - state.PausedTime
 			logger.Info("Stopwatch paused", "timerId", params.TimerID)
+
+			if params.Settings.LiveActivity {
+				sendLiveActivity(ctx, timerLiveActivity(ctx, params, state, LiveActivityUpdate))
+			}
 		}
 	})
 
 	selector.AddReceive(resumeChannel, func(c workflow.ReceiveChannel, more bool) {
-		if more && state.Status == "paused" {
+		c.Receive(ctx, nil)
+		if state.Status == "paused" {
 			pauseDuration := workflow.Now(ctx).Sub(state.LastPauseStart)
 			state.PausedTime += pauseDuration
 			state.Status = "running"
 			logger.Info("Stopwatch resumed", "timerId", params.TimerID)
+
+			if params.Settings.LiveActivity {
+				sendLiveActivity(ctx, timerLiveActivity(ctx, params, state, LiveActivityUpdate))
+			}
 		}
 	})
 
@@ -316,44 +337,12 @@ func runStopwatchTimer(ctx workflow.Context, params TimerWorkflowParams, state *
 
 // runTimerPeriod runs a timer for a specific duration with pause/resume support
 func runTimerPeriod(ctx workflow.Context, params TimerWorkflowParams, state *TimerState, duration time.Duration, message string) error {
-	selector := workflow.NewSelector(ctx)
-	timerFuture := workflow.NewTimer(ctx, duration)
-
-	selector.AddFuture(timerFuture, func(f workflow.Future) {
-		// Period completed
-	})
-
-	// Handle pause/resume during period
-	pauseChannel := workflow.GetSignalChannel(ctx, "pause_timer")
-	resumeChannel := workflow.GetSignalChannel(ctx, "resume_timer")
-	stopChannel := workflow.GetSignalChannel(ctx, "stop_timer")
-
-	selector.AddReceive(pauseChannel, func(c workflow.ReceiveChannel, more bool) {
-		if more {
-			state.Status = "paused"
-			state.LastPauseStart = workflow.Now(ctx)
-		}
-	})
-
-	selector.AddReceive(resumeChannel, func(c workflow.ReceiveChannel, more bool) {
-		if more && state.Status == "paused" {
-			pauseDuration := workflow.Now(ctx).Sub(state.LastPauseStart)
-			state.PausedTime += pauseDuration
-			state.Status = "running"
-		}
-	})
-
-	selector.AddReceive(stopChannel, func(c workflow.ReceiveChannel, more bool) {
-		if more {
-			state.Status = "stopped"
-		}
-	})
-
-	selector.Select(ctx)
+	waitTimerPeriod(ctx, params, state, duration)
 
 	// Send period completion notification
 	if state.Status != "stopped" {
-		err := workflow.ExecuteActivity(ctx, SendNotificationActivity, NotificationRequest{
+		var a *Activities
+		err := workflow.ExecuteActivity(ctx, a.SendNotificationActivity, NotificationRequest{
 			UserID:      params.UserID,
 			HouseholdID: params.HouseholdID,
 			Title:       params.Name,
@@ -372,3 +361,120 @@ func runTimerPeriod(ctx workflow.Context, params TimerWorkflowParams, state *Tim
 
 	return nil
 }
+
+// waitTimerPeriod waits until a period has run for duration, not counting
+// time spent paused. It returns early when the timer is stopped.
+func waitTimerPeriod(ctx workflow.Context, params TimerWorkflowParams, state *TimerState, duration time.Duration) {
+	logger := workflow.GetLogger(ctx)
+
+	pauseChannel := workflow.GetSignalChannel(ctx, "pause_timer")
+	resumeChannel := workflow.GetSignalChannel(ctx, "resume_timer")
+	stopChannel := workflow.GetSignalChannel(ctx, "stop_timer")
+
+	state.RemainingTime = duration
+	for {
+		runningSince := workflow.Now(ctx)
+		timerCtx, cancelTimer := workflow.WithCancel(ctx)
+		elapsed := false
+
+		selector := workflow.NewSelector(ctx)
+		if state.Status == "running" {
+			selector.AddFuture(workflow.NewTimer(timerCtx, state.RemainingTime), func(f workflow.Future) {
+				elapsed = true
+			})
+		}
+
+		selector.AddReceive(pauseChannel, func(c workflow.ReceiveChannel, more bool) {
+			c.Receive(ctx, nil)
+			if state.Status == "running" {
+				state.Status = "paused"
+				state.LastPauseStart = workflow.Now(ctx)
+				state.RemainingTime -= state.LastPauseStart.Sub(runningSince)
+				logger.Info("Timer paused", "timerId", params.TimerID, "remaining", state.RemainingTime)
+			}
+		})
+
+		selector.AddReceive(resumeChannel, func(c workflow.ReceiveChannel, more bool) {
+			c.Receive(ctx, nil)
+			if state.Status == "paused" {
+				pauseDuration := workflow.Now(ctx).Sub(state.LastPauseStart)
+				state.PausedTime += pauseDuration
+				state.Status = "running"
+				logger.Info("Timer resumed", "timerId", params.TimerID, "pausedFor", pauseDuration)
+			}
+		})
+
+		selector.AddReceive(stopChannel, func(c workflow.ReceiveChannel, more bool) {
+			c.Receive(ctx, nil)
+			if state.Status == "running" {
+				state.RemainingTime -= workflow.Now(ctx).Sub(runningSince)
+			}
+			state.Status = "stopped"
+			logger.Info("Timer stopped", "timerId", params.TimerID)
+		})
+
+		status := state.Status
+		selector.Select(ctx)
+		cancelTimer()
+
+		if elapsed {
+			state.RemainingTime = 0
+			return
+		}
+		if state.Status == "stopped" {
+			return
+		}
+		if state.Status != status && params.Settings.LiveActivity {
+			sendLiveActivity(ctx, timerLiveActivity(ctx, params, state, LiveActivityUpdate))
+		}
+	}
+}
+
+// timerLiveActivity describes the timer's current state as a Live Activity event.
+// Running timers carry the instant they end (or, for a stopwatch, started)
+// so the device can count without further pushes.
+func timerLiveActivity(ctx workflow.Context, params TimerWorkflowParams, state *TimerState, event string) LiveActivityRequest {
+	now := workflow.Now(ctx)
+
+	contentState := map[string]interface{}{
+		"status":  state.Status,
+		"cycle":   state.CurrentCycle,
+		"isBreak": state.IsBreak,
+	}
+
+	req := LiveActivityRequest{
+		ActivityID:   params.TimerID,
+		UserID:       params.UserID,
+		Event:        event,
+		ContentState: contentState,
+	}
+
+	if params.Type == "stopwatch" {
+		contentState["elapsedSeconds"] = int64(state.ElapsedTime / time.Second)
+		if state.Status == "running" {
+			contentState["startedAt"] = now.Add(-state.ElapsedTime).Unix()
+		}
+	} else {
+		contentState["remainingSeconds"] = int64(state.RemainingTime / time.Second)
+		if state.Status == "running" {
+			endsAt := now.Add(state.RemainingTime)
+			contentState["endsAt"] = endsAt.Unix()
+			req.StaleDate = endsAt.Add(liveActivityStaleGrace)
+		}
+	}
+
+	switch event {
+	case LiveActivityStart:
+		req.AttributesType = TimerActivityAttributes
+		req.Attributes = map[string]interface{}{
+			"timerId": params.TimerID,
+			"name":    params.Name,
+			"type":    params.Type,
+		}
+	case LiveActivityEnd:
+		req.DismissalDate = now.Add(liveActivityDismissal)
+		req.Urgent = true
+	}
+
+	return req
+}
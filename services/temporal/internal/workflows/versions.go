@@ -0,0 +1,140 @@
+package workflows
+
+import "go.temporal.io/sdk/workflow"
+
+// Workflow versioning
+//
+// A workflow that is running when a new worker is deployed is replayed by the
+// new code, which must issue the same commands (activities, timers, child
+// workflows, markers) in the same order as the code that wrote its history.
+// A change that alters those commands is therefore made behind a version:
+//
+//   - each workflow has one change ID, recorded with workflow.GetVersion the
+//     first time the workflow reaches a versioned branch point
+//   - a command-altering change bumps the workflow's current version and
+//     keeps the previous behaviour for executions that recorded a lower one
+//   - executions started before the workflow was versioned record no marker
+//     and replay as workflow.DefaultVersion
+//   - a branch is removed only once no execution that can reach it is open,
+//     by raising the minimum supported version
+//
+// testdata holds recorded histories of every version still supported;
+// TestReplayRecordedHistories replays them against the current code and fails
+// when a change is not replay safe. Record a history of each new version
+// alongside the change.
+const (
+	timerChangeID         = "timer-workflow"
+	laundryChangeID       = "laundry-workflow"
+	recurringTaskChangeID = "recurring-task-workflow"
+	taskReminderChangeID  = "task-reminder-workflow"
+	billLifecycleChangeID = "bill-lifecycle-workflow"
+)
+
+// Timer workflow versions
+const (
+	// timerLiveActivityVersion mirrors timers in iOS Live Activities
+	timerLiveActivityVersion workflow.Version = 1
+
+	timerVersion = timerLiveActivityVersion
+)
+
+// Laundry workflow versions
+const (
+	// laundryLiveActivityVersion mirrors loads in iOS Live Activities, which
+	// replace the dry cycle started push
+	laundryLiveActivityVersion workflow.Version = 1
+
+	laundryVersion = laundryLiveActivityVersion
+)
+
+// Recurring task workflow versions
+const (
+	// recurringTaskContinueAsNewVersion continues long-running series as new
+	// and waits for each occurrence's reminders to start
+	recurringTaskContinueAsNewVersion workflow.Version = 1
+
+	// recurringTaskAwayVersion pauses the series while the household is away
+	// and passes over away members when auto-assigning
+	recurringTaskAwayVersion workflow.Version = 2
+
+	// recurringTaskAssignmentVersion has CreateTaskOccurrenceActivity pick
+	// each occurrence's assignee with the assignment engine
+	recurringTaskAssignmentVersion workflow.Version = 3
+
+	// recurringTaskPausesVersion starts a series with the household's current
+	// away periods and carries pauses signalled just before it continues as
+	// new
+	recurringTaskPausesVersion workflow.Version = 4
+
+	recurringTaskVersion = recurringTaskPausesVersion
+)
+
+// Task reminder workflow versions
+const (
+	// taskReminderInitialVersion is the first versioned release
+	taskReminderInitialVersion workflow.Version = 1
+
+	// taskReminderEscalationVersion escalates ignored reminders to the
+	// household admins and reassigns the task
+	taskReminderEscalationVersion workflow.Version = 2
+
+	// taskReminderAwayVersion holds reminders while the assignee is away and
+	// does not reassign the task to away members
+	taskReminderAwayVersion workflow.Version = 3
+
+	// taskReminderReviewVersion holds reminders while a task waits for an
+	// admin's review and asks the admins to review it
+	taskReminderReviewVersion workflow.Version = 4
+
+	// taskReminderMissedVersion gives the assignee an interval after the last
+	// reminder and records the task as missed if it is still open then
+	taskReminderMissedVersion workflow.Version = 5
+
+	taskReminderVersion = taskReminderMissedVersion
+)
+
+// Bill lifecycle workflow versions
+const (
+	// billLifecycleInitialVersion is the first release
+	billLifecycleInitialVersion workflow.Version = 1
+
+	// billLifecycleSeriesVersion creates the next instance of a recurring
+	// bill once the bill is paid or due
+	billLifecycleSeriesVersion workflow.Version = 2
+
+	// billLifecycleUpdateVersion applies bill_updated signals, moving the
+	// reminder and due date waits and the recipient of later notifications
+	billLifecycleUpdateVersion workflow.Version = 3
+
+	billLifecycleVersion = billLifecycleUpdateVersion
+)
+
+// timerWorkflowVersion returns the version of the timer workflow the
+// execution runs
+func timerWorkflowVersion(ctx workflow.Context) workflow.Version {
+	return workflow.GetVersion(ctx, timerChangeID, workflow.DefaultVersion, timerVersion)
+}
+
+// laundryWorkflowVersion returns the version of the laundry workflow the
+// execution runs
+func laundryWorkflowVersion(ctx workflow.Context) workflow.Version {
+	return workflow.GetVersion(ctx, laundryChangeID, workflow.DefaultVersion, laundryVersion)
+}
+
+// recurringTaskWorkflowVersion returns the version of the recurring task
+// workflow the execution runs
+func recurringTaskWorkflowVersion(ctx workflow.Context) workflow.Version {
+	return workflow.GetVersion(ctx, recurringTaskChangeID, workflow.DefaultVersion, recurringTaskVersion)
+}
+
+// taskReminderWorkflowVersion returns the version of the task reminder
+// workflow the execution runs
+func taskReminderWorkflowVersion(ctx workflow.Context) workflow.Version {
+	return workflow.GetVersion(ctx, taskReminderChangeID, workflow.DefaultVersion, taskReminderVersion)
+}
+
+// billLifecycleWorkflowVersion returns the version of the bill lifecycle
+// workflow the execution runs
+func billLifecycleWorkflowVersion(ctx workflow.Context) workflow.Version {
+	return workflow.GetVersion(ctx, billLifecycleChangeID, workflow.DefaultVersion, billLifecycleVersion)
+}
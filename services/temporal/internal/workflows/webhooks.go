@@ -0,0 +1,314 @@
+package workflows
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/househelper/temporal/internal/store"
+	"github.com/househelper/temporal/pkg/webhooks"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// WebhookDisableThreshold is the number of consecutive events an endpoint
+// may fail to receive, after all retries, before it is disabled
+const WebhookDisableThreshold = 5
+
+// WebhookEvent is a household event delivered to the household's webhooks.
+// It is the JSON body of every delivery.
+type WebhookEvent struct {
+	ID          string                 `json:"id"`
+	Type        string                 `json:"type"`
+	Source      string                 `json:"source,omitempty"`
+	HouseholdID string                 `json:"householdId"`
+	UserID      string                 `json:"userId,omitempty"`
+	Timestamp   time.Time              `json:"timestamp"`
+	Data        map[string]interface{} `json:"data,omitempty"`
+}
+
+// WebhookDispatchResult summarises the deliveries of an event
+type WebhookDispatchResult struct {
+	Endpoints int      `json:"endpoints"`
+	Delivered int      `json:"delivered"`
+	Failed    int      `json:"failed"`
+	Disabled  []string `json:"disabled,omitempty"`
+}
+
+// ListWebhookEndpointsRequest selects the endpoints an event goes to
+type ListWebhookEndpointsRequest struct {
+	HouseholdID string `json:"householdId"`
+	EventType   string `json:"eventType"`
+}
+
+// DeliverWebhookRequest represents one event to deliver to one endpoint.
+// The endpoint's URL and secret are read by the activity so the secret is
+// not written to the workflow history.
+type DeliverWebhookRequest struct {
+	EndpointID string       `json:"endpointId"`
+	Event      WebhookEvent `json:"event"`
+}
+
+// webhookRetryPolicy spreads a delivery's attempts over about an hour
+var webhookRetryPolicy = &temporal.RetryPolicy{
+	InitialInterval:    30 * time.Second,
+	BackoffCoefficient: 2.0,
+	MaximumInterval:    30 * time.Minute,
+	MaximumAttempts:    8,
+}
+
+// WebhookDispatchWorkflow delivers an event to every active endpoint of its
+// household subscribed to the event's type. Each delivery retries with
+// backoff on its own; an endpoint that keeps failing is disabled.
+func WebhookDispatchWorkflow(ctx workflow.Context, event WebhookEvent) (*WebhookDispatchResult, error) {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("Dispatching webhook event", "eventId", event.ID, "type", event.Type, "householdId", event.HouseholdID)
+
+	var a *Activities
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumInterval:    time.Minute,
+			MaximumAttempts:    5,
+		},
+	})
+
+	var endpointIDs []string
+	err := workflow.ExecuteActivity(ctx, a.ListWebhookEndpointsActivity, ListWebhookEndpointsRequest{
+		HouseholdID: event.HouseholdID,
+		EventType:   event.Type,
+	}).Get(ctx, &endpointIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &WebhookDispatchResult{Endpoints: len(endpointIDs)}
+	if len(endpointIDs) == 0 {
+		return result, nil
+	}
+
+	deliveryCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy:         webhookRetryPolicy,
+	})
+	deliveries := make([]workflow.Future, len(endpointIDs))
+	for i, endpointID := range endpointIDs {
+		deliveries[i] = workflow.ExecuteActivity(deliveryCtx, a.DeliverWebhookActivity, DeliverWebhookRequest{
+			EndpointID: endpointID,
+			Event:      event,
+		})
+	}
+
+	for i, endpointID := range endpointIDs {
+		err := deliveries[i].Get(ctx, nil)
+		if err == nil {
+			result.Delivered++
+			continue
+		}
+
+		var appErr *temporal.ApplicationError
+		if errors.As(err, &appErr) && appErr.Type() == "WebhookEndpointGone" {
+			// Deleted or disabled while the event was being delivered
+			continue
+		}
+
+		result.Failed++
+		logger.Warn("Webhook delivery failed", "endpointId", endpointID, "eventId", event.ID, "error", err)
+
+		var disabled bool
+		err = workflow.ExecuteActivity(ctx, a.RecordWebhookFailureActivity, endpointID).Get(ctx, &disabled)
+		if err != nil {
+			logger.Error("Failed to record webhook failure", "endpointId", endpointID, "error", err)
+			continue
+		}
+		if disabled {
+			result.Disabled = append(result.Disabled, endpointID)
+		}
+	}
+
+	logger.Info("Webhook event dispatched", "eventId", event.ID,
+		"delivered", result.Delivered, "failed", result.Failed, "disabled", len(result.Disabled))
+	return result, nil
+}
+
+// ListWebhookEndpointsActivity returns the IDs of the endpoints an event goes to
+func (a *Activities) ListWebhookEndpointsActivity(ctx context.Context, req ListWebhookEndpointsRequest) ([]string, error) {
+	if req.HouseholdID == "" {
+		return nil, nil
+	}
+	return a.Store.WebhookEndpoints(ctx, req.HouseholdID, req.EventType)
+}
+
+// DeliverWebhookActivity POSTs an event to an endpoint, signed with the
+// endpoint's secret, and logs the attempt with its response code. Timeouts,
+// 408, 429 and 5xx responses are retried; other responses outside 2xx are
+// final.
+func (a *Activities) DeliverWebhookActivity(ctx context.Context, req DeliverWebhookRequest) error {
+	logger := activity.GetLogger(ctx)
+	attempt := int(activity.GetInfo(ctx).Attempt)
+
+	endpoint, err := a.Store.WebhookEndpoint(ctx, req.EndpointID)
+	if errors.Is(err, store.ErrNotFound) {
+		return temporal.NewNonRetryableApplicationError("webhook endpoint deleted or disabled", "WebhookEndpointGone", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(req.Event)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("failed to marshal webhook event", "InvalidEvent", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("invalid webhook URL", "WebhookRejected", err)
+	}
+	sentAt := time.Now()
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", "HouseHelper-Webhooks/1.0")
+	httpReq.Header.Set(webhooks.EventHeader, req.Event.Type)
+	httpReq.Header.Set(webhooks.DeliveryHeader, req.Event.ID)
+	httpReq.Header.Set(webhooks.TimestampHeader, strconv.FormatInt(sentAt.Unix(), 10))
+	httpReq.Header.Set(webhooks.SignatureHeader, webhooks.Sign(endpoint.Secret, sentAt, body))
+
+	resp, err := a.webhookClient().Do(httpReq)
+	record := store.WebhookAttempt{
+		EndpointID: req.EndpointID,
+		EventID:    req.Event.ID,
+		EventType:  req.Event.Type,
+		Attempt:    attempt,
+		Duration:   time.Since(sentAt),
+	}
+	blocked := errors.Is(err, errWebhookAddressBlocked)
+	if err == nil {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+		record.StatusCode = resp.StatusCode
+	} else if blocked {
+		// The log is visible to the household; it does not say what the
+		// endpoint's host resolved to
+		record.Error = errWebhookAddressBlocked.Error()
+	} else {
+		record.Error = err.Error()
+	}
+	if resp != nil && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		record.Error = resp.Status
+	}
+
+	// The log is informational; a delivery is not repeated because its
+	// attempt could not be recorded
+	if recordErr := a.Store.RecordWebhookAttempt(ctx, record); recordErr != nil {
+		logger.Warn("Failed to record webhook attempt", "endpointId", req.EndpointID, "error", recordErr)
+	}
+
+	if blocked {
+		return temporal.NewNonRetryableApplicationError("webhook endpoint address is not public", "WebhookRejected", err)
+	}
+	if err != nil {
+		return fmt.Errorf("webhook delivery failed: %w", err)
+	}
+
+	logger.Info("Webhook endpoint responded", "endpointId", req.EndpointID, "eventId", req.Event.ID,
+		"status", resp.StatusCode, "attempt", attempt)
+
+	switch code := resp.StatusCode; {
+	case code >= 200 && code <= 299:
+		if err := a.Store.ResetWebhookFailures(ctx, req.EndpointID); err != nil {
+			logger.Warn("Failed to reset webhook failures", "endpointId", req.EndpointID, "error", err)
+		}
+		return nil
+	case code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= 500:
+		return fmt.Errorf("webhook endpoint responded %s", resp.Status)
+	default:
+		return temporal.NewNonRetryableApplicationError("webhook endpoint responded "+resp.Status, "WebhookRejected", nil)
+	}
+}
+
+// RecordWebhookFailureActivity counts an event the endpoint failed to receive
+// and reports whether the endpoint is now disabled
+func (a *Activities) RecordWebhookFailureActivity(ctx context.Context, endpointID string) (bool, error) {
+	disabled, err := a.Store.RecordWebhookFailure(ctx, endpointID, WebhookDisableThreshold)
+	if errors.Is(err, store.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if disabled {
+		activity.GetLogger(ctx).Warn("Webhook endpoint disabled after repeated failures", "endpointId", endpointID)
+	}
+	return disabled, nil
+}
+
+func (a *Activities) webhookClient() *http.Client {
+	if a.WebhookClient != nil {
+		return a.WebhookClient
+	}
+	return defaultWebhookClient
+}
+
+// errWebhookAddressBlocked is returned when an endpoint's host resolves to
+// an address inside the cluster or the host's networks
+var errWebhookAddressBlocked = errors.New("endpoint address is not public")
+
+// defaultWebhookClient does not follow redirects, so a redirect is reported
+// as the endpoint's response rather than replayed against another URL. It
+// only connects to public addresses; the check runs on the address dialed,
+// so a host that resolves differently after it was registered is caught too.
+var defaultWebhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: dialPublicOnly,
+		}).DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     90 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// dialPublicOnly refuses connections to loopback, private, link-local and
+// other non-public addresses
+func dialPublicOnly(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return errWebhookAddressBlocked
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !publicAddress(ip) {
+		return errWebhookAddressBlocked
+	}
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, RFC 6598
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// publicAddress reports whether ip is routable on the internet
+func publicAddress(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	if ip4 := ip.To4(); ip4 != nil && (ip4[0] == 0 || sharedAddressSpace.Contains(ip4)) {
+		return false
+	}
+	return true
+}
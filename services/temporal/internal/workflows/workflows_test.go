@@ -1,14 +1,26 @@
 package workflows
 
 import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/househelper/temporal/internal/store"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
 )
 
+// activities names the Activities methods in mock expectations
+var activities *Activities
+
 type TimerWorkflowTestSuite struct {
 	suite.Suite
 	testsuite.WorkflowTestSuite
@@ -19,9 +31,8 @@ func (s *TimerWorkflowTestSuite) SetupTest() {
 	s.env = s.NewTestWorkflowEnvironment()
 	
 	// Register activities
-	s.env.RegisterActivity(StartTimerActivity)
-	s.env.RegisterActivity(CompleteTimerActivity)
-	s.env.RegisterActivity(SendNotificationActivity)
+	s.env.RegisterActivity(&Activities{})
+	s.env.RegisterActivity(&LiveActivityActivities{})
 }
 
 func (s *TimerWorkflowTestSuite) AfterTest(suiteName, testName string) {
@@ -43,13 +54,14 @@ func (s *TimerWorkflowTestSuite) TestCountdownTimer() {
 	}
 
 	// Mock activity expectations
-	s.env.OnActivity(StartTimerActivity, mock.Anything, StartTimerRequest{
-		TimerID: params.TimerID,
-		UserID:  params.UserID,
-		Name:    params.Name,
+	s.env.OnActivity(activities.StartTimerActivity, mock.Anything, StartTimerRequest{
+		TimerID:     params.TimerID,
+		UserID:      params.UserID,
+		HouseholdID: params.HouseholdID,
+		Name:        params.Name,
 	}).Return(nil)
 
-	s.env.OnActivity(SendNotificationActivity, mock.Anything, NotificationRequest{
+	s.env.OnActivity(activities.SendNotificationActivity, mock.Anything, NotificationRequest{
 		UserID:      params.UserID,
 		HouseholdID: params.HouseholdID,
 		Title:       "Timer Started",
@@ -60,9 +72,9 @@ func (s *TimerWorkflowTestSuite) TestCountdownTimer() {
 		},
 	}).Return(nil)
 
-	s.env.OnActivity(CompleteTimerActivity, mock.Anything, mock.AnythingOfType("CompleteTimerRequest")).Return(nil)
+	s.env.OnActivity(activities.CompleteTimerActivity, mock.Anything, mock.AnythingOfType("CompleteTimerRequest")).Return(nil)
 
-	s.env.OnActivity(SendNotificationActivity, mock.Anything, NotificationRequest{
+	s.env.OnActivity(activities.SendNotificationActivity, mock.Anything, NotificationRequest{
 		UserID:      params.UserID,
 		HouseholdID: params.HouseholdID,
 		Title:       "Timer Completed",
@@ -98,9 +110,9 @@ func (s *TimerWorkflowTestSuite) TestPomodoroTimer() {
 	}
 
 	// Mock activity expectations
-	s.env.OnActivity(StartTimerActivity, mock.Anything, mock.AnythingOfType("StartTimerRequest")).Return(nil)
-	s.env.OnActivity(SendNotificationActivity, mock.Anything, mock.AnythingOfType("NotificationRequest")).Return(nil).Times(5) // Start + 2 work periods + 1 break + finish
-	s.env.OnActivity(CompleteTimerActivity, mock.Anything, mock.AnythingOfType("CompleteTimerRequest")).Return(nil)
+	s.env.OnActivity(activities.StartTimerActivity, mock.Anything, mock.AnythingOfType("StartTimerRequest")).Return(nil)
+	s.env.OnActivity(activities.SendNotificationActivity, mock.Anything, mock.AnythingOfType("NotificationRequest")).Return(nil).Times(5) // Start + 2 work periods + 1 break + finish
+	s.env.OnActivity(activities.CompleteTimerActivity, mock.Anything, mock.AnythingOfType("CompleteTimerRequest")).Return(nil)
 
 	s.env.ExecuteWorkflow(TimerWorkflow, params)
 	s.True(s.env.IsWorkflowCompleted())
@@ -121,8 +133,8 @@ func (s *TimerWorkflowTestSuite) TestTimerPause() {
 	}
 
 	// Mock activity expectations
-	s.env.OnActivity(StartTimerActivity, mock.Anything, mock.AnythingOfType("StartTimerRequest")).Return(nil)
-	s.env.OnActivity(CompleteTimerActivity, mock.Anything, mock.AnythingOfType("CompleteTimerRequest")).Return(nil)
+	s.env.OnActivity(activities.StartTimerActivity, mock.Anything, mock.AnythingOfType("StartTimerRequest")).Return(nil)
+	s.env.OnActivity(activities.CompleteTimerActivity, mock.Anything, mock.AnythingOfType("CompleteTimerRequest")).Return(nil)
 
 	// Start workflow
 	s.env.ExecuteWorkflow(TimerWorkflow, params)
@@ -141,6 +153,61 @@ func (s *TimerWorkflowTestSuite) TestTimerPause() {
 	s.NoError(s.env.GetWorkflowError())
 }
 
+func (s *TimerWorkflowTestSuite) TestTimerLiveActivity() {
+	params := TimerWorkflowParams{
+		TimerID:     "timer-004",
+		UserID:      "user-001",
+		HouseholdID: "household-001",
+		Name:        "Bread Proofing",
+		Type:        "countdown",
+		Duration:    10 * time.Minute,
+		Settings: TimerSettings{
+			LiveActivity: true,
+		},
+	}
+
+	s.env.OnActivity(activities.StartTimerActivity, mock.Anything, mock.AnythingOfType("StartTimerRequest")).Return(nil)
+	s.env.OnActivity(activities.CompleteTimerActivity, mock.Anything, mock.AnythingOfType("CompleteTimerRequest")).Return(nil)
+
+	var events []LiveActivityRequest
+	var a *LiveActivityActivities
+	s.env.OnActivity(a.UpdateLiveActivity, mock.Anything, mock.AnythingOfType("LiveActivityRequest")).Return(
+		func(ctx context.Context, req LiveActivityRequest) error {
+			events = append(events, req)
+			return nil
+		})
+
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow("pause_timer", nil)
+	}, 4*time.Minute)
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow("resume_timer", nil)
+	}, 7*time.Minute)
+
+	start := s.env.Now()
+	s.env.ExecuteWorkflow(TimerWorkflow, params)
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	s.Require().Len(events, 4) // start, pause, resume, end
+	s.Equal(LiveActivityStart, events[0].Event)
+	s.Equal(TimerActivityAttributes, events[0].AttributesType)
+	s.EqualValues(start.Add(10*time.Minute).Unix(), events[0].ContentState["endsAt"])
+
+	s.Equal("paused", events[1].ContentState["status"])
+	s.EqualValues(int64(6*60), events[1].ContentState["remainingSeconds"])
+	s.True(events[1].StaleDate.IsZero())
+
+	// The pause pushed the end back by three minutes
+	s.Equal("running", events[2].ContentState["status"])
+	s.EqualValues(start.Add(13*time.Minute).Unix(), events[2].ContentState["endsAt"])
+	s.WithinDuration(start.Add(13*time.Minute+liveActivityStaleGrace), events[2].StaleDate, 0)
+
+	s.Equal(LiveActivityEnd, events[3].Event)
+	s.Equal("completed", events[3].ContentState["status"])
+	s.WithinDuration(start.Add(13*time.Minute+liveActivityDismissal), events[3].DismissalDate, 0)
+}
+
 func TestTimerWorkflowSuite(t *testing.T) {
 	suite.Run(t, new(TimerWorkflowTestSuite))
 }
@@ -156,9 +223,8 @@ func (s *LaundryWorkflowTestSuite) SetupTest() {
 	s.env = s.NewTestWorkflowEnvironment()
 	
 	// Register activities
-	s.env.RegisterActivity(StartLaundryActivity)
-	s.env.RegisterActivity(CompleteLaundryActivity)
-	s.env.RegisterActivity(SendNotificationActivity)
+	s.env.RegisterActivity(&Activities{})
+	s.env.RegisterActivity(&LiveActivityActivities{})
 }
 
 func (s *LaundryWorkflowTestSuite) AfterTest(suiteName, testName string) {
@@ -182,9 +248,9 @@ func (s *LaundryWorkflowTestSuite) TestCompleteLaundryCycle() {
 	}
 
 	// Mock activity expectations
-	s.env.OnActivity(StartLaundryActivity, mock.Anything, mock.AnythingOfType("StartLaundryRequest")).Return(nil)
-	s.env.OnActivity(SendNotificationActivity, mock.Anything, mock.AnythingOfType("NotificationRequest")).Return(nil).Times(3) // Wash start, wash done, dry done
-	s.env.OnActivity(CompleteLaundryActivity, mock.Anything, mock.AnythingOfType("CompleteLaundryRequest")).Return(nil)
+	s.env.OnActivity(activities.StartLaundryActivity, mock.Anything, mock.AnythingOfType("StartLaundryRequest")).Return(nil)
+	s.env.OnActivity(activities.SendNotificationActivity, mock.Anything, mock.AnythingOfType("NotificationRequest")).Return(nil).Times(3) // Wash start, wash done, dry done
+	s.env.OnActivity(activities.CompleteLaundryActivity, mock.Anything, mock.AnythingOfType("CompleteLaundryRequest")).Return(nil)
 
 	s.env.ExecuteWorkflow(LaundryWorkflow, params)
 	s.True(s.env.IsWorkflowCompleted())
@@ -209,19 +275,70 @@ func (s *LaundryWorkflowTestSuite) TestLaundryWithReminders() {
 	}
 
 	// Mock activity expectations - including reminders
-	s.env.OnActivity(StartLaundryActivity, mock.Anything, mock.AnythingOfType("StartLaundryRequest")).Return(nil)
-	s.env.OnActivity(SendNotificationActivity, mock.Anything, mock.AnythingOfType("NotificationRequest")).Return(nil).Times(4) // Wash done + 2 reminders + dry done
-	s.env.OnActivity(CompleteLaundryActivity, mock.Anything, mock.AnythingOfType("CompleteLaundryRequest")).Return(nil)
+	s.env.OnActivity(activities.StartLaundryActivity, mock.Anything, mock.AnythingOfType("StartLaundryRequest")).Return(nil)
+	s.env.OnActivity(activities.SendNotificationActivity, mock.Anything, mock.AnythingOfType("NotificationRequest")).Return(nil).Times(4) // Wash done + 2 reminders + dry done
+	s.env.OnActivity(activities.CompleteLaundryActivity, mock.Anything, mock.AnythingOfType("CompleteLaundryRequest")).Return(nil)
+
+	// Simulate dry start signal after wash reminders
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow("start_dry", nil)
+	}, 50*time.Minute)
 
 	s.env.ExecuteWorkflow(LaundryWorkflow, params)
 
-	// Simulate dry start signal after wash reminders
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+}
+
+func (s *LaundryWorkflowTestSuite) TestLaundryLiveActivity() {
+	params := LaundryWorkflowParams{
+		LaundryID:   "laundry-003",
+		UserID:      "user-001",
+		HouseholdID: "household-001",
+		LoadType:    "heavy",
+		WashTime:    30 * time.Minute,
+		DryTime:     45 * time.Minute,
+		Settings: LaundrySettings{
+			LiveActivity: true,
+		},
+	}
+
+	s.env.OnActivity(activities.StartLaundryActivity, mock.Anything, mock.AnythingOfType("StartLaundryRequest")).Return(nil)
+	s.env.OnActivity(activities.CompleteLaundryActivity, mock.Anything, mock.AnythingOfType("CompleteLaundryRequest")).Return(nil)
+
+	var events []LiveActivityRequest
+	var a *LiveActivityActivities
+	s.env.OnActivity(a.UpdateLiveActivity, mock.Anything, mock.AnythingOfType("LiveActivityRequest")).Return(
+		func(ctx context.Context, req LiveActivityRequest) error {
+			events = append(events, req)
+			return nil
+		})
+
 	s.env.RegisterDelayedCallback(func() {
 		s.env.SignalWorkflow("start_dry", nil)
-	}, 35*time.Minute)
+	}, 40*time.Minute)
 
+	start := s.env.Now()
+	s.env.ExecuteWorkflow(LaundryWorkflow, params)
 	s.True(s.env.IsWorkflowCompleted())
 	s.NoError(s.env.GetWorkflowError())
+
+	s.Require().Len(events, 4) // washing, wash done, drying, dry done
+	s.Equal(LiveActivityStart, events[0].Event)
+	s.Equal(LaundryActivityAttributes, events[0].AttributesType)
+	s.Equal("washing", events[0].ContentState["phase"])
+	s.EqualValues(start.Add(30*time.Minute).Unix(), events[0].ContentState["endsAt"])
+
+	s.Equal("wash_done", events[1].ContentState["phase"])
+	s.True(events[1].Urgent)
+	s.NotEmpty(events[1].AlertBody)
+
+	s.Equal("drying", events[2].ContentState["phase"])
+	s.EqualValues(start.Add(85*time.Minute).Unix(), events[2].ContentState["endsAt"])
+
+	s.Equal(LiveActivityEnd, events[3].Event)
+	s.Equal("dry_done", events[3].ContentState["phase"])
+	s.False(events[3].DismissalDate.IsZero())
 }
 
 func TestLaundryWorkflowSuite(t *testing.T) {
@@ -239,9 +356,7 @@ func (s *RecurringTaskWorkflowTestSuite) SetupTest() {
 	s.env = s.NewTestWorkflowEnvironment()
 	
 	// Register activities and workflows
-	s.env.RegisterActivity(CreateTaskOccurrenceActivity)
-	s.env.RegisterActivity(CheckTaskCompletionActivity)
-	s.env.RegisterActivity(SendNotificationActivity)
+	s.env.RegisterActivity(&Activities{})
 	s.env.RegisterWorkflow(TaskReminderWorkflow)
 }
 
@@ -273,8 +388,9 @@ func (s *RecurringTaskWorkflowTestSuite) TestDailyRecurringTask() {
 	}
 
 	// Mock activity expectations
-	s.env.OnActivity(CreateTaskOccurrenceActivity, mock.Anything, mock.AnythingOfType("CreateTaskOccurrenceRequest")).Return(nil).Times(3)
+	s.env.OnActivity(activities.CreateTaskOccurrenceActivity, mock.Anything, mock.AnythingOfType("CreateTaskOccurrenceRequest")).Return("", nil).Times(3)
 
+	s.env.OnActivity(activities.HouseholdPausesActivity, mock.Anything, mock.Anything).Return([]PauseWindow{}, nil).Once()
 	s.env.ExecuteWorkflow(RecurringTaskWorkflow, params)
 	s.True(s.env.IsWorkflowCompleted())
 	s.NoError(s.env.GetWorkflowError())
@@ -303,11 +419,250 @@ func (s *RecurringTaskWorkflowTestSuite) TestWeeklyRecurringTask() {
 	}
 
 	// Mock activity expectations
-	s.env.OnActivity(CreateTaskOccurrenceActivity, mock.Anything, mock.AnythingOfType("CreateTaskOccurrenceRequest")).Return(nil).Times(2)
+	s.env.OnActivity(activities.CreateTaskOccurrenceActivity, mock.Anything, mock.AnythingOfType("CreateTaskOccurrenceRequest")).Return("", nil).Times(2)
+
+	s.env.OnActivity(activities.HouseholdPausesActivity, mock.Anything, mock.Anything).Return([]PauseWindow{}, nil).Once()
+	s.env.ExecuteWorkflow(RecurringTaskWorkflow, params)
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+}
+
+func (s *RecurringTaskWorkflowTestSuite) TestContinuesAsNew() {
+	startDate := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	total := recurringTaskOccurrencesPerRun + 20
+
+	params := RecurringTaskWorkflowParams{
+		TaskID:      "task-003",
+		UserID:      "user-001",
+		HouseholdID: "household-001",
+		Name:        "Feed the cat",
+		RecurrenceRule: RecurrenceRule{
+			Type:           "daily",
+			Interval:       1,
+			StartDate:      startDate,
+			MaxOccurrences: total,
+		},
+		AssignedMembers: []string{"user-001", "user-002", "user-003"},
+		AutoAssign:      true,
+	}
+
+	// The activity assigns occurrences round-robin from their position in
+	// the series
+	var created []TaskOccurrence
+	var assigned []string
+	record := func(ctx context.Context, req CreateTaskOccurrenceRequest) (string, error) {
+		created = append(created, req.Occurrence)
+		assignee := req.Assignment.Members[req.Assignment.Index%len(req.Assignment.Members)]
+		assigned = append(assigned, assignee)
+		return assignee, nil
+	}
+	s.env.OnActivity(activities.CreateTaskOccurrenceActivity, mock.Anything, mock.Anything).Return(record)
+
+	s.env.OnActivity(activities.HouseholdPausesActivity, mock.Anything, mock.Anything).Return([]PauseWindow{}, nil).Once()
+	s.env.ExecuteWorkflow(RecurringTaskWorkflow, params)
+	s.True(s.env.IsWorkflowCompleted())
+
+	var continued *workflow.ContinueAsNewError
+	s.Require().ErrorAs(s.env.GetWorkflowError(), &continued)
+	s.Len(created, recurringTaskOccurrencesPerRun)
+
+	var next RecurringTaskWorkflowParams
+	s.Require().NoError(converter.GetDefaultDataConverter().FromPayloads(continued.Input, &next))
+	s.Equal(recurringTaskOccurrencesPerRun, next.OccurrenceCount)
+	s.Require().NotNil(next.NextDueDate)
+	s.True(next.NextDueDate.Equal(startDate.AddDate(0, 0, recurringTaskOccurrencesPerRun)))
+
+	// The next run picks up where the first stopped and still honours the
+	// rule's limit
+	env := s.NewTestWorkflowEnvironment()
+	env.RegisterActivity(&Activities{})
+	env.OnActivity(activities.CreateTaskOccurrenceActivity, mock.Anything, mock.Anything).Return(record)
+	env.ExecuteWorkflow(RecurringTaskWorkflow, next)
+	s.True(env.IsWorkflowCompleted())
+	s.NoError(env.GetWorkflowError())
+
+	s.Require().Len(created, total)
+	for i, occurrence := range created {
+		s.Equal(fmt.Sprintf("task-003_%d", i+1), occurrence.OccurrenceID)
+		s.True(occurrence.DueDate.Equal(startDate.AddDate(0, 0, i)), "occurrence %d due %s", i+1, occurrence.DueDate)
+		s.Equal(params.AssignedMembers[i%len(params.AssignedMembers)], assigned[i])
+	}
+}
+
+func (s *RecurringTaskWorkflowTestSuite) TestAwaySkipsOccurrences() {
+	startDate := time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)
+	s.env.SetStartTime(startDate.Add(-time.Hour))
+
+	params := RecurringTaskWorkflowParams{
+		TaskID:      "task-004",
+		UserID:      "user-001",
+		HouseholdID: "household-001",
+		Name:        "Water the plants",
+		RecurrenceRule: RecurrenceRule{
+			Type:           "daily",
+			Interval:       1,
+			StartDate:      startDate,
+			MaxOccurrences: 3,
+		},
+		AssignedMembers: []string{"user-001", "user-002"},
+		AutoAssign:      true,
+		Pauses: []PauseWindow{{
+			AwayID: "away-001",
+			From:   startDate.AddDate(0, 0, 1),
+			Until:  startDate.AddDate(0, 0, 3),
+		}},
+	}
+
+	var created []TaskOccurrence
+	var assignments []OccurrenceAssignment
+	s.env.OnActivity(activities.CreateTaskOccurrenceActivity, mock.Anything, mock.Anything).Return(func(ctx context.Context, req CreateTaskOccurrenceRequest) (string, error) {
+		created = append(created, req.Occurrence)
+		assignments = append(assignments, *req.Assignment)
+		return "user-002", nil
+	})
+
+	s.env.OnActivity(activities.HouseholdPausesActivity, mock.Anything, mock.Anything).Return([]PauseWindow{}, nil).Once()
+	s.env.ExecuteWorkflow(RecurringTaskWorkflow, params)
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	// The two days the household is away are skipped, not counted, so the
+	// rotation carries on where it stopped
+	s.Require().Len(created, 3)
+	for i, day := range []int{0, 3, 4} {
+		s.Equal(fmt.Sprintf("task-004_%d", i+1), created[i].OccurrenceID)
+		s.True(created[i].DueDate.Equal(startDate.AddDate(0, 0, day)), "occurrence %d due %s", i+1, created[i].DueDate)
+		s.Equal(i, assignments[i].Index)
+		s.Equal(params.AssignedMembers, assignments[i].Members)
+	}
+}
+
+func (s *RecurringTaskWorkflowTestSuite) TestPauseUntilShiftsOccurrence() {
+	startDate := time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)
+	s.env.SetStartTime(startDate.Add(-time.Hour))
+	returns := startDate.AddDate(0, 0, 2).Add(3 * time.Hour)
+
+	params := RecurringTaskWorkflowParams{
+		TaskID:      "task-005",
+		UserID:      "user-001",
+		HouseholdID: "household-001",
+		Name:        "Take out the trash",
+		RecurrenceRule: RecurrenceRule{
+			Type:           "daily",
+			Interval:       1,
+			StartDate:      startDate,
+			MaxOccurrences: 3,
+		},
+		AwayPolicy: AwayShift,
+	}
+
+	var created []TaskOccurrence
+	s.env.OnActivity(activities.CreateTaskOccurrenceActivity, mock.Anything, mock.Anything).Return(func(ctx context.Context, req CreateTaskOccurrenceRequest) (string, error) {
+		created = append(created, req.Occurrence)
+		return "", nil
+	})
 
+	// The household leaves while the first occurrence is pending
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow(PauseUntilSignal, PauseWindow{AwayID: "away-001", Until: returns})
+	}, 10*time.Minute)
+
+	s.env.OnActivity(activities.HouseholdPausesActivity, mock.Anything, mock.Anything).Return([]PauseWindow{}, nil).Once()
 	s.env.ExecuteWorkflow(RecurringTaskWorkflow, params)
 	s.True(s.env.IsWorkflowCompleted())
 	s.NoError(s.env.GetWorkflowError())
+
+	// The first occurrence moves to the return, the others in the window are
+	// dropped
+	s.Require().Len(created, 3)
+	s.True(created[0].DueDate.Equal(returns), "shifted occurrence due %s", created[0].DueDate)
+	s.True(created[1].DueDate.Equal(startDate.AddDate(0, 0, 3)), "occurrence 2 due %s", created[1].DueDate)
+	s.True(created[2].DueDate.Equal(startDate.AddDate(0, 0, 4)), "occurrence 3 due %s", created[2].DueDate)
+}
+
+func (s *RecurringTaskWorkflowTestSuite) TestStartsWithCurrentAwayPeriods() {
+	startDate := time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)
+	s.env.SetStartTime(startDate.Add(-time.Hour))
+
+	params := RecurringTaskWorkflowParams{
+		TaskID:      "task-006",
+		UserID:      "user-001",
+		HouseholdID: "household-001",
+		Name:        "Water the plants",
+		RecurrenceRule: RecurrenceRule{
+			Type:           "daily",
+			Interval:       1,
+			StartDate:      startDate,
+			MaxOccurrences: 3,
+		},
+	}
+
+	// The household's trip was planned before the series started, so the
+	// series was never signalled about it
+	s.env.OnActivity(activities.HouseholdPausesActivity, mock.Anything, HouseholdPausesRequest{
+		HouseholdID: "household-001",
+		At:          startDate.Add(-time.Hour),
+	}).Return([]PauseWindow{{
+		AwayID: "away-001",
+		From:   startDate.AddDate(0, 0, 1),
+		Until:  startDate.AddDate(0, 0, 3),
+	}}, nil).Once()
+
+	var created []TaskOccurrence
+	s.env.OnActivity(activities.CreateTaskOccurrenceActivity, mock.Anything, mock.Anything).Return(func(ctx context.Context, req CreateTaskOccurrenceRequest) (string, error) {
+		created = append(created, req.Occurrence)
+		return "", nil
+	})
+
+	s.env.ExecuteWorkflow(RecurringTaskWorkflow, params)
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	s.Require().Len(created, 3)
+	for i, day := range []int{0, 3, 4} {
+		s.True(created[i].DueDate.Equal(startDate.AddDate(0, 0, day)), "occurrence %d due %s", i+1, created[i].DueDate)
+	}
+}
+
+func (s *RecurringTaskWorkflowTestSuite) TestContinueAsNewCarriesSignalledPause() {
+	// The occurrences are all due already, so nothing waits between them
+	startDate := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	now := startDate.AddDate(1, 0, 0)
+	s.env.SetStartTime(now)
+	returns := now.AddDate(0, 0, 14)
+
+	params := RecurringTaskWorkflowParams{
+		TaskID:      "task-007",
+		UserID:      "user-001",
+		HouseholdID: "household-001",
+		Name:        "Feed the cat",
+		RecurrenceRule: RecurrenceRule{
+			Type:      "daily",
+			Interval:  1,
+			StartDate: startDate,
+		},
+	}
+
+	s.env.OnActivity(activities.HouseholdPausesActivity, mock.Anything, mock.Anything).Return([]PauseWindow{}, nil).Once()
+	s.env.OnActivity(activities.CreateTaskOccurrenceActivity, mock.Anything, mock.Anything).Return("", nil)
+
+	// The household leaves during the short wait after the run's last
+	// occurrence, just before the series continues as new
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow(PauseUntilSignal, PauseWindow{AwayID: "away-001", Until: returns})
+	}, time.Duration(recurringTaskOccurrencesPerRun)*time.Second-time.Second/2)
+
+	s.env.ExecuteWorkflow(RecurringTaskWorkflow, params)
+	s.True(s.env.IsWorkflowCompleted())
+
+	var continued *workflow.ContinueAsNewError
+	s.Require().ErrorAs(s.env.GetWorkflowError(), &continued)
+
+	var next RecurringTaskWorkflowParams
+	s.Require().NoError(converter.GetDefaultDataConverter().FromPayloads(continued.Input, &next))
+	s.Require().Len(next.Pauses, 1)
+	s.Equal("away-001", next.Pauses[0].AwayID)
+	s.True(next.Pauses[0].Until.Equal(returns))
 }
 
 func TestRecurringTaskWorkflowSuite(t *testing.T) {
@@ -325,8 +680,7 @@ func (s *TaskReminderWorkflowTestSuite) SetupTest() {
 	s.env = s.NewTestWorkflowEnvironment()
 	
 	// Register activities
-	s.env.RegisterActivity(CheckTaskCompletionActivity)
-	s.env.RegisterActivity(SendNotificationActivity)
+	s.env.RegisterActivity(&Activities{})
 }
 
 func (s *TaskReminderWorkflowTestSuite) AfterTest(suiteName, testName string) {
@@ -354,8 +708,17 @@ func (s *TaskReminderWorkflowTestSuite) TestTaskReminders() {
 	}
 
 	// Mock activity expectations
-	s.env.OnActivity(CheckTaskCompletionActivity, mock.Anything, mock.AnythingOfType("CheckTaskCompletionRequest")).Return(false, nil).Times(3)
-	s.env.OnActivity(SendNotificationActivity, mock.Anything, mock.AnythingOfType("NotificationRequest")).Return(nil).Times(3)
+	s.env.OnActivity(activities.CheckTaskCompletionActivity, mock.Anything, mock.AnythingOfType("CheckTaskCompletionRequest")).Return(false, nil).Times(4)
+	s.env.OnActivity(activities.MemberAwayUntilActivity, mock.Anything, mock.Anything).Return(time.Time{}, nil)
+	s.env.OnActivity(activities.SendNotificationActivity, mock.Anything, mock.AnythingOfType("NotificationRequest")).Return(nil).Times(3)
+
+	// The task is still open an interval after the last reminder
+	s.env.OnActivity(activities.RecordMissedChoreActivity, mock.Anything, MissedChoreRequest{
+		OccurrenceID: "occurrence-001",
+		HouseholdID:  "household-001",
+		AssignedTo:   "user-002",
+		Reminders:    3,
+	}).Return(true, nil).Once()
 
 	s.env.ExecuteWorkflow(TaskReminderWorkflow, params)
 	s.True(s.env.IsWorkflowCompleted())
@@ -382,9 +745,289 @@ func (s *TaskReminderWorkflowTestSuite) TestTaskCompletedEarly() {
 	}
 
 	// Mock task completion after first reminder
-	s.env.OnActivity(CheckTaskCompletionActivity, mock.Anything, mock.AnythingOfType("CheckTaskCompletionRequest")).Return(false, nil).Once()
-	s.env.OnActivity(SendNotificationActivity, mock.Anything, mock.AnythingOfType("NotificationRequest")).Return(nil).Once()
-	s.env.OnActivity(CheckTaskCompletionActivity, mock.Anything, mock.AnythingOfType("CheckTaskCompletionRequest")).Return(true, nil).Once()
+	s.env.OnActivity(activities.CheckTaskCompletionActivity, mock.Anything, mock.AnythingOfType("CheckTaskCompletionRequest")).Return(false, nil).Once()
+	s.env.OnActivity(activities.MemberAwayUntilActivity, mock.Anything, mock.Anything).Return(time.Time{}, nil)
+	s.env.OnActivity(activities.SendNotificationActivity, mock.Anything, mock.AnythingOfType("NotificationRequest")).Return(nil).Once()
+	s.env.OnActivity(activities.CheckTaskCompletionActivity, mock.Anything, mock.AnythingOfType("CheckTaskCompletionRequest")).Return(true, nil).Once()
+
+	s.env.ExecuteWorkflow(TaskReminderWorkflow, params)
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+}
+
+func (s *TaskReminderWorkflowTestSuite) TestEscalationTiers() {
+	params := TaskReminderWorkflowParams{
+		OccurrenceID: "occurrence-003",
+		TaskID:       "task-003",
+		UserID:       "user-001",
+		HouseholdID:  "household-001",
+		AssignedTo:   "user-002",
+		DueDate:      time.Now(),
+		Name:         "Take out the trash",
+		ReminderSettings: ReminderSettings{
+			Enabled:          true,
+			ReminderInterval: 30 * time.Minute,
+			MaxReminders:     3,
+			EscalateAfter:    2,
+			Escalation: EscalationPolicy{
+				Admins:   EscalationTier{Channel: ChannelInApp},
+				Reassign: true,
+			},
+		},
+		Rotation: []string{"user-001", "user-002", "user-003"},
+	}
+
+	s.env.OnActivity(activities.CheckTaskCompletionActivity, mock.Anything, mock.Anything).Return(false, nil)
+	s.env.OnActivity(activities.MemberAwayUntilActivity, mock.Anything, mock.Anything).Return(time.Time{}, nil)
+	s.env.OnActivity(activities.AwayMembersActivity, mock.Anything, mock.Anything).Return(nil, nil)
+
+	// Tier 1: the assignee is reminded with normal priority pushes
+	var reminders []NotificationRequest
+	s.env.OnActivity(activities.SendNotificationActivity, mock.Anything, mock.MatchedBy(func(req NotificationRequest) bool {
+		return req.UserID == "user-002"
+	})).Return(func(ctx context.Context, req NotificationRequest) error {
+		reminders = append(reminders, req)
+		return nil
+	}).Times(3)
+
+	// Tier 2: after two ignored reminders the admins, other than the
+	// assignee, are told in the app with high priority
+	s.env.OnActivity(activities.ListHouseholdAdminsActivity, mock.Anything, "household-001").Return([]string{"user-001", "user-002"}, nil).Once()
+	s.env.OnActivity(activities.SendInAppNotificationActivity, mock.Anything, mock.MatchedBy(func(req NotificationRequest) bool {
+		return req.UserID == "user-001" && req.Priority == PriorityHigh && req.Data["type"] == "task_escalated"
+	})).Return(nil).Once()
+	s.env.OnActivity(activities.RecordEscalationActivity, mock.Anything, mock.MatchedBy(func(e TaskEscalation) bool {
+		return e.Tier == TierAdmins && e.Reminders == 2 && len(e.Notified) == 1 && e.Notified[0] == "user-001"
+	})).Return(nil).Once()
+
+	// Tier 3: once all reminders are ignored the miss is recorded against the
+	// assignee and the task moves to the next member
+	s.env.OnActivity(activities.RecordMissedChoreActivity, mock.Anything, mock.MatchedBy(func(req MissedChoreRequest) bool {
+		return req.AssignedTo == "user-002" && req.Reminders == 3
+	})).Return(true, nil).Once()
+	s.env.OnActivity(activities.ReassignTaskActivity, mock.Anything, ReassignTaskRequest{
+		OccurrenceID: "occurrence-003",
+		HouseholdID:  "household-001",
+		From:         "user-002",
+		To:           "user-003",
+	}).Return("task-occurrence-003", nil).Once()
+	s.env.OnActivity(activities.RecordEscalationActivity, mock.Anything, mock.MatchedBy(func(e TaskEscalation) bool {
+		return e.Tier == TierReassign && e.TaskID == "task-occurrence-003" && e.ReassignedTo == "user-003"
+	})).Return(nil).Once()
+	s.env.OnActivity(activities.SendNotificationActivity, mock.Anything, mock.MatchedBy(func(req NotificationRequest) bool {
+		return req.UserID == "user-003" && req.Priority == PriorityHigh && req.Data["type"] == "task_reassigned"
+	})).Return(nil).Once()
+
+	s.env.ExecuteWorkflow(TaskReminderWorkflow, params)
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	s.Len(reminders, 3)
+	for i, req := range reminders {
+		s.Equal(PriorityNormal, req.Priority)
+		if i < 2 {
+			s.Equal("reminder", req.Data["type"])
+		} else {
+			s.Equal("escalated_reminder", req.Data["type"])
+		}
+	}
+}
+
+func (s *TaskReminderWorkflowTestSuite) TestEscalationStopsWhenTaskCompleted() {
+	params := TaskReminderWorkflowParams{
+		OccurrenceID: "occurrence-004",
+		TaskID:       "task-004",
+		HouseholdID:  "household-001",
+		AssignedTo:   "user-002",
+		DueDate:      time.Now(),
+		Name:         "Water the plants",
+		ReminderSettings: ReminderSettings{
+			Enabled:          true,
+			ReminderInterval: 30 * time.Minute,
+			MaxReminders:     3,
+			EscalateAfter:    1,
+			Escalation:       EscalationPolicy{Reassign: true},
+		},
+		Rotation: []string{"user-002", "user-003"},
+	}
+
+	s.env.OnActivity(activities.CheckTaskCompletionActivity, mock.Anything, mock.Anything).Return(false, nil).Once()
+	s.env.OnActivity(activities.MemberAwayUntilActivity, mock.Anything, mock.Anything).Return(time.Time{}, nil)
+	s.env.OnActivity(activities.SendNotificationActivity, mock.Anything, mock.Anything).Return(nil).Once()
+
+	// The assignee finishes the task before the admins are told
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow("task_completed", nil)
+	}, 10*time.Minute)
+
+	s.env.ExecuteWorkflow(TaskReminderWorkflow, params)
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+}
+
+func (s *TaskReminderWorkflowTestSuite) TestRemindersHeldWhileAssigneeAway() {
+	start := time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)
+	s.env.SetStartTime(start)
+	returns := start.AddDate(0, 0, 5)
+
+	params := TaskReminderWorkflowParams{
+		OccurrenceID: "occurrence-005",
+		TaskID:       "task-005",
+		HouseholdID:  "household-001",
+		AssignedTo:   "user-002",
+		DueDate:      start,
+		Name:         "Clean the fridge",
+		ReminderSettings: ReminderSettings{
+			Enabled:          true,
+			ReminderInterval: 30 * time.Minute,
+			MaxReminders:     1,
+		},
+	}
+
+	s.env.OnActivity(activities.CheckTaskCompletionActivity, mock.Anything, mock.Anything).Return(false, nil).Times(3)
+	s.env.OnActivity(activities.RecordMissedChoreActivity, mock.Anything, mock.Anything).Return(true, nil).Once()
+	s.env.OnActivity(activities.MemberAwayUntilActivity, mock.Anything, MemberAwayRequest{
+		HouseholdID: "household-001",
+		UserID:      "user-002",
+		At:          start,
+	}).Return(returns, nil).Once()
+	s.env.OnActivity(activities.MemberAwayUntilActivity, mock.Anything, mock.Anything).Return(time.Time{}, nil).Twice()
+
+	// The reminder waits for the assignee to return
+	var remindedAt time.Time
+	s.env.OnActivity(activities.SendNotificationActivity, mock.Anything, mock.Anything).Return(func(ctx context.Context, req NotificationRequest) error {
+		remindedAt = s.env.Now()
+		return nil
+	}).Once()
+
+	s.env.ExecuteWorkflow(TaskReminderWorkflow, params)
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+	s.False(remindedAt.Before(returns), "reminded at %s, before the assignee returned at %s", remindedAt, returns)
+}
+
+func (s *TaskReminderWorkflowTestSuite) TestReassignPassesOverAwayMembers() {
+	params := TaskReminderWorkflowParams{
+		OccurrenceID: "occurrence-006",
+		TaskID:       "task-006",
+		HouseholdID:  "household-001",
+		AssignedTo:   "user-002",
+		DueDate:      time.Now(),
+		Name:         "Walk the dog",
+		ReminderSettings: ReminderSettings{
+			Enabled:          true,
+			ReminderInterval: 30 * time.Minute,
+			MaxReminders:     1,
+			Escalation:       EscalationPolicy{Reassign: true},
+		},
+		Rotation: []string{"user-001", "user-002", "user-003"},
+	}
+
+	s.env.OnActivity(activities.CheckTaskCompletionActivity, mock.Anything, mock.Anything).Return(false, nil)
+	s.env.OnActivity(activities.MemberAwayUntilActivity, mock.Anything, mock.Anything).Return(time.Time{}, nil)
+	s.env.OnActivity(activities.SendNotificationActivity, mock.Anything, mock.Anything).Return(nil)
+	s.env.OnActivity(activities.RecordEscalationActivity, mock.Anything, mock.Anything).Return(nil).Once()
+	s.env.OnActivity(activities.RecordMissedChoreActivity, mock.Anything, mock.Anything).Return(true, nil).Once()
+
+	// user-003 is next in the rotation but away, so the task goes to user-001
+	s.env.OnActivity(activities.AwayMembersActivity, mock.Anything, mock.Anything).Return([]string{"user-003"}, nil).Once()
+	s.env.OnActivity(activities.ReassignTaskActivity, mock.Anything, ReassignTaskRequest{
+		OccurrenceID: "occurrence-006",
+		HouseholdID:  "household-001",
+		From:         "user-002",
+		To:           "user-001",
+	}).Return("task-occurrence-006", nil).Once()
+
+	s.env.ExecuteWorkflow(TaskReminderWorkflow, params)
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+}
+
+func (s *TaskReminderWorkflowTestSuite) TestRemindersHeldWhileTaskPendingReview() {
+	start := time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)
+	s.env.SetStartTime(start)
+
+	params := TaskReminderWorkflowParams{
+		OccurrenceID: "occurrence-007",
+		TaskID:       "task-007",
+		HouseholdID:  "household-001",
+		AssignedTo:   "user-002",
+		DueDate:      start,
+		Name:         "Tidy your room",
+		ReminderSettings: ReminderSettings{
+			Enabled:          true,
+			ReminderInterval: 30 * time.Minute,
+			MaxReminders:     3,
+		},
+	}
+
+	s.env.OnActivity(activities.CheckTaskCompletionActivity, mock.Anything, mock.Anything).Return(false, nil)
+	s.env.OnActivity(activities.MemberAwayUntilActivity, mock.Anything, mock.Anything).Return(time.Time{}, nil)
+
+	var reminders []NotificationRequest
+	var remindedAt []time.Time
+	s.env.OnActivity(activities.SendNotificationActivity, mock.Anything, mock.MatchedBy(func(req NotificationRequest) bool {
+		return req.UserID == "user-002"
+	})).Return(func(ctx context.Context, req NotificationRequest) error {
+		reminders = append(reminders, req)
+		remindedAt = append(remindedAt, s.env.Now())
+		return nil
+	}).Twice()
+
+	// The child submits the task for review; the admins are asked once
+	s.env.OnActivity(activities.ListHouseholdAdminsActivity, mock.Anything, "household-001").Return([]string{"user-001"}, nil).Once()
+	s.env.OnActivity(activities.SendNotificationActivity, mock.Anything, mock.MatchedBy(func(req NotificationRequest) bool {
+		return req.UserID == "user-001" && req.Data["type"] == "task_review_requested"
+	})).Return(nil).Once()
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow("task_review", TaskReviewSignal{Status: TaskReviewPending})
+	}, 10*time.Minute)
+
+	// No reminders while the admins take their time, then the rejection
+	// sends the child back to the task
+	note := "Missed the closet"
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow("task_review", TaskReviewSignal{Status: TaskReviewRejected, ReviewedBy: "user-001", Note: &note})
+	}, 5*time.Hour)
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow("task_completed", nil)
+	}, 5*time.Hour+10*time.Minute)
+
+	s.env.ExecuteWorkflow(TaskReminderWorkflow, params)
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	s.Require().Len(reminders, 2)
+	s.Equal("reminder", reminders[0].Data["type"])
+	s.Equal("task_rejected", reminders[1].Data["type"])
+	s.Contains(reminders[1].Body, note)
+	s.False(remindedAt[1].Before(start.Add(5*time.Hour)), "reminded at %s while the task was pending review", remindedAt[1])
+}
+
+func (s *TaskReminderWorkflowTestSuite) TestNoMissedChoreWhenDoneAfterLastReminder() {
+	params := TaskReminderWorkflowParams{
+		OccurrenceID: "occurrence-008",
+		TaskID:       "task-008",
+		HouseholdID:  "household-001",
+		AssignedTo:   "user-002",
+		DueDate:      time.Now(),
+		Name:         "Feed the cat",
+		ReminderSettings: ReminderSettings{
+			Enabled:          true,
+			ReminderInterval: 30 * time.Minute,
+			MaxReminders:     2,
+		},
+	}
+
+	s.env.OnActivity(activities.CheckTaskCompletionActivity, mock.Anything, mock.Anything).Return(false, nil).Twice()
+	s.env.OnActivity(activities.MemberAwayUntilActivity, mock.Anything, mock.Anything).Return(time.Time{}, nil)
+	s.env.OnActivity(activities.SendNotificationActivity, mock.Anything, mock.Anything).Return(nil).Twice()
+
+	// The assignee finishes within the interval after the last reminder, so
+	// no miss is recorded
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow("task_completed", nil)
+	}, 50*time.Minute)
 
 	s.env.ExecuteWorkflow(TaskReminderWorkflow, params)
 	s.True(s.env.IsWorkflowCompleted())
@@ -393,4 +1036,468 @@ func (s *TaskReminderWorkflowTestSuite) TestTaskCompletedEarly() {
 
 func TestTaskReminderWorkflowSuite(t *testing.T) {
 	suite.Run(t, new(TaskReminderWorkflowTestSuite))
-}
\ No newline at end of file
+}
+// BillLifecycleWorkflowTestSuite tests the bill lifecycle workflow
+type BillLifecycleWorkflowTestSuite struct {
+	suite.Suite
+	testsuite.WorkflowTestSuite
+	env *testsuite.TestWorkflowEnvironment
+}
+
+func (s *BillLifecycleWorkflowTestSuite) SetupTest() {
+	s.env = s.NewTestWorkflowEnvironment()
+	s.env.RegisterActivity(&Activities{})
+}
+
+func (s *BillLifecycleWorkflowTestSuite) AfterTest(suiteName, testName string) {
+	s.env.AssertExpectations(s.T())
+}
+
+func (s *BillLifecycleWorkflowTestSuite) params() BillLifecycleWorkflowParams {
+	return BillLifecycleWorkflowParams{
+		BillID:      "bill-001",
+		HouseholdID: "household-001",
+		CreatedBy:   "user-001",
+		AssignedTo:  "user-002",
+		Name:        "Electricity",
+		Amount:      150.75,
+		Currency:    "USD",
+		DueDate:     s.env.Now().Add(10 * 24 * time.Hour),
+	}
+}
+
+func (s *BillLifecycleWorkflowTestSuite) TestOverdueBillEscalates() {
+	params := s.params()
+	params.FollowUps = BillFollowUpSettings{Interval: 24 * time.Hour, MaxFollowUps: 3, EscalateAfter: 2}
+	remindAt := params.DueDate.Add(-DefaultBillReminderDays * 24 * time.Hour)
+
+	s.env.OnActivity(activities.CheckBillSettledActivity, mock.Anything, CheckBillSettledRequest{BillID: "bill-001"}).Return(false, nil).Times(4)
+
+	// The assignee is reminded three days ahead, then with high priority
+	// once the bill is overdue
+	var sent []NotificationRequest
+	var sentAt []time.Time
+	s.env.OnActivity(activities.SendNotificationActivity, mock.Anything, mock.MatchedBy(func(req NotificationRequest) bool {
+		return req.UserID == "user-002"
+	})).Return(func(ctx context.Context, req NotificationRequest) error {
+		sent = append(sent, req)
+		sentAt = append(sentAt, s.env.Now())
+		return nil
+	}).Times(5)
+
+	s.env.OnActivity(activities.MarkBillOverdueActivity, mock.Anything, mock.MatchedBy(func(req MarkBillOverdueRequest) bool {
+		return req.BillID == "bill-001" && req.AssignedTo == "user-002"
+	})).Return(func(ctx context.Context, req MarkBillOverdueRequest) (bool, error) {
+		s.False(s.env.Now().Before(params.DueDate), "bill marked overdue before its due date")
+		return true, nil
+	}).Once()
+
+	// After the second follow-up the admins, other than the assignee, are told
+	s.env.OnActivity(activities.ListHouseholdAdminsActivity, mock.Anything, "household-001").Return([]string{"user-001", "user-002"}, nil).Once()
+	s.env.OnActivity(activities.SendNotificationActivity, mock.Anything, mock.MatchedBy(func(req NotificationRequest) bool {
+		return req.UserID == "user-001" && req.Priority == PriorityHigh && req.Data["type"] == "bill_escalated"
+	})).Return(nil).Once()
+
+	s.env.ExecuteWorkflow(BillLifecycleWorkflow, params)
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	s.Len(sent, 5)
+	wantTypes := []string{"bill_reminder", "bill_overdue", "bill_follow_up", "bill_follow_up", "bill_follow_up"}
+	for i, req := range sent {
+		s.Equal(wantTypes[i], req.Data["type"])
+	}
+	s.Equal(PriorityNormal, sent[0].Priority)
+	s.Equal(PriorityHigh, sent[1].Priority)
+	s.False(sentAt[0].Before(remindAt), "reminder sent before %s", remindAt)
+
+	// Follow-ups come further apart each time
+	s.Equal(24*time.Hour, sentAt[2].Sub(sentAt[1]))
+	s.Equal(48*time.Hour, sentAt[3].Sub(sentAt[2]))
+	s.Equal(96*time.Hour, sentAt[4].Sub(sentAt[3]))
+}
+
+func (s *BillLifecycleWorkflowTestSuite) TestPaidSignalEndsWorkflow() {
+	params := s.params()
+
+	s.env.OnActivity(activities.CheckBillSettledActivity, mock.Anything, mock.Anything).Return(false, nil).Once()
+	s.env.OnActivity(activities.SendNotificationActivity, mock.Anything, mock.MatchedBy(func(req NotificationRequest) bool {
+		return req.Data["type"] == "bill_reminder"
+	})).Return(nil).Once()
+
+	// The bill is paid after the reminder, before it is due
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow("bill_paid", BillPaidSignal{PaidBy: "user-002", Amount: 150.75})
+	}, 8*24*time.Hour)
+
+	s.env.ExecuteWorkflow(BillLifecycleWorkflow, params)
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+	s.env.AssertNotCalled(s.T(), "MarkBillOverdueActivity", mock.Anything, mock.Anything)
+}
+
+func (s *BillLifecycleWorkflowTestSuite) TestUpdatedBillMovesDueDateAndAssignee() {
+	params := s.params()
+	dueDate := s.env.Now().Add(20 * 24 * time.Hour)
+	remindAt := dueDate.Add(-DefaultBillReminderDays * 24 * time.Hour)
+
+	// Before the reminder the bill is due ten days later and reassigned
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow("bill_updated", BillUpdatedSignal{
+			Name:       "Electricity",
+			Amount:     175,
+			Currency:   "USD",
+			DueDate:    dueDate,
+			AssignedTo: "user-003",
+		})
+	}, 2*24*time.Hour)
+
+	s.env.OnActivity(activities.CheckBillSettledActivity, mock.Anything, mock.Anything).Return(false, nil).Once()
+	s.env.OnActivity(activities.SendNotificationActivity, mock.Anything, mock.MatchedBy(func(req NotificationRequest) bool {
+		return req.UserID == "user-003" && req.Data["type"] == "bill_reminder" && req.Data["amount"] == "175.00"
+	})).Return(func(ctx context.Context, req NotificationRequest) error {
+		s.False(s.env.Now().Before(remindAt), "reminder sent before %s", remindAt)
+		return nil
+	}).Once()
+	s.env.OnActivity(activities.MarkBillOverdueActivity, mock.Anything, mock.MatchedBy(func(req MarkBillOverdueRequest) bool {
+		return req.AssignedTo == "user-003" && req.DueDate.Equal(dueDate)
+	})).Return(func(ctx context.Context, req MarkBillOverdueRequest) (bool, error) {
+		s.False(s.env.Now().Before(dueDate), "bill marked overdue before its new due date")
+		return false, nil
+	}).Once()
+
+	s.env.ExecuteWorkflow(BillLifecycleWorkflow, params)
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+}
+
+func (s *BillLifecycleWorkflowTestSuite) TestBillSettledWithoutSignal() {
+	params := s.params()
+	params.DueDate = s.env.Now().Add(time.Hour)
+
+	// A bill created a day before it is due is reminded right away, and one
+	// deleted in the meantime is not marked overdue
+	s.env.OnActivity(activities.CheckBillSettledActivity, mock.Anything, mock.Anything).Return(false, nil).Once()
+	s.env.OnActivity(activities.SendNotificationActivity, mock.Anything, mock.Anything).Return(nil).Once()
+	s.env.OnActivity(activities.MarkBillOverdueActivity, mock.Anything, mock.Anything).Return(false, nil).Once()
+
+	s.env.ExecuteWorkflow(BillLifecycleWorkflow, params)
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+}
+
+func (s *BillLifecycleWorkflowTestSuite) TestPaidSeriesBillStartsNextInstance() {
+	params := s.params()
+	params.SeriesID = "series-001"
+	params.AmountType = "fixed"
+	params.FollowUps = BillFollowUpSettings{Interval: time.Hour, MaxFollowUps: 1}
+	next := BillLifecycleWorkflowParams{
+		BillID:      "bill-002",
+		HouseholdID: "household-001",
+		CreatedBy:   "user-001",
+		AssignedTo:  "user-002",
+		Name:        "Electricity",
+		Amount:      150.75,
+		Currency:    "USD",
+		DueDate:     params.DueDate.AddDate(0, 1, 0),
+		SeriesID:    "series-001",
+		AmountType:  "fixed",
+	}
+
+	s.env.OnActivity(activities.CheckBillSettledActivity, mock.Anything, mock.Anything).Return(false, nil).Once()
+	s.env.OnActivity(activities.SendNotificationActivity, mock.Anything, mock.Anything).Return(nil).Once()
+	s.env.OnActivity(activities.CreateNextBillInstanceActivity, mock.Anything, CreateNextBillInstanceRequest{
+		BillID:      "bill-001",
+		SeriesID:    "series-001",
+		HouseholdID: "household-001",
+	}).Return(&next, nil).Once()
+
+	// The next instance gets a lifecycle of its own, with the same follow-ups.
+	// Mocks apply to this run too, which goes on to the workflow itself.
+	s.env.OnWorkflow(BillLifecycleWorkflow, mock.Anything, mock.MatchedBy(func(p BillLifecycleWorkflowParams) bool {
+		return p.BillID == "bill-001"
+	})).Return(BillLifecycleWorkflow).Once()
+	var started BillLifecycleWorkflowParams
+	s.env.OnWorkflow(BillLifecycleWorkflow, mock.Anything, mock.MatchedBy(func(p BillLifecycleWorkflowParams) bool {
+		return p.BillID == "bill-002"
+	})).Return(func(ctx workflow.Context, p BillLifecycleWorkflowParams) error {
+		started = p
+		return nil
+	}).Once()
+
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow("bill_paid", BillPaidSignal{PaidBy: "user-002", Amount: 150.75})
+	}, 8*24*time.Hour)
+
+	s.env.ExecuteWorkflow(BillLifecycleWorkflow, params)
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+	s.Equal("bill-002", started.BillID)
+	s.Equal(params.FollowUps, started.FollowUps)
+	s.env.AssertNotCalled(s.T(), "MarkBillOverdueActivity", mock.Anything, mock.Anything)
+}
+
+func (s *BillLifecycleWorkflowTestSuite) TestSeriesBillDueCreatesNextInstanceOnce() {
+	params := s.params()
+	params.SeriesID = "series-001"
+	params.AmountType = "open"
+	params.Amount = 0
+	params.FollowUps = BillFollowUpSettings{Interval: time.Hour, MaxFollowUps: 1}
+
+	// An unpaid bill creates the next instance at its due date, and not
+	// again once it is paid; the series has ended so nothing is started
+	s.env.OnActivity(activities.CheckBillSettledActivity, mock.Anything, mock.Anything).Return(false, nil).Once()
+	s.env.OnActivity(activities.CheckBillSettledActivity, mock.Anything, mock.Anything).Return(true, nil).Once()
+	s.env.OnActivity(activities.CreateNextBillInstanceActivity, mock.Anything, mock.Anything).Return(func(ctx context.Context, req CreateNextBillInstanceRequest) (*BillLifecycleWorkflowParams, error) {
+		s.False(s.env.Now().Before(params.DueDate), "next instance created before the due date")
+		return nil, nil
+	}).Once()
+	s.env.OnActivity(activities.MarkBillOverdueActivity, mock.Anything, mock.Anything).Return(true, nil).Once()
+
+	// The amount of an open bill is not known yet
+	var sent []NotificationRequest
+	s.env.OnActivity(activities.SendNotificationActivity, mock.Anything, mock.Anything).Return(func(ctx context.Context, req NotificationRequest) error {
+		sent = append(sent, req)
+		return nil
+	}).Times(2)
+
+	s.env.ExecuteWorkflow(BillLifecycleWorkflow, params)
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+	for _, req := range sent {
+		s.Contains(req.Body, "an open amount")
+	}
+}
+
+func TestBillLifecycleWorkflowSuite(t *testing.T) {
+	suite.Run(t, new(BillLifecycleWorkflowTestSuite))
+}
+
+type WebhookDispatchWorkflowTestSuite struct {
+	suite.Suite
+	testsuite.WorkflowTestSuite
+	env *testsuite.TestWorkflowEnvironment
+}
+
+func (s *WebhookDispatchWorkflowTestSuite) SetupTest() {
+	s.env = s.NewTestWorkflowEnvironment()
+	s.env.RegisterActivity(&Activities{})
+}
+
+func (s *WebhookDispatchWorkflowTestSuite) AfterTest(suiteName, testName string) {
+	s.env.AssertExpectations(s.T())
+}
+
+func (s *WebhookDispatchWorkflowTestSuite) TestFailedDeliveryDisablesEndpoint() {
+	event := WebhookEvent{ID: "event-001", Type: "laundry.wash.complete", HouseholdID: "household-001"}
+
+	s.env.OnActivity(activities.ListWebhookEndpointsActivity, mock.Anything, ListWebhookEndpointsRequest{
+		HouseholdID: "household-001",
+		EventType:   "laundry.wash.complete",
+	}).Return([]string{"endpoint-ok", "endpoint-failing", "endpoint-deleted"}, nil)
+
+	s.env.OnActivity(activities.DeliverWebhookActivity, mock.Anything, DeliverWebhookRequest{EndpointID: "endpoint-ok", Event: event}).Return(nil)
+	s.env.OnActivity(activities.DeliverWebhookActivity, mock.Anything, DeliverWebhookRequest{EndpointID: "endpoint-failing", Event: event}).
+		Return(temporal.NewNonRetryableApplicationError("webhook endpoint responded 404 Not Found", "WebhookRejected", nil))
+	s.env.OnActivity(activities.DeliverWebhookActivity, mock.Anything, DeliverWebhookRequest{EndpointID: "endpoint-deleted", Event: event}).
+		Return(temporal.NewNonRetryableApplicationError("webhook endpoint deleted or disabled", "WebhookEndpointGone", nil))
+
+	// Only the endpoint that failed counts a failure
+	s.env.OnActivity(activities.RecordWebhookFailureActivity, mock.Anything, "endpoint-failing").Return(true, nil).Once()
+
+	s.env.ExecuteWorkflow(WebhookDispatchWorkflow, event)
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	var result WebhookDispatchResult
+	s.NoError(s.env.GetWorkflowResult(&result))
+	s.Equal(WebhookDispatchResult{Endpoints: 3, Delivered: 1, Failed: 1, Disabled: []string{"endpoint-failing"}}, result)
+}
+
+func TestWebhookDispatchWorkflowSuite(t *testing.T) {
+	suite.Run(t, new(WebhookDispatchWorkflowTestSuite))
+}
+
+// newReplayer returns a replayer with the versioned workflows registered
+func newReplayer() worker.WorkflowReplayer {
+	replayer := worker.NewWorkflowReplayer()
+	replayer.RegisterWorkflow(TimerWorkflow)
+	replayer.RegisterWorkflow(LaundryWorkflow)
+	replayer.RegisterWorkflow(RecurringTaskWorkflow)
+	replayer.RegisterWorkflow(TaskReminderWorkflow)
+	replayer.RegisterWorkflow(BillLifecycleWorkflow)
+	return replayer
+}
+
+// HouseholdDigestWorkflowTestSuite tests the household digest workflow
+type HouseholdDigestWorkflowTestSuite struct {
+	suite.Suite
+	testsuite.WorkflowTestSuite
+	env *testsuite.TestWorkflowEnvironment
+}
+
+func (s *HouseholdDigestWorkflowTestSuite) SetupTest() {
+	s.env = s.NewTestWorkflowEnvironment()
+	s.env.RegisterActivity(&Activities{})
+}
+
+func (s *HouseholdDigestWorkflowTestSuite) AfterTest(suiteName, testName string) {
+	s.env.AssertExpectations(s.T())
+}
+
+func (s *HouseholdDigestWorkflowTestSuite) TestDigestFollowsMemberPreferences() {
+	loc := time.FixedZone("IST", 3*60*60)
+	today := time.Date(2026, 10, 18, 0, 0, 0, 0, loc)
+	digest := store.HouseholdDigest{
+		HouseholdID: "household-001",
+		Name:        "Home",
+		Timezone:    "Asia/Jerusalem",
+		Date:        today,
+		Members: []store.DigestMember{
+			{UserID: "user-001", FirstName: "Dana", Email: "dana@example.com", Language: "en-US", NotificationsEnabled: true, PushNotifications: true, EmailNotifications: true, EmailDigest: "daily"},
+			{UserID: "user-002", FirstName: "Avi", Email: "avi@example.com", Language: "he", NotificationsEnabled: true, EmailDigest: "weekly"},
+		},
+		TasksToday: []store.DigestTask{
+			{ID: "t1", Title: "Vacuum", AssignedTo: "user-001", DueDate: today.Add(10 * time.Hour)},
+			{ID: "t2", Title: "Laundry", AssignedTo: "user-001", DueDate: today.Add(12 * time.Hour)},
+			{ID: "t3", Title: "Dishes", AssignedTo: "user-001", DueDate: today.Add(18 * time.Hour)},
+			{ID: "t4", Title: "Trash", AssignedTo: "user-001", DueDate: today.Add(20 * time.Hour)},
+		},
+		OverdueTasks:  []store.DigestTask{{ID: "t5", Title: "Water plants", DueDate: today.Add(-24 * time.Hour)}},
+		BillsDue:      []store.DigestBill{{ID: "b1", Name: "Electricity", Amount: 150.75, Currency: "ILS", Status: "pending", DueDate: today.AddDate(0, 0, 2)}},
+		ShoppingItems: 1,
+	}
+
+	s.env.OnActivity(activities.LoadHouseholdDigestActivity, mock.Anything, mock.MatchedBy(func(req LoadHouseholdDigestRequest) bool {
+		return req.HouseholdID == "household-001"
+	})).Return(&digest, nil).Once()
+
+	// Dana allows pushes and chose a daily email digest
+	var push, email NotificationRequest
+	s.env.OnActivity(activities.SendNotificationActivity, mock.Anything, mock.Anything).Return(func(ctx context.Context, req NotificationRequest) error {
+		push = req
+		return nil
+	}).Once()
+	s.env.OnActivity(activities.SendDigestEmailActivity, mock.Anything, mock.Anything).Return(func(ctx context.Context, req DigestEmailRequest) error {
+		s.Equal("dana@example.com", req.Email)
+		s.Equal("Your day at Home", req.Subject)
+		email = NotificationRequest{UserID: req.UserID, Title: req.Title, Body: req.Body}
+		return nil
+	}).Once()
+
+	// Avi allows neither, so sees the digest in the app, in Hebrew
+	var inApp NotificationRequest
+	s.env.OnActivity(activities.SendInAppNotificationActivity, mock.Anything, mock.Anything).Return(func(ctx context.Context, req NotificationRequest) error {
+		inApp = req
+		return nil
+	}).Once()
+
+	s.env.ExecuteWorkflow(HouseholdDigestWorkflow, HouseholdDigestWorkflowParams{HouseholdID: "household-001"})
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	s.Equal("user-001", push.UserID)
+	s.Equal(PriorityLow, push.Priority)
+	s.Equal("household_digest", push.Data["type"])
+	s.Equal("2026-10-18", push.Data["date"])
+	s.Equal("Good morning, Dana", push.Title)
+	s.Equal(strings.Join([]string{
+		"You have 4 tasks today: Vacuum, Laundry, Dishes and 1 more",
+		"1 overdue task: Water plants",
+		"1 bill due this week: Electricity 150.75 ILS (Oct 20)",
+		"1 item on the shopping list",
+	}, "\n"), push.Body)
+	s.Equal(push.Body, email.Body)
+
+	s.Equal("user-002", inApp.UserID)
+	s.Equal("בוקר טוב, Avi", inApp.Title)
+	s.Contains(inApp.Body, "אין לך משימות היום")
+	s.Contains(inApp.Body, "Electricity 150.75 ILS (20/10)")
+}
+
+func (s *HouseholdDigestWorkflowTestSuite) TestChildDigestLeavesOutBills() {
+	today := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	digest := store.HouseholdDigest{
+		HouseholdID: "household-001",
+		Name:        "Home",
+		Date:        today,
+		Members: []store.DigestMember{
+			{UserID: "user-003", FirstName: "Noa", Role: store.RoleChild, NotificationsEnabled: true, PushNotifications: true},
+			{UserID: "user-004", FirstName: "Tom", Role: store.RoleChild, NotificationsEnabled: true, PushNotifications: true},
+		},
+		TasksToday: []store.DigestTask{{ID: "t1", Title: "Feed the cat", AssignedTo: "user-003", DueDate: today.Add(8 * time.Hour)}},
+		BillsDue:   []store.DigestBill{{ID: "b1", Name: "Electricity", Amount: 150.75, Currency: "USD", Status: "pending", DueDate: today.AddDate(0, 0, 2)}},
+	}
+	s.env.OnActivity(activities.LoadHouseholdDigestActivity, mock.Anything, mock.Anything).Return(&digest, nil).Once()
+
+	// The child with a task hears of it alone; the one with nothing but a
+	// bill to report hears nothing
+	var push NotificationRequest
+	s.env.OnActivity(activities.SendNotificationActivity, mock.Anything, mock.Anything).Return(func(ctx context.Context, req NotificationRequest) error {
+		push = req
+		return nil
+	}).Once()
+
+	s.env.ExecuteWorkflow(HouseholdDigestWorkflow, HouseholdDigestWorkflowParams{HouseholdID: "household-001"})
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+
+	s.Equal("user-003", push.UserID)
+	s.Equal("You have 1 task today: Feed the cat", push.Body)
+}
+
+func (s *HouseholdDigestWorkflowTestSuite) TestNothingToReport() {
+	digest := store.HouseholdDigest{
+		HouseholdID: "household-001",
+		Name:        "Home",
+		Members:     []store.DigestMember{{UserID: "user-001", NotificationsEnabled: true, PushNotifications: true}},
+		TasksToday:  []store.DigestTask{{ID: "t1", Title: "Vacuum", AssignedTo: "user-002"}},
+	}
+	s.env.OnActivity(activities.LoadHouseholdDigestActivity, mock.Anything, mock.Anything).Return(&digest, nil).Once()
+
+	s.env.ExecuteWorkflow(HouseholdDigestWorkflow, HouseholdDigestWorkflowParams{HouseholdID: "household-001"})
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+	s.env.AssertNotCalled(s.T(), "SendNotificationActivity", mock.Anything, mock.Anything)
+}
+
+func TestHouseholdDigestWorkflowSuite(t *testing.T) {
+	suite.Run(t, new(HouseholdDigestWorkflowTestSuite))
+}
+
+// TestReplayRecordedHistories replays the histories in testdata, recorded by
+// earlier versions of the workflows, against the current code. It fails when
+// a change is not replay safe; see versions.go.
+func TestReplayRecordedHistories(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no recorded histories in testdata")
+	}
+
+	for _, file := range files {
+		file := file
+		t.Run(strings.TrimSuffix(filepath.Base(file), ".json"), func(t *testing.T) {
+			if err := newReplayer().ReplayWorkflowHistoryFromJSONFile(nil, file); err != nil {
+				t.Fatalf("replaying %s: %v", file, err)
+			}
+		})
+	}
+}
+
+// TestReplayDetectsUnversionedChange checks the replay test catches a change
+// made without a version: a timer that no longer mirrors its Live Activity
+func TestReplayDetectsUnversionedChange(t *testing.T) {
+	replayer := worker.NewWorkflowReplayer()
+	replayer.RegisterWorkflowWithOptions(func(ctx workflow.Context, params TimerWorkflowParams) error {
+		params.Settings.LiveActivity = false
+		return TimerWorkflow(ctx, params)
+	}, workflow.RegisterOptions{Name: "TimerWorkflow"})
+
+	err := replayer.ReplayWorkflowHistoryFromJSONFile(nil, filepath.Join("testdata", "timer_v1.json"))
+	if err == nil {
+		t.Fatal("expected replaying timer_v1.json to fail after an unversioned change")
+	}
+}
@@ -0,0 +1,125 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Source identifies events published by the Temporal worker
+const Source = "temporal-worker"
+
+// Event types published by the worker; they match the consumer in services/kafka
+const (
+	TaskCreated      = "task.created"
+	TaskUpdated      = "task.updated"
+	TaskCompleted    = "task.completed"
+	TaskEscalated    = "task.escalated"
+	BillCreated      = "bill.created"
+	BillOverdue      = "bill.overdue"
+	TimerStarted     = "timer.started"
+	TimerCompleted   = "timer.completed"
+	TimerStopped     = "timer.stopped"
+	LaundryStarted   = "laundry.started"
+	LaundryCompleted = "laundry.completed"
+)
+
+// Event is a domain event in the format read by the Kafka consumer
+type Event struct {
+	ID          string                 `json:"id"`
+	Type        string                 `json:"type"`
+	Source      string                 `json:"source"`
+	HouseholdID string                 `json:"householdId,omitempty"`
+	UserID      string                 `json:"userId,omitempty"`
+	Timestamp   time.Time              `json:"timestamp"`
+	Version     string                 `json:"version"`
+	Data        map[string]interface{} `json:"data"`
+	Metadata    map[string]string      `json:"metadata,omitempty"`
+}
+
+// Publisher publishes domain events
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// KafkaProducer publishes events to the topic of their resource
+type KafkaProducer struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaProducer creates a new producer for the given brokers
+func NewKafkaProducer(brokers []string) *KafkaProducer {
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireOne,
+	}
+	return &KafkaProducer{writer: writer}
+}
+
+// Publish writes the event synchronously so activity retries cover broker failures
+func (p *KafkaProducer) Publish(ctx context.Context, event Event) error {
+	if event.Source == "" {
+		event.Source = Source
+	}
+	if event.Version == "" {
+		event.Version = "1.0"
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+
+	value, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	// Events of a household stay ordered on one partition
+	key := event.HouseholdID
+	if key == "" {
+		key = event.ID
+	}
+
+	err = p.writer.WriteMessages(ctx, kafka.Message{
+		Topic: TopicFor(event.Type),
+		Key:   []byte(key),
+		Value: value,
+		Headers: []kafka.Header{
+			{Key: "event-type", Value: []byte(event.Type)},
+			{Key: "event-source", Value: []byte(event.Source)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish %s event: %w", event.Type, err)
+	}
+	return nil
+}
+
+// Close flushes and closes the producer
+func (p *KafkaProducer) Close() error {
+	return p.writer.Close()
+}
+
+// TopicFor returns the Kafka topic of an event type, e.g. house-helper.timers
+// for timer.started
+func TopicFor(eventType string) string {
+	resource, _, _ := strings.Cut(eventType, ".")
+	switch resource {
+	case "task":
+		return "house-helper.tasks"
+	case "bill":
+		return "house-helper.bills"
+	case "timer":
+		return "house-helper.timers"
+	case "laundry":
+		return "house-helper.laundry"
+	case "notification":
+		return "house-helper.notifications"
+	default:
+		return "house-helper.misc"
+	}
+}
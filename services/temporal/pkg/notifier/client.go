@@ -0,0 +1,213 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// StatusError is returned when the notifier answers with a non-2xx status
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("notifier returned %d: %s", e.StatusCode, e.Body)
+}
+
+// Retryable reports whether the request may succeed if sent again
+func (e *StatusError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// NoDevices reports whether the notifier rejected a notification because
+// the user has no registered devices
+func (e *StatusError) NoDevices() bool {
+	return e.StatusCode == http.StatusNotFound && strings.Contains(e.Body, "no_devices")
+}
+
+// Client calls the notifier service over HTTP
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a new notifier client
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// LiveActivityEvent starts, updates or ends a live activity
+type LiveActivityEvent struct {
+	UserID         string                 `json:"userId,omitempty"`
+	Event          string                 `json:"event"`
+	ContentState   map[string]interface{} `json:"contentState,omitempty"`
+	StaleDate      *time.Time             `json:"staleDate,omitempty"`
+	DismissalDate  *time.Time             `json:"dismissalDate,omitempty"`
+	AttributesType string                 `json:"attributesType,omitempty"`
+	Attributes     map[string]interface{} `json:"attributes,omitempty"`
+	AlertTitle     string                 `json:"alertTitle,omitempty"`
+	AlertBody      string                 `json:"alertBody,omitempty"`
+	Urgent         bool                   `json:"urgent,omitempty"`
+}
+
+// LiveActivityResult reports how the notifier handled a live activity event
+type LiveActivityResult struct {
+	Sent     int  `json:"sent"`
+	Removed  int  `json:"removed"`
+	Deferred bool `json:"deferred"`
+	Priority int  `json:"priority"`
+}
+
+// SendLiveActivityEvent delivers an event to the live activity of a timer or laundry load
+func (c *Client) SendLiveActivityEvent(ctx context.Context, activityID string, event LiveActivityEvent) (*LiveActivityResult, error) {
+	var result LiveActivityResult
+	path := "/live-activities/" + url.PathEscape(activityID) + "/events"
+	if err := c.post(ctx, path, event, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Notification is a push notification for every registered device of a user
+type Notification struct {
+	UserID      string            `json:"userId"`
+	Title       string            `json:"title"`
+	Body        string            `json:"body"`
+	Data        map[string]string `json:"data,omitempty"`
+	CollapseKey string            `json:"collapseKey,omitempty"`
+
+	// Urgency is "low", "normal" or "high"; providers deliver urgent
+	// notifications immediately
+	Urgency string `json:"urgency,omitempty"`
+}
+
+// Receipt reports the delivery state of a notification queued for one device
+type Receipt struct {
+	ID        string `json:"id"`
+	DeviceID  string `json:"deviceId"`
+	Platform  string `json:"platform"`
+	Status    string `json:"status"`
+	Duplicate bool   `json:"duplicate,omitempty"`
+}
+
+// Notify queues a notification through the notifier's delivery queue.
+// Requests with the same idempotency key are queued once; a repeat returns
+// the original receipts. A user without devices gets a 404 StatusError.
+func (c *Client) Notify(ctx context.Context, idempotencyKey string, notification Notification) ([]Receipt, error) {
+	var result struct {
+		Receipts []Receipt `json:"receipts"`
+	}
+	header := http.Header{}
+	if idempotencyKey != "" {
+		header.Set("Idempotency-Key", idempotencyKey)
+	}
+	if err := c.do(ctx, "/notify", header, notification, &result); err != nil {
+		return nil, err
+	}
+	return result.Receipts, nil
+}
+
+// Broadcast is a real-time update for the connected clients of a household
+type Broadcast struct {
+	Type     string      `json:"type"`
+	Resource string      `json:"resource,omitempty"`
+	Data     interface{} `json:"data"`
+}
+
+// BroadcastToHousehold sends an update over WebSocket and SSE to every
+// connected member of the household
+func (c *Client) BroadcastToHousehold(ctx context.Context, householdID string, broadcast Broadcast) error {
+	return c.post(ctx, "/broadcast/household?householdId="+url.QueryEscape(householdID), broadcast, nil)
+}
+
+// BroadcastToUser sends an update over WebSocket and SSE to the user's
+// connected clients
+func (c *Client) BroadcastToUser(ctx context.Context, userID string, broadcast Broadcast) error {
+	return c.post(ctx, "/broadcast/user?userId="+url.QueryEscape(userID), broadcast, nil)
+}
+
+// Email is a notification email, sent right away
+type Email struct {
+	To            EmailRecipient `json:"to"`
+	Subject       string         `json:"subject"`
+	Title         string         `json:"title"`
+	Body          string         `json:"body"`
+	ActionURL     string         `json:"actionUrl,omitempty"`
+	HouseholdID   string         `json:"householdId,omitempty"`
+	HouseholdName string         `json:"householdName,omitempty"`
+	Category      string         `json:"category,omitempty"`
+}
+
+// EmailRecipient is the addressee of an email
+type EmailRecipient struct {
+	UserID string `json:"userId,omitempty"`
+	Email  string `json:"email"`
+	Name   string `json:"name,omitempty"`
+}
+
+// SendEmail sends an email through the notifier. Emails to suppressed
+// addresses are accepted and dropped; suppressed reports them.
+func (c *Client) SendEmail(ctx context.Context, email Email) (suppressed bool, err error) {
+	var result struct {
+		Suppressed bool `json:"suppressed"`
+	}
+	if err := c.post(ctx, "/notify/email", email, &result); err != nil {
+		return false, err
+	}
+	return result.Suppressed, nil
+}
+
+// post sends a JSON request and decodes the JSON response into out
+func (c *Client) post(ctx context.Context, path string, body, out interface{}) error {
+	return c.do(ctx, path, nil, body, out)
+}
+
+// do posts a JSON request with extra headers and decodes the JSON response into out
+func (c *Client) do(ctx context.Context, path string, header http.Header, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call notifier: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read notifier response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &StatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(respBody))}
+	}
+
+	if out != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("failed to decode notifier response: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+// Package webhooks signs the deliveries of household webhooks so receivers
+// can check they come from House Helper and are not replayed
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every delivery
+const (
+	EventHeader     = "X-HouseHelper-Event"
+	DeliveryHeader  = "X-HouseHelper-Delivery"
+	TimestampHeader = "X-HouseHelper-Timestamp"
+	SignatureHeader = "X-HouseHelper-Signature"
+)
+
+// DefaultTolerance is how old a delivery's timestamp may be when verified
+const DefaultTolerance = 5 * time.Minute
+
+const signaturePrefix = "v1="
+
+var (
+	// ErrInvalidSignature is returned when the signature does not match the body
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+
+	// ErrTimestampOutOfRange is returned for a delivery signed too long ago,
+	// which may be a replay
+	ErrTimestampOutOfRange = errors.New("webhook timestamp out of range")
+)
+
+// Sign returns the signature header value of a body sent at timestamp: the
+// hex HMAC-SHA256, keyed with the endpoint's secret, of "<unix seconds>.<body>"
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	return signaturePrefix + hex.EncodeToString(mac(secret, timestamp.Unix(), body))
+}
+
+// Verify checks the timestamp and signature headers of a received body
+func Verify(secret, timestampHeader, signatureHeader string, body []byte, now time.Time, tolerance time.Duration) error {
+	seconds, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return ErrTimestampOutOfRange
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > tolerance || age < -tolerance {
+		return ErrTimestampOutOfRange
+	}
+
+	signature, err := hex.DecodeString(strings.TrimPrefix(signatureHeader, signaturePrefix))
+	if err != nil || !strings.HasPrefix(signatureHeader, signaturePrefix) {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal(signature, mac(secret, seconds, body)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func mac(secret string, seconds int64, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(strconv.FormatInt(seconds, 10)))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
@@ -0,0 +1,42 @@
+package webhooks
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"type":"task.completed"}`)
+	sentAt := time.Unix(1700000000, 0)
+	signature := Sign("whsec_test", sentAt, body)
+	timestamp := strconv.FormatInt(sentAt.Unix(), 10)
+
+	if err := Verify("whsec_test", timestamp, signature, body, sentAt.Add(time.Minute), DefaultTolerance); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      []byte
+		now       time.Time
+		want      error
+	}{
+		{"other secret", "whsec_other", timestamp, body, sentAt, ErrInvalidSignature},
+		{"changed body", "whsec_test", timestamp, []byte(`{"type":"bill.paid"}`), sentAt, ErrInvalidSignature},
+		{"changed timestamp", "whsec_test", strconv.FormatInt(sentAt.Unix()+60, 10), body, sentAt, ErrInvalidSignature},
+		{"replayed", "whsec_test", timestamp, body, sentAt.Add(DefaultTolerance + time.Second), ErrTimestampOutOfRange},
+		{"malformed timestamp", "whsec_test", "yesterday", body, sentAt, ErrTimestampOutOfRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.timestamp, signature, tt.body, tt.now, DefaultTolerance)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}